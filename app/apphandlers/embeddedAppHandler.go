@@ -109,10 +109,12 @@ func (embedded *EmbeddedApp) StartRudderCore(options *app.Options) {
 		var gateway gateway.HandleT
 		var rateLimiter ratelimiter.HandleT
 
-		rateLimiter.SetUp()
+		rateLimiter.SetUp(backendconfig.DefaultBackendConfig)
 		gateway.SetReadonlyDBs(&readonlyGatewayDB, &readonlyRouterDB, &readonlyBatchRouterDB)
+		gateway.SetTracer(&tracer)
 		gateway.Setup(embedded.App, backendconfig.DefaultBackendConfig, &gatewayDB, &rateLimiter, embedded.VersionHandler)
 		go gateway.StartAdminHandler()
+		go gateway.StartGRPCHandler()
 		gateway.StartWebHandler()
 	}
 	//go readIOforResume(router) //keeping it as input from IO, to be replaced by UI
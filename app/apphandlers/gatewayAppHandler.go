@@ -57,10 +57,12 @@ func (gatewayApp *GatewayApp) StartRudderCore(options *app.Options) {
 		var gateway gateway.HandleT
 		var rateLimiter ratelimiter.HandleT
 
-		rateLimiter.SetUp()
+		rateLimiter.SetUp(backendconfig.DefaultBackendConfig)
 		gateway.SetReadonlyDBs(&readonlyGatewayDB, &readonlyRouterDB, &readonlyBatchRouterDB)
+		gateway.SetTracer(&tracer)
 		gateway.Setup(gatewayApp.App, backendconfig.DefaultBackendConfig, &gatewayDB, &rateLimiter, gatewayApp.VersionHandler)
 		go gateway.StartAdminHandler()
+		go gateway.StartGRPCHandler()
 		gateway.StartWebHandler()
 	}
 	//go readIOforResume(router) //keeping it as input from IO, to be replaced by UI
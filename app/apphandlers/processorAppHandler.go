@@ -19,6 +19,7 @@ import (
 	"github.com/rudderlabs/rudder-server/services/db"
 	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
 	transformationdebugger "github.com/rudderlabs/rudder-server/services/debugger/transformation"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types"
 
@@ -139,6 +140,7 @@ func startHealthWebHandler() {
 	srvMux := mux.NewRouter()
 	srvMux.HandleFunc("/health", healthHandler)
 	srvMux.HandleFunc("/", healthHandler)
+	srvMux.HandleFunc("/metrics", stats.MetricsHandler)
 	srv := &http.Server{
 		Addr:              ":" + strconv.Itoa(webPort),
 		Handler:           bugsnag.Handler(srvMux),
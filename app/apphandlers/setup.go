@@ -15,7 +15,9 @@ import (
 	"github.com/rudderlabs/rudder-server/processor"
 	"github.com/rudderlabs/rudder-server/router"
 	"github.com/rudderlabs/rudder-server/router/batchrouter"
+	"github.com/rudderlabs/rudder-server/router/dlq"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
+	"github.com/rudderlabs/rudder-server/services/trace"
 	"github.com/rudderlabs/rudder-server/services/validators"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
@@ -37,6 +39,7 @@ var (
 	Diagnostics                                                diagnostics.DiagnosticsI = diagnostics.Diagnostics
 	readonlyGatewayDB, readonlyRouterDB, readonlyBatchRouterDB jobsdb.ReadonlyHandleT
 	readonlyProcErrorDB                                        jobsdb.ReadonlyHandleT
+	tracer                                                     trace.HandleT
 )
 
 //AppHandler to be implemented by different app type objects.
@@ -76,7 +79,7 @@ func loadConfig() {
 	config.RegisterBoolConfigVariable(true, &enableRouter, false, "enableRouter")
 	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES"}
 	asyncDestinations = []string{"MARKETO_BULK_UPLOAD"}
-	warehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE"}
+	warehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "DUCKDB", "DELTALAKE"}
 }
 
 func rudderCoreBaseSetup() {
@@ -103,6 +106,7 @@ func rudderCoreBaseSetup() {
 
 	processor.RegisterAdminHandlers(&readonlyProcErrorDB)
 	router.RegisterAdminHandlers(&readonlyRouterDB, &readonlyBatchRouterDB)
+	tracer.Setup(&readonlyGatewayDB, &readonlyRouterDB, &readonlyBatchRouterDB, &readonlyProcErrorDB)
 
 	runtime.GOMAXPROCS(maxProcess)
 }
@@ -138,6 +142,8 @@ func StartRouter(enableRouter bool, routerDB, batchRouterDB, procErrorDB *jobsdb
 	if enableRouter {
 		router.RoutersManagerSetup()
 		batchrouter.BatchRoutersManagerSetup()
+		var routerDLQ dlq.HandleT
+		routerDLQ.Setup(&readonlyProcErrorDB, routerDB, batchRouterDB)
 		go monitorDestRouters(routerDB, batchRouterDB, procErrorDB, reporting)
 		routerLoaded = true
 	}
@@ -82,7 +82,7 @@ func RegisterReportingFeature(f ReportingFeatureSetup) {
 Replay Feature
 *********************************/
 
-// ReplayFeature handles inserting of failed jobs into repsective gw/rt jobsdb
+// ReplayFeature replays the archived jobs of the gateway jobsdb to selected destinations
 type ReplayFeature interface {
 	Setup(replayDB *jobsdb.HandleT, gwDB *jobsdb.HandleT, routerDB *jobsdb.HandleT)
 }
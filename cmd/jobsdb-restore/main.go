@@ -0,0 +1,87 @@
+/*
+jobsdb-restore lists and restores the jobsdb backups of a running rudder-server over its admin interface.
+
+	jobsdb-restore -prefix rt -list
+	jobsdb-restore -prefix rt -keys <key>,<key> -destination <destination_id> -from 2021-06-01T00:00:00Z
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+func parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		exit(err)
+	}
+	return t
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func main() {
+	prefix := flag.String("prefix", "", "jobsdb to restore: gw, rt or batch_rt")
+	list := flag.Bool("list", false, "list the backups of the jobsdb")
+	keys := flag.String("keys", "", "comma separated keys of the backups to restore")
+	sourceID := flag.String("source", "", "restore only the jobs of this source")
+	destinationID := flag.String("destination", "", "restore only the jobs of this destination")
+	from := flag.String("from", "", "restore only the jobs created after this RFC3339 time")
+	to := flag.String("to", "", "restore only the jobs created before this RFC3339 time")
+	flag.Parse()
+
+	if *prefix == "" || (!*list && *keys == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		exit(err)
+	}
+	client, err := rpc.DialHTTPPath("unix", filepath.Join(tmpDirPath, "rudder-server.sock"), rpc.DefaultRPCPath)
+	if err != nil {
+		exit(err)
+	}
+	defer client.Close()
+
+	var reply string
+	if *list {
+		err = client.Call("JobsDBBackups.ListBackups", *prefix, &reply)
+	} else {
+		request, marshalErr := json.Marshal(jobsdb.RestoreRequestT{
+			TablePrefix:   *prefix,
+			Keys:          strings.Split(*keys, ","),
+			SourceID:      *sourceID,
+			DestinationID: *destinationID,
+			From:          parseTime(*from),
+			To:            parseTime(*to),
+		})
+		if marshalErr != nil {
+			exit(marshalErr)
+		}
+		err = client.Call("JobsDBBackups.Restore", string(request), &reply)
+	}
+	if reply != "" {
+		fmt.Println(reply)
+	}
+	if err != nil {
+		exit(err)
+	}
+}
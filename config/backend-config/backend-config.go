@@ -3,6 +3,7 @@ package backendconfig
 //go:generate mockgen -destination=../../mocks/config/backend-config/mock_backendconfig.go -package=mock_backendconfig github.com/rudderlabs/rudder-server/config/backend-config BackendConfig
 
 import (
+	"encoding/json"
 	"reflect"
 	"sort"
 	"sync"
@@ -111,6 +112,17 @@ type SourceT struct {
 	WorkspaceID      string
 	Destinations     []DestinationT
 	WriteKey         string
+	TrackingPlan     TrackingPlanT `json:"trackingPlan"`
+}
+
+//TrackingPlanT holds the JSON Schema of every planned event of a source, keyed by event name for track events
+//and by event type for the others. ViolationAction is one of drop, annotate or procError
+type TrackingPlanT struct {
+	ID                   string                     `json:"id"`
+	Version              int                        `json:"version"`
+	ViolationAction      string                     `json:"violationAction"`
+	BlockUnplannedEvents bool                       `json:"blockUnplannedEvents"`
+	Events               map[string]json.RawMessage `json:"events"`
 }
 
 type WorkspaceRegulationT struct {
@@ -134,6 +146,21 @@ type ConfigT struct {
 	Sources         []SourceT       `json:"sources"`
 	Libraries       LibrariesT      `json:"libraries"`
 	ConnectionFlags ConnectionFlags `json:"flags"`
+	RateLimits      RateLimitsT     `json:"rateLimits"`
+}
+
+//QuotaT is the number of requests allowed in a rolling window
+type QuotaT struct {
+	Limit     int64 `json:"limit"`
+	WindowInS int64 `json:"windowInS"`
+}
+
+//RateLimitsT holds gateway quotas. Workspaces and Users are keyed by workspaceID, Sources by sourceID.
+//Users quota is applied to each userId of the workspace separately
+type RateLimitsT struct {
+	Workspaces map[string]QuotaT `json:"workspaces"`
+	Sources    map[string]QuotaT `json:"sources"`
+	Users      map[string]QuotaT `json:"users"`
 }
 
 type ConnectionFlags struct {
@@ -236,6 +263,7 @@ func trackConfig(preConfig ConfigT, curConfig ConfigT) {
 func filterProcessorEnabledDestinations(config ConfigT) ConfigT {
 	var modifiedConfig ConfigT
 	modifiedConfig.Libraries = config.Libraries
+	modifiedConfig.RateLimits = config.RateLimits
 	modifiedConfig.Sources = make([]SourceT, 0)
 	for _, source := range config.Sources {
 		destinations := make([]DestinationT, 0)
@@ -425,3 +453,22 @@ func startRegulationPolling() {
 		pollRegulations()
 	})
 }
+
+func (rateLimits *RateLimitsT) merge(other RateLimitsT) {
+	rateLimits.Workspaces = mergeQuotas(rateLimits.Workspaces, other.Workspaces)
+	rateLimits.Sources = mergeQuotas(rateLimits.Sources, other.Sources)
+	rateLimits.Users = mergeQuotas(rateLimits.Users, other.Users)
+}
+
+func mergeQuotas(quotas, other map[string]QuotaT) map[string]QuotaT {
+	if len(other) == 0 {
+		return quotas
+	}
+	if quotas == nil {
+		quotas = make(map[string]QuotaT)
+	}
+	for key, quota := range other {
+		quotas[key] = quota
+	}
+	return quotas
+}
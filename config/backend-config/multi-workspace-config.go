@@ -105,6 +105,7 @@ func (multiWorkspaceConfig *MultiWorkspaceConfig) Get() (ConfigT, bool) {
 			workspaceIDToLibrariesMap[workspaceID] = workspaceConfig.Libraries
 		}
 		sourcesJSON.Sources = append(sourcesJSON.Sources, workspaceConfig.Sources...)
+		sourcesJSON.RateLimits.merge(workspaceConfig.RateLimits)
 	}
 	sourcesJSON.ConnectionFlags.URL = config.GetEnv("CP_ROUTER_URL", "")
 	sourcesJSON.ConnectionFlags.Services = map[string]bool{"warehouse": true} // always set connection flags to true for hosted warehouse service
@@ -62,6 +62,7 @@ type EventModelT struct {
 	LastSeen        time.Time
 	reservoirSample *ReservoirSample
 	TotalCount      int64
+	ViolationCount  int64
 }
 
 // SchemaVersionT is a struct that represents SCHEMA_VERSIONS_TABLE
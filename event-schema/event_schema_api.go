@@ -11,6 +11,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rudderlabs/rudder-server/gateway/response"
+	trackingplan "github.com/rudderlabs/rudder-server/tracking-plan"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -282,9 +283,11 @@ func (manager *EventSchemaManagerT) GetSchemaVersionMissingKeys(w http.ResponseW
 func (manager *EventSchemaManagerT) fetchEventModelsByWriteKey(writeKey string) []*EventModelT {
 	var eventModelsSelectSQL string
 	if writeKey == "" {
-		eventModelsSelectSQL = fmt.Sprintf(`SELECT id, uuid, write_key, event_type, event_model_identifier, created_at, schema, total_count, last_seen FROM %s`, EVENT_MODELS_TABLE)
+		eventModelsSelectSQL = fmt.Sprintf(`SELECT em.id, em.uuid, em.write_key, em.event_type, em.event_model_identifier, em.created_at, em.schema, em.total_count, em.last_seen, COALESCE(v.violation_count, 0) FROM %s em
+			LEFT JOIN %s v ON v.write_key = em.write_key AND v.event_type = em.event_type AND v.event_model_identifier = em.event_model_identifier`, EVENT_MODELS_TABLE, trackingplan.ViolationsTable)
 	} else {
-		eventModelsSelectSQL = fmt.Sprintf(`SELECT id, uuid, write_key, event_type, event_model_identifier, created_at, schema, total_count, last_seen FROM %s WHERE write_key = '%s'`, EVENT_MODELS_TABLE, writeKey)
+		eventModelsSelectSQL = fmt.Sprintf(`SELECT em.id, em.uuid, em.write_key, em.event_type, em.event_model_identifier, em.created_at, em.schema, em.total_count, em.last_seen, COALESCE(v.violation_count, 0) FROM %s em
+			LEFT JOIN %s v ON v.write_key = em.write_key AND v.event_type = em.event_type AND v.event_model_identifier = em.event_model_identifier WHERE em.write_key = '%s'`, EVENT_MODELS_TABLE, trackingplan.ViolationsTable, writeKey)
 	}
 
 	rows, err := manager.dbHandle.Query(eventModelsSelectSQL)
@@ -296,7 +299,7 @@ func (manager *EventSchemaManagerT) fetchEventModelsByWriteKey(writeKey string)
 	for rows.Next() {
 		var eventModel EventModelT
 		err := rows.Scan(&eventModel.ID, &eventModel.UUID, &eventModel.WriteKey, &eventModel.EventType,
-			&eventModel.EventIdentifier, &eventModel.CreatedAt, &eventModel.Schema, &eventModel.TotalCount, &eventModel.LastSeen)
+			&eventModel.EventIdentifier, &eventModel.CreatedAt, &eventModel.Schema, &eventModel.TotalCount, &eventModel.LastSeen, &eventModel.ViolationCount)
 		assertError(err)
 
 		eventModels = append(eventModels, &eventModel)
@@ -327,7 +330,8 @@ func (manager *EventSchemaManagerT) fetchSchemaVersionsByEventID(eventID string)
 }
 
 func (manager *EventSchemaManagerT) fetchEventModelByID(id string) (*EventModelT, error) {
-	eventModelsSelectSQL := fmt.Sprintf(`SELECT id, uuid, write_key, event_type, event_model_identifier, created_at, schema, total_count, last_seen FROM %s WHERE uuid = '%s'`, EVENT_MODELS_TABLE, id)
+	eventModelsSelectSQL := fmt.Sprintf(`SELECT em.id, em.uuid, em.write_key, em.event_type, em.event_model_identifier, em.created_at, em.schema, em.total_count, em.last_seen, COALESCE(v.violation_count, 0) FROM %s em
+			LEFT JOIN %s v ON v.write_key = em.write_key AND v.event_type = em.event_type AND v.event_model_identifier = em.event_model_identifier WHERE em.uuid = '%s'`, EVENT_MODELS_TABLE, trackingplan.ViolationsTable, id)
 
 	rows, err := manager.dbHandle.Query(eventModelsSelectSQL)
 	assertError(err)
@@ -338,7 +342,7 @@ func (manager *EventSchemaManagerT) fetchEventModelByID(id string) (*EventModelT
 	for rows.Next() {
 		var eventModel EventModelT
 		err := rows.Scan(&eventModel.ID, &eventModel.UUID, &eventModel.WriteKey, &eventModel.EventType,
-			&eventModel.EventIdentifier, &eventModel.CreatedAt, &eventModel.Schema, &eventModel.TotalCount, &eventModel.LastSeen)
+			&eventModel.EventIdentifier, &eventModel.CreatedAt, &eventModel.Schema, &eventModel.TotalCount, &eventModel.LastSeen, &eventModel.ViolationCount)
 		assertError(err)
 
 		eventModels = append(eventModels, &eventModel)
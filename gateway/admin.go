@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/tidwall/gjson"
+
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 )
@@ -39,8 +41,9 @@ type GatewayRPCHandler struct {
 }
 
 type SqlRunner struct {
-	dbHandle     *sql.DB
-	jobTableName string
+	dbHandle       *sql.DB
+	jobTableName   string
+	readOnlyJobsDB jobsdb.ReadonlyJobsDB
 }
 
 type SourceEvents struct {
@@ -84,9 +87,16 @@ func (r *SqlRunner) getNumUniqueUsers() (int, error) {
 }
 
 func (r *SqlRunner) getAvgBatchSize() (float64, error) {
+	compressed, err := r.readOnlyJobsDB.IsCompressedTable(r.jobTableName)
+	if err != nil {
+		return 0, err
+	}
+	if compressed {
+		return r.getAvgCompressedBatchSize()
+	}
+
 	var batchSize sql.NullFloat64
 	var avgBatchSize float64
-	var err error
 	avgBatchSizeStmt := fmt.Sprintf(`select avg(jsonb_array_length(batch)) from (select event_payload->'batch' as batch from %s) t`, r.jobTableName)
 	err = runSQL(r, avgBatchSizeStmt, &batchSize)
 	if batchSize.Valid {
@@ -95,6 +105,32 @@ func (r *SqlRunner) getAvgBatchSize() (float64, error) {
 	return avgBatchSize, err
 }
 
+//getAvgCompressedBatchSize averages the batch sizes of a table with compressed payloads, which can't be read in postgres
+func (r *SqlRunner) getAvgCompressedBatchSize() (float64, error) {
+	rows, err := r.dbHandle.Query(fmt.Sprintf(`select event_payload from %s`, r.jobTableName))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var numBatches, numEvents int64
+	for rows.Next() {
+		var payload []byte
+		if err = rows.Scan(&payload); err != nil {
+			return 0, err
+		}
+		if payload, err = r.readOnlyJobsDB.DecompressPayload(payload); err != nil {
+			return 0, err
+		}
+		numBatches++
+		numEvents += gjson.GetBytes(payload, "batch.#").Int()
+	}
+	if err = rows.Err(); err != nil || numBatches == 0 {
+		return 0, err
+	}
+	return float64(numEvents) / float64(numBatches), nil
+}
+
 func (r *SqlRunner) getTableSize() (int64, error) {
 	var tableSize int64
 	var err error
@@ -156,7 +192,7 @@ func (g *GatewayRPCHandler) GetDSStats(dsName string, result *string) (err error
 		return err
 	}
 	defer dbHandle.Close() // since this also returns an error, we can explicitly close but not doing
-	runner := &SqlRunner{dbHandle: dbHandle, jobTableName: jobTableName}
+	runner := &SqlRunner{dbHandle: dbHandle, jobTableName: jobTableName, readOnlyJobsDB: g.readOnlyJobsDB}
 	sources, err := runner.getUniqueSources()
 	if err != nil {
 		misc.AppendError("getUniqueSources", &completeErr, &err)
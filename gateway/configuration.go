@@ -11,6 +11,10 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(8080, &webPort, false, 1, "Gateway.webPort")
 	//Port where AdminHandler is running
 	config.RegisterIntConfigVariable(8089, &adminWebPort, false, 1, "Gateway.adminWebPort")
+	// Enables the grpc IngestService endpoint. false by default
+	config.RegisterBoolConfigVariable(false, &enableGRPC, false, "Gateway.enableGRPC")
+	//Port where the grpc IngestService is running
+	config.RegisterIntConfigVariable(8090, &grpcPort, false, 1, "Gateway.grpcPort")
 	//Number of incoming requests that are batched before handing off to write workers
 	config.RegisterIntConfigVariable(128, &maxUserWebRequestBatchSize, false, 1, "Gateway.maxUserRequestBatchSize")
 	//Number of userWorkerBatchRequest that are batched before initiating write
@@ -29,6 +33,9 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(4000, &maxReqSize, true, 1024, "Gateway.maxReqSizeInKB")
 	// Enable rate limit on incoming events. false by default
 	config.RegisterBoolConfigVariable(false, &enableRateLimit, true, "Gateway.enableRateLimit")
+	// Enables honoring the Idempotency-Key header of web requests. false by default
+	config.RegisterBoolConfigVariable(false, &enableIdempotency, false, "Gateway.enableIdempotency")
+	config.RegisterIntConfigVariable(255, &maxIdempotencyKeyLength, false, 1, "Gateway.maxIdempotencyKeyLength")
 	// Enable suppress user feature. false by default
 	config.RegisterBoolConfigVariable(true, &enableSuppressUserFeature, false, "Gateway.enableSuppressUserFeature")
 	// EventSchemas feature. false by default
@@ -69,6 +76,13 @@ func SetEnableRateLimit(b bool) bool {
 	return prev
 }
 
+//SetEnableIdempotency overrides enableIdempotency configuration and returns previous value
+func SetEnableIdempotency(b bool) bool {
+	prev := enableIdempotency
+	enableIdempotency = b
+	return prev
+}
+
 //SetEnableSuppressUserFeature overrides enableSuppressUserFeature configuration and returns previous value
 func SetEnableSuppressUserFeature(b bool) bool {
 	prev := enableSuppressUserFeature
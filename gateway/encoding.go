@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+)
+
+//bodyDecoders wrap a request body with a reader decoding the Content-Encoding they are registered for
+var bodyDecoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip":    newGzipReader,
+	"x-gzip":  newGzipReader,
+	"deflate": newDeflateReader,
+	"zstd":    newZstdReader,
+	"br":      newBrotliReader,
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+//newDeflateReader reads zlib wrapped deflate as per RFC 7230, and raw deflate sent by some clients
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// zlib header: compression method 8 in the low nibble of CMF and CMF<<8|FLG a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(maxReqSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}
+
+func newBrotliReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(r)), nil
+}
+
+//contentEncodings returns the encodings of the request body in the order they were applied, ignoring identity
+func contentEncodings(r *http.Request) []string {
+	var encodings []string
+	for _, header := range r.Header["Content-Encoding"] {
+		for _, encoding := range strings.Split(header, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+//readRequestBody reads the request body decoding it as per its Content-Encoding.
+//Decoded bodies are limited to maxReqSize so that small compressed requests can't take up unbounded memory
+func readRequestBody(r *http.Request) ([]byte, error) {
+	encodings := contentEncodings(r)
+	if len(encodings) == 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	var body io.Reader = r.Body
+	for i := len(encodings) - 1; i >= 0; i-- {
+		newDecoder, ok := bodyDecoders[encodings[i]]
+		if !ok {
+			return nil, errors.New(response.UnsupportedContentEncoding)
+		}
+		decoder, err := newDecoder(body)
+		if err != nil {
+			return nil, errors.New(response.RequestBodyDecompressionFailed)
+		}
+		defer decoder.Close()
+		body = decoder
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(body, int64(maxReqSize)+1))
+	if err != nil {
+		return nil, errors.New(response.RequestBodyDecompressionFailed)
+	}
+	if len(payload) > maxReqSize {
+		return nil, errors.New(response.DecompressedRequestBodyTooLarge)
+	}
+	return payload, nil
+}
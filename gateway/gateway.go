@@ -22,6 +22,7 @@ import (
 	"github.com/rudderlabs/rudder-server/gateway/webhook"
 	operationmanager "github.com/rudderlabs/rudder-server/operation-manager"
 	"github.com/rudderlabs/rudder-server/services/diagnostics"
+	"github.com/rudderlabs/rudder-server/services/idempotency"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
 
 	"github.com/bugsnag/bugsnag-go"
@@ -33,8 +34,11 @@ import (
 	"github.com/rudderlabs/rudder-server/jobsdb"
 	ratelimiter "github.com/rudderlabs/rudder-server/rate-limiter"
 	"github.com/rudderlabs/rudder-server/rruntime"
+	livedebugger "github.com/rudderlabs/rudder-server/services/debugger/live"
 	sourcedebugger "github.com/rudderlabs/rudder-server/services/debugger/source"
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/trace"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -64,6 +68,7 @@ type webRequestT struct {
 	requestPayload []byte
 	writeKey       string
 	ipAddr         string
+	traceParent    string
 }
 
 type batchWebRequestT struct {
@@ -72,6 +77,7 @@ type batchWebRequestT struct {
 
 var (
 	webPort, maxUserWebRequestWorkerProcess, maxDBWriterProcess, adminWebPort int
+	grpcPort                                                                  int
 	maxUserWebRequestBatchSize, maxDBBatchSize, MaxHeaderBytes                int
 	userWebRequestBatchTimeout, dbBatchWriteTimeout                           time.Duration
 	enabledWriteKeysSourceMap                                                 map[string]backendconfig.SourceT
@@ -80,6 +86,9 @@ var (
 	configSubscriberLock                                                      sync.RWMutex
 	maxReqSize                                                                int
 	enableRateLimit                                                           bool
+	enableGRPC                                                                bool
+	enableIdempotency                                                         bool
+	maxIdempotencyKeyLength                                                   int
 	enableSuppressUserFeature                                                 bool
 	enableEventSchemasFeature                                                 bool
 	diagnosisTickerTime                                                       time.Duration
@@ -160,8 +169,10 @@ type HandleT struct {
 	rrh                                                        *RegularRequestHandler
 	irh                                                        *ImportRequestHandler
 	readonlyGatewayDB, readonlyRouterDB, readonlyBatchRouterDB jobsdb.ReadonlyJobsDB
+	tracer                                                     trace.TracerI
 	netHandle                                                  *http.Client
 	httpTimeout                                                time.Duration
+	idempotencyStore                                           idempotency.StoreI
 }
 
 func (gateway *HandleT) updateSourceStats(sourceStats map[string]int, bucket string, sourceTagMap map[string]string) {
@@ -339,7 +350,7 @@ func (gateway *HandleT) getSourceTagFromWriteKey(writeKey string) string {
 }
 
 //	Listens on the `batchRequestQ` channel of the webRequestWorker for new batches of webRequests
-//	Goes over the webRequests in the batch and filters them out(`rateLimit`, `maxReqSize`).
+//	Goes over the webRequests in the batch and filters them out(`maxReqSize`).
 // 	And creates a `jobList` which is then sent to `userWorkerBatchRequestQ` of the gateway and waits for a response
 // 	from the `dbwriterWorker`s that batch them and write to the db.
 // Finally sends responses(error) if any back to the webRequests over their `done` channels
@@ -356,7 +367,6 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 		var sourceSuccessEventStats = make(map[string]int)
 		var sourceFailStats = make(map[string]int)
 		var sourceFailEventStats = make(map[string]int)
-		var workspaceDropRequestStats = make(map[string]int)
 		var sourceTagMap = make(map[string]string)
 		var preDbStoreCount int
 		//Saving the event data read from req.request.Body to the splice.
@@ -374,17 +384,6 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 
 			body := req.requestPayload
 
-			if enableRateLimit {
-				//In case of "batch" requests, if ratelimiter returns true for LimitReached, just drop the event batch and continue.
-				restrictorKey := gateway.backendConfig.GetWorkspaceIDForWriteKey(writeKey)
-				if gateway.rateLimiter.LimitReached(restrictorKey) {
-					req.done <- response.GetStatus(response.TooManyRequests)
-					preDbStoreCount++
-					misc.IncrementMapByKey(workspaceDropRequestStats, restrictorKey, 1)
-					continue
-				}
-			}
-
 			if !gjson.ValidBytes(body) {
 				req.done <- response.GetStatus(response.InvalidJSON)
 				preDbStoreCount++
@@ -483,6 +482,9 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 				"batch_id":          counter,
 				"source_job_run_id": sourcesJobRunID,
 			}
+			if req.traceParent != "" {
+				params[tracing.TRACEPARENT] = req.traceParent
+			}
 			marshalledParams, err := json.Marshal(params)
 			if err != nil {
 				gateway.logger.Errorf("[Gateway] Failed to marshal parameters map. Parameters: %+v", params)
@@ -540,9 +542,6 @@ func (gateway *HandleT) userWebRequestWorkerProcess(userWebRequestWorker *userWe
 		gateway.updateSourceStats(sourceStats, "gateway.write_key_requests", sourceTagMap)
 		gateway.updateSourceStats(sourceSuccessStats, "gateway.write_key_successful_requests", sourceTagMap)
 		gateway.updateSourceStats(sourceFailStats, "gateway.write_key_failed_requests", sourceTagMap)
-		if enableRateLimit {
-			gateway.updateSourceStats(workspaceDropRequestStats, "gateway.work_space_dropped_requests", sourceTagMap)
-		}
 		// update stats event wise
 		gateway.updateSourceStats(sourceEventStats, "gateway.write_key_events", sourceTagMap)
 		gateway.updateSourceStats(sourceSuccessEventStats, "gateway.write_key_successful_events", sourceTagMap)
@@ -609,7 +608,7 @@ func (gateway *HandleT) eventSchemaWebHandler(wrappedFunc func(http.ResponseWrit
 
 func (gateway *HandleT) getPayloadFromRequest(r *http.Request) ([]byte, error) {
 	if r.Body != nil {
-		payload, err := ioutil.ReadAll(r.Body)
+		payload, err := readRequestBody(r)
 		r.Body.Close()
 		return payload, err
 	}
@@ -829,20 +828,27 @@ func (gateway *HandleT) pendingEventsHandler(w http.ResponseWriter, r *http.Requ
 
 	var gwPendingCount, rtPendingCount, brtPendingCount, totalPendingTillNow int64
 	if !excludeGateway {
-		gwPendingCount = gateway.readonlyGatewayDB.GetPendingJobsCount([]string{CustomVal}, -1, gwParameterFilters)
+		gwPendingCount, err = gateway.readonlyGatewayDB.GetPendingJobsCount([]string{CustomVal}, -1, gwParameterFilters)
 		totalPendingTillNow = gwPendingCount
 	}
 
-	if totalPendingTillNow <= 0 {
-		rtPendingCount = gateway.readonlyRouterDB.GetPendingJobsCount(nil, -1, rtParameterFilters)
+	if err == nil && totalPendingTillNow <= 0 {
+		rtPendingCount, err = gateway.readonlyRouterDB.GetPendingJobsCount(nil, -1, rtParameterFilters)
 		totalPendingTillNow += rtPendingCount
 	}
 
-	if totalPendingTillNow <= 0 {
-		brtPendingCount = gateway.readonlyBatchRouterDB.GetPendingJobsCount(nil, -1, rtParameterFilters)
+	if err == nil && totalPendingTillNow <= 0 {
+		brtPendingCount, err = gateway.readonlyBatchRouterDB.GetPendingJobsCount(nil, -1, rtParameterFilters)
 		totalPendingTillNow += brtPendingCount
 	}
 
+	//pending events can't be counted for jobsdbs kept in badger, so they are not reported as none
+	if err != nil {
+		gateway.logger.Errorf("IP: %s -- %s -- Response: 500, %v", misc.GetIPFromReq(r), r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	whPending := false
 	if totalPendingTillNow <= 0 {
 		whPending = gateway.getWarehousePending(payload)
@@ -856,6 +862,137 @@ func (gateway *HandleT) pendingEventsHandler(w http.ResponseWriter, r *http.Requ
 	w.Write([]byte(fmt.Sprintf("{ \"pending_events\": %d }", pendingEventsResponse)))
 }
 
+/*
+traceHandler returns the lifecycle of the events of the source of the write key matching the trace request in the body,
+from their receipt to their delivery to every destination. It is served by the admin server only, as traces hold the payloads of the events
+*/
+func (gateway *HandleT) traceHandler(w http.ResponseWriter, r *http.Request) {
+	gateway.logger.LogRequest(r)
+	var errorMessage string
+	defer func() {
+		if errorMessage != "" {
+			gateway.logger.Info(fmt.Sprintf("IP: %s -- %s -- Response: 400, %s", misc.GetIPFromReq(r), r.URL.Path, errorMessage))
+			http.Error(w, errorMessage, 400)
+		}
+	}()
+
+	if gateway.tracer == nil {
+		errorMessage = "Tracing is not available"
+		return
+	}
+	payload, writeKey, err := gateway.getPayloadAndWriteKey(w, r, "trace")
+	if err != nil {
+		errorMessage = err.Error()
+		return
+	}
+	sourceID := gateway.getSourceIDForWriteKey(writeKey)
+	if sourceID == "" {
+		errorMessage = response.GetStatus(response.InvalidWriteKey)
+		return
+	}
+
+	var request trace.RequestT
+	if err = json.Unmarshal(payload, &request); err != nil {
+		errorMessage = err.Error()
+		return
+	}
+	request.SourceID = sourceID
+	eventTrace, err := gateway.tracer.Trace(request)
+	if err != nil {
+		errorMessage = err.Error()
+		return
+	}
+	traceResponse, err := json.Marshal(eventTrace)
+	if err != nil {
+		errorMessage = err.Error()
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(traceResponse)
+}
+
+/*
+liveDebuggerHandler streams the events of a writeKey or the statuses of a destinationId as server-sent events,
+sampled with the optional sample query parameter and capped to the optional rate query parameter in events per second.
+Only the events recorded by this server are streamed
+*/
+func (gateway *HandleT) liveDebuggerHandler(w http.ResponseWriter, r *http.Request) {
+	gateway.logger.LogRequest(r)
+	query := r.URL.Query()
+	filter, err := getLiveDebuggerFilter(query.Get("writeKey"), query.Get("destinationId"))
+	if err != nil {
+		gateway.logger.Info(fmt.Sprintf("IP: %s -- %s -- Response: 400, %s", misc.GetIPFromReq(r), r.URL.Path, err.Error()))
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	samplingRate := 1.0
+	if sample := query.Get("sample"); sample != "" {
+		if samplingRate, err = strconv.ParseFloat(sample, 64); err != nil || samplingRate <= 0 || samplingRate > 1 {
+			http.Error(w, "sample should be a number in (0, 1]", 400)
+			return
+		}
+	}
+	eventsPerSecond := 0
+	if eventRate := query.Get("rate"); eventRate != "" {
+		if eventsPerSecond, err = strconv.Atoi(eventRate); err != nil || eventsPerSecond <= 0 {
+			http.Error(w, "rate should be a positive integer", 400)
+			return
+		}
+	}
+
+	subscription, ok := livedebugger.Subscribe(filter, samplingRate, eventsPerSecond)
+	if !ok {
+		http.Error(w, "Too many live debugger connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer subscription.Close()
+	if err = livedebugger.Stream(w, r.Context().Done(), subscription); err != nil {
+		gateway.logger.Debugf("Live debugger stream of %s ended: %v", r.URL.RawQuery, err)
+	}
+}
+
+//getLiveDebuggerFilter selects the events of the enabled source of writeKey and the statuses of its destinations,
+//or the statuses of destinationID and the events of the enabled sources connected to it
+func getLiveDebuggerFilter(writeKey string, destinationID string) (livedebugger.FilterT, error) {
+	filter := livedebugger.FilterT{
+		WriteKeys:      make(map[string]struct{}),
+		SourceIDs:      make(map[string]struct{}),
+		DestinationIDs: make(map[string]struct{}),
+	}
+	if (writeKey == "") == (destinationID == "") {
+		return filter, errors.New("either writeKey or destinationId is required")
+	}
+
+	configSubscriberLock.RLock()
+	defer configSubscriberLock.RUnlock()
+	if writeKey != "" {
+		source, ok := enabledWriteKeysSourceMap[writeKey]
+		if !ok {
+			return filter, errors.New(response.GetStatus(response.InvalidWriteKey))
+		}
+		filter.WriteKeys[writeKey] = struct{}{}
+		filter.SourceIDs[source.ID] = struct{}{}
+		for _, destination := range source.Destinations {
+			filter.DestinationIDs[destination.ID] = struct{}{}
+		}
+		return filter, nil
+	}
+
+	filter.DestinationIDs[destinationID] = struct{}{}
+	for writeKey, source := range enabledWriteKeysSourceMap {
+		for _, destination := range source.Destinations {
+			if destination.ID == destinationID {
+				filter.WriteKeys[writeKey] = struct{}{}
+				break
+			}
+		}
+	}
+	if len(filter.WriteKeys) == 0 {
+		return filter, errors.New("Invalid destinationId")
+	}
+	return filter, nil
+}
+
 func (gateway *HandleT) getWarehousePending(payload []byte) bool {
 	uri := fmt.Sprintf(`%s/v1/warehouse/pending-events?triggerUpload=true`, misc.GetWarehouseURL())
 	resp, err := gateway.netHandle.Post(uri, "application/json; charset=utf-8",
@@ -880,11 +1017,127 @@ func (gateway *HandleT) getWarehousePending(payload []byte) bool {
 	return whPendingResponse.PendingEvents
 }
 
+//rateLimit counts the request against the workspace and source quotas of the writeKey and each of its events against the quota of its user,
+//and sets X-RateLimit-* headers on the response. Returns TooManyRequests if any of the quotas is reached
+func (gateway *HandleT) rateLimit(w *http.ResponseWriter, reqType string, payload []byte, writeKey string) string {
+	if !enableRateLimit {
+		return ""
+	}
+	userEvents := make(map[string]int64)
+	countUserEvent := func(event gjson.Result) {
+		userID := strings.TrimSpace(event.Get("userId").String())
+		if userID == "" {
+			userID = strings.TrimSpace(event.Get("anonymousId").String())
+		}
+		userEvents[userID]++
+	}
+	if reqType == "batch" || reqType == "import" {
+		gjson.GetBytes(payload, "batch").ForEach(func(_, event gjson.Result) bool {
+			countUserEvent(event)
+			return true
+		})
+	} else {
+		countUserEvent(gjson.ParseBytes(payload))
+	}
+	workspaceID := gateway.backendConfig.GetWorkspaceIDForWriteKey(writeKey)
+	limitStatus := gateway.rateLimiter.LimitReached(ratelimiter.RequestT{
+		WorkspaceID: workspaceID,
+		SourceID:    gateway.getSourceIDForWriteKey(writeKey),
+		UserEvents:  userEvents,
+	})
+
+	if w != nil && limitStatus.Level != "" {
+		header := (*w).Header()
+		header.Set("X-RateLimit-Limit", strconv.FormatInt(limitStatus.Limit, 10))
+		header.Set("X-RateLimit-Remaining", strconv.FormatInt(limitStatus.Remaining, 10))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(limitStatus.Reset.Seconds())), 10))
+		if limitStatus.Reached {
+			header.Set("Retry-After", strconv.FormatInt(int64(math.Ceil(limitStatus.RetryAfter.Seconds())), 10))
+		}
+	}
+	if !limitStatus.Reached {
+		return ""
+	}
+
+	gateway.stats.NewTaggedStat("gateway.work_space_dropped_requests", stats.CountType, stats.Tags{
+		"workspaceId": workspaceID,
+		"source":      gateway.getSourceTagFromWriteKey(writeKey),
+		"level":       limitStatus.Level,
+		"reqType":     reqType,
+	}).Increment()
+	return response.TooManyRequests
+}
+
+//reserveIdempotencyKey reserves the Idempotency-Key of the request for the writeKey.
+//If a request with the key has already completed, its response is written and replayed is true.
+//The returned key is empty if the request has to be processed without one
+func (gateway *HandleT) reserveIdempotencyKey(w http.ResponseWriter, r *http.Request, writeKey string) (idempotencyKey string, reservationID string, replayed bool, errorMessage string) {
+	if !enableIdempotency || gateway.idempotencyStore == nil {
+		return "", "", false, ""
+	}
+	idempotencyKey = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if idempotencyKey == "" {
+		return "", "", false, ""
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return "", "", false, response.InvalidIdempotencyKey
+	}
+
+	status, reservationID, storedResponse, err := gateway.idempotencyStore.Reserve(writeKey, idempotencyKey)
+	if err != nil {
+		gateway.logger.Errorf("Failed to reserve Idempotency-Key %s of writeKey %s, processing request without it: %v", idempotencyKey, writeKey, err)
+		return "", "", false, ""
+	}
+	switch status {
+	case idempotency.InProgress:
+		return "", "", false, response.IdempotencyKeyInUse
+	case idempotency.Completed:
+		gateway.stats.NewTaggedStat("gateway.idempotent_replays", stats.CountType, stats.Tags{
+			"source": gateway.getSourceTagFromWriteKey(writeKey),
+		}).Increment()
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.Write([]byte(storedResponse))
+		return "", "", true, ""
+	}
+	return idempotencyKey, reservationID, false, ""
+}
+
+//completeIdempotencyKey stores the response of a successful request against its Idempotency-Key.
+//Keys of failed requests are released so that clients can retry with them.
+//Requests slower than the idempotency lock timeout may have lost the key to a retry, which can then have stored the same events
+func (gateway *HandleT) completeIdempotencyKey(writeKey, idempotencyKey, reservationID, errorMessage string) {
+	var err error
+	if errorMessage == "" {
+		err = gateway.idempotencyStore.Commit(writeKey, idempotencyKey, reservationID, response.GetStatus(response.Ok))
+	} else {
+		err = gateway.idempotencyStore.Release(writeKey, idempotencyKey, reservationID)
+	}
+	if err == idempotency.ErrReservationLost {
+		gateway.logger.Warnf("Idempotency-Key %s of writeKey %s was reserved again before the request completed, its events may be stored twice", idempotencyKey, writeKey)
+		gateway.stats.NewTaggedStat("gateway.idempotency_reservations_lost", stats.CountType, stats.Tags{
+			"source": gateway.getSourceTagFromWriteKey(writeKey),
+		}).Increment()
+		return
+	}
+	if err != nil {
+		gateway.logger.Errorf("Failed to update Idempotency-Key %s of writeKey %s: %v", idempotencyKey, writeKey, err)
+	}
+}
+
 //ProcessRequest throws a webRequest into the queue and waits for the response before returning
 func (rrh *RegularRequestHandler) ProcessRequest(gateway *HandleT, w *http.ResponseWriter, r *http.Request, reqType string, payload []byte, writeKey string) string {
+	ctx, span := tracing.StartSpanFromRequest(r, "gateway.request", tracing.StringAttribute("request.type", reqType))
+	defer span.End()
+	if errorMessage := gateway.rateLimit(w, reqType, payload, writeKey); errorMessage != "" {
+		tracing.SetError(span, errors.New(errorMessage))
+		return errorMessage
+	}
 	done := make(chan string, 1)
-	gateway.addToWebRequestQ(w, r, done, reqType, payload, writeKey)
+	gateway.addToWebRequestQ(w, r.WithContext(ctx), done, reqType, payload, writeKey)
 	errorMessage := <-done
+	if errorMessage != "" {
+		tracing.SetError(span, errors.New(errorMessage))
+	}
 	return errorMessage
 }
 
@@ -940,8 +1193,13 @@ func (gateway *HandleT) webRequestHandler(rh RequestHandler, w http.ResponseWrit
 	var errorMessage string
 	defer func() {
 		if errorMessage != "" {
-			gateway.logger.Info(fmt.Sprintf("IP: %s -- %s -- Response: 400, %s", misc.GetIPFromReq(r), r.URL.Path, response.GetStatus(errorMessage)))
-			http.Error(w, response.GetStatus(errorMessage), 400)
+			statusCode := http.StatusBadRequest
+			switch errorMessage {
+			case response.TooManyRequests, response.UnsupportedContentEncoding, response.DecompressedRequestBodyTooLarge, response.IdempotencyKeyInUse:
+				statusCode = response.GetStatusCode(errorMessage)
+			}
+			gateway.logger.Info(fmt.Sprintf("IP: %s -- %s -- Response: %d, %s", misc.GetIPFromReq(r), r.URL.Path, statusCode, response.GetStatus(errorMessage)))
+			http.Error(w, response.GetStatus(errorMessage), statusCode)
 		}
 	}()
 	payload, writeKey, err := gateway.getPayloadAndWriteKey(w, r, reqType)
@@ -949,7 +1207,14 @@ func (gateway *HandleT) webRequestHandler(rh RequestHandler, w http.ResponseWrit
 		errorMessage = err.Error()
 		return
 	}
+	idempotencyKey, reservationID, replayed, errorMessage := gateway.reserveIdempotencyKey(w, r, writeKey)
+	if replayed || errorMessage != "" {
+		return
+	}
 	errorMessage = rh.ProcessRequest(gateway, &w, r, reqType, payload, writeKey)
+	if idempotencyKey != "" {
+		gateway.completeIdempotencyKey(writeKey, idempotencyKey, reservationID, errorMessage)
+	}
 	atomic.AddUint64(&gateway.ackCount, 1)
 	gateway.trackRequestMetrics(errorMessage)
 	if errorMessage != "" {
@@ -982,7 +1247,10 @@ func (gateway *HandleT) pixelWebRequestHandler(rh RequestHandler, w http.Respons
 
 //ProcessRequest on ImportRequestHandler splits payload by user and throws them into the webrequestQ and waits for all their responses before returning
 func (irh *ImportRequestHandler) ProcessRequest(gateway *HandleT, w *http.ResponseWriter, r *http.Request, reqType string, payload []byte, writeKey string) string {
-	errorMessage := ""
+	errorMessage := gateway.rateLimit(w, reqType, payload, writeKey)
+	if errorMessage != "" {
+		return errorMessage
+	}
 	usersPayload, payloadError := gateway.getUsersPayload(payload)
 	if payloadError != nil {
 		return payloadError.Error()
@@ -1241,6 +1509,9 @@ func (gateway *HandleT) StartAdminHandler() {
 	srvMux.HandleFunc("/v1/clear", gateway.stat(gateway.ClearHandler)).Methods("POST")
 	srvMux.HandleFunc("/v1/clear", gateway.stat(gateway.OperationStatusHandler)).Methods("GET")
 	srvMux.HandleFunc("/v1/pending-events", gateway.stat(gateway.pendingEventsHandler)).Methods("POST")
+	srvMux.HandleFunc("/v1/trace", gateway.stat(gateway.traceHandler)).Methods("POST")
+	srvMux.HandleFunc("/metrics", stats.MetricsHandler).Methods("GET")
+	srvMux.HandleFunc("/v1/debugger/live", gateway.liveDebuggerHandler).Methods("GET")
 
 	srv := &http.Server{
 		Addr:    ":" + strconv.Itoa(adminWebPort),
@@ -1301,9 +1572,14 @@ func (gateway *HandleT) addToWebRequestQ(writer *http.ResponseWriter, req *http.
 		//If the request comes through proxy, proxy would already send this. So this shouldn't be happening in that case
 		userIDHeader = uuid.NewV4().String()
 	}
-	userWebRequestWorker := gateway.findUserWebRequestWorker(userIDHeader)
-	ipAddr := misc.GetIPFromReq(req)
-	webReq := webRequestT{done: done, reqType: reqType, requestPayload: requestPayload, writeKey: writeKey, ipAddr: ipAddr}
+	traceParent := tracing.Traceparent(tracing.FromContext(req.Context()))
+	gateway.enqueueWebRequest(userIDHeader, misc.GetIPFromReq(req), done, reqType, requestPayload, writeKey, traceParent)
+}
+
+//enqueueWebRequest queues the webrequest with the worker of userID. traceParent, if set, is carried in the parameters of the job of the request
+func (gateway *HandleT) enqueueWebRequest(userID string, ipAddr string, done chan string, reqType string, requestPayload []byte, writeKey string, traceParent string) {
+	userWebRequestWorker := gateway.findUserWebRequestWorker(userID)
+	webReq := webRequestT{done: done, reqType: reqType, requestPayload: requestPayload, writeKey: writeKey, ipAddr: ipAddr, traceParent: traceParent}
 	userWebRequestWorker.webRequestQ <- &webReq
 }
 
@@ -1341,6 +1617,11 @@ func (gateway *HandleT) SetReadonlyDBs(readonlyGatewayDB, readonlyRouterDB, read
 	gateway.readonlyBatchRouterDB = readonlyBatchRouterDB
 }
 
+//SetTracer sets the tracer serving /v1/trace of the admin server
+func (gateway *HandleT) SetTracer(tracer trace.TracerI) {
+	gateway.tracer = tracer
+}
+
 /*
 Setup initializes this module:
 - Monitors backend config for changes.
@@ -1394,6 +1675,14 @@ func (gateway *HandleT) Setup(application app.Interface, backendConfig backendco
 		gateway.eventSchemaHandler = event_schema.GetInstance()
 	}
 
+	if enableIdempotency {
+		idempotencyStore, err := idempotency.NewPostgresStore()
+		if err != nil {
+			panic(err)
+		}
+		gateway.idempotencyStore = idempotencyStore
+	}
+
 	rruntime.Go(func() {
 		gateway.backendConfigSubscriber()
 	})
@@ -2,23 +2,34 @@ package gateway
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	gocontext "context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/andybalholm/brotli"
 	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/zstd"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	uuid "github.com/satori/go.uuid"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/rudderlabs/rudder-server/app"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
@@ -28,7 +39,11 @@ import (
 	mocksBackendConfig "github.com/rudderlabs/rudder-server/mocks/config/backend-config"
 	mocksJobsDB "github.com/rudderlabs/rudder-server/mocks/jobsdb"
 	mocksRateLimiter "github.com/rudderlabs/rudder-server/mocks/rate-limiter"
+	mocksIdempotency "github.com/rudderlabs/rudder-server/mocks/services/idempotency"
 	mocksTypes "github.com/rudderlabs/rudder-server/mocks/utils/types"
+	proto "github.com/rudderlabs/rudder-server/proto/ingest"
+	ratelimiter "github.com/rudderlabs/rudder-server/rate-limiter"
+	"github.com/rudderlabs/rudder-server/services/idempotency"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -219,6 +234,44 @@ var _ = Describe("Gateway Enterprise", func() {
 
 })
 
+//zstdReadonlyJobsDB reads zstd compressed payloads from every table
+type zstdReadonlyJobsDB struct {
+	jobsdb.ReadonlyJobsDB
+}
+
+func (*zstdReadonlyJobsDB) IsCompressedTable(string) (bool, error) {
+	return true, nil
+}
+
+func (*zstdReadonlyJobsDB) DecompressPayload(payload []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(payload, nil)
+}
+
+var _ = Describe("Gateway admin", func() {
+	It("should average the batch sizes of a table with compressed payloads", func() {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+
+		encoder, err := zstd.NewWriter(nil)
+		Expect(err).NotTo(HaveOccurred())
+		rows := sqlmock.NewRows([]string{"event_payload"})
+		for _, payload := range []string{`{"batch":[{"type":"track"}]}`, `{"batch":[{"type":"track"},{"type":"identify"},{"type":"page"}]}`} {
+			rows.AddRow(encoder.EncodeAll([]byte(payload), nil))
+		}
+		mock.ExpectQuery(`select event_payload from gw_jobs_1`).WillReturnRows(rows)
+
+		runner := &SqlRunner{dbHandle: db, jobTableName: "gw_jobs_1", readOnlyJobsDB: &zstdReadonlyJobsDB{}}
+		Expect(runner.getAvgBatchSize()).To(Equal(float64(2)))
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+})
+
 var _ = Describe("Gateway", func() {
 	var c *context
 
@@ -436,19 +489,289 @@ var _ = Describe("Gateway", func() {
 			workspaceID := "some-workspace-id"
 
 			c.mockBackendConfig.EXPECT().GetWorkspaceIDForWriteKey(WriteKeyEnabled).Return(workspaceID).AnyTimes().Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
-			c.mockRateLimiter.EXPECT().LimitReached(workspaceID).Return(false).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+			c.mockRateLimiter.EXPECT().LimitReached(ratelimiter.RequestT{WorkspaceID: workspaceID, SourceID: SourceIDEnabled, UserEvents: map[string]int64{"dummyId": 1}}).Return(ratelimiter.LimitStatusT{}).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
 			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(jobsToEmptyErrors).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
 
 			expectHandlerResponse(gateway.webAliasHandler, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString(`{"userId":"dummyId"}`)), 200, "OK")
 		})
 
+		It("should count each event of a batch against the quota of its user", func() {
+			workspaceID := "some-workspace-id"
+
+			c.mockBackendConfig.EXPECT().GetWorkspaceIDForWriteKey(WriteKeyEnabled).Return(workspaceID).AnyTimes().Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+			c.mockRateLimiter.EXPECT().LimitReached(ratelimiter.RequestT{WorkspaceID: workspaceID, SourceID: SourceIDEnabled, UserEvents: map[string]int64{"user-1": 2, "anon-1": 1}}).Return(ratelimiter.LimitStatusT{}).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(jobsToEmptyErrors).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+
+			expectHandlerResponse(gateway.webBatchHandler, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString(`{"batch":[{"userId":"user-1"},{"anonymousId":"anon-1"},{"userId":"user-1"}]}`)), 200, "OK")
+		})
+
 		It("should reject messages if rate limit is reached for workspace", func() {
 			workspaceID := "some-workspace-id"
 
 			c.mockBackendConfig.EXPECT().GetWorkspaceIDForWriteKey(WriteKeyEnabled).Return(workspaceID).AnyTimes().Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
-			c.mockRateLimiter.EXPECT().LimitReached(workspaceID).Return(true).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+			c.mockRateLimiter.EXPECT().LimitReached(gomock.Any()).Return(ratelimiter.LimitStatusT{
+				Reached:    true,
+				Level:      ratelimiter.WorkspaceLevel,
+				Limit:      10,
+				Reset:      30 * time.Second,
+				RetryAfter: 1500 * time.Millisecond,
+			}).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+
+			expectHandlerResponse(gateway.webAliasHandler, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString("{}")), 429, response.TooManyRequests+"\n")
+		})
+
+		It("should set rate limit headers on responses", func() {
+			workspaceID := "some-workspace-id"
+
+			c.mockBackendConfig.EXPECT().GetWorkspaceIDForWriteKey(WriteKeyEnabled).Return(workspaceID).AnyTimes().Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+			c.mockRateLimiter.EXPECT().LimitReached(gomock.Any()).Return(ratelimiter.LimitStatusT{
+				Reached:    true,
+				Level:      ratelimiter.SourceLevel,
+				Limit:      10,
+				Reset:      30 * time.Second,
+				RetryAfter: 1500 * time.Millisecond,
+			}).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName(""))
+
+			rr := httptest.NewRecorder()
+			gateway.webAliasHandler(rr, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString(`{"userId":"dummyId"}`)))
+
+			Expect(rr.Result().StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(rr.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(rr.Header().Get("X-RateLimit-Limit")).To(Equal("10"))
+			Expect(rr.Header().Get("X-RateLimit-Remaining")).To(Equal("0"))
+			Expect(rr.Header().Get("X-RateLimit-Reset")).To(Equal("30"))
+		})
+	})
+
+	Context("Idempotency", func() {
+		var (
+			gateway              = &HandleT{}
+			mockIdempotencyStore *mocksIdempotency.MockStoreI
+			idempotencyKey       = "some-idempotency-key"
+			reservationID        = "some-reservation-id"
+		)
+
+		BeforeEach(func() {
+			gateway.Setup(c.mockApp, c.mockBackendConfig, c.mockJobsDB, nil, c.mockVersionHandler)
+			mockIdempotencyStore = mocksIdempotency.NewMockStoreI(c.mockCtrl)
+			gateway.idempotencyStore = mockIdempotencyStore
+			SetEnableIdempotency(true)
+		})
+
+		AfterEach(func() {
+			SetEnableIdempotency(false)
+		})
+
+		idempotentRequest := func(body string) *http.Request {
+			req := authorizedRequest(WriteKeyEnabled, bytes.NewBufferString(body))
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+			return req
+		}
+
+		It("should store the request and commit its response against the key", func() {
+			mockIdempotencyStore.EXPECT().Reserve(WriteKeyEnabled, idempotencyKey).Return(idempotency.Reserved, reservationID, "", nil).Times(1)
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(jobsToEmptyErrors).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName("store-job"))
+			mockIdempotencyStore.EXPECT().Commit(WriteKeyEnabled, idempotencyKey, reservationID, "OK").Return(nil).Times(1)
+
+			expectHandlerResponse(gateway.webBatchHandler, idempotentRequest(`{"batch":[{"userId":"dummyId"}]}`), 200, "OK")
+		})
+
+		It("should respond as usual to stored requests which lost their reservation", func() {
+			mockIdempotencyStore.EXPECT().Reserve(WriteKeyEnabled, idempotencyKey).Return(idempotency.Reserved, reservationID, "", nil).Times(1)
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(jobsToEmptyErrors).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName("store-job"))
+			mockIdempotencyStore.EXPECT().Commit(WriteKeyEnabled, idempotencyKey, reservationID, "OK").Return(idempotency.ErrReservationLost).Times(1)
+
+			expectHandlerResponse(gateway.webBatchHandler, idempotentRequest(`{"batch":[{"userId":"dummyId"}]}`), 200, "OK")
+		})
+
+		It("should return the original response without storing replayed requests", func() {
+			mockIdempotencyStore.EXPECT().Reserve(WriteKeyEnabled, idempotencyKey).Return(idempotency.Completed, "", "OK", nil).Times(1)
+
+			rr := httptest.NewRecorder()
+			gateway.webBatchHandler(rr, idempotentRequest(`{"batch":[{"userId":"dummyId"}]}`))
+			Expect(rr.Result().StatusCode).To(Equal(http.StatusOK))
+			Expect(rr.Body.String()).To(Equal("OK"))
+			Expect(rr.Header().Get("Idempotent-Replayed")).To(Equal("true"))
+		})
+
+		It("should reject requests while a request with the same key is in progress", func() {
+			mockIdempotencyStore.EXPECT().Reserve(WriteKeyEnabled, idempotencyKey).Return(idempotency.InProgress, "", "", nil).Times(1)
+
+			expectHandlerResponse(gateway.webBatchHandler, idempotentRequest(`{"batch":[{"userId":"dummyId"}]}`), 409, response.IdempotencyKeyInUse+"\n")
+		})
+
+		It("should release the key of failed requests", func() {
+			mockIdempotencyStore.EXPECT().Reserve(WriteKeyEnabled, idempotencyKey).Return(idempotency.Reserved, reservationID, "", nil).Times(1)
+			mockIdempotencyStore.EXPECT().Release(WriteKeyEnabled, idempotencyKey, reservationID).Return(nil).Times(1)
+
+			expectHandlerResponse(gateway.webBatchHandler, idempotentRequest(`{"batch":[{}]}`), 400, response.NonIdentifiableRequest+"\n")
+		})
+
+		It("should process requests without a key as usual", func() {
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(jobsToEmptyErrors).Times(1).Do(c.asyncHelper.ExpectAndNotifyCallbackWithName("store-job"))
+
+			expectHandlerResponse(gateway.webBatchHandler, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString(`{"batch":[{"userId":"dummyId"}]}`)), 200, "OK")
+		})
+	})
+
+	Context("Compressed requests", func() {
+		var (
+			gateway = &HandleT{}
+		)
+
+		BeforeEach(func() {
+			gateway.Setup(c.mockApp, c.mockBackendConfig, c.mockJobsDB, nil, c.mockVersionHandler)
+		})
+
+		compressors := map[string]func(io.Writer) io.WriteCloser{
+			"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+			"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+			"zstd": func(w io.Writer) io.WriteCloser {
+				encoder, _ := zstd.NewWriter(w)
+				return encoder
+			},
+			"br": func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		}
+
+		compress := func(encoding string, data []byte) *bytes.Buffer {
+			buf := &bytes.Buffer{}
+			writer := compressors[encoding](buf)
+			writer.Write(data)
+			writer.Close()
+			return buf
+		}
+
+		compressedRequest := func(encoding string, body io.Reader) *http.Request {
+			req := authorizedRequest(WriteKeyEnabled, body)
+			req.Header.Set("Content-Encoding", encoding)
+			return req
+		}
+
+		for encoding := range compressors {
+			encoding := encoding
+			It(fmt.Sprintf("should accept %s encoded requests and store the decoded payload", encoding), func() {
+				validBody := `{"batch":[{"userId":"dummyId","event":"compressed"}]}`
+
+				c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(func(jobs []*jobsdb.JobT) map[uuid.UUID]string {
+					Expect(jobs).To(HaveLen(1))
+					Expect(gjson.GetBytes(jobs[0].EventPayload, "batch.0.event").String()).To(Equal("compressed"))
+					c.asyncHelper.ExpectAndNotifyCallbackWithName("jobsdb_store")()
+					return jobsToEmptyErrors(jobs)
+				}).Times(1)
+
+				expectHandlerResponse(gateway.webBatchHandler, compressedRequest(encoding, compress(encoding, []byte(validBody))), 200, "OK")
+			})
+		}
+
+		It("should reject requests with unsupported encodings", func() {
+			expectHandlerResponse(gateway.webBatchHandler, compressedRequest("compress", bytes.NewBufferString("{}")), 415, response.UnsupportedContentEncoding+"\n")
+		})
+
+		It("should reject requests which can't be decompressed", func() {
+			expectHandlerResponse(gateway.webBatchHandler, compressedRequest("gzip", bytes.NewBufferString(`{"batch":[]}`)), 400, response.RequestBodyDecompressionFailed+"\n")
+		})
+
+		It("should reject requests larger than configured limit after decompression", func() {
+			data := bytes.Repeat([]byte{'a'}, gateway.MaxReqSize()+1)
+			expectHandlerResponse(gateway.webBatchHandler, compressedRequest("gzip", compress("gzip", data)), 413, response.DecompressedRequestBodyTooLarge+"\n")
+		})
+	})
+
+	Context("gRPC ingestion", func() {
+		var (
+			gateway = &HandleT{}
+			server  *grpc.Server
+			conn    *grpc.ClientConn
+		)
+
+		BeforeEach(func() {
+			gateway.Setup(c.mockApp, c.mockBackendConfig, c.mockJobsDB, nil, c.mockVersionHandler)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			server = grpc.NewServer()
+			proto.RegisterIngestServiceServer(server, &ingestgrpc{gateway: gateway})
+			go server.Serve(listener)
+
+			conn, err = grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			conn.Close()
+			server.Stop()
+		})
+
+		sendEvents := func(writeKey string, batches ...*proto.EventBatch) (map[string]*proto.BatchAck, error) {
+			ctx := metadata.AppendToOutgoingContext(gocontext.Background(), WriteKeyMetadataKey, writeKey)
+			stream, err := proto.NewIngestServiceClient(conn).SendEvents(ctx)
+			Expect(err).To(BeNil())
+			for _, batch := range batches {
+				Expect(stream.Send(batch)).To(BeNil())
+			}
+			Expect(stream.CloseSend()).To(BeNil())
+			acks := make(map[string]*proto.BatchAck)
+			for {
+				ack, err := stream.Recv()
+				if err == io.EOF {
+					return acks, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				acks[ack.BatchId] = ack
+			}
+		}
+
+		It("should store batches to jobsdb and ack each of them", func() {
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(func(jobs []*jobsdb.JobT) map[uuid.UUID]string {
+				Expect(jobs).To(HaveLen(1))
+				payload := gjson.GetBytes(jobs[0].EventPayload, "batch.0")
+				Expect(payload.Get("type").String()).To(Equal("track"))
+				Expect(payload.Get("userId").String()).To(Equal("dummyId"))
+				Expect(gjson.GetBytes(jobs[0].EventPayload, "writeKey").String()).To(Equal(WriteKeyEnabled))
+				c.asyncHelper.ExpectAndNotifyCallbackWithName("jobsdb_store")()
+				return jobsToEmptyErrors(jobs)
+			}).Times(1)
+
+			acks, err := sendEvents(WriteKeyEnabled,
+				&proto.EventBatch{BatchId: "1", Type: "track", Payload: []byte(`{"userId":"dummyId"}`)},
+				&proto.EventBatch{BatchId: "2", Type: "unknown", Payload: []byte(`{"userId":"dummyId"}`)},
+			)
+			Expect(err).To(BeNil())
+			Expect(acks).To(HaveLen(2))
+			Expect(acks["1"].Success).To(BeTrue())
+			Expect(acks["2"].Success).To(BeFalse())
+			Expect(acks["2"].Error).To(Equal(response.InvalidRequestType))
+		})
+
+		It("should ack a batch as soon as it is stored, before the stream is closed", func() {
+			c.mockJobsDB.EXPECT().StoreWithRetryEach(gomock.Any()).DoAndReturn(func(jobs []*jobsdb.JobT) map[uuid.UUID]string {
+				c.asyncHelper.ExpectAndNotifyCallbackWithName("jobsdb_store")()
+				return jobsToEmptyErrors(jobs)
+			}).Times(1)
+
+			ctx := metadata.AppendToOutgoingContext(gocontext.Background(), WriteKeyMetadataKey, WriteKeyEnabled)
+			stream, err := proto.NewIngestServiceClient(conn).SendEvents(ctx)
+			Expect(err).To(BeNil())
+			Expect(stream.Send(&proto.EventBatch{BatchId: "1", Payload: []byte(`{"batch":[{"userId":"dummyId"}]}`)})).To(BeNil())
+
+			ack, err := stream.Recv()
+			Expect(err).To(BeNil())
+			Expect(ack.BatchId).To(Equal("1"))
+			Expect(ack.Success).To(BeTrue())
+
+			Expect(stream.CloseSend()).To(BeNil())
+			_, err = stream.Recv()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		It("should reject streams with invalid write keys", func() {
+			_, err := sendEvents(WriteKeyInvalid, &proto.EventBatch{Payload: []byte(`{"batch":[{"userId":"dummyId"}]}`)})
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
 
-			expectHandlerResponse(gateway.webAliasHandler, authorizedRequest(WriteKeyEnabled, bytes.NewBufferString("{}")), 400, response.TooManyRequests+"\n")
+			_, err = sendEvents(WriteKeyEmpty, &proto.EventBatch{Payload: []byte(`{"batch":[{"userId":"dummyId"}]}`)})
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
 		})
 	})
 
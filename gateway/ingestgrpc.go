@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	uuid "github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/rudderlabs/rudder-server/gateway/response"
+	proto "github.com/rudderlabs/rudder-server/proto/ingest"
+	"github.com/rudderlabs/rudder-server/rruntime"
+)
+
+//WriteKeyMetadataKey is the grpc metadata key clients of IngestService send the writeKey in
+const WriteKeyMetadataKey = "writekey"
+
+var ingestRequestTypes = map[string]struct{}{
+	"batch":    {},
+	"identify": {},
+	"track":    {},
+	"page":     {},
+	"screen":   {},
+	"alias":    {},
+	"merge":    {},
+	"group":    {},
+}
+
+type ingestgrpc struct {
+	proto.UnimplementedIngestServiceServer
+	gateway *HandleT
+}
+
+//SendEvents queues every batch of the stream with the user web request workers as soon as it is received,
+//and sends the ack of each batch as soon as the batch is written to gateway db
+func (ig *ingestgrpc) SendEvents(stream proto.IngestService_SendEventsServer) error {
+	gateway := ig.gateway
+	writeKey := writeKeyFromMetadata(stream)
+	if writeKey == "" {
+		return status.Error(codes.Unauthenticated, response.GetStatus(response.NoWriteKeyInBasicAuth))
+	}
+	if !gateway.isWriteKeyEnabled(writeKey) {
+		return status.Error(codes.Unauthenticated, response.GetStatus(response.InvalidWriteKey))
+	}
+	var ipAddr string
+	if p, ok := peer.FromContext(stream.Context()); ok {
+		ipAddr = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ipAddr); err == nil {
+			ipAddr = host
+		}
+	}
+
+	// grpc streams do not support concurrent sends, acks are sent from a single goroutine
+	acks := make(chan *proto.BatchAck)
+	sendDone := make(chan error, 1)
+	rruntime.Go(func() {
+		var sendErr error
+		for ack := range acks {
+			if sendErr == nil {
+				sendErr = stream.Send(ack)
+			}
+		}
+		sendDone <- sendErr
+	})
+	var pendingAcks sync.WaitGroup
+	ackWhenDone := func(batchID string, done chan string) {
+		pendingAcks.Add(1)
+		rruntime.Go(func() {
+			defer pendingAcks.Done()
+			errorMessage := <-done
+			atomic.AddUint64(&gateway.ackCount, 1)
+			gateway.trackRequestMetrics(errorMessage)
+			acks <- &proto.BatchAck{
+				BatchId: batchID,
+				Success: errorMessage == "",
+				Error:   response.GetStatus(errorMessage),
+			}
+		})
+	}
+
+	var recvErr error
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			recvErr = err
+			break
+		}
+		atomic.AddUint64(&gateway.recvCount, 1)
+		reqType := batch.Type
+		if reqType == "" {
+			reqType = "batch"
+		}
+		done := make(chan string, 1)
+		ackWhenDone(batch.BatchId, done)
+		if _, ok := ingestRequestTypes[reqType]; !ok {
+			done <- response.InvalidRequestType
+			continue
+		}
+		if errorMessage := gateway.rateLimit(nil, reqType, batch.Payload, writeKey); errorMessage != "" {
+			done <- errorMessage
+			continue
+		}
+		anonymousID := batch.AnonymousId
+		if anonymousID == "" {
+			// same as requests without AnonymousId header in addToWebRequestQ
+			anonymousID = uuid.NewV4().String()
+		}
+		gateway.enqueueWebRequest(anonymousID, ipAddr, done, reqType, batch.Payload, writeKey, "")
+	}
+
+	// batches already queued are still written, even if the stream broke
+	pendingAcks.Wait()
+	close(acks)
+	sendErr := <-sendDone
+	if recvErr != nil {
+		return recvErr
+	}
+	return sendErr
+}
+
+func writeKeyFromMetadata(stream grpc.ServerStream) string {
+	md, ok := metadata.FromIncomingContext(stream.Context())
+	if !ok {
+		return ""
+	}
+	values := md.Get(WriteKeyMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+/*
+StartGRPCHandler starts the IngestService grpc server, listening on gateway grpc port.
+Returns right away if the grpc endpoint is not enabled, blocks otherwise.
+*/
+func (gateway *HandleT) StartGRPCHandler() {
+	if !enableGRPC {
+		return
+	}
+	gateway.logger.Infof("Starting IngestService in %d", grpcPort)
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(grpcPort))
+	if err != nil {
+		gateway.logger.Fatal(err)
+		return
+	}
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxReqSize))
+	proto.RegisterIngestServiceServer(srv, &ingestgrpc{gateway: gateway})
+	gateway.logger.Fatal(srv.Serve(listener))
+}
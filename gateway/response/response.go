@@ -48,6 +48,18 @@ const (
 	ErrorInParseForm = "Error during parsing form"
 	//ErrorInParseMultiform - Error during parsing multiform
 	ErrorInParseMultiform = "Error during parsing multiform"
+	//InvalidRequestType - Request type is not supported
+	InvalidRequestType = "Invalid request type"
+	//UnsupportedContentEncoding - Content-Encoding of request body is not supported
+	UnsupportedContentEncoding = "Unsupported Content-Encoding"
+	//RequestBodyDecompressionFailed - Failed to decompress request body
+	RequestBodyDecompressionFailed = "Failed to decompress request body"
+	//DecompressedRequestBodyTooLarge - Decompressed request size exceeds max limit
+	DecompressedRequestBodyTooLarge = "Decompressed request size exceeds max limit"
+	//InvalidIdempotencyKey - Idempotency-Key header is too long
+	InvalidIdempotencyKey = "Idempotency-Key exceeds max length"
+	//IdempotencyKeyInUse - Another request with the same Idempotency-Key is being processed
+	IdempotencyKeyInUse = "Request with the same Idempotency-Key is in progress"
 )
 
 var (
@@ -88,6 +100,12 @@ func loadStatusMap() {
 	statusMap[ErrorInMarshal] = ResponseStatus{message: ErrorInMarshal, code: http.StatusBadRequest}
 	statusMap[ErrorInParseForm] = ResponseStatus{message: ErrorInParseForm, code: http.StatusBadRequest}
 	statusMap[ErrorInParseMultiform] = ResponseStatus{message: ErrorInParseMultiform, code: http.StatusBadRequest}
+	statusMap[InvalidRequestType] = ResponseStatus{message: InvalidRequestType, code: http.StatusBadRequest}
+	statusMap[UnsupportedContentEncoding] = ResponseStatus{message: UnsupportedContentEncoding, code: http.StatusUnsupportedMediaType}
+	statusMap[RequestBodyDecompressionFailed] = ResponseStatus{message: RequestBodyDecompressionFailed, code: http.StatusBadRequest}
+	statusMap[DecompressedRequestBodyTooLarge] = ResponseStatus{message: DecompressedRequestBodyTooLarge, code: http.StatusRequestEntityTooLarge}
+	statusMap[InvalidIdempotencyKey] = ResponseStatus{message: InvalidIdempotencyKey, code: http.StatusBadRequest}
+	statusMap[IdempotencyKeyInUse] = ResponseStatus{message: IdempotencyKeyInUse, code: http.StatusConflict}
 }
 
 func GetStatus(key string) string {
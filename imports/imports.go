@@ -3,4 +3,5 @@ package imports
 import (
 	// placeholder import to ensure that this package is valid in open source version
 	_ "github.com/rudderlabs/rudder-server/app"
+	_ "github.com/rudderlabs/rudder-server/services/replay"
 )
@@ -0,0 +1,430 @@
+package jobsdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/admin"
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+//postgresJSONTimeFormat is the format of timestamp columns in the json dumps of backupTable
+const postgresJSONTimeFormat = "2006-01-02T15:04:05.999999999"
+
+var (
+	restorableJobsDBs     = make(map[string]*HandleT)
+	restorableJobsDBsLock sync.RWMutex
+	restoreBatchSize      int
+)
+
+func init() {
+	config.RegisterIntConfigVariable(1000, &restoreBatchSize, true, 1, "JobsDB.restore.batchSize")
+	admin.RegisterAdminHandler("JobsDBBackups", &BackupsRpcHandler{})
+}
+
+//BackupT is a dump of a dataset uploaded by backupDS. Dumps of failed only backups have no job id or time range
+type BackupT struct {
+	Key          string    `json:"key"`
+	TablePrefix  string    `json:"tablePrefix"`
+	Index        string    `json:"index"`
+	FailedOnly   bool      `json:"failedOnly"`
+	MinJobID     int64     `json:"minJobId"`
+	MaxJobID     int64     `json:"maxJobId"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+//RestoreRequestT selects the backups to restore and the jobs of these backups to restore.
+//Empty fields don't filter, From and To bound the time jobs were created at and jobs with the ExcludeParameter parameter aren't selected
+type RestoreRequestT struct {
+	TablePrefix      string    `json:"tablePrefix"`
+	Keys             []string  `json:"keys"`
+	SourceID         string    `json:"sourceId"`
+	DestinationID    string    `json:"destinationId"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	ExcludeParameter string    `json:"excludeParameter"`
+}
+
+//RestoreResultT reports the dataset jobs were restored into
+type RestoreResultT struct {
+	JobTable string `json:"jobTable"`
+	Restored int    `json:"restored"`
+	Skipped  int    `json:"skipped"`
+}
+
+//backupJobT is a row of a jobs table dump
+type backupJobT struct {
+	JobID        int64           `json:"job_id"`
+	UUID         uuid.UUID       `json:"uuid"`
+	UserID       string          `json:"user_id"`
+	CustomVal    string          `json:"custom_val"`
+	Parameters   json.RawMessage `json:"parameters"`
+	EventPayload json.RawMessage `json:"event_payload"`
+	CreatedAt    string          `json:"created_at"`
+}
+
+func registerRestorableJobsDB(jd *HandleT) {
+	restorableJobsDBsLock.Lock()
+	defer restorableJobsDBsLock.Unlock()
+	restorableJobsDBs[jd.tablePrefix] = jd
+}
+
+func getRestorableJobsDB(tablePrefix string) (*HandleT, error) {
+	restorableJobsDBsLock.RLock()
+	defer restorableJobsDBsLock.RUnlock()
+	jd, ok := restorableJobsDBs[tablePrefix]
+	if !ok {
+		return nil, fmt.Errorf("no %s jobsdb is writable in this server", tablePrefix)
+	}
+	return jd, nil
+}
+
+/*
+parseBackupKey parses the object key of a dump uploaded by backupTable, i.e.
+	<prefixes>/<tablePrefix>_jobs_<index>.<minJobID>.<maxJobID>.<startTime>.<endTime>.gz or
+	<prefixes>/<tablePrefix>_job_status_<index>_aborted.gz
+Dumps of job status tables are not restorable and aren't parsed
+*/
+func parseBackupKey(tablePrefix, key string) (BackupT, bool) {
+	fileName := strings.TrimSuffix(filepath.Base(key), ".gz")
+	backup := BackupT{Key: key, TablePrefix: tablePrefix}
+
+	abortedPrefix := tablePrefix + "_job_status_"
+	abortedSuffix := "_" + Aborted.State
+	if strings.HasPrefix(fileName, abortedPrefix) && strings.HasSuffix(fileName, abortedSuffix) {
+		backup.Index = strings.TrimSuffix(strings.TrimPrefix(fileName, abortedPrefix), abortedSuffix)
+		backup.FailedOnly = true
+		return backup, backup.Index != ""
+	}
+
+	parts := strings.Split(fileName, ".")
+	if len(parts) != 5 || !strings.HasPrefix(parts[0], tablePrefix+"_jobs_") {
+		return backup, false
+	}
+	backup.Index = strings.TrimPrefix(parts[0], tablePrefix+"_jobs_")
+	var values [4]int64
+	for i, part := range parts[1:] {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return backup, false
+		}
+		values[i] = value
+	}
+	backup.MinJobID, backup.MaxJobID = values[0], values[1]
+	backup.StartTime = time.Unix(0, values[2]*int64(time.Millisecond)).UTC()
+	backup.EndTime = time.Unix(0, values[3]*int64(time.Millisecond)).UTC()
+	return backup, true
+}
+
+//backupKeyPrefix is the prefix of the keys of the dumps uploaded by backupTable
+func (jd *HandleT) backupKeyPrefix() string {
+	var prefixes []string
+	if storagePrefix := strings.Trim(config.GetEnv("JOBS_BACKUP_PREFIX", ""), "/"); storagePrefix != "" {
+		prefixes = append(prefixes, storagePrefix)
+	}
+	if jd.BackupSettings.PathPrefix != "" {
+		prefixes = append(prefixes, jd.BackupSettings.PathPrefix)
+	}
+	prefixes = append(prefixes, config.GetEnv("INSTANCE_ID", "1"), jd.tablePrefix+"_job")
+	return strings.Join(prefixes, "/")
+}
+
+//ListBackups lists the restorable dumps of the jobsdb in the backup storage, ordered by dataset
+func (jd *HandleT) ListBackups() ([]BackupT, error) {
+	fileManager, err := jd.getFileUploader()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := fileManager.ListFilesWithPrefix(jd.backupKeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]BackupT, 0)
+	for _, object := range objects {
+		backup, ok := parseBackupKey(jd.tablePrefix, object.Key)
+		if !ok {
+			continue
+		}
+		backup.LastModified = object.LastModifiedTime
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].MinJobID != backups[j].MinJobID {
+			return backups[i].MinJobID < backups[j].MinJobID
+		}
+		return backups[i].LastModified.Before(backups[j].LastModified)
+	})
+	return backups, nil
+}
+
+//matches returns whether a job of a dump is selected by the request
+func (request *RestoreRequestT) matches(job *backupJobT, createdAt time.Time) bool {
+	if request.SourceID != "" && gjson.GetBytes(job.Parameters, "source_id").String() != request.SourceID {
+		return false
+	}
+	if request.DestinationID != "" && gjson.GetBytes(job.Parameters, "destination_id").String() != request.DestinationID {
+		return false
+	}
+	if !request.From.IsZero() && createdAt.Before(request.From) {
+		return false
+	}
+	if !request.To.IsZero() && createdAt.After(request.To) {
+		return false
+	}
+	if request.ExcludeParameter != "" && gjson.GetBytes(job.Parameters, request.ExcludeParameter).Exists() {
+		return false
+	}
+	return true
+}
+
+//eventPayload returns the payload of a job of a dump. Payloads of compressed datasets are dumped as hex encoded bytea strings
+func (jd *HandleT) eventPayload(job *backupJobT) ([]byte, error) {
+	if len(job.EventPayload) == 0 || job.EventPayload[0] != '"' {
+		return job.EventPayload, nil
+	}
+	var encoded string
+	if err := json.Unmarshal(job.EventPayload, &encoded); err != nil {
+		return nil, err
+	}
+	compressed, err := hex.DecodeString(strings.TrimPrefix(encoded, `\x`))
+	if err != nil {
+		return nil, err
+	}
+	return jd.payloadCompressor.decompress(compressed)
+}
+
+/*
+readBackup reads the jobs selected by request from a gzipped dump, from its row at offset, calling onJobs with batches
+of restoreBatchSize jobs and the offset of the row following the batch.
+Jobs are read once even if failed only dumps have a row per failed attempt. It returns the number of skipped jobs
+*/
+func (jd *HandleT) readBackup(reader io.Reader, request RestoreRequestT, offset int, seenJobIDs map[int64]struct{}, onJobs func(jobs []*JobT, offset int) error) (int, error) {
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+
+	var skipped, row int
+	jobs := make([]*JobT, 0, restoreBatchSize)
+	lineReader := bufio.NewReader(gzipReader)
+	for {
+		line, readErr := lineReader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return skipped, readErr
+		}
+		isRow := len(strings.TrimSpace(string(line))) > 0
+		if isRow {
+			row++
+		}
+		if isRow && row > offset {
+			var backupJob backupJobT
+			if err := json.Unmarshal(line, &backupJob); err != nil {
+				return skipped, err
+			}
+			createdAt, err := time.Parse(postgresJSONTimeFormat, backupJob.CreatedAt)
+			if err != nil {
+				return skipped, err
+			}
+			_, seen := seenJobIDs[backupJob.JobID]
+			if seen || !request.matches(&backupJob, createdAt) {
+				skipped++
+			} else {
+				seenJobIDs[backupJob.JobID] = struct{}{}
+				eventPayload, err := jd.eventPayload(&backupJob)
+				if err != nil {
+					return skipped, err
+				}
+				jobs = append(jobs, &JobT{
+					UUID:         backupJob.UUID,
+					UserID:       backupJob.UserID,
+					CustomVal:    backupJob.CustomVal,
+					Parameters:   backupJob.Parameters,
+					EventPayload: eventPayload,
+				})
+			}
+		}
+		if len(jobs) > 0 && (len(jobs) >= restoreBatchSize || readErr == io.EOF) {
+			if err := onJobs(jobs, row); err != nil {
+				return skipped, err
+			}
+			jobs = make([]*JobT, 0, restoreBatchSize)
+		}
+		if readErr == io.EOF {
+			return skipped, nil
+		}
+	}
+}
+
+//downloadBackup downloads the dump at key under TMPDIR and returns it opened
+func (jd *HandleT) downloadBackup(key string) (*os.File, error) {
+	fileManager, err := jd.getFileUploader()
+	if err != nil {
+		return nil, err
+	}
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf(`%v/rudder-s3-dumps/restore/%v`, tmpDirPath, filepath.Base(key))
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = fileManager.Download(file, key); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return file, nil
+}
+
+/*
+ReadBackup downloads the dump at key and reads its jobs selected by request from the row at offset.
+onJobs is called with batches of jobs and the offset to read the dump from to get the jobs following the batch
+*/
+func (jd *HandleT) ReadBackup(key string, request RestoreRequestT, offset int, onJobs func(jobs []*JobT, offset int) error) error {
+	if _, ok := parseBackupKey(jd.tablePrefix, key); !ok {
+		return fmt.Errorf("%s is not a backup of a %s dataset", key, jd.tablePrefix)
+	}
+	file, err := jd.downloadBackup(key)
+	if err != nil {
+		return fmt.Errorf("downloading backup %s: %w", key, err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	_, err = jd.readBackup(file, request, offset, make(map[int64]struct{}), onJobs)
+	return err
+}
+
+/*
+RestoreBackups downloads the dumps selected by request and stores their jobs, as unprocessed jobs with new job ids,
+into a new dataset appended to the jobsdb. Jobs keep their uuid, user, custom val, parameters and payload
+*/
+func (jd *HandleT) RestoreBackups(request RestoreRequestT) (RestoreResultT, error) {
+	var result RestoreResultT
+	if jd.queueStore != nil {
+		return result, errors.New("backups can only be restored into a postgres jobsdb")
+	}
+	if len(request.Keys) == 0 {
+		return result, errors.New("no backup to restore")
+	}
+	for _, key := range request.Keys {
+		if _, ok := parseBackupKey(jd.tablePrefix, key); !ok {
+			return result, fmt.Errorf("%s is not a backup of a %s dataset", key, jd.tablePrefix)
+		}
+	}
+
+	jd.dsListLock.Lock()
+	restoreDS := jd.addNewDS(appendToDsList, dataSetT{})
+	jd.dsListLock.Unlock()
+	result.JobTable = restoreDS.JobTable
+	jd.logger.Infof("[JobsDB] :: Restoring %d backups into %s", len(request.Keys), restoreDS.JobTable)
+
+	storeJobs := func(jobs []*JobT, _ int) error {
+		jd.dsListLock.RLock()
+		defer jd.dsListLock.RUnlock()
+		if err := jd.storeJobsDS(restoreDS, false, jobs); err != nil {
+			return err
+		}
+		result.Restored += len(jobs)
+		return nil
+	}
+
+	seenJobIDs := make(map[int64]struct{})
+	for _, key := range request.Keys {
+		file, err := jd.downloadBackup(key)
+		if err != nil {
+			return result, fmt.Errorf("downloading backup %s: %w", key, err)
+		}
+		skipped, err := jd.readBackup(file, request, 0, seenJobIDs, storeJobs)
+		file.Close()
+		os.Remove(file.Name())
+		result.Skipped += skipped
+		if err != nil {
+			return result, fmt.Errorf("restoring backup %s: %w", key, err)
+		}
+		jd.logger.Infof("[JobsDB] :: Restored backup %s into %s", key, restoreDS.JobTable)
+	}
+
+	stats.NewTaggedStat("jobsdb.restored_jobs", stats.CountType, stats.Tags{"customVal": jd.tablePrefix}).Count(result.Restored)
+	return result, nil
+}
+
+//BackupsRpcHandler lists and restores the backups of the writable jobsdbs of the server over the admin rpc interface
+type BackupsRpcHandler struct {
+}
+
+//ListBackups lists the backups of the jobsdb with the table prefix in arg
+func (handler *BackupsRpcHandler) ListBackups(arg string, reply *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	jd, err := getRestorableJobsDB(arg)
+	if err != nil {
+		return err
+	}
+	backups, err := jd.ListBackups()
+	if err != nil {
+		return err
+	}
+	response, err := json.MarshalIndent(backups, "", " ")
+	*reply = string(response)
+	return err
+}
+
+//Restore restores the backups selected by the RestoreRequestT in arg
+func (handler *BackupsRpcHandler) Restore(arg string, reply *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var request RestoreRequestT
+	if err = json.Unmarshal([]byte(arg), &request); err != nil {
+		return err
+	}
+	jd, err := getRestorableJobsDB(request.TablePrefix)
+	if err != nil {
+		return err
+	}
+	result, restoreErr := jd.RestoreBackups(request)
+	response, err := json.MarshalIndent(result, "", " ")
+	*reply = string(response)
+	if restoreErr != nil {
+		return restoreErr
+	}
+	return err
+}
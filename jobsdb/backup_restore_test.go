@@ -0,0 +1,153 @@
+package jobsdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mocksFileManager "github.com/rudderlabs/rudder-server/mocks/services/filemanager"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+var _ = Describe("Backup restore", func() {
+	gzipDump := func(rows ...string) *bytes.Buffer {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		for _, row := range rows {
+			writer.Write([]byte(row + "\n"))
+		}
+		writer.Close()
+		return &buffer
+	}
+
+	readAll := func(jd *HandleT, dump *bytes.Buffer, request RestoreRequestT) ([]*JobT, int) {
+		var restored []*JobT
+		skipped, err := jd.readBackup(dump, request, 0, make(map[int64]struct{}), func(jobs []*JobT, _ int) error {
+			restored = append(restored, jobs...)
+			return nil
+		})
+		Expect(err).To(BeNil())
+		return restored, skipped
+	}
+
+	It("should parse the keys of jobs and failed only dumps", func() {
+		backup, ok := parseBackupKey("rt", "backups/1/rt_jobs_12_1.100.200.1623000000000.1623001000000.gz")
+		Expect(ok).To(BeTrue())
+		Expect(backup.Index).To(Equal("12_1"))
+		Expect(backup.MinJobID).To(Equal(int64(100)))
+		Expect(backup.MaxJobID).To(Equal(int64(200)))
+		Expect(backup.StartTime).To(Equal(time.Unix(1623000000, 0).UTC()))
+		Expect(backup.FailedOnly).To(BeFalse())
+
+		backup, ok = parseBackupKey("rt", "backups/1/rt_job_status_12_aborted.gz")
+		Expect(ok).To(BeTrue())
+		Expect(backup.Index).To(Equal("12"))
+		Expect(backup.FailedOnly).To(BeTrue())
+
+		_, ok = parseBackupKey("rt", "backups/1/rt_job_status_12.gz")
+		Expect(ok).To(BeFalse())
+		_, ok = parseBackupKey("rt", "backups/1/batch_rt_jobs_12.100.200.1623000000000.1623001000000.gz")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should list the restorable backups of the jobsdb", func() {
+		mockCtrl := gomock.NewController(GinkgoT())
+		defer mockCtrl.Finish()
+		mockFileManager := mocksFileManager.NewMockFileManager(mockCtrl)
+		jd := &HandleT{tablePrefix: "rt", BackupSettings: &BackupSettingsT{PathPrefix: "rt"}, jobsFileUploader: mockFileManager}
+
+		mockFileManager.EXPECT().ListFilesWithPrefix("rt/1/rt_job").Return([]*filemanager.FileObject{
+			{Key: "rt/1/rt_jobs_2.201.300.1623001000000.1623002000000.gz"},
+			{Key: "rt/1/rt_job_status_2.gz"},
+			{Key: "rt/1/rt_jobs_1.1.200.1623000000000.1623001000000.gz"},
+		}, nil).Times(1)
+
+		backups, err := jd.ListBackups()
+		Expect(err).To(BeNil())
+		Expect(backups).To(HaveLen(2))
+		Expect(backups[0].Index).To(Equal("1"))
+		Expect(backups[1].Index).To(Equal("2"))
+	})
+
+	It("should read the jobs of dumps matching the request", func() {
+		jd := &HandleT{}
+		dump := gzipDump(
+			`{"job_id":1,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","parameters":{"source_id":"s1","destination_id":"d1"},"custom_val":"WEBHOOK","event_payload":{"n":1},"created_at":"2021-06-06T20:26:39.598123","expire_at":"2021-06-06T20:26:39.598123"}`,
+			`{"job_id":2,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7d","user_id":"u2","parameters":{"source_id":"s2","destination_id":"d1"},"custom_val":"WEBHOOK","event_payload":{"n":2},"created_at":"2021-06-06T21:26:39","expire_at":"2021-06-06T21:26:39"}`,
+			`{"job_id":3,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7e","user_id":"u3","parameters":{"source_id":"s1","destination_id":"d2"},"custom_val":"WEBHOOK","event_payload":{"n":3},"created_at":"2021-06-07T20:26:39","expire_at":"2021-06-07T20:26:39"}`,
+		)
+
+		jobs, skipped := readAll(jd, dump, RestoreRequestT{SourceID: "s1", To: time.Date(2021, 6, 7, 0, 0, 0, 0, time.UTC)})
+		Expect(skipped).To(Equal(2))
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].UserID).To(Equal("u1"))
+		Expect(jobs[0].CustomVal).To(Equal("WEBHOOK"))
+		Expect(string(jobs[0].EventPayload)).To(Equal(`{"n":1}`))
+		Expect(jobs[0].UUID.String()).To(Equal("d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c"))
+	})
+
+	It("should not read the jobs with the excluded parameter", func() {
+		jd := &HandleT{}
+		dump := gzipDump(
+			`{"job_id":1,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","parameters":{"source_id":"s1"},"custom_val":"GW","event_payload":{"n":1},"created_at":"2021-06-06T20:26:39"}`,
+			`{"job_id":2,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7d","user_id":"u2","parameters":{"source_id":"s1","replay_id":"r1"},"custom_val":"GW","event_payload":{"n":1},"created_at":"2021-06-06T20:26:40"}`,
+		)
+
+		jobs, skipped := readAll(jd, dump, RestoreRequestT{SourceID: "s1", ExcludeParameter: "replay_id"})
+		Expect(skipped).To(Equal(1))
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].UserID).To(Equal("u1"))
+	})
+
+	It("should read dumps from an offset", func() {
+		jd := &HandleT{}
+		dump := gzipDump(
+			`{"job_id":1,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","parameters":{},"custom_val":"GW","event_payload":{"n":1},"created_at":"2021-06-06T20:26:39"}`,
+			`{"job_id":2,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7d","user_id":"u2","parameters":{},"custom_val":"GW","event_payload":{"n":2},"created_at":"2021-06-06T20:26:39"}`,
+			`{"job_id":3,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7e","user_id":"u3","parameters":{},"custom_val":"GW","event_payload":{"n":3},"created_at":"2021-06-06T20:26:39"}`,
+		)
+
+		var userIDs []string
+		var offsets []int
+		_, err := jd.readBackup(dump, RestoreRequestT{}, 1, make(map[int64]struct{}), func(jobs []*JobT, offset int) error {
+			for _, job := range jobs {
+				userIDs = append(userIDs, job.UserID)
+			}
+			offsets = append(offsets, offset)
+			return nil
+		})
+		Expect(err).To(BeNil())
+		Expect(userIDs).To(Equal([]string{"u2", "u3"}))
+		Expect(offsets[len(offsets)-1]).To(Equal(3))
+	})
+
+	It("should read every job of failed only dumps once", func() {
+		jd := &HandleT{}
+		dump := gzipDump(
+			`{"id":1,"job_id":1,"job_state":"failed","parameters":{"source_id":"s1"},"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","custom_val":"WEBHOOK","event_payload":{"n":1},"created_at":"2021-06-06T20:26:39"}`,
+			`{"id":2,"job_id":1,"job_state":"aborted","parameters":{"source_id":"s1"},"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","custom_val":"WEBHOOK","event_payload":{"n":1},"created_at":"2021-06-06T20:26:39"}`,
+		)
+
+		jobs, skipped := readAll(jd, dump, RestoreRequestT{})
+		Expect(jobs).To(HaveLen(1))
+		Expect(skipped).To(Equal(1))
+	})
+
+	It("should decompress the payloads of compressed datasets", func() {
+		compressor, err := newPayloadCompressor(3, "")
+		Expect(err).To(BeNil())
+		compressed, err := compressor.compress([]byte(`{"n":1}`))
+		Expect(err).To(BeNil())
+		jd := &HandleT{payloadCompressor: compressor}
+		dump := gzipDump(`{"job_id":1,"uuid":"d3d4b3a3-3d0e-4bd6-a0a8-0e6b7c0e6b7c","user_id":"u1","parameters":{},"custom_val":"WEBHOOK","event_payload":"\\x` + hex.EncodeToString(compressed) + `","created_at":"2021-06-06T20:26:39"}`)
+
+		jobs, _ := readAll(jd, dump, RestoreRequestT{})
+		Expect(jobs).To(HaveLen(1))
+		Expect(string(jobs[0].EventPayload)).To(Equal(`{"n":1}`))
+	})
+})
@@ -0,0 +1,607 @@
+package jobsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+	uuid "github.com/satori/go.uuid"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+const (
+	//badgerBatchSize is the number of jobs or statuses written in one badger transaction
+	badgerBatchSize = 500
+	//badgerConflictRetries is the number of times a badger transaction conflicting with another one is retried
+	badgerConflictRetries = 10
+	//badgerSequenceBandwidth is the number of ids leased at once from badger sequences
+	badgerSequenceBandwidth = 1000
+)
+
+//Key prefixes of the badger queue store. Job ids in keys are zero padded so that keys sort by job id
+const (
+	badgerJobPrefix         = "j/"
+	badgerStatusPrefix      = "s/"
+	badgerUnprocessedPrefix = "u/"
+	badgerStatePrefix       = "x/"
+	badgerJournalPrefix     = "o/"
+	badgerJobIDSequence     = "seq/jobs"
+	badgerJournalSequence   = "seq/journal"
+)
+
+//badgerStatusT is the latest status of a job. The status before the latest one is kept
+//so that executing statuses can be deleted, putting the jobs back in their previous state
+type badgerStatusT struct {
+	Status   JobStatusT  `json:"status"`
+	Previous *JobStatusT `json:"previous,omitempty"`
+}
+
+//badgerQueueStoreT is a queueStoreT over a node-local BadgerDB. Only the latest status of every job is kept,
+//and jobs in a terminal state are dropped after the terminal state retention
+type badgerQueueStoreT struct {
+	tablePrefix            string
+	path                   string
+	badgerDB               *badger.DB
+	jobIDSequence          *badger.Sequence
+	journalSequence        *badger.Sequence
+	terminalStateRetention time.Duration
+	logger                 logger.LoggerI
+}
+
+type badgerLoggerT struct {
+	logger logger.LoggerI
+}
+
+func (l *badgerLoggerT) Errorf(s string, args ...interface{}) {
+	l.logger.Errorf(s, args...)
+}
+
+func (l *badgerLoggerT) Warningf(s string, args ...interface{}) {
+	l.logger.Warnf(s, args...)
+}
+
+func (l *badgerLoggerT) Infof(s string, args ...interface{}) {
+	l.logger.Infof(s, args...)
+}
+
+func (l *badgerLoggerT) Debugf(s string, args ...interface{}) {
+	l.logger.Debugf(s, args...)
+}
+
+func newBadgerQueueStore(tablePrefix, path string, clearAll bool, terminalStateRetention time.Duration, logger logger.LoggerI) (*badgerQueueStoreT, error) {
+	store := &badgerQueueStoreT{
+		tablePrefix:            tablePrefix,
+		path:                   path,
+		terminalStateRetention: terminalStateRetention,
+		logger:                 logger,
+	}
+	var err error
+	store.badgerDB, err = badger.Open(badger.DefaultOptions(path).WithTruncate(true).WithLogger(&badgerLoggerT{logger: logger}))
+	if err != nil {
+		return nil, err
+	}
+	if clearAll {
+		if err = store.badgerDB.DropAll(); err != nil {
+			return nil, err
+		}
+	}
+	if store.jobIDSequence, err = store.badgerDB.GetSequence([]byte(badgerJobIDSequence), badgerSequenceBandwidth); err != nil {
+		return nil, err
+	}
+	if store.journalSequence, err = store.badgerDB.GetSequence([]byte(badgerJournalSequence), badgerSequenceBandwidth); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *badgerQueueStoreT) startGC() {
+	rruntime.Go(func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			for store.badgerDB.RunValueLogGC(0.5) == nil {
+			}
+		}
+	})
+}
+
+func badgerJobIDSuffix(jobID int64) string {
+	return fmt.Sprintf("%020d", jobID)
+}
+
+func badgerJobKey(jobID int64) []byte {
+	return []byte(badgerJobPrefix + badgerJobIDSuffix(jobID))
+}
+
+func badgerStatusKey(jobID int64) []byte {
+	return []byte(badgerStatusPrefix + badgerJobIDSuffix(jobID))
+}
+
+func badgerUnprocessedKey(customVal string, jobID int64) []byte {
+	return []byte(badgerUnprocessedPrefix + customVal + "/" + badgerJobIDSuffix(jobID))
+}
+
+func badgerStateKey(state, customVal string, jobID int64) []byte {
+	return []byte(badgerStatePrefix + state + "/" + customVal + "/" + badgerJobIDSuffix(jobID))
+}
+
+func badgerJournalKey(opID int64) []byte {
+	return []byte(badgerJournalPrefix + badgerJobIDSuffix(opID))
+}
+
+//badgerJobIDFromKey returns the job id every index key ends with
+func badgerJobIDFromKey(key []byte) (jobID int64) {
+	fmt.Sscanf(string(key[len(key)-20:]), "%d", &jobID)
+	return jobID
+}
+
+func isTerminalState(state string) bool {
+	for _, jobState := range jobStates {
+		if jobState.State == state {
+			return jobState.isTerminal
+		}
+	}
+	return false
+}
+
+//matchesParameterFilters matches parameters the way constructParameterJSONQuery does: either all filters match,
+//or all mandatory filters match and none of the optional parameters is set
+func matchesParameterFilters(parameters []byte, parameterFilters []ParameterFilterT) bool {
+	allMatch, mandatoryMatch, optionalMissing := true, true, true
+	for _, parameter := range parameterFilters {
+		value := gjson.GetBytes(parameters, parameter.Name)
+		if !value.Exists() || value.Type != gjson.String || value.Str != parameter.Value {
+			allMatch = false
+			if !parameter.Optional {
+				mandatoryMatch = false
+			}
+		}
+		if parameter.Optional && value.Exists() {
+			optionalMissing = false
+		}
+	}
+	return allMatch || (mandatoryMatch && optionalMissing)
+}
+
+//update runs fn in a badger transaction, retrying it when it conflicts with another transaction
+func (store *badgerQueueStoreT) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for i := 0; i < badgerConflictRetries; i++ {
+		err = store.badgerDB.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+func (store *badgerQueueStoreT) setWithRetention(txn *badger.Txn, key, value []byte, terminal bool) error {
+	entry := badger.NewEntry(key, value)
+	if terminal && store.terminalStateRetention > 0 {
+		entry = entry.WithTTL(store.terminalStateRetention)
+	}
+	return txn.SetEntry(entry)
+}
+
+func (store *badgerQueueStoreT) store(jobList []*JobT) error {
+	for start := 0; start < len(jobList); start += badgerBatchSize {
+		end := start + badgerBatchSize
+		if end > len(jobList) {
+			end = len(jobList)
+		}
+		if err := store.storeBatch(jobList[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *badgerQueueStoreT) storeBatch(jobList []*JobT) error {
+	jobIDs := make([]int64, len(jobList))
+	for i := range jobList {
+		jobID, err := store.jobIDSequence.Next()
+		if err != nil {
+			return err
+		}
+		//sequences start at 0 while postgres job ids start at 1
+		jobIDs[i] = int64(jobID) + 1
+	}
+	now := time.Now()
+	return store.update(func(txn *badger.Txn) error {
+		for i, job := range jobList {
+			storedJob := *job
+			storedJob.JobID = jobIDs[i]
+			storedJob.LastJobStatus = JobStatusT{}
+			storedJob.CreatedAt = now
+			storedJob.ExpireAt = now
+			value, err := json.Marshal(storedJob)
+			if err != nil {
+				return err
+			}
+			if err = txn.Set(badgerJobKey(storedJob.JobID), value); err != nil {
+				return err
+			}
+			if err = txn.Set(badgerUnprocessedKey(storedJob.CustomVal, storedJob.JobID), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//storeWithRetryEach stores jobs in batches, storing the jobs of a batch that failed one by one
+func (store *badgerQueueStoreT) storeWithRetryEach(jobList []*JobT) (errorMessagesMap map[uuid.UUID]string) {
+	for start := 0; start < len(jobList); start += badgerBatchSize {
+		end := start + badgerBatchSize
+		if end > len(jobList) {
+			end = len(jobList)
+		}
+		if err := store.storeBatch(jobList[start:end]); err == nil {
+			continue
+		}
+		if errorMessagesMap == nil {
+			errorMessagesMap = make(map[uuid.UUID]string)
+		}
+		for _, job := range jobList[start:end] {
+			if err := store.storeBatch([]*JobT{job}); err != nil {
+				errorMessagesMap[job.UUID] = err.Error()
+			}
+		}
+	}
+	return
+}
+
+func getBadgerJob(txn *badger.Txn, jobID int64) (*JobT, error) {
+	item, err := txn.Get(badgerJobKey(jobID))
+	if err != nil {
+		return nil, err
+	}
+	var job JobT
+	err = item.Value(func(value []byte) error {
+		return json.Unmarshal(value, &job)
+	})
+	return &job, err
+}
+
+func getBadgerStatus(txn *badger.Txn, jobID int64) (*badgerStatusT, error) {
+	item, err := txn.Get(badgerStatusKey(jobID))
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status badgerStatusT
+	err = item.Value(func(value []byte) error {
+		return json.Unmarshal(value, &status)
+	})
+	return &status, err
+}
+
+func (store *badgerQueueStoreT) updateJobStatus(statusList []*JobStatusT) error {
+	for start := 0; start < len(statusList); start += badgerBatchSize {
+		end := start + badgerBatchSize
+		if end > len(statusList) {
+			end = len(statusList)
+		}
+		batch := statusList[start:end]
+		if err := store.update(func(txn *badger.Txn) error {
+			for _, status := range batch {
+				if err := store.setStatus(txn, status); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setStatus makes status the latest status of its job, moving the job from the index of its previous state to the one of its new state
+func (store *badgerQueueStoreT) setStatus(txn *badger.Txn, status *JobStatusT) error {
+	job, err := getBadgerJob(txn, status.JobID)
+	if err == badger.ErrKeyNotFound {
+		//jobs in a terminal state are dropped after the terminal state retention
+		store.logger.Warnf("[[ %s ]]: Dropping status %s of missing job %d", store.tablePrefix, status.JobState, status.JobID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	latestStatus, err := getBadgerStatus(txn, status.JobID)
+	if err != nil {
+		return err
+	}
+	storedStatus := badgerStatusT{Status: *status}
+	if latestStatus == nil {
+		err = txn.Delete(badgerUnprocessedKey(job.CustomVal, job.JobID))
+	} else {
+		storedStatus.Previous = &latestStatus.Status
+		err = txn.Delete(badgerStateKey(latestStatus.Status.JobState, job.CustomVal, job.JobID))
+	}
+	if err != nil {
+		return err
+	}
+	return store.putStatus(txn, job, storedStatus)
+}
+
+func (store *badgerQueueStoreT) putStatus(txn *badger.Txn, job *JobT, storedStatus badgerStatusT) error {
+	value, err := json.Marshal(storedStatus)
+	if err != nil {
+		return err
+	}
+	terminal := isTerminalState(storedStatus.Status.JobState)
+	if err = store.setWithRetention(txn, badgerStatusKey(job.JobID), value, terminal); err != nil {
+		return err
+	}
+	if err = store.setWithRetention(txn, badgerStateKey(storedStatus.Status.JobState, job.CustomVal, job.JobID), nil, terminal); err != nil {
+		return err
+	}
+	if terminal {
+		jobValue, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return store.setWithRetention(txn, badgerJobKey(job.JobID), jobValue, true)
+	}
+	return nil
+}
+
+//indexPrefixes returns the prefixes of the index keys of the custom vals in params
+func indexPrefixes(prefix string, params GetQueryParamsT) []string {
+	if len(params.CustomValFilters) == 0 || params.IgnoreCustomValFiltersInQuery {
+		return []string{prefix}
+	}
+	prefixes := make([]string, 0, len(params.CustomValFilters))
+	for _, customVal := range params.CustomValFilters {
+		prefixes = append(prefixes, prefix+customVal+"/")
+	}
+	return prefixes
+}
+
+//scanIndex returns up to count jobs (all if count is negative) of the index keys under the prefixes for which match returns true.
+//Jobs are returned in job id order
+func (store *badgerQueueStoreT) scanIndex(prefixes []string, count int, match func(txn *badger.Txn, job *JobT) (bool, error)) ([]*JobT, error) {
+	outJobs := make([]*JobT, 0)
+	err := store.badgerDB.View(func(txn *badger.Txn) error {
+		for _, prefix := range prefixes {
+			iterator := txn.NewIterator(badger.IteratorOptions{PrefetchValues: false, Prefix: []byte(prefix)})
+			prefixCount := 0
+			for iterator.Rewind(); iterator.Valid() && (count < 0 || prefixCount < count); iterator.Next() {
+				job, err := getBadgerJob(txn, badgerJobIDFromKey(iterator.Item().Key()))
+				if err == badger.ErrKeyNotFound {
+					continue
+				}
+				if err != nil {
+					iterator.Close()
+					return err
+				}
+				ok, err := match(txn, job)
+				if err != nil {
+					iterator.Close()
+					return err
+				}
+				if ok {
+					outJobs = append(outJobs, job)
+					prefixCount++
+				}
+			}
+			iterator.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(outJobs, func(i, j int) bool {
+		return outJobs[i].JobID < outJobs[j].JobID
+	})
+	if count >= 0 && len(outJobs) > count {
+		outJobs = outJobs[:count]
+	}
+	return outJobs, nil
+}
+
+func (store *badgerQueueStoreT) getUnprocessed(params GetQueryParamsT) []*JobT {
+	jobs, err := store.scanIndex(indexPrefixes(badgerUnprocessedPrefix, params), params.Count, func(txn *badger.Txn, job *JobT) (bool, error) {
+		if params.UseTimeFilter && !job.CreatedAt.Before(params.Before) {
+			return false, nil
+		}
+		return matchesParameterFilters(job.Parameters, params.ParameterFilters), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return jobs
+}
+
+//getProcessed returns jobs whose latest state is one of the StateFilters, and whose retry time has passed
+func (store *badgerQueueStoreT) getProcessed(params GetQueryParamsT) []*JobT {
+	now := time.Now()
+	outJobs := make([]*JobT, 0)
+	for _, state := range params.StateFilters {
+		jobs, err := store.scanIndex(indexPrefixes(badgerStatePrefix+state+"/", params), params.Count, func(txn *badger.Txn, job *JobT) (bool, error) {
+			if !matchesParameterFilters(job.Parameters, params.ParameterFilters) {
+				return false, nil
+			}
+			latestStatus, err := getBadgerStatus(txn, job.JobID)
+			if err != nil || latestStatus == nil {
+				return false, err
+			}
+			job.LastJobStatus = latestStatus.Status
+			return latestStatus.Status.RetryTime.Before(now), nil
+		})
+		if err != nil {
+			panic(err)
+		}
+		outJobs = append(outJobs, jobs...)
+	}
+
+	sort.Slice(outJobs, func(i, j int) bool {
+		return outJobs[i].JobID < outJobs[j].JobID
+	})
+	if params.Count >= 0 && len(outJobs) > params.Count {
+		outJobs = outJobs[:params.Count]
+	}
+	return outJobs
+}
+
+//deleteExecuting puts the executing jobs matching params back in the state they were in before being marked as executing
+func (store *badgerQueueStoreT) deleteExecuting(params GetQueryParamsT) {
+	now := time.Now()
+	jobs, err := store.scanIndex(indexPrefixes(badgerStatePrefix+Executing.State+"/", params), params.Count, func(txn *badger.Txn, job *JobT) (bool, error) {
+		if !matchesParameterFilters(job.Parameters, params.ParameterFilters) {
+			return false, nil
+		}
+		latestStatus, err := getBadgerStatus(txn, job.JobID)
+		if err != nil || latestStatus == nil {
+			return false, err
+		}
+		return latestStatus.Status.RetryTime.Before(now) &&
+			(len(params.StatusParameterFilters) == 0 || matchesParameterFilters(latestStatus.Status.Parameters, params.StatusParameterFilters)), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	for start := 0; start < len(jobs); start += badgerBatchSize {
+		end := start + badgerBatchSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+		batch := jobs[start:end]
+		err = store.update(func(txn *badger.Txn) error {
+			for _, job := range batch {
+				latestStatus, err := getBadgerStatus(txn, job.JobID)
+				if err != nil {
+					return err
+				}
+				if latestStatus == nil || latestStatus.Status.JobState != Executing.State {
+					continue
+				}
+				if err = txn.Delete(badgerStateKey(Executing.State, job.CustomVal, job.JobID)); err != nil {
+					return err
+				}
+				if latestStatus.Previous == nil {
+					if err = txn.Delete(badgerStatusKey(job.JobID)); err != nil {
+						return err
+					}
+					if err = txn.Set(badgerUnprocessedKey(job.CustomVal, job.JobID), nil); err != nil {
+						return err
+					}
+					continue
+				}
+				if err = store.putStatus(txn, job, badgerStatusT{Status: *latestStatus.Previous}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (store *badgerQueueStoreT) journalMarkStart(opType string, opPayload json.RawMessage) int64 {
+	opID, err := store.journalSequence.Next()
+	if err != nil {
+		panic(err)
+	}
+	entry := JournalEntryT{OpID: int64(opID) + 1, OpType: opType, OpPayload: opPayload}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		panic(err)
+	}
+	err = store.update(func(txn *badger.Txn) error {
+		return txn.Set(badgerJournalKey(entry.OpID), value)
+	})
+	if err != nil {
+		panic(err)
+	}
+	return entry.OpID
+}
+
+func (store *badgerQueueStoreT) journalMarkDone(opID int64) {
+	err := store.update(func(txn *badger.Txn) error {
+		item, err := txn.Get(badgerJournalKey(opID))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var entry JournalEntryT
+		if err = item.Value(func(value []byte) error {
+			return json.Unmarshal(value, &entry)
+		}); err != nil {
+			return err
+		}
+		entry.OpDone = true
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return txn.Set(badgerJournalKey(opID), value)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (store *badgerQueueStoreT) journalDeleteEntry(opID int64) {
+	err := store.update(func(txn *badger.Txn) error {
+		return txn.Delete(badgerJournalKey(opID))
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (store *badgerQueueStoreT) getJournalEntries(opType string) (entries []JournalEntryT) {
+	err := store.badgerDB.View(func(txn *badger.Txn) error {
+		iterator := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, Prefix: []byte(badgerJournalPrefix)})
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			var entry JournalEntryT
+			if err := iterator.Item().Value(func(value []byte) error {
+				return json.Unmarshal(value, &entry)
+			}); err != nil {
+				return err
+			}
+			if !entry.OpDone && entry.OpType == opType {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+func (store *badgerQueueStoreT) status() interface{} {
+	lsmSize, vlogSize := store.badgerDB.Size()
+	return map[string]interface{}{
+		"storage":   BADGER_STORAGE,
+		"path":      store.path,
+		"lsm-size":  lsmSize,
+		"vlog-size": vlogSize,
+	}
+}
+
+func (store *badgerQueueStoreT) tearDown() {
+	store.jobIDSequence.Release()
+	store.journalSequence.Release()
+	store.badgerDB.Close()
+}
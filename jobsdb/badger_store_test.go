@@ -0,0 +1,152 @@
+package jobsdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+var _ = Describe("Badger queue store", func() {
+	var (
+		path  string
+		store *badgerQueueStoreT
+	)
+
+	newJob := func(customVal, destinationID string) *JobT {
+		return &JobT{
+			UUID:         uuid.NewV4(),
+			UserID:       "user-1",
+			CustomVal:    customVal,
+			EventPayload: []byte(`{"event":"track"}`),
+			Parameters:   []byte(`{"destination_id":"` + destinationID + `"}`),
+		}
+	}
+
+	jobIDs := func(jobs []*JobT) []int64 {
+		ids := make([]int64, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.JobID)
+		}
+		return ids
+	}
+
+	newStatus := func(jobID int64, state string, retryTime time.Time, parameters string) *JobStatusT {
+		return &JobStatusT{
+			JobID:         jobID,
+			JobState:      state,
+			AttemptNum:    1,
+			ExecTime:      time.Now(),
+			RetryTime:     retryTime,
+			ErrorResponse: []byte(`{}`),
+			Parameters:    []byte(parameters),
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		path, err = ioutil.TempDir("", "jobsdb_badger")
+		Expect(err).To(BeNil())
+		store, err = newBadgerQueueStore("rt", path, true, time.Hour, logger.NewLogger().Child("jobsdb"))
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		store.tearDown()
+		os.RemoveAll(path)
+	})
+
+	It("should return unprocessed jobs in order, filtered by custom val and parameters", func() {
+		Expect(store.store([]*JobT{newJob("WEBHOOK", "dest-1"), newJob("AM", "dest-2"), newJob("WEBHOOK", "dest-2"), newJob("WEBHOOK", "dest-1")})).To(BeNil())
+
+		jobs := store.getUnprocessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, Count: 10})
+		Expect(jobIDs(jobs)).To(Equal([]int64{1, 3, 4}))
+		Expect(string(jobs[0].EventPayload)).To(Equal(`{"event":"track"}`))
+
+		jobs = store.getUnprocessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK", "AM"}, ParameterFilters: []ParameterFilterT{{Name: "destination_id", Value: "dest-2"}}, Count: 10})
+		Expect(jobIDs(jobs)).To(Equal([]int64{2, 3}))
+
+		jobs = store.getUnprocessed(GetQueryParamsT{Count: 2})
+		Expect(jobIDs(jobs)).To(Equal([]int64{1, 2}))
+	})
+
+	It("should move jobs between states on status updates", func() {
+		Expect(store.store([]*JobT{newJob("WEBHOOK", "dest-1"), newJob("WEBHOOK", "dest-1"), newJob("WEBHOOK", "dest-1")})).To(BeNil())
+		Expect(store.updateJobStatus([]*JobStatusT{
+			newStatus(1, Failed.State, time.Now().Add(-time.Minute), `{}`),
+			newStatus(2, Failed.State, time.Now().Add(time.Hour), `{}`),
+			newStatus(3, Succeeded.State, time.Now(), `{}`),
+		})).To(BeNil())
+
+		Expect(store.getUnprocessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, Count: 10})).To(BeEmpty())
+		jobs := store.getProcessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, StateFilters: []string{Failed.State}, Count: 10})
+		Expect(jobIDs(jobs)).To(Equal([]int64{1}))
+		Expect(jobs[0].LastJobStatus.JobState).To(Equal(Failed.State))
+		Expect(jobs[0].LastJobStatus.AttemptNum).To(Equal(1))
+		Expect(jobIDs(store.getProcessed(GetQueryParamsT{StateFilters: []string{Succeeded.State}, Count: 10}))).To(Equal([]int64{3}))
+	})
+
+	It("should put executing jobs back in their previous state", func() {
+		Expect(store.store([]*JobT{newJob("WEBHOOK", "dest-1"), newJob("WEBHOOK", "dest-1"), newJob("WEBHOOK", "dest-1")})).To(BeNil())
+		Expect(store.updateJobStatus([]*JobStatusT{newStatus(2, Failed.State, time.Now().Add(-time.Minute), `{}`)})).To(BeNil())
+		Expect(store.updateJobStatus([]*JobStatusT{
+			newStatus(1, Executing.State, time.Now().Add(-time.Second), `{"node_id":"node-a"}`),
+			newStatus(2, Executing.State, time.Now().Add(-time.Second), `{"node_id":"node-a"}`),
+			newStatus(3, Executing.State, time.Now().Add(-time.Second), `{"node_id":"node-b"}`),
+		})).To(BeNil())
+
+		store.deleteExecuting(GetQueryParamsT{
+			CustomValFilters:       []string{"WEBHOOK"},
+			Count:                  -1,
+			StatusParameterFilters: []ParameterFilterT{{Name: "node_id", Value: "node-a"}},
+		})
+		Expect(jobIDs(store.getUnprocessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, Count: 10}))).To(Equal([]int64{1}))
+		Expect(jobIDs(store.getProcessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, StateFilters: []string{Failed.State}, Count: 10}))).To(Equal([]int64{2}))
+		Expect(jobIDs(store.getProcessed(GetQueryParamsT{CustomValFilters: []string{"WEBHOOK"}, StateFilters: []string{Executing.State}, Count: 10}))).To(Equal([]int64{3}))
+	})
+
+	It("should keep journal entries till they are done", func() {
+		opID := store.journalMarkStart(RawDataDestUploadOperation, json.RawMessage(`{"file":"f"}`))
+		otherOpID := store.journalMarkStart(RawDataDestUploadOperation, json.RawMessage(`{}`))
+		store.journalMarkDone(otherOpID)
+
+		entries := store.getJournalEntries(RawDataDestUploadOperation)
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].OpID).To(Equal(opID))
+		Expect(string(entries[0].OpPayload)).To(Equal(`{"file":"f"}`))
+
+		store.journalDeleteEntry(opID)
+		Expect(store.getJournalEntries(RawDataDestUploadOperation)).To(BeEmpty())
+	})
+
+	It("should match optional parameter filters like postgres", func() {
+		filters := []ParameterFilterT{{Name: "source_id", Value: "source-1"}, {Name: "destination_id", Value: "dest-1", Optional: true}}
+		Expect(matchesParameterFilters([]byte(`{"source_id":"source-1","destination_id":"dest-1"}`), filters)).To(BeTrue())
+		Expect(matchesParameterFilters([]byte(`{"source_id":"source-1"}`), filters)).To(BeTrue())
+		Expect(matchesParameterFilters([]byte(`{"source_id":"source-1","destination_id":"dest-2"}`), filters)).To(BeFalse())
+		Expect(matchesParameterFilters([]byte(`{"destination_id":"dest-1"}`), filters)).To(BeFalse())
+	})
+
+	It("should only be set up by embedded servers owning the jobsdb as ReadWrite", func() {
+		defer os.Unsetenv("APP_TYPE")
+		defer os.Unsetenv("RUDDER_TMPDIR")
+		os.Setenv("RUDDER_TMPDIR", path)
+		jd := &HandleT{tablePrefix: "gw", logger: logger.NewLogger().Child("jobsdb")}
+
+		os.Setenv("APP_TYPE", "PROCESSOR")
+		Expect(func() { jd.setupBadgerQueueStore(ReadWrite, true) }).To(Panic())
+		os.Setenv("APP_TYPE", EMBEDDED_APP_TYPE)
+		Expect(func() { jd.setupBadgerQueueStore(Write, true) }).To(Panic())
+		Expect(jd.queueStore).To(BeNil())
+
+		jd.setupBadgerQueueStore(ReadWrite, true)
+		Expect(jd.queueStore).NotTo(BeNil())
+		jd.queueStore.tearDown()
+	})
+})
@@ -0,0 +1,117 @@
+package jobsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+//zstdMagic starts every zstd frame. JSON payloads can't start with it, which tells compressed payloads apart
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+//plainDecoder decompresses payloads compressed without a dictionary. zstd is pure go so that builds without cgo can read compressed datasets
+var plainDecoder, _ = zstd.NewReader(nil)
+
+//payloadCompressorT compresses event payloads with zstd, using a dictionary shared by all the payloads if one is set.
+//Its encoder and decoder are safe for concurrent use
+type payloadCompressorT struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newPayloadCompressor(level int, dictionaryPath string) (*payloadCompressorT, error) {
+	encoderOptions := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level))}
+	decoder := plainDecoder
+	if dictionaryPath != "" {
+		dictionary, err := ioutil.ReadFile(dictionaryPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(dictionary) > 0 {
+			encoderOptions = append(encoderOptions, zstd.WithEncoderDict(dictionary))
+			if decoder, err = zstd.NewReader(nil, zstd.WithDecoderDicts(dictionary)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	encoder, err := zstd.NewWriter(nil, encoderOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &payloadCompressorT{encoder: encoder, decoder: decoder}, nil
+}
+
+//compress returns the zstd frame of payload, which has to be valid json
+func (compressor *payloadCompressorT) compress(payload []byte) ([]byte, error) {
+	if !json.Valid(payload) {
+		return nil, errors.New("invalid json payload")
+	}
+	return compressor.encoder.EncodeAll(payload, nil), nil
+}
+
+//decompress returns payloads which are not zstd frames, read from datasets created before compression was enabled, as they are
+func (compressor *payloadCompressorT) decompress(payload []byte) ([]byte, error) {
+	if !bytes.HasPrefix(payload, zstdMagic) {
+		return payload, nil
+	}
+	if compressor == nil {
+		return plainDecoder.DecodeAll(payload, nil)
+	}
+	return compressor.decoder.DecodeAll(payload, nil)
+}
+
+//isCompressedTable returns whether the payloads of a jobs table are stored compressed, in a bytea event_payload column
+func (jd *HandleT) isCompressedTable(jobTable string) bool {
+	jd.compressedTablesLock.Lock()
+	defer jd.compressedTablesLock.Unlock()
+
+	if jd.compressedTables == nil {
+		jd.compressedTables = make(map[string]bool)
+	}
+	compressed, ok := jd.compressedTables[jobTable]
+	if ok {
+		return compressed
+	}
+	var dataType string
+	sqlStatement := `SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = 'event_payload'`
+	err := jd.dbHandle.QueryRow(sqlStatement, jobTable).Scan(&dataType)
+	jd.assertError(err)
+	compressed = dataType == "bytea"
+	jd.compressedTables[jobTable] = compressed
+	return compressed
+}
+
+func (jd *HandleT) setCompressedTable(jobTable string, compressed bool) {
+	jd.compressedTablesLock.Lock()
+	defer jd.compressedTablesLock.Unlock()
+
+	if jd.compressedTables == nil {
+		jd.compressedTables = make(map[string]bool)
+	}
+	jd.compressedTables[jobTable] = compressed
+}
+
+//decompressPayloads decompresses in place the payloads of jobs read from any dataset
+func (jd *HandleT) decompressPayloads(jobList []*JobT) {
+	for _, job := range jobList {
+		payload, err := jd.payloadCompressor.decompress(job.EventPayload)
+		jd.assertError(err)
+		job.EventPayload = payload
+	}
+}
+
+//storedPayload returns the payload of job as stored in the jobs table, compressed if the table is
+func (jd *HandleT) storedPayload(compressed bool, job *JobT) (interface{}, error) {
+	if !compressed {
+		return string(job.EventPayload), nil
+	}
+	payload, err := jd.payloadCompressor.compress(job.EventPayload)
+	if err != nil {
+		return nil, fmt.Errorf("compressing payload of job %v: %w", job.UUID, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,70 @@
+package jobsdb
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Payload compression", func() {
+	payload := []byte(`{"batch":[{"type":"track","event":"Product Viewed","properties":{"price":10}}]}`)
+
+	It("should round trip payloads", func() {
+		compressor, err := newPayloadCompressor(3, "")
+		Expect(err).To(BeNil())
+
+		compressed, err := compressor.compress(payload)
+		Expect(err).To(BeNil())
+		Expect(compressed[:len(zstdMagic)]).To(Equal(zstdMagic))
+
+		decompressed, err := compressor.decompress(compressed)
+		Expect(err).To(BeNil())
+		Expect(decompressed).To(Equal(payload))
+	})
+
+	It("should round trip payloads with a dictionary", func() {
+		// payloads.dict was trained from batches of events with zstd --train
+		compressor, err := newPayloadCompressor(3, "testdata/payloads.dict")
+		Expect(err).To(BeNil())
+
+		compressed, err := compressor.compress(payload)
+		Expect(err).To(BeNil())
+		_, err = plainDecoder.DecodeAll(compressed, nil)
+		Expect(err).NotTo(BeNil())
+
+		decompressed, err := compressor.decompress(compressed)
+		Expect(err).To(BeNil())
+		Expect(decompressed).To(Equal(payload))
+	})
+
+	It("should not start with a dictionary which isn't in the zstd format", func() {
+		dictionaryFile, err := ioutil.TempFile("", "jobsdb_dictionary")
+		Expect(err).To(BeNil())
+		defer os.Remove(dictionaryFile.Name())
+		_, err = dictionaryFile.Write([]byte(`{"batch":[{"type":"track","event":"","properties":{}}]}`))
+		Expect(err).To(BeNil())
+		Expect(dictionaryFile.Close()).To(BeNil())
+
+		_, err = newPayloadCompressor(3, dictionaryFile.Name())
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should return uncompressed payloads as they are", func() {
+		compressor, err := newPayloadCompressor(3, "")
+		Expect(err).To(BeNil())
+
+		decompressed, err := compressor.decompress(payload)
+		Expect(err).To(BeNil())
+		Expect(decompressed).To(Equal(payload))
+	})
+
+	It("should reject invalid json payloads", func() {
+		compressor, err := newPayloadCompressor(3, "")
+		Expect(err).To(BeNil())
+
+		_, err = compressor.compress([]byte(`{"batch":`))
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -21,6 +21,7 @@ package jobsdb
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -31,6 +32,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/rudderlabs/rudder-server/admin"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/tidwall/gjson"
 
@@ -68,6 +70,8 @@ type GetQueryParamsT struct {
 	IgnoreCustomValFiltersInQuery bool
 	UseTimeFilter                 bool
 	Before                        time.Time
+	//StatusParameterFilters filter on the parameters of the latest job status, used only when deleting job statuses
+	StatusParameterFilters []ParameterFilterT
 }
 
 //StatTagsT is a struct to hold tags for stats
@@ -75,6 +79,7 @@ type StatTagsT struct {
 	CustomValFilters []string
 	ParameterFilters []ParameterFilterT
 	StateFilters     []string
+	Compressed       bool
 }
 
 var getTimeNowFunc = func() time.Time {
@@ -107,6 +112,7 @@ type JobsDB interface {
 
 	Status() interface{}
 	GetIdentifier() string
+	IsSharedByNodes() bool
 	DeleteExecuting(params GetQueryParamsT)
 
 	GetJournalEntries(opType string) (entries []JournalEntryT)
@@ -163,6 +169,7 @@ func (jd *HandleT) CommitTransaction(txn *sql.Tx) {
 
 //AcquireStoreLock acquires locks necessary for storing jobs in transaction
 func (jd *HandleT) AcquireStoreLock() {
+	jd.pushDatasetsLock(jd.lockDatasetsToWrite())
 	//Only locks the list
 	jd.dsListLock.RLock()
 }
@@ -170,10 +177,12 @@ func (jd *HandleT) AcquireStoreLock() {
 //ReleaseStoreLock releases locks held to store jobs in transaction
 func (jd *HandleT) ReleaseStoreLock() {
 	jd.dsListLock.RUnlock()
+	jd.unlockDatasets(jd.popDatasetsLock())
 }
 
 //AcquireUpdateJobStatusLocks acquires locks necessary for updating job statuses in transaction
 func (jd *HandleT) AcquireUpdateJobStatusLocks() {
+	jd.pushDatasetsLock(jd.lockDatasetsToRead())
 	//The order of lock is very important. The migrateDSLoop
 	//takes lock in this order so reversing this will cause
 	//deadlocks
@@ -185,6 +194,7 @@ func (jd *HandleT) AcquireUpdateJobStatusLocks() {
 func (jd *HandleT) ReleaseUpdateJobStatusLocks() {
 	jd.dsListLock.RUnlock()
 	jd.dsMigrationLock.RUnlock()
+	jd.unlockDatasets(jd.popDatasetsLock())
 }
 
 /*
@@ -203,6 +213,11 @@ func (jd *HandleT) UpdateJobStatusInTxn(txn *sql.Tx, statusList []*JobStatusT, c
 	queryStat.Start()
 	defer queryStat.End()
 
+	//the statuses of jobs in a queue store are not part of the transaction
+	if jd.queueStore != nil {
+		return jd.queueStore.updateJobStatus(statusList)
+	}
+
 	updatedStatesByDS, err := jd.updateJobStatusInTxn(txn, statusList, tags)
 	if err != nil {
 		jd.rollbackTx(err, txn)
@@ -318,6 +333,16 @@ type HandleT struct {
 	maxReaders                    int
 	maxWriters                    int
 	queryFilterKeys               QueryFiltersT
+	queueStore                    queueStoreT
+	compressPayload               bool
+	payloadCompressor             *payloadCompressorT
+	compressedTables              map[string]bool
+	compressedTablesLock          sync.Mutex
+	sharedByNodes                 bool
+	dsOwnerConn                   *sql.Conn
+	dsOwnerLock                   sync.Mutex
+	datasetsLockTxns              []*sql.Tx
+	datasetsLockTxnsLock          sync.Mutex
 }
 
 type QueryFiltersT struct {
@@ -402,6 +427,9 @@ func (jd *HandleT) assert(cond bool, errorString string) {
 }
 
 func (jd *HandleT) Status() interface{} {
+	if jd.queueStore != nil {
+		return jd.queueStore.status()
+	}
 	statusObj := map[string]interface{}{
 		"dataset-list":    jd.getDSList(false),
 		"dataset-ranges":  jd.getDSRangeList(false),
@@ -587,9 +615,36 @@ func (jd *HandleT) Setup(ownerType OwnerType, clearAll bool, tablePrefix string,
 	jd.assertError(err)
 
 	jd.workersAndAuxSetup(ownerType, tablePrefix, retentionPeriod, migrationMode, registerStatusHandler, queryFilterKeys)
+
+	var storage string
+	config.RegisterStringConfigVariable(POSTGRES_STORAGE, &storage, false, "JobsDB."+jd.tablePrefix+"."+"storage")
+	if storage == BADGER_STORAGE {
+		jd.setupBadgerQueueStore(ownerType, clearAll)
+		return
+	}
 	jd.setUpForOwnerType(ownerType, clearAll)
 }
 
+//setupBadgerQueueStore keeps the jobs of the jobsdb in a node-local BadgerDB under TMPDIR instead of postgres.
+//The BadgerDB can't be shared across processes, so only an embedded server owning the jobsdb as ReadWrite can use it
+func (jd *HandleT) setupBadgerQueueStore(ownerType OwnerType, clearAll bool) {
+	appType := strings.ToUpper(config.GetEnv("APP_TYPE", EMBEDDED_APP_TYPE))
+	jd.assert(appType == EMBEDDED_APP_TYPE && ownerType == ReadWrite,
+		fmt.Sprintf("badger storage needs the jobsdb to be owned as ReadWrite by an embedded server, got APP_TYPE %s and owner type %q", appType, ownerType))
+	var terminalStateRetention time.Duration
+	terminalStateRetentionKeys := []string{"JobsDB." + jd.tablePrefix + "." + "badger.terminalStateRetention", "JobsDB." + "badger.terminalStateRetention"}
+	config.RegisterDurationConfigVariable(60, &terminalStateRetention, false, time.Minute, terminalStateRetentionKeys...)
+	tmpDirPath, err := misc.CreateTMPDIR()
+	jd.assertError(err)
+	path := fmt.Sprintf(`%v/jobsdb_%v_badger`, tmpDirPath, jd.tablePrefix)
+
+	store, err := newBadgerQueueStore(jd.tablePrefix, path, clearAll, terminalStateRetention, jd.logger)
+	jd.assertError(err)
+	store.startGC()
+	jd.queueStore = store
+	jd.logger.Infof("Storing %s jobs in badger at %s", jd.tablePrefix, path)
+}
+
 func (jd *HandleT) workersAndAuxSetup(ownerType OwnerType, tablePrefix string, retentionPeriod time.Duration, migrationMode string, registerStatusHandler bool, queryFilterKeys QueryFiltersT) {
 	jd.queryFilterKeys = queryFilterKeys
 
@@ -613,6 +668,10 @@ func (jd *HandleT) workersAndAuxSetup(ownerType OwnerType, tablePrefix string, r
 	jd.statNewDSPeriod = stats.NewTaggedStat("jobsdb.new_ds_period", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 	jd.statDropDSPeriod = stats.NewTaggedStat("jobsdb.drop_ds_period", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 
+	sharedByNodesKeys := []string{"JobsDB." + jd.tablePrefix + "." + "sharedByNodes", "JobsDB." + "sharedByNodes"}
+	config.RegisterBoolConfigVariable(false, &jd.sharedByNodes, false, sharedByNodesKeys...)
+	jd.assert(!jd.sharedByNodes || ownerType == ReadWrite, fmt.Sprintf("only ReadWrite owners can share the jobsdb with other nodes, got owner type %q", ownerType))
+
 	enableWriterQueueKeys := []string{"JobsDB." + jd.tablePrefix + "." + "enableWriterQueue", "JobsDB." + "enableWriterQueue"}
 	config.RegisterBoolConfigVariable(true, &jd.enableWriterQueue, true, enableWriterQueueKeys...)
 	enableReaderQueueKeys := []string{"JobsDB." + jd.tablePrefix + "." + "enableReaderQueue", "JobsDB." + "enableReaderQueue"}
@@ -627,6 +686,20 @@ func (jd *HandleT) workersAndAuxSetup(ownerType OwnerType, tablePrefix string, r
 	config.RegisterIntConfigVariable(1, &jd.maxWriters, false, 1, maxWritersKeys...)
 	maxReadersKeys := []string{"JobsDB." + jd.tablePrefix + "." + "maxReaders", "JobsDB." + "maxReaders"}
 	config.RegisterIntConfigVariable(3, &jd.maxReaders, false, 1, maxReadersKeys...)
+
+	//payloads of datasets created while compressPayload is set are stored compressed,
+	//payloads of the other datasets are read and migrated as they are
+	compressPayloadKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compressPayload", "JobsDB." + "compressPayload"}
+	config.RegisterBoolConfigVariable(false, &jd.compressPayload, false, compressPayloadKeys...)
+	var compressionLevel int
+	var compressionDictionaryPath string
+	compressionLevelKeys := []string{"JobsDB." + jd.tablePrefix + "." + "compressionLevel", "JobsDB." + "compressionLevel"}
+	config.RegisterIntConfigVariable(3, &compressionLevel, false, 1, compressionLevelKeys...)
+	config.RegisterStringConfigVariable("", &compressionDictionaryPath, false, "JobsDB.compressionDictionaryPath")
+	var err error
+	jd.payloadCompressor, err = newPayloadCompressor(compressionLevel, compressionDictionaryPath)
+	jd.assertError(err)
+	jd.compressedTables = make(map[string]bool)
 	jd.initDBWriters()
 	jd.initDBReaders()
 }
@@ -638,9 +711,11 @@ func (jd *HandleT) setUpForOwnerType(ownerType OwnerType, clearAll bool) {
 	case Write:
 		jd.setupDatabaseTables(clearAll)
 		jd.writerSetup()
+		registerRestorableJobsDB(jd)
 	case ReadWrite:
 		jd.setupDatabaseTables(clearAll)
 		jd.readerWriterSetup()
+		registerRestorableJobsDB(jd)
 	}
 }
 
@@ -688,10 +763,16 @@ func (jd *HandleT) readerSetup() {
 }
 
 func (jd *HandleT) writerSetup() {
-	jd.recoverFromJournal(Write)
-	//This is a thread-safe operation.
-	//Even if two different services (gateway and processor) perform this operation, there should not be any problem.
-	jd.recoverFromJournal(ReadWrite)
+	//The journal of a jobsdb shared by nodes is recovered by the owner of its datasets
+	if !jd.sharedByNodes {
+		jd.recoverFromJournal(Write)
+		//This is a thread-safe operation.
+		//Even if two different services (gateway and processor) perform this operation, there should not be any problem.
+		jd.recoverFromJournal(ReadWrite)
+	}
+
+	//Nodes sharing the jobsdb list its datasets and add the first one one at a time
+	lockTxn := jd.lockDatasets(true, dsMigrationAdvisoryLock, dsListAdvisoryLock)
 
 	//Refresh in memory list. We don't take lock
 	//here because this is called before anything
@@ -703,6 +784,7 @@ func (jd *HandleT) writerSetup() {
 	if len(jd.datasetList) == 0 {
 		jd.addNewDS(appendToDsList, dataSetT{})
 	}
+	jd.unlockDatasets(lockTxn)
 
 	rruntime.Go(func() {
 		jd.addNewDSLoop()
@@ -710,7 +792,12 @@ func (jd *HandleT) writerSetup() {
 }
 
 func (jd *HandleT) readerWriterSetup() {
-	jd.recoverFromJournal(Read)
+	if jd.sharedByNodes {
+		//takes over the datasets, recovering the journal, unless another node owns them
+		jd.ownsDatasets()
+	} else {
+		jd.recoverFromJournal(Read)
+	}
 
 	jd.writerSetup()
 
@@ -793,6 +880,9 @@ func (jd *HandleT) dbReader() {
 TearDown releases all the resources
 */
 func (jd *HandleT) TearDown() {
+	if jd.queueStore != nil {
+		jd.queueStore.tearDown()
+	}
 	jd.dbHandle.Close()
 }
 
@@ -1275,18 +1365,23 @@ func (jd *HandleT) createDS(appendLast bool, newDSIdx string) dataSetT {
 	opID := jd.JournalMarkStart(addDSOperation, opPayload)
 
 	//Create the jobs and job_status tables
+	eventPayloadType := "JSONB"
+	if jd.compressPayload {
+		eventPayloadType = "BYTEA"
+	}
 	sqlStatement := fmt.Sprintf(`CREATE TABLE %s (
                                       job_id BIGSERIAL PRIMARY KEY,
 									  uuid UUID NOT NULL,
 									  user_id TEXT NOT NULL,
 									  parameters JSONB NOT NULL,
                                       custom_val VARCHAR(64) NOT NULL,
-                                      event_payload JSONB NOT NULL,
+                                      event_payload %s NOT NULL,
                                       created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-                                      expire_at TIMESTAMP NOT NULL DEFAULT NOW());`, newDS.JobTable)
+                                      expire_at TIMESTAMP NOT NULL DEFAULT NOW());`, newDS.JobTable, eventPayloadType)
 
 	_, err = jd.dbHandle.Exec(sqlStatement)
 	jd.assertError(err)
+	jd.setCompressedTable(newDS.JobTable, jd.compressPayload)
 
 	sqlStatement = fmt.Sprintf(`CREATE TABLE %s (
                                      id BIGSERIAL PRIMARY KEY,
@@ -1603,6 +1698,16 @@ func (jd *HandleT) postMigrateHandleDS(migrateFrom []dataSetT) error {
 	return nil
 }
 
+//startQuerySpan starts the span of a query on table, handling count jobs. Queries handle jobs of many traces, so their spans are roots of their own traces,
+//linked to the spans of the traceparents
+func (jd *HandleT) startQuerySpan(name string, table string, count int, traceParents ...string) tracing.Span {
+	_, span := tracing.StartLinkedClientSpan(context.Background(), name, traceParents,
+		tracing.StringAttribute("db.system", "postgresql"),
+		tracing.StringAttribute("db.sql.table", table),
+		tracing.Int64Attribute("jobs", int64(count)))
+	return span
+}
+
 /*
 Next set of functions are for reading/writing jobs and job_status for
 a given dataset. The names should be self explainatory
@@ -1611,20 +1716,29 @@ func (jd *HandleT) storeJobsDS(ds dataSetT, copyID bool, jobList []*JobT) error
 	queryStat := jd.storeTimerStat("store_jobs")
 	queryStat.Start()
 	defer queryStat.End()
+	traceParents := make([]string, len(jobList))
+	for i, job := range jobList {
+		traceParents[i] = gjson.GetBytes(job.Parameters, tracing.TRACEPARENT).String()
+	}
+	span := jd.startQuerySpan("jobsdb.store_jobs", ds.JobTable, len(jobList), traceParents...)
+	defer span.End()
 
 	txn, err := jd.dbHandle.Begin()
 	if err != nil {
+		tracing.SetError(span, err)
 		return err
 	}
 
 	err = jd.storeJobsDSInTxn(txn, ds, copyID, jobList)
 	if err != nil {
 		txn.Rollback()
+		tracing.SetError(span, err)
 		return err
 	}
 
 	err = txn.Commit()
 	if err != nil {
+		tracing.SetError(span, err)
 		return err
 	}
 
@@ -1706,6 +1820,7 @@ func (jd *HandleT) storeJobsDSInTxn(txHandler transactionHandler, ds dataSetT, c
 	var stmt *sql.Stmt
 	var err error
 
+	compressed := jd.isCompressedTable(ds.JobTable)
 	if copyID {
 		stmt, err = txHandler.Prepare(pq.CopyIn(ds.JobTable, "job_id", "uuid", "user_id", "custom_val", "parameters",
 			"event_payload", "created_at", "expire_at"))
@@ -1719,20 +1834,33 @@ func (jd *HandleT) storeJobsDSInTxn(txHandler transactionHandler, ds dataSetT, c
 
 	defer stmt.Close()
 
+	var payloadBytes, storedPayloadBytes int
 	customValParamMap := make(map[string]map[string]struct{})
 	for _, job := range jobList {
+		payload, err := jd.storedPayload(compressed, job)
+		if err != nil {
+			return err
+		}
 		if copyID {
 			_, err = stmt.Exec(job.JobID, job.UUID, job.UserID, job.CustomVal, string(job.Parameters),
-				string(job.EventPayload), job.CreatedAt, job.ExpireAt)
+				payload, job.CreatedAt, job.ExpireAt)
 		} else {
-			_, err = stmt.Exec(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload))
+			_, err = stmt.Exec(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), payload)
 		}
 		if err != nil {
 			return err
 		}
 
+		payloadBytes += len(job.EventPayload)
+		if compressed {
+			storedPayloadBytes += len(payload.([]byte))
+		} else {
+			storedPayloadBytes += len(job.EventPayload)
+		}
 		jd.populateCustomValParamMap(customValParamMap, job.CustomVal, job.Parameters)
 	}
+	jd.getSizeStat("event_payload_bytes", StatTagsT{Compressed: compressed}).Count(payloadBytes)
+	jd.getSizeStat("stored_event_payload_bytes", StatTagsT{Compressed: compressed}).Count(storedPayloadBytes)
 	if useNewCacheBurst {
 		jd.clearCache(ds, customValParamMap)
 	} else {
@@ -1744,6 +1872,9 @@ func (jd *HandleT) storeJobsDSInTxn(txHandler transactionHandler, ds dataSetT, c
 }
 
 func (jd *HandleT) storeJobDS(ds dataSetT, job *JobT) (err error) {
+	if jd.isCompressedTable(ds.JobTable) {
+		return jd.storeCompressedJobDS(ds, job)
+	}
 	sqlStatement := fmt.Sprintf(`INSERT INTO %s (uuid, user_id, custom_val, parameters, event_payload)
 	                                   VALUES ($1, $2, $3, $4, (regexp_replace($5::text, '\\u0000', '', 'g'))::json) RETURNING job_id`, ds.JobTable)
 	stmt, err := jd.dbHandle.Prepare(sqlStatement)
@@ -1765,6 +1896,28 @@ func (jd *HandleT) storeJobDS(ds dataSetT, job *JobT) (err error) {
 	return
 }
 
+//storeCompressedJobDS stores job in a dataset whose payloads are compressed, which postgres can't validate as json
+func (jd *HandleT) storeCompressedJobDS(ds dataSetT, job *JobT) error {
+	payload, err := jd.storedPayload(true, job)
+	if err != nil {
+		return errors.New("Invalid JSON")
+	}
+	sqlStatement := fmt.Sprintf(`INSERT INTO %s (uuid, user_id, custom_val, parameters, event_payload)
+	                                   VALUES ($1, $2, $3, $4, $5) RETURNING job_id`, ds.JobTable)
+	stmt, err := jd.dbHandle.Prepare(sqlStatement)
+	jd.assertError(err)
+	defer stmt.Close()
+	_, err = stmt.Exec(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), payload)
+	if err != nil {
+		return err
+	}
+	jd.getSizeStat("event_payload_bytes", StatTagsT{Compressed: true}).Count(len(job.EventPayload))
+	jd.getSizeStat("stored_event_payload_bytes", StatTagsT{Compressed: true}).Count(len(payload.([]byte)))
+	//Empty customValFilters means we want to clear for all
+	jd.markClearEmptyResult(ds, []string{}, []string{}, nil, hasJobs, nil)
+	return nil
+}
+
 type cacheValue string
 
 const (
@@ -1908,6 +2061,8 @@ func (jd *HandleT) getProcessedJobsDS(ds dataSetT, getAll bool, limitCount int,
 	queryStat := jd.getTimerStat("processed_ds_time", tags)
 	queryStat.Start()
 	defer queryStat.End()
+	span := jd.startQuerySpan("jobsdb.get_processed_jobs", ds.JobTable, limitCount)
+	defer span.End()
 
 	// We don't reset this in case of error for now, as any error in this function causes panic
 	jd.markClearEmptyResult(ds, stateFilters, customValFilters, parameterFilters, willTryToSet, nil)
@@ -1997,6 +2152,7 @@ func (jd *HandleT) getProcessedJobsDS(ds dataSetT, getAll bool, limitCount int,
 		jd.assertError(err)
 		jobList = append(jobList, &job)
 	}
+	jd.decompressPayloads(jobList)
 
 	result := hasJobs
 	if len(jobList) == 0 {
@@ -2027,6 +2183,8 @@ func (jd *HandleT) getUnprocessedJobsDS(ds dataSetT, order bool, count int, para
 	queryStat := jd.getTimerStat("unprocessed_ds_time", tags)
 	queryStat.Start()
 	defer queryStat.End()
+	span := jd.startQuerySpan("jobsdb.get_unprocessed_jobs", ds.JobTable, count)
+	defer span.End()
 
 	// We don't reset this in case of error for now, as any error in this function causes panic
 	jd.markClearEmptyResult(ds, []string{NotProcessed.State}, customValFilters, parameterFilters, willTryToSet, nil)
@@ -2090,6 +2248,7 @@ func (jd *HandleT) getUnprocessedJobsDS(ds dataSetT, order bool, count int, para
 		jd.assertError(err)
 		jobList = append(jobList, &job)
 	}
+	jd.decompressPayloads(jobList)
 
 	result := hasJobs
 	dsList := jd.getDSList(false)
@@ -2137,6 +2296,11 @@ func (jd *HandleT) updateJobStatusDSInTxn(txHandler transactionHandler, ds dataS
 	queryStat := jd.getTimerStat("update_job_status_ds_time", tags)
 	queryStat.Start()
 	defer queryStat.End()
+	span := jd.startQuerySpan("jobsdb.update_job_status", ds.JobStatusTable, len(statusList))
+	defer func() {
+		tracing.SetError(span, err)
+		span.End()
+	}()
 
 	stmt, err := txHandler.Prepare(pq.CopyIn(ds.JobStatusTable, "job_id", "job_state", "attempt", "exec_time",
 		"retry_time", "error_code", "error_response", "parameters"))
@@ -2199,23 +2363,34 @@ so take both the list and data lock
 func (jd *HandleT) addNewDSLoop() {
 	for {
 		time.Sleep(addNewDSLoopSleepDuration)
+		//Of the nodes sharing the jobsdb, only the owner of the datasets adds them
+		if !jd.ownsDatasets() {
+			continue
+		}
 		jd.logger.Debugf("[[ %s : addNewDSLoop ]]: Start", jd.tablePrefix)
 		jd.dsListLock.RLock()
 		dsList := jd.getDSList(false)
 		jd.dsListLock.RUnlock()
 		latestDS := dsList[len(dsList)-1]
 		if jd.checkIfFullDS(latestDS) {
-			//Adding a new DS updates the list
-			//Doesn't move any data so we only
-			//take the list lock
-			jd.dsListLock.Lock()
 			jd.logger.Infof("[[ %s : addNewDSLoop ]]: NewDS", jd.tablePrefix)
-			jd.addNewDS(appendToDsList, dataSetT{})
-			jd.dsListLock.Unlock()
+			jd.appendNewDS()
 		}
 	}
 }
 
+//appendNewDS adds a dataset for new jobs to the end of the list
+func (jd *HandleT) appendNewDS() {
+	//Adding a new DS updates the list
+	//Doesn't move any data so we only
+	//take the list lock
+	lockTxn := jd.lockDatasets(true, dsListAdvisoryLock)
+	jd.dsListLock.Lock()
+	jd.addNewDS(appendToDsList, dataSetT{})
+	jd.dsListLock.Unlock()
+	jd.unlockDatasets(lockTxn)
+}
+
 func (jd *HandleT) refreshDSListLoop() {
 	for {
 		time.Sleep(refreshDSListLoopSleepDuration)
@@ -2241,6 +2416,11 @@ func (jd *HandleT) migrateDSLoop() {
 			continue
 		}
 
+		//Of the nodes sharing the jobsdb, only the owner of the datasets migrates them
+		if !jd.ownsDatasets() {
+			continue
+		}
+
 		jd.dsListLock.RLock()
 		dsList := jd.getDSList(false)
 		jd.dsListLock.RUnlock()
@@ -2282,6 +2462,7 @@ func (jd *HandleT) migrateDSLoop() {
 		}
 
 		//Take the lock and run actual migration
+		migrationLockTxn := jd.lockDatasets(true, dsMigrationAdvisoryLock)
 		jd.dsMigrationLock.Lock()
 
 		migrationLoopStat := stats.NewTaggedStat("migration_loop", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
@@ -2289,10 +2470,12 @@ func (jd *HandleT) migrateDSLoop() {
 		//Add a temp DS to append to
 		if len(migrateFrom) > 0 {
 			if liveJobCount > 0 {
+				listLockTxn := jd.lockDatasets(true, dsListAdvisoryLock)
 				jd.dsListLock.Lock()
 				migrateTo := jd.addNewDS(insertForMigration, insertBeforeDS)
 				jd.inProgressMigrationTargetDS = &migrateTo
 				jd.dsListLock.Unlock()
+				jd.unlockDatasets(listLockTxn)
 
 				jd.logger.Infof("[[ %s : migrateDSLoop ]]: Migrate from: %v", jd.tablePrefix, migrateFrom)
 				jd.logger.Infof("[[ %s : migrateDSLoop ]]: Next: %v", jd.tablePrefix, insertBeforeDS)
@@ -2313,10 +2496,12 @@ func (jd *HandleT) migrateDSLoop() {
 				}
 
 				if totalJobsMigrated <= 0 {
+					listLockTxn := jd.lockDatasets(true, dsListAdvisoryLock)
 					jd.dsListLock.Lock()
 					jd.dropDS(migrateTo, false)
 					jd.inProgressMigrationTargetDS = nil
 					jd.dsListLock.Unlock()
+					jd.unlockDatasets(listLockTxn)
 				}
 
 				jd.JournalMarkDone(opID)
@@ -2329,14 +2514,17 @@ func (jd *HandleT) migrateDSLoop() {
 			jd.assertError(err)
 			opID := jd.JournalMarkStart(postMigrateDSOperation, opPayload)
 
+			listLockTxn := jd.lockDatasets(true, dsListAdvisoryLock)
 			jd.dsListLock.Lock()
 			jd.postMigrateHandleDS(migrateFrom)
 			jd.dsListLock.Unlock()
+			jd.unlockDatasets(listLockTxn)
 
 			jd.JournalMarkDone(opID)
 		}
 		migrationLoopStat.End()
 		jd.dsMigrationLock.Unlock()
+		jd.unlockDatasets(migrationLockTxn)
 
 	}
 }
@@ -2344,6 +2532,10 @@ func (jd *HandleT) migrateDSLoop() {
 func (jd *HandleT) backupDSLoop() {
 	for {
 		time.Sleep(backupCheckSleepDuration)
+		//Of the nodes sharing the jobsdb, only the owner of the datasets backs them up
+		if !jd.ownsDatasets() {
+			continue
+		}
 		jd.logger.Info("BackupDS check:Start")
 		backupDSRange := jd.getBackupDSRange()
 		// check if non empty dataset is present to backup
@@ -2696,6 +2888,10 @@ func (jd *HandleT) JournalMarkStart(opType string, opPayload json.RawMessage) in
 		opType == dropDSOperation ||
 		opType == RawDataDestUploadOperation, fmt.Sprintf("opType: %s is not a supported op", opType))
 
+	if jd.queueStore != nil {
+		return jd.queueStore.journalMarkStart(opType, opPayload)
+	}
+
 	sqlStatement := fmt.Sprintf(`INSERT INTO %s_journal (operation, done, operation_payload, start_time, owner)
                                        VALUES ($1, $2, $3, $4, $5) RETURNING id`, jd.tablePrefix)
 	stmt, err := jd.dbHandle.Prepare(sqlStatement)
@@ -2712,6 +2908,10 @@ func (jd *HandleT) JournalMarkStart(opType string, opPayload json.RawMessage) in
 
 //JournalMarkDone marks the end of a journal action
 func (jd *HandleT) JournalMarkDone(opID int64) {
+	if jd.queueStore != nil {
+		jd.queueStore.journalMarkDone(opID)
+		return
+	}
 	err := jd.journalMarkDoneInTxn(jd.dbHandle, opID)
 	jd.assertError(err)
 }
@@ -2727,12 +2927,19 @@ func (jd *HandleT) journalMarkDoneInTxn(txHandler transactionHandler, opID int64
 }
 
 func (jd *HandleT) JournalDeleteEntry(opID int64) {
+	if jd.queueStore != nil {
+		jd.queueStore.journalDeleteEntry(opID)
+		return
+	}
 	sqlStatement := fmt.Sprintf(`DELETE FROM %s_journal WHERE id=$1 AND owner=$2`, jd.tablePrefix)
 	_, err := jd.dbHandle.Exec(sqlStatement, opID, jd.ownerType)
 	jd.assertError(err)
 }
 
 func (jd *HandleT) GetJournalEntries(opType string) (entries []JournalEntryT) {
+	if jd.queueStore != nil {
+		return jd.queueStore.getJournalEntries(opType)
+	}
 	sqlStatement := fmt.Sprintf(`SELECT id, operation, done, operation_payload
                                 	FROM %s_journal
                                 	WHERE
@@ -2934,9 +3141,15 @@ func (jd *HandleT) updateJobStatus(statusList []*JobStatusT, customValFilters []
 	queryStat.Start()
 	defer queryStat.End()
 
+	if jd.queueStore != nil {
+		return jd.queueStore.updateJobStatus(statusList)
+	}
+
 	txn, err := jd.dbHandle.Begin()
 	jd.assertError(err)
 
+	lockTxn := jd.lockDatasetsToRead()
+	defer jd.unlockDatasets(lockTxn)
 	//The order of lock is very important. The migrateDSLoop
 	//takes lock in this order so reversing this will cause
 	//deadlocks
@@ -3075,6 +3288,11 @@ func (jd *HandleT) Store(jobList []*JobT) error {
 store call is used to create new Jobs
 */
 func (jd *HandleT) store(jobList []*JobT) error {
+	if jd.queueStore != nil {
+		return jd.queueStore.store(jobList)
+	}
+	lockTxn := jd.lockDatasetsToWrite()
+	defer jd.unlockDatasets(lockTxn)
 	//Only locks the list
 	jd.dsListLock.RLock()
 	defer jd.dsListLock.RUnlock()
@@ -3110,7 +3328,12 @@ func (jd *HandleT) StoreWithRetryEach(jobList []*JobT) map[uuid.UUID]string {
 storeWithRetryEach call is used to create new Jobs. This retries if the bulk store fails and retries for each job returning error messages for jobs failed to store
 */
 func (jd *HandleT) storeWithRetryEach(jobList []*JobT) map[uuid.UUID]string {
+	if jd.queueStore != nil {
+		return jd.queueStore.storeWithRetryEach(jobList)
+	}
 
+	lockTxn := jd.lockDatasetsToWrite()
+	defer jd.unlockDatasets(lockTxn)
 	//Only locks the list
 	jd.dsListLock.RLock()
 	defer jd.dsListLock.RUnlock()
@@ -3183,6 +3406,12 @@ func (jd *HandleT) getUnprocessed(params GetQueryParamsT) []*JobT {
 	queryStat.Start()
 	defer queryStat.End()
 
+	if jd.queueStore != nil {
+		return jd.queueStore.getUnprocessed(params)
+	}
+
+	lockTxn := jd.lockDatasetsToRead()
+	defer jd.unlockDatasets(lockTxn)
 	//The order of lock is very important. The migrateDSLoop
 	//takes lock in this order so reversing this will cause
 	//deadlocks
@@ -3255,6 +3484,10 @@ This is only done during recovery, which happens during the server start.
 So, we don't have to worry about dsEmptyResultCache
 */
 func (jd *HandleT) deleteJobStatus(params GetQueryParamsT) {
+	if jd.queueStore != nil {
+		jd.queueStore.deleteExecuting(params)
+		return
+	}
 	txn, err := jd.dbHandle.Begin()
 	jd.assertError(err)
 
@@ -3279,6 +3512,8 @@ func (jd *HandleT) deleteJobStatusInTxn(txHandler transactionHandler, params Get
 	queryStat.Start()
 	defer queryStat.End()
 
+	lockTxn := jd.lockDatasetsToRead()
+	defer jd.unlockDatasets(lockTxn)
 	//The order of lock is very important. The migrateDSLoop
 	//takes lock in this order so reversing this will cause
 	//deadlocks
@@ -3333,6 +3568,9 @@ func (jd *HandleT) deleteJobStatusDSInTxn(txHandler transactionHandler, ds dataS
 	} else {
 		stateQuery = ""
 	}
+	if len(params.StatusParameterFilters) > 0 {
+		stateQuery += " AND " + constructParameterJSONQuery(jd, ds.JobStatusTable, params.StatusParameterFilters)
+	}
 	if len(customValFilters) > 0 {
 		customValQuery = " WHERE " +
 			constructQuery(jd, fmt.Sprintf("%s.custom_val", ds.JobTable),
@@ -3401,6 +3639,12 @@ func (jd *HandleT) GetProcessed(params GetQueryParamsT) []*JobT {
 	queryStat.Start()
 	defer queryStat.End()
 
+	if jd.queueStore != nil {
+		return jd.queueStore.getProcessed(params)
+	}
+
+	lockTxn := jd.lockDatasetsToRead()
+	defer jd.unlockDatasets(lockTxn)
 	//The order of lock is very important. The migrateDSLoop
 	//takes lock in this order so reversing this will cause
 	//deadlocks
@@ -3655,5 +3899,6 @@ func (jd *HandleT) GetLastJob() *JobT {
 	if err != nil && err != sql.ErrNoRows {
 		jd.assertError(err)
 	}
+	jd.decompressPayloads([]*JobT{&job})
 	return &job
 }
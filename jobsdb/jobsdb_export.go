@@ -202,6 +202,7 @@ func (jd *HandleT) getNonMigratedJobsFromDS(ds dataSetT, count int) ([]*JobT, er
 		}
 		jobList = append(jobList, &job)
 	}
+	jd.decompressPayloads(jobList)
 
 	return jobList, nil
 }
@@ -189,28 +189,28 @@ var sampleTestJob = JobT{
 	CustomVal:    "MOCKDS",
 }
 
-type context struct {
+type testContext struct {
 	mock       sqlmock.Sqlmock
 	db         *sql.DB
 	globalMock sqlmock.Sqlmock
 	globalDB   *sql.DB
 }
 
-func (c *context) Setup() {
+func (c *testContext) Setup() {
 	c.db, c.mock, _ = sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	c.globalDB, c.globalMock, _ = sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 }
 
-func (c *context) Finish() {
+func (c *testContext) Finish() {
 	c.db.Close()
 }
 
 var _ = Describe("jobsdb", func() {
 
-	var c *context
+	var c *testContext
 
 	BeforeEach(func() {
-		c = &context{}
+		c = &testContext{}
 		c.Setup()
 
 		// setup static requirements of dependencies
@@ -278,6 +278,7 @@ var _ = Describe("jobsdb", func() {
 			jd.enableWriterQueue = true
 
 			c.mock.ExpectBegin()
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			stmt := c.mock.ExpectPrepare(fmt.Sprintf(`COPY "%s" ("uuid", "user_id", "custom_val", "parameters", "event_payload") FROM STDIN`, ds.JobTable))
 			for _, job := range mockedStoreJobs {
 				stmt.ExpectExec().WithArgs(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload)).WillReturnResult(sqlmock.NewResult(0, 1))
@@ -295,6 +296,7 @@ var _ = Describe("jobsdb", func() {
 		})
 		It("should store jobs to db directly and not through workers", func() {
 			c.mock.ExpectBegin()
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			stmt := c.mock.ExpectPrepare(fmt.Sprintf(`COPY "%s" ("uuid", "user_id", "custom_val", "parameters", "event_payload") FROM STDIN`, ds.JobTable))
 			for _, job := range mockedStoreJobs {
 				stmt.ExpectExec().WithArgs(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload)).WillReturnResult(sqlmock.NewResult(0, 1))
@@ -339,6 +341,7 @@ var _ = Describe("jobsdb", func() {
 
 		It("should store jobs to db with storeJobsDS", func() {
 			c.mock.ExpectBegin()
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			stmt := c.mock.ExpectPrepare(fmt.Sprintf(`COPY "%s" ("uuid", "user_id", "custom_val", "parameters", "event_payload") FROM STDIN`, ds.JobTable))
 			for _, job := range mockedStoreJobs {
 				stmt.ExpectExec().WithArgs(job.UUID, job.UserID, job.CustomVal, string(job.Parameters), string(job.EventPayload)).WillReturnResult(sqlmock.NewResult(0, 1))
@@ -356,6 +359,7 @@ var _ = Describe("jobsdb", func() {
 		})
 		It("should store jobs to db even when bulk store(storeJobsDS) returns error", func() {
 			c.mock.ExpectBegin().WillReturnError(errors.New("failed to prepare"))
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 
 			for _, job := range mockedStoreJobs {
 				stmt := c.mock.ExpectPrepare(fmt.Sprintf(`INSERT INTO %s (uuid, user_id, custom_val, parameters, event_payload)
@@ -372,6 +376,7 @@ var _ = Describe("jobsdb", func() {
 		})
 		It("should store jobs partially because one job has invalid json payload", func() {
 			c.mock.ExpectBegin().WillReturnError(errors.New("failed to prepare"))
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 
 			job1 := mockedPartiallyStoredJobs[0]
 			job2 := mockedPartiallyStoredJobs[1]
@@ -903,6 +908,11 @@ var mockRows = func() *sqlmock.Rows {
 	return sqlMockRows
 }
 
+var expectPayloadColumnType = func(mock sqlmock.Sqlmock, ds dataSetT, dataType string) {
+	mock.ExpectQuery(`SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = 'event_payload'`).WithArgs(ds.JobTable).
+		WillReturnRows(sqlmock.NewRows([]string{"data_type"}).AddRow(dataType))
+}
+
 var mockJobs = []*JobT{
 	{
 		JobID:        1,
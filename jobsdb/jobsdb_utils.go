@@ -243,26 +243,38 @@ func (handler *JobsdbUtilsHandler) RunSQLQuery(argString string, reply *string)
 }
 
 func (jd *HandleT) getTimerStat(stat string, tags StatTagsT) stats.RudderStats {
+	return stats.NewTaggedStat(stat, stats.TimerType, jd.statTags(tags))
+}
+
+func (jd *HandleT) getSizeStat(stat string, tags StatTagsT) stats.RudderStats {
+	return stats.NewTaggedStat(stat, stats.CountType, jd.statTags(tags))
+}
+
+func (jd *HandleT) statTags(tags StatTagsT) map[string]string {
 	customValTag := strings.Join(tags.CustomValFilters, "_")
 	stateFiltersTag := strings.Join(tags.StateFilters, "_")
 
-	timingTags := map[string]string{
+	statTags := map[string]string{
 		"tablePrefix": jd.tablePrefix,
 	}
 
 	if customValTag != "" {
-		timingTags["customVal"] = customValTag
+		statTags["customVal"] = customValTag
 	}
 
 	if stateFiltersTag != "" {
-		timingTags["stateFilters"] = stateFiltersTag
+		statTags["stateFilters"] = stateFiltersTag
 	}
 
 	for _, paramTag := range tags.ParameterFilters {
-		timingTags[paramTag.Name] = paramTag.Value
+		statTags[paramTag.Name] = paramTag.Value
+	}
+
+	if tags.Compressed {
+		statTags["compressed"] = "true"
 	}
 
-	return stats.NewTaggedStat(stat, stats.TimerType, timingTags)
+	return statTags
 }
 
 func (jd *HandleT) storeTimerStat(stat string) stats.RudderStats {
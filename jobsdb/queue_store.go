@@ -0,0 +1,37 @@
+package jobsdb
+
+import (
+	"encoding/json"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+//Storages a jobsdb can keep its jobs in, selected per jobsdb through JobsDB.<tablePrefix>.storage
+const (
+	POSTGRES_STORAGE = "postgres"
+	BADGER_STORAGE   = "badger"
+)
+
+//EMBEDDED_APP_TYPE is the APP_TYPE of servers running the gateway, processor and routers in one process,
+//the only ones a jobsdb kept in badger can be used by. It matches app.EMBEDDED, which jobsdb can't import
+const EMBEDDED_APP_TYPE = "EMBEDDED"
+
+//queueStoreT is implemented by the storages a jobsdb can keep its jobs in instead of its partitioned postgres tables.
+//Such a jobsdb still uses postgres for global transactions and health checks, but has no datasets,
+//so it is not migrated, backed up or archived
+type queueStoreT interface {
+	store(jobList []*JobT) error
+	storeWithRetryEach(jobList []*JobT) map[uuid.UUID]string
+	updateJobStatus(statusList []*JobStatusT) error
+	getUnprocessed(params GetQueryParamsT) []*JobT
+	getProcessed(params GetQueryParamsT) []*JobT
+	deleteExecuting(params GetQueryParamsT)
+
+	journalMarkStart(opType string, opPayload json.RawMessage) int64
+	journalMarkDone(opID int64)
+	journalDeleteEntry(opID int64)
+	getJournalEntries(opType string) []JournalEntryT
+
+	status() interface{}
+	tearDown()
+}
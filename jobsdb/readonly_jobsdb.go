@@ -7,6 +7,9 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 
 	"time"
@@ -22,8 +25,8 @@ import (
 ReadonlyJobsDB interface contains public methods to access JobsDB data
 */
 type ReadonlyJobsDB interface {
-	GetPendingJobsCount(customValFilters []string, count int, parameterFilters []ParameterFilterT) int64
-	GetUnprocessedCount(customValFilters []string, parameterFilters []ParameterFilterT) int64
+	GetPendingJobsCount(customValFilters []string, count int, parameterFilters []ParameterFilterT) (int64, error)
+	GetUnprocessedCount(customValFilters []string, parameterFilters []ParameterFilterT) (int64, error)
 	GetJobSummaryCount(arg string, prefix string) (string, error)
 	GetLatestFailedJobs(arg string, prefix string) (string, error)
 	GetJobIDsForUser(args []string) (string, error)
@@ -31,12 +34,18 @@ type ReadonlyJobsDB interface {
 	GetDSListString() (string, error)
 	GetJobIDStatus(job_id string, prefix string) (string, error)
 	GetJobByID(job_id string, prefix string) (string, error)
+	GetDSPairs() ([]DSPair, error)
+	GetTracedJobs(filter TraceFilterT) ([]*TracedJobT, error)
+	IsCompressedTable(jobTable string) (bool, error)
+	DecompressPayload(payload []byte) ([]byte, error)
 }
 
 type ReadonlyHandleT struct {
-	DbHandle    *sql.DB
-	tablePrefix string
-	logger      logger.LoggerI
+	DbHandle          *sql.DB
+	tablePrefix       string
+	storage           string
+	logger            logger.LoggerI
+	payloadCompressor *payloadCompressorT
 }
 
 type DSPair struct {
@@ -92,6 +101,7 @@ func (jd *ReadonlyHandleT) Setup(tablePrefix string) {
 	var err error
 	psqlInfo := GetConnectionString()
 	jd.tablePrefix = tablePrefix
+	config.RegisterStringConfigVariable(POSTGRES_STORAGE, &jd.storage, false, "JobsDB."+jd.tablePrefix+"."+"storage")
 
 	jd.DbHandle, err = sql.Open("postgres", psqlInfo)
 	jd.assertError(err)
@@ -99,6 +109,11 @@ func (jd *ReadonlyHandleT) Setup(tablePrefix string) {
 	err = jd.DbHandle.Ping()
 	jd.assertError(err)
 
+	var compressionDictionaryPath string
+	config.RegisterStringConfigVariable("", &compressionDictionaryPath, false, "JobsDB.compressionDictionaryPath")
+	jd.payloadCompressor, err = newPayloadCompressor(0, compressionDictionaryPath)
+	jd.assertError(err)
+
 	jd.logger.Infof("Readonly user connected to %s DB", tablePrefix)
 }
 
@@ -122,6 +137,15 @@ func (jd *ReadonlyHandleT) assert(cond bool, errorString string) {
 	}
 }
 
+//checkStorage returns an error if the jobs of the jobsdb are kept in the badger store of the embedded server,
+//as they are not in postgres for the readonly handle to read
+func (jd *ReadonlyHandleT) checkStorage() error {
+	if jd.storage == BADGER_STORAGE {
+		return fmt.Errorf("%s jobs are stored in badger by the embedded server and can't be read from postgres", jd.tablePrefix)
+	}
+	return nil
+}
+
 /*
 Function to return an ordered list of datasets and datasetRanges
 Most callers use the in-memory list of dataset and datasetRanges
@@ -137,17 +161,23 @@ Count queries
 GetPendingJobsCount returns the count of pending events. Pending events are
 those whose jobs don't have a state or whose jobs status is neither succeeded nor aborted
 */
-func (jd *ReadonlyHandleT) GetPendingJobsCount(customValFilters []string, count int, parameterFilters []ParameterFilterT) int64 {
-	unProcessedCount := jd.GetUnprocessedCount(customValFilters, parameterFilters)
+func (jd *ReadonlyHandleT) GetPendingJobsCount(customValFilters []string, count int, parameterFilters []ParameterFilterT) (int64, error) {
+	unProcessedCount, err := jd.GetUnprocessedCount(customValFilters, parameterFilters)
+	if err != nil {
+		return 0, err
+	}
 	nonSucceededCount := jd.getNonSucceededJobsCount(customValFilters, parameterFilters)
-	return unProcessedCount + nonSucceededCount
+	return unProcessedCount + nonSucceededCount, nil
 }
 
 /*
 GetUnprocessedCount returns the number of unprocessed events. Unprocessed events are
 those whose state hasn't been marked in the DB
 */
-func (jd *ReadonlyHandleT) GetUnprocessedCount(customValFilters []string, parameterFilters []ParameterFilterT) int64 {
+func (jd *ReadonlyHandleT) GetUnprocessedCount(customValFilters []string, parameterFilters []ParameterFilterT) (int64, error) {
+	if err := jd.checkStorage(); err != nil {
+		return 0, err
+	}
 	var queryStat stats.RudderStats
 	statName := ""
 	if len(customValFilters) > 0 {
@@ -164,7 +194,7 @@ func (jd *ReadonlyHandleT) GetUnprocessedCount(customValFilters []string, parame
 		totalCount += count
 	}
 
-	return totalCount
+	return totalCount, nil
 }
 
 func (jd *ReadonlyHandleT) prepareAndExecStmtInTxn(txn *sql.Tx, sqlStatement string) error {
@@ -195,6 +225,17 @@ func (jd *ReadonlyHandleT) getUnprocessedJobsDSCount(ds dataSetT, customValFilte
 	queryStat = stats.NewTaggedStat(statName+"unprocessed_jobs_count", stats.TimerType, stats.Tags{"customVal": jd.tablePrefix})
 	queryStat.Start()
 	defer queryStat.End()
+	// compressed payloads can't be read in postgres, so the events in their batches are counted here
+	countInDB := true
+	if jd.tablePrefix == "gw" {
+		compressed, err := jd.IsCompressedTable(ds.JobTable)
+		if err != nil {
+			jd.logger.Errorf("Returning 0 because failed to check compression of dataset: %v. Err: %w", ds, err)
+			return 0
+		}
+		countInDB = !compressed
+	}
+
 	txn, err := jd.DbHandle.Begin()
 	if err != nil {
 		jd.logger.Errorf("transaction begin err. Dataset: %v. Err: %w", ds, err)
@@ -222,6 +263,9 @@ func (jd *ReadonlyHandleT) getUnprocessedJobsDSCount(ds dataSetT, customValFilte
 	var selectColumn string
 	if jd.tablePrefix == "gw" {
 		selectColumn = "event_payload->'batch' as batch"
+		if !countInDB {
+			selectColumn = "event_payload"
+		}
 	} else {
 		selectColumn = "COUNT(*)"
 	}
@@ -237,15 +281,23 @@ func (jd *ReadonlyHandleT) getUnprocessedJobsDSCount(ds dataSetT, customValFilte
 		sqlStatement += " AND " + constructParameterJSONQuery(jd, ds.JobTable, parameterFilters)
 	}
 
-	if jd.tablePrefix == "gw" {
+	if jd.tablePrefix == "gw" && countInDB {
 		sqlStatement = fmt.Sprintf("select sum(jsonb_array_length(batch)) from (%s) t", sqlStatement)
 	}
 
 	jd.logger.Debug(sqlStatement)
 
-	row := txn.QueryRow(sqlStatement)
-
 	defer txn.Commit()
+	if !countInDB {
+		eventCount, err := jd.countBatchEvents(txn, sqlStatement)
+		if err != nil {
+			jd.logger.Errorf("Returning 0 because failed to fetch unprocessed count from dataset: %v. Err: %w", ds, err)
+			return 0
+		}
+		return eventCount
+	}
+
+	row := txn.QueryRow(sqlStatement)
 
 	var count sql.NullInt64
 	err = row.Scan(&count)
@@ -337,6 +389,17 @@ func (jd *ReadonlyHandleT) getProcessedJobsDSCount(ds dataSetT, stateFilters []s
 		sourceQuery = ""
 	}
 
+	// compressed payloads can't be read in postgres, so the events in their batches are counted here
+	countInDB := true
+	if jd.tablePrefix == "gw" {
+		compressed, err := jd.IsCompressedTable(ds.JobTable)
+		if err != nil {
+			jd.logger.Errorf("Returning 0 because failed to check compression of dataset: %v. Err: %w", ds, err)
+			return 0
+		}
+		countInDB = !compressed
+	}
+
 	txn, err := jd.DbHandle.Begin()
 	if err != nil {
 		jd.logger.Errorf("transaction on ds(%v) begin err. Err: %w", ds, err)
@@ -364,6 +427,9 @@ func (jd *ReadonlyHandleT) getProcessedJobsDSCount(ds dataSetT, stateFilters []s
 	var selectColumn string
 	if jd.tablePrefix == "gw" {
 		selectColumn = fmt.Sprintf("%[1]s.event_payload->'batch' as batch", ds.JobTable)
+		if !countInDB {
+			selectColumn = fmt.Sprintf("%[1]s.event_payload", ds.JobTable)
+		}
 	} else {
 		selectColumn = fmt.Sprintf("COUNT(%[1]s.job_id)", ds.JobTable)
 	}
@@ -377,14 +443,23 @@ func (jd *ReadonlyHandleT) getProcessedJobsDSCount(ds dataSetT, stateFilters []s
                                              AND job_latest_state.retry_time < $1`,
 		ds.JobTable, ds.JobStatusTable, stateQuery, customValQuery, sourceQuery, selectColumn)
 
-	if jd.tablePrefix == "gw" {
+	if jd.tablePrefix == "gw" && countInDB {
 		sqlStatement = fmt.Sprintf("select sum(jsonb_array_length(batch)) from (%s) t", sqlStatement)
 	}
 
 	jd.logger.Debug(sqlStatement)
 
-	row := txn.QueryRow(sqlStatement, time.Now())
 	defer txn.Commit()
+	if !countInDB {
+		eventCount, err := jd.countBatchEvents(txn, sqlStatement, time.Now())
+		if err != nil {
+			jd.logger.Errorf("Returning 0 because failed to fetch processed count from dataset: %v. Err: %w", ds, err)
+			return 0
+		}
+		return eventCount
+	}
+
+	row := txn.QueryRow(sqlStatement, time.Now())
 
 	var count sql.NullInt64
 	err = row.Scan(&count)
@@ -401,6 +476,29 @@ func (jd *ReadonlyHandleT) getProcessedJobsDSCount(ds dataSetT, stateFilters []s
 	return 0
 }
 
+//countBatchEvents returns the number of events in the batches of the gateway jobs whose event_payload is selected by sqlStatement.
+//Compressed payloads can't be read in postgres, so they are decompressed and counted here
+func (jd *ReadonlyHandleT) countBatchEvents(txn *sql.Tx, sqlStatement string, args ...interface{}) (int64, error) {
+	rows, err := txn.Query(sqlStatement, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var payload []byte
+		if err = rows.Scan(&payload); err != nil {
+			return 0, err
+		}
+		if payload, err = jd.payloadCompressor.decompress(payload); err != nil {
+			return 0, err
+		}
+		count += gjson.GetBytes(payload, "batch.#").Int()
+	}
+	return count, rows.Err()
+}
+
 func getStatusPrefix(jobPrefix string) string {
 	var response string
 	switch jobPrefix {
@@ -446,6 +544,9 @@ func getJobPrefix(prefix string) string {
 }
 
 func (jd *ReadonlyHandleT) GetJobSummaryCount(arg string, prefix string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	dsListArr := make([]DSPair, 0)
 	argList := strings.Split(arg, ":")
 	if argList[0] != "" {
@@ -519,6 +620,9 @@ func (jd *ReadonlyHandleT) GetJobSummaryCount(arg string, prefix string) (string
 }
 
 func (jd *ReadonlyHandleT) GetLatestFailedJobs(arg string, prefix string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	var dsList DSPair
 	argList := strings.Split(arg, ":")
 	if argList[0] != "" {
@@ -569,6 +673,9 @@ func (jd *ReadonlyHandleT) GetLatestFailedJobs(arg string, prefix string) (strin
 }
 
 func (jd *ReadonlyHandleT) GetJobByID(job_id string, prefix string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	dsListTotal := jd.getDSList()
 	var response []byte
 	for _, dsPair := range dsListTotal {
@@ -625,6 +732,10 @@ func (jd *ReadonlyHandleT) GetJobByID(job_id string, prefix string) (string, err
 				return "", err1
 			}
 		}
+		event.EventPayload, err = jd.payloadCompressor.decompress(event.EventPayload)
+		if err != nil {
+			return "", err
+		}
 		response, err = json.MarshalIndent(event, "", " ")
 		if err != nil {
 			return "", err
@@ -634,6 +745,9 @@ func (jd *ReadonlyHandleT) GetJobByID(job_id string, prefix string) (string, err
 }
 
 func (jd *ReadonlyHandleT) GetJobIDStatus(job_id string, prefix string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	dsListTotal := jd.getDSList()
 	var response []byte
 	for _, dsPair := range dsListTotal {
@@ -682,6 +796,9 @@ func (jd *ReadonlyHandleT) GetJobIDStatus(job_id string, prefix string) (string,
 }
 
 func (jd *ReadonlyHandleT) GetJobIDsForUser(args []string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	dsListTotal := jd.getDSList()
 	var response string
 	for _, dsPair := range dsListTotal {
@@ -729,6 +846,9 @@ func (jd *ReadonlyHandleT) GetJobIDsForUser(args []string) (string, error) {
 }
 
 func (jd *ReadonlyHandleT) GetFailedStatusErrorCodeCountsByDestination(args []string) (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	var response []byte
 	statusPrefix := getStatusPrefix(args[0])
 	jobPrefix := getJobPrefix(args[0])
@@ -760,7 +880,25 @@ func (jd *ReadonlyHandleT) GetFailedStatusErrorCodeCountsByDestination(args []st
 	return string(response), nil
 }
 
+/*
+GetDSPairs returns the job and job status tables of every dataset, oldest first
+*/
+func (jd *ReadonlyHandleT) GetDSPairs() ([]DSPair, error) {
+	if err := jd.checkStorage(); err != nil {
+		return nil, err
+	}
+	dsList := jd.getDSList()
+	dsPairs := make([]DSPair, 0, len(dsList))
+	for _, ds := range dsList {
+		dsPairs = append(dsPairs, DSPair{JobTableName: ds.JobTable, JobStatusTableName: ds.JobStatusTable})
+	}
+	return dsPairs, nil
+}
+
 func (jd *ReadonlyHandleT) GetDSListString() (string, error) {
+	if err := jd.checkStorage(); err != nil {
+		return "", err
+	}
 	var response string
 	dsList := jd.getDSList()
 	for _, ds := range dsList {
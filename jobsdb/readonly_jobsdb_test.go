@@ -17,10 +17,10 @@ import (
 )
 
 var _ = Describe("readonly_jobsdb", func() {
-	var c *context
+	var c *testContext
 
 	BeforeEach(func() {
-		c = &context{}
+		c = &testContext{}
 		c.Setup()
 		stats.Setup()
 	})
@@ -206,6 +206,7 @@ var _ = Describe("readonly_jobsdb", func() {
 				schemaname != 'information_schema'`).ExpectQuery().WillReturnRows(gwmockTables())
 
 			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -214,6 +215,7 @@ var _ = Describe("readonly_jobsdb", func() {
 			c.mock.ExpectCommit()
 
 			ds = gwDSListInDB[1]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -228,6 +230,40 @@ var _ = Describe("readonly_jobsdb", func() {
 			}
 		})
 
+		It("counts the events in the batches of compressed GW datasets", func() {
+			jd.tablePrefix = "gw"
+			jd.payloadCompressor, _ = newPayloadCompressor(3, "")
+			compressedRows := func() *sqlmock.Rows {
+				rows := sqlmock.NewRows([]string{"event_payload"})
+				for _, job := range mockJobs {
+					payload, err := jd.payloadCompressor.compress(job.EventPayload)
+					Expect(err).NotTo(HaveOccurred())
+					rows.AddRow(payload)
+				}
+				return rows
+			}
+			c.mock.ExpectPrepare(`SELECT tablename
+				FROM pg_catalog.pg_tables
+				WHERE schemaname != 'pg_catalog' AND
+				schemaname != 'information_schema'`).ExpectQuery().WillReturnRows(gwmockTables())
+
+			for _, ds := range gwDSListInDB {
+				expectPayloadColumnType(c.mock, ds, "bytea")
+				c.mock.ExpectBegin()
+				c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+				c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+				c.mock.ExpectQuery(fmt.Sprintf(`SELECT event_payload FROM %[1]s LEFT JOIN %[2]s ON %[1]s.job_id=%[2]s.job_id
+				WHERE %[2]s.job_id is NULL AND ((%[1]s.custom_val='MOCKDS'))`, ds.JobTable, ds.JobStatusTable)).WillReturnRows(compressedRows())
+				c.mock.ExpectCommit()
+			}
+
+			Expect(jd.GetUnprocessedCount([]string{"MOCKDS"}, nil)).To(Equal(int64(2 * len(mockJobs))))
+
+			if err := c.mock.ExpectationsWereMet(); err != nil {
+				ginkgo.Fail(err.Error())
+			}
+		})
+
 		It("returns 0 for a DS and goes to the next if the query is invalid", func() {
 			customValFilters := []string{"MOCKDS"}
 			parameterFilters := []ParameterFilterT{
@@ -374,6 +410,7 @@ var _ = Describe("readonly_jobsdb", func() {
 				schemaname != 'information_schema'`).ExpectQuery().WillReturnRows(gwmockTables())
 
 			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -387,6 +424,7 @@ var _ = Describe("readonly_jobsdb", func() {
 		  AND job_latest_state.retry_time < $1) t`, ds.JobTable, ds.JobStatusTable)).WillReturnRows(mockCountRows())
 
 			ds = gwDSListInDB[1]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -425,6 +463,7 @@ var _ = Describe("readonly_jobsdb", func() {
 			jd.tablePrefix = "gw"
 
 			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -444,10 +483,43 @@ var _ = Describe("readonly_jobsdb", func() {
 			}
 		})
 
+		It("counts the events in the batches of a compressed GW dataset", func() {
+			jd.tablePrefix = "gw"
+			jd.payloadCompressor, _ = newPayloadCompressor(3, "")
+			rows := sqlmock.NewRows([]string{"event_payload"})
+			for _, job := range mockJobs {
+				payload, err := jd.payloadCompressor.compress(job.EventPayload)
+				Expect(err).NotTo(HaveOccurred())
+				rows.AddRow(payload)
+			}
+
+			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "bytea")
+			c.mock.ExpectBegin()
+			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+			c.mock.ExpectQuery(fmt.Sprintf(`SELECT %[1]s.event_payload FROM
+			%[1]s,
+			(SELECT job_id, retry_time FROM %[2]s WHERE id IN
+				(SELECT MAX(id) from %[2]s GROUP BY job_id) )
+			AS job_latest_state
+		 WHERE %[1]s.job_id=job_latest_state.job_id
+
+		  AND job_latest_state.retry_time < $1`, ds.JobTable, ds.JobStatusTable)).WillReturnRows(rows)
+			c.mock.ExpectCommit()
+
+			Expect(jd.getProcessedJobsDSCount(ds, []string{}, []string{}, []ParameterFilterT{})).To(Equal(int64(len(mockJobs))))
+
+			if err := c.mock.ExpectationsWereMet(); err != nil {
+				ginkgo.Fail(err.Error())
+			}
+		})
+
 		It("returns 0 if queries are valid and no jobs are found", func() {
 			jd.tablePrefix = "gw"
 
 			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -471,6 +543,7 @@ var _ = Describe("readonly_jobsdb", func() {
 			jd.tablePrefix = "gw"
 
 			ds := gwDSListInDB[0]
+			expectPayloadColumnType(c.mock, ds, "jsonb")
 			c.mock.ExpectBegin()
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobStatusTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
 			c.mock.ExpectPrepare(fmt.Sprintf(`LOCK TABLE %s IN ACCESS SHARE MODE;`, ds.JobTable)).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
@@ -1546,6 +1619,31 @@ var _ = Describe("readonly_jobsdb", func() {
 			}
 		})
 	})
+
+	Context("badger storage", func() {
+		var jd *ReadonlyHandleT
+
+		BeforeEach(func() {
+			jd = &ReadonlyHandleT{}
+			jd.DbHandle = c.db
+			jd.tablePrefix = `rt`
+			jd.storage = BADGER_STORAGE
+			jd.logger = pkgLogger.Child("readonly-" + jd.tablePrefix)
+		})
+
+		It("reports that the jobs can't be read instead of counting none", func() {
+			_, err := jd.GetPendingJobsCount(nil, -1, nil)
+			Expect(err).To(MatchError(ContainSubstring("rt jobs are stored in badger")))
+			_, err = jd.GetDSListString()
+			Expect(err).To(MatchError(ContainSubstring("rt jobs are stored in badger")))
+			_, err = jd.GetDSPairs()
+			Expect(err).To(MatchError(ContainSubstring("rt jobs are stored in badger")))
+
+			if err := c.mock.ExpectationsWereMet(); err != nil {
+				ginkgo.Fail(err.Error())
+			}
+		})
+	})
 })
 
 var jobTableInMemoryString = func() string {
@@ -0,0 +1,236 @@
+package jobsdb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+/*
+TraceFilterT selects the jobs of SourceID, if set, created between From and To with any of MessageIDs in their parameters.
+Gateway and processor error jobs are also selected by the events in their payloads, with any of MessageIDs, UserID or AnonymousID
+*/
+type TraceFilterT struct {
+	SourceID    string
+	MessageIDs  []string
+	UserID      string
+	AnonymousID string
+	From        time.Time
+	To          time.Time
+	Limit       int
+}
+
+//TracedJobT is a job along with every status it went through, oldest first
+type TracedJobT struct {
+	JobT
+	Statuses []JobStatusT
+}
+
+//PayloadEvents returns the events in the payload of a job of the jobsdb with tablePrefix, if its payload holds events
+func PayloadEvents(tablePrefix string, payload []byte) []gjson.Result {
+	switch tablePrefix {
+	case "gw":
+		return gjson.GetBytes(payload, "batch").Array()
+	case "proc_error":
+		return gjson.ParseBytes(payload).Array()
+	}
+	return nil
+}
+
+//MatchesEvent returns whether event has any of the ids of the filter
+func (filter *TraceFilterT) MatchesEvent(event gjson.Result) bool {
+	if filter.UserID != "" && event.Get("userId").String() == filter.UserID {
+		return true
+	}
+	if filter.AnonymousID != "" && event.Get("anonymousId").String() == filter.AnonymousID {
+		return true
+	}
+	messageID := event.Get("messageId").String()
+	for _, id := range filter.MessageIDs {
+		if messageID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (filter *TraceFilterT) matchesJob(tablePrefix string, job *JobT) bool {
+	if filter.SourceID != "" && gjson.GetBytes(job.Parameters, "source_id").String() != filter.SourceID {
+		return false
+	}
+	messageID := gjson.GetBytes(job.Parameters, "message_id").String()
+	for _, id := range filter.MessageIDs {
+		if messageID == id {
+			return true
+		}
+	}
+	for _, event := range PayloadEvents(tablePrefix, job.EventPayload) {
+		if filter.MatchesEvent(event) {
+			return true
+		}
+	}
+	return false
+}
+
+//payloadContainments returns the jsonb values contained by the payloads of jobs with an event matching the filter
+func (filter *TraceFilterT) payloadContainments(tablePrefix string) []string {
+	var path string
+	switch tablePrefix {
+	case "gw":
+		path = "batch.0."
+	case "proc_error":
+		path = "0."
+	default:
+		return nil
+	}
+	containments := make([]string, 0)
+	addContainment := func(key, value string) {
+		containment, _ := sjson.Set("", path+key, value)
+		containments = append(containments, containment)
+	}
+	for _, messageID := range filter.MessageIDs {
+		addContainment("messageId", messageID)
+	}
+	if filter.UserID != "" {
+		addContainment("userId", filter.UserID)
+	}
+	if filter.AnonymousID != "" {
+		addContainment("anonymousId", filter.AnonymousID)
+	}
+	return containments
+}
+
+//IsCompressedTable returns whether the payloads of a jobs table are stored compressed, in a bytea event_payload column
+func (jd *ReadonlyHandleT) IsCompressedTable(jobTable string) (bool, error) {
+	var dataType string
+	sqlStatement := `SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = 'event_payload'`
+	err := jd.DbHandle.QueryRow(sqlStatement, jobTable).Scan(&dataType)
+	return dataType == "bytea", err
+}
+
+//DecompressPayload returns the json of an event_payload read from a compressed table. Uncompressed payloads are returned as is
+func (jd *ReadonlyHandleT) DecompressPayload(payload []byte) ([]byte, error) {
+	return jd.payloadCompressor.decompress(payload)
+}
+
+/*
+GetTracedJobs returns the jobs matching filter along with their statuses, oldest first.
+Compressed payloads can't be searched in postgres, so every job of compressed datasets created in the time window is read to be matched
+*/
+func (jd *ReadonlyHandleT) GetTracedJobs(filter TraceFilterT) ([]*TracedJobT, error) {
+	if err := jd.checkStorage(); err != nil {
+		return nil, err
+	}
+	tracedJobs := make([]*TracedJobT, 0)
+	for _, ds := range jd.getDSList() {
+		jobs, err := jd.getTracedDSJobs(ds, filter)
+		if err != nil {
+			return nil, err
+		}
+		tracedJobs = append(tracedJobs, jobs...)
+		if filter.Limit > 0 && len(tracedJobs) >= filter.Limit {
+			return tracedJobs[:filter.Limit], nil
+		}
+	}
+	return tracedJobs, nil
+}
+
+func (jd *ReadonlyHandleT) getTracedDSJobs(ds dataSetT, filter TraceFilterT) ([]*TracedJobT, error) {
+	compressed, err := jd.IsCompressedTable(ds.JobTable)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{filter.From, filter.To}
+	var matchConditions []string
+	if len(filter.MessageIDs) > 0 {
+		args = append(args, pq.Array(filter.MessageIDs))
+		matchConditions = append(matchConditions, fmt.Sprintf(`parameters->>'message_id' = ANY($%d)`, len(args)))
+	}
+	containments := filter.payloadContainments(jd.tablePrefix)
+	matchInDB := !compressed || len(containments) == 0
+	if matchInDB {
+		if len(containments) > 0 {
+			args = append(args, pq.Array(containments))
+			matchConditions = append(matchConditions, fmt.Sprintf(`event_payload @> ANY($%d::jsonb[])`, len(args)))
+		}
+		if len(matchConditions) == 0 {
+			return nil, nil
+		}
+	}
+	whereClause := `created_at >= $1 AND created_at <= $2`
+	if filter.SourceID != "" {
+		args = append(args, filter.SourceID)
+		whereClause += fmt.Sprintf(` AND parameters->>'source_id' = $%d`, len(args))
+	}
+	if matchInDB {
+		whereClause += ` AND (` + strings.Join(matchConditions, " OR ") + `)`
+	}
+	sqlStatement := fmt.Sprintf(`SELECT job_id, uuid, user_id, parameters, custom_val, event_payload, created_at, expire_at
+								FROM %s WHERE %s ORDER BY job_id`, ds.JobTable, whereClause)
+	if filter.Limit > 0 && matchInDB {
+		sqlStatement += fmt.Sprintf(` LIMIT %d`, filter.Limit)
+	}
+
+	rows, err := jd.DbHandle.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracedJobs := make([]*TracedJobT, 0)
+	jobsByID := make(map[int64]*TracedJobT)
+	for rows.Next() {
+		var job TracedJobT
+		err = rows.Scan(&job.JobID, &job.UUID, &job.UserID, &job.Parameters, &job.CustomVal, &job.EventPayload, &job.CreatedAt, &job.ExpireAt)
+		if err != nil {
+			return nil, err
+		}
+		if compressed {
+			if job.EventPayload, err = jd.payloadCompressor.decompress(job.EventPayload); err != nil {
+				return nil, err
+			}
+		}
+		if !matchInDB && !filter.matchesJob(jd.tablePrefix, &job.JobT) {
+			continue
+		}
+		tracedJobs = append(tracedJobs, &job)
+		jobsByID[job.JobID] = &job
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tracedJobs) == 0 {
+		return tracedJobs, nil
+	}
+
+	jobIDs := make([]int64, 0, len(tracedJobs))
+	for _, job := range tracedJobs {
+		jobIDs = append(jobIDs, job.JobID)
+	}
+	sqlStatement = fmt.Sprintf(`SELECT job_id, job_state, attempt, exec_time, retry_time, error_code, error_response
+								FROM %s WHERE job_id = ANY($1) ORDER BY id`, ds.JobStatusTable)
+	statusRows, err := jd.DbHandle.Query(sqlStatement, pq.Array(jobIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer statusRows.Close()
+	for statusRows.Next() {
+		var status JobStatusT
+		var errorCode sql.NullString
+		err = statusRows.Scan(&status.JobID, &status.JobState, &status.AttemptNum, &status.ExecTime, &status.RetryTime, &errorCode, &status.ErrorResponse)
+		if err != nil {
+			return nil, err
+		}
+		status.ErrorCode = errorCode.String
+		job := jobsByID[status.JobID]
+		job.Statuses = append(job.Statuses, status)
+		job.LastJobStatus = status
+	}
+	return tracedJobs, statusRows.Err()
+}
@@ -0,0 +1,25 @@
+package jobsdb
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Trace filter", func() {
+	filter := TraceFilterT{SourceID: "s1", MessageIDs: []string{"m1"}, UserID: "u1"}
+
+	It("should search the payloads of gateway and processor error jobs only", func() {
+		Expect(filter.payloadContainments("gw")).To(Equal([]string{`{"batch":[{"messageId":"m1"}]}`, `{"batch":[{"userId":"u1"}]}`}))
+		Expect(filter.payloadContainments("proc_error")).To(Equal([]string{`[{"messageId":"m1"}]`, `[{"userId":"u1"}]`}))
+		Expect(filter.payloadContainments("rt")).To(BeNil())
+	})
+
+	It("should match jobs by their parameters or the events in their payloads", func() {
+		Expect(filter.matchesJob("rt", &JobT{Parameters: []byte(`{"source_id":"s1","message_id":"m1"}`)})).To(BeTrue())
+		Expect(filter.matchesJob("rt", &JobT{Parameters: []byte(`{"source_id":"s2","message_id":"m1"}`)})).To(BeFalse())
+		Expect(filter.matchesJob("rt", &JobT{Parameters: []byte(`{"source_id":"s1","message_id":"m2"}`), EventPayload: []byte(`{"userId":"u1"}`)})).To(BeFalse())
+		Expect(filter.matchesJob("gw", &JobT{Parameters: []byte(`{"source_id":"s1"}`), EventPayload: []byte(`{"batch":[{"messageId":"m2"},{"messageId":"m3","userId":"u1"}]}`)})).To(BeTrue())
+		Expect(filter.matchesJob("gw", &JobT{Parameters: []byte(`{"source_id":"s1"}`), EventPayload: []byte(`{"batch":[{"messageId":"m2","anonymousId":"u1"}]}`)})).To(BeFalse())
+		Expect(filter.matchesJob("proc_error", &JobT{Parameters: []byte(`{"source_id":"s1"}`), EventPayload: []byte(`[{"messageId":"m1"}]`)})).To(BeTrue())
+	})
+})
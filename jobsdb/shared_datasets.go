@@ -0,0 +1,146 @@
+package jobsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+//Advisory locks on the datasets of a jobsdb shared by nodes, keyed along with its table prefix.
+//dsMigrationAdvisoryLock and dsListAdvisoryLock do across the nodes what dsMigrationLock and dsListLock do in a node and are taken in that order.
+//dsOwnerAdvisoryLock is held by the one node adding, migrating and backing up the datasets
+const (
+	dsMigrationAdvisoryLock = "jobsdb_ds_migration"
+	dsListAdvisoryLock      = "jobsdb_ds_list"
+	dsOwnerAdvisoryLock     = "jobsdb_ds_owner"
+)
+
+//IsSharedByNodes returns whether the datasets of the jobsdb are read and written by several nodes, as set by JobsDB.<tablePrefix>.sharedByNodes
+func (jd *HandleT) IsSharedByNodes() bool {
+	return jd.sharedByNodes
+}
+
+//lockDatasets takes advisory locks on the datasets of a jobsdb shared by nodes, in the order given,
+//in a transaction holding them till unlockDatasets. It returns nil if the jobsdb is not shared
+func (jd *HandleT) lockDatasets(exclusive bool, locks ...string) *sql.Tx {
+	if !jd.sharedByNodes {
+		return nil
+	}
+	lockFunction := "pg_advisory_xact_lock_shared"
+	if exclusive {
+		lockFunction = "pg_advisory_xact_lock"
+	}
+	txn, err := jd.dbHandle.Begin()
+	jd.assertError(err)
+	for _, lock := range locks {
+		_, err = txn.Exec(fmt.Sprintf(`SELECT %s(hashtext($1), hashtext($2))`, lockFunction), lock, jd.tablePrefix)
+		jd.assertErrorAndRollbackTx(err, txn)
+	}
+	return txn
+}
+
+func (jd *HandleT) unlockDatasets(txn *sql.Tx) {
+	if txn == nil {
+		return
+	}
+	err := txn.Commit()
+	jd.assertError(err)
+}
+
+//lockDatasetsToWrite takes the advisory lock on the list of datasets of a jobsdb shared by nodes to store jobs,
+//and refreshes the in-memory list so that jobs go to the last dataset added by any node.
+//The caller must not hold dsListLock
+func (jd *HandleT) lockDatasetsToWrite() *sql.Tx {
+	txn := jd.lockDatasets(false, dsListAdvisoryLock)
+	if txn != nil {
+		jd.dsListLock.Lock()
+		jd.getDSList(true)
+		jd.dsListLock.Unlock()
+	}
+	return txn
+}
+
+//lockDatasetsToRead takes the advisory locks on the datasets of a jobsdb shared by nodes to read jobs or update their statuses.
+//As other nodes add and migrate the datasets and store jobs in them, the in-memory lists of datasets are refreshed
+//and the empty result cache is dropped. The caller must not hold dsListLock or dsMigrationLock
+func (jd *HandleT) lockDatasetsToRead() *sql.Tx {
+	txn := jd.lockDatasets(false, dsMigrationAdvisoryLock, dsListAdvisoryLock)
+	if txn != nil {
+		jd.dsListLock.Lock()
+		jd.getDSRangeList(true)
+		jd.dsListLock.Unlock()
+
+		jd.dsCacheLock.Lock()
+		jd.dsEmptyResultCache = map[dataSetT]map[string]map[string]map[string]cacheValue{}
+		jd.dsCacheLock.Unlock()
+	}
+	return txn
+}
+
+//pushDatasetsLock keeps the transaction of advisory locks taken by AcquireStoreLock or AcquireUpdateJobStatusLocks.
+//The locks of the kept transactions are the same, so any of them is released by popDatasetsLock
+func (jd *HandleT) pushDatasetsLock(txn *sql.Tx) {
+	if txn == nil {
+		return
+	}
+	jd.datasetsLockTxnsLock.Lock()
+	defer jd.datasetsLockTxnsLock.Unlock()
+	jd.datasetsLockTxns = append(jd.datasetsLockTxns, txn)
+}
+
+func (jd *HandleT) popDatasetsLock() *sql.Tx {
+	jd.datasetsLockTxnsLock.Lock()
+	defer jd.datasetsLockTxnsLock.Unlock()
+	if len(jd.datasetsLockTxns) == 0 {
+		return nil
+	}
+	txn := jd.datasetsLockTxns[len(jd.datasetsLockTxns)-1]
+	jd.datasetsLockTxns = jd.datasetsLockTxns[:len(jd.datasetsLockTxns)-1]
+	return txn
+}
+
+//ownsDatasets returns whether this handle adds, migrates and backs up the datasets of the jobsdb. Of the handles of a jobsdb
+//shared by nodes, only the one holding a session advisory lock on a dedicated connection does. The lock goes along with the connection,
+//so another node takes over once the owner is gone, first recovering the operations the previous owner left unfinished
+func (jd *HandleT) ownsDatasets() bool {
+	if !jd.sharedByNodes {
+		return true
+	}
+	jd.dsOwnerLock.Lock()
+	defer jd.dsOwnerLock.Unlock()
+
+	ctx := context.Background()
+	if jd.dsOwnerConn != nil {
+		err := jd.dsOwnerConn.PingContext(ctx)
+		if err == nil {
+			return true
+		}
+		jd.logger.Errorf("[[ %s ]]: Lost the ownership of the datasets along with its connection. Err: %v", jd.tablePrefix, err)
+		jd.dsOwnerConn.Close()
+		jd.dsOwnerConn = nil
+	}
+
+	conn, err := jd.dbHandle.Conn(ctx)
+	if err != nil {
+		jd.logger.Errorf("[[ %s ]]: Failed to get a connection to own the datasets. Err: %v", jd.tablePrefix, err)
+		return false
+	}
+	var owner bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1), hashtext($2))`, dsOwnerAdvisoryLock, jd.tablePrefix).Scan(&owner)
+	if err != nil || !owner {
+		conn.Close()
+		return false
+	}
+	jd.dsOwnerConn = conn
+	jd.logger.Infof("[[ %s ]]: Owning the datasets shared by nodes", jd.tablePrefix)
+
+	lockTxn := jd.lockDatasets(true, dsMigrationAdvisoryLock, dsListAdvisoryLock)
+	jd.recoverFromJournal(Read)
+	jd.recoverFromJournal(Write)
+	jd.recoverFromJournal(ReadWrite)
+	jd.dsListLock.Lock()
+	jd.getDSRangeList(true)
+	jd.dsListLock.Unlock()
+	jd.unlockDatasets(lockTxn)
+	return true
+}
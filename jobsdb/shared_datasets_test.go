@@ -0,0 +1,131 @@
+package jobsdb
+
+import (
+	"database/sql"
+	"regexp"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+var _ = Describe("jobsdb shared by nodes", func() {
+	var (
+		db     *sql.DB
+		mock   sqlmock.Sqlmock
+		nodeA  *HandleT
+		nodeB  *HandleT
+		ds1    = dataSetT{JobTable: "tt_jobs_1", JobStatusTable: "tt_job_status_1", Index: "1"}
+		ds2    = dataSetT{JobTable: "tt_jobs_2", JobStatusTable: "tt_job_status_2", Index: "2"}
+		tables = []string{"tt_jobs_1", "tt_job_status_1"}
+	)
+
+	newNode := func() *HandleT {
+		jd := &HandleT{dbHandle: db}
+		jd.workersAndAuxSetup(ReadWrite, "tt", 0*time.Hour, "", false, QueryFiltersT{})
+		jd.sharedByNodes = true
+		jd.enableReaderQueue = false
+		jd.datasetList = []dataSetT{ds1}
+		return jd
+	}
+
+	expectTables := func(tableNames ...string) {
+		rows := sqlmock.NewRows([]string{"tablename"})
+		for _, tableName := range tableNames {
+			rows.AddRow(tableName)
+		}
+		mock.ExpectPrepare(regexp.QuoteMeta(`SELECT tablename`)).ExpectQuery().WillReturnRows(rows)
+	}
+
+	expectJobIDRange := func(ds dataSetT, minJobID, maxJobID interface{}) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT MIN(job_id), MAX(job_id) FROM `+ds.JobTable)).
+			WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(minJobID, maxJobID))
+	}
+
+	expectLock := func(lockFunction, lock string) {
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT `+lockFunction+`(hashtext($1), hashtext($2))`)).
+			WithArgs(lock, "tt").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	BeforeEach(func() {
+		var err error
+		db, mock, err = sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		stats.Setup()
+		nodeA = newNode()
+		nodeB = newNode()
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+		db.Close()
+	})
+
+	It("should let only one node own the datasets", func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock(hashtext($1), hashtext($2))`)).
+			WithArgs(dsOwnerAdvisoryLock, "tt").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectBegin()
+		expectLock("pg_advisory_xact_lock", dsMigrationAdvisoryLock)
+		expectLock("pg_advisory_xact_lock", dsListAdvisoryLock)
+		//the journal left by the previous owner is recovered, for every owner type and go routine
+		for i := 0; i < 9; i++ {
+			mock.ExpectPrepare(regexp.QuoteMeta(`SELECT id, operation, done, operation_payload`)).ExpectQuery().
+				WillReturnRows(sqlmock.NewRows([]string{"id", "operation", "done", "operation_payload"}))
+		}
+		expectTables(tables...)
+		expectJobIDRange(ds1, 1, 10)
+		mock.ExpectCommit()
+		Expect(nodeA.ownsDatasets()).To(BeTrue())
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock(hashtext($1), hashtext($2))`)).
+			WithArgs(dsOwnerAdvisoryLock, "tt").WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		Expect(nodeB.ownsDatasets()).To(BeFalse())
+
+		//the owner keeps the lock as long as its connection is alive
+		Expect(nodeA.ownsDatasets()).To(BeTrue())
+	})
+
+	It("should read the datasets added by another node without the empty results cached before", func() {
+		nodeB.markClearEmptyResult(ds1, []string{NotProcessed.State}, []string{"MOCKDS"}, nil, noJobs, nil)
+
+		//node A adds a dataset holding the list lock
+		mock.ExpectBegin()
+		expectLock("pg_advisory_xact_lock", dsListAdvisoryLock)
+		expectTables(tables...)
+		mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO tt_journal`)).ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE tt_jobs_2`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE tt_job_status_2`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectTables(append(tables, "tt_jobs_2", "tt_job_status_2")...)
+		expectTables(append(tables, "tt_jobs_2", "tt_job_status_2")...)
+		expectJobIDRange(ds1, 1, 10)
+		expectJobIDRange(ds2, nil, nil)
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT setval(pg_get_serial_sequence('tt_jobs_2', 'job_id'), 10)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE tt_journal SET done=$2`)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		nodeA.appendNewDS()
+		Expect(nodeA.getDSList(false)).To(Equal([]dataSetT{ds1, ds2}))
+
+		//node B refreshes its stale list on reading, and reads the dataset it had found empty again
+		mock.ExpectBegin()
+		expectLock("pg_advisory_xact_lock_shared", dsMigrationAdvisoryLock)
+		expectLock("pg_advisory_xact_lock_shared", dsListAdvisoryLock)
+		expectTables(append(tables, "tt_jobs_2", "tt_job_status_2")...)
+		expectJobIDRange(ds1, 1, 10)
+		expectJobIDRange(ds2, 11, 11)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT tt_jobs_1.job_id`)).WillReturnRows(mockUnprocessedJobs(ds1, 0))
+		job := sampleTestJob
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT tt_jobs_2.job_id`)).WillReturnRows(
+			sqlmock.NewRows([]string{"job_id", "uuid", "user_id", "parameters", "custom_val", "event_payload", "created_at", "expire_at"}).
+				AddRow(11, job.UUID, job.UserID, job.Parameters, job.CustomVal, job.EventPayload, time.Now(), time.Now()))
+		mock.ExpectCommit()
+
+		jobs := nodeB.GetUnprocessed(GetQueryParamsT{CustomValFilters: []string{"MOCKDS"}, Count: 10})
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].JobID).To(Equal(int64(11)))
+		Expect(nodeB.getDSList(false)).To(Equal([]dataSetT{ds1, ds2}))
+	})
+})
@@ -28,6 +28,7 @@ import (
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/db"
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types"
@@ -139,6 +140,7 @@ func main() {
 
 	//Creating Stats Client should be done right after setting up logger and before setting up other modules.
 	stats.Setup()
+	tracing.Setup()
 
 	var pollRegulations bool
 	if enableSuppressUserFeature {
@@ -166,6 +168,7 @@ func main() {
 			logger.Log.Sync()
 		}
 		stats.StopRuntimeStats()
+		tracing.Shutdown()
 		os.Exit(1)
 	}()
 
@@ -201,6 +204,7 @@ func startStandbyWebHandler() {
 	srvMux.HandleFunc("/health", standbyHealthHandler)
 	srvMux.HandleFunc("/", standbyHealthHandler)
 	srvMux.HandleFunc("/version", versionHandler)
+	srvMux.HandleFunc("/metrics", stats.MetricsHandler)
 
 	// route everything else to defaultHandler:
 	srvMux.PathPrefix("/").HandlerFunc(standbyDefaultHandler)
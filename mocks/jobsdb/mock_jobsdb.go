@@ -238,6 +238,20 @@ func (mr *MockJobsDBMockRecorder) GetWaiting(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWaiting", reflect.TypeOf((*MockJobsDB)(nil).GetWaiting), arg0)
 }
 
+// IsSharedByNodes mocks base method
+func (m *MockJobsDB) IsSharedByNodes() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSharedByNodes")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsSharedByNodes indicates an expected call of IsSharedByNodes
+func (mr *MockJobsDBMockRecorder) IsSharedByNodes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSharedByNodes", reflect.TypeOf((*MockJobsDB)(nil).IsSharedByNodes))
+}
+
 // JournalDeleteEntry mocks base method
 func (m *MockJobsDB) JournalDeleteEntry(arg0 int64) {
 	m.ctrl.T.Helper()
@@ -6,6 +6,7 @@ package mocks_ratelimiter
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	ratelimiter "github.com/rudderlabs/rudder-server/rate-limiter"
 	reflect "reflect"
 )
 
@@ -33,10 +34,10 @@ func (m *MockRateLimiter) EXPECT() *MockRateLimiterMockRecorder {
 }
 
 // LimitReached mocks base method
-func (m *MockRateLimiter) LimitReached(arg0 string) bool {
+func (m *MockRateLimiter) LimitReached(arg0 ratelimiter.RequestT) ratelimiter.LimitStatusT {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LimitReached", arg0)
-	ret0, _ := ret[0].(bool)
+	ret0, _ := ret[0].(ratelimiter.LimitStatusT)
 	return ret0
 }
 
@@ -5,9 +5,11 @@
 package mock_network
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
 	integrations "github.com/rudderlabs/rudder-server/processor/integrations"
 	reflect "reflect"
+	time "time"
 )
 
 // MockNetHandleI is a mock of NetHandleI interface
@@ -34,16 +36,17 @@ func (m *MockNetHandleI) EXPECT() *MockNetHandleIMockRecorder {
 }
 
 // SendPost mocks base method
-func (m *MockNetHandleI) SendPost(arg0 integrations.PostParametersT) (int, string) {
+func (m *MockNetHandleI) SendPost(arg0 context.Context, arg1 integrations.PostParametersT) (int, string, time.Duration) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SendPost", arg0)
+	ret := m.ctrl.Call(m, "SendPost", arg0, arg1)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(string)
-	return ret0, ret1
+	ret2, _ := ret[2].(time.Duration)
+	return ret0, ret1, ret2
 }
 
 // SendPost indicates an expected call of SendPost
-func (mr *MockNetHandleIMockRecorder) SendPost(arg0 interface{}) *gomock.Call {
+func (mr *MockNetHandleIMockRecorder) SendPost(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPost", reflect.TypeOf((*MockNetHandleI)(nil).SendPost), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPost", reflect.TypeOf((*MockNetHandleI)(nil).SendPost), arg0, arg1)
 }
@@ -33,21 +33,21 @@ func (m *MockDedupI) EXPECT() *MockDedupIMockRecorder {
 }
 
 // FindDuplicates mocks base method
-func (m *MockDedupI) FindDuplicates(arg0 []string, arg1 map[string]struct{}) []int {
+func (m *MockDedupI) FindDuplicates(arg0 string, arg1 []string, arg2 map[string]struct{}) []int {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindDuplicates", arg0, arg1)
+	ret := m.ctrl.Call(m, "FindDuplicates", arg0, arg1, arg2)
 	ret0, _ := ret[0].([]int)
 	return ret0
 }
 
 // FindDuplicates indicates an expected call of FindDuplicates
-func (mr *MockDedupIMockRecorder) FindDuplicates(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockDedupIMockRecorder) FindDuplicates(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicates", reflect.TypeOf((*MockDedupI)(nil).FindDuplicates), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDuplicates", reflect.TypeOf((*MockDedupI)(nil).FindDuplicates), arg0, arg1, arg2)
 }
 
 // MarkProcessed mocks base method
-func (m *MockDedupI) MarkProcessed(arg0 []string) {
+func (m *MockDedupI) MarkProcessed(arg0 map[string][]string) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "MarkProcessed", arg0)
 }
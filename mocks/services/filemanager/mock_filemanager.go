@@ -129,6 +129,21 @@ func (mr *MockFileManagerMockRecorder) GetObjectNameFromLocation(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectNameFromLocation", reflect.TypeOf((*MockFileManager)(nil).GetObjectNameFromLocation), arg0)
 }
 
+// ListFilesWithPrefix mocks base method
+func (m *MockFileManager) ListFilesWithPrefix(arg0 string) ([]*filemanager.FileObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFilesWithPrefix", arg0)
+	ret0, _ := ret[0].([]*filemanager.FileObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFilesWithPrefix indicates an expected call of ListFilesWithPrefix
+func (mr *MockFileManagerMockRecorder) ListFilesWithPrefix(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFilesWithPrefix", reflect.TypeOf((*MockFileManager)(nil).ListFilesWithPrefix), arg0)
+}
+
 // Upload mocks base method
 func (m *MockFileManager) Upload(arg0 *os.File, arg1 ...string) (filemanager.UploadOutput, error) {
 	m.ctrl.T.Helper()
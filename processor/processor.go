@@ -2,6 +2,7 @@ package processor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,7 +30,10 @@ import (
 	"github.com/rudderlabs/rudder-server/rruntime"
 	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
 	transformationdebugger "github.com/rudderlabs/rudder-server/services/debugger/transformation"
+	"github.com/rudderlabs/rudder-server/services/replay"
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/tracing"
+	trackingplan "github.com/rudderlabs/rudder-server/tracking-plan"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -59,6 +63,8 @@ type HandleT struct {
 	batchRouterDB                  jobsdb.JobsDB
 	errorDB                        jobsdb.JobsDB
 	transformer                    transformer.Transformer
+	nativeTransformer              transformer.Transformer
+	userTransformer                transformer.Transformer
 	pStatsJobs                     *misc.PerfStats
 	pStatsDBR                      *misc.PerfStats
 	statGatewayDBR                 stats.RudderStats
@@ -87,6 +93,7 @@ type HandleT struct {
 	logger                         logger.LoggerI
 	eventSchemaHandler             types.EventSchemasI
 	dedupHandler                   dedup.DedupI
+	trackingPlanHandler            *trackingplan.HandleT
 	reporting                      types.ReportingI
 	reportingEnabled               bool
 	transformerFeatures            json.RawMessage
@@ -99,6 +106,9 @@ var defaultTransformerFeatures = `{
 	}
   }`
 
+// trackingPlanStage is the stage of proc errors of events violating the tracking plan of their source
+const trackingPlanStage = "tracking_plan"
+
 var mainLoopTimeout = 200 * time.Millisecond
 var featuresRetryMaxAttempts = 10
 
@@ -121,6 +131,7 @@ type ParametersT struct {
 	SourceTaskRunID string `json:"source_task_run_id"`
 	SourceJobID     string `json:"source_job_id"`
 	SourceJobRunID  string `json:"source_job_run_id"`
+	TraceParent     string `json:"traceparent,omitempty"`
 }
 
 type MetricMetadata struct {
@@ -283,6 +294,10 @@ func (proc *HandleT) Setup(backendConfig backendconfig.BackendConfig, gatewayDB
 	if enableDedup {
 		proc.dedupHandler = dedup.GetInstance(clearDB)
 	}
+	proc.trackingPlanHandler = &trackingplan.HandleT{}
+	rruntime.Go(func() {
+		proc.trackingPlanHandler.FlushViolations()
+	})
 	rruntime.Go(func() {
 		proc.backendConfigSubscriber()
 	})
@@ -292,6 +307,17 @@ func (proc *HandleT) Setup(backendConfig backendconfig.BackendConfig, gatewayDB
 	})
 
 	proc.transformer.Setup()
+	if proc.nativeTransformer == nil {
+		proc.nativeTransformer = transformer.NewNativeTransformer()
+	}
+	proc.nativeTransformer.Setup()
+	proc.userTransformer = proc.transformer
+	if enableEmbeddedUserTransform {
+		transformer.RegisterScriptEngine(transformer.NewGojaEngine())
+		embeddedUserTransformer := transformer.NewEmbeddedUserTransformer(proc.transformer)
+		embeddedUserTransformer.Setup()
+		proc.userTransformer = embeddedUserTransformer
+	}
 
 	proc.crashRecover()
 }
@@ -330,6 +356,8 @@ var (
 	enableEventSchemasFeature           bool
 	enableEventSchemasAPIOnly           bool
 	enableDedup                         bool
+	enableTrackingPlans                 bool
+	enableEmbeddedUserTransform         bool
 	transformTimesPQLength              int
 	captureEventNameStats               bool
 	transformerURL                      string
@@ -346,7 +374,11 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(200, &userTransformBatchSize, true, 1, "Processor.userTransformBatchSize")
 	// Enable dedup of incoming events by default
 	config.RegisterBoolConfigVariable(false, &enableDedup, false, "Dedup.enableDedup")
-	batchDestinations = []string{"S3", "GCS", "MINIO", "RS", "BQ", "AZURE_BLOB", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "DIGITAL_OCEAN_SPACES", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "MARKETO_BULK_UPLOAD"}
+	// Validate events against the tracking plans of their sources. true by default, sources without a tracking plan are not affected
+	config.RegisterBoolConfigVariable(true, &enableTrackingPlans, true, "Processor.enableTrackingPlans")
+	// Run user transformations in process with the registered script engine, falling back to rudder-transformer for unsupported code
+	config.RegisterBoolConfigVariable(false, &enableEmbeddedUserTransform, false, "Processor.embeddedUserTransform.enabled")
+	batchDestinations = []string{"S3", "GCS", "MINIO", "RS", "BQ", "AZURE_BLOB", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "DIGITAL_OCEAN_SPACES", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "MARKETO_BULK_UPLOAD", "DUCKDB", "DELTALAKE"}
 	customDestinations = []string{"KAFKA", "KINESIS", "AZURE_EVENT_HUB", "CONFLUENT_CLOUD"}
 	// EventSchemas feature. false by default
 	config.RegisterBoolConfigVariable(false, &enableEventSchemasFeature, false, "EventSchemas.enableEventSchemasFeature")
@@ -447,6 +479,7 @@ func (proc *HandleT) backendConfigSubscriber() {
 			}
 		}
 		configSubscriberLock.Unlock()
+		proc.trackingPlanHandler.Update(sources.Sources)
 	}
 }
 
@@ -491,6 +524,17 @@ func getSourceByWriteKey(writeKey string) (backendconfig.SourceT, error) {
 	return source, err
 }
 
+//filterDestinations returns the destinations with destinationIDs
+func filterDestinations(destinations []backendconfig.DestinationT, destinationIDs map[string]bool) []backendconfig.DestinationT {
+	filtered := make([]backendconfig.DestinationT, 0)
+	for _, destination := range destinations {
+		if destinationIDs[destination.ID] {
+			filtered = append(filtered, destination)
+		}
+	}
+	return filtered
+}
+
 func getEnabledDestinations(writeKey string, destinationName string) []backendconfig.DestinationT {
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
@@ -590,6 +634,7 @@ func enhanceWithMetadata(commonMetadata *transformer.MetadataT, event *transform
 	metadata.SourceTaskRunID = commonMetadata.SourceTaskRunID
 	metadata.SourceJobID = commonMetadata.SourceJobID
 	metadata.SourceJobRunID = commonMetadata.SourceJobRunID
+	metadata.TraceParent = commonMetadata.TraceParent
 
 	metadata.DestinationID = destination.ID
 	metadata.DestinationType = destination.DestinationDefinition.Name
@@ -663,6 +708,7 @@ func (proc *HandleT) getDestTransformerEvents(response transformer.ResponseT, co
 		eventMetadata.RudderID = userTransformedEvent.Metadata.RudderID
 		eventMetadata.ReceivedAt = userTransformedEvent.Metadata.ReceivedAt
 		eventMetadata.SessionID = userTransformedEvent.Metadata.SessionID
+		eventMetadata.TraceParent = userTransformedEvent.Metadata.TraceParent
 		updatedEvent := transformer.TransformerEventT{
 			Message:     userTransformedEvent.Output,
 			Metadata:    eventMetadata,
@@ -717,6 +763,59 @@ func (proc *HandleT) updateMetricMaps(countMetadataMap map[string]MetricMetadata
 	}
 }
 
+// applyTrackingPlan validates the event against the tracking plan of its source and takes the violation action of the plan.
+// Returns the proc error job to store for the procError action and whether the event is to be removed from the batch
+func (proc *HandleT) applyTrackingPlan(singularEvent types.SingularEventT, batchEvent *jobsdb.JobT, writeKey string) (*jobsdb.JobT, bool) {
+	sourceID := gjson.GetBytes(batchEvent.Parameters, "source_id").Str
+	result := proc.trackingPlanHandler.Validate(sourceID, singularEvent)
+	if !result.Violated {
+		return nil, false
+	}
+
+	proc.stats.NewTaggedStat("processor.tracking_plan_violations", stats.CountType, stats.Tags{
+		"source":       sourceID,
+		"trackingPlan": result.PlanID,
+		"action":       result.Action,
+	}).Increment()
+	proc.trackingPlanHandler.RecordViolation(writeKey, singularEvent)
+
+	switch result.Action {
+	case trackingplan.DropAction:
+		proc.logger.Debugf("Dropping event with messageId: %v violating tracking plan %s", singularEvent["messageId"], result.PlanID)
+		return nil, true
+	case trackingplan.ProcErrorAction:
+		payload, err := json.Marshal([]types.SingularEventT{singularEvent})
+		if err != nil {
+			proc.logger.Errorf("[Processor: applyTrackingPlan] Failed to marshal event violating tracking plan: %v", err)
+			return nil, true
+		}
+		params := map[string]interface{}{
+			"source_id":         sourceID,
+			"destination_id":    "",
+			"source_job_run_id": gjson.GetBytes(payload, "0.context.sources.job_run_id").String(),
+			"error":             string(result.ErrorsJSON()),
+			"status_code":       400,
+			"stage":             trackingPlanStage,
+		}
+		marshalledParams, err := json.Marshal(params)
+		if err != nil {
+			proc.logger.Errorf("[Processor] Failed to marshal parameters. Parameters: %v", params)
+			marshalledParams = []byte(`{"error": "Processor failed to marshal params"}`)
+		}
+		return &jobsdb.JobT{
+			UUID:         uuid.NewV4(),
+			EventPayload: payload,
+			Parameters:   marshalledParams,
+			CreatedAt:    time.Now(),
+			ExpireAt:     time.Now(),
+			UserID:       batchEvent.UserID,
+		}, true
+	default:
+		trackingplan.Annotate(singularEvent, result)
+		return nil, false
+	}
+}
+
 func (proc *HandleT) getFailedEventJobs(response transformer.ResponseT, commonMetaData transformer.MetadataT, eventsByMessageID map[string]types.SingularEventWithReceivedAt, stage string, transformationEnabled bool) ([]*jobsdb.JobT, []*types.PUReportedMetric, map[string]int64) {
 	failedMetrics := make([]*types.PUReportedMetric, 0)
 	connectionDetailsMap := make(map[string]*types.ConnectionDetails)
@@ -886,6 +985,7 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 	var groupedEvents = make(map[string][]transformer.TransformerEventT)
 	var eventsByMessageID = make(map[string]types.SingularEventWithReceivedAt)
 	var procErrorJobsByDestID = make(map[string][]*jobsdb.JobT)
+	var trackingPlanErrorJobs []*jobsdb.JobT
 
 	if !(parsedEventList == nil || len(jobList) == len(parsedEventList)) {
 		panic(fmt.Errorf("parsedEventList != nil and len(jobList):%d != len(parsedEventList):%d", len(jobList), len(parsedEventList)))
@@ -904,6 +1004,7 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 
 	proc.marshalSingularEvents.Start()
 	uniqueMessageIds := make(map[string]struct{})
+	uniqueMessageIdsBySourceID := make(map[string][]string)
 	uniqueMessageIdsBySrcDestKey := make(map[string]map[string]struct{})
 	var sourceDupStats = make(map[string]int)
 
@@ -913,6 +1014,14 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 	connectionDetailsMap := make(map[string]*types.ConnectionDetails)
 	statusDetailsMap := make(map[string]*types.StatusDetail)
 
+	//every gateway job is processed under a span continuing the trace of its request, ended once its router jobs are stored
+	var jobSpans []tracing.Span
+	defer func() {
+		for _, span := range jobSpans {
+			span.End()
+		}
+	}()
+
 	for idx, batchEvent := range jobList {
 
 		var singularEvents []types.SingularEventT
@@ -926,21 +1035,33 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 		writeKey := gjson.Get(string(batchEvent.EventPayload), "writeKey").Str
 		requestIP := gjson.Get(string(batchEvent.EventPayload), "requestIP").Str
 		receivedAt := gjson.Get(string(batchEvent.EventPayload), "receivedAt").Time()
+		//replayed jobs are sent only to the destinations they are replayed to, and were deduped when first received
+		replayDestinationIDs := replay.TargetDestinationIDs(batchEvent.Parameters)
+		dedup := enableDedup && replayDestinationIDs == nil
+		_, jobSpan := tracing.StartSpanFromParameters(context.Background(), "processor.process", batchEvent.Parameters, tracing.Int64Attribute("job.id", batchEvent.JobID))
+		if jobSpan.IsRecording() {
+			jobSpans = append(jobSpans, jobSpan)
+		}
+		traceParent := tracing.Traceparent(jobSpan)
 
 		if ok {
 			var duplicateIndexes []int
-			if enableDedup {
+			var dedupSourceID string
+			if dedup {
 				var allMessageIdsInBatch []string
 				for _, singularEvent := range singularEvents {
 					allMessageIdsInBatch = append(allMessageIdsInBatch, singularEvent["messageId"].(string))
 				}
-				duplicateIndexes = proc.dedupHandler.FindDuplicates(allMessageIdsInBatch, uniqueMessageIds)
+				if source, err := getSourceByWriteKey(writeKey); err == nil {
+					dedupSourceID = source.ID
+				}
+				duplicateIndexes = proc.dedupHandler.FindDuplicates(dedupSourceID, allMessageIdsInBatch, uniqueMessageIds)
 			}
 
 			//Iterate through all the events in the batch
 			for eventIndex, singularEvent := range singularEvents {
 				messageId := singularEvent["messageId"].(string)
-				if enableDedup && misc.Contains(duplicateIndexes, eventIndex) {
+				if dedup && misc.Contains(duplicateIndexes, eventIndex) {
 					proc.logger.Debugf("Dropping event with duplicate messageId: %s", messageId)
 					misc.IncrementMapByKey(sourceDupStats, writeKey, 1)
 					continue
@@ -949,6 +1070,19 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 				proc.updateSourceEventStatsDetailed(singularEvent, writeKey)
 
 				uniqueMessageIds[messageId] = struct{}{}
+				if dedup {
+					uniqueMessageIdsBySourceID[dedupSourceID] = append(uniqueMessageIdsBySourceID[dedupSourceID], messageId)
+				}
+
+				if enableTrackingPlans {
+					violationJob, dropEvent := proc.applyTrackingPlan(singularEvent, batchEvent, writeKey)
+					if violationJob != nil {
+						trackingPlanErrorJobs = append(trackingPlanErrorJobs, violationJob)
+					}
+					if dropEvent {
+						continue
+					}
+				}
 				//We count this as one, not destination specific ones
 				totalEvents++
 				eventsByMessageID[messageId] = types.SingularEventWithReceivedAt{SingularEvent: singularEvent, ReceivedAt: receivedAt}
@@ -972,9 +1106,13 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 				}
 
 				commonMetadataFromSingularEvent := makeCommonMetadataFromSingularEvent(singularEvent, batchEvent, receivedAt, sourceForSingularEvent)
+				commonMetadataFromSingularEvent.TraceParent = traceParent
 				enabledDestinationsMap := map[string][]backendconfig.DestinationT{}
 				for _, destType := range enabledDestTypes {
 					enabledDestinationsList := getEnabledDestinations(writeKey, destType)
+					if replayDestinationIDs != nil {
+						enabledDestinationsList = filterDestinations(enabledDestinationsList, replayDestinationIDs)
+					}
 					enabledDestinationsMap[destType] = enabledDestinationsList
 
 					// Adding a singular event multiple times if there are multiple destinations of same type
@@ -1130,7 +1268,7 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 
 			userTransformationStat.transformTime.Start()
 			startedAt = time.Now()
-			response = proc.transformer.Transform(eventList, integrations.GetUserTransformURL(), userTransformBatchSize)
+			response = proc.userTransformer.Transform(eventList, integrations.GetUserTransformURL(), userTransformBatchSize)
 			endedAt = time.Now()
 			timeTaken = endedAt.Sub(startedAt).Seconds()
 			userTransformationStat.transformTime.End()
@@ -1194,7 +1332,11 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 			response = ConvertToFilteredTransformerResponse(eventsToTransform, transformAt != "none")
 		} else {
 			destTransformationStat.transformTime.Start()
-			response = proc.transformer.Transform(eventsToTransform, url, transformBatchSize)
+			if useNativeTransformer(destination.DestinationDefinition.Name) {
+				response = proc.nativeTransformer.Transform(eventsToTransform, url, transformBatchSize)
+			} else {
+				response = proc.transformer.Transform(eventsToTransform, url, transformBatchSize)
+			}
 			destTransformationStat.transformTime.End()
 			transformAt = "processor"
 		}
@@ -1266,6 +1408,7 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 				SourceTaskRunID: sourceTaskRunId,
 				SourceJobID:     sourceJobId,
 				SourceJobRunID:  sourceJobRunId,
+				TraceParent:     destEvent.Metadata.TraceParent,
 			}
 			marshalledParams, err := json.Marshal(params)
 			if err != nil {
@@ -1344,7 +1487,7 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 		proc.statBatchDestNumOutputEvents.Count(len(batchDestJobs))
 	}
 
-	var procErrorJobs []*jobsdb.JobT
+	procErrorJobs := trackingPlanErrorJobs
 	for _, jobs := range procErrorJobsByDestID {
 		procErrorJobs = append(procErrorJobs, jobs...)
 	}
@@ -1372,12 +1515,8 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 
 	if enableDedup {
 		proc.updateSourceStats(sourceDupStats, "processor.write_key_duplicate_events")
-		if len(uniqueMessageIds) > 0 {
-			var dedupedMessageIdsAcrossJobs []string
-			for k := range uniqueMessageIds {
-				dedupedMessageIdsAcrossJobs = append(dedupedMessageIdsAcrossJobs, k)
-			}
-			proc.dedupHandler.MarkProcessed(dedupedMessageIdsAcrossJobs)
+		if len(uniqueMessageIdsBySourceID) > 0 {
+			proc.dedupHandler.MarkProcessed(uniqueMessageIdsBySourceID)
 		}
 	}
 	proc.gatewayDB.CommitTransaction(txn)
@@ -1399,6 +1538,12 @@ func (proc *HandleT) processJobsForDest(jobList []*jobsdb.JobT, parsedEventList
 	proc.pStatsDBW.Print()
 }
 
+//useNativeTransformer returns whether events of the destination type are transformed in process instead of by rudder-transformer.
+//Enabled per destination type with Processor.<DEST_TYPE>.nativeTransform, for destination types with a go transformer
+func useNativeTransformer(destType string) bool {
+	return config.GetBool("Processor."+destType+".nativeTransform", false) && transformer.HasNativeDestinationTransformer(destType)
+}
+
 func ConvertToFilteredTransformerResponse(events []transformer.TransformerEventT, filterUnsupportedMessageTypes bool) transformer.ResponseT {
 	var responses []transformer.TransformerResponseT
 	var failedEvents []transformer.TransformerResponseT
@@ -32,7 +32,7 @@ import (
 
 var testTimeout = 5 * time.Second
 
-type context struct {
+type testContext struct {
 	asyncHelper       testutils.AsyncTestHelper
 	configInitialised bool
 	dbReadBatchSize   int
@@ -47,7 +47,7 @@ type context struct {
 	MockDedup             *mockDedup.MockDedupI
 }
 
-func (c *context) Setup() {
+func (c *testContext) Setup() {
 	c.mockCtrl = gomock.NewController(GinkgoT())
 	c.mockBackendConfig = mocksBackendConfig.NewMockBackendConfig(c.mockCtrl)
 	c.mockGatewayJobsDB = mocksJobsDB.NewMockJobsDB(c.mockCtrl)
@@ -72,7 +72,7 @@ func (c *context) Setup() {
 	c.MockDedup = mockDedup.NewMockDedupI(c.mockCtrl)
 }
 
-func (c *context) Finish() {
+func (c *testContext) Finish() {
 	c.asyncHelper.WaitWithTimeout(testTimeout)
 	c.mockCtrl.Finish()
 }
@@ -231,12 +231,12 @@ var sampleBackendConfig = backendconfig.ConfigT{
 }
 
 var _ = Describe("Processor", func() {
-	var c *context
+	var c *testContext
 
 	BeforeEach(func() {
 		transformerURL = "http://test"
 
-		c = &context{}
+		c = &testContext{}
 		c.Setup()
 
 		// setup static requirements of dependencies
@@ -725,8 +725,10 @@ var _ = Describe("Processor", func() {
 
 			callRetry := c.mockGatewayJobsDB.EXPECT().GetToRetry(gomock.Any()).Return(toRetryJobsList).Times(1)
 			callUnprocessed := c.mockGatewayJobsDB.EXPECT().GetUnprocessed(gomock.Any()).Return(unprocessedJobsList).Times(1).After(callRetry)
-			c.MockDedup.EXPECT().FindDuplicates(gomock.Any(), gomock.Any()).Return([]int{1}).After(callUnprocessed).Times(2)
-			c.MockDedup.EXPECT().MarkProcessed(gomock.Any()).Times(1)
+			c.MockDedup.EXPECT().FindDuplicates(SourceIDEnabled, gomock.Any(), gomock.Any()).Return([]int{1}).After(callUnprocessed).Times(2)
+			c.MockDedup.EXPECT().MarkProcessed(gomock.Any()).Times(1).Do(func(messageIDsBySourceID map[string][]string) {
+				Expect(messageIDsBySourceID).To(HaveKey(SourceIDEnabled))
+			})
 
 			// We expect one transform call to destination A, after callUnprocessed.
 			mockTransformer.EXPECT().Transform(gomock.Any(), gomock.Any(), gomock.Any()).Times(0).After(callUnprocessed)
@@ -1552,12 +1554,12 @@ func assertDestinationTransform(messages map[string]mockEventData, destinationID
 	}
 }
 
-func processorSetupAndAssertJobHandling(processor *HandleT, c *context, enableDedup, enableReporting bool) {
+func processorSetupAndAssertJobHandling(processor *HandleT, c *testContext, enableDedup, enableReporting bool) {
 	Setup(processor, c, enableDedup, enableReporting)
 	handlePendingGatewayJobs(processor)
 }
 
-func Setup(processor *HandleT, c *context, enableDedup, enableReporting bool) {
+func Setup(processor *HandleT, c *testContext, enableDedup, enableReporting bool) {
 	var clearDB = false
 	SetDisableDedupFeature(enableDedup)
 	processor.Setup(c.mockBackendConfig, c.mockGatewayJobsDB, c.mockRouterJobsDB, c.mockBatchRouterJobsDB, c.mockProcErrorsDB, &clearDB, c.MockReportingI)
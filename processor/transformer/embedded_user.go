@@ -0,0 +1,131 @@
+package transformer
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+//ErrUnsupportedScript is returned by script engines for code they can't run in process,
+//e.g. code using APIs that are not available in the sandbox. Such code is run by rudder-transformer instead
+var ErrUnsupportedScript = errors.New("user transformation code is not supported by the embedded script engine")
+
+//ScriptT is the code of a user transformation, defining transformEvent or transformBatch,
+//and the libraries of the workspace it can import
+type ScriptT struct {
+	VersionID string
+	Language  string
+	Code      string
+	Libraries backendconfig.LibrariesT
+}
+
+//LimitsT are the resources a single invocation of a user transformation may use
+type LimitsT struct {
+	Timeout          time.Duration
+	MaxCallStackSize int
+}
+
+//ScriptEngineI runs user transformations in a sandbox in process.
+//Implementations enforce the limits on every invocation and return a response per output event, in the shape of rudder-transformer
+type ScriptEngineI interface {
+	Transform(script ScriptT, events []TransformerEventT, limits LimitsT) ([]TransformerResponseT, error)
+}
+
+var (
+	scriptEngine                ScriptEngineI
+	scriptEngineLock            sync.RWMutex
+	userTransformTimeout          time.Duration
+	userTransformMaxCallStackSize int
+)
+
+//RegisterScriptEngine sets the engine user transformations are run with in process
+func RegisterScriptEngine(engine ScriptEngineI) {
+	scriptEngineLock.Lock()
+	defer scriptEngineLock.Unlock()
+	scriptEngine = engine
+}
+
+func getScriptEngine() ScriptEngineI {
+	scriptEngineLock.RLock()
+	defer scriptEngineLock.RUnlock()
+	return scriptEngine
+}
+
+//EmbeddedUserHandleT runs user transformations with the registered script engine,
+//falling back to the remote transformer when there is no engine or the engine can't run the code
+type EmbeddedUserHandleT struct {
+	remote        Transformer
+	fallbackStat  stats.RudderStats
+	embeddedStat  stats.RudderStats
+	transformStat stats.RudderStats
+	logger        logger.LoggerI
+}
+
+//NewEmbeddedUserTransformer creates a user transformer falling back to remote, which is expected to be set up already
+func NewEmbeddedUserTransformer(remote Transformer) *EmbeddedUserHandleT {
+	return &EmbeddedUserHandleT{remote: remote}
+}
+
+//Setup initializes this class
+func (trans *EmbeddedUserHandleT) Setup() {
+	trans.logger = pkgLogger.Child("embedded")
+	trans.fallbackStat = stats.NewStat("processor.embedded_user_transform_fallbacks", stats.CountType)
+	trans.embeddedStat = stats.NewStat("processor.embedded_user_transform_events", stats.CountType)
+	trans.transformStat = stats.NewStat("processor.embedded_user_transform_time", stats.TimerType)
+}
+
+//Transform runs the user transformation of the destination of the events.
+//The processor calls it with events of a single destination, so they all share the same transformation
+func (trans *EmbeddedUserHandleT) Transform(clientEvents []TransformerEventT, url string, batchSize int) ResponseT {
+	engine := getScriptEngine()
+	if engine == nil || len(clientEvents) == 0 || len(clientEvents[0].Destination.Transformations) == 0 {
+		return trans.remote.Transform(clientEvents, url, batchSize)
+	}
+
+	transformation := clientEvents[0].Destination.Transformations[0]
+	script := ScriptT{
+		VersionID: transformation.VersionID,
+		Libraries: clientEvents[0].Libraries,
+	}
+	script.Code, _ = transformation.Config["code"].(string)
+	script.Language, _ = transformation.Config["language"].(string)
+	if script.Language == "" {
+		script.Language = "javascript"
+	}
+	if script.Code == "" || script.Language != "javascript" {
+		trans.fallbackStat.Increment()
+		return trans.remote.Transform(clientEvents, url, batchSize)
+	}
+
+	trans.transformStat.Start()
+	responses, err := engine.Transform(script, clientEvents, LimitsT{Timeout: userTransformTimeout, MaxCallStackSize: userTransformMaxCallStackSize})
+	trans.transformStat.End()
+	if err == ErrUnsupportedScript {
+		trans.logger.Debugf("Running transformation version %s with remote transformer: %v", script.VersionID, err)
+		trans.fallbackStat.Increment()
+		return trans.remote.Transform(clientEvents, url, batchSize)
+	}
+	if err != nil {
+		// the whole invocation failed, e.g. limits were exceeded, so every event of it fails as with rudder-transformer
+		responses = make([]TransformerResponseT, 0, len(clientEvents))
+		for _, event := range clientEvents {
+			responses = append(responses, TransformerResponseT{Metadata: event.Metadata, StatusCode: http.StatusBadRequest, Error: err.Error()})
+		}
+	}
+	trans.embeddedStat.Count(len(clientEvents))
+
+	var response ResponseT
+	for _, transformerResponse := range responses {
+		if transformerResponse.StatusCode != http.StatusOK {
+			response.FailedEvents = append(response.FailedEvents, transformerResponse)
+			continue
+		}
+		response.Events = append(response.Events, transformerResponse)
+	}
+	return response
+}
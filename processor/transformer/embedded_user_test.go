@@ -0,0 +1,133 @@
+package transformer_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	mocksTransformer "github.com/rudderlabs/rudder-server/mocks/processor/transformer"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+const userTransformURL = "http://transformer/customTransform"
+
+type fakeScriptEngine struct {
+	err     error
+	scripts []transformer.ScriptT
+	limits  []transformer.LimitsT
+}
+
+func (engine *fakeScriptEngine) Transform(script transformer.ScriptT, events []transformer.TransformerEventT, limits transformer.LimitsT) ([]transformer.TransformerResponseT, error) {
+	engine.scripts = append(engine.scripts, script)
+	engine.limits = append(engine.limits, limits)
+	if engine.err != nil {
+		return nil, engine.err
+	}
+	responses := make([]transformer.TransformerResponseT, 0, len(events))
+	for _, event := range events {
+		if event.Message["drop"] == true {
+			responses = append(responses, transformer.TransformerResponseT{Metadata: event.Metadata, StatusCode: http.StatusBadRequest, Error: "dropped"})
+			continue
+		}
+		responses = append(responses, transformer.TransformerResponseT{Output: event.Message, Metadata: event.Metadata, StatusCode: http.StatusOK})
+	}
+	return responses, nil
+}
+
+func userTransformEvents(transformationConfig map[string]interface{}) []transformer.TransformerEventT {
+	destination := backendconfig.DestinationT{
+		ID:              "destination-id",
+		Transformations: []backendconfig.TransformationT{{VersionID: "version-id", Config: transformationConfig}},
+	}
+	libraries := []backendconfig.LibraryT{{VersionID: "library-version-id"}}
+	return []transformer.TransformerEventT{
+		{Message: types.SingularEventT{"messageId": "message-1"}, Metadata: transformer.MetadataT{MessageID: "message-1"}, Destination: destination, Libraries: libraries},
+		{Message: types.SingularEventT{"messageId": "message-2", "drop": true}, Metadata: transformer.MetadataT{MessageID: "message-2"}, Destination: destination, Libraries: libraries},
+	}
+}
+
+var _ = Describe("EmbeddedUserTransformer", func() {
+	var (
+		mockCtrl          *gomock.Controller
+		mockRemote        *mocksTransformer.MockTransformer
+		engine            *fakeScriptEngine
+		embeddedTransform *transformer.EmbeddedUserHandleT
+		remoteResponse    transformer.ResponseT
+	)
+
+	BeforeEach(func() {
+		stats.Setup()
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockRemote = mocksTransformer.NewMockTransformer(mockCtrl)
+		engine = &fakeScriptEngine{}
+		transformer.RegisterScriptEngine(engine)
+		embeddedTransform = transformer.NewEmbeddedUserTransformer(mockRemote)
+		embeddedTransform.Setup()
+		remoteResponse = transformer.ResponseT{Events: []transformer.TransformerResponseT{{StatusCode: http.StatusOK}}}
+	})
+
+	AfterEach(func() {
+		transformer.RegisterScriptEngine(nil)
+		mockCtrl.Finish()
+	})
+
+	It("should run javascript transformations with the script engine", func() {
+		events := userTransformEvents(map[string]interface{}{"code": "export function transformEvent(event) { return event; }"})
+
+		response := embeddedTransform.Transform(events, userTransformURL, 200)
+		Expect(response.Events).To(HaveLen(1))
+		Expect(response.Events[0].Metadata.MessageID).To(Equal("message-1"))
+		Expect(response.FailedEvents).To(HaveLen(1))
+		Expect(response.FailedEvents[0].Error).To(Equal("dropped"))
+
+		Expect(engine.scripts).To(HaveLen(1))
+		Expect(engine.scripts[0].VersionID).To(Equal("version-id"))
+		Expect(engine.scripts[0].Language).To(Equal("javascript"))
+		Expect(engine.scripts[0].Libraries).To(Equal(backendconfig.LibrariesT(events[0].Libraries)))
+		Expect(engine.limits[0].Timeout).To(BeNumerically(">", 0))
+		Expect(engine.limits[0].MaxCallStackSize).To(BeNumerically(">", 0))
+	})
+
+	It("should fail every event of an invocation that fails", func() {
+		engine.err = errors.New("memory limit exceeded")
+
+		response := embeddedTransform.Transform(userTransformEvents(map[string]interface{}{"code": "code"}), userTransformURL, 200)
+		Expect(response.Events).To(BeEmpty())
+		Expect(response.FailedEvents).To(HaveLen(2))
+		Expect(response.FailedEvents[1].Error).To(Equal("memory limit exceeded"))
+		Expect(response.FailedEvents[1].StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should fall back to the remote transformer for code the engine doesn't support", func() {
+		engine.err = transformer.ErrUnsupportedScript
+		events := userTransformEvents(map[string]interface{}{"code": "import fetch from 'node-fetch';"})
+		mockRemote.EXPECT().Transform(events, userTransformURL, 200).Return(remoteResponse).Times(1)
+
+		Expect(embeddedTransform.Transform(events, userTransformURL, 200)).To(Equal(remoteResponse))
+	})
+
+	It("should fall back to the remote transformer for transformations without javascript code", func() {
+		events := userTransformEvents(map[string]interface{}{"code": "def transformEvent(event): return event", "language": "python"})
+		noCodeEvents := userTransformEvents(map[string]interface{}{})
+		mockRemote.EXPECT().Transform(events, userTransformURL, 200).Return(remoteResponse).Times(1)
+		mockRemote.EXPECT().Transform(noCodeEvents, userTransformURL, 200).Return(remoteResponse).Times(1)
+
+		Expect(embeddedTransform.Transform(events, userTransformURL, 200)).To(Equal(remoteResponse))
+		Expect(embeddedTransform.Transform(noCodeEvents, userTransformURL, 200)).To(Equal(remoteResponse))
+		Expect(engine.scripts).To(BeEmpty())
+	})
+
+	It("should use the remote transformer when no script engine is registered", func() {
+		transformer.RegisterScriptEngine(nil)
+		events := userTransformEvents(map[string]interface{}{"code": "code"})
+		mockRemote.EXPECT().Transform(events, userTransformURL, 200).Return(remoteResponse).Times(1)
+
+		Expect(embeddedTransform.Transform(events, userTransformURL, 200)).To(Equal(remoteResponse))
+	})
+})
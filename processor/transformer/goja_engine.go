@@ -0,0 +1,267 @@
+package transformer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+var (
+	errScriptTimeout       = errors.New("user transformation timed out")
+	errScriptCallStackSize = errors.New("user transformation exceeded the maximum call stack size")
+	errScriptNoTransformFn = errors.New("transformEvent or transformBatch is not defined by the user transformation")
+
+	// libraries are only known by their version in the workspace config, so imports can only be resolved by rudder-transformer.
+	// fetch and the like are apis of rudder-transformer which aren't available in the sandbox
+	unsupportedScriptRegex = regexp.MustCompile(`(?m)^\s*import\s|\b(require|fetch|fetchV2)\s*\(`)
+	exportRegex            = regexp.MustCompile(`(?m)^(\s*)export\s+(default\s+)?`)
+)
+
+type compiledScriptT struct {
+	code    string
+	program *goja.Program
+	err     error
+}
+
+//GojaEngineT runs javascript user transformations in process with goja, a new runtime for every invocation.
+//Programs are compiled once per transformation version
+type GojaEngineT struct {
+	programs     map[string]*compiledScriptT
+	programsLock sync.Mutex
+}
+
+//NewGojaEngine creates a script engine running javascript with goja
+func NewGojaEngine() *GojaEngineT {
+	return &GojaEngineT{programs: make(map[string]*compiledScriptT)}
+}
+
+func (engine *GojaEngineT) compile(script ScriptT) (*goja.Program, error) {
+	engine.programsLock.Lock()
+	defer engine.programsLock.Unlock()
+	compiled, ok := engine.programs[script.VersionID]
+	if ok && compiled.code == script.Code {
+		return compiled.program, compiled.err
+	}
+
+	compiled = &compiledScriptT{code: script.Code}
+	if unsupportedScriptRegex.MatchString(script.Code) {
+		compiled.err = ErrUnsupportedScript
+	} else {
+		// transformations are es modules exporting their functions, which goja can't load, so they are run as scripts
+		code := exportRegex.ReplaceAllString(script.Code, "${1}")
+		compiled.program, compiled.err = goja.Compile(script.VersionID, code, false)
+		if compiled.err != nil {
+			// syntax goja doesn't support, e.g. async functions, or code rudder-transformer reports the error of
+			compiled.err = ErrUnsupportedScript
+		}
+	}
+	if script.VersionID != "" {
+		engine.programs[script.VersionID] = compiled
+	}
+	return compiled.program, compiled.err
+}
+
+//Transform runs the transformEvent function of the script on every event, or its transformBatch function on all of them.
+//An error is returned when the invocation exceeds the limits, in which case every event fails.
+//goja has no heap accounting per runtime, so the memory of an invocation is only bounded by its timeout and call stack size
+func (engine *GojaEngineT) Transform(script ScriptT, events []TransformerEventT, limits LimitsT) ([]TransformerResponseT, error) {
+	program, err := engine.compile(script)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := goja.New()
+	if limits.MaxCallStackSize > 0 {
+		vm.SetMaxCallStackSize(limits.MaxCallStackSize)
+	}
+	if limits.Timeout > 0 {
+		timer := time.AfterFunc(limits.Timeout, func() {
+			vm.Interrupt(errScriptTimeout)
+		})
+		defer timer.Stop()
+	}
+
+	responses, err := runScript(vm, program, events)
+	if interrupted, ok := err.(*goja.InterruptedError); ok {
+		if limitErr, ok := interrupted.Value().(error); ok {
+			return nil, limitErr
+		}
+	}
+	return responses, err
+}
+
+func runScript(vm *goja.Runtime, program *goja.Program, events []TransformerEventT) ([]TransformerResponseT, error) {
+	err := vm.Set("log", func(goja.FunctionCall) goja.Value { return goja.Undefined() })
+	if err != nil {
+		return nil, err
+	}
+	_, err = vm.RunProgram(program)
+	if _, ok := err.(*goja.StackOverflowError); ok {
+		return nil, errScriptCallStackSize
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jsonObject := vm.Get("JSON").ToObject(vm)
+	parse, _ := goja.AssertFunction(jsonObject.Get("parse"))
+	stringify, _ := goja.AssertFunction(jsonObject.Get("stringify"))
+	toJS := func(v interface{}) (goja.Value, error) {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return parse(goja.Undefined(), vm.ToValue(string(raw)))
+	}
+	fromJS := func(v goja.Value) (interface{}, error) {
+		raw, err := stringify(goja.Undefined(), v)
+		if err != nil {
+			return nil, err
+		}
+		var output interface{}
+		if goja.IsUndefined(raw) {
+			return nil, nil
+		}
+		err = json.Unmarshal([]byte(raw.String()), &output)
+		return output, err
+	}
+
+	if transformBatch, ok := goja.AssertFunction(vm.Get("transformBatch")); ok {
+		return runTransformBatch(vm, transformBatch, events, toJS, fromJS)
+	}
+	transformEvent, ok := goja.AssertFunction(vm.Get("transformEvent"))
+	if !ok {
+		return nil, errScriptNoTransformFn
+	}
+
+	responses := make([]TransformerResponseT, 0, len(events))
+	for _, event := range events {
+		metadata := event.Metadata
+		outputs, err := callTransform(vm, transformEvent, event.Message, func(goja.Value) interface{} { return metadata }, toJS, fromJS)
+		if err != nil {
+			if isInvocationError(err) {
+				return nil, err
+			}
+			responses = append(responses, TransformerResponseT{Metadata: metadata, StatusCode: http.StatusBadRequest, Error: err.Error()})
+			continue
+		}
+		for _, output := range outputs {
+			responses = append(responses, TransformerResponseT{Output: output, Metadata: metadata, StatusCode: http.StatusOK})
+		}
+	}
+	return responses, nil
+}
+
+//runTransformBatch calls transformBatch with all events. Outputs get the metadata of the input event with their messageId,
+//or else the metadata of the first event with the message ids of the whole batch, like rudder-transformer
+func runTransformBatch(vm *goja.Runtime, transformBatch goja.Callable, events []TransformerEventT, toJS func(interface{}) (goja.Value, error), fromJS func(goja.Value) (interface{}, error)) ([]TransformerResponseT, error) {
+	messages := make([]interface{}, 0, len(events))
+	metadataByMessageID := make(map[string]MetadataT, len(events))
+	messageIDs := make([]string, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, event.Message)
+		metadataByMessageID[event.Metadata.MessageID] = event.Metadata
+		messageIDs = append(messageIDs, event.Metadata.MessageID)
+	}
+	groupMetadata := events[0].Metadata
+	groupMetadata.MessageIDs = messageIDs
+	metadataOf := func(output map[string]interface{}) MetadataT {
+		if messageID, ok := output["messageId"].(string); ok {
+			if metadata, ok := metadataByMessageID[messageID]; ok {
+				return metadata
+			}
+		}
+		return groupMetadata
+	}
+
+	outputs, err := callTransform(vm, transformBatch, messages, func(event goja.Value) interface{} {
+		exported, _ := event.Export().(map[string]interface{})
+		return metadataOf(exported)
+	}, toJS, fromJS)
+	if err != nil {
+		if isInvocationError(err) {
+			return nil, err
+		}
+		responses := make([]TransformerResponseT, 0, len(events))
+		for _, event := range events {
+			responses = append(responses, TransformerResponseT{Metadata: event.Metadata, StatusCode: http.StatusBadRequest, Error: err.Error()})
+		}
+		return responses, nil
+	}
+
+	responses := make([]TransformerResponseT, 0, len(outputs))
+	for _, output := range outputs {
+		responses = append(responses, TransformerResponseT{Output: output, Metadata: metadataOf(output), StatusCode: http.StatusOK})
+	}
+	return responses, nil
+}
+
+//isInvocationError is true for errors failing the whole invocation, the runtime being interrupted or unwound by goja
+func isInvocationError(err error) bool {
+	_, interrupted := err.(*goja.InterruptedError)
+	return interrupted || err == errScriptCallStackSize
+}
+
+//callTransform calls a transform function with the input and a metadata function, returning the events it returned.
+//null and undefined drop the input, and arrays are many outputs
+func callTransform(vm *goja.Runtime, transform goja.Callable, input interface{}, metadataOf func(goja.Value) interface{}, toJS func(interface{}) (goja.Value, error), fromJS func(goja.Value) (interface{}, error)) ([]map[string]interface{}, error) {
+	jsInput, err := toJS(input)
+	if err != nil {
+		return nil, err
+	}
+	var metadataErr error
+	metadataFn := vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		metadata, err := toJS(metadataOf(call.Argument(0)))
+		if err != nil {
+			metadataErr = err
+			return goja.Undefined()
+		}
+		return metadata
+	})
+
+	result, err := transform(goja.Undefined(), jsInput, metadataFn)
+	if _, ok := err.(*goja.StackOverflowError); ok {
+		return nil, errScriptCallStackSize
+	}
+	if exception, ok := err.(*goja.Exception); ok {
+		return nil, errors.New(exception.Value().String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if metadataErr != nil {
+		return nil, metadataErr
+	}
+	output, err := fromJS(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []interface{}
+	switch output := output.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		outputs = output
+	default:
+		outputs = []interface{}{output}
+	}
+	events := make([]map[string]interface{}, 0, len(outputs))
+	for _, output := range outputs {
+		if output == nil {
+			continue
+		}
+		event, ok := output.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("returned event in events from user transformation is not an object: %v", output)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
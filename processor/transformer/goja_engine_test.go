@@ -0,0 +1,130 @@
+package transformer_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+var _ = Describe("GojaEngine", func() {
+	var (
+		engine *transformer.GojaEngineT
+		limits transformer.LimitsT
+		events []transformer.TransformerEventT
+	)
+
+	BeforeEach(func() {
+		engine = transformer.NewGojaEngine()
+		limits = transformer.LimitsT{Timeout: 2 * time.Second, MaxCallStackSize: 1000}
+		events = []transformer.TransformerEventT{
+			{Message: types.SingularEventT{"messageId": "message-1", "event": "keep"}, Metadata: transformer.MetadataT{MessageID: "message-1", SourceID: "source-id"}},
+			{Message: types.SingularEventT{"messageId": "message-2", "event": "drop"}, Metadata: transformer.MetadataT{MessageID: "message-2", SourceID: "source-id"}},
+			{Message: types.SingularEventT{"messageId": "message-3", "event": "fail"}, Metadata: transformer.MetadataT{MessageID: "message-3", SourceID: "source-id"}},
+			{Message: types.SingularEventT{"messageId": "message-4", "event": "split"}, Metadata: transformer.MetadataT{MessageID: "message-4", SourceID: "source-id"}},
+		}
+	})
+
+	It("should run transformEvent on every event", func() {
+		script := transformer.ScriptT{VersionID: "event-version", Code: `
+export function transformEvent(event, metadata) {
+	switch (event.event) {
+	case "drop":
+		return null;
+	case "fail":
+		throw new Error("failed " + event.messageId);
+	case "split":
+		return [{ part: 1 }, { part: 2 }];
+	}
+	event.sourceId = metadata(event).sourceId;
+	return event;
+}`}
+
+		responses, err := engine.Transform(script, events, limits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses).To(HaveLen(4))
+		Expect(responses[0].StatusCode).To(Equal(http.StatusOK))
+		Expect(responses[0].Output).To(Equal(map[string]interface{}{"messageId": "message-1", "event": "keep", "sourceId": "source-id"}))
+		Expect(responses[0].Metadata.MessageID).To(Equal("message-1"))
+		Expect(responses[1].StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(responses[1].Error).To(Equal("Error: failed message-3"))
+		Expect(responses[1].Metadata.MessageID).To(Equal("message-3"))
+		Expect(responses[2].Output).To(Equal(map[string]interface{}{"part": float64(1)}))
+		Expect(responses[3].Output).To(Equal(map[string]interface{}{"part": float64(2)}))
+		Expect(responses[3].Metadata.MessageID).To(Equal("message-4"))
+
+		Expect(events[0].Message).NotTo(HaveKey("sourceId"))
+	})
+
+	It("should run transformBatch on all events", func() {
+		script := transformer.ScriptT{VersionID: "batch-version", Code: `
+export function transformBatch(events, metadata) {
+	const kept = events.filter(event => event.event !== "drop");
+	kept.forEach(event => { event.sourceId = metadata(event).sourceId; });
+	kept.push({ summary: kept.length });
+	return kept;
+}`}
+
+		responses, err := engine.Transform(script, events, limits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses).To(HaveLen(4))
+		Expect(responses[0].Output["sourceId"]).To(Equal("source-id"))
+		Expect(responses[0].Metadata.MessageID).To(Equal("message-1"))
+		Expect(responses[2].Metadata.MessageID).To(Equal("message-4"))
+		Expect(responses[3].Output).To(Equal(map[string]interface{}{"summary": float64(3)}))
+		Expect(responses[3].Metadata.MessageIDs).To(Equal([]string{"message-1", "message-2", "message-3", "message-4"}))
+	})
+
+	It("should fail the invocation when it runs longer than the timeout", func() {
+		limits.Timeout = 50 * time.Millisecond
+		script := transformer.ScriptT{VersionID: "loop-version", Code: `export function transformEvent(event) { while (true) {} }`}
+
+		_, err := engine.Transform(script, events, limits)
+		Expect(err).To(MatchError("user transformation timed out"))
+	})
+
+	It("should fail the invocation when it recurses deeper than the call stack size", func() {
+		script := transformer.ScriptT{VersionID: "recursive-version", Code: `
+function depth(n) { return depth(n + 1); }
+export function transformEvent(event) { return depth(0); }`}
+
+		_, err := engine.Transform(script, events, limits)
+		Expect(err).To(MatchError("user transformation exceeded the maximum call stack size"))
+	})
+
+	It("should fail every event when the script defines no transform function", func() {
+		script := transformer.ScriptT{VersionID: "empty-version", Code: `const x = 1;`}
+
+		_, err := engine.Transform(script, events, limits)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not support code using libraries or apis of rudder-transformer", func() {
+		for _, code := range []string{
+			`import { lib } from "library";
+export function transformEvent(event) { return lib(event); }`,
+			`export async function transformEvent(event) { const res = await fetch("https://example.com"); return event; }`,
+			`export function transformEvent(event) { const lodash = require("lodash"); return event; }`,
+			`export function transformEvent(event) { return event`,
+		} {
+			_, err := engine.Transform(transformer.ScriptT{VersionID: "unsupported-version", Code: code}, events, limits)
+			Expect(err).To(Equal(transformer.ErrUnsupportedScript), code)
+		}
+	})
+
+	It("should recompile a version when its code changes", func() {
+		script := transformer.ScriptT{VersionID: "changing-version", Code: `export function transformEvent(event) { return { version: 1 }; }`}
+		responses, err := engine.Transform(script, events[:1], limits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses[0].Output["version"]).To(Equal(float64(1)))
+
+		script.Code = `export function transformEvent(event) { return { version: 2 }; }`
+		responses, err = engine.Transform(script, events[:1], limits)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responses[0].Output["version"]).To(Equal(float64(2)))
+	})
+})
@@ -0,0 +1,128 @@
+package transformer
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+//DestinationTransformerFunc transforms an event for a destination the way the rudder-transformer does for its destination type.
+//An event can be transformed into many outputs. Errors are returned as failed events with the status code of a TransformErrorT or 400
+type DestinationTransformerFunc func(event TransformerEventT) ([]map[string]interface{}, error)
+
+//TransformErrorT is an error of a native destination transformer with the status code to report the event with
+type TransformErrorT struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *TransformErrorT) Error() string {
+	return err.Message
+}
+
+var (
+	nativeDestinationTransformers     = map[string]DestinationTransformerFunc{}
+	nativeDestinationTransformersLock sync.RWMutex
+)
+
+func init() {
+	for destType, transform := range builtinDestinationTransformers {
+		RegisterNativeDestinationTransformer(destType, transform)
+	}
+}
+
+//RegisterNativeDestinationTransformer adds a go transformer for the destination type, replacing the one registered before if any
+func RegisterNativeDestinationTransformer(destType string, transform DestinationTransformerFunc) {
+	nativeDestinationTransformersLock.Lock()
+	defer nativeDestinationTransformersLock.Unlock()
+	nativeDestinationTransformers[destType] = transform
+}
+
+//HasNativeDestinationTransformer returns whether a go transformer is registered for the destination type
+func HasNativeDestinationTransformer(destType string) bool {
+	return getNativeDestinationTransformer(destType) != nil
+}
+
+func getNativeDestinationTransformer(destType string) DestinationTransformerFunc {
+	nativeDestinationTransformersLock.RLock()
+	defer nativeDestinationTransformersLock.RUnlock()
+	return nativeDestinationTransformers[destType]
+}
+
+//NativeHandleT transforms events in process with the registered go destination transformers, instead of calling rudder-transformer
+type NativeHandleT struct {
+	perfStats          *misc.PerfStats
+	sentStat           stats.RudderStats
+	receivedStat       stats.RudderStats
+	failedStat         stats.RudderStats
+	transformTimerStat stats.RudderStats
+	logger             logger.LoggerI
+}
+
+//NewNativeTransformer creates a new in process transformer
+func NewNativeTransformer() *NativeHandleT {
+	return &NativeHandleT{}
+}
+
+//Setup initializes this class
+func (trans *NativeHandleT) Setup() {
+	trans.logger = pkgLogger.Child("native")
+	trans.sentStat = stats.NewTaggedStat("processor.transformer_sent", stats.CountType, stats.Tags{"runtime": "native"})
+	trans.receivedStat = stats.NewTaggedStat("processor.transformer_received", stats.CountType, stats.Tags{"runtime": "native"})
+	trans.failedStat = stats.NewTaggedStat("processor.transformer_failed", stats.CountType, stats.Tags{"runtime": "native"})
+	trans.transformTimerStat = stats.NewTaggedStat("processor.transformation_time", stats.TimerType, stats.Tags{"runtime": "native"})
+	trans.perfStats = &misc.PerfStats{}
+	trans.perfStats.Setup("Native Transform")
+}
+
+//Transform transforms every event with the go transformer of its destination type.
+//url and batchSize are not used, they are part of the signature to be interchangeable with the rudder-transformer client.
+//Events of destination types without a go transformer fail with 404, as they do with rudder-transformer
+func (trans *NativeHandleT) Transform(clientEvents []TransformerEventT, url string, batchSize int) ResponseT {
+	trans.transformTimerStat.Start()
+	trans.perfStats.Start()
+	trans.sentStat.Count(len(clientEvents))
+
+	var outClientEvents []TransformerResponseT
+	var failedEvents []TransformerResponseT
+	for _, event := range clientEvents {
+		destType := event.Destination.DestinationDefinition.Name
+		transform := getNativeDestinationTransformer(destType)
+		if transform == nil {
+			failedEvents = append(failedEvents, TransformerResponseT{
+				Metadata:   event.Metadata,
+				StatusCode: http.StatusNotFound,
+				Error:      fmt.Sprintf("No native transformer for destination type %s", destType),
+			})
+			continue
+		}
+
+		outputs, err := transform(event)
+		if err != nil {
+			statusCode := http.StatusBadRequest
+			if transformErr, ok := err.(*TransformErrorT); ok {
+				statusCode = transformErr.StatusCode
+			}
+			failedEvents = append(failedEvents, TransformerResponseT{Metadata: event.Metadata, StatusCode: statusCode, Error: err.Error()})
+			continue
+		}
+		for _, output := range outputs {
+			outClientEvents = append(outClientEvents, TransformerResponseT{Output: output, Metadata: event.Metadata, StatusCode: http.StatusOK})
+		}
+	}
+
+	trans.receivedStat.Count(len(outClientEvents))
+	trans.failedStat.Count(len(failedEvents))
+	trans.perfStats.End(len(clientEvents))
+	trans.perfStats.Print()
+	trans.transformTimerStat.End()
+
+	return ResponseT{
+		Events:       outClientEvents,
+		FailedEvents: failedEvents,
+	}
+}
@@ -0,0 +1,86 @@
+package transformer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jeremywohl/flatten"
+)
+
+//builtinDestinationTransformers are the go ports of rudder-transformer destination transformers
+var builtinDestinationTransformers = map[string]DestinationTransformerFunc{
+	"WEBHOOK":              transformWebhook,
+	"S3":                   transformObjectStorage,
+	"GCS":                  transformObjectStorage,
+	"MINIO":                transformObjectStorage,
+	"AZURE_BLOB":           transformObjectStorage,
+	"DIGITAL_OCEAN_SPACES": transformObjectStorage,
+}
+
+//defaultRequestConfig is the router payload every REST destination transformer starts from
+func defaultRequestConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"version":  "1",
+		"type":     "REST",
+		"method":   http.MethodPost,
+		"endpoint": "",
+		"headers":  map[string]interface{}{},
+		"params":   map[string]interface{}{},
+		"body": map[string]interface{}{
+			"JSON":       map[string]interface{}{},
+			"JSON_ARRAY": map[string]interface{}{},
+			"XML":        map[string]interface{}{},
+			"FORM":       map[string]interface{}{},
+		},
+		"files": map[string]interface{}{},
+	}
+}
+
+//transformWebhook sends the event as json body, or as flattened query params for GET webhooks
+func transformWebhook(event TransformerEventT) ([]map[string]interface{}, error) {
+	config := event.Destination.Config
+	url, _ := config["webhookUrl"].(string)
+	if url == "" {
+		return nil, &TransformErrorT{StatusCode: http.StatusBadRequest, Message: "Invalid Url in destination"}
+	}
+
+	response := defaultRequestConfig()
+	headers := response["headers"].(map[string]interface{})
+	method, _ := config["webhookMethod"].(string)
+	method = strings.ToUpper(method)
+	switch method {
+	case http.MethodGet:
+		params, err := flatten.Flatten(event.Message, "", flatten.DotStyle)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to flatten event into query params: %v", err)
+		}
+		response["method"] = http.MethodGet
+		response["params"] = params
+	default:
+		if method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete {
+			response["method"] = method
+		}
+		response["body"].(map[string]interface{})["JSON"] = map[string]interface{}(event.Message)
+		headers["content-type"] = "application/json"
+	}
+
+	if configHeaders, ok := config["headers"].([]interface{}); ok {
+		for _, configHeader := range configHeaders {
+			header, _ := configHeader.(map[string]interface{})
+			from, _ := header["from"].(string)
+			to, _ := header["to"].(string)
+			if from != "" && to != "" {
+				headers[from] = to
+			}
+		}
+	}
+	response["endpoint"] = url
+	response["userId"] = event.Message["anonymousId"]
+	return []map[string]interface{}{response}, nil
+}
+
+//transformObjectStorage passes the event through as is, object storage destinations upload events without any mapping
+func transformObjectStorage(event TransformerEventT) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{event.Message}, nil
+}
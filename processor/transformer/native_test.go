@@ -0,0 +1,129 @@
+package transformer_test
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/processor/transformer"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/types"
+)
+
+func nativeTransformerEvent(destType string, destConfig map[string]interface{}, messageID string) transformer.TransformerEventT {
+	return transformer.TransformerEventT{
+		Message: types.SingularEventT{
+			"type":        "track",
+			"event":       "Product Viewed",
+			"anonymousId": "anon-id",
+			"messageId":   messageID,
+			"properties":  map[string]interface{}{"sku": "a", "price": 10.5},
+		},
+		Metadata: transformer.MetadataT{MessageID: messageID, DestinationType: destType},
+		Destination: backendconfig.DestinationT{
+			Config:                destConfig,
+			DestinationDefinition: backendconfig.DestinationDefinitionT{Name: destType},
+		},
+	}
+}
+
+var _ = Describe("NativeTransformer", func() {
+	var nativeTransformer *transformer.NativeHandleT
+
+	BeforeEach(func() {
+		stats.Setup()
+		nativeTransformer = transformer.NewNativeTransformer()
+		nativeTransformer.Setup()
+	})
+
+	It("should transform webhook events into router payloads", func() {
+		event := nativeTransformerEvent("WEBHOOK", map[string]interface{}{
+			"webhookUrl": "https://example.com/hook",
+			"headers":    []interface{}{map[string]interface{}{"from": "X-Api-Key", "to": "secret"}, map[string]interface{}{"from": "", "to": "ignored"}},
+		}, "message-1")
+
+		response := nativeTransformer.Transform([]transformer.TransformerEventT{event}, "", 100)
+		Expect(response.FailedEvents).To(BeEmpty())
+		Expect(response.Events).To(HaveLen(1))
+		Expect(response.Events[0].StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Events[0].Metadata).To(Equal(event.Metadata))
+
+		output := response.Events[0].Output
+		Expect(output["type"]).To(Equal("REST"))
+		Expect(output["method"]).To(Equal(http.MethodPost))
+		Expect(output["endpoint"]).To(Equal("https://example.com/hook"))
+		Expect(output["userId"]).To(Equal("anon-id"))
+		Expect(output["headers"]).To(Equal(map[string]interface{}{"content-type": "application/json", "X-Api-Key": "secret"}))
+		Expect(output["body"].(map[string]interface{})["JSON"]).To(Equal(map[string]interface{}(event.Message)))
+	})
+
+	It("should send GET webhook events as flattened query params", func() {
+		event := nativeTransformerEvent("WEBHOOK", map[string]interface{}{"webhookUrl": "https://example.com/hook", "webhookMethod": "get"}, "message-1")
+
+		response := nativeTransformer.Transform([]transformer.TransformerEventT{event}, "", 100)
+		Expect(response.Events).To(HaveLen(1))
+		output := response.Events[0].Output
+		Expect(output["method"]).To(Equal(http.MethodGet))
+		Expect(output["params"]).To(HaveKeyWithValue("properties.sku", "a"))
+		Expect(output["params"]).To(HaveKeyWithValue("event", "Product Viewed"))
+	})
+
+	It("should pass object storage events through", func() {
+		event := nativeTransformerEvent("S3", map[string]interface{}{}, "message-1")
+
+		response := nativeTransformer.Transform([]transformer.TransformerEventT{event}, "", 100)
+		Expect(response.Events).To(HaveLen(1))
+		Expect(response.Events[0].Output).To(Equal(map[string]interface{}(event.Message)))
+	})
+
+	It("should fail events that can't be transformed, keeping the order of the others", func() {
+		events := []transformer.TransformerEventT{
+			nativeTransformerEvent("WEBHOOK", map[string]interface{}{}, "message-1"),
+			nativeTransformerEvent("MINIO", map[string]interface{}{}, "message-2"),
+			nativeTransformerEvent("GA", map[string]interface{}{}, "message-3"),
+			nativeTransformerEvent("GCS", map[string]interface{}{}, "message-4"),
+		}
+
+		response := nativeTransformer.Transform(events, "", 100)
+		Expect(response.Events).To(HaveLen(2))
+		Expect(response.Events[0].Metadata.MessageID).To(Equal("message-2"))
+		Expect(response.Events[1].Metadata.MessageID).To(Equal("message-4"))
+
+		Expect(response.FailedEvents).To(HaveLen(2))
+		Expect(response.FailedEvents[0].Metadata.MessageID).To(Equal("message-1"))
+		Expect(response.FailedEvents[0].StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(response.FailedEvents[0].Error).To(Equal("Invalid Url in destination"))
+		Expect(response.FailedEvents[1].Metadata.MessageID).To(Equal("message-3"))
+		Expect(response.FailedEvents[1].StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should use registered destination transformers", func() {
+		Expect(transformer.HasNativeDestinationTransformer("NATIVE_TEST")).To(BeFalse())
+		transformer.RegisterNativeDestinationTransformer("NATIVE_TEST", func(event transformer.TransformerEventT) ([]map[string]interface{}, error) {
+			if event.Metadata.MessageID == "message-2" {
+				return nil, &transformer.TransformErrorT{StatusCode: http.StatusNotImplemented, Message: "not implemented"}
+			}
+			if event.Metadata.MessageID == "message-3" {
+				return nil, errors.New("some error")
+			}
+			return []map[string]interface{}{{"n": 1}, {"n": 2}}, nil
+		})
+		Expect(transformer.HasNativeDestinationTransformer("NATIVE_TEST")).To(BeTrue())
+
+		events := []transformer.TransformerEventT{
+			nativeTransformerEvent("NATIVE_TEST", nil, "message-1"),
+			nativeTransformerEvent("NATIVE_TEST", nil, "message-2"),
+			nativeTransformerEvent("NATIVE_TEST", nil, "message-3"),
+		}
+		response := nativeTransformer.Transform(events, "", 100)
+		Expect(response.Events).To(HaveLen(2))
+		Expect(response.Events[1].Output).To(Equal(map[string]interface{}{"n": 2}))
+		Expect(response.FailedEvents).To(HaveLen(2))
+		Expect(response.FailedEvents[0].StatusCode).To(Equal(http.StatusNotImplemented))
+		Expect(response.FailedEvents[1].StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(response.FailedEvents[1].Error).To(Equal("some error"))
+	})
+})
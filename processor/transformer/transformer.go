@@ -4,6 +4,7 @@ package transformer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -18,6 +19,7 @@ import (
 	"github.com/rudderlabs/rudder-server/processor/integrations"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/types"
@@ -51,6 +53,8 @@ type MetadataT struct {
 	RudderID   string   `json:"rudderId"`
 	SessionID  string   `json:"sessionId,omitempty"`
 	ReceivedAt string   `json:"receivedAt"`
+	// w3c traceparent of the span processing the gateway job of the event
+	TraceParent string `json:"traceParent,omitempty"`
 }
 
 type TransformerEventT struct {
@@ -108,6 +112,9 @@ func loadConfig() {
 	config.RegisterIntConfigVariable(8, &numTransformWorker, false, 1, "Processor.numTransformWorker")
 	config.RegisterIntConfigVariable(30, &maxRetry, true, 1, "Processor.maxRetry")
 	config.RegisterDurationConfigVariable(time.Duration(100), &retrySleep, true, time.Millisecond, []string{"Processor.retrySleep", "Processor.retrySleepInMS"}...)
+	// Limits of every invocation of a user transformation run in process
+	config.RegisterDurationConfigVariable(time.Duration(4), &userTransformTimeout, true, time.Second, "Processor.embeddedUserTransform.timeout")
+	config.RegisterIntConfigVariable(10000, &userTransformMaxCallStackSize, true, 1, "Processor.embeddedUserTransform.maxCallStackSize")
 }
 
 func init() {
@@ -140,10 +147,22 @@ func (trans *HandleT) transformWorker() {
 		//We should rarely have error communicating with our JS
 		reqFailed := false
 
+		//the request continues the trace of its first event, the traces of the other events are linked
+		traceParents := make([]string, len(job.data))
+		for i, transformEvent := range job.data {
+			traceParents[i] = transformEvent.Metadata.TraceParent
+		}
+		_, span := tracing.StartSpanFromTraceparents(context.Background(), "processor.transform", traceParents, tracing.StringAttribute("http.url", job.url), tracing.Int64Attribute("events", int64(len(job.data))))
+
 		for {
 			transformRequestTimerStat.Start()
-			resp, err = client.Post(job.url, "application/json; charset=utf-8",
-				bytes.NewBuffer(rawJSON))
+			var req *http.Request
+			req, err = http.NewRequest(http.MethodPost, job.url, bytes.NewBuffer(rawJSON))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+				tracing.SetHeader(req.Header, span)
+				resp, err = client.Do(req)
+			}
 
 			if err == nil {
 				//If no err returned by client.Post, reading body.
@@ -182,6 +201,9 @@ func (trans *HandleT) transformWorker() {
 			transformRequestTimerStat.End()
 			break
 		}
+		span.SetAttributes(tracing.Int64Attribute("retries", int64(retryCount)))
+		tracing.SetStatusCode(span, resp.StatusCode)
+		span.End()
 
 		// Remove Assertion?
 		if !(resp.StatusCode == http.StatusOK ||
@@ -25,6 +25,9 @@ type WarehouseClient interface {
 	GetWHUpload(ctx context.Context, in *WHUploadRequest, opts ...grpc.CallOption) (*WHUploadResponse, error)
 	TriggerWHUpload(ctx context.Context, in *WHUploadRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	TriggerWHUploads(ctx context.Context, in *WHUploadsRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetWHSchemaChanges(ctx context.Context, in *WHSchemaChangesRequest, opts ...grpc.CallOption) (*WHSchemaChangesResponse, error)
+	ApproveWHSchemaChange(ctx context.Context, in *WHSchemaChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RejectWHSchemaChange(ctx context.Context, in *WHSchemaChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type warehouseClient struct {
@@ -80,6 +83,33 @@ func (c *warehouseClient) TriggerWHUploads(ctx context.Context, in *WHUploadsReq
 	return out, nil
 }
 
+func (c *warehouseClient) GetWHSchemaChanges(ctx context.Context, in *WHSchemaChangesRequest, opts ...grpc.CallOption) (*WHSchemaChangesResponse, error) {
+	out := new(WHSchemaChangesResponse)
+	err := c.cc.Invoke(ctx, "/proto.Warehouse/GetWHSchemaChanges", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *warehouseClient) ApproveWHSchemaChange(ctx context.Context, in *WHSchemaChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.Warehouse/ApproveWHSchemaChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *warehouseClient) RejectWHSchemaChange(ctx context.Context, in *WHSchemaChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/proto.Warehouse/RejectWHSchemaChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WarehouseServer is the server API for Warehouse service.
 // All implementations must embed UnimplementedWarehouseServer
 // for forward compatibility
@@ -89,6 +119,9 @@ type WarehouseServer interface {
 	GetWHUpload(context.Context, *WHUploadRequest) (*WHUploadResponse, error)
 	TriggerWHUpload(context.Context, *WHUploadRequest) (*emptypb.Empty, error)
 	TriggerWHUploads(context.Context, *WHUploadsRequest) (*emptypb.Empty, error)
+	GetWHSchemaChanges(context.Context, *WHSchemaChangesRequest) (*WHSchemaChangesResponse, error)
+	ApproveWHSchemaChange(context.Context, *WHSchemaChangeRequest) (*emptypb.Empty, error)
+	RejectWHSchemaChange(context.Context, *WHSchemaChangeRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedWarehouseServer()
 }
 
@@ -111,6 +144,15 @@ func (UnimplementedWarehouseServer) TriggerWHUpload(context.Context, *WHUploadRe
 func (UnimplementedWarehouseServer) TriggerWHUploads(context.Context, *WHUploadsRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method TriggerWHUploads not implemented")
 }
+func (UnimplementedWarehouseServer) GetWHSchemaChanges(context.Context, *WHSchemaChangesRequest) (*WHSchemaChangesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWHSchemaChanges not implemented")
+}
+func (UnimplementedWarehouseServer) ApproveWHSchemaChange(context.Context, *WHSchemaChangeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveWHSchemaChange not implemented")
+}
+func (UnimplementedWarehouseServer) RejectWHSchemaChange(context.Context, *WHSchemaChangeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectWHSchemaChange not implemented")
+}
 func (UnimplementedWarehouseServer) mustEmbedUnimplementedWarehouseServer() {}
 
 // UnsafeWarehouseServer may be embedded to opt out of forward compatibility for this service.
@@ -214,6 +256,60 @@ func _Warehouse_TriggerWHUploads_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Warehouse_GetWHSchemaChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WHSchemaChangesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WarehouseServer).GetWHSchemaChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Warehouse/GetWHSchemaChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WarehouseServer).GetWHSchemaChanges(ctx, req.(*WHSchemaChangesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Warehouse_ApproveWHSchemaChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WHSchemaChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WarehouseServer).ApproveWHSchemaChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Warehouse/ApproveWHSchemaChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WarehouseServer).ApproveWHSchemaChange(ctx, req.(*WHSchemaChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Warehouse_RejectWHSchemaChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WHSchemaChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WarehouseServer).RejectWHSchemaChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Warehouse/RejectWHSchemaChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WarehouseServer).RejectWHSchemaChange(ctx, req.(*WHSchemaChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Warehouse_ServiceDesc is the grpc.ServiceDesc for Warehouse service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -241,6 +337,18 @@ var Warehouse_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "TriggerWHUploads",
 			Handler:    _Warehouse_TriggerWHUploads_Handler,
 		},
+		{
+			MethodName: "GetWHSchemaChanges",
+			Handler:    _Warehouse_GetWHSchemaChanges_Handler,
+		},
+		{
+			MethodName: "ApproveWHSchemaChange",
+			Handler:    _Warehouse_ApproveWHSchemaChange_Handler,
+		},
+		{
+			MethodName: "RejectWHSchemaChange",
+			Handler:    _Warehouse_RejectWHSchemaChange_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/warehouse/warehouse.proto",
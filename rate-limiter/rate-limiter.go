@@ -3,28 +3,75 @@ package ratelimiter
 //go:generate mockgen -destination=../mocks/rate-limiter/mock_ratelimiter.go -package=mocks_ratelimiter github.com/rudderlabs/rudder-server/rate-limiter RateLimiter
 
 import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/EagleChen/restrictor"
 	"github.com/rudderlabs/rudder-server/config"
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/router/throttler/ratelimiter"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 )
 
+const (
+	WorkspaceLevel = "workspace"
+	SourceLevel    = "source"
+	UserLevel      = "user"
+
+	MemoryStore   = "memory"
+	PostgresStore = "postgres"
+	RedisStore    = "redis"
+)
+
 var (
 	eventLimit            int
 	rateLimitWindowInMins time.Duration
-	noOfBucketsInWindow   int
+	maxWindow             time.Duration
+	limitStore            string
 	pkgLogger             logger.LoggerI
 )
 
 //RateLimiter is an interface for rate limiting functions
 type RateLimiter interface {
-	LimitReached(key string) bool
+	LimitReached(request RequestT) LimitStatusT
+}
+
+//RequestT identifies the quotas a gateway request is counted against.
+//The request counts once against the workspace and source quotas, and each of its events counts against the quota of the user of the event
+type RequestT struct {
+	WorkspaceID string
+	SourceID    string
+	UserEvents  map[string]int64
+}
+
+//LimitStatusT is the state of the most restrictive quota applicable to a request.
+//Remaining is the number of requests, or events for user quotas, left in the window after counting the current one.
+//Level is empty if none of the quotas could be checked
+type LimitStatusT struct {
+	Reached    bool
+	Level      string
+	Limit      int64
+	Remaining  int64
+	Reset      time.Duration
+	RetryAfter time.Duration
 }
 
 //HandleT is a Handle for event limiter
 type HandleT struct {
-	restrictor restrictor.Restrictor
+	store      limitStoreT
+	rateLimits backendconfig.RateLimitsT
+	configLock sync.RWMutex
+}
+
+type quotaCheckT struct {
+	level string
+	key   string
+	count int64
+	quota backendconfig.QuotaT
 }
 
 func init() {
@@ -33,25 +80,152 @@ func init() {
 }
 
 func loadConfig() {
-	// Event limit when rate limit is enabled. 1000 by default
+	// Event limit per workspace when rate limit is enabled and no quota is set for the workspace in backend config. 1000 by default
 	config.RegisterIntConfigVariable(1000, &eventLimit, false, 1, "RateLimit.eventLimit")
 	// Rolling time window for event limit. 60 mins by default
 	config.RegisterDurationConfigVariable(time.Duration(60), &rateLimitWindowInMins, false, time.Minute, []string{"RateLimit.rateLimitWindow", "RateLimit.rateLimitWindowInMins"}...)
-	// Number of buckets in time window. 12 by default
-	config.RegisterIntConfigVariable(12, &noOfBucketsInWindow, false, 1, "RateLimit.noOfBucketsInWindow")
+	// Longest window a quota from backend config can use. Counters are kept for twice this long
+	config.RegisterDurationConfigVariable(time.Duration(24), &maxWindow, false, time.Hour, "RateLimit.maxWindow")
+	// Store for counters. memory limits each gateway separately, postgres and redis share counters across gateways
+	config.RegisterStringConfigVariable(MemoryStore, &limitStore, false, "RateLimit.store")
 }
 
 //SetUp eventLimiter
-func (rateLimiter *HandleT) SetUp() {
-	store, err := restrictor.NewMemoryStore()
+func (rateLimiter *HandleT) SetUp(backendConfig backendconfig.BackendConfig) {
+	var err error
+	switch limitStore {
+	case MemoryStore:
+		rateLimiter.store = newMemoryLimitStore()
+	case PostgresStore:
+		rateLimiter.store, err = newPostgresLimitStore()
+	case RedisStore:
+		rateLimiter.store, err = newRedisLimitStore()
+	default:
+		err = fmt.Errorf("unknown rate limit store: %s", limitStore)
+	}
 	if err != nil {
-		pkgLogger.Error("memory store failed")
+		panic(err)
 	}
+	pkgLogger.Infof("Using %s store for rate limits", limitStore)
 
-	rateLimiter.restrictor = restrictor.NewRestrictor(rateLimitWindowInMins, uint32(eventLimit), uint32(noOfBucketsInWindow), store)
+	rruntime.Go(func() {
+		rateLimiter.backendConfigSubscriber(backendConfig)
+	})
 }
 
-//LimitReached returns true if number of events in the rolling window is less than the max events allowed, else false
-func (rateLimiter *HandleT) LimitReached(key string) bool {
-	return rateLimiter.restrictor.LimitReached(key)
+func (rateLimiter *HandleT) backendConfigSubscriber(backendConfig backendconfig.BackendConfig) {
+	ch := make(chan utils.DataEvent)
+	backendConfig.Subscribe(ch, backendconfig.TopicProcessConfig)
+	for {
+		config := <-ch
+		rateLimiter.configLock.Lock()
+		rateLimiter.rateLimits = config.Data.(backendconfig.ConfigT).RateLimits
+		rateLimiter.configLock.Unlock()
+	}
+}
+
+func (rateLimiter *HandleT) quotaChecks(request RequestT) []quotaCheckT {
+	rateLimiter.configLock.RLock()
+	defer rateLimiter.configLock.RUnlock()
+
+	workspaceQuota, ok := rateLimiter.rateLimits.Workspaces[request.WorkspaceID]
+	if !ok {
+		workspaceQuota = backendconfig.QuotaT{Limit: int64(eventLimit), WindowInS: int64(rateLimitWindowInMins / time.Second)}
+	}
+	checks := []quotaCheckT{{level: WorkspaceLevel, key: "workspace:" + request.WorkspaceID, count: 1, quota: workspaceQuota}}
+	if sourceQuota, ok := rateLimiter.rateLimits.Sources[request.SourceID]; ok && request.SourceID != "" {
+		checks = append(checks, quotaCheckT{level: SourceLevel, key: "source:" + request.SourceID, count: 1, quota: sourceQuota})
+	}
+	if userQuota, ok := rateLimiter.rateLimits.Users[request.WorkspaceID]; ok {
+		userIDs := make([]string, 0, len(request.UserEvents))
+		for userID := range request.UserEvents {
+			userIDs = append(userIDs, userID)
+		}
+		// stable order of checks, so the same quota is reported for the same request
+		sort.Strings(userIDs)
+		for _, userID := range userIDs {
+			if userID == "" || request.UserEvents[userID] <= 0 {
+				continue
+			}
+			checks = append(checks, quotaCheckT{level: UserLevel, key: fmt.Sprintf("user:%s:%s", request.WorkspaceID, userID), count: request.UserEvents[userID], quota: userQuota})
+		}
+	}
+	return checks
+}
+
+func window(quota backendconfig.QuotaT) time.Duration {
+	window := time.Duration(quota.WindowInS) * time.Second
+	if window <= 0 || window > maxWindow {
+		return maxWindow
+	}
+	return window
+}
+
+//LimitReached counts the request against workspace, source and user quotas and checks the counts, which include the request, against the quotas.
+//Counting and reading a quota is a single store operation, so concurrent requests never both take the last of a quota.
+//If any quota is reached, the request is uncounted from all of them.
+//Errors from the store are logged and the request is let through
+func (rateLimiter *HandleT) LimitReached(request RequestT) LimitStatusT {
+	now := time.Now().UTC()
+	checks := rateLimiter.quotaChecks(request)
+
+	status := LimitStatusT{Remaining: math.MaxInt64}
+	var counted []quotaCheckT
+	var reachedStatus *LimitStatusT
+	for _, check := range checks {
+		checkWindow := window(check.quota)
+		currentWindow := now.Truncate(checkWindow)
+		prevValue, currValue, err := rateLimiter.store.IncBy(check.key, check.count, currentWindow.Add(-checkWindow), currentWindow)
+		if err != nil {
+			pkgLogger.Errorf("Failed to count %s rate limit for %s: %v", check.level, check.key, err)
+			continue
+		}
+		counted = append(counted, check)
+
+		sinceWindow := now.Sub(currentWindow)
+		reset := checkWindow - sinceWindow
+		rate := float64(checkWindow-sinceWindow)/float64(checkWindow)*float64(prevValue) + float64(currValue)
+		if rate > float64(check.quota.Limit) {
+			reachedStatus = &LimitStatusT{
+				Reached:    true,
+				Level:      check.level,
+				Limit:      check.quota.Limit,
+				Remaining:  0,
+				Reset:      reset,
+				RetryAfter: reset,
+			}
+			break
+		}
+		remaining := check.quota.Limit - int64(math.Ceil(rate))
+		if remaining < status.Remaining {
+			status = LimitStatusT{
+				Level:     check.level,
+				Limit:     check.quota.Limit,
+				Remaining: remaining,
+				Reset:     reset,
+			}
+		}
+	}
+
+	if reachedStatus != nil {
+		for _, check := range counted {
+			err := rateLimiter.store.Dec(check.key, check.count, now.Truncate(window(check.quota)))
+			if err != nil {
+				pkgLogger.Errorf("Failed to uncount %s rate limit for %s: %v", check.level, check.key, err)
+			}
+		}
+		// the last check is the one reached, retry once it has room for the request again
+		reached := counted[len(counted)-1]
+		limitStatus, err := ratelimiter.New(rateLimiter.store, reached.quota.Limit-reached.count+1, window(reached.quota)).Check(reached.key, now)
+		if err == nil && limitStatus.LimitDuration != nil && *limitStatus.LimitDuration > 0 {
+			reachedStatus.RetryAfter = *limitStatus.LimitDuration
+		}
+		return *reachedStatus
+	}
+
+	if status.Level == "" {
+		// no quota could be checked
+		status.Remaining = 0
+	}
+	return status
 }
@@ -0,0 +1,150 @@
+package ratelimiter_test
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	mocksBackendConfig "github.com/rudderlabs/rudder-server/mocks/config/backend-config"
+	ratelimiter "github.com/rudderlabs/rudder-server/rate-limiter"
+	"github.com/rudderlabs/rudder-server/utils"
+)
+
+const (
+	WorkspaceID      = "some-workspace-id"
+	OtherWorkspaceID = "other-workspace-id"
+	SourceID         = "some-source-id"
+)
+
+var sampleRateLimits = backendconfig.RateLimitsT{
+	Workspaces: map[string]backendconfig.QuotaT{
+		WorkspaceID: {Limit: 5, WindowInS: 3600},
+	},
+	Sources: map[string]backendconfig.QuotaT{
+		SourceID: {Limit: 3, WindowInS: 3600},
+	},
+	Users: map[string]backendconfig.QuotaT{
+		WorkspaceID: {Limit: 2, WindowInS: 3600},
+	},
+}
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		mockCtrl          *gomock.Controller
+		mockBackendConfig *mocksBackendConfig.MockBackendConfig
+		rateLimiter       *ratelimiter.HandleT
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockBackendConfig = mocksBackendConfig.NewMockBackendConfig(mockCtrl)
+
+		configReceived := make(chan struct{})
+		mockBackendConfig.EXPECT().Subscribe(gomock.Any(), backendconfig.TopicProcessConfig).
+			Do(func(channel chan utils.DataEvent, topic backendconfig.Topic) {
+				go func() {
+					// second send returns only after the first config is applied
+					channel <- utils.DataEvent{Data: backendconfig.ConfigT{RateLimits: sampleRateLimits}, Topic: string(topic)}
+					channel <- utils.DataEvent{Data: backendconfig.ConfigT{RateLimits: sampleRateLimits}, Topic: string(topic)}
+					close(configReceived)
+				}()
+			})
+
+		rateLimiter = &ratelimiter.HandleT{}
+		rateLimiter.SetUp(mockBackendConfig)
+		Eventually(configReceived).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("quotas from backend config", func() {
+		It("should limit requests of a workspace", func() {
+			request := ratelimiter.RequestT{WorkspaceID: WorkspaceID}
+			for i := 0; i < 5; i++ {
+				status := rateLimiter.LimitReached(request)
+				Expect(status.Reached).To(BeFalse())
+				Expect(status.Level).To(Equal(ratelimiter.WorkspaceLevel))
+				Expect(status.Remaining).To(Equal(int64(4 - i)))
+			}
+
+			status := rateLimiter.LimitReached(request)
+			Expect(status.Reached).To(BeTrue())
+			Expect(status.Level).To(Equal(ratelimiter.WorkspaceLevel))
+			Expect(status.Limit).To(Equal(int64(5)))
+			Expect(status.RetryAfter).To(BeNumerically(">", 0))
+		})
+
+		It("should apply the most restrictive of workspace and source quotas", func() {
+			request := ratelimiter.RequestT{WorkspaceID: WorkspaceID, SourceID: SourceID}
+			for i := 0; i < 3; i++ {
+				status := rateLimiter.LimitReached(request)
+				Expect(status.Reached).To(BeFalse())
+				Expect(status.Level).To(Equal(ratelimiter.SourceLevel))
+			}
+
+			status := rateLimiter.LimitReached(request)
+			Expect(status.Reached).To(BeTrue())
+			Expect(status.Level).To(Equal(ratelimiter.SourceLevel))
+		})
+
+		It("should limit each user of a workspace separately", func() {
+			for i := 0; i < 2; i++ {
+				Expect(rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-1": 1}}).Reached).To(BeFalse())
+			}
+
+			status := rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-1": 1}})
+			Expect(status.Reached).To(BeTrue())
+			Expect(status.Level).To(Equal(ratelimiter.UserLevel))
+
+			Expect(rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-2": 1}}).Reached).To(BeFalse())
+		})
+
+		It("should count each event against the quota of its user", func() {
+			status := rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-1": 3}})
+			Expect(status.Reached).To(BeTrue())
+			Expect(status.Level).To(Equal(ratelimiter.UserLevel))
+
+			status = rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-1": 2, "user-2": 1}})
+			Expect(status.Reached).To(BeFalse())
+			Expect(status.Level).To(Equal(ratelimiter.UserLevel))
+			Expect(status.Remaining).To(Equal(int64(0)))
+		})
+
+		It("should not count requests rejected by any quota", func() {
+			Expect(rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID, UserEvents: map[string]int64{"user-1": 3}}).Reached).To(BeTrue())
+
+			status := rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID})
+			Expect(status.Reached).To(BeFalse())
+			Expect(status.Remaining).To(Equal(int64(4)))
+		})
+
+		It("should let only as many concurrent requests through as the quota", func() {
+			var allowed int64
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if !rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: WorkspaceID}).Reached {
+						atomic.AddInt64(&allowed, 1)
+					}
+				}()
+			}
+			wg.Wait()
+			Expect(allowed).To(Equal(int64(5)))
+		})
+
+		It("should use the default quota for workspaces without one", func() {
+			status := rateLimiter.LimitReached(ratelimiter.RequestT{WorkspaceID: OtherWorkspaceID})
+			Expect(status.Reached).To(BeFalse())
+			Expect(status.Level).To(Equal(ratelimiter.WorkspaceLevel))
+			Expect(status.Limit).To(Equal(int64(1000)))
+		})
+	})
+})
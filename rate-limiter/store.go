@@ -0,0 +1,202 @@
+package ratelimiter
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/router/throttler/ratelimiter"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/kvstoremanager"
+)
+
+const rateLimitsTable = "gw_rate_limits"
+
+// limitStoreT is a ratelimiter.LimitStore that can also count and read a key in a single step,
+// so that concurrent requests, from the same or other gateways, never see the same count
+type limitStoreT interface {
+	ratelimiter.LimitStore
+	// IncBy increments current window limit counter for key by count and returns previous window counter and current window counter including the increment
+	IncBy(key string, count int64, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error)
+}
+
+// memoryLimitStoreT keeps window counters of a single gateway in memory
+type memoryLimitStoreT struct {
+	*ratelimiter.MapLimitStore
+	incLock sync.Mutex
+}
+
+func newMemoryLimitStore() *memoryLimitStoreT {
+	return &memoryLimitStoreT{MapLimitStore: ratelimiter.NewMapLimitStore(2*maxWindow, 10*time.Second)}
+}
+
+// IncBy increments current window limit counter for key by count and returns previous window counter and current window counter including the increment
+func (m *memoryLimitStoreT) IncBy(key string, count int64, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error) {
+	m.incLock.Lock()
+	defer m.incLock.Unlock()
+	for i := int64(0); i < count; i++ {
+		err = m.Inc(key, currentWindow)
+		if err != nil {
+			return
+		}
+	}
+	return m.Get(key, previousWindow, currentWindow)
+}
+
+// postgresLimitStoreT keeps window counters in a table shared by all gateways using the same jobsdb
+type postgresLimitStoreT struct {
+	dbHandle *sql.DB
+}
+
+func newPostgresLimitStore() (*postgresLimitStoreT, error) {
+	dbHandle, err := sql.Open("postgres", jobsdb.GetConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	err = dbHandle.Ping()
+	if err != nil {
+		return nil, err
+	}
+	store := &postgresLimitStoreT{dbHandle: dbHandle}
+
+	sqlStmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+									key TEXT NOT NULL,
+									window_start TIMESTAMP NOT NULL,
+									count BIGINT NOT NULL DEFAULT 0,
+									PRIMARY KEY (key, window_start));`, rateLimitsTable)
+	_, err = dbHandle.Exec(sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+
+	rruntime.Go(func() {
+		store.deleteExpiredLoop()
+	})
+	return store, nil
+}
+
+func (p *postgresLimitStoreT) deleteExpiredLoop() {
+	for {
+		time.Sleep(time.Minute)
+		sqlStmt := fmt.Sprintf(`DELETE FROM %s WHERE window_start < $1`, rateLimitsTable)
+		_, err := p.dbHandle.Exec(sqlStmt, time.Now().UTC().Add(-2*maxWindow))
+		if err != nil {
+			pkgLogger.Errorf("Failed to delete expired rate limit counters: %v", err)
+		}
+	}
+}
+
+// Inc increments current window limit counter for key
+func (p *postgresLimitStoreT) Inc(key string, window time.Time) error {
+	sqlStmt := fmt.Sprintf(`INSERT INTO %[1]s (key, window_start, count) VALUES ($1, $2, 1)
+								ON CONFLICT (key, window_start) DO UPDATE SET count = %[1]s.count + 1`, rateLimitsTable)
+	_, err := p.dbHandle.Exec(sqlStmt, key, window.UTC())
+	return err
+}
+
+// IncBy increments current window limit counter for key by count and returns previous window counter and current window counter including the increment.
+// The upsert locks the row of the current window, so concurrent increments of key are serialized
+func (p *postgresLimitStoreT) IncBy(key string, count int64, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error) {
+	sqlStmt := fmt.Sprintf(`WITH counter AS (
+									INSERT INTO %[1]s (key, window_start, count) VALUES ($1, $2, $3)
+									ON CONFLICT (key, window_start) DO UPDATE SET count = %[1]s.count + EXCLUDED.count
+									RETURNING count)
+								SELECT COALESCE((SELECT count FROM %[1]s WHERE key = $1 AND window_start = $4), 0), (SELECT count FROM counter)`, rateLimitsTable)
+	err = p.dbHandle.QueryRow(sqlStmt, key, currentWindow.UTC(), count, previousWindow.UTC()).Scan(&prevValue, &currValue)
+	return
+}
+
+// Dec decrements current window limit counter for key
+func (p *postgresLimitStoreT) Dec(key string, count int64, window time.Time) error {
+	sqlStmt := fmt.Sprintf(`UPDATE %s SET count = GREATEST(count - $3, 0) WHERE key = $1 AND window_start = $2`, rateLimitsTable)
+	_, err := p.dbHandle.Exec(sqlStmt, key, window.UTC(), count)
+	return err
+}
+
+// Get gets value of previous window counter and current window counter for key
+func (p *postgresLimitStoreT) Get(key string, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error) {
+	sqlStmt := fmt.Sprintf(`SELECT window_start, count FROM %s WHERE key = $1 AND window_start IN ($2, $3)`, rateLimitsTable)
+	rows, err := p.dbHandle.Query(sqlStmt, key, previousWindow.UTC(), currentWindow.UTC())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var windowStart time.Time
+		var count int64
+		err = rows.Scan(&windowStart, &count)
+		if err != nil {
+			return
+		}
+		if windowStart.Equal(currentWindow.UTC()) {
+			currValue = count
+		} else {
+			prevValue = count
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// redisLimitStoreT keeps window counters in redis through kvstoremanager
+type redisLimitStoreT struct {
+	kvManager kvstoremanager.KVStoreManager
+}
+
+func newRedisLimitStore() (*redisLimitStoreT, error) {
+	address := config.GetString("RateLimit.redis.address", "localhost:6379")
+	kvManager := kvstoremanager.New("REDIS", map[string]interface{}{
+		"address":     address,
+		"password":    config.GetString("RateLimit.redis.password", ""),
+		"database":    config.GetString("RateLimit.redis.database", "0"),
+		"clusterMode": config.GetBool("RateLimit.redis.clusterMode", false),
+		"secure":      config.GetBool("RateLimit.redis.secure", false),
+	})
+	if kvManager == nil {
+		return nil, fmt.Errorf("could not create redis client for %s", address)
+	}
+	return &redisLimitStoreT{kvManager: kvManager}, nil
+}
+
+// redisKey wraps key in a hash tag so that counters of all windows of a key are in the same cluster slot
+func redisKey(key string, window time.Time) string {
+	return fmt.Sprintf("gw_rate_limit:{%s}:%d", key, window.Unix())
+}
+
+// Inc increments current window limit counter for key
+func (r *redisLimitStoreT) Inc(key string, window time.Time) error {
+	_, err := r.kvManager.IncrBy(redisKey(key, window), 1, 2*maxWindow)
+	return err
+}
+
+// IncBy increments current window limit counter for key by count and returns previous window counter and current window counter including the increment.
+// INCRBY returns the counter it set, so concurrent increments of key never see the same count
+func (r *redisLimitStoreT) IncBy(key string, count int64, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error) {
+	currValue, err = r.kvManager.IncrBy(redisKey(key, currentWindow), count, 2*maxWindow)
+	if err != nil {
+		return
+	}
+	values, err := r.kvManager.MGetInt64(redisKey(key, previousWindow))
+	if err != nil {
+		return
+	}
+	return values[0], currValue, nil
+}
+
+// Dec decrements current window limit counter for key
+func (r *redisLimitStoreT) Dec(key string, count int64, window time.Time) error {
+	_, err := r.kvManager.IncrBy(redisKey(key, window), -count, 2*maxWindow)
+	return err
+}
+
+// Get gets value of previous window counter and current window counter for key
+func (r *redisLimitStoreT) Get(key string, previousWindow, currentWindow time.Time) (prevValue int64, currValue int64, err error) {
+	values, err := r.kvManager.MGetInt64(redisKey(key, previousWindow), redisKey(key, currentWindow))
+	if err != nil {
+		return
+	}
+	return values[0], values[1], nil
+}
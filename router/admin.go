@@ -62,6 +62,12 @@ func (ra *RouterAdmin) Status() interface{} {
 		if len(abortedUsersMap) > 0 {
 			routerStatus["aborted-usersmap"] = abortedUsersMap
 		}
+		if router.circuitBreaker != nil && router.circuitBreaker.IsEnabled() {
+			routerStatus["circuit-breakers"] = router.circuitBreaker.Status()
+		}
+		if router.jobLeaser != nil && router.jobLeaser.IsEnabled() {
+			routerStatus["job-leases"] = router.jobLeaser.Status()
+		}
 
 		statusList = append(statusList, routerStatus)
 	}
@@ -833,7 +833,7 @@ func (brt *HandleT) postToWarehouse(batchJobs *BatchJobsT, output StorageUploadO
 		SourceJobRunID:   sampleParameters.SourceJobRunID,
 	}
 
-	if brt.destType == "S3_DATALAKE" {
+	if misc.ContainsString(warehouseutils.TimeWindowDestinations, brt.destType) {
 		payload.TimeWindow = batchJobs.TimeWindow
 	}
 
@@ -915,7 +915,7 @@ func (brt *HandleT) setJobStatus(batchJobs *BatchJobsT, isWarehouse bool, err er
 
 		timeElapsed := time.Since(firstAttemptedAt)
 		if jobState == jobsdb.Failed.State && timeElapsed > brt.retryTimeWindow && job.LastJobStatus.AttemptNum >= brt.maxFailedCountForJob && !postToWarehouseErr {
-			job.Parameters = misc.UpdateJSONWithNewKeyVal(job.Parameters, "stage", "batch_router")
+			setAbortParameters(job, errorResp)
 			abortedEvents = append(abortedEvents, job)
 			jobState = jobsdb.Aborted.State
 		} else {
@@ -923,7 +923,7 @@ func (brt *HandleT) setJobStatus(batchJobs *BatchJobsT, isWarehouse bool, err er
 			if jobState == jobsdb.Failed.State && isWarehouse && postToWarehouseErr {
 				warehouseServiceFailedTimeLock.RLock()
 				if time.Since(warehouseServiceFailedTime) > warehouseServiceMaxRetryTime {
-					job.Parameters = misc.UpdateJSONWithNewKeyVal(job.Parameters, "stage", "batch_router")
+					setAbortParameters(job, errorResp)
 					abortedEvents = append(abortedEvents, job)
 					jobState = jobsdb.Aborted.State
 				}
@@ -1133,6 +1133,13 @@ func (brt *HandleT) setMultipleJobStatus(asyncOutput asyncdestinationmanager.Asy
 	brt.jobsDB.ReleaseUpdateJobStatusLocks()
 }
 
+//setAbortParameters adds the stage and error of an aborted job to its parameters, as the router does, before it is stored in the proc error db
+func setAbortParameters(job *jobsdb.JobT, errorResp []byte) {
+	job.Parameters = misc.UpdateJSONWithNewKeyVal(job.Parameters, "stage", "batch_router")
+	job.Parameters = misc.UpdateJSONWithNewKeyVal(job.Parameters, "error_code", strconv.Itoa(getBRTErrorCode(jobsdb.Aborted.State)))
+	job.Parameters = misc.UpdateJSONWithNewKeyVal(job.Parameters, "error_response", errorResp)
+}
+
 func getBRTErrorCode(state string) int {
 	if state == jobsdb.Succeeded.State {
 		return 200
@@ -1715,8 +1722,8 @@ func IsWarehouseDestination(destType string) bool {
 
 func (brt *HandleT) splitBatchJobsOnTimeWindow(batchJobs BatchJobsT) map[time.Time]*BatchJobsT {
 	var splitBatches = map[time.Time]*BatchJobsT{}
-	if brt.destType != "S3_DATALAKE" {
-		// return only one batchJob if the destination type is not s3 datalake
+	if !misc.ContainsString(warehouseutils.TimeWindowDestinations, brt.destType) {
+		// return only one batchJob if the destination type is not a datalake
 		splitBatches[time.Time{}] = &batchJobs
 		return splitBatches
 	}
@@ -1778,7 +1785,7 @@ func loadConfig() {
 	config.RegisterDurationConfigVariable(time.Duration(2), &mainLoopSleep, true, time.Second, []string{"BatchRouter.mainLoopSleep", "BatchRouter.mainLoopSleepInS"}...)
 	config.RegisterInt64ConfigVariable(30, &uploadFreqInS, true, 1, "BatchRouter.uploadFreqInS")
 	objectStorageDestinations = []string{"S3", "GCS", "AZURE_BLOB", "MINIO", "DIGITAL_OCEAN_SPACES"}
-	warehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE"}
+	warehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "DUCKDB", "DELTALAKE"}
 	asyncDestinations = []string{"MARKETO_BULK_UPLOAD"}
 	warehouseURL = misc.GetWarehouseURL()
 	// Time period for diagnosis ticker
@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	uuid "github.com/satori/go.uuid"
+	"github.com/tidwall/gjson"
 
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/jobsdb"
@@ -249,6 +250,17 @@ var _ = Describe("BatchRouter", func() {
 	})
 })
 
+var _ = Describe("setAbortParameters", func() {
+	It("should add the stage and error of the aborted job to its parameters", func() {
+		job := &jobsdb.JobT{Parameters: []byte(`{"source_id": "source-1", "destination_id": "dest-1"}`)}
+		setAbortParameters(job, []byte(`{"error":"upload failed","firstAttemptedAt":"2021-06-28T15:57:30.742+05:30"}`))
+		Expect(gjson.GetBytes(job.Parameters, "stage").String()).To(Equal("batch_router"))
+		Expect(gjson.GetBytes(job.Parameters, "error_code").String()).To(Equal("500"))
+		Expect(gjson.GetBytes(job.Parameters, "error_response").String()).To(ContainSubstring("upload failed"))
+		Expect(gjson.GetBytes(job.Parameters, "destination_id").String()).To(Equal("dest-1"))
+	})
+})
+
 func assertJobStatus(job *jobsdb.JobT, status *jobsdb.JobStatusT, expectedState string, errorCode string, errorResponse string, attemptNum int) {
 	Expect(status.JobID).To(Equal(job.JobID))
 	Expect(status.JobState).To(Equal(expectedState))
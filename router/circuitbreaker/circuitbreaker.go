@@ -0,0 +1,258 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+const (
+	CLOSED    = "closed"
+	OPEN      = "open"
+	HALF_OPEN = "half-open"
+)
+
+//stateGaugeValues are the values the state gauge of a destination is set to
+var stateGaugeValues = map[string]int{
+	CLOSED:    0,
+	HALF_OPEN: 1,
+	OPEN:      2,
+}
+
+//CircuitBreaker is an interface for pausing delivery to destinations that keep failing
+type CircuitBreaker interface {
+	Allow(destID string) bool
+	Release(destID string)
+	RecordResult(destID string, success bool)
+	Status() map[string]StatusT
+	IsEnabled() bool
+}
+
+//Settings of a circuit breaker. The circuit of a destination opens once at least MinRequests were made to it in Window
+//and FailureRateThreshold of them failed. After OpenDuration it half-opens, letting HalfOpenProbes jobs through:
+//the circuit closes once all of them succeed and opens again on the first failure
+type Settings struct {
+	Enabled              bool
+	FailureRateThreshold float64
+	MinRequests          int
+	Window               time.Duration
+	OpenDuration         time.Duration
+	HalfOpenProbes       int
+}
+
+//StatusT is the state of the circuit of a destination, as shown by the router admin status
+type StatusT struct {
+	State       string    `json:"state"`
+	Requests    int       `json:"requests"`
+	Failures    int       `json:"failures"`
+	OpenedAt    time.Time `json:"openedAt,omitempty"`
+	Transitions int       `json:"transitions"`
+}
+
+type breakerT struct {
+	state          string
+	windowStart    time.Time
+	requests       int
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+	probesSentAt   time.Time
+	transitions    int
+	stateStat      stats.RudderStats
+}
+
+//HandleT is a Handle for the circuit breakers of the destinations of a router
+type HandleT struct {
+	destinationName string
+	settings        Settings
+	breakers        map[string]*breakerT
+	breakersLock    sync.Mutex
+}
+
+var pkgLogger logger.LoggerI
+
+func loadSettings(destName string) Settings {
+	var settings Settings
+	config.RegisterBoolConfigVariable(false, &settings.Enabled, false, keys(destName, "enabled")...)
+	config.RegisterFloat64ConfigVariable(0.5, &settings.FailureRateThreshold, false, keys(destName, "failureRateThreshold")...)
+	config.RegisterIntConfigVariable(20, &settings.MinRequests, false, 1, keys(destName, "minRequests")...)
+	config.RegisterDurationConfigVariable(60, &settings.Window, false, time.Second, keys(destName, "window")...)
+	config.RegisterDurationConfigVariable(30, &settings.OpenDuration, false, time.Second, keys(destName, "openDuration")...)
+	config.RegisterIntConfigVariable(5, &settings.HalfOpenProbes, false, 1, keys(destName, "halfOpenProbes")...)
+	return settings
+}
+
+func keys(destName, key string) []string {
+	return []string{fmt.Sprintf(`Router.circuitBreaker.%s.%s`, destName, key), fmt.Sprintf(`Router.circuitBreaker.%s`, key)}
+}
+
+//SetUp circuit breakers of the destinations of destName with settings from config
+func (cb *HandleT) SetUp(destName string) {
+	cb.SetUpWithSettings(destName, loadSettings(destName))
+}
+
+//SetUpWithSettings sets up circuit breakers of the destinations of destName with the given settings
+func (cb *HandleT) SetUpWithSettings(destName string, settings Settings) {
+	pkgLogger = logger.NewLogger().Child("router").Child("circuitbreaker")
+	cb.destinationName = destName
+	cb.settings = settings
+	if cb.settings.HalfOpenProbes < 1 {
+		cb.settings.HalfOpenProbes = 1
+	}
+	cb.breakers = make(map[string]*breakerT)
+	if cb.settings.Enabled {
+		pkgLogger.Infof(`[[ %s-router-circuitbreaker: Enabled circuit breaker with failureRateThreshold: %v, minRequests: %d, window: %v, openDuration: %v]]`, destName, cb.settings.FailureRateThreshold, cb.settings.MinRequests, cb.settings.Window, cb.settings.OpenDuration)
+	}
+}
+
+//IsEnabled returns whether circuit breaking is enabled for the destination type
+func (cb *HandleT) IsEnabled() bool {
+	return cb.settings.Enabled
+}
+
+//Allow returns whether a job of the destination can be sent. Jobs are always allowed while the circuit is closed,
+//never while it is open, and up to HalfOpenProbes at a time while it is half-open.
+//Every allowed job has to be either released or have its result recorded
+func (cb *HandleT) Allow(destID string) bool {
+	if !cb.settings.Enabled {
+		return true
+	}
+	cb.breakersLock.Lock()
+	defer cb.breakersLock.Unlock()
+
+	breaker := cb.getBreaker(destID)
+	if breaker.state == OPEN && time.Since(breaker.openedAt) >= cb.settings.OpenDuration {
+		cb.transition(destID, breaker, HALF_OPEN)
+	}
+	switch breaker.state {
+	case OPEN:
+		return false
+	case HALF_OPEN:
+		//probes whose results didn't come back in another open duration are not waited for anymore
+		if breaker.probesInFlight >= cb.settings.HalfOpenProbes && time.Since(breaker.probesSentAt) >= cb.settings.OpenDuration {
+			breaker.probesInFlight = 0
+		}
+		if breaker.probesInFlight >= cb.settings.HalfOpenProbes {
+			return false
+		}
+		breaker.probesInFlight++
+		breaker.probesSentAt = time.Now()
+	}
+	return true
+}
+
+//Release gives back a job allowed by Allow that was not sent after all
+func (cb *HandleT) Release(destID string) {
+	if !cb.settings.Enabled {
+		return
+	}
+	cb.breakersLock.Lock()
+	defer cb.breakersLock.Unlock()
+
+	breaker := cb.getBreaker(destID)
+	if breaker.state == HALF_OPEN && breaker.probesInFlight > 0 {
+		breaker.probesInFlight--
+	}
+}
+
+//RecordResult records the result of sending a job to the destination, opening or closing its circuit as needed.
+//Results of jobs sent before the circuit opened are ignored
+func (cb *HandleT) RecordResult(destID string, success bool) {
+	if !cb.settings.Enabled {
+		return
+	}
+	cb.breakersLock.Lock()
+	defer cb.breakersLock.Unlock()
+
+	breaker := cb.getBreaker(destID)
+	switch breaker.state {
+	case CLOSED:
+		now := time.Now()
+		if now.Sub(breaker.windowStart) > cb.settings.Window {
+			breaker.windowStart = now
+			breaker.requests = 0
+			breaker.failures = 0
+		}
+		breaker.requests++
+		if !success {
+			breaker.failures++
+		}
+		if breaker.requests >= cb.settings.MinRequests && float64(breaker.failures)/float64(breaker.requests) >= cb.settings.FailureRateThreshold {
+			cb.transition(destID, breaker, OPEN)
+		}
+	case HALF_OPEN:
+		if breaker.probesInFlight > 0 {
+			breaker.probesInFlight--
+		}
+		if !success {
+			cb.transition(destID, breaker, OPEN)
+			return
+		}
+		breaker.probeSuccesses++
+		if breaker.probeSuccesses >= cb.settings.HalfOpenProbes {
+			cb.transition(destID, breaker, CLOSED)
+		}
+	}
+}
+
+//Status returns the state of the circuit of every destination jobs were sent to
+func (cb *HandleT) Status() map[string]StatusT {
+	cb.breakersLock.Lock()
+	defer cb.breakersLock.Unlock()
+
+	status := make(map[string]StatusT, len(cb.breakers))
+	for destID, breaker := range cb.breakers {
+		status[destID] = StatusT{
+			State:       breaker.state,
+			Requests:    breaker.requests,
+			Failures:    breaker.failures,
+			OpenedAt:    breaker.openedAt,
+			Transitions: breaker.transitions,
+		}
+	}
+	return status
+}
+
+func (cb *HandleT) getBreaker(destID string) *breakerT {
+	breaker, ok := cb.breakers[destID]
+	if !ok {
+		breaker = &breakerT{
+			state:       CLOSED,
+			windowStart: time.Now(),
+			stateStat: stats.NewTaggedStat("router_circuit_breaker_state", stats.GaugeType, stats.Tags{
+				"destType": cb.destinationName,
+				"destId":   destID,
+			}),
+		}
+		cb.breakers[destID] = breaker
+	}
+	return breaker
+}
+
+func (cb *HandleT) transition(destID string, breaker *breakerT, state string) {
+	pkgLogger.Infof(`[[ %s-router-circuitbreaker: Circuit of destination %s is %s, was %s with %d/%d failed requests]]`, cb.destinationName, destID, state, breaker.state, breaker.failures, breaker.requests)
+	breaker.state = state
+	breaker.transitions++
+	breaker.probesInFlight = 0
+	breaker.probeSuccesses = 0
+	switch state {
+	case OPEN:
+		breaker.openedAt = time.Now()
+	case CLOSED:
+		breaker.openedAt = time.Time{}
+		breaker.windowStart = time.Now()
+		breaker.requests = 0
+		breaker.failures = 0
+	}
+	breaker.stateStat.Gauge(stateGaugeValues[state])
+	stats.NewTaggedStat("router_circuit_breaker_transitions", stats.CountType, stats.Tags{
+		"destType": cb.destinationName,
+		"destId":   destID,
+		"state":    state,
+	}).Increment()
+}
@@ -0,0 +1,103 @@
+package circuitbreaker_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/router/circuitbreaker"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+var _ = Describe("CircuitBreaker", func() {
+	var breaker *circuitbreaker.HandleT
+
+	BeforeEach(func() {
+		stats.Setup()
+		breaker = &circuitbreaker.HandleT{}
+		breaker.SetUpWithSettings("WEBHOOK", circuitbreaker.Settings{
+			Enabled:              true,
+			FailureRateThreshold: 0.5,
+			MinRequests:          4,
+			Window:               time.Minute,
+			OpenDuration:         50 * time.Millisecond,
+			HalfOpenProbes:       2,
+		})
+	})
+
+	It("should allow every job when disabled", func() {
+		breaker.SetUpWithSettings("WEBHOOK", circuitbreaker.Settings{MinRequests: 1})
+		breaker.RecordResult("dest-1", false)
+		Expect(breaker.IsEnabled()).To(BeFalse())
+		Expect(breaker.Allow("dest-1")).To(BeTrue())
+		Expect(breaker.Status()).To(BeEmpty())
+	})
+
+	It("should open once the failure rate reaches the threshold", func() {
+		breaker.RecordResult("dest-1", false)
+		breaker.RecordResult("dest-1", false)
+		breaker.RecordResult("dest-1", false)
+		Expect(breaker.Allow("dest-1")).To(BeTrue(), "fewer requests than the minimum were made")
+
+		breaker.RecordResult("dest-1", true)
+		Expect(breaker.Allow("dest-1")).To(BeFalse())
+		Expect(breaker.Allow("dest-2")).To(BeTrue(), "circuits are per destination")
+		Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.OPEN))
+		Expect(breaker.Status()["dest-1"].Failures).To(Equal(3))
+		Expect(breaker.Status()["dest-2"].State).To(Equal(circuitbreaker.CLOSED))
+	})
+
+	It("should stay closed while the failure rate is below the threshold", func() {
+		for i := 0; i < 10; i++ {
+			breaker.RecordResult("dest-1", i%4 != 0)
+		}
+		Expect(breaker.Allow("dest-1")).To(BeTrue())
+		Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.CLOSED))
+	})
+
+	Context("when open", func() {
+		BeforeEach(func() {
+			for i := 0; i < 4; i++ {
+				breaker.RecordResult("dest-1", false)
+			}
+			Expect(breaker.Allow("dest-1")).To(BeFalse())
+			time.Sleep(60 * time.Millisecond)
+		})
+
+		It("should half-open after the open duration, allowing a limited number of probes", func() {
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.HALF_OPEN))
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			Expect(breaker.Allow("dest-1")).To(BeFalse())
+
+			breaker.Release("dest-1")
+			Expect(breaker.Allow("dest-1")).To(BeTrue(), "released probes can be used again")
+		})
+
+		It("should close when every probe succeeds", func() {
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			breaker.RecordResult("dest-1", true)
+			Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.HALF_OPEN))
+			breaker.RecordResult("dest-1", true)
+
+			Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.CLOSED))
+			Expect(breaker.Status()["dest-1"].Requests).To(BeZero())
+			for i := 0; i < 5; i++ {
+				Expect(breaker.Allow("dest-1")).To(BeTrue())
+			}
+		})
+
+		It("should open again when a probe fails", func() {
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			Expect(breaker.Allow("dest-1")).To(BeTrue())
+			breaker.RecordResult("dest-1", true)
+			breaker.RecordResult("dest-1", false)
+
+			Expect(breaker.Status()["dest-1"].State).To(Equal(circuitbreaker.OPEN))
+			Expect(breaker.Allow("dest-1")).To(BeFalse())
+			Expect(breaker.Status()["dest-1"].Transitions).To(Equal(3))
+		})
+	})
+})
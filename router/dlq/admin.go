@@ -0,0 +1,102 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+//DLQRpcHandler exposes the dlq over the admin rpc interface. Arguments and results are json
+type DLQRpcHandler struct {
+	dlq *HandleT
+}
+
+func marshalResult(value interface{}, result *string) error {
+	response, err := json.MarshalIndent(value, "", " ")
+	if err != nil {
+		*result = ""
+		return err
+	}
+	*result = string(response)
+	return nil
+}
+
+//GetAbortedJobs lists the aborted jobs matching the FilterT in arg
+func (h *DLQRpcHandler) GetAbortedJobs(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var filter FilterT
+	if err = json.Unmarshal([]byte(arg), &filter); err != nil {
+		return err
+	}
+	abortedJobs, err := h.dlq.GetAbortedJobs(filter)
+	if err != nil {
+		return err
+	}
+	return marshalResult(abortedJobs, result)
+}
+
+//GetAbortedJobCounts counts the aborted jobs matching the FilterT in arg by destination, stage and error code
+func (h *DLQRpcHandler) GetAbortedJobCounts(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var filter FilterT
+	if err = json.Unmarshal([]byte(arg), &filter); err != nil {
+		return err
+	}
+	counts, err := h.dlq.GetAbortedJobCounts(filter)
+	if err != nil {
+		return err
+	}
+	return marshalResult(counts, result)
+}
+
+//Replay re-enqueues the aborted jobs selected by the ReplayRequestT in arg
+func (h *DLQRpcHandler) Replay(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var request ReplayRequestT
+	if err = json.Unmarshal([]byte(arg), &request); err != nil {
+		return err
+	}
+	replay, replayErr := h.dlq.Replay(request)
+	if replay.ID != "" {
+		if err = marshalResult(replay, result); err != nil {
+			return err
+		}
+	}
+	return replayErr
+}
+
+//GetReplays lists the latest replays, arg being the number of replays to list
+func (h *DLQRpcHandler) GetReplays(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var limit int
+	if arg != "" {
+		if limit, err = strconv.Atoi(arg); err != nil {
+			return err
+		}
+	}
+	replays, err := h.dlq.GetReplays(limit)
+	if err != nil {
+		return err
+	}
+	return marshalResult(replays, result)
+}
@@ -0,0 +1,528 @@
+package dlq
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/admin"
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+const (
+	ROUTER_STAGE       = "router"
+	BATCH_ROUTER_STAGE = "batch_router"
+	REPLAYS_TABLE      = "dlq_replays"
+)
+
+// statuses of replays, which claim their jobs while running
+const (
+	RUNNING   = "running"
+	SUCCEEDED = "succeeded"
+	FAILED    = "failed"
+)
+
+var (
+	maxJobsPerReplay int
+	defaultListLimit int
+	pkgLogger        logger.LoggerI
+)
+
+// parameters set on jobs when they are aborted, which are removed from replayed jobs
+var abortParameters = []string{"stage", "error", "error_code", "error_response"}
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("router").Child("dlq")
+}
+
+func loadConfig() {
+	config.RegisterIntConfigVariable(10000, &maxJobsPerReplay, true, 1, "Router.dlq.maxJobsPerReplay")
+	config.RegisterIntConfigVariable(100, &defaultListLimit, true, 1, "Router.dlq.defaultListLimit")
+}
+
+// FilterT selects aborted router and batch router jobs from the proc error db.
+// Empty fields don't filter, From and To bound the time jobs were aborted at
+type FilterT struct {
+	DestType      string    `json:"destType"`
+	DestinationID string    `json:"destinationId"`
+	ErrorCode     string    `json:"errorCode"`
+	Stage         string    `json:"stage"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	JobIDs        []int64   `json:"jobIds"`
+	Limit         int       `json:"limit"`
+}
+
+// AbortedJobT is a job aborted by the router or the batch router
+type AbortedJobT struct {
+	JobID         int64           `json:"jobId"`
+	UserID        string          `json:"userId"`
+	AbortedAt     time.Time       `json:"abortedAt"`
+	DestType      string          `json:"destType"`
+	DestinationID string          `json:"destinationId"`
+	SourceID      string          `json:"sourceId"`
+	Stage         string          `json:"stage"`
+	ErrorCode     string          `json:"errorCode"`
+	ErrorResponse json.RawMessage `json:"errorResponse"`
+	EventPayload  json.RawMessage `json:"eventPayload"`
+	Parameters    json.RawMessage `json:"parameters"`
+}
+
+// AbortedJobCountT is the number of aborted jobs of a destination with an error code
+type AbortedJobCountT struct {
+	DestType      string `json:"destType"`
+	DestinationID string `json:"destinationId"`
+	Stage         string `json:"stage"`
+	ErrorCode     string `json:"errorCode"`
+	Count         int    `json:"count"`
+}
+
+// PatchT sets Value at Path of the payload of replayed jobs, or deletes Path if Delete is set.
+// Paths are in sjson syntax, e.g. body.JSON.properties.price
+type PatchT struct {
+	Path   string      `json:"path"`
+	Value  interface{} `json:"value"`
+	Delete bool        `json:"delete"`
+}
+
+// ReplayRequestT re-enqueues the jobs matching Filter into the jobsdb of the stage they were aborted at, after applying Patches.
+// Jobs replayed before are skipped unless Force is set.
+// Operator is who the caller says they are: the admin interface doesn't authenticate callers, so it is recorded as a claim
+type ReplayRequestT struct {
+	Filter   FilterT  `json:"filter"`
+	Patches  []PatchT `json:"patches"`
+	Operator string   `json:"operator"`
+	Reason   string   `json:"reason"`
+	Force    bool     `json:"force"`
+}
+
+// ReplayT is the audit record of a replay. ClaimedOperator is the unverified operator of the request.
+// JobIDs are the jobs the replay enqueued, or is enqueuing while running
+type ReplayT struct {
+	ID              string    `json:"id"`
+	ClaimedOperator string    `json:"claimedOperator"`
+	Reason          string    `json:"reason"`
+	Filter          FilterT   `json:"filter"`
+	Patches         []PatchT  `json:"patches"`
+	JobIDs          []int64   `json:"jobIds"`
+	SkippedJobIDs   []int64   `json:"skippedJobIds"`
+	RouterJobs      int       `json:"routerJobs"`
+	BatchRouterJobs int       `json:"batchRouterJobs"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// HandleT lists aborted jobs of the proc error db and replays them into the router and batch router dbs
+type HandleT struct {
+	errorDB       jobsdb.ReadonlyJobsDB
+	dbHandle      *sql.DB
+	routerDB      jobsdb.JobsDB
+	batchRouterDB jobsdb.JobsDB
+	logger        logger.LoggerI
+}
+
+// Setup initializes the dlq and registers its admin handler
+func (dlq *HandleT) Setup(readonlyErrorDB *jobsdb.ReadonlyHandleT, routerDB, batchRouterDB jobsdb.JobsDB) {
+	dlq.logger = pkgLogger
+	dlq.errorDB = readonlyErrorDB
+	dlq.dbHandle = readonlyErrorDB.DbHandle
+	dlq.routerDB = routerDB
+	dlq.batchRouterDB = batchRouterDB
+	admin.RegisterAdminHandler("DLQ", &DLQRpcHandler{dlq: dlq})
+}
+
+// whereClause builds the conditions of filter on the jobs table aliased j, along with their arguments
+func (filter *FilterT) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Stage != "" {
+		addCondition(`j.parameters->>'stage' = $%d`, filter.Stage)
+	} else {
+		addCondition(`j.parameters->>'stage' = ANY($%d)`, pq.Array([]string{ROUTER_STAGE, BATCH_ROUTER_STAGE}))
+	}
+	if filter.DestType != "" {
+		addCondition(`j.custom_val = $%d`, filter.DestType)
+	}
+	if filter.DestinationID != "" {
+		addCondition(`j.parameters->>'destination_id' = $%d`, filter.DestinationID)
+	}
+	if filter.ErrorCode != "" {
+		addCondition(`j.parameters->>'error_code' = $%d`, filter.ErrorCode)
+	}
+	if !filter.From.IsZero() {
+		addCondition(`j.created_at >= $%d`, filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition(`j.created_at <= $%d`, filter.To)
+	}
+	if len(filter.JobIDs) > 0 {
+		addCondition(`j.job_id = ANY($%d)`, pq.Array(filter.JobIDs))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetAbortedJobs returns the aborted jobs matching filter, oldest first
+func (dlq *HandleT) GetAbortedJobs(filter FilterT) ([]AbortedJobT, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	whereClause, args := filter.whereClause()
+
+	dsPairs, err := dlq.errorDB.GetDSPairs()
+	if err != nil {
+		return nil, err
+	}
+	abortedJobs := make([]AbortedJobT, 0)
+	for _, ds := range dsPairs {
+		if len(abortedJobs) >= limit {
+			break
+		}
+		sqlStatement := fmt.Sprintf(`SELECT j.job_id, j.user_id, j.created_at, j.custom_val, j.event_payload, j.parameters FROM %s j
+									WHERE %s ORDER BY j.job_id LIMIT %d`, ds.JobTableName, whereClause, limit-len(abortedJobs))
+		rows, err := dlq.dbHandle.Query(sqlStatement, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var job AbortedJobT
+			var eventPayload, parameters []byte
+			err = rows.Scan(&job.JobID, &job.UserID, &job.AbortedAt, &job.DestType, &eventPayload, &parameters)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			// the payloads of the jobs are stored compressed in datasets created with jobsdb compression enabled
+			job.EventPayload, err = dlq.errorDB.DecompressPayload(eventPayload)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			job.Parameters = parameters
+			job.DestinationID = gjson.GetBytes(parameters, "destination_id").String()
+			job.SourceID = gjson.GetBytes(parameters, "source_id").String()
+			job.Stage = gjson.GetBytes(parameters, "stage").String()
+			job.ErrorCode = gjson.GetBytes(parameters, "error_code").String()
+			if errorResponse := gjson.GetBytes(parameters, "error_response"); errorResponse.Exists() {
+				job.ErrorResponse = json.RawMessage(errorResponse.Raw)
+			}
+			abortedJobs = append(abortedJobs, job)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return abortedJobs, nil
+}
+
+// GetAbortedJobCounts returns the number of aborted jobs matching filter per destination, stage and error code
+func (dlq *HandleT) GetAbortedJobCounts(filter FilterT) ([]AbortedJobCountT, error) {
+	whereClause, args := filter.whereClause()
+
+	dsPairs, err := dlq.errorDB.GetDSPairs()
+	if err != nil {
+		return nil, err
+	}
+	countsByKey := make(map[AbortedJobCountT]int)
+	for _, ds := range dsPairs {
+		sqlStatement := fmt.Sprintf(`SELECT j.custom_val, COALESCE(j.parameters->>'destination_id', ''), COALESCE(j.parameters->>'stage', ''),
+									COALESCE(j.parameters->>'error_code', ''), count(*) FROM %s j WHERE %s GROUP BY 1, 2, 3, 4`, ds.JobTableName, whereClause)
+		rows, err := dlq.dbHandle.Query(sqlStatement, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key AbortedJobCountT
+			var count int
+			err = rows.Scan(&key.DestType, &key.DestinationID, &key.Stage, &key.ErrorCode, &count)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			countsByKey[key] += count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make([]AbortedJobCountT, 0, len(countsByKey))
+	for key, count := range countsByKey {
+		key.Count = count
+		counts = append(counts, key)
+	}
+	return counts, nil
+}
+
+// getReplayedJobIDs returns which of jobIDs were replayed before, or are claimed by a running replay
+func getReplayedJobIDs(txn *sql.Tx, jobIDs []int64) (map[int64]bool, error) {
+	sqlStatement := fmt.Sprintf(`SELECT DISTINCT replayed.job_id FROM %s r, unnest(r.job_ids) AS replayed(job_id)
+								WHERE replayed.job_id = ANY($1)`, REPLAYS_TABLE)
+	rows, err := txn.Query(sqlStatement, pq.Array(jobIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replayedJobIDs := make(map[int64]bool)
+	for rows.Next() {
+		var jobID int64
+		if err = rows.Scan(&jobID); err != nil {
+			return nil, err
+		}
+		replayedJobIDs[jobID] = true
+	}
+	return replayedJobIDs, rows.Err()
+}
+
+// replayJob builds the job re-enqueuing an aborted job, with the patches applied to its payload
+func replayJob(abortedJob AbortedJobT, patches []PatchT, replayID string) (*jobsdb.JobT, error) {
+	eventPayload := []byte(abortedJob.EventPayload)
+	var err error
+	for _, patch := range patches {
+		if patch.Delete {
+			eventPayload, err = sjson.DeleteBytes(eventPayload, patch.Path)
+		} else {
+			eventPayload, err = sjson.SetBytes(eventPayload, patch.Path, patch.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to patch %s of job %d: %w", patch.Path, abortedJob.JobID, err)
+		}
+	}
+	if !json.Valid(eventPayload) {
+		return nil, fmt.Errorf("payload of job %d is not valid json after patching", abortedJob.JobID)
+	}
+
+	parameters := []byte(abortedJob.Parameters)
+	for _, key := range abortParameters {
+		parameters, err = sjson.DeleteBytes(parameters, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parameters, err = sjson.SetBytes(parameters, "dlq_replay_id", replayID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jobsdb.JobT{
+		UUID:         uuid.NewV4(),
+		UserID:       abortedJob.UserID,
+		CustomVal:    abortedJob.DestType,
+		EventPayload: eventPayload,
+		Parameters:   parameters,
+		CreatedAt:    time.Now(),
+		ExpireAt:     time.Now(),
+	}, nil
+}
+
+/*
+Replay re-enqueues the aborted jobs selected by the request. The replay is recorded as running first, claiming its jobs so that
+concurrent replays and retries skip them. If enqueuing fails, the replay is recorded as failed with the jobs enqueued already,
+so that a retry only enqueues the others. Jobs of replays left running by a crash are skipped unless Force is set
+*/
+func (dlq *HandleT) Replay(request ReplayRequestT) (ReplayT, error) {
+	if request.Operator == "" {
+		return ReplayT{}, fmt.Errorf("operator is required to replay jobs")
+	}
+	filter := request.Filter
+	if filter.Limit <= 0 || filter.Limit > maxJobsPerReplay {
+		filter.Limit = maxJobsPerReplay
+	}
+	abortedJobs, err := dlq.GetAbortedJobs(filter)
+	if err != nil {
+		return ReplayT{}, err
+	}
+
+	replay := ReplayT{
+		ID:              uuid.NewV4().String(),
+		ClaimedOperator: request.Operator,
+		Reason:          request.Reason,
+		Filter:          filter,
+		Patches:         request.Patches,
+		JobIDs:          make([]int64, 0),
+		SkippedJobIDs:   make([]int64, 0),
+		Status:          RUNNING,
+		CreatedAt:       time.Now(),
+	}
+	if len(abortedJobs) == 0 {
+		replay.Status = SUCCEEDED
+		return replay, nil
+	}
+
+	jobIDs := make([]int64, 0, len(abortedJobs))
+	for _, abortedJob := range abortedJobs {
+		jobIDs = append(jobIDs, abortedJob.JobID)
+	}
+	var routerJobs, batchRouterJobs []*jobsdb.JobT
+	var routerJobIDs []int64
+	err = dlq.claimJobs(&replay, jobIDs, request.Force, func(replayedJobIDs map[int64]bool) error {
+		for _, abortedJob := range abortedJobs {
+			if replayedJobIDs[abortedJob.JobID] {
+				replay.SkippedJobIDs = append(replay.SkippedJobIDs, abortedJob.JobID)
+				continue
+			}
+			job, err := replayJob(abortedJob, request.Patches, replay.ID)
+			if err != nil {
+				return err
+			}
+			switch abortedJob.Stage {
+			case ROUTER_STAGE:
+				routerJobs = append(routerJobs, job)
+				routerJobIDs = append(routerJobIDs, abortedJob.JobID)
+			case BATCH_ROUTER_STAGE:
+				batchRouterJobs = append(batchRouterJobs, job)
+			default:
+				replay.SkippedJobIDs = append(replay.SkippedJobIDs, abortedJob.JobID)
+				continue
+			}
+			replay.JobIDs = append(replay.JobIDs, abortedJob.JobID)
+		}
+		return nil
+	})
+	if err != nil {
+		return ReplayT{}, err
+	}
+
+	if len(routerJobs) > 0 {
+		if err = dlq.routerDB.Store(routerJobs); err != nil {
+			return dlq.failReplay(replay, nil, fmt.Errorf("failed to store replayed jobs in router db: %w", err))
+		}
+		replay.RouterJobs = len(routerJobs)
+	}
+	if len(batchRouterJobs) > 0 {
+		if err = dlq.batchRouterDB.Store(batchRouterJobs); err != nil {
+			return dlq.failReplay(replay, routerJobIDs, fmt.Errorf("failed to store replayed jobs in batch router db: %w", err))
+		}
+		replay.BatchRouterJobs = len(batchRouterJobs)
+	}
+	stats.NewTaggedStat("dlq_replayed_jobs", stats.CountType, stats.Tags{"stage": ROUTER_STAGE}).Count(replay.RouterJobs)
+	stats.NewTaggedStat("dlq_replayed_jobs", stats.CountType, stats.Tags{"stage": BATCH_ROUTER_STAGE}).Count(replay.BatchRouterJobs)
+
+	replay.Status = SUCCEEDED
+	err = dlq.finishReplay(replay)
+	if err != nil {
+		//jobs are enqueued and claimed by the running replay, so the replay is reported along with the error
+		dlq.logger.Errorf("DLQ: Failed to record replay %s of %v by claimed operator %s as succeeded: %v", replay.ID, replay.JobIDs, replay.ClaimedOperator, err)
+		return replay, fmt.Errorf("jobs were replayed but the replay could not be recorded as succeeded: %w", err)
+	}
+	dlq.logger.Infof("DLQ: Claimed operator %s replayed %d jobs into router db and %d jobs into batch router db with replay %s", replay.ClaimedOperator, replay.RouterJobs, replay.BatchRouterJobs, replay.ID)
+	return replay, nil
+}
+
+// failReplay records the replay as failed with err, keeping the claims on enqueuedJobIDs only
+func (dlq *HandleT) failReplay(replay ReplayT, enqueuedJobIDs []int64, err error) (ReplayT, error) {
+	replay.Status = FAILED
+	replay.Error = err.Error()
+	replay.JobIDs = append(make([]int64, 0, len(enqueuedJobIDs)), enqueuedJobIDs...)
+	replay.RouterJobs = len(enqueuedJobIDs)
+	replay.BatchRouterJobs = 0
+	if recordErr := dlq.finishReplay(replay); recordErr != nil {
+		dlq.logger.Errorf("DLQ: Failed to record replay %s by claimed operator %s as failed: %v", replay.ID, replay.ClaimedOperator, recordErr)
+		return replay, fmt.Errorf("%v, and the replay still claims its jobs as it could not be recorded as failed: %w", err, recordErr)
+	}
+	dlq.logger.Errorf("DLQ: Replay %s by claimed operator %s failed after enqueuing %v: %v", replay.ID, replay.ClaimedOperator, enqueuedJobIDs, err)
+	return replay, err
+}
+
+// claimJobs records the replay as running with the jobs selected by selectJobs, given which of jobIDs were replayed before.
+// Claims are made under an advisory lock, so concurrent replays don't select the same jobs
+func (dlq *HandleT) claimJobs(replay *ReplayT, jobIDs []int64, force bool, selectJobs func(replayedJobIDs map[int64]bool) error) error {
+	filter, err := json.Marshal(replay.Filter)
+	if err != nil {
+		return err
+	}
+	patches, err := json.Marshal(replay.Patches)
+	if err != nil {
+		return err
+	}
+
+	txn, err := dlq.dbHandle.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+	_, err = txn.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, REPLAYS_TABLE)
+	if err != nil {
+		return err
+	}
+	replayedJobIDs := make(map[int64]bool)
+	if !force {
+		replayedJobIDs, err = getReplayedJobIDs(txn, jobIDs)
+		if err != nil {
+			return err
+		}
+	}
+	if err = selectJobs(replayedJobIDs); err != nil {
+		return err
+	}
+	sqlStatement := fmt.Sprintf(`INSERT INTO %s (id, operator, reason, filter, patches, job_ids, skipped_job_ids, router_jobs, batch_router_jobs, status, created_at)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`, REPLAYS_TABLE)
+	_, err = txn.Exec(sqlStatement, replay.ID, replay.ClaimedOperator, replay.Reason, filter, patches, pq.Array(replay.JobIDs),
+		pq.Array(replay.SkippedJobIDs), replay.RouterJobs, replay.BatchRouterJobs, replay.Status, replay.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// finishReplay records the final status of a running replay, along with the jobs it enqueued
+func (dlq *HandleT) finishReplay(replay ReplayT) error {
+	sqlStatement := fmt.Sprintf(`UPDATE %s SET job_ids = $1, router_jobs = $2, batch_router_jobs = $3, status = $4, error = $5 WHERE id = $6`, REPLAYS_TABLE)
+	_, err := dlq.dbHandle.Exec(sqlStatement, pq.Array(replay.JobIDs), replay.RouterJobs, replay.BatchRouterJobs, replay.Status, replay.Error, replay.ID)
+	return err
+}
+
+// GetReplays returns the latest replays, newest first
+func (dlq *HandleT) GetReplays(limit int) ([]ReplayT, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	sqlStatement := fmt.Sprintf(`SELECT id, operator, reason, filter, patches, job_ids, skipped_job_ids, router_jobs, batch_router_jobs, status, error, created_at
+								FROM %s ORDER BY created_at DESC LIMIT %d`, REPLAYS_TABLE, limit)
+	rows, err := dlq.dbHandle.Query(sqlStatement)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replays := make([]ReplayT, 0)
+	for rows.Next() {
+		var replay ReplayT
+		var filter, patches []byte
+		err = rows.Scan(&replay.ID, &replay.ClaimedOperator, &replay.Reason, &filter, &patches, pq.Array(&replay.JobIDs),
+			pq.Array(&replay.SkippedJobIDs), &replay.RouterJobs, &replay.BatchRouterJobs, &replay.Status, &replay.Error, &replay.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(filter, &replay.Filter); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(patches, &replay.Patches); err != nil {
+			return nil, err
+		}
+		replays = append(replays, replay)
+	}
+	return replays, rows.Err()
+}
@@ -0,0 +1,218 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/klauspost/compress/zstd"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+//zstdReadonlyJobsDB is a proc error db of one dataset with zstd compressed payloads
+type zstdReadonlyJobsDB struct {
+	jobsdb.ReadonlyJobsDB
+}
+
+func (*zstdReadonlyJobsDB) GetDSPairs() ([]jobsdb.DSPair, error) {
+	return []jobsdb.DSPair{{JobTableName: "proc_error_jobs_1", JobStatusTableName: "proc_error_job_status_1"}}, nil
+}
+
+func (*zstdReadonlyJobsDB) DecompressPayload(payload []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(payload, nil)
+}
+
+//storingJobsDB records the jobs stored into it, failing to store them if err is set
+type storingJobsDB struct {
+	jobsdb.JobsDB
+	stored []*jobsdb.JobT
+	err    error
+}
+
+func (jd *storingJobsDB) Store(jobList []*jobsdb.JobT) error {
+	if jd.err != nil {
+		return jd.err
+	}
+	jd.stored = append(jd.stored, jobList...)
+	return nil
+}
+
+var _ = Describe("DLQ", func() {
+	Context("filters", func() {
+		It("should only select router and batch router jobs by default", func() {
+			filter := FilterT{}
+			whereClause, args := filter.whereClause()
+			Expect(whereClause).To(Equal(`j.parameters->>'stage' = ANY($1)`))
+			Expect(args).To(Equal([]interface{}{pq.Array([]string{ROUTER_STAGE, BATCH_ROUTER_STAGE})}))
+		})
+
+		It("should filter by destination, error code, time window and job ids", func() {
+			from := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+			to := from.Add(time.Hour)
+			filter := FilterT{Stage: ROUTER_STAGE, DestType: "WEBHOOK", DestinationID: "dest-1", ErrorCode: "400", From: from, To: to, JobIDs: []int64{1, 2}}
+			whereClause, args := filter.whereClause()
+			Expect(whereClause).To(Equal(`j.parameters->>'stage' = $1 AND j.custom_val = $2 AND j.parameters->>'destination_id' = $3 AND ` +
+				`j.parameters->>'error_code' = $4 AND j.created_at >= $5 AND j.created_at <= $6 AND j.job_id = ANY($7)`))
+			Expect(args).To(Equal([]interface{}{ROUTER_STAGE, "WEBHOOK", "dest-1", "400", from, to, pq.Array([]int64{1, 2})}))
+		})
+	})
+
+	Context("replayed jobs", func() {
+		var abortedJob AbortedJobT
+
+		BeforeEach(func() {
+			abortedJob = AbortedJobT{
+				JobID:        10,
+				UserID:       "user-1",
+				DestType:     "WEBHOOK",
+				Stage:        ROUTER_STAGE,
+				EventPayload: json.RawMessage(`{"body":{"JSON":{"price":"10","secret":"x"}},"endpoint":"https://example.com"}`),
+				Parameters:   json.RawMessage(`{"source_id":"source-1","destination_id":"dest-1","stage":"router","error_code":"400","error_response":{"reason":"bad request"}}`),
+			}
+		})
+
+		It("should drop the abort parameters and patch the payload", func() {
+			job, err := replayJob(abortedJob, []PatchT{{Path: "body.JSON.price", Value: 10}, {Path: "body.JSON.secret", Delete: true}}, "replay-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.UserID).To(Equal("user-1"))
+			Expect(job.CustomVal).To(Equal("WEBHOOK"))
+			Expect(string(job.EventPayload)).To(MatchJSON(`{"body":{"JSON":{"price":10}},"endpoint":"https://example.com"}`))
+			Expect(string(job.Parameters)).To(MatchJSON(`{"source_id":"source-1","destination_id":"dest-1","dlq_replay_id":"replay-1"}`))
+		})
+
+		It("should reject patches with invalid paths", func() {
+			_, err := replayJob(abortedJob, []PatchT{{Path: "", Value: 1}}, "replay-1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("should read the payloads of aborted jobs stored compressed", func() {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+		encoder, err := zstd.NewWriter(nil)
+		Expect(err).NotTo(HaveOccurred())
+		payload := `{"body":{"JSON":{"price":"10"}},"endpoint":"https://example.com"}`
+
+		filter := FilterT{Stage: ROUTER_STAGE}
+		whereClause, _ := filter.whereClause()
+		mock.ExpectQuery(fmt.Sprintf(`SELECT j.job_id, j.user_id, j.created_at, j.custom_val, j.event_payload, j.parameters FROM proc_error_jobs_1 j
+			WHERE %s ORDER BY j.job_id LIMIT %d`, whereClause, defaultListLimit)).WithArgs(ROUTER_STAGE).
+			WillReturnRows(sqlmock.NewRows([]string{"job_id", "user_id", "created_at", "custom_val", "event_payload", "parameters"}).
+				AddRow(10, "user-1", time.Now(), "WEBHOOK", encoder.EncodeAll([]byte(payload), nil), []byte(`{"destination_id":"dest-1","stage":"router","error_code":"400"}`)))
+
+		dlq := &HandleT{errorDB: &zstdReadonlyJobsDB{}, dbHandle: db}
+		abortedJobs, err := dlq.GetAbortedJobs(filter)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(abortedJobs).To(HaveLen(1))
+		Expect(string(abortedJobs[0].EventPayload)).To(MatchJSON(payload))
+
+		job, err := replayJob(abortedJobs[0], nil, "replay-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(job.EventPayload)).To(MatchJSON(payload))
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	It("should require an operator to replay jobs", func() {
+		dlq := &HandleT{}
+		_, err := dlq.Replay(ReplayRequestT{Filter: FilterT{DestType: "WEBHOOK"}})
+		Expect(err).To(MatchError("operator is required to replay jobs"))
+	})
+
+	Context("replays", func() {
+		var (
+			mock          sqlmock.Sqlmock
+			dlq           *HandleT
+			routerDB      *storingJobsDB
+			batchRouterDB *storingJobsDB
+		)
+
+		BeforeEach(func() {
+			stats.Setup()
+			db, sqlMock, err := sqlmock.New()
+			Expect(err).NotTo(HaveOccurred())
+			mock = sqlMock
+			routerDB = &storingJobsDB{}
+			batchRouterDB = &storingJobsDB{}
+			dlq = &HandleT{errorDB: &zstdReadonlyJobsDB{}, dbHandle: db, routerDB: routerDB, batchRouterDB: batchRouterDB, logger: pkgLogger}
+
+			encoder, err := zstd.NewWriter(nil)
+			Expect(err).NotTo(HaveOccurred())
+			payload := encoder.EncodeAll([]byte(`{"endpoint":"https://example.com"}`), nil)
+			mock.ExpectQuery(`SELECT j.job_id, j.user_id, j.created_at, j.custom_val, j.event_payload, j.parameters FROM proc_error_jobs_1 j`).
+				WillReturnRows(sqlmock.NewRows([]string{"job_id", "user_id", "created_at", "custom_val", "event_payload", "parameters"}).
+					AddRow(10, "user-1", time.Now(), "WEBHOOK", payload, []byte(`{"destination_id":"dest-1","stage":"router"}`)).
+					AddRow(11, "user-1", time.Now(), "S3", payload, []byte(`{"destination_id":"dest-2","stage":"batch_router"}`)))
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		expectClaim := func(replayedJobIDs []int64, claimedJobIDs []int64) {
+			mock.ExpectBegin()
+			mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs(REPLAYS_TABLE).WillReturnResult(sqlmock.NewResult(0, 0))
+			rows := sqlmock.NewRows([]string{"job_id"})
+			for _, jobID := range replayedJobIDs {
+				rows.AddRow(jobID)
+			}
+			mock.ExpectQuery(`SELECT DISTINCT replayed.job_id FROM dlq_replays`).WithArgs(pq.Array([]int64{10, 11})).WillReturnRows(rows)
+			mock.ExpectExec(`INSERT INTO dlq_replays`).
+				WithArgs(sqlmock.AnyArg(), "operator", "", sqlmock.AnyArg(), sqlmock.AnyArg(), pq.Array(claimedJobIDs), sqlmock.AnyArg(), 0, 0, RUNNING, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		It("should claim the jobs before enqueuing them and record the replay as succeeded", func() {
+			expectClaim(nil, []int64{10, 11})
+			mock.ExpectExec(`UPDATE dlq_replays SET job_ids`).
+				WithArgs(pq.Array([]int64{10, 11}), 1, 1, SUCCEEDED, "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			replay, err := dlq.Replay(ReplayRequestT{Operator: "operator"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replay.Status).To(Equal(SUCCEEDED))
+			Expect(routerDB.stored).To(HaveLen(1))
+			Expect(batchRouterDB.stored).To(HaveLen(1))
+		})
+
+		It("should only keep the claims on enqueued jobs when enqueuing fails", func() {
+			batchRouterDB.err = errors.New("connection refused")
+			expectClaim(nil, []int64{10, 11})
+			mock.ExpectExec(`UPDATE dlq_replays SET job_ids`).
+				WithArgs(pq.Array([]int64{10}), 1, 0, FAILED, "failed to store replayed jobs in batch router db: connection refused", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			replay, err := dlq.Replay(ReplayRequestT{Operator: "operator"})
+			Expect(err).To(MatchError(ContainSubstring("connection refused")))
+			Expect(replay.Status).To(Equal(FAILED))
+			Expect(replay.JobIDs).To(Equal([]int64{10}))
+			Expect(routerDB.stored).To(HaveLen(1))
+		})
+
+		It("should skip the jobs claimed by earlier replays when retrying", func() {
+			expectClaim([]int64{10}, []int64{11})
+			mock.ExpectExec(`UPDATE dlq_replays SET job_ids`).
+				WithArgs(pq.Array([]int64{11}), 0, 1, SUCCEEDED, "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			replay, err := dlq.Replay(ReplayRequestT{Operator: "operator"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replay.SkippedJobIDs).To(Equal([]int64{10}))
+			Expect(routerDB.stored).To(BeEmpty())
+			Expect(batchRouterDB.stored).To(HaveLen(1))
+		})
+	})
+})
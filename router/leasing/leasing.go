@@ -0,0 +1,424 @@
+package leasing
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+const (
+	LEASES_TABLE = "router_job_leases"
+	//NODE_ID_PARAMETER is the parameter of executing job statuses holding the node executing the job
+	NODE_ID_PARAMETER = "node_id"
+)
+
+//JobLeaser is an interface for sharing the jobs of a destination type among router nodes consuming the same jobs db.
+//Users are split in partitions and a partition is leased to one node at a time, keeping the jobs of a user in order
+type JobLeaser interface {
+	Allow(userID string) bool
+	Release(userID string)
+	ExecutingParameters() []byte
+	RecoverExecuting()
+	Status() StatusT
+	IsEnabled() bool
+}
+
+//Settings of a job leaser. Every HeartbeatInterval a node renews its leases for LeaseDuration and takes or hands over
+//partitions so that every live node holds a fair share of the Partitions. Nodes are identified by NodeID,
+//which has to be unique among the nodes and stay the same across restarts of a node, so it has to be set explicitly
+//with Router.leasing.nodeId or INSTANCE_ID when leasing is enabled
+type Settings struct {
+	Enabled           bool
+	NodeID            string
+	Partitions        int
+	LeaseDuration     time.Duration
+	HeartbeatInterval time.Duration
+}
+
+//StatusT is the state of the leases of a node, as shown by the router admin status
+type StatusT struct {
+	NodeID     string    `json:"nodeId"`
+	Partitions []int     `json:"partitions"`
+	Draining   []int     `json:"draining"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+//leaseT is the lease of a partition as stored in the leases table
+type leaseT struct {
+	partition int
+	nodeID    string
+	live      bool
+}
+
+//planT is what a node does with the leases of its destination type on a heartbeat
+type planT struct {
+	owned     []int
+	draining  []int
+	renew     []int
+	release   []int
+	deadNodes []string
+}
+
+//HandleT is a Handle for the job leases of the destinations of a router
+type HandleT struct {
+	destinationName     string
+	settings            Settings
+	dbHandle            *sql.DB
+	jobsDB              jobsdb.JobsDB
+	executingParameters []byte
+	owned               map[int]bool
+	drainingSince       map[int]time.Time
+	inFlight            map[int]int
+	expiresAt           time.Time
+	leasesLock          sync.Mutex
+	ownedStat           stats.RudderStats
+}
+
+var pkgLogger logger.LoggerI
+
+func loadSettings(destName string) Settings {
+	var settings Settings
+	config.RegisterBoolConfigVariable(false, &settings.Enabled, false, keys(destName, "enabled")...)
+	config.RegisterStringConfigVariable(config.GetInstanceID(), &settings.NodeID, false, keys(destName, "nodeId")...)
+	config.RegisterIntConfigVariable(64, &settings.Partitions, false, 1, keys(destName, "partitions")...)
+	config.RegisterDurationConfigVariable(30, &settings.LeaseDuration, false, time.Second, keys(destName, "leaseDuration")...)
+	config.RegisterDurationConfigVariable(10, &settings.HeartbeatInterval, false, time.Second, keys(destName, "heartbeatInterval")...)
+	return settings
+}
+
+//nodeIDIsSet returns whether the node id of destName is configured explicitly,
+//as the default INSTANCE_ID is the same on every node
+func nodeIDIsSet(destName string) bool {
+	for _, key := range keys(destName, "nodeId") {
+		if config.IsSet(key) {
+			return true
+		}
+	}
+	return config.IsEnvSet("INSTANCE_ID")
+}
+
+func keys(destName, key string) []string {
+	return []string{fmt.Sprintf(`Router.leasing.%s.%s`, destName, key), fmt.Sprintf(`Router.leasing.%s`, key)}
+}
+
+//SetUp job leases of the destinations of destName with settings from config
+func (lh *HandleT) SetUp(destName string, jobsDB jobsdb.JobsDB) {
+	settings := loadSettings(destName)
+	var dbHandle *sql.DB
+	if settings.Enabled {
+		if !nodeIDIsSet(destName) {
+			panic(fmt.Errorf("job leasing of %s router needs a node id unique among the nodes, set Router.leasing.nodeId or INSTANCE_ID", destName))
+		}
+		//the datasets of the jobs db are only consistent among the nodes if it is shared by them
+		if !jobsDB.IsSharedByNodes() {
+			panic(fmt.Errorf("job leasing of %s router needs the %s jobs db to be shared by nodes, set JobsDB.%s.sharedByNodes", destName, jobsDB.GetIdentifier(), jobsDB.GetIdentifier()))
+		}
+		var err error
+		dbHandle, err = sql.Open("postgres", jobsdb.GetConnectionString())
+		if err != nil {
+			panic(err)
+		}
+	}
+	lh.SetUpWithSettings(destName, settings, dbHandle, jobsDB)
+	if settings.Enabled {
+		if err := lh.heartbeat(); err != nil {
+			pkgLogger.Errorf(`[[ %s-router-leasing: Failed to acquire job leases. Err: %v]]`, destName, err)
+		}
+		rruntime.Go(func() {
+			lh.heartbeatLoop()
+		})
+	}
+}
+
+//SetUpWithSettings sets up job leases of the destinations of destName with the given settings,
+//leases being kept in the database of dbHandle
+func (lh *HandleT) SetUpWithSettings(destName string, settings Settings, dbHandle *sql.DB, jobsDB jobsdb.JobsDB) {
+	pkgLogger = logger.NewLogger().Child("router").Child("leasing")
+	lh.destinationName = destName
+	lh.settings = settings
+	if lh.settings.Partitions < 1 {
+		lh.settings.Partitions = 1
+	}
+	lh.dbHandle = dbHandle
+	lh.jobsDB = jobsDB
+	lh.owned = make(map[int]bool)
+	lh.drainingSince = make(map[int]time.Time)
+	lh.inFlight = make(map[int]int)
+	lh.executingParameters = []byte(`{}`)
+	if lh.settings.Enabled {
+		lh.executingParameters, _ = sjson.SetBytes(lh.executingParameters, NODE_ID_PARAMETER, lh.settings.NodeID)
+		lh.ownedStat = stats.NewTaggedStat("router_job_leases_owned", stats.GaugeType, stats.Tags{
+			"destType": destName,
+		})
+		pkgLogger.Infof(`[[ %s-router-leasing: Enabled job leasing for node %s with partitions: %d, leaseDuration: %v, heartbeatInterval: %v]]`, destName, lh.settings.NodeID, lh.settings.Partitions, lh.settings.LeaseDuration, lh.settings.HeartbeatInterval)
+	}
+}
+
+//IsEnabled returns whether job leasing is enabled for the destination type
+func (lh *HandleT) IsEnabled() bool {
+	return lh.settings.Enabled
+}
+
+func (lh *HandleT) partition(userID string) int {
+	return int(math.Abs(float64(misc.GetHash(userID) % lh.settings.Partitions)))
+}
+
+//Allow returns whether a job of the user can be executed by this node, which is the case while the node holds
+//an unexpired lease on the partition of the user. Every allowed job has to be released once its status is committed
+//or it is not executed after all
+func (lh *HandleT) Allow(userID string) bool {
+	if !lh.settings.Enabled {
+		return true
+	}
+	lh.leasesLock.Lock()
+	defer lh.leasesLock.Unlock()
+
+	partition := lh.partition(userID)
+	if !lh.owned[partition] || !time.Now().Before(lh.expiresAt) {
+		return false
+	}
+	lh.inFlight[partition]++
+	return true
+}
+
+//Release gives back a job allowed by Allow. A partition is handed over to another node only once its jobs are released
+func (lh *HandleT) Release(userID string) {
+	if !lh.settings.Enabled {
+		return
+	}
+	lh.leasesLock.Lock()
+	defer lh.leasesLock.Unlock()
+
+	partition := lh.partition(userID)
+	if lh.inFlight[partition] > 0 {
+		lh.inFlight[partition]--
+	}
+}
+
+//ExecutingParameters returns the parameters of the statuses of jobs marked as executing by this node
+func (lh *HandleT) ExecutingParameters() []byte {
+	return lh.executingParameters
+}
+
+//RecoverExecuting deletes the executing statuses left by this node, so that its jobs can be picked again.
+//Without leasing every executing status of the destination type is deleted
+func (lh *HandleT) RecoverExecuting() {
+	params := jobsdb.GetQueryParamsT{CustomValFilters: []string{lh.destinationName}, Count: -1}
+	if lh.settings.Enabled {
+		//statuses without a node were left by the node before it leased jobs
+		params.StatusParameterFilters = []jobsdb.ParameterFilterT{{Name: NODE_ID_PARAMETER, Value: lh.settings.NodeID, Optional: true}}
+		lh.leasesLock.Lock()
+		lh.inFlight = make(map[int]int)
+		lh.leasesLock.Unlock()
+	}
+	lh.jobsDB.DeleteExecuting(params)
+}
+
+//reclaim deletes the executing statuses left by a node whose leases expired, so that its jobs can be picked by other nodes
+func (lh *HandleT) reclaim(nodeID string) {
+	pkgLogger.Infof(`[[ %s-router-leasing: Reclaiming executing jobs of node %s whose leases expired]]`, lh.destinationName, nodeID)
+	lh.jobsDB.DeleteExecuting(jobsdb.GetQueryParamsT{
+		CustomValFilters:       []string{lh.destinationName},
+		Count:                  -1,
+		StatusParameterFilters: []jobsdb.ParameterFilterT{{Name: NODE_ID_PARAMETER, Value: nodeID}},
+	})
+	stats.NewTaggedStat("router_job_leases_reclaimed_nodes", stats.CountType, stats.Tags{
+		"destType": lh.destinationName,
+	}).Increment()
+}
+
+//Status returns the partitions leased by this node
+func (lh *HandleT) Status() StatusT {
+	lh.leasesLock.Lock()
+	defer lh.leasesLock.Unlock()
+
+	status := StatusT{NodeID: lh.settings.NodeID, Partitions: []int{}, Draining: []int{}, ExpiresAt: lh.expiresAt}
+	for partition := range lh.owned {
+		status.Partitions = append(status.Partitions, partition)
+	}
+	for partition := range lh.drainingSince {
+		status.Draining = append(status.Draining, partition)
+	}
+	sort.Ints(status.Partitions)
+	sort.Ints(status.Draining)
+	return status
+}
+
+func (lh *HandleT) heartbeatLoop() {
+	for {
+		time.Sleep(lh.settings.HeartbeatInterval)
+		if err := lh.heartbeat(); err != nil {
+			pkgLogger.Errorf(`[[ %s-router-leasing: Failed to renew job leases. Err: %v]]`, lh.destinationName, err)
+		}
+	}
+}
+
+//heartbeat renews the leases of this node and rebalances the partitions among the live nodes.
+//Nodes of the destination type heartbeat one at a time, holding an advisory lock till their changes are committed.
+//The executing jobs of dead nodes are reclaimed only once their leases are taken over
+func (lh *HandleT) heartbeat() (err error) {
+	startedAt := time.Now()
+	txn, err := lh.dbHandle.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			txn.Rollback()
+		}
+	}()
+
+	if _, err = txn.Exec(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`, LEASES_TABLE, lh.destinationName); err != nil {
+		return err
+	}
+	sqlStatement := fmt.Sprintf(`INSERT INTO %s (dest_type, partition) SELECT $1, generate_series(0, $2 - 1) ON CONFLICT DO NOTHING`, LEASES_TABLE)
+	if _, err = txn.Exec(sqlStatement, lh.destinationName, lh.settings.Partitions); err != nil {
+		return err
+	}
+	leases, err := lh.getLeases(txn)
+	if err != nil {
+		return err
+	}
+
+	lh.leasesLock.Lock()
+	plan := planLeases(leases, lh.settings.NodeID, lh.settings.Partitions, lh.isDrained)
+	lh.leasesLock.Unlock()
+
+	if len(plan.deadNodes) > 0 {
+		sqlStatement = fmt.Sprintf(`UPDATE %s SET node_id = '' WHERE dest_type = $1 AND node_id = ANY($2) AND expires_at <= NOW()`, LEASES_TABLE)
+		if _, err = txn.Exec(sqlStatement, lh.destinationName, pq.Array(plan.deadNodes)); err != nil {
+			return err
+		}
+	}
+	sqlStatement = fmt.Sprintf(`UPDATE %s SET node_id = $3, expires_at = NOW() + $4 * INTERVAL '1 millisecond' WHERE dest_type = $1 AND partition = ANY($2)`, LEASES_TABLE)
+	if _, err = txn.Exec(sqlStatement, lh.destinationName, pq.Array(plan.renew), lh.settings.NodeID, lh.settings.LeaseDuration.Milliseconds()); err != nil {
+		return err
+	}
+	sqlStatement = fmt.Sprintf(`UPDATE %s SET node_id = '', expires_at = NOW() WHERE dest_type = $1 AND partition = ANY($2) AND node_id = $3`, LEASES_TABLE)
+	if _, err = txn.Exec(sqlStatement, lh.destinationName, pq.Array(plan.release), lh.settings.NodeID); err != nil {
+		return err
+	}
+	if err = txn.Commit(); err != nil {
+		return err
+	}
+	for _, nodeID := range plan.deadNodes {
+		lh.reclaim(nodeID)
+	}
+
+	lh.leasesLock.Lock()
+	defer lh.leasesLock.Unlock()
+	lh.applyPlan(plan, startedAt)
+	return nil
+}
+
+func (lh *HandleT) getLeases(txn *sql.Tx) ([]leaseT, error) {
+	sqlStatement := fmt.Sprintf(`SELECT partition, node_id, expires_at > NOW() FROM %s WHERE dest_type = $1 AND partition < $2`, LEASES_TABLE)
+	rows, err := txn.Query(sqlStatement, lh.destinationName, lh.settings.Partitions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leases []leaseT
+	for rows.Next() {
+		var lease leaseT
+		if err = rows.Scan(&lease.partition, &lease.nodeID, &lease.live); err != nil {
+			return nil, err
+		}
+		leases = append(leases, lease)
+	}
+	return leases, rows.Err()
+}
+
+//isDrained returns whether a partition can be handed over: it has to be draining since the previous heartbeat,
+//so that no job of it is allowed anymore, and all of its jobs have to be released or a lease duration has passed
+func (lh *HandleT) isDrained(partition int) bool {
+	since, ok := lh.drainingSince[partition]
+	if !ok {
+		return false
+	}
+	return lh.inFlight[partition] == 0 || time.Since(since) >= lh.settings.LeaseDuration
+}
+
+func (lh *HandleT) applyPlan(plan planT, startedAt time.Time) {
+	lh.owned = make(map[int]bool, len(plan.owned))
+	for _, partition := range plan.owned {
+		lh.owned[partition] = true
+	}
+	drainingSince := make(map[int]time.Time, len(plan.draining))
+	for _, partition := range plan.draining {
+		since, ok := lh.drainingSince[partition]
+		if !ok {
+			since = startedAt
+		}
+		drainingSince[partition] = since
+	}
+	lh.drainingSince = drainingSince
+	lh.expiresAt = startedAt.Add(lh.settings.LeaseDuration)
+	lh.ownedStat.Gauge(len(lh.owned))
+}
+
+//planLeases splits the partitions evenly among the live nodes. nodeID keeps up to its share of the partitions it leases,
+//drains the ones over its share till isDrained and takes free partitions and the ones of dead nodes up to its share
+func planLeases(leases []leaseT, nodeID string, partitions int, isDrained func(partition int) bool) planT {
+	var plan planT
+	liveNodes := map[string]bool{nodeID: true}
+	for _, lease := range leases {
+		if lease.live && lease.nodeID != "" {
+			liveNodes[lease.nodeID] = true
+		}
+	}
+	share := (partitions + len(liveNodes) - 1) / len(liveNodes)
+
+	var mine, free []int
+	deadNodes := make(map[string]bool)
+	for _, lease := range leases {
+		switch {
+		case lease.nodeID == nodeID:
+			mine = append(mine, lease.partition)
+		case lease.nodeID == "":
+			free = append(free, lease.partition)
+		case !liveNodes[lease.nodeID]:
+			free = append(free, lease.partition)
+			if !deadNodes[lease.nodeID] {
+				deadNodes[lease.nodeID] = true
+				plan.deadNodes = append(plan.deadNodes, lease.nodeID)
+			}
+		}
+	}
+	sort.Ints(mine)
+	sort.Ints(free)
+	sort.Strings(plan.deadNodes)
+
+	if len(mine) > share {
+		plan.owned = mine[:share]
+		for _, partition := range mine[share:] {
+			if isDrained(partition) {
+				plan.release = append(plan.release, partition)
+			} else {
+				plan.draining = append(plan.draining, partition)
+			}
+		}
+	} else {
+		plan.owned = mine
+		if toAcquire := share - len(mine); toAcquire < len(free) {
+			free = free[:toAcquire]
+		}
+		plan.owned = append(plan.owned, free...)
+	}
+	plan.renew = append(append([]int{}, plan.owned...), plan.draining...)
+	return plan
+}
@@ -0,0 +1,13 @@
+package leasing
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLeasing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Leasing Suite")
+}
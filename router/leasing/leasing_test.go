@@ -0,0 +1,224 @@
+package leasing
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	mocksJobsDB "github.com/rudderlabs/rudder-server/mocks/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+func neverDrained(int) bool {
+	return false
+}
+
+func alwaysDrained(int) bool {
+	return true
+}
+
+var _ = Describe("Leasing", func() {
+	Context("planning leases", func() {
+		It("should take its share of the free partitions", func() {
+			leases := []leaseT{{0, "", false}, {1, "", false}, {2, "node-b", true}, {3, "", false}}
+
+			plan := planLeases(leases, "node-a", 4, neverDrained)
+			Expect(plan.owned).To(Equal([]int{0, 1}))
+			Expect(plan.renew).To(Equal([]int{0, 1}))
+			Expect(plan.release).To(BeEmpty())
+			Expect(plan.deadNodes).To(BeEmpty())
+		})
+
+		It("should keep its leases, even if they expired, while they are not reclaimed", func() {
+			leases := []leaseT{{0, "node-a", false}, {1, "node-a", false}}
+
+			plan := planLeases(leases, "node-a", 2, neverDrained)
+			Expect(plan.owned).To(Equal([]int{0, 1}))
+			Expect(plan.deadNodes).To(BeEmpty())
+		})
+
+		It("should take over the partitions of nodes whose leases expired", func() {
+			leases := []leaseT{{0, "node-b", false}, {1, "node-b", false}, {2, "node-c", true}, {3, "node-c", true}}
+
+			plan := planLeases(leases, "node-a", 4, neverDrained)
+			Expect(plan.deadNodes).To(Equal([]string{"node-b"}))
+			Expect(plan.owned).To(Equal([]int{0, 1}))
+		})
+
+		It("should drain the partitions over its share before releasing them", func() {
+			leases := []leaseT{{0, "node-a", true}, {1, "node-a", true}, {2, "node-a", true}, {3, "node-a", true}, {4, "", false}, {5, "node-b", true}}
+
+			plan := planLeases(leases, "node-a", 6, neverDrained)
+			Expect(plan.owned).To(Equal([]int{0, 1, 2}))
+			Expect(plan.draining).To(Equal([]int{3}))
+			Expect(plan.renew).To(Equal([]int{0, 1, 2, 3}))
+			Expect(plan.release).To(BeEmpty())
+
+			plan = planLeases(leases, "node-a", 6, alwaysDrained)
+			Expect(plan.owned).To(Equal([]int{0, 1, 2}))
+			Expect(plan.draining).To(BeEmpty())
+			Expect(plan.renew).To(Equal([]int{0, 1, 2}))
+			Expect(plan.release).To(Equal([]int{3}))
+		})
+	})
+
+	Context("leasing jobs", func() {
+		var (
+			mockCtrl   *gomock.Controller
+			mockJobsDB *mocksJobsDB.MockJobsDB
+			jobLeaser  *HandleT
+		)
+
+		BeforeEach(func() {
+			stats.Setup()
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockJobsDB = mocksJobsDB.NewMockJobsDB(mockCtrl)
+			jobLeaser = &HandleT{}
+			jobLeaser.SetUpWithSettings("WEBHOOK", Settings{Enabled: true, NodeID: "node-a", Partitions: 4, LeaseDuration: time.Minute}, nil, mockJobsDB)
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+		})
+
+		It("should allow every job when disabled", func() {
+			disabledLeaser := &HandleT{}
+			disabledLeaser.SetUpWithSettings("WEBHOOK", Settings{}, nil, mockJobsDB)
+			Expect(disabledLeaser.Allow("user-1")).To(BeTrue())
+			Expect(string(disabledLeaser.ExecutingParameters())).To(Equal(`{}`))
+		})
+
+		It("should only allow jobs of users in unexpired owned partitions", func() {
+			userPartition := jobLeaser.partition("user-1")
+			Expect(jobLeaser.Allow("user-1")).To(BeFalse())
+
+			jobLeaser.applyPlan(planT{owned: []int{userPartition}}, time.Now())
+			Expect(jobLeaser.Allow("user-1")).To(BeTrue())
+			Expect(jobLeaser.Status().Partitions).To(Equal([]int{userPartition}))
+
+			jobLeaser.applyPlan(planT{owned: []int{userPartition}}, time.Now().Add(-2*time.Minute))
+			Expect(jobLeaser.Allow("user-1")).To(BeFalse())
+		})
+
+		It("should only hand over a draining partition once its jobs are released", func() {
+			userPartition := jobLeaser.partition("user-1")
+			jobLeaser.applyPlan(planT{owned: []int{userPartition}}, time.Now())
+			Expect(jobLeaser.Allow("user-1")).To(BeTrue())
+			Expect(jobLeaser.isDrained(userPartition)).To(BeFalse())
+
+			jobLeaser.applyPlan(planT{draining: []int{userPartition}}, time.Now())
+			Expect(jobLeaser.Allow("user-1")).To(BeFalse())
+			Expect(jobLeaser.isDrained(userPartition)).To(BeFalse())
+			Expect(jobLeaser.Status().Draining).To(Equal([]int{userPartition}))
+
+			jobLeaser.Release("user-1")
+			Expect(jobLeaser.isDrained(userPartition)).To(BeTrue())
+		})
+
+		It("should mark executing jobs with its node", func() {
+			Expect(string(jobLeaser.ExecutingParameters())).To(Equal(`{"node_id":"node-a"}`))
+		})
+
+		It("should recover the executing jobs of its node and of nodes without leasing", func() {
+			mockJobsDB.EXPECT().DeleteExecuting(jobsdb.GetQueryParamsT{
+				CustomValFilters:       []string{"WEBHOOK"},
+				Count:                  -1,
+				StatusParameterFilters: []jobsdb.ParameterFilterT{{Name: NODE_ID_PARAMETER, Value: "node-a", Optional: true}},
+			}).Times(1)
+			jobLeaser.RecoverExecuting()
+		})
+
+		It("should reclaim the executing jobs of dead nodes", func() {
+			mockJobsDB.EXPECT().DeleteExecuting(jobsdb.GetQueryParamsT{
+				CustomValFilters:       []string{"WEBHOOK"},
+				Count:                  -1,
+				StatusParameterFilters: []jobsdb.ParameterFilterT{{Name: NODE_ID_PARAMETER, Value: "node-b"}},
+			}).Times(1)
+			jobLeaser.reclaim("node-b")
+		})
+	})
+
+	Context("heartbeat", func() {
+		var (
+			mockCtrl   *gomock.Controller
+			mockJobsDB *mocksJobsDB.MockJobsDB
+			db         *sql.DB
+			sqlMock    sqlmock.Sqlmock
+			jobLeaser  *HandleT
+		)
+
+		BeforeEach(func() {
+			stats.Setup()
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockJobsDB = mocksJobsDB.NewMockJobsDB(mockCtrl)
+			var err error
+			db, sqlMock, err = sqlmock.New()
+			Expect(err).NotTo(HaveOccurred())
+			jobLeaser = &HandleT{}
+			jobLeaser.SetUpWithSettings("WEBHOOK", Settings{Enabled: true, NodeID: "node-a", Partitions: 2, LeaseDuration: time.Minute}, db, mockJobsDB)
+
+			sqlMock.ExpectBegin()
+			sqlMock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock`)).WillReturnResult(sqlmock.NewResult(0, 0))
+			sqlMock.ExpectExec(regexp.QuoteMeta(`INSERT INTO router_job_leases`)).WillReturnResult(sqlmock.NewResult(0, 0))
+			sqlMock.ExpectQuery(regexp.QuoteMeta(`SELECT partition, node_id, expires_at > NOW() FROM router_job_leases`)).
+				WillReturnRows(sqlmock.NewRows([]string{"partition", "node_id", "live"}).AddRow(0, "node-b", false).AddRow(1, "node-b", false))
+			sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE router_job_leases SET node_id = ''`)).WithArgs("WEBHOOK", pq.Array([]string{"node-b"})).WillReturnResult(sqlmock.NewResult(0, 2))
+			sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE router_job_leases SET node_id = $3`)).WillReturnResult(sqlmock.NewResult(0, 2))
+			sqlMock.ExpectExec(regexp.QuoteMeta(`UPDATE router_job_leases SET node_id = '', expires_at = NOW()`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		})
+
+		AfterEach(func() {
+			mockCtrl.Finish()
+			Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+		})
+
+		It("should reclaim the executing jobs of dead nodes once their leases are taken over", func() {
+			sqlMock.ExpectCommit()
+			mockJobsDB.EXPECT().DeleteExecuting(jobsdb.GetQueryParamsT{
+				CustomValFilters:       []string{"WEBHOOK"},
+				Count:                  -1,
+				StatusParameterFilters: []jobsdb.ParameterFilterT{{Name: NODE_ID_PARAMETER, Value: "node-b"}},
+			}).Times(1).Do(func(jobsdb.GetQueryParamsT) {
+				Expect(sqlMock.ExpectationsWereMet()).To(Succeed())
+			})
+
+			Expect(jobLeaser.heartbeat()).To(Succeed())
+			Expect(jobLeaser.Status().Partitions).To(Equal([]int{0, 1}))
+		})
+
+		It("should not reclaim the executing jobs of dead nodes when taking over their leases fails", func() {
+			sqlMock.ExpectCommit().WillReturnError(errors.New("commit failed"))
+
+			Expect(jobLeaser.heartbeat()).To(MatchError("commit failed"))
+			Expect(jobLeaser.Status().Partitions).To(BeEmpty())
+		})
+	})
+
+	Context("node id", func() {
+		AfterEach(func() {
+			os.Unsetenv("INSTANCE_ID")
+			config.SetString("Router.leasing.nodeId", "")
+		})
+
+		It("should require the node id to be set explicitly", func() {
+			Expect(nodeIDIsSet("WEBHOOK")).To(BeFalse())
+
+			os.Setenv("INSTANCE_ID", "node-a")
+			Expect(nodeIDIsSet("WEBHOOK")).To(BeTrue())
+			os.Unsetenv("INSTANCE_ID")
+
+			config.SetString("Router.leasing.nodeId", "node-a")
+			Expect(nodeIDIsSet("WEBHOOK")).To(BeTrue())
+		})
+	})
+})
@@ -3,6 +3,7 @@
 package router
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -10,10 +11,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rudderlabs/rudder-server/processor/integrations"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/utils/sysUtils"
@@ -27,7 +30,7 @@ type NetHandleT struct {
 
 //Network interface
 type NetHandleI interface {
-	SendPost(structData integrations.PostParametersT) (statusCode int, respBody string)
+	SendPost(ctx context.Context, structData integrations.PostParametersT) (statusCode int, respBody string, retryAfter time.Duration)
 }
 
 //temp solution for handling complex query params
@@ -49,10 +52,12 @@ func handleQueryParam(param interface{}) string {
 }
 
 //SendPost takes the EventPayload of a transformed job, gets the necessary values from the payload and makes a call to destination to push the event to it
-//this returns the statusCode, status and response body from the response of the destination call
-func (network *NetHandleT) SendPost(structData integrations.PostParametersT) (statusCode int, respBody string) {
+//this returns the statusCode, status and response body from the response of the destination call,
+//along with the duration the destination asked to wait before sending again through the Retry-After header, if any.
+//The call is traced as a child of the span in ctx, the trace context isn't sent to the destination
+func (network *NetHandleT) SendPost(ctx context.Context, structData integrations.PostParametersT) (statusCode int, respBody string, retryAfter time.Duration) {
 	if disableEgress {
-		return 200, `200: outgoing disabled`
+		return 200, `200: outgoing disabled`, 0
 	}
 	client := network.httpClient
 	postInfo := structData
@@ -93,7 +98,7 @@ func (network *NetHandleT) SendPost(structData integrations.PostParametersT) (st
 			case "XML":
 				strValue, ok := bodyValue["payload"].(string)
 				if !ok {
-					return 400, "400 Unable to construct xml payload. Unexpected transformer response"
+					return 400, "400 Unable to construct xml payload. Unexpected transformer response", 0
 				}
 				payload = strings.NewReader(strValue)
 			case "FORM":
@@ -110,7 +115,7 @@ func (network *NetHandleT) SendPost(structData integrations.PostParametersT) (st
 		req, err := http.NewRequest(requestMethod, postInfo.URL, payload)
 		if err != nil {
 			network.logger.Error(fmt.Sprintf(`400 Unable to construct "%s" request for URL : "%s"`, requestMethod, postInfo.URL))
-			return 400, fmt.Sprintf(`400 Unable to construct "%s" request for URL : "%s"`, requestMethod, postInfo.URL)
+			return 400, fmt.Sprintf(`400 Unable to construct "%s" request for URL : "%s"`, requestMethod, postInfo.URL), 0
 		}
 
 		// add queryparams to the url
@@ -135,6 +140,8 @@ func (network *NetHandleT) SendPost(structData integrations.PostParametersT) (st
 
 		req.Header.Add("User-Agent", "RudderLabs")
 
+		_, span := tracing.StartClientSpan(ctx, "router.send_post", tracing.StringAttribute("http.method", requestMethod), tracing.StringAttribute("http.host", req.URL.Host))
+		defer span.End()
 		resp, err := client.Do(req)
 
 		var respBody []byte
@@ -163,20 +170,39 @@ func (network *NetHandleT) SendPost(structData integrations.PostParametersT) (st
 
 		if err != nil {
 			network.logger.Error("Errored when sending request to the server", err)
-			return http.StatusGatewayTimeout, string(respBody)
+			tracing.SetError(span, err)
+			return http.StatusGatewayTimeout, string(respBody), 0
 		}
+		tracing.SetStatusCode(span, resp.StatusCode)
 
-		return resp.StatusCode, string(respBody)
+		return resp.StatusCode, string(respBody), parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 
 	}
 
 	// returning 200 with a message in case of unsupported processing
 	// so that we don't process again. can change this code to anything
 	// to be not picked up by router again
-	return 200, ""
+	return 200, "", 0
 
 }
 
+//parseRetryAfter parses a Retry-After header, given either in seconds or as an http date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 //Setup initializes the module
 func (network *NetHandleT) Setup(destID string, netClientTimeout time.Duration) {
 	network.logger.Info("Network Handler Startup")
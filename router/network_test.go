@@ -2,11 +2,14 @@ package router
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 	mocksSysUtils "github.com/rudderlabs/rudder-server/mocks/utils/sysUtils"
 	"github.com/rudderlabs/rudder-server/processor/integrations"
 	"github.com/rudderlabs/rudder-server/utils/logger"
@@ -88,7 +91,38 @@ var _ = Describe("Network", func() {
 				Body:       r,
 			}, nil)
 
-			network.SendPost(structData)
+			network.SendPost(context.Background(), structData)
+		})
+
+		It("should return the Retry-After of throttled responses", func() {
+			network := &NetHandleT{}
+			network.logger = logger.NewLogger().Child("network")
+			network.httpClient = c.mockHTTPClient
+
+			var structData integrations.PostParametersT
+			structData.Type = "REST"
+			structData.URL = "https://example.com/events"
+			structData.RequestMethod = "POST"
+			structData.Body = map[string]interface{}{"JSON": map[string]interface{}{"event": "Demo Track"}}
+
+			c.mockHTTPClient.EXPECT().Do(gomock.Any()).Times(1).Return(&http.Response{
+				StatusCode: 429,
+				Header:     http.Header{"Retry-After": []string{"120"}},
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte("rate limited"))),
+			}, nil)
+
+			statusCode, _, retryAfter := network.SendPost(context.Background(), structData)
+			Expect(statusCode).To(Equal(429))
+			Expect(retryAfter).To(Equal(2 * time.Minute))
+		})
+
+		It("should parse Retry-After headers in seconds and as dates", func() {
+			now := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+			Expect(parseRetryAfter("30", now)).To(Equal(30 * time.Second))
+			Expect(parseRetryAfter("Sat, 01 May 2021 10:01:00 GMT", now)).To(Equal(time.Minute))
+			Expect(parseRetryAfter("Sat, 01 May 2021 09:59:00 GMT", now)).To(BeZero())
+			Expect(parseRetryAfter("-5", now)).To(BeZero())
+			Expect(parseRetryAfter("", now)).To(BeZero())
 		})
 	})
 })
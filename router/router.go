@@ -2,6 +2,7 @@ package router
 
 import (
 	"container/list"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -16,8 +17,10 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/processor/integrations"
+	"github.com/rudderlabs/rudder-server/router/circuitbreaker"
 	"github.com/rudderlabs/rudder-server/router/customdestinationmanager"
 	customDestinationManager "github.com/rudderlabs/rudder-server/router/customdestinationmanager"
+	"github.com/rudderlabs/rudder-server/router/leasing"
 	"github.com/rudderlabs/rudder-server/router/throttler"
 	"github.com/rudderlabs/rudder-server/router/transformer"
 	"github.com/rudderlabs/rudder-server/router/types"
@@ -34,6 +37,7 @@ import (
 	"github.com/rudderlabs/rudder-server/rruntime"
 	destinationdebugger "github.com/rudderlabs/rudder-server/services/debugger/destination"
 	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/services/tracing"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 )
@@ -76,6 +80,9 @@ type HandleT struct {
 	failuresMetric                         map[string][]failureMetric
 	customDestinationManager               customdestinationmanager.DestinationManager
 	throttler                              throttler.Throttler
+	circuitBreaker                         circuitbreaker.CircuitBreaker
+	jobLeaser                              leasing.JobLeaser
+	circuitOpenSkippedStat                 stats.RudderStats
 	throttlerMutex                         sync.RWMutex
 	guaranteeUserEventOrder                bool
 	netClientTimeout                       time.Duration
@@ -113,6 +120,13 @@ type jobResponseT struct {
 	JobT   *jobsdb.JobT
 }
 
+//circuitBreakerResultT is the result of a job for the circuit breaker, over all the destination jobs it was transformed into
+type circuitBreakerResultT struct {
+	destinationID string
+	attempted     bool
+	success       bool
+}
+
 //JobParametersT struct holds source id and destination id of a job
 type JobParametersT struct {
 	SourceID        string `json:"source_id"`
@@ -237,6 +251,20 @@ func (worker *workerT) trackStuckDelivery() chan struct{} {
 	return ch
 }
 
+//startDeliverySpan starts the span delivering destinationJob. It continues the trace of the first job, the traces of the other jobs batched with it are linked
+func (worker *workerT) startDeliverySpan(destinationJob types.DestinationJobT) (context.Context, tracing.Span) {
+	traceParents := make([]string, len(destinationJob.JobMetadataArray))
+	for i, jobMetadata := range destinationJob.JobMetadataArray {
+		if jobMetadata.JobT != nil {
+			traceParents[i] = gjson.GetBytes(jobMetadata.JobT.Parameters, tracing.TRACEPARENT).String()
+		}
+	}
+	return tracing.StartSpanFromTraceparents(context.Background(), "router.deliver", traceParents,
+		tracing.StringAttribute("destination.type", worker.rt.destName),
+		tracing.StringAttribute("destination.id", destinationJob.Destination.ID),
+		tracing.Int64Attribute("jobs", int64(len(destinationJob.JobMetadataArray))))
+}
+
 func (worker *workerT) routerTransform(routerJobs []types.RouterJobT) []types.DestinationJobT {
 	worker.rt.batchInputCountStat.Count(len(routerJobs))
 	destinationJobs := worker.rt.transformer.Transform(transformer.ROUTER_TRANSFORM, &types.TransformMessageT{Data: routerJobs, DestType: strings.ToLower(worker.rt.destName)})
@@ -325,6 +353,7 @@ func (worker *workerT) workerProcess() {
 					if markedAsWaiting {
 						worker.rt.logger.Debugf(`Decrementing in throttle map for destination:%s since job:%d is marked as waiting for user:%s`, parameters.DestinationID, job.JobID, userID)
 						worker.rt.throttler.Dec(parameters.DestinationID, userID, 1, worker.throttledAtTime, throttler.ALL_LEVELS)
+						worker.rt.circuitBreaker.Release(parameters.DestinationID)
 						continue
 					}
 				}
@@ -358,6 +387,7 @@ func (worker *workerT) workerProcess() {
 					Parameters:    []byte(`{}`),
 				}
 				worker.rt.responseQ <- jobResponseT{status: &status, worker: worker, userID: userID, JobT: job}
+				worker.rt.circuitBreaker.Release(parameters.DestinationID)
 				continue
 			}
 			destination := batchDestination.Destination
@@ -471,6 +501,7 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 	var respBody string
 	var respBodyTemp string
 	handledJobMetadatas := make(map[int64]*types.JobMetadataT)
+	circuitBreakerResults := make(map[int64]*circuitBreakerResultT)
 
 	var destinationResponseHandler ResponseHandlerI
 	worker.rt.configSubscriberLock.RLock()
@@ -518,6 +549,7 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 	apiCallsCount := make(map[string]*destJobCountsT)
 	for _, destinationJob := range worker.destinationJobs {
 		var attemptedToSendTheJob bool
+		var retryAfter time.Duration
 		respBodyArr := make([]string, 0)
 		if destinationJob.StatusCode == 200 || destinationJob.StatusCode == 0 {
 			if worker.canSendJobToDestination(prevRespStatusCode, failedUserIDsMap, destinationJob) {
@@ -536,6 +568,7 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 					"destination": misc.GetTagName(destinationJob.Destination.ID, destinationJob.Destination.Name),
 				})
 				deliveryLatencyStat.Start()
+				ctx, deliverySpan := worker.startDeliverySpan(destinationJob)
 
 				ch := worker.trackStuckDelivery()
 				if worker.rt.customDestinationManager != nil {
@@ -556,7 +589,7 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 							respStatusCode, respBodyTemp = 400, fmt.Sprintf(`400 GetPostInfoFailed with error: %s`, err.Error())
 							respBodyArr = append(respBodyArr, respBodyTemp)
 						} else {
-							respStatusCode, respBodyTemp = worker.rt.netHandle.SendPost(val)
+							respStatusCode, respBodyTemp, retryAfter = worker.rt.netHandle.SendPost(ctx, val)
 							if isSuccessStatus(respStatusCode) {
 								respBodyArr = append(respBodyArr, respBodyTemp)
 							} else {
@@ -575,9 +608,12 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 				}
 
 				attemptedToSendTheJob = true
+				worker.recordThrottlingFeedback(destinationJob.JobMetadataArray, respStatusCode, retryAfter)
 
 				worker.deliveryTimeStat.End()
 				deliveryLatencyStat.End()
+				tracing.SetStatusCode(deliverySpan, respStatusCode)
+				deliverySpan.End()
 				// END: request to destination endpoint
 
 				if isSuccessStatus(respStatusCode) {
@@ -627,6 +663,15 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 			}
 
 			worker.postStatusOnResponseQ(respStatusCode, respBody, destinationJob.Message, &destinationJobMetadata, &status)
+			result, ok := circuitBreakerResults[destinationJobMetadata.JobID]
+			if !ok {
+				result = &circuitBreakerResultT{destinationID: destinationJobMetadata.DestinationID, success: true}
+				circuitBreakerResults[destinationJobMetadata.JobID] = result
+			}
+			if attemptedToSendTheJob {
+				result.attempted = true
+				result.success = result.success && respStatusCode < 500 && respStatusCode != 429
+			}
 
 			worker.sendEventDeliveryStat(&destinationJobMetadata, &status, &destinationJob.Destination)
 
@@ -641,6 +686,15 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 		}
 	}
 
+	//a job can be transformed into many destination jobs, but it was allowed by the circuit breaker once, so its result is recorded once
+	for _, result := range circuitBreakerResults {
+		if result.attempted {
+			worker.rt.circuitBreaker.RecordResult(result.destinationID, result.success)
+		} else {
+			worker.rt.circuitBreaker.Release(result.destinationID)
+		}
+	}
+
 	worker.decrementInThrottleMap(apiCallsCount)
 
 	//if batching/routerTransform is enabled, we need to make sure that all the routerJobs status are written to DB.
@@ -658,6 +712,7 @@ func (worker *workerT) handleWorkerDestinationJobs() {
 			}
 
 			worker.postStatusOnResponseQ(500, "transformer failed to handle this job", nil, &routerJob.JobMetadata, &status)
+			worker.rt.circuitBreaker.Release(routerJob.JobMetadata.DestinationID)
 		}
 	}
 
@@ -696,6 +751,24 @@ func (worker *workerT) recordAPICallCount(apiCallsCount map[string]*destJobCount
 	}
 }
 
+//recordThrottlingFeedback adapts the throttling limits of the destination and users of a request to its response
+func (worker *workerT) recordThrottlingFeedback(jobMetadatas []types.JobMetadataT, respStatusCode int, retryAfter time.Duration) {
+	if !worker.rt.throttler.IsEnabled() || len(jobMetadatas) == 0 {
+		return
+	}
+	var userIDs []string
+	if worker.rt.throttler.IsUserLevelEnabled() {
+		seenUserIDs := make(map[string]struct{})
+		for _, jobMetadata := range jobMetadatas {
+			if _, ok := seenUserIDs[jobMetadata.UserID]; !ok {
+				seenUserIDs[jobMetadata.UserID] = struct{}{}
+				userIDs = append(userIDs, jobMetadata.UserID)
+			}
+		}
+	}
+	worker.rt.throttler.RecordResponse(jobMetadatas[0].DestinationID, userIDs, respStatusCode, retryAfter)
+}
+
 // decrements counts in throttle map by the diff between api calls made to destinations and initial incremented ones in throttler
 func (worker *workerT) decrementInThrottleMap(apiCallsCount map[string]*destJobCountsT) {
 	for destID, incrementedMapByDestID := range worker.jobCountsByDestAndUser {
@@ -837,6 +910,7 @@ func (worker *workerT) postStatusOnResponseQ(respStatusCode int, respBody string
 			addToFailedMap = false
 			worker.updateAbortedMetrics(destinationJobMetadata.DestinationID, status.ErrorCode)
 			destinationJobMetadata.JobT.Parameters = misc.UpdateJSONWithNewKeyVal(destinationJobMetadata.JobT.Parameters, "stage", "router")
+			destinationJobMetadata.JobT.Parameters = misc.UpdateJSONWithNewKeyVal(destinationJobMetadata.JobT.Parameters, "error_code", status.ErrorCode)
 			destinationJobMetadata.JobT.Parameters = misc.UpdateJSONWithNewKeyVal(destinationJobMetadata.JobT.Parameters, "error_response", status.ErrorResponse)
 		}
 
@@ -1249,6 +1323,10 @@ func (rt *HandleT) commitStatusList(responseList *[]jobResponseT) {
 		rt.jobsDB.CommitTransaction(txn)
 		rt.jobsDB.ReleaseUpdateJobStatusLocks()
 	}
+	//jobs are released only once their statuses are committed, so that their partitions are handed over after that
+	for _, resp := range *responseList {
+		rt.jobLeaser.Release(resp.userID)
+	}
 
 	if rt.guaranteeUserEventOrder {
 		//#JobOrder (see other #JobOrder comment)
@@ -1499,6 +1577,7 @@ func (rt *HandleT) readAndProcess() int {
 	var drainList []*jobsdb.JobStatusT
 	var drainJobList []*jobsdb.JobT
 	drainCountByDest := make(map[string]int)
+	var circuitOpenCount int
 
 	var toProcess []workerJobT
 
@@ -1529,8 +1608,21 @@ func (rt *HandleT) readAndProcess() int {
 			drainCountByDest[destID] = drainCountByDest[destID] + 1
 			continue
 		}
+		//jobs of users leased to other nodes are left to them
+		if !rt.jobLeaser.Allow(job.UserID) {
+			continue
+		}
+		//jobs of destinations with an open circuit are left as they are, to be picked once it half-opens
+		if !rt.circuitBreaker.Allow(destID) {
+			rt.jobLeaser.Release(job.UserID)
+			circuitOpenCount++
+			continue
+		}
 		w := rt.findWorker(job, throttledAtTime)
-		if w != nil {
+		if w == nil {
+			rt.circuitBreaker.Release(destID)
+			rt.jobLeaser.Release(job.UserID)
+		} else {
 			status := jobsdb.JobStatusT{
 				JobID:         job.JobID,
 				AttemptNum:    job.LastJobStatus.AttemptNum,
@@ -1539,13 +1631,14 @@ func (rt *HandleT) readAndProcess() int {
 				RetryTime:     time.Now(),
 				ErrorCode:     "",
 				ErrorResponse: []byte(`{}`), // check
-				Parameters:    []byte(`{}`),
+				Parameters:    rt.jobLeaser.ExecutingParameters(),
 			}
 			statusList = append(statusList, &status)
 			toProcess = append(toProcess, workerJobT{worker: w, job: job})
 		}
 	}
 	rt.throttledUserMap = nil
+	rt.circuitOpenSkippedStat.Count(circuitOpenCount)
 
 	//Mark the jobs as executing
 	err := rt.jobsDB.UpdateJobStatus(statusList, []string{rt.destName}, nil)
@@ -1594,7 +1687,7 @@ func destinationID(job *jobsdb.JobT) string {
 }
 
 func (rt *HandleT) crashRecover() {
-	rt.jobsDB.DeleteExecuting(jobsdb.GetQueryParamsT{CustomValFilters: []string{rt.destName}, Count: -1})
+	rt.jobLeaser.RecoverExecuting()
 }
 
 func init() {
@@ -1628,6 +1721,9 @@ func (rt *HandleT) Setup(backendConfig backendconfig.BackendConfig, jobsDB, erro
 	rt.destName = destName
 	netClientTimeoutKeys := []string{"Router." + rt.destName + "." + "httpTimeout", "Router." + rt.destName + "." + "httpTimeoutInS", "Router." + "httpTimeout", "Router." + "httpTimeoutInS"}
 	config.RegisterDurationConfigVariable(30, &rt.netClientTimeout, false, time.Second, netClientTimeoutKeys...)
+	var jobLeaser leasing.HandleT
+	jobLeaser.SetUp(rt.destName, rt.jobsDB)
+	rt.jobLeaser = &jobLeaser
 	rt.crashRecover()
 	rt.requestQ = make(chan *jobsdb.JobT, jobQueryBatchSize)
 	rt.responseQ = make(chan jobResponseT, jobQueryBatchSize)
@@ -1685,6 +1781,13 @@ func (rt *HandleT) Setup(backendConfig backendconfig.BackendConfig, jobsDB, erro
 	throttler.SetUp(rt.destName)
 	rt.throttler = &throttler
 
+	var circuitBreaker circuitbreaker.HandleT
+	circuitBreaker.SetUp(rt.destName)
+	rt.circuitBreaker = &circuitBreaker
+	rt.circuitOpenSkippedStat = stats.NewTaggedStat("router_circuit_open_skipped_jobs", stats.CountType, stats.Tags{
+		"destType": rt.destName,
+	})
+
 	rt.isBackendConfigInitialized = false
 	rt.backendConfigInitialized = make(chan bool)
 
@@ -3,6 +3,7 @@ package router
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -16,6 +17,7 @@ import (
 	mocksJobsDB "github.com/rudderlabs/rudder-server/mocks/jobsdb"
 	mocksRouter "github.com/rudderlabs/rudder-server/mocks/router"
 	mocksTransformer "github.com/rudderlabs/rudder-server/mocks/router/transformer"
+	"github.com/rudderlabs/rudder-server/router/circuitbreaker"
 	"github.com/rudderlabs/rudder-server/router/types"
 	router_utils "github.com/rudderlabs/rudder-server/router/utils"
 	"github.com/rudderlabs/rudder-server/services/stats"
@@ -52,7 +54,7 @@ var sampleBackendConfig = backendconfig.ConfigT{
 	},
 }
 
-type context struct {
+type testContext struct {
 	asyncHelper     testutils.AsyncTestHelper
 	dbReadBatchSize int
 
@@ -63,7 +65,7 @@ type context struct {
 }
 
 // Initiaze mocks and common expectations
-func (c *context) Setup() {
+func (c *testContext) Setup() {
 	c.asyncHelper.Setup()
 	c.mockCtrl = gomock.NewController(GinkgoT())
 	c.mockRouterJobsDB = mocksJobsDB.NewMockJobsDB(c.mockCtrl)
@@ -82,7 +84,7 @@ func (c *context) Setup() {
 	c.dbReadBatchSize = 10000
 }
 
-func (c *context) Finish() {
+func (c *testContext) Finish() {
 	c.asyncHelper.WaitWithTimeout(testTimeout)
 	c.mockCtrl.Finish()
 }
@@ -92,12 +94,53 @@ var (
 	emptyJobsList []*jobsdb.JobT
 )
 
+//countingCircuitBreakerT allows every job and counts the calls made to it
+type countingCircuitBreakerT struct {
+	lock     sync.Mutex
+	allowed  int
+	released int
+	results  []bool
+}
+
+func (cb *countingCircuitBreakerT) Allow(destID string) bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.allowed++
+	return true
+}
+
+func (cb *countingCircuitBreakerT) Release(destID string) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.released++
+}
+
+func (cb *countingCircuitBreakerT) RecordResult(destID string, success bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.results = append(cb.results, success)
+}
+
+func (cb *countingCircuitBreakerT) recordedResults() []bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	return append([]bool{}, cb.results...)
+}
+
+func (cb *countingCircuitBreakerT) Status() map[string]circuitbreaker.StatusT {
+	return nil
+}
+
+func (cb *countingCircuitBreakerT) IsEnabled() bool {
+	return true
+}
+
 var _ = Describe("Router", func() {
-	var c *context
+	var c *testContext
 
 	BeforeEach(func() {
 		router_utils.JobRetention = time.Duration(175200) * time.Hour //20 Years(20*365*24)
-		c = &context{}
+		c = &testContext{}
 		c.Setup()
 
 		// setup static requirements of dependencies
@@ -182,7 +225,7 @@ var _ = Describe("Router", func() {
 					assertJobStatus(unprocessedJobsList[0], statuses[1], jobsdb.Executing.State, "", `{}`, 0)
 				}).Return(nil)
 
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(2).Return(200, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(2).Return(200, "", time.Duration(0))
 
 			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().Times(1).Return(nil)
 			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().Times(1).After(callBeginTransaction)
@@ -237,7 +280,7 @@ var _ = Describe("Router", func() {
 					assertJobStatus(unprocessedJobsList[0], statuses[0], jobsdb.Executing.State, "", `{}`, 0)
 				})
 
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(1).Return(400, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(1).Return(400, "", time.Duration(0))
 
 			c.mockProcErrorsDB.EXPECT().Store(gomock.Any()).Times(1).
 				Do(func(jobList []*jobsdb.JobT) {
@@ -444,7 +487,7 @@ var _ = Describe("Router", func() {
 						}
 					})
 
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(1).Return(200, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(1).Return(200, "", time.Duration(0))
 
 			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().Times(1).Return(nil)
 			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().Times(1).After(callBeginTransaction)
@@ -586,7 +629,7 @@ var _ = Describe("Router", func() {
 					}
 				})
 
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(0).Return(200, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(0).Return(200, "", time.Duration(0))
 
 			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().Times(1).Return(nil)
 			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().Times(1).After(callBeginTransaction)
@@ -806,7 +849,7 @@ var _ = Describe("Router", func() {
 					}
 				})
 
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(2).Return(200, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(2).Return(200, "", time.Duration(0))
 
 			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().AnyTimes().Return(nil)
 			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().AnyTimes().After(callBeginTransaction)
@@ -821,6 +864,72 @@ var _ = Describe("Router", func() {
 			time.Sleep(9 * time.Second)
 		})
 
+		It("records the result of a job transformed into many destination jobs once with the circuit breaker", func() {
+			router := &HandleT{}
+
+			router.Setup(c.mockBackendConfig, c.mockRouterJobsDB, c.mockProcErrorsDB, gaDestinationDefinition, nil)
+			circuitBreaker := &countingCircuitBreakerT{}
+			router.circuitBreaker = circuitBreaker
+
+			mockTransformer := mocksTransformer.NewMockTransformer(c.mockCtrl)
+			router.transformer = mockTransformer
+
+			mockNetHandle := mocksRouter.NewMockNetHandleI(c.mockCtrl)
+			router.netHandle = mockNetHandle
+			router.noOfWorkers = 1
+
+			gaPayload := `{"body": {"XML": {}, "FORM": {}, "JSON": {}}, "type": "REST", "files": {}, "method": "POST", "params": {"t": "event", "v": "1", "an": "RudderAndroidClient", "av": "1.0", "ds": "android-sdk", "ea": "Demo Track", "ec": "Demo Category", "el": "Demo Label", "ni": 0, "qt": 59268380964, "ul": "en-US", "cid": "anon_id", "tid": "UA-185645846-1", "uip": "[::1]", "aiid": "com.rudderlabs.android.sdk"}, "userId": "anon_id", "headers": {}, "version": "1", "endpoint": "https://www.google-analytics.com/collect"}`
+			parameters := fmt.Sprintf(`{"source_id": "1fMCVYZboDlYlauh4GFsEo2JU77", "destination_id": "%s", "message_id": "2f548e6d-60f6-44af-a1f4-62b3272445c3", "received_at": "2021-06-28T10:04:48.527+05:30", "transform_at": "router"}`, GADestinationID)
+
+			var unprocessedJobsList []*jobsdb.JobT = []*jobsdb.JobT{
+				{
+					UUID:          uuid.NewV4(),
+					UserID:        "u1",
+					JobID:         2010,
+					CreatedAt:     time.Date(2020, 04, 28, 13, 26, 00, 00, time.UTC),
+					ExpireAt:      time.Date(2020, 04, 28, 13, 26, 00, 00, time.UTC),
+					CustomVal:     CustomVal["GA"],
+					EventPayload:  []byte(gaPayload),
+					LastJobStatus: jobsdb.JobStatusT{AttemptNum: 0},
+					Parameters:    []byte(parameters),
+				},
+			}
+
+			callRetry := c.mockRouterJobsDB.EXPECT().GetToRetry(jobsdb.GetQueryParamsT{CustomValFilters: []string{CustomVal["GA"]}, Count: c.dbReadBatchSize}).Return(emptyJobsList).Times(1)
+			callThrottled := c.mockRouterJobsDB.EXPECT().GetThrottled(jobsdb.GetQueryParamsT{CustomValFilters: []string{CustomVal["GA"]}, Count: c.dbReadBatchSize}).Return(emptyJobsList).Times(1).After(callRetry)
+			callWaiting := c.mockRouterJobsDB.EXPECT().GetWaiting(jobsdb.GetQueryParamsT{CustomValFilters: []string{CustomVal["GA"]}, Count: c.dbReadBatchSize}).Return(emptyJobsList).Times(1).After(callThrottled)
+			callUnprocessed := c.mockRouterJobsDB.EXPECT().GetUnprocessed(jobsdb.GetQueryParamsT{CustomValFilters: []string{CustomVal["GA"]}, Count: c.dbReadBatchSize}).Return(unprocessedJobsList).Times(1).After(callWaiting)
+
+			c.mockRouterJobsDB.EXPECT().UpdateJobStatus(gomock.Any(), []string{CustomVal["GA"]}, nil).Times(1).Return(nil)
+
+			mockTransformer.EXPECT().Transform("ROUTER_TRANSFORM", gomock.Any()).After(callUnprocessed).Times(1).DoAndReturn(
+				func(_ string, transformMessage *types.TransformMessageT) []types.DestinationJobT {
+					destinationJob := types.DestinationJobT{
+						Message:          []byte(`{"message": "some transformed message"}`),
+						JobMetadataArray: []types.JobMetadataT{{UserID: "u1", JobID: 2010, DestinationID: GADestinationID}},
+						StatusCode:       200,
+					}
+					return []types.DestinationJobT{destinationJob, destinationJob}
+				})
+
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(2).Return(200, "", time.Duration(0))
+
+			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().AnyTimes().Return(nil)
+			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().AnyTimes().After(callBeginTransaction)
+			callUpdateStatus := c.mockRouterJobsDB.EXPECT().UpdateJobStatusInTxn(gomock.Any(), gomock.Any(), []string{CustomVal["GA"]}, nil).AnyTimes().After(callAcquireLocks)
+			callCommitTransaction := c.mockRouterJobsDB.EXPECT().CommitTransaction(gomock.Any()).AnyTimes().After(callUpdateStatus)
+			c.mockRouterJobsDB.EXPECT().ReleaseUpdateJobStatusLocks().AnyTimes().After(callCommitTransaction)
+
+			<-router.backendConfigInitialized
+			count := router.readAndProcess()
+			Expect(count).To(Equal(1))
+
+			Eventually(circuitBreaker.recordedResults, 15*time.Second).Should(Equal([]bool{true}))
+			Consistently(circuitBreaker.recordedResults, time.Second).Should(Equal([]bool{true}))
+			Expect(circuitBreaker.allowed).To(Equal(1))
+			Expect(circuitBreaker.released).To(Equal(0))
+		})
+
 		/*
 				Job1 u1
 				Job2 u1
@@ -952,7 +1061,7 @@ var _ = Describe("Router", func() {
 						},
 					}
 				})
-			mockNetHandle.EXPECT().SendPost(gomock.Any()).Times(0).Return(200, "")
+			mockNetHandle.EXPECT().SendPost(gomock.Any(), gomock.Any()).Times(0).Return(200, "", time.Duration(0))
 
 			callBeginTransaction := c.mockRouterJobsDB.EXPECT().BeginGlobalTransaction().AnyTimes().Return(nil)
 			callAcquireLocks := c.mockRouterJobsDB.EXPECT().AcquireUpdateJobStatusLocks().AnyTimes().After(callBeginTransaction)
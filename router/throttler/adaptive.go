@@ -0,0 +1,141 @@
+package throttler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+//AdaptiveSettings of an adaptive limiter. Limits start at MaxLimit events per TimeWindow, are multiplied by DecreaseFactor
+//at most once per TimeWindow when the destination responds with 429 or 503, and are raised by Increase per TimeWindow
+//worth of successful requests, never going below MinLimit or above MaxLimit
+type AdaptiveSettings struct {
+	Enabled        bool
+	UserLevel      bool
+	TimeWindow     time.Duration
+	MinLimit       int
+	MaxLimit       int
+	Increase       int
+	DecreaseFactor float64
+	MaxRetryAfter  time.Duration
+}
+
+type adaptiveLimitT struct {
+	limit        float64
+	decreasedAt  time.Time
+	blockedUntil time.Time
+	limitStat    stats.RudderStats
+}
+
+//adaptiveLimitsT are the limits of the keys of an adaptive limiter.
+//Keys whose limit is back at MaxLimit are dropped, so only keys the destination pushed back on are kept
+type adaptiveLimitsT struct {
+	destinationName string
+	settings        AdaptiveSettings
+	limits          map[string]*adaptiveLimitT
+	limitsLock      sync.Mutex
+}
+
+func adaptiveKeys(destName, key string) []string {
+	return []string{fmt.Sprintf(`Router.throttler.%s.adaptive.%s`, destName, key), fmt.Sprintf(`Router.throttler.adaptive.%s`, key)}
+}
+
+func loadAdaptiveSettings(destName string) AdaptiveSettings {
+	var settings AdaptiveSettings
+	config.RegisterBoolConfigVariable(false, &settings.Enabled, false, adaptiveKeys(destName, "enabled")...)
+	config.RegisterBoolConfigVariable(false, &settings.UserLevel, false, adaptiveKeys(destName, "userLevel")...)
+	config.RegisterDurationConfigVariable(1, &settings.TimeWindow, false, time.Second, adaptiveKeys(destName, "timeWindow")...)
+	config.RegisterIntConfigVariable(1, &settings.MinLimit, false, 1, adaptiveKeys(destName, "minLimit")...)
+	config.RegisterIntConfigVariable(1000, &settings.MaxLimit, false, 1, adaptiveKeys(destName, "maxLimit")...)
+	config.RegisterIntConfigVariable(1, &settings.Increase, false, 1, adaptiveKeys(destName, "increase")...)
+	config.RegisterFloat64ConfigVariable(0.5, &settings.DecreaseFactor, false, adaptiveKeys(destName, "decreaseFactor")...)
+	config.RegisterDurationConfigVariable(300, &settings.MaxRetryAfter, false, time.Second, adaptiveKeys(destName, "maxRetryAfter")...)
+	return settings
+}
+
+func newAdaptiveLimits(destName string, settings AdaptiveSettings) *adaptiveLimitsT {
+	if settings.MinLimit < 1 {
+		settings.MinLimit = 1
+	}
+	if settings.MaxLimit < settings.MinLimit {
+		settings.MaxLimit = settings.MinLimit
+	}
+	if settings.TimeWindow <= 0 {
+		settings.TimeWindow = time.Second
+	}
+	if settings.DecreaseFactor <= 0 || settings.DecreaseFactor >= 1 {
+		settings.DecreaseFactor = 0.5
+	}
+	return &adaptiveLimitsT{
+		destinationName: destName,
+		settings:        settings,
+		limits:          make(map[string]*adaptiveLimitT),
+	}
+}
+
+//isThrottledStatus returns whether a destination response asks for sending less
+func isThrottledStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+//limit returns the current limit of the key, and whether the key is blocked because of a Retry-After
+func (adaptive *adaptiveLimitsT) limit(key string, currentTime time.Time) (limit int64, blocked bool) {
+	adaptive.limitsLock.Lock()
+	defer adaptive.limitsLock.Unlock()
+
+	adaptiveLimit, ok := adaptive.limits[key]
+	if !ok {
+		return int64(adaptive.settings.MaxLimit), false
+	}
+	return int64(adaptiveLimit.limit), currentTime.Before(adaptiveLimit.blockedUntil)
+}
+
+//onResponse lowers the limit of the key on 429 and 503 responses, blocking it for retryAfter if set, and raises it on success
+func (adaptive *adaptiveLimitsT) onResponse(key string, destID string, statusCode int, retryAfter time.Duration, currentTime time.Time) {
+	adaptive.limitsLock.Lock()
+	defer adaptive.limitsLock.Unlock()
+
+	adaptiveLimit, ok := adaptive.limits[key]
+	if isThrottledStatus(statusCode) {
+		if !ok {
+			adaptiveLimit = &adaptiveLimitT{limit: float64(adaptive.settings.MaxLimit)}
+			if destID != "" {
+				adaptiveLimit.limitStat = stats.NewTaggedStat("router_throttler_adaptive_limit", stats.GaugeType, stats.Tags{
+					"destType": adaptive.destinationName,
+					"destId":   destID,
+				})
+			}
+			adaptive.limits[key] = adaptiveLimit
+		}
+		if currentTime.Sub(adaptiveLimit.decreasedAt) >= adaptive.settings.TimeWindow {
+			adaptiveLimit.limit = math.Max(float64(adaptive.settings.MinLimit), adaptiveLimit.limit*adaptive.settings.DecreaseFactor)
+			adaptiveLimit.decreasedAt = currentTime
+			pkgLogger.Debugf(`[[ %s-router-throttler: Lowered adaptive limit of %s to %v]]`, adaptive.destinationName, key, adaptiveLimit.limit)
+		}
+		if retryAfter > 0 {
+			if retryAfter > adaptive.settings.MaxRetryAfter {
+				retryAfter = adaptive.settings.MaxRetryAfter
+			}
+			if blockedUntil := currentTime.Add(retryAfter); blockedUntil.After(adaptiveLimit.blockedUntil) {
+				adaptiveLimit.blockedUntil = blockedUntil
+			}
+		}
+	} else if ok && statusCode >= 200 && statusCode < 300 {
+		//raising by Increase/limit per success raises the limit by Increase once a whole limit of events succeeds
+		adaptiveLimit.limit += float64(adaptive.settings.Increase) / adaptiveLimit.limit
+		if adaptiveLimit.limit >= float64(adaptive.settings.MaxLimit) && !currentTime.Before(adaptiveLimit.blockedUntil) {
+			adaptiveLimit.limit = float64(adaptive.settings.MaxLimit)
+			delete(adaptive.limits, key)
+		}
+	} else {
+		return
+	}
+	if adaptiveLimit.limitStat != nil {
+		adaptiveLimit.limitStat.Gauge(int(adaptiveLimit.limit))
+	}
+}
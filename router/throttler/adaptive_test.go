@@ -0,0 +1,101 @@
+package throttler_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/router/throttler"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+//sendUntilLimited counts the events the throttler lets through until the limit is reached
+func sendUntilLimited(handle *throttler.HandleT, destID string, userID string, currentTime time.Time) int {
+	sent := 0
+	for !handle.CheckLimitReached(destID, userID, currentTime) && sent < 1000 {
+		handle.Inc(destID, userID, currentTime)
+		sent++
+	}
+	return sent
+}
+
+var _ = Describe("Adaptive throttler", func() {
+	var (
+		handle   *throttler.HandleT
+		settings throttler.AdaptiveSettings
+	)
+
+	BeforeEach(func() {
+		stats.Setup()
+		settings = throttler.AdaptiveSettings{
+			Enabled:        true,
+			TimeWindow:     time.Hour,
+			MinLimit:       2,
+			MaxLimit:       16,
+			Increase:       4,
+			DecreaseFactor: 0.5,
+			MaxRetryAfter:  time.Minute,
+		}
+		handle = &throttler.HandleT{}
+	})
+
+	It("should not throttle when disabled", func() {
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", throttler.AdaptiveSettings{})
+		Expect(handle.IsEnabled()).To(BeFalse())
+	})
+
+	It("should lower the limit multiplicatively on 429 and 503 responses, at most once per window", func() {
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", settings)
+		Expect(handle.IsDestLevelEnabled()).To(BeTrue())
+		Expect(handle.IsUserLevelEnabled()).To(BeFalse())
+
+		handle.RecordResponse("dest-1", nil, http.StatusTooManyRequests, 0)
+		handle.RecordResponse("dest-1", nil, http.StatusServiceUnavailable, 0)
+		Expect(sendUntilLimited(handle, "dest-1", "", time.Now())).To(Equal(8))
+		Expect(sendUntilLimited(handle, "dest-2", "", time.Now())).To(Equal(16), "limits are per destination")
+	})
+
+	It("should never go below the minimum limit", func() {
+		settings.TimeWindow = time.Millisecond
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", settings)
+
+		for i := 0; i < 5; i++ {
+			handle.RecordResponse("dest-1", nil, http.StatusTooManyRequests, 0)
+			time.Sleep(2 * time.Millisecond)
+		}
+		Expect(sendUntilLimited(handle, "dest-1", "", time.Now())).To(BeNumerically(">=", 2))
+	})
+
+	It("should raise the limit additively on success", func() {
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", settings)
+
+		handle.RecordResponse("dest-1", nil, http.StatusTooManyRequests, 0)
+		for i := 0; i < 8; i++ {
+			handle.RecordResponse("dest-1", nil, http.StatusOK, 0)
+		}
+		handle.RecordResponse("dest-1", nil, http.StatusBadRequest, 0)
+		Expect(sendUntilLimited(handle, "dest-1", "", time.Now())).To(Equal(11))
+	})
+
+	It("should block the destination for the Retry-After duration", func() {
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", settings)
+
+		now := time.Now()
+		handle.RecordResponse("dest-1", nil, http.StatusTooManyRequests, 10*time.Minute)
+		Expect(handle.CheckLimitReached("dest-1", "", now.Add(30*time.Second))).To(BeTrue())
+		Expect(handle.CheckLimitReached("dest-1", "", now.Add(2*time.Minute))).To(BeFalse(), "retry after is capped")
+	})
+
+	It("should adapt limits per user when user level is enabled", func() {
+		settings.UserLevel = true
+		handle.SetUpWithAdaptiveSettings("ADAPTIVE_TEST", settings)
+		Expect(handle.IsUserLevelEnabled()).To(BeTrue())
+
+		now := time.Now()
+		handle.RecordResponse("dest-1", []string{"user-1"}, http.StatusTooManyRequests, time.Second)
+		Expect(handle.CheckLimitReached("dest-1", "user-2", now)).To(BeTrue(), "the destination is blocked")
+		Expect(sendUntilLimited(handle, "dest-1", "user-1", now.Add(2*time.Second))).To(Equal(8))
+	})
+})
@@ -61,6 +61,11 @@ type LimitStatus struct {
 
 // Check checks status of rate-limiting for a key. It returns error when limiter data could not be read
 func (r *RateLimiter) Check(key string, currentTime time.Time) (limitStatus *LimitStatus, err error) {
+	return r.CheckWithLimit(key, r.requestsLimit, currentTime)
+}
+
+// CheckWithLimit checks status of rate-limiting for a key against requestsLimit instead of the limit declared in the constructor, for limits that change over time
+func (r *RateLimiter) CheckWithLimit(key string, requestsLimit int64, currentTime time.Time) (limitStatus *LimitStatus, err error) {
 	if currentTime.IsZero() {
 		currentTime = time.Now()
 	}
@@ -74,9 +79,9 @@ func (r *RateLimiter) Check(key string, currentTime time.Time) (limitStatus *Lim
 
 	rate := float64((float64(r.windowSize)-float64(timeFromCurrWindow))/float64(r.windowSize))*float64(prevValue) + float64(currentValue)
 	limitStatus = &LimitStatus{}
-	if rate >= float64(r.requestsLimit) {
+	if rate >= float64(requestsLimit) {
 		limitStatus.IsLimited = true
-		limitDuration := r.calcLimitDuration(prevValue, currentValue, timeFromCurrWindow)
+		limitDuration := r.calcLimitDuration(requestsLimit, prevValue, currentValue, timeFromCurrWindow)
 		limitStatus.LimitDuration = &limitDuration
 	}
 	limitStatus.CurrentRate = rate
@@ -88,18 +93,18 @@ func (r *RateLimiter) calcRate(timeFromCurrWindow time.Duration, prevValue int64
 	return float64((float64(r.windowSize)-float64(timeFromCurrWindow))/float64(r.windowSize))*float64(prevValue) + float64(currentValue)
 }
 
-func (r *RateLimiter) calcLimitDuration(prevValue, currValue int64, timeFromCurrWindow time.Duration) time.Duration {
-	// we should find x parameter in equation: x*prevValue+currentValue = r.requestsLimit
+func (r *RateLimiter) calcLimitDuration(requestsLimit, prevValue, currValue int64, timeFromCurrWindow time.Duration) time.Duration {
+	// we should find x parameter in equation: x*prevValue+currentValue = requestsLimit
 	// then (1.0-x)*windowSize is duration from current window start when limit can be removed
 	// then ((1.0-x)*windowSize) - timeFromCurrWindow is duration since current time to the time when limit can be removed = limitDuration
 	// --
-	// if prevValue is zero then unblock is in the next window so we should use equation x*currentValue+nextWindowValue = r.requestsLimit
+	// if prevValue is zero then unblock is in the next window so we should use equation x*currentValue+nextWindowValue = requestsLimit
 	// to calculate x parameter
 	var limitDuration time.Duration
 	if prevValue == 0 {
 		// unblock in the next window where prevValue is currValue and currValue is zero (assuming that since limit start all requests are blocked)
 		if currValue != 0 {
-			nextWindowUnblockPoint := float64(r.windowSize) * (1.0 - (float64(r.requestsLimit) / float64(currValue)))
+			nextWindowUnblockPoint := float64(r.windowSize) * (1.0 - (float64(requestsLimit) / float64(currValue)))
 			timeToNextWindow := r.windowSize - timeFromCurrWindow
 			limitDuration = timeToNextWindow + time.Duration(int64(nextWindowUnblockPoint)+1)
 		} else {
@@ -107,7 +112,7 @@ func (r *RateLimiter) calcLimitDuration(prevValue, currValue int64, timeFromCurr
 			limitDuration = -1
 		}
 	} else {
-		currWindowUnblockPoint := float64(r.windowSize) * (1.0 - (float64(r.requestsLimit-currValue) / float64(prevValue)))
+		currWindowUnblockPoint := float64(r.windowSize) * (1.0 - (float64(requestsLimit-currValue) / float64(prevValue)))
 		limitDuration = time.Duration(int64(currWindowUnblockPoint+1)) - timeFromCurrWindow
 
 	}
@@ -23,6 +23,7 @@ type Throttler interface {
 	IsEnabled() bool
 	IsUserLevelEnabled() bool
 	IsDestLevelEnabled() bool
+	RecordResponse(destID string, userIDs []string, statusCode int, retryAfter time.Duration)
 }
 
 type Limiter struct {
@@ -30,6 +31,7 @@ type Limiter struct {
 	eventLimit  int
 	timeWindow  time.Duration
 	ratelimiter *ratelimiter.RateLimiter
+	adaptive    *adaptiveLimitsT
 }
 
 type Settings struct {
@@ -76,8 +78,27 @@ func (throttler *HandleT) setLimits() {
 	}
 }
 
+//setAdaptiveLimits replaces the static limits with adaptive ones, per destination and optionally per user
+func (throttler *HandleT) setAdaptiveLimits(settings AdaptiveSettings) {
+	if !settings.Enabled {
+		return
+	}
+	destLimits := newAdaptiveLimits(throttler.destinationName, settings)
+	settings = destLimits.settings
+	pkgLogger.Infof(`[[ %s-router-throttler: Enabled adaptive throttler with limits between %d and %d per %v, userLevel: %v]]`, throttler.destinationName, settings.MinLimit, settings.MaxLimit, settings.TimeWindow, settings.UserLevel)
+	throttler.destLimiter = &Limiter{enabled: true, eventLimit: settings.MaxLimit, timeWindow: settings.TimeWindow, adaptive: destLimits}
+	if settings.UserLevel {
+		throttler.userLimiter = &Limiter{enabled: true, eventLimit: settings.MaxLimit, timeWindow: settings.TimeWindow, adaptive: newAdaptiveLimits(throttler.destinationName, settings)}
+	}
+}
+
 //SetUp eventLimiter
 func (throttler *HandleT) SetUp(destName string) {
+	throttler.SetUpWithAdaptiveSettings(destName, loadAdaptiveSettings(destName))
+}
+
+//SetUpWithAdaptiveSettings sets up eventLimiter with the given adaptive settings instead of the ones from config
+func (throttler *HandleT) SetUpWithAdaptiveSettings(destName string, adaptiveSettings AdaptiveSettings) {
 	pkgLogger = logger.NewLogger().Child("router").Child("throttler")
 	throttler.destinationName = destName
 	throttler.destLimiter = &Limiter{}
@@ -85,6 +106,7 @@ func (throttler *HandleT) SetUp(destName string) {
 
 	// check if it has throttling config for destination
 	throttler.setLimits()
+	throttler.setAdaptiveLimits(adaptiveSettings)
 
 	if throttler.destLimiter.enabled {
 		dataStore := ratelimiter.NewMapLimitStore(2*throttler.destLimiter.timeWindow, 10*time.Second)
@@ -102,7 +124,7 @@ func (throttler *HandleT) CheckLimitReached(destID string, userID string, curren
 	var destLevelLimitReached bool
 	if throttler.destLimiter.enabled {
 		destKey := throttler.getDestKey(destID)
-		limitStatus, err := throttler.destLimiter.ratelimiter.Check(destKey, currentTime)
+		limitStatus, err := throttler.destLimiter.check(destKey, currentTime)
 		if err != nil {
 			// TODO: handle this
 			pkgLogger.Errorf(`[[ %s-router-throttler: Error checking limitStatus: %v]]`, throttler.destinationName, err)
@@ -114,7 +136,7 @@ func (throttler *HandleT) CheckLimitReached(destID string, userID string, curren
 	var userLevelLimitReached bool
 	if !destLevelLimitReached && throttler.userLimiter.enabled {
 		userKey := throttler.getUserKey(destID, userID)
-		limitStatus, err := throttler.userLimiter.ratelimiter.Check(userKey, currentTime)
+		limitStatus, err := throttler.userLimiter.check(userKey, currentTime)
 		if err != nil {
 			// TODO: handle this
 			pkgLogger.Errorf(`[[ %s-router-throttler: Error checking limitStatus: %v]]`, throttler.destinationName, err)
@@ -152,6 +174,22 @@ func (throttler *HandleT) Dec(destID string, userID string, count int64, current
 	}
 }
 
+//RecordResponse adapts the limits of the destination and users of a request to its response, if adaptive throttling is enabled.
+//429 and 503 responses lower the limits and block them for retryAfter, if set. Successful responses raise them
+func (throttler *HandleT) RecordResponse(destID string, userIDs []string, statusCode int, retryAfter time.Duration) {
+	currentTime := time.Now()
+	if throttler.destLimiter.adaptive != nil && destID != "" {
+		throttler.destLimiter.adaptive.onResponse(throttler.getDestKey(destID), destID, statusCode, retryAfter, currentTime)
+	}
+	if throttler.userLimiter.adaptive != nil {
+		for _, userID := range userIDs {
+			if userID != "" {
+				throttler.userLimiter.adaptive.onResponse(throttler.getUserKey(destID, userID), "", statusCode, retryAfter, currentTime)
+			}
+		}
+	}
+}
+
 func (throttler *HandleT) IsEnabled() bool {
 	return throttler.destLimiter.enabled || throttler.userLimiter.enabled
 }
@@ -164,6 +202,18 @@ func (throttler *HandleT) IsDestLevelEnabled() bool {
 	return throttler.destLimiter.enabled
 }
 
+//check checks the rate of the key against the limit of the limiter, or the current limit of the key for adaptive limiters
+func (limiter *Limiter) check(key string, currentTime time.Time) (*ratelimiter.LimitStatus, error) {
+	if limiter.adaptive == nil {
+		return limiter.ratelimiter.Check(key, currentTime)
+	}
+	limit, blocked := limiter.adaptive.limit(key, currentTime)
+	if blocked {
+		return &ratelimiter.LimitStatus{IsLimited: true}, nil
+	}
+	return limiter.ratelimiter.CheckWithLimit(key, limit, currentTime)
+}
+
 func (throttler *HandleT) getDestKey(destID string) string {
 	return fmt.Sprintf(`%s_%s`, throttler.destinationName, destID)
 }
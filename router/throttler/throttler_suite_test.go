@@ -0,0 +1,13 @@
+package throttler_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestThrottler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Throttler Suite")
+}
@@ -9,6 +9,7 @@ import (
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/debugger"
+	livedebugger "github.com/rudderlabs/rudder-server/services/debugger/live"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 )
@@ -50,8 +51,10 @@ type EventDeliveryStatusUploader struct {
 }
 
 //RecordEventDeliveryStatus is used to put the delivery status in the deliveryStatusesBatchChannel,
-//which will be processed by handleJobs.
+//which will be processed by handleJobs. The status is also streamed to the live debugger, uploads enabled or not.
 func RecordEventDeliveryStatus(destinationID string, deliveryStatus *DeliveryStatusT) bool {
+	livedebugger.RecordDeliveryStatus(deliveryStatus.SourceID, destinationID, deliveryStatus)
+
 	//if disableEventUploads is true, return;
 	if disableEventDeliveryStatusUploads {
 		return false
@@ -68,7 +71,11 @@ func RecordEventDeliveryStatus(destinationID string, deliveryStatus *DeliverySta
 	return true
 }
 
+//HasUploadEnabled returns whether the delivery statuses of destID are to be recorded, uploaded or streamed to the live debugger
 func HasUploadEnabled(destID string) bool {
+	if livedebugger.HasDestinationSubscriptions(destID) {
+		return true
+	}
 	configSubscriberLock.RLock()
 	defer configSubscriberLock.RUnlock()
 	_, ok := uploadEnabledDestinationIDs[destID]
@@ -0,0 +1,296 @@
+package livedebugger
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/time/rate"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+//Types of the events streamed to live debugger subscriptions
+const (
+	SourceEventType          = "source_event"
+	TransformationStatusType = "transformation_status"
+	DeliveryStatusType       = "delivery_status"
+)
+
+//REDACTED replaces the values of redacted keys in streamed payloads
+const REDACTED = "[REDACTED]"
+
+var (
+	maxEventsPerSecond int
+	maxSubscriptions   int
+	queueSize          int
+	redactedKeys       string
+	keepAliveInterval  time.Duration
+	pkgLogger          logger.LoggerI
+)
+
+var hub *hubT
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("debugger").Child("live")
+	hub = &hubT{subscriptions: make(map[*SubscriptionT]struct{})}
+}
+
+func loadConfig() {
+	config.RegisterIntConfigVariable(10, &maxEventsPerSecond, true, 1, "LiveDebugger.maxEventsPerSecond")
+	config.RegisterIntConfigVariable(10, &maxSubscriptions, true, 1, "LiveDebugger.maxSubscriptions")
+	config.RegisterIntConfigVariable(100, &queueSize, false, 1, "LiveDebugger.queueSize")
+	config.RegisterStringConfigVariable("email,phone,password,firstName,lastName,address,ip,requestIP", &redactedKeys, true, "LiveDebugger.redactedKeys")
+	config.RegisterDurationConfigVariable(time.Duration(15), &keepAliveInterval, false, time.Second, "LiveDebugger.keepAliveInterval")
+}
+
+//FilterT selects the events of a subscription. Source events are selected by WriteKeys,
+//transformation and delivery statuses by DestinationIDs and, if set, SourceIDs
+type FilterT struct {
+	WriteKeys      map[string]struct{}
+	SourceIDs      map[string]struct{}
+	DestinationIDs map[string]struct{}
+}
+
+//EventT is an event streamed to subscriptions, its payload redacted
+type EventT struct {
+	Type          string          `json:"type"`
+	WriteKey      string          `json:"writeKey,omitempty"`
+	SourceID      string          `json:"sourceId,omitempty"`
+	DestinationID string          `json:"destinationId,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+//SubscriptionT receives the events matching its filter, sampled with its sampling rate and capped to its rate limit.
+//Events which can't be received at once are dropped and counted
+type SubscriptionT struct {
+	filter       FilterT
+	samplingRate float64
+	limiter      *rate.Limiter
+	events       chan *EventT
+	dropped      uint64
+}
+
+type hubT struct {
+	lock          sync.RWMutex
+	subscriptions map[*SubscriptionT]struct{}
+	count         int32
+}
+
+func (filter *FilterT) matchesSource(writeKey string) bool {
+	_, ok := filter.WriteKeys[writeKey]
+	return ok
+}
+
+func (filter *FilterT) matchesDestination(sourceID string, destinationID string) bool {
+	if _, ok := filter.DestinationIDs[destinationID]; !ok {
+		return false
+	}
+	if len(filter.SourceIDs) == 0 {
+		return true
+	}
+	_, ok := filter.SourceIDs[sourceID]
+	return ok
+}
+
+/*
+Subscribe starts streaming the events matching filter. samplingRate is the fraction of the matching events to stream,
+eventsPerSecond caps the streamed events, up to LiveDebugger.maxEventsPerSecond.
+It returns false if there are already LiveDebugger.maxSubscriptions subscriptions
+*/
+func Subscribe(filter FilterT, samplingRate float64, eventsPerSecond int) (*SubscriptionT, bool) {
+	if eventsPerSecond <= 0 || eventsPerSecond > maxEventsPerSecond {
+		eventsPerSecond = maxEventsPerSecond
+	}
+	if samplingRate <= 0 || samplingRate > 1 {
+		samplingRate = 1
+	}
+	subscription := &SubscriptionT{
+		filter:       filter,
+		samplingRate: samplingRate,
+		limiter:      rate.NewLimiter(rate.Limit(eventsPerSecond), eventsPerSecond),
+		events:       make(chan *EventT, queueSize),
+	}
+
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	if len(hub.subscriptions) >= maxSubscriptions {
+		return nil, false
+	}
+	hub.subscriptions[subscription] = struct{}{}
+	atomic.StoreInt32(&hub.count, int32(len(hub.subscriptions)))
+	return subscription, true
+}
+
+//Events returns the channel of the events of the subscription
+func (subscription *SubscriptionT) Events() <-chan *EventT {
+	return subscription.events
+}
+
+//Dropped returns the number of events dropped since the last call, being over the rate limit or not received in time
+func (subscription *SubscriptionT) Dropped() uint64 {
+	return atomic.SwapUint64(&subscription.dropped, 0)
+}
+
+//Close stops streaming events to the subscription
+func (subscription *SubscriptionT) Close() {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	delete(hub.subscriptions, subscription)
+	atomic.StoreInt32(&hub.count, int32(len(hub.subscriptions)))
+}
+
+func (subscription *SubscriptionT) send(event *EventT) {
+	if subscription.samplingRate < 1 && rand.Float64() >= subscription.samplingRate {
+		return
+	}
+	if !subscription.limiter.Allow() {
+		atomic.AddUint64(&subscription.dropped, 1)
+		return
+	}
+	select {
+	case subscription.events <- event:
+	default:
+		atomic.AddUint64(&subscription.dropped, 1)
+	}
+}
+
+//matching returns the subscriptions matching match, without locking the hub when there are none
+func matching(match func(filter *FilterT) bool) []*SubscriptionT {
+	if atomic.LoadInt32(&hub.count) == 0 {
+		return nil
+	}
+	hub.lock.RLock()
+	defer hub.lock.RUnlock()
+	var subscriptions []*SubscriptionT
+	for subscription := range hub.subscriptions {
+		if match(&subscription.filter) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions
+}
+
+//HasDestinationSubscriptions returns whether any subscription streams the statuses of destinationID
+func HasDestinationSubscriptions(destinationID string) bool {
+	return len(matching(func(filter *FilterT) bool {
+		_, ok := filter.DestinationIDs[destinationID]
+		return ok
+	})) > 0
+}
+
+//HasSubscriptions returns whether any subscription streams the statuses of destinationID for sourceID
+func HasSubscriptions(sourceID string, destinationID string) bool {
+	return len(matching(func(filter *FilterT) bool {
+		return filter.matchesDestination(sourceID, destinationID)
+	})) > 0
+}
+
+//RecordSourceEvents streams the events of a batch received by the gateway for writeKey
+func RecordSourceEvents(writeKey string, eventBatch string) {
+	subscriptions := matching(func(filter *FilterT) bool {
+		return filter.matchesSource(writeKey)
+	})
+	if len(subscriptions) == 0 {
+		return
+	}
+	receivedAt := gjson.Get(eventBatch, "receivedAt").String()
+	gjson.Get(eventBatch, "batch").ForEach(func(_, value gjson.Result) bool {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(value.Raw), &event); err != nil {
+			return true
+		}
+		if _, ok := event["receivedAt"]; !ok && receivedAt != "" {
+			event["receivedAt"] = receivedAt
+		}
+		payload, err := redactedPayload(event)
+		if err != nil {
+			pkgLogger.Errorf("[Live debugger] Failed to marshal source event. Err: %v", err)
+			return true
+		}
+		liveEvent := &EventT{Type: SourceEventType, WriteKey: writeKey, Payload: payload}
+		for _, subscription := range subscriptions {
+			subscription.send(liveEvent)
+		}
+		return true
+	})
+}
+
+//RecordTransformationStatus streams the status of the transformation of an event of sourceID for destinationID
+func RecordTransformationStatus(sourceID string, destinationID string, status interface{}) {
+	record(TransformationStatusType, sourceID, destinationID, status)
+}
+
+//RecordDeliveryStatus streams the status of the delivery of an event of sourceID to destinationID
+func RecordDeliveryStatus(sourceID string, destinationID string, status interface{}) {
+	record(DeliveryStatusType, sourceID, destinationID, status)
+}
+
+func record(eventType string, sourceID string, destinationID string, status interface{}) {
+	subscriptions := matching(func(filter *FilterT) bool {
+		return filter.matchesDestination(sourceID, destinationID)
+	})
+	if len(subscriptions) == 0 {
+		return
+	}
+	//statuses are round tripped through json to be redacted like any other payload
+	rawStatus, err := json.Marshal(status)
+	if err != nil {
+		pkgLogger.Errorf("[Live debugger] Failed to marshal %s. Err: %v", eventType, err)
+		return
+	}
+	var genericStatus interface{}
+	if err = json.Unmarshal(rawStatus, &genericStatus); err != nil {
+		pkgLogger.Errorf("[Live debugger] Failed to unmarshal %s. Err: %v", eventType, err)
+		return
+	}
+	payload, err := redactedPayload(genericStatus)
+	if err != nil {
+		pkgLogger.Errorf("[Live debugger] Failed to marshal %s. Err: %v", eventType, err)
+		return
+	}
+	liveEvent := &EventT{Type: eventType, SourceID: sourceID, DestinationID: destinationID, Payload: payload}
+	for _, subscription := range subscriptions {
+		subscription.send(liveEvent)
+	}
+}
+
+//redactedPayload marshals value with the values of every LiveDebugger.redactedKeys key replaced, at any depth, keys being matched case insensitively
+func redactedPayload(value interface{}) (json.RawMessage, error) {
+	keys := make(map[string]struct{})
+	for _, key := range strings.Split(redactedKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+	return json.Marshal(redact(value, keys))
+}
+
+func redact(value interface{}, keys map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if _, ok := keys[strings.ToLower(key)]; ok {
+				v[key] = REDACTED
+				continue
+			}
+			v[key] = redact(nested, keys)
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = redact(nested, keys)
+		}
+	case json.RawMessage:
+		var nested interface{}
+		if err := json.Unmarshal(v, &nested); err == nil {
+			return redact(nested, keys)
+		}
+	}
+	return value
+}
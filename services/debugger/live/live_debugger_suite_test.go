@@ -0,0 +1,13 @@
+package livedebugger
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLiveDebugger(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LiveDebugger Suite")
+}
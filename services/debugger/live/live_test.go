@@ -0,0 +1,180 @@
+package livedebugger
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func set(values ...string) map[string]struct{} {
+	s := make(map[string]struct{})
+	for _, value := range values {
+		s[value] = struct{}{}
+	}
+	return s
+}
+
+func receive(subscription *SubscriptionT) *EventT {
+	select {
+	case event := <-subscription.Events():
+		return event
+	case <-time.After(time.Second):
+		return nil
+	}
+}
+
+var _ = Describe("LiveDebugger", func() {
+	var filter FilterT
+
+	BeforeEach(func() {
+		maxEventsPerSecond = 10
+		maxSubscriptions = 10
+		queueSize = 100
+		redactedKeys = "email,password"
+		filter = FilterT{
+			WriteKeys:      set("write-key"),
+			SourceIDs:      set("source-id"),
+			DestinationIDs: set("destination-id"),
+		}
+	})
+
+	Context("Subscriptions", func() {
+		It("streams the source events of the subscribed writeKey with redacted payloads", func() {
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			RecordSourceEvents("other-write-key", `{"batch":[{"event":"ignored"}]}`)
+			RecordSourceEvents("write-key", `{"receivedAt":"2021-01-01T00:00:00.000Z","batch":[{"event":"signup","context":{"traits":{"Email":"a@b.c","plan":"pro"}},"password":"secret"}]}`)
+
+			event := receive(subscription)
+			Expect(event).NotTo(BeNil())
+			Expect(event.Type).To(Equal(SourceEventType))
+			Expect(event.WriteKey).To(Equal("write-key"))
+			var payload map[string]interface{}
+			Expect(json.Unmarshal(event.Payload, &payload)).To(Succeed())
+			Expect(payload["event"]).To(Equal("signup"))
+			Expect(payload["receivedAt"]).To(Equal("2021-01-01T00:00:00.000Z"))
+			Expect(payload["password"]).To(Equal(REDACTED))
+			traits := payload["context"].(map[string]interface{})["traits"].(map[string]interface{})
+			Expect(traits["Email"]).To(Equal(REDACTED))
+			Expect(traits["plan"]).To(Equal("pro"))
+			Expect(receive(subscription)).To(BeNil())
+		})
+
+		It("streams the statuses of the subscribed source and destination", func() {
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			Expect(HasDestinationSubscriptions("destination-id")).To(BeTrue())
+			Expect(HasSubscriptions("other-source-id", "destination-id")).To(BeFalse())
+			RecordDeliveryStatus("other-source-id", "destination-id", map[string]string{"jobState": "aborted"})
+			RecordTransformationStatus("source-id", "other-destination-id", map[string]string{"error": "ignored"})
+			RecordDeliveryStatus("source-id", "destination-id", map[string]interface{}{"jobState": "succeeded", "payload": map[string]string{"email": "a@b.c"}})
+
+			event := receive(subscription)
+			Expect(event).NotTo(BeNil())
+			Expect(event.Type).To(Equal(DeliveryStatusType))
+			Expect(event.SourceID).To(Equal("source-id"))
+			Expect(event.DestinationID).To(Equal("destination-id"))
+			Expect(string(event.Payload)).To(MatchJSON(`{"jobState":"succeeded","payload":{"email":"[REDACTED]"}}`))
+			Expect(receive(subscription)).To(BeNil())
+		})
+
+		It("stops streaming once closed", func() {
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			subscription.Close()
+
+			Expect(HasDestinationSubscriptions("destination-id")).To(BeFalse())
+			RecordDeliveryStatus("source-id", "destination-id", map[string]string{"jobState": "succeeded"})
+			Expect(receive(subscription)).To(BeNil())
+		})
+
+		It("limits the number of subscriptions", func() {
+			maxSubscriptions = 1
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			_, ok = Subscribe(filter, 1, 0)
+			Expect(ok).To(BeFalse())
+			subscription.Close()
+
+			subscription, ok = Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			subscription.Close()
+		})
+	})
+
+	Context("Rate cap", func() {
+		It("drops and counts the events over the rate of the subscription", func() {
+			subscription, ok := Subscribe(filter, 1, 2)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			for i := 0; i < 5; i++ {
+				RecordDeliveryStatus("source-id", "destination-id", map[string]int{"attemptNum": i})
+			}
+			Expect(receive(subscription)).NotTo(BeNil())
+			Expect(receive(subscription)).NotTo(BeNil())
+			Expect(receive(subscription)).To(BeNil())
+			Expect(subscription.Dropped()).To(Equal(uint64(3)))
+			Expect(subscription.Dropped()).To(Equal(uint64(0)))
+		})
+
+		It("caps the rate of subscriptions to LiveDebugger.maxEventsPerSecond", func() {
+			maxEventsPerSecond = 1
+			subscription, ok := Subscribe(filter, 1, 100)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			RecordDeliveryStatus("source-id", "destination-id", map[string]int{"attemptNum": 1})
+			RecordDeliveryStatus("source-id", "destination-id", map[string]int{"attemptNum": 2})
+			Expect(receive(subscription)).NotTo(BeNil())
+			Expect(receive(subscription)).To(BeNil())
+			Expect(subscription.Dropped()).To(Equal(uint64(1)))
+		})
+
+		It("drops the events not received in time", func() {
+			queueSize = 1
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			RecordDeliveryStatus("source-id", "destination-id", map[string]int{"attemptNum": 1})
+			RecordDeliveryStatus("source-id", "destination-id", map[string]int{"attemptNum": 2})
+			Expect(subscription.Dropped()).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("Stream", func() {
+		It("writes events as server-sent events", func() {
+			subscription, ok := Subscribe(filter, 1, 0)
+			Expect(ok).To(BeTrue())
+			defer subscription.Close()
+
+			RecordTransformationStatus("source-id", "destination-id", map[string]bool{"error": false})
+			recorder := httptest.NewRecorder()
+			done := make(chan struct{})
+			streamed := make(chan error)
+			go func() {
+				streamed <- Stream(recorder, done, subscription)
+			}()
+			Eventually(func() int {
+				return len(subscription.Events())
+			}).Should(Equal(0))
+			close(done)
+			Expect(<-streamed).To(Succeed())
+
+			Expect(recorder.Header().Get("Content-Type")).To(Equal("text/event-stream"))
+			body := recorder.Body.String()
+			Expect(strings.HasPrefix(body, "event: transformation_status\ndata: ")).To(BeTrue())
+			data := strings.TrimSuffix(strings.TrimPrefix(body, "event: transformation_status\ndata: "), "\n\n")
+			Expect(data).To(MatchJSON(`{"type":"transformation_status","sourceId":"source-id","destinationId":"destination-id","payload":{"error":false}}`))
+		})
+	})
+})
@@ -0,0 +1,65 @@
+package livedebugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+//Stream writes the events of subscription to w as server-sent events until done is closed or a write fails.
+//Dropped events are reported with dropped events and idle streams are kept alive with comments
+func Stream(w http.ResponseWriter, done <-chan struct{}, subscription *SubscriptionT) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming unsupported by response writer")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return nil
+		case event := <-subscription.Events():
+			err = writeEvent(w, event)
+			if err == nil {
+				err = writeDropped(w, subscription.Dropped())
+			}
+		case <-keepAlive.C:
+			err = writeDropped(w, subscription.Dropped())
+			if err == nil {
+				_, err = io.WriteString(w, ": ping\n\n")
+			}
+		}
+		if err != nil {
+			return err
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, event *EventT) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func writeDropped(w io.Writer, dropped uint64) error {
+	if dropped == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", dropped)
+	return err
+}
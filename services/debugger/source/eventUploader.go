@@ -10,6 +10,7 @@ import (
 	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/debugger"
+	livedebugger "github.com/rudderlabs/rudder-server/services/debugger/live"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -69,8 +70,10 @@ func Setup() {
 }
 
 //RecordEvent is used to put the event batch in the eventBatchChannel,
-//which will be processed by handleEvents.
+//which will be processed by handleEvents. The events are also streamed to the live debugger, uploads enabled or not.
 func RecordEvent(writeKey string, eventBatch string) bool {
+	livedebugger.RecordSourceEvents(writeKey, eventBatch)
+
 	//if disableEventUploads is true, return;
 	if disableEventUploads {
 		return false
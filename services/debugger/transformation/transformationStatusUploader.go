@@ -11,6 +11,7 @@ import (
 	"github.com/rudderlabs/rudder-server/processor/transformer"
 	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/debugger"
+	livedebugger "github.com/rudderlabs/rudder-server/services/debugger/live"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
@@ -163,13 +164,23 @@ func UploadTransformationStatus(tStatus *TransformationStatusT) {
 		}
 	}()
 
-	//if disableTransformationUploads is true, return;
-	if disableTransformationUploads {
+	streaming := livedebugger.HasSubscriptions(tStatus.SourceID, tStatus.DestID)
+	//if disableTransformationUploads is true and nothing is streamed to the live debugger, return;
+	if disableTransformationUploads && !streaming {
 		return
 	}
 
 	for _, transformation := range tStatus.Destination.Transformations {
-		if IsUploadEnabled(transformation.ID) {
+		upload := !disableTransformationUploads && IsUploadEnabled(transformation.ID)
+		record := func(transformStatus *TransformStatusT) {
+			if streaming {
+				livedebugger.RecordTransformationStatus(transformStatus.SourceID, transformStatus.DestinationID, transformStatus)
+			}
+			if upload {
+				RecordTransformationStatus(transformStatus)
+			}
+		}
+		if upload || streaming {
 			reportedMessageIDs := make(map[string]struct{})
 			eventBeforeMap := make(map[string]*EventBeforeTransform)
 			eventAfterMap := make(map[string]*EventsAfterTransform)
@@ -192,7 +203,7 @@ func UploadTransformationStatus(tStatus *TransformationStatusT) {
 			}
 
 			for k := range eventBeforeMap {
-				RecordTransformationStatus(&TransformStatusT{TransformationID: transformation.ID,
+				record(&TransformStatusT{TransformationID: transformation.ID,
 					SourceID:      tStatus.SourceID,
 					DestinationID: tStatus.DestID,
 					EventBefore:   eventBeforeMap[k],
@@ -212,7 +223,7 @@ func UploadTransformationStatus(tStatus *TransformationStatusT) {
 							StatusCode: failedEvent.StatusCode,
 						}
 
-						RecordTransformationStatus(&TransformStatusT{TransformationID: transformation.ID,
+						record(&TransformStatusT{TransformationID: transformation.ID,
 							SourceID:      tStatus.SourceID,
 							DestinationID: tStatus.DestID,
 							EventBefore:   eventBefore,
@@ -229,7 +240,7 @@ func UploadTransformationStatus(tStatus *TransformationStatusT) {
 						StatusCode: failedEvent.StatusCode,
 					}
 
-					RecordTransformationStatus(&TransformStatusT{TransformationID: transformation.ID,
+					record(&TransformStatusT{TransformationID: transformation.ID,
 						SourceID:      tStatus.SourceID,
 						DestinationID: tStatus.DestID,
 						EventBefore:   eventBefore,
@@ -247,7 +258,7 @@ func UploadTransformationStatus(tStatus *TransformationStatusT) {
 						IsDropped:  true,
 					}
 
-					RecordTransformationStatus(&TransformStatusT{TransformationID: transformation.ID,
+					record(&TransformStatusT{TransformationID: transformation.ID,
 						SourceID:      tStatus.SourceID,
 						DestinationID: tStatus.DestID,
 						EventBefore:   eventBefore,
@@ -0,0 +1,114 @@
+package dedup
+
+import (
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+// badgerStoreT is the default dedup store, backed by a node-local BadgerDB under TMPDIR.
+// Keys are the source and messageID, as the rows of the postgres store, so that both stores dedup
+// the same events and the dedup windows of sources don't overwrite each other
+type badgerStoreT struct {
+	badgerDB *badger.DB
+}
+
+var badgerLogger badger.Logger
+
+type loggerT struct{}
+
+func (l *loggerT) Errorf(s string, args ...interface{}) {
+	pkgLogger.Errorf(s, args)
+}
+
+func (l *loggerT) Warningf(s string, args ...interface{}) {
+	pkgLogger.Warnf(s, args)
+}
+
+func (l *loggerT) Infof(s string, args ...interface{}) {
+	pkgLogger.Infof(s, args)
+}
+
+func (l *loggerT) Debugf(s string, args ...interface{}) {
+	pkgLogger.Debugf(s, args)
+}
+
+func (b *badgerStoreT) setup(clearDB *bool) {
+	badgerLogger = &loggerT{}
+	b.openBadger(clearDB)
+}
+
+func (b *badgerStoreT) openBadger(clearDB *bool) {
+	var err error
+	badgerPathName := "/badgerdbv2"
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		panic(err)
+	}
+	path := fmt.Sprintf(`%v%v`, tmpDirPath, badgerPathName)
+
+	b.badgerDB, err = badger.Open(badger.DefaultOptions(path).WithTruncate(true).WithLogger(badgerLogger))
+	if err != nil {
+		panic(err)
+	}
+	if *clearDB {
+		err = b.badgerDB.DropAll()
+		if err != nil {
+			panic(err)
+		}
+	}
+	rruntime.Go(func() {
+		b.gcBadgerDB()
+	})
+}
+
+func (b *badgerStoreT) printHistogram() {
+	b.badgerDB.PrintHistogram(nil)
+}
+
+func (b *badgerStoreT) gcBadgerDB() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+	again:
+		err := b.badgerDB.RunValueLogGC(0.5)
+		if err == nil {
+			goto again
+		}
+	}
+}
+
+func badgerKey(sourceID string, messageID string) []byte {
+	return []byte(sourceID + "/" + messageID)
+}
+
+func (b *badgerStoreT) set(sourceID string, messageIDs []string, ttl time.Duration) error {
+	return b.badgerDB.Update(func(txn *badger.Txn) error {
+		for _, messageID := range messageIDs {
+			e := badger.NewEntry(badgerKey(sourceID, messageID), nil).WithTTL(ttl)
+			if err := txn.SetEntry(e); err == badger.ErrTxnTooBig {
+				_ = txn.Commit()
+				txn = b.badgerDB.NewTransaction(true)
+				_ = txn.SetEntry(e)
+			}
+		}
+		return nil
+	})
+}
+
+func (b *badgerStoreT) exists(sourceID string, messageIDs []string) (map[string]struct{}, error) {
+	found := make(map[string]struct{})
+	err := b.badgerDB.View(func(txn *badger.Txn) error {
+		for _, messageID := range messageIDs {
+			_, err := txn.Get(badgerKey(sourceID, messageID))
+			if err != badger.ErrKeyNotFound {
+				found[messageID] = struct{}{}
+			}
+		}
+		return nil
+	})
+	return found, err
+}
@@ -7,33 +7,49 @@ import (
 	"sort"
 	"time"
 
-	badger "github.com/dgraph-io/badger/v2"
 	"github.com/rudderlabs/rudder-server/config"
-	"github.com/rudderlabs/rudder-server/rruntime"
 	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/utils/logger"
-	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+const (
+	BadgerBackend   = "badger"
+	PostgresBackend = "postgres"
 )
 
 type DedupI interface {
-	FindDuplicates(messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int)
-	MarkProcessed(messageIDs []string)
+	FindDuplicates(sourceID string, messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int)
+	MarkProcessed(messageIDsBySourceID map[string][]string)
 	PrintHistogram()
 }
 
-type DedupHandleT struct {
-	badgerDB *badger.DB
-	stats    stats.Stats
+// storeT is implemented by every dedup backend. It only needs to know about messageIDs
+// seen in earlier batches, dedup within the current batch is handled by dedupHandleT
+type storeT interface {
+	// exists returns the subset of messageIDs of the source already present in the store
+	exists(sourceID string, messageIDs []string) (map[string]struct{}, error)
+	// set marks messageIDs of the source as processed for the given ttl
+	set(sourceID string, messageIDs []string, ttl time.Duration) error
+	printHistogram()
+}
+
+// dedupHandleT implements DedupI on top of a backend store
+type dedupHandleT struct {
+	backend string
+	store   storeT
+	stats   stats.Stats
 }
 
 var (
 	dedupWindow time.Duration
+	backend     string
 	pkgLogger   logger.LoggerI
 )
 
 func loadConfig() {
 	// Dedup time window in hours
 	config.RegisterDurationConfigVariable(time.Duration(3600), &dedupWindow, true, time.Second, []string{"Dedup.dedupWindow", "Dedup.dedupWindowInS"}...)
+	config.RegisterStringConfigVariable(BadgerBackend, &backend, false, "Dedup.backend")
 }
 
 func init() {
@@ -41,94 +57,36 @@ func init() {
 	pkgLogger = logger.NewLogger().Child("dedup")
 }
 
-func (d *DedupHandleT) setup(clearDB *bool) {
-	d.stats = stats.DefaultStats
-	badgerLogger = &loggerT{}
-	d.openBadger(clearDB)
-}
-
-var badgerLogger badger.Logger
-
-type loggerT struct{}
-
-func (l *loggerT) Errorf(s string, args ...interface{}) {
-	pkgLogger.Errorf(s, args)
-}
-
-func (l *loggerT) Warningf(s string, args ...interface{}) {
-	pkgLogger.Warnf(s, args)
-}
-
-func (l *loggerT) Infof(s string, args ...interface{}) {
-	pkgLogger.Infof(s, args)
-}
-
-func (l *loggerT) Debugf(s string, args ...interface{}) {
-	pkgLogger.Debugf(s, args)
-}
-
-func (d *DedupHandleT) openBadger(clearDB *bool) {
-	var err error
-	badgerPathName := "/badgerdbv2"
-	tmpDirPath, err := misc.CreateTMPDIR()
-	if err != nil {
-		panic(err)
-	}
-	path := fmt.Sprintf(`%v%v`, tmpDirPath, badgerPathName)
-
-	d.badgerDB, err = badger.Open(badger.DefaultOptions(path).WithTruncate(true).WithLogger(badgerLogger))
-	if err != nil {
-		panic(err)
+// WindowForSource returns the dedup window configured for a source
+// through Dedup.<sourceID>.dedupWindow, falling back to Dedup.dedupWindow
+func WindowForSource(sourceID string) time.Duration {
+	if sourceID == "" {
+		return dedupWindow
 	}
-	if *clearDB {
-		err = d.badgerDB.DropAll()
-		if err != nil {
-			panic(err)
-		}
+	key := fmt.Sprintf("Dedup.%s.dedupWindow", sourceID)
+	if !config.IsSet(key) {
+		return dedupWindow
 	}
-	rruntime.Go(func() {
-		d.gcBadgerDB()
-	})
+	return config.GetDuration(key, dedupWindow/time.Second, time.Second)
 }
 
-func (d *DedupHandleT) PrintHistogram() {
-	d.badgerDB.PrintHistogram(nil)
+func (d *dedupHandleT) PrintHistogram() {
+	d.store.printHistogram()
 }
 
-func (d *DedupHandleT) gcBadgerDB() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	for range ticker.C {
-	again:
-		err := d.badgerDB.RunValueLogGC(0.5)
-		if err == nil {
-			goto again
+func (d *dedupHandleT) MarkProcessed(messageIDsBySourceID map[string][]string) {
+	for sourceID, messageIDs := range messageIDsBySourceID {
+		if len(messageIDs) == 0 {
+			continue
 		}
-	}
-}
-
-func (d *DedupHandleT) writeToBadger(messageIDs []string) {
-	err := d.badgerDB.Update(func(txn *badger.Txn) error {
-		for _, messageID := range messageIDs {
-			e := badger.NewEntry([]byte(messageID), nil).WithTTL(dedupWindow * time.Second)
-			if err := txn.SetEntry(e); err == badger.ErrTxnTooBig {
-				_ = txn.Commit()
-				txn = d.badgerDB.NewTransaction(true)
-				_ = txn.SetEntry(e)
-			}
+		err := d.store.set(sourceID, messageIDs, WindowForSource(sourceID))
+		if err != nil {
+			panic(err)
 		}
-		return nil
-	})
-	if err != nil {
-		panic(err)
 	}
 }
 
-func (d *DedupHandleT) MarkProcessed(messageIDs []string) {
-	d.writeToBadger(messageIDs)
-}
-
-func (d *DedupHandleT) FindDuplicates(messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int) {
+func (d *dedupHandleT) FindDuplicates(sourceID string, messageIDs []string, allMessageIDsSet map[string]struct{}) (duplicateIndexes []int) {
 	toRemoveMessageIndexesSet := make(map[int]struct{})
 	//Dedup within events batch in a web request
 	messageIDSet := make(map[string]struct{})
@@ -154,24 +112,34 @@ func (d *DedupHandleT) FindDuplicates(messageIDs []string, allMessageIDsSet map[
 		}
 	}
 
-	//Dedup with badgerDB
-	err := d.badgerDB.View(func(txn *badger.Txn) error {
-		for idx, messageID := range messageIDs {
-			_, err := txn.Get([]byte(messageID))
-			if err != badger.ErrKeyNotFound {
-				toRemoveMessageIndexesSet[idx] = struct{}{}
-			}
-		}
-		return nil
-	})
+	//Dedup with the backend store
+	lookupStat := d.stats.NewTaggedStat("dedup.lookup_time", stats.TimerType, stats.Tags{"backend": d.backend})
+	lookupStat.Start()
+	seenMessageIDs, err := d.store.exists(sourceID, messageIDs)
 	if err != nil {
 		panic(err)
 	}
+	lookupStat.End()
+	for idx, messageID := range messageIDs {
+		if _, ok := seenMessageIDs[messageID]; ok {
+			toRemoveMessageIndexesSet[idx] = struct{}{}
+		}
+	}
+
 	toRemoveMessageIndexes := make([]int, 0, len(toRemoveMessageIndexesSet))
 	for k := range toRemoveMessageIndexesSet {
 		toRemoveMessageIndexes = append(toRemoveMessageIndexes, k)
 	}
-
 	sort.Ints(toRemoveMessageIndexes)
+
+	d.reportHitRate(sourceID, len(messageIDs), len(toRemoveMessageIndexes))
 	return toRemoveMessageIndexes
 }
+
+// reportHitRate emits the number of messageIDs looked up and the number found to be duplicates.
+// Hit rate for a source is dedup.duplicates / dedup.lookups
+func (d *dedupHandleT) reportHitRate(sourceID string, lookups, duplicates int) {
+	tags := stats.Tags{"backend": d.backend, "sourceID": sourceID}
+	d.stats.NewTaggedStat("dedup.lookups", stats.CountType, tags).Count(lookups)
+	d.stats.NewTaggedStat("dedup.duplicates", stats.CountType, tags).Count(duplicates)
+}
@@ -0,0 +1,112 @@
+package dedup
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+// memoryStoreT is a store keeping messageIDs by source in memory, recording the ttl they were set with
+type memoryStoreT struct {
+	ttls map[string]map[string]time.Duration
+}
+
+func (m *memoryStoreT) exists(sourceID string, messageIDs []string) (map[string]struct{}, error) {
+	found := make(map[string]struct{})
+	for _, messageID := range messageIDs {
+		if _, ok := m.ttls[sourceID][messageID]; ok {
+			found[messageID] = struct{}{}
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStoreT) set(sourceID string, messageIDs []string, ttl time.Duration) error {
+	if m.ttls[sourceID] == nil {
+		m.ttls[sourceID] = make(map[string]time.Duration)
+	}
+	for _, messageID := range messageIDs {
+		m.ttls[sourceID][messageID] = ttl
+	}
+	return nil
+}
+
+func (m *memoryStoreT) printHistogram() {}
+
+// countingStatsT records the counts of count stats by name and sourceID tag
+type countingStatsT struct {
+	counts map[string]int
+}
+
+type countingStatT struct {
+	stats.RudderStats
+	key    string
+	parent *countingStatsT
+}
+
+func (c *countingStatT) Count(n int) {
+	c.parent.counts[c.key] += n
+}
+
+func (c *countingStatT) Start() {}
+
+func (c *countingStatT) End() {}
+
+func (c *countingStatsT) NewStat(name string, statType string) stats.RudderStats {
+	return c.NewTaggedStat(name, statType, nil)
+}
+
+func (c *countingStatsT) NewTaggedStat(name string, statType string, tags stats.Tags) stats.RudderStats {
+	return &countingStatT{key: name + ":" + tags["sourceID"], parent: c}
+}
+
+func (c *countingStatsT) NewSampledTaggedStat(name string, statType string, tags stats.Tags) stats.RudderStats {
+	return c.NewTaggedStat(name, statType, tags)
+}
+
+var _ = Describe("Dedup", func() {
+	var (
+		store   *memoryStoreT
+		counter *countingStatsT
+		handle  *dedupHandleT
+	)
+
+	BeforeEach(func() {
+		store = &memoryStoreT{ttls: make(map[string]map[string]time.Duration)}
+		counter = &countingStatsT{counts: make(map[string]int)}
+		handle = &dedupHandleT{backend: "memory", store: store, stats: counter}
+	})
+
+	It("should find duplicates within the batch, the batch of jobs and the store", func() {
+		handle.MarkProcessed(map[string][]string{"src-1": {"m4"}})
+		duplicateIndexes := handle.FindDuplicates("src-1", []string{"m1", "m2", "m1", "m3", "m4"}, map[string]struct{}{"m3": {}})
+		Expect(duplicateIndexes).To(Equal([]int{2, 3, 4}))
+	})
+
+	It("should dedup messageIDs per source", func() {
+		handle.MarkProcessed(map[string][]string{"src-1": {"m1"}})
+		Expect(handle.FindDuplicates("src-1", []string{"m1"}, map[string]struct{}{})).To(Equal([]int{0}))
+		Expect(handle.FindDuplicates("src-2", []string{"m1"}, map[string]struct{}{})).To(BeEmpty())
+	})
+
+	It("should mark messageIDs processed for the dedup window of their source", func() {
+		config.SetString("Dedup.src-windowed.dedupWindow", "10m")
+		handle.MarkProcessed(map[string][]string{"src-windowed": {"m1"}, "src-default": {"m2"}})
+		Expect(store.ttls["src-windowed"]["m1"]).To(Equal(10 * time.Minute))
+		Expect(store.ttls["src-default"]["m2"]).To(Equal(dedupWindow))
+	})
+
+	It("should report lookups and duplicates by source for the hit rate", func() {
+		handle.MarkProcessed(map[string][]string{"src-1": {"m1"}})
+		handle.FindDuplicates("src-1", []string{"m1", "m2", "m3"}, map[string]struct{}{})
+		handle.FindDuplicates("src-2", []string{"m1"}, map[string]struct{}{})
+		Expect(counter.counts["dedup.lookups:src-1"]).To(Equal(3))
+		Expect(counter.counts["dedup.duplicates:src-1"]).To(Equal(1))
+		Expect(counter.counts["dedup.lookups:src-2"]).To(Equal(1))
+		Expect(counter.counts["dedup.duplicates:src-2"]).To(Equal(0))
+	})
+})
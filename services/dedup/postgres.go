@@ -0,0 +1,125 @@
+package dedup
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/rruntime"
+)
+
+const dedupTableName = "dedup_message_ids"
+
+var (
+	postgresCleanupInterval time.Duration
+)
+
+func init() {
+	config.RegisterDurationConfigVariable(time.Duration(5), &postgresCleanupInterval, false, time.Minute, "Dedup.postgres.cleanupInterval")
+}
+
+// postgresStoreT keeps processed messageIDs in a postgres table, so that all processor nodes
+// pointing to the same database share dedup state. Rows are keyed on the source and messageID,
+// and expire after the dedup window of their source
+type postgresStoreT struct {
+	dbHandle *sql.DB
+}
+
+func (p *postgresStoreT) setup(clearDB *bool) {
+	var err error
+	p.dbHandle, err = sql.Open("postgres", jobsdb.GetConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	err = p.dbHandle.Ping()
+	if err != nil {
+		panic(err)
+	}
+	err = p.setupTable()
+	if err != nil {
+		panic(err)
+	}
+	if *clearDB {
+		_, err = p.dbHandle.Exec(fmt.Sprintf(`TRUNCATE TABLE %s`, dedupTableName))
+		if err != nil {
+			panic(err)
+		}
+	}
+	rruntime.Go(func() {
+		p.deleteExpiredLoop()
+	})
+}
+
+func (p *postgresStoreT) setupTable() (err error) {
+	pkgLogger.Infof("Dedup: Creating %s table", dedupTableName)
+	sqlStmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+									source_id TEXT NOT NULL,
+									message_id TEXT NOT NULL,
+									expires_at TIMESTAMP NOT NULL,
+									PRIMARY KEY (source_id, message_id));`, dedupTableName)
+	_, err = p.dbHandle.Exec(sqlStmt)
+	if err != nil {
+		return
+	}
+
+	sqlStmt = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_expires_at_idx ON %[1]s (expires_at)`, dedupTableName)
+	_, err = p.dbHandle.Exec(sqlStmt)
+	return
+}
+
+// deleteExpiredLoop removes rows whose dedup window has passed. Expired rows are already
+// ignored by exists, this only keeps the table from growing unbounded
+func (p *postgresStoreT) deleteExpiredLoop() {
+	for {
+		time.Sleep(postgresCleanupInterval)
+		sqlStmt := fmt.Sprintf(`DELETE FROM %s WHERE expires_at < NOW()`, dedupTableName)
+		result, err := p.dbHandle.Exec(sqlStmt)
+		if err != nil {
+			pkgLogger.Errorf("Dedup: Failed to delete expired messageIDs: %v", err)
+			continue
+		}
+		rowsAffected, _ := result.RowsAffected()
+		pkgLogger.Debugf("Dedup: Deleted %d expired messageIDs", rowsAffected)
+	}
+}
+
+func (p *postgresStoreT) printHistogram() {
+	var count int64
+	sqlStmt := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE expires_at > NOW()`, dedupTableName)
+	err := p.dbHandle.QueryRow(sqlStmt).Scan(&count)
+	if err != nil {
+		pkgLogger.Errorf("Dedup: Failed to count messageIDs: %v", err)
+		return
+	}
+	pkgLogger.Infof("Dedup: %d messageIDs within dedup window in %s", count, dedupTableName)
+}
+
+func (p *postgresStoreT) set(sourceID string, messageIDs []string, ttl time.Duration) error {
+	sqlStmt := fmt.Sprintf(`INSERT INTO %s (source_id, message_id, expires_at)
+								SELECT DISTINCT $1, UNNEST($2::text[]), NOW() + $3 * INTERVAL '1 second'
+								ON CONFLICT (source_id, message_id) DO UPDATE SET expires_at = EXCLUDED.expires_at`, dedupTableName)
+	_, err := p.dbHandle.Exec(sqlStmt, sourceID, pq.Array(messageIDs), ttl.Seconds())
+	return err
+}
+
+func (p *postgresStoreT) exists(sourceID string, messageIDs []string) (map[string]struct{}, error) {
+	found := make(map[string]struct{})
+	sqlStmt := fmt.Sprintf(`SELECT message_id FROM %s WHERE source_id = $1 AND message_id = ANY($2) AND expires_at > NOW()`, dedupTableName)
+	rows, err := p.dbHandle.Query(sqlStmt, sourceID, pq.Array(messageIDs))
+	if err != nil {
+		return found, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var messageID string
+		err = rows.Scan(&messageID)
+		if err != nil {
+			return found, err
+		}
+		found[messageID] = struct{}{}
+	}
+	return found, rows.Err()
+}
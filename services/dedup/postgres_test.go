@@ -0,0 +1,50 @@
+package dedup
+
+import (
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("postgresStoreT", func() {
+	var (
+		mock  sqlmock.Sqlmock
+		store *postgresStoreT
+	)
+
+	BeforeEach(func() {
+		db, sqlMock, err := sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		mock = sqlMock
+		store = &postgresStoreT{dbHandle: db}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	It("should key the table on the source and messageID", func() {
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS dedup_message_ids \(.*PRIMARY KEY \(source_id, message_id\)\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`CREATE INDEX IF NOT EXISTS dedup_message_ids_expires_at_idx`).WillReturnResult(sqlmock.NewResult(0, 0))
+		Expect(store.setupTable()).To(Succeed())
+	})
+
+	It("should set messageIDs of a source for the ttl", func() {
+		mock.ExpectExec(`INSERT INTO dedup_message_ids \(source_id, message_id, expires_at\).*ON CONFLICT \(source_id, message_id\) DO UPDATE`).
+			WithArgs("src-1", pq.Array([]string{"m1", "m2"}), float64(600)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		Expect(store.set("src-1", []string{"m1", "m2"}, 10*time.Minute)).To(Succeed())
+	})
+
+	It("should find unexpired messageIDs of a source", func() {
+		mock.ExpectQuery(`SELECT message_id FROM dedup_message_ids WHERE source_id = \$1 AND message_id = ANY\(\$2\) AND expires_at > NOW\(\)`).
+			WithArgs("src-1", pq.Array([]string{"m1", "m2"})).
+			WillReturnRows(sqlmock.NewRows([]string{"message_id"}).AddRow("m2"))
+		found, err := store.exists("src-1", []string{"m1", "m2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(Equal(map[string]struct{}{"m2": {}}))
+	})
+})
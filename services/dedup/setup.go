@@ -1,15 +1,36 @@
 package dedup
 
+import (
+	"fmt"
+
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
 var (
 	dedupManager DedupI
 )
 
-// GetInstance returns an instance of DedupI
+// GetInstance returns an instance of DedupI backed by the store configured through Dedup.backend
 func GetInstance(clearDB *bool) DedupI {
 	pkgLogger.Info("[[ Dedup ]] Setting up Dedup Manager")
 	if dedupManager == nil {
-		handler := &DedupHandleT{}
-		handler.setup(clearDB)
+		handler := &dedupHandleT{
+			backend: backend,
+			stats:   stats.DefaultStats,
+		}
+		switch backend {
+		case BadgerBackend:
+			store := &badgerStoreT{}
+			store.setup(clearDB)
+			handler.store = store
+		case PostgresBackend:
+			store := &postgresStoreT{}
+			store.setup(clearDB)
+			handler.store = store
+		default:
+			panic(fmt.Errorf("unknown dedup backend: %s", backend))
+		}
+		pkgLogger.Infof("[[ Dedup ]] Using %s backend", backend)
 		dedupManager = handler
 	}
 	return dedupManager
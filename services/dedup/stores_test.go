@@ -0,0 +1,60 @@
+package dedup
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// the stores must dedup the same events, so every store runs the same scenario
+var _ = Describe("stores", func() {
+	scenario := func(store storeT) {
+		Expect(store.set("src-1", []string{"m1", "m2"}, 10*time.Minute)).To(Succeed())
+		Expect(store.set("src-2", []string{"m1"}, time.Minute)).To(Succeed())
+
+		found, err := store.exists("src-1", []string{"m1", "m2", "m3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(Equal(map[string]struct{}{"m1": {}, "m2": {}}))
+		found, err = store.exists("src-2", []string{"m1", "m2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(Equal(map[string]struct{}{"m1": {}}))
+		found, err = store.exists("src-3", []string{"m1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeEmpty())
+	}
+
+	It("should dedup messageIDs per source in badger", func() {
+		tmpDir, err := ioutil.TempDir("", "dedup")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(tmpDir)
+		os.Setenv("RUDDER_TMPDIR", tmpDir)
+		defer os.Unsetenv("RUDDER_TMPDIR")
+
+		store := &badgerStoreT{}
+		clearDB := true
+		store.setup(&clearDB)
+		defer store.badgerDB.Close()
+		scenario(store)
+	})
+
+	It("should dedup messageIDs per source in postgres", func() {
+		db, mock, err := sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		defer db.Close()
+		const insert = `INSERT INTO dedup_message_ids`
+		const query = `SELECT message_id FROM dedup_message_ids WHERE source_id = \$1 AND message_id = ANY\(\$2\)`
+		mock.ExpectExec(insert).WithArgs("src-1", pq.Array([]string{"m1", "m2"}), float64(600)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(insert).WithArgs("src-2", pq.Array([]string{"m1"}), float64(60)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(query).WithArgs("src-1", pq.Array([]string{"m1", "m2", "m3"})).WillReturnRows(sqlmock.NewRows([]string{"message_id"}).AddRow("m1").AddRow("m2"))
+		mock.ExpectQuery(query).WithArgs("src-2", pq.Array([]string{"m1", "m2"})).WillReturnRows(sqlmock.NewRows([]string{"message_id"}).AddRow("m1"))
+		mock.ExpectQuery(query).WithArgs("src-3", pq.Array([]string{"m1"})).WillReturnRows(sqlmock.NewRows([]string{"message_id"}))
+
+		scenario(&postgresStoreT{dbHandle: db})
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+})
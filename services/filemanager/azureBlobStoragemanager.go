@@ -181,3 +181,27 @@ type AzureBlobStorageConfig struct {
 func (manager *AzureBlobStorageManager) DeleteObjects(locations []string) (err error) {
 	return
 }
+
+func (manager *AzureBlobStorageManager) ListFilesWithPrefix(prefix string) ([]*FileObject, error) {
+	blobObjects := make([]*FileObject, 0)
+
+	containerURL, err := manager.getContainerURL()
+	if err != nil {
+		return blobObjects, err
+	}
+
+	ctx := context.Background()
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		response, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix})
+		if err != nil {
+			return blobObjects, err
+		}
+		marker = response.NextMarker
+
+		for _, blobInfo := range response.Segment.BlobItems {
+			blobObjects = append(blobObjects, &FileObject{blobInfo.Name, blobInfo.Properties.LastModified})
+		}
+	}
+
+	return blobObjects, nil
+}
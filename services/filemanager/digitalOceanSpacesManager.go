@@ -6,7 +6,6 @@ import (
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -104,13 +103,8 @@ func (manager *DOSpacesManager) GetObjectNameFromLocation(location string) (stri
 	return strings.TrimPrefix(path, fmt.Sprintf(`%s/`, manager.Config.Bucket)), nil
 }
 
-type SpacesObject struct {
-	Key              string
-	LastModifiedTime time.Time
-}
-
-func (manager *DOSpacesManager) ListFilesWithPrefix(prefix string) ([]*SpacesObject, error) {
-	spacesObjects := make([]*SpacesObject, 0)
+func (manager *DOSpacesManager) ListFilesWithPrefix(prefix string) ([]*FileObject, error) {
+	spacesObjects := make([]*FileObject, 0)
 
 	getRegionSession := session.Must(session.NewSession())
 	region, err := SpacesManager.GetBucketRegion(aws.BackgroundContext(), getRegionSession, manager.Config.Bucket, "us-east-1")
@@ -126,21 +120,19 @@ func (manager *DOSpacesManager) ListFilesWithPrefix(prefix string) ([]*SpacesObj
 	// Create S3 service client
 	svc := s3.New(sess)
 
-	// Get the list of items
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{
+	// Get the list of items, page by page
+	err = svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
 		Bucket: aws.String(manager.Config.Bucket),
 		Prefix: aws.String(prefix),
 		// Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			spacesObjects = append(spacesObjects, &FileObject{*item.Key, *item.LastModified})
+		}
+		return true
 	})
-	if err != nil {
-		return spacesObjects, err
-	}
-
-	for _, item := range resp.Contents {
-		spacesObjects = append(spacesObjects, &SpacesObject{*item.Key, *item.LastModified})
-	}
 
-	return spacesObjects, nil
+	return spacesObjects, err
 }
 
 func (manager *DOSpacesManager) DeleteObjects(locations []string) (err error) {
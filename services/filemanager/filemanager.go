@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/rudderlabs/rudder-server/config"
 )
@@ -21,6 +22,12 @@ type UploadOutput struct {
 	ObjectName string
 }
 
+// FileObject is an object in the bucket of a FileManager
+type FileObject struct {
+	Key              string
+	LastModifiedTime time.Time
+}
+
 type FileManagerFactory interface {
 	New(settings *SettingsT) (FileManager, error)
 }
@@ -32,6 +39,7 @@ type FileManager interface {
 	GetObjectNameFromLocation(string) (string, error)
 	GetDownloadKeyFromFileLocation(location string) string
 	DeleteObjects(locations []string) error
+	ListFilesWithPrefix(prefix string) ([]*FileObject, error)
 }
 
 // SettingsT sets configuration for FileManager
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -132,3 +133,26 @@ type GCSConfig struct {
 func (manager *GCSManager) DeleteObjects(locations []string) (err error) {
 	return
 }
+
+func (manager *GCSManager) ListFilesWithPrefix(prefix string) ([]*FileObject, error) {
+	gcsObjects := make([]*FileObject, 0)
+
+	client, err := manager.getClient()
+	if err != nil {
+		return gcsObjects, err
+	}
+
+	it := client.Bucket(manager.Config.Bucket).Objects(context.Background(), &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return gcsObjects, err
+		}
+		gcsObjects = append(gcsObjects, &FileObject{attrs.Name, attrs.Updated})
+	}
+
+	return gcsObjects, nil
+}
@@ -132,3 +132,23 @@ type MinioConfig struct {
 	SecretAccessKey string
 	UseSSL          bool
 }
+
+func (manager *MinioManager) ListFilesWithPrefix(prefix string) ([]*FileObject, error) {
+	minioObjects := make([]*FileObject, 0)
+
+	minioClient, err := minio.New(manager.Config.EndPoint, manager.Config.AccessKeyID, manager.Config.SecretAccessKey, manager.Config.UseSSL)
+	if err != nil {
+		return minioObjects, err
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	for object := range minioClient.ListObjectsV2(manager.Config.Bucket, prefix, true, doneCh) {
+		if object.Err != nil {
+			return minioObjects, object.Err
+		}
+		minioObjects = append(minioObjects, &FileObject{object.Key, object.LastModified})
+	}
+
+	return minioObjects, nil
+}
@@ -6,7 +6,6 @@ import (
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -191,13 +190,8 @@ func (manager *S3Manager) GetObjectNameFromLocation(location string) (string, er
 	return strings.TrimPrefix(path, fmt.Sprintf(`%s/`, manager.Config.Bucket)), nil
 }
 
-type S3Object struct {
-	Key              string
-	LastModifiedTime time.Time
-}
-
-func (manager *S3Manager) ListFilesWithPrefix(prefix string) ([]*S3Object, error) {
-	s3Objects := make([]*S3Object, 0)
+func (manager *S3Manager) ListFilesWithPrefix(prefix string) ([]*FileObject, error) {
+	s3Objects := make([]*FileObject, 0)
 
 	getRegionSession := session.Must(session.NewSession())
 	region, err := awsS3Manager.GetBucketRegion(aws.BackgroundContext(), getRegionSession, manager.Config.Bucket, manager.Config.RegionHint)
@@ -224,21 +218,19 @@ func (manager *S3Manager) ListFilesWithPrefix(prefix string) ([]*S3Object, error
 	// Create S3 service client
 	svc := s3.New(sess)
 
-	// Get the list of items
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{
+	// Get the list of items, page by page
+	err = svc.ListObjectsV2Pages(&s3.ListObjectsV2Input{
 		Bucket: aws.String(manager.Config.Bucket),
 		Prefix: aws.String(prefix),
 		// Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			s3Objects = append(s3Objects, &FileObject{*item.Key, *item.LastModified})
+		}
+		return true
 	})
-	if err != nil {
-		return s3Objects, err
-	}
-
-	for _, item := range resp.Contents {
-		s3Objects = append(s3Objects, &S3Object{*item.Key, *item.LastModified})
-	}
 
-	return s3Objects, nil
+	return s3Objects, err
 }
 
 type S3Manager struct {
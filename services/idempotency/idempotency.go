@@ -0,0 +1,190 @@
+package idempotency
+
+//go:generate mockgen -destination=../../mocks/services/idempotency/mock_idempotency.go -package mock_idempotency github.com/rudderlabs/rudder-server/services/idempotency StoreI
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+//StatusT is the state of an idempotency key when a request tries to reserve it
+type StatusT int
+
+const (
+	//Reserved - the key was free and is now held by the request until it is committed or released
+	Reserved StatusT = iota
+	//InProgress - another request with the key is being processed
+	InProgress
+	//Completed - a request with the key has completed within the idempotency window
+	Completed
+)
+
+const idempotencyKeysTable = "gw_idempotency_keys"
+
+//ErrReservationLost is returned when a request commits or releases a key it reserved,
+//after its reservation timed out and the key was reserved by another request or deleted
+var ErrReservationLost = errors.New("idempotency key is no longer reserved by the request")
+
+var (
+	window          time.Duration
+	lockTimeout     time.Duration
+	cleanupInterval time.Duration
+	pkgLogger       logger.LoggerI
+)
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("idempotency")
+}
+
+func loadConfig() {
+	// Time for which the response of a request is returned for requests replayed with its idempotency key
+	config.RegisterDurationConfigVariable(time.Duration(24), &window, true, time.Hour, "Gateway.idempotency.window")
+	// Time after which a key reserved by a request that never completed, e.g. due to a crash, can be reserved again
+	config.RegisterDurationConfigVariable(time.Duration(60), &lockTimeout, true, time.Second, "Gateway.idempotency.lockTimeout")
+	config.RegisterDurationConfigVariable(time.Duration(5), &cleanupInterval, false, time.Minute, "Gateway.idempotency.cleanupInterval")
+}
+
+//StoreI keeps idempotency keys of gateway requests per writeKey.
+//Reserve returns a reservationID for Reserved keys, which the request commits or releases the key with
+type StoreI interface {
+	Reserve(writeKey, key string) (status StatusT, reservationID string, response string, err error)
+	Commit(writeKey, key, reservationID, response string) error
+	Release(writeKey, key, reservationID string) error
+}
+
+//PostgresStoreT keeps idempotency keys in a table of the gateway database,
+//so that they survive restarts and are shared by all gateways pointing to it
+type PostgresStoreT struct {
+	dbHandle *sql.DB
+}
+
+//NewPostgresStore connects to the gateway database and creates the idempotency keys table if it doesn't exist
+func NewPostgresStore() (*PostgresStoreT, error) {
+	dbHandle, err := sql.Open("postgres", jobsdb.GetConnectionString())
+	if err != nil {
+		return nil, err
+	}
+	err = dbHandle.Ping()
+	if err != nil {
+		return nil, err
+	}
+	store := &PostgresStoreT{dbHandle: dbHandle}
+	err = store.setupTable()
+	if err != nil {
+		return nil, err
+	}
+	rruntime.Go(func() {
+		store.deleteExpiredLoop()
+	})
+	return store, nil
+}
+
+func (store *PostgresStoreT) setupTable() (err error) {
+	sqlStmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+									write_key TEXT NOT NULL,
+									key TEXT NOT NULL,
+									reservation_id TEXT NOT NULL,
+									completed BOOLEAN NOT NULL DEFAULT FALSE,
+									response TEXT,
+									expires_at TIMESTAMP NOT NULL,
+									PRIMARY KEY (write_key, key));`, idempotencyKeysTable)
+	_, err = store.dbHandle.Exec(sqlStmt)
+	if err != nil {
+		return
+	}
+
+	sqlStmt = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_expires_at_idx ON %[1]s (expires_at)`, idempotencyKeysTable)
+	_, err = store.dbHandle.Exec(sqlStmt)
+	return
+}
+
+func (store *PostgresStoreT) deleteExpiredLoop() {
+	for {
+		time.Sleep(cleanupInterval)
+		sqlStmt := fmt.Sprintf(`DELETE FROM %s WHERE expires_at < NOW()`, idempotencyKeysTable)
+		_, err := store.dbHandle.Exec(sqlStmt)
+		if err != nil {
+			pkgLogger.Errorf("Failed to delete expired idempotency keys: %v", err)
+		}
+	}
+}
+
+//Reserve holds the key for the request if it is free or has expired.
+//Otherwise returns whether the request holding it is still in progress or its response if it has completed
+func (store *PostgresStoreT) Reserve(writeKey, key string) (status StatusT, reservationID string, response string, err error) {
+	reservationID = uuid.NewV4().String()
+	sqlStmt := fmt.Sprintf(`INSERT INTO %[1]s (write_key, key, reservation_id, completed, response, expires_at)
+								VALUES ($1, $2, $3, FALSE, NULL, NOW() + $4 * INTERVAL '1 second')
+								ON CONFLICT (write_key, key) DO UPDATE SET reservation_id = EXCLUDED.reservation_id, completed = FALSE, response = NULL, expires_at = EXCLUDED.expires_at
+								WHERE %[1]s.expires_at < NOW()`, idempotencyKeysTable)
+	result, err := store.dbHandle.Exec(sqlStmt, writeKey, key, reservationID, lockTimeout.Seconds())
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected > 0 {
+		return Reserved, reservationID, "", nil
+	}
+	var completed bool
+	var storedResponse sql.NullString
+	sqlStmt = fmt.Sprintf(`SELECT completed, response FROM %s WHERE write_key = $1 AND key = $2`, idempotencyKeysTable)
+	err = store.dbHandle.QueryRow(sqlStmt, writeKey, key).Scan(&completed, &storedResponse)
+	if err == sql.ErrNoRows {
+		// released or deleted by cleanup in between
+		return store.Reserve(writeKey, key)
+	}
+	if err != nil {
+		return
+	}
+	if !completed {
+		return InProgress, "", "", nil
+	}
+	return Completed, "", storedResponse.String, nil
+}
+
+//Commit stores the response of the request holding the key, to be returned for the key till the idempotency window passes.
+//Returns ErrReservationLost if the reservation of the request timed out and was taken over in the meantime
+func (store *PostgresStoreT) Commit(writeKey, key, reservationID, response string) error {
+	sqlStmt := fmt.Sprintf(`UPDATE %s SET completed = TRUE, response = $4, expires_at = NOW() + $5 * INTERVAL '1 second'
+								WHERE write_key = $1 AND key = $2 AND reservation_id = $3 AND completed = FALSE`, idempotencyKeysTable)
+	result, err := store.dbHandle.Exec(sqlStmt, writeKey, key, reservationID, response, window.Seconds())
+	if err != nil {
+		return err
+	}
+	return reservationHeld(result)
+}
+
+//Release frees the key so that the request can be retried with it.
+//Returns ErrReservationLost if the reservation of the request timed out and was taken over in the meantime
+func (store *PostgresStoreT) Release(writeKey, key, reservationID string) error {
+	sqlStmt := fmt.Sprintf(`DELETE FROM %s WHERE write_key = $1 AND key = $2 AND reservation_id = $3 AND completed = FALSE`, idempotencyKeysTable)
+	result, err := store.dbHandle.Exec(sqlStmt, writeKey, key, reservationID)
+	if err != nil {
+		return err
+	}
+	return reservationHeld(result)
+}
+
+func reservationHeld(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrReservationLost
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// reservationIDArg matches any reservationID and keeps the last one matched
+type reservationIDArg struct {
+	value string
+}
+
+func (a *reservationIDArg) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	if ok {
+		a.value = value
+	}
+	return ok && value != ""
+}
+
+var _ = Describe("PostgresStoreT", func() {
+	var (
+		mock  sqlmock.Sqlmock
+		store *PostgresStoreT
+	)
+
+	const (
+		writeKey       = "some-write-key"
+		key            = "some-idempotency-key"
+		reserveQuery   = `INSERT INTO gw_idempotency_keys \(write_key, key, reservation_id, completed, response, expires_at\).*ON CONFLICT \(write_key, key\) DO UPDATE SET reservation_id = EXCLUDED.reservation_id.*WHERE gw_idempotency_keys.expires_at < NOW\(\)`
+		reservedQuery  = `SELECT completed, response FROM gw_idempotency_keys WHERE write_key = \$1 AND key = \$2`
+		commitQuery    = `UPDATE gw_idempotency_keys SET completed = TRUE.*WHERE write_key = \$1 AND key = \$2 AND reservation_id = \$3 AND completed = FALSE`
+		releaseQuery   = `DELETE FROM gw_idempotency_keys WHERE write_key = \$1 AND key = \$2 AND reservation_id = \$3 AND completed = FALSE`
+		reservationID  = "some-reservation-id"
+		storedResponse = "OK"
+	)
+
+	BeforeEach(func() {
+		db, sqlMock, err := sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+		mock = sqlMock
+		store = &PostgresStoreT{dbHandle: db}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	Context("Reserve", func() {
+		It("should reserve free and expired keys for lockTimeout with a new reservationID", func() {
+			reservationIDs := &reservationIDArg{}
+			mock.ExpectExec(reserveQuery).WithArgs(writeKey, key, reservationIDs, lockTimeout.Seconds()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			status, gotReservationID, response, err := store.Reserve(writeKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(Reserved))
+			Expect(gotReservationID).To(Equal(reservationIDs.value))
+			Expect(response).To(BeEmpty())
+		})
+
+		It("should report keys reserved by other requests as in progress", func() {
+			mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(reservedQuery).WithArgs(writeKey, key).WillReturnRows(sqlmock.NewRows([]string{"completed", "response"}).AddRow(false, nil))
+
+			status, gotReservationID, _, err := store.Reserve(writeKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(InProgress))
+			Expect(gotReservationID).To(BeEmpty())
+		})
+
+		It("should return the response of completed keys", func() {
+			mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(reservedQuery).WithArgs(writeKey, key).WillReturnRows(sqlmock.NewRows([]string{"completed", "response"}).AddRow(true, storedResponse))
+
+			status, _, response, err := store.Reserve(writeKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(Completed))
+			Expect(response).To(Equal(storedResponse))
+		})
+
+		It("should retry reserving keys deleted after the conflict", func() {
+			mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(reservedQuery).WithArgs(writeKey, key).WillReturnError(sql.ErrNoRows)
+			mock.ExpectExec(reserveQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			status, _, _, err := store.Reserve(writeKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(Reserved))
+		})
+	})
+
+	Context("Commit", func() {
+		It("should store the response for the idempotency window", func() {
+			mock.ExpectExec(commitQuery).WithArgs(writeKey, key, reservationID, storedResponse, window.Seconds()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			Expect(store.Commit(writeKey, key, reservationID, storedResponse)).To(Succeed())
+		})
+
+		It("should return ErrReservationLost if the key was reserved again after lockTimeout", func() {
+			mock.ExpectExec(commitQuery).WithArgs(writeKey, key, reservationID, storedResponse, window.Seconds()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			Expect(store.Commit(writeKey, key, reservationID, storedResponse)).To(Equal(ErrReservationLost))
+		})
+	})
+
+	Context("Release", func() {
+		It("should delete the key reserved by the request", func() {
+			mock.ExpectExec(releaseQuery).WithArgs(writeKey, key, reservationID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			Expect(store.Release(writeKey, key, reservationID)).To(Succeed())
+		})
+
+		It("should return ErrReservationLost if the key was reserved again after lockTimeout", func() {
+			mock.ExpectExec(releaseQuery).WithArgs(writeKey, key, reservationID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			Expect(store.Release(writeKey, key, reservationID)).To(Equal(ErrReservationLost))
+		})
+	})
+})
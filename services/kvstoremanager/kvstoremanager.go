@@ -2,6 +2,7 @@ package kvstoremanager
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/tidwall/gjson"
 )
@@ -10,6 +11,8 @@ type KVStoreManager interface {
 	Connect()
 	Close() error
 	HMSet(key string, fields map[string]interface{}) error
+	IncrBy(key string, value int64, expiration time.Duration) (int64, error)
+	MGetInt64(keys ...string) ([]int64, error)
 	StatusCode(err error) int
 }
 
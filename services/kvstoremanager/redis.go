@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-redis/redis"
 	"github.com/rudderlabs/rudder-server/utils/types"
@@ -93,6 +94,45 @@ func (m *redisManagerT) HMSet(key string, fields map[string]interface{}) (err er
 	return err
 }
 
+// IncrBy increments key by value and resets its expiry, both in a single transaction. Returns the value of key after the increment
+func (m *redisManagerT) IncrBy(key string, value int64, expiration time.Duration) (int64, error) {
+	var pipe redis.Pipeliner
+	if m.clusterMode {
+		pipe = m.clusterClient.TxPipeline()
+	} else {
+		pipe = m.client.TxPipeline()
+	}
+	incr := pipe.IncrBy(key, value)
+	pipe.Expire(key, expiration)
+	_, err := pipe.Exec()
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// MGetInt64 returns integer values of keys. Missing keys are returned as 0.
+// In cluster mode all keys need to hash to the same slot
+func (m *redisManagerT) MGetInt64(keys ...string) ([]int64, error) {
+	var values []interface{}
+	var err error
+	if m.clusterMode {
+		values, err = m.clusterClient.MGet(keys...).Result()
+	} else {
+		values, err = m.client.MGet(keys...).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int64, len(values))
+	for i, value := range values {
+		if str, ok := value.(string); ok {
+			result[i], _ = strconv.ParseInt(str, 10, 64)
+		}
+	}
+	return result, nil
+}
+
 func (m *redisManagerT) StatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
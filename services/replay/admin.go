@@ -0,0 +1,92 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+//ReplayRpcHandler exposes replays over the admin rpc interface. Arguments and results are json
+type ReplayRpcHandler struct {
+	replay *HandleT
+}
+
+func marshalResult(value interface{}, result *string) error {
+	response, err := json.MarshalIndent(value, "", " ")
+	if err != nil {
+		*result = ""
+		return err
+	}
+	*result = string(response)
+	return nil
+}
+
+//Start starts the replay described by the RequestT in arg
+func (h *ReplayRpcHandler) Start(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var request RequestT
+	if err = json.Unmarshal([]byte(arg), &request); err != nil {
+		return err
+	}
+	replay, err := h.replay.Start(request)
+	if err != nil {
+		return err
+	}
+	return marshalResult(replay, result)
+}
+
+//Pause pauses the replay with the id in arg
+func (h *ReplayRpcHandler) Pause(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	if err = h.replay.Pause(arg); err != nil {
+		return err
+	}
+	*result = fmt.Sprintf("Replay %s paused", arg)
+	return nil
+}
+
+//Resume resumes the paused or failed replay with the id in arg
+func (h *ReplayRpcHandler) Resume(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	if err = h.replay.Resume(arg); err != nil {
+		return err
+	}
+	*result = fmt.Sprintf("Replay %s resumed", arg)
+	return nil
+}
+
+//GetReplays lists the latest replays with their progress, arg being the number of replays to list
+func (h *ReplayRpcHandler) GetReplays(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var limit int
+	if arg != "" {
+		if limit, err = strconv.Atoi(arg); err != nil {
+			return err
+		}
+	}
+	replays, err := h.replay.GetReplays(limit)
+	if err != nil {
+		return err
+	}
+	return marshalResult(replays, result)
+}
@@ -0,0 +1,373 @@
+package replay
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/rudderlabs/rudder-server/admin"
+	"github.com/rudderlabs/rudder-server/app"
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/stats"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+const (
+	REPLAYS_TABLE = "gw_replays"
+	//REPLAY_ID_PARAMETER and DESTINATION_IDS_PARAMETER are set on the parameters of replayed gateway jobs
+	REPLAY_ID_PARAMETER       = "replay_id"
+	DESTINATION_IDS_PARAMETER = "replay_destination_ids"
+)
+
+const (
+	RUNNING   = "running"
+	PAUSED    = "paused"
+	COMPLETED = "completed"
+	FAILED    = "failed"
+)
+
+var (
+	maxJobsPerSecond int
+	defaultListLimit int
+	loopSleep        time.Duration
+	enabled          bool
+	pkgLogger        logger.LoggerI
+	errNotRunning    = errors.New("replay is not running")
+)
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("replay")
+	app.RegisterReplayFeature(newReplayFeature)
+}
+
+//newReplayFeature returns no feature unless Replay.enabled is set, so that servers don't set up the replay jobsdb
+func newReplayFeature(app.Interface) app.ReplayFeature {
+	if !enabled {
+		return nil
+	}
+	return &HandleT{}
+}
+
+func loadConfig() {
+	config.RegisterBoolConfigVariable(false, &enabled, false, "Replay.enabled")
+	config.RegisterIntConfigVariable(100, &maxJobsPerSecond, true, 1, "Replay.maxJobsPerSecond")
+	config.RegisterIntConfigVariable(100, &defaultListLimit, true, 1, "Replay.defaultListLimit")
+	config.RegisterDurationConfigVariable(10, &loopSleep, true, time.Second, "Replay.loopSleep")
+}
+
+//RequestT replays the gateway jobs of a source received between From and To to the destinations of the source with DestinationIDs.
+//To defaults to the time of the request
+type RequestT struct {
+	SourceID       string    `json:"sourceId"`
+	DestinationIDs []string  `json:"destinationIds"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+}
+
+//ReplayT is a replay along with its progress. BackupOffset rows of the backup at BackupIndex are replayed already
+type ReplayT struct {
+	ID             string    `json:"id"`
+	SourceID       string    `json:"sourceId"`
+	DestinationIDs []string  `json:"destinationIds"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	BackupKeys     []string  `json:"backupKeys"`
+	BackupIndex    int       `json:"backupIndex"`
+	BackupOffset   int       `json:"backupOffset"`
+	ReplayedJobs   int       `json:"replayedJobs"`
+	Status         string    `json:"status"`
+	Error          string    `json:"error"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+//gatewayDBT is the gateway jobsdb, which backups are replayed into
+type gatewayDBT interface {
+	ListBackups() ([]jobsdb.BackupT, error)
+	ReadBackup(key string, request jobsdb.RestoreRequestT, offset int, onJobs func(jobs []*jobsdb.JobT, offset int) error) error
+	Store(jobList []*jobsdb.JobT) error
+}
+
+//HandleT replays gateway backups through the processor, one replay at a time
+type HandleT struct {
+	gatewayDB gatewayDBT
+	dbHandle  *sql.DB
+	logger    logger.LoggerI
+}
+
+//Setup starts replaying the running replays and registers the admin handler. The feature is only registered when Replay.enabled is set.
+//Replayed jobs are stored into the gateway jobsdb to be processed again, so replayDB and routerDB aren't used
+func (replay *HandleT) Setup(replayDB *jobsdb.HandleT, gatewayDB *jobsdb.HandleT, routerDB *jobsdb.HandleT) {
+	replay.logger = pkgLogger
+	replay.gatewayDB = gatewayDB
+	var err error
+	replay.dbHandle, err = sql.Open("postgres", jobsdb.GetConnectionString())
+	if err != nil {
+		panic(err)
+	}
+	admin.RegisterAdminHandler("Replay", &ReplayRpcHandler{replay: replay})
+	rruntime.Go(func() {
+		replay.replayLoop()
+	})
+}
+
+//TargetDestinationIDs returns the destinations a replayed gateway job is replayed to, nil for jobs which aren't replayed
+func TargetDestinationIDs(parameters []byte) map[string]bool {
+	result := gjson.GetBytes(parameters, DESTINATION_IDS_PARAMETER)
+	if !result.Exists() {
+		return nil
+	}
+	destinationIDs := make(map[string]bool)
+	for _, destinationID := range result.Array() {
+		destinationIDs[destinationID.String()] = true
+	}
+	return destinationIDs
+}
+
+//selectBackupKeys returns the keys of the gateway dumps with jobs received between from and to
+func selectBackupKeys(backups []jobsdb.BackupT, from, to time.Time) []string {
+	keys := make([]string, 0)
+	for _, backup := range backups {
+		if backup.FailedOnly || backup.StartTime.After(to) || backup.EndTime.Before(from) {
+			continue
+		}
+		keys = append(keys, backup.Key)
+	}
+	return keys
+}
+
+//tagJobs marks jobs as replayed to the destinations of r
+func tagJobs(jobs []*jobsdb.JobT, r *ReplayT) error {
+	for _, job := range jobs {
+		parameters, err := sjson.SetBytes(job.Parameters, REPLAY_ID_PARAMETER, r.ID)
+		if err != nil {
+			return err
+		}
+		parameters, err = sjson.SetBytes(parameters, DESTINATION_IDS_PARAMETER, r.DestinationIDs)
+		if err != nil {
+			return err
+		}
+		job.Parameters = parameters
+	}
+	return nil
+}
+
+//Start records a replay of the gateway backups matching request, which the replay loop picks up
+func (replay *HandleT) Start(request RequestT) (ReplayT, error) {
+	if request.SourceID == "" || len(request.DestinationIDs) == 0 {
+		return ReplayT{}, errors.New("a source and at least one destination are required")
+	}
+	if request.To.IsZero() {
+		request.To = time.Now()
+	}
+	if !request.From.Before(request.To) {
+		return ReplayT{}, fmt.Errorf("from %v is not before to %v", request.From, request.To)
+	}
+
+	backups, err := replay.gatewayDB.ListBackups()
+	if err != nil {
+		return ReplayT{}, err
+	}
+	keys := selectBackupKeys(backups, request.From, request.To)
+	if len(keys) == 0 {
+		return ReplayT{}, fmt.Errorf("no gateway backups between %v and %v", request.From, request.To)
+	}
+
+	now := time.Now()
+	r := ReplayT{
+		ID:             uuid.NewV4().String(),
+		SourceID:       request.SourceID,
+		DestinationIDs: request.DestinationIDs,
+		From:           request.From,
+		To:             request.To,
+		BackupKeys:     keys,
+		Status:         RUNNING,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	sqlStatement := fmt.Sprintf(`INSERT INTO %s (id, source_id, destination_ids, start_time, end_time, backup_keys, status, created_at, updated_at)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, REPLAYS_TABLE)
+	_, err = replay.dbHandle.Exec(sqlStatement, r.ID, r.SourceID, pq.Array(r.DestinationIDs), r.From, r.To, pq.Array(r.BackupKeys), r.Status, r.CreatedAt, r.UpdatedAt)
+	if err != nil {
+		return ReplayT{}, err
+	}
+	replay.logger.Infof("Replay: Started replay %s of source %s to %v from %d backups", r.ID, r.SourceID, r.DestinationIDs, len(keys))
+	return r, nil
+}
+
+//setStatus moves the replay with id from one of the fromStatuses to status
+func (replay *HandleT) setStatus(id, status string, fromStatuses ...string) error {
+	sqlStatement := fmt.Sprintf(`UPDATE %s SET status = $1, error = '', updated_at = NOW() WHERE id = $2 AND status = ANY($3)`, REPLAYS_TABLE)
+	result, err := replay.dbHandle.Exec(sqlStatement, status, id, pq.Array(fromStatuses))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("no replay %s in status %v", id, fromStatuses)
+	}
+	return nil
+}
+
+//Pause stops the replay with id after the batch of jobs being replayed
+func (replay *HandleT) Pause(id string) error {
+	return replay.setStatus(id, PAUSED, RUNNING)
+}
+
+//Resume continues a paused or failed replay from its progress
+func (replay *HandleT) Resume(id string) error {
+	return replay.setStatus(id, RUNNING, PAUSED, FAILED)
+}
+
+func (replay *HandleT) getReplays(whereClause string, limit int, args ...interface{}) ([]ReplayT, error) {
+	sqlStatement := fmt.Sprintf(`SELECT id, source_id, destination_ids, start_time, end_time, backup_keys, backup_index, backup_offset,
+								replayed_jobs, status, error, created_at, updated_at FROM %s %s ORDER BY created_at DESC LIMIT %d`, REPLAYS_TABLE, whereClause, limit)
+	rows, err := replay.dbHandle.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replays := make([]ReplayT, 0)
+	for rows.Next() {
+		var r ReplayT
+		err = rows.Scan(&r.ID, &r.SourceID, pq.Array(&r.DestinationIDs), &r.From, &r.To, pq.Array(&r.BackupKeys), &r.BackupIndex, &r.BackupOffset,
+			&r.ReplayedJobs, &r.Status, &r.Error, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		replays = append(replays, r)
+	}
+	return replays, rows.Err()
+}
+
+//GetReplays returns the latest replays along with their progress, newest first
+func (replay *HandleT) GetReplays(limit int) ([]ReplayT, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	return replay.getReplays("", limit)
+}
+
+func (replay *HandleT) getStatus(id string) (string, error) {
+	var status string
+	sqlStatement := fmt.Sprintf(`SELECT status FROM %s WHERE id = $1`, REPLAYS_TABLE)
+	err := replay.dbHandle.QueryRow(sqlStatement, id).Scan(&status)
+	return status, err
+}
+
+//saveProgress saves the progress of r while it is running. errNotRunning is returned if it was paused meanwhile, so the pause isn't overwritten
+func (replay *HandleT) saveProgress(r *ReplayT) error {
+	sqlStatement := fmt.Sprintf(`UPDATE %s SET backup_index = $1, backup_offset = $2, replayed_jobs = $3, status = $4, error = $5, updated_at = NOW()
+								WHERE id = $6 AND status = $7`, REPLAYS_TABLE)
+	result, err := replay.dbHandle.Exec(sqlStatement, r.BackupIndex, r.BackupOffset, r.ReplayedJobs, r.Status, r.Error, r.ID, RUNNING)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errNotRunning
+	}
+	return nil
+}
+
+func (replay *HandleT) saveProgressOrLog(r *ReplayT) bool {
+	err := replay.saveProgress(r)
+	if err == errNotRunning {
+		replay.logger.Infof("Replay: Stopped replay %s at backup %d", r.ID, r.BackupIndex)
+		return false
+	}
+	if err != nil {
+		replay.logger.Errorf("Replay: Failed to save progress of replay %s: %v", r.ID, err)
+		return false
+	}
+	return true
+}
+
+func (replay *HandleT) replayLoop() {
+	for {
+		time.Sleep(loopSleep)
+		replays, err := replay.getReplays("WHERE status = $1", defaultListLimit, RUNNING)
+		if err != nil {
+			replay.logger.Errorf("Replay: Failed to get running replays: %v", err)
+			continue
+		}
+		//oldest replay first
+		for i := len(replays) - 1; i >= 0; i-- {
+			replay.run(&replays[i])
+		}
+	}
+}
+
+/*
+run replays the backups of r from its progress, storing their jobs into the gateway jobsdb at most maxJobsPerSecond at a time.
+Progress is saved after each batch of jobs is stored, so a batch may be replayed twice if the server stops in between
+*/
+func (replay *HandleT) run(r *ReplayT) {
+	replayedJobsStat := stats.NewTaggedStat("replay_jobs", stats.CountType, stats.Tags{"sourceId": r.SourceID})
+	//jobs replayed earlier are backed up again when their dataset is, they are replayed from their original backup only
+	filter := jobsdb.RestoreRequestT{SourceID: r.SourceID, From: r.From, To: r.To, ExcludeParameter: REPLAY_ID_PARAMETER}
+	for r.BackupIndex < len(r.BackupKeys) {
+		key := r.BackupKeys[r.BackupIndex]
+		err := replay.gatewayDB.ReadBackup(key, filter, r.BackupOffset, func(jobs []*jobsdb.JobT, offset int) error {
+			status, err := replay.getStatus(r.ID)
+			if err != nil {
+				return err
+			}
+			if status != RUNNING {
+				return errNotRunning
+			}
+			if err = tagJobs(jobs, r); err != nil {
+				return err
+			}
+			if err = replay.gatewayDB.Store(jobs); err != nil {
+				return err
+			}
+			r.BackupOffset = offset
+			r.ReplayedJobs += len(jobs)
+			replayedJobsStat.Count(len(jobs))
+			if err = replay.saveProgress(r); err != nil {
+				return err
+			}
+			//leave the processor to live traffic
+			time.Sleep(time.Duration(len(jobs)) * time.Second / time.Duration(maxJobsPerSecond))
+			return nil
+		})
+		if err == errNotRunning {
+			replay.logger.Infof("Replay: Stopped replay %s at backup %s", r.ID, key)
+			return
+		}
+		if err != nil {
+			replay.logger.Errorf("Replay: Failed to replay backup %s for replay %s: %v", key, r.ID, err)
+			r.Status = FAILED
+			r.Error = err.Error()
+			replay.saveProgressOrLog(r)
+			return
+		}
+		r.BackupIndex++
+		r.BackupOffset = 0
+		if !replay.saveProgressOrLog(r) {
+			return
+		}
+	}
+	r.Status = COMPLETED
+	if !replay.saveProgressOrLog(r) {
+		return
+	}
+	replay.logger.Infof("Replay: Completed replay %s with %d jobs", r.ID, r.ReplayedJobs)
+}
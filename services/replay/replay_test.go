@@ -0,0 +1,125 @@
+package replay
+
+import (
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/stats"
+)
+
+type gatewayDBMockT struct {
+	backups []jobsdb.BackupT
+	dumps   map[string][]*jobsdb.JobT
+	offsets  []int
+	requests []jobsdb.RestoreRequestT
+	stored   []*jobsdb.JobT
+}
+
+func (gatewayDB *gatewayDBMockT) ListBackups() ([]jobsdb.BackupT, error) {
+	return gatewayDB.backups, nil
+}
+
+func (gatewayDB *gatewayDBMockT) ReadBackup(key string, request jobsdb.RestoreRequestT, offset int, onJobs func(jobs []*jobsdb.JobT, offset int) error) error {
+	gatewayDB.offsets = append(gatewayDB.offsets, offset)
+	gatewayDB.requests = append(gatewayDB.requests, request)
+	jobs := gatewayDB.dumps[key]
+	for i := offset; i < len(jobs); i++ {
+		if err := onJobs([]*jobsdb.JobT{jobs[i]}, i+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gatewayDB *gatewayDBMockT) Store(jobList []*jobsdb.JobT) error {
+	gatewayDB.stored = append(gatewayDB.stored, jobList...)
+	return nil
+}
+
+var _ = Describe("Replay", func() {
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	It("should select the jobs dumps overlapping the replay window", func() {
+		backups := []jobsdb.BackupT{
+			{Key: "gw_jobs_1", StartTime: start, EndTime: start.Add(time.Hour)},
+			{Key: "gw_jobs_2", StartTime: start.Add(time.Hour), EndTime: start.Add(2 * time.Hour)},
+			{Key: "gw_job_status_2_aborted", StartTime: start.Add(time.Hour), EndTime: start.Add(2 * time.Hour), FailedOnly: true},
+			{Key: "gw_jobs_3", StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)},
+		}
+		keys := selectBackupKeys(backups, start.Add(90*time.Minute), start.Add(150*time.Minute))
+		Expect(keys).To(Equal([]string{"gw_jobs_2", "gw_jobs_3"}))
+	})
+
+	It("should only be a feature when enabled", func() {
+		defer func(wasEnabled bool) { enabled = wasEnabled }(enabled)
+		enabled = false
+		Expect(newReplayFeature(nil)).To(BeNil())
+		enabled = true
+		Expect(newReplayFeature(nil)).NotTo(BeNil())
+	})
+
+	It("should tag replayed jobs with their target destinations", func() {
+		jobs := []*jobsdb.JobT{{Parameters: []byte(`{"source_id":"s1"}`)}, {Parameters: []byte(`{}`)}}
+		err := tagJobs(jobs, &ReplayT{ID: "r1", DestinationIDs: []string{"d1", "d2"}})
+		Expect(err).To(BeNil())
+		for _, job := range jobs {
+			Expect(gjson.GetBytes(job.Parameters, REPLAY_ID_PARAMETER).String()).To(Equal("r1"))
+			Expect(TargetDestinationIDs(job.Parameters)).To(Equal(map[string]bool{"d1": true, "d2": true}))
+		}
+		Expect(gjson.GetBytes(jobs[0].Parameters, "source_id").String()).To(Equal("s1"))
+		Expect(TargetDestinationIDs([]byte(`{"source_id":"s1"}`))).To(BeNil())
+	})
+
+	It("should resume replays from their progress and stop once paused", func() {
+		stats.Setup()
+		db, mock, err := sqlmock.New()
+		Expect(err).To(BeNil())
+		defer db.Close()
+		gatewayDB := &gatewayDBMockT{dumps: map[string][]*jobsdb.JobT{
+			"gw_jobs_1": {{Parameters: []byte(`{}`)}, {Parameters: []byte(`{}`)}, {Parameters: []byte(`{}`)}},
+		}}
+		replay := &HandleT{gatewayDB: gatewayDB, dbHandle: db, logger: pkgLogger}
+		maxJobsPerSecond = 1000
+
+		mock.ExpectQuery("SELECT status FROM gw_replays").WithArgs("r1").WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(RUNNING))
+		mock.ExpectExec("UPDATE gw_replays SET backup_index").WithArgs(0, 2, 6, RUNNING, "", "r1", RUNNING).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT status FROM gw_replays").WithArgs("r1").WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(PAUSED))
+
+		r := &ReplayT{ID: "r1", DestinationIDs: []string{"d1"}, BackupKeys: []string{"gw_jobs_1"}, BackupOffset: 1, ReplayedJobs: 5, Status: RUNNING}
+		replay.run(r)
+		Expect(gatewayDB.offsets).To(Equal([]int{1}))
+		Expect(gatewayDB.requests[0].ExcludeParameter).To(Equal(REPLAY_ID_PARAMETER))
+		Expect(gatewayDB.stored).To(HaveLen(1))
+		Expect(r.BackupOffset).To(Equal(2))
+		Expect(r.Status).To(Equal(RUNNING))
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+
+	It("should not overwrite a pause made while a batch of jobs was stored", func() {
+		stats.Setup()
+		db, mock, err := sqlmock.New()
+		Expect(err).To(BeNil())
+		defer db.Close()
+		gatewayDB := &gatewayDBMockT{dumps: map[string][]*jobsdb.JobT{
+			"gw_jobs_1": {{Parameters: []byte(`{}`)}, {Parameters: []byte(`{}`)}},
+			"gw_jobs_2": {{Parameters: []byte(`{}`)}},
+		}}
+		replay := &HandleT{gatewayDB: gatewayDB, dbHandle: db, logger: pkgLogger}
+		maxJobsPerSecond = 1000
+
+		mock.ExpectQuery("SELECT status FROM gw_replays").WithArgs("r1").WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(RUNNING))
+		mock.ExpectExec("UPDATE gw_replays SET backup_index").WithArgs(0, 1, 1, RUNNING, "", "r1", RUNNING).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		r := &ReplayT{ID: "r1", DestinationIDs: []string{"d1"}, BackupKeys: []string{"gw_jobs_1", "gw_jobs_2"}, Status: RUNNING}
+		replay.run(r)
+		Expect(gatewayDB.offsets).To(Equal([]int{0}))
+		Expect(gatewayDB.stored).To(HaveLen(1))
+		Expect(r.BackupIndex).To(Equal(0))
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+})
@@ -135,6 +135,68 @@ var MigrationAssets = func() http.FileSystem {
 
 			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x84\x8d\xb1\x4e\xf3\x30\x14\x46\x67\xfb\x29\xbe\xad\x89\x54\x0f\xff\x3f\x21\x31\x39\xc5\x55\x0d\x8e\x5d\x39\x0e\xa5\x2c\x91\xd5\x78\x88\x04\x71\x94\xb8\x03\x3c\x3d\x4a\x84\x22\xba\xc0\xdd\xee\x3d\x47\xe7\x32\xc6\x28\x63\x0c\x66\x08\xa3\x4f\x5d\xec\xa7\x79\xa5\x74\x67\x05\x77\x02\x8e\x17\x4a\x40\xee\xa1\x8d\x83\x78\x91\x95\xab\x10\x57\x15\x19\x25\xa4\x6b\x51\x09\x2b\xb9\xc2\xd1\xca\x92\xdb\x33\x9e\xc4\x79\x4b\x09\x59\x3d\x3c\x73\xbb\x3b\x70\x9b\xfd\xfb\x7f\x97\x2f\x25\x5d\x2b\xb5\xa5\xf8\x9e\xc1\x7f\xbc\x45\xdf\xe2\xb1\x32\xba\xf8\xc1\x09\x69\x63\x1f\x50\x18\xa3\x04\xd7\x37\x20\x0e\xcd\x94\x7c\xba\x4e\x37\xed\x99\x5c\xc6\xe0\x53\x68\x1b\x9f\xe0\x64\x29\x2a\xc7\xcb\x23\x4e\xd2\x1d\x4c\xed\x96\x0b\x5e\x8d\x16\x6b\x0c\x0f\x62\xcf\x6b\xe5\x90\x69\x73\xca\x72\xf8\x84\xd4\xbd\x07\x7c\xce\x9f\x37\xd7\x74\xd9\x2c\xd5\x29\xf9\x31\x35\x0b\xf9\xa5\x3a\x9b\xa1\x6f\xff\xf4\xf2\xfb\xaf\x00\x00\x00\xff\xff\xdd\x5b\x28\x06\x77\x01\x00\x00"),
 		},
+		"/node/000006_create_tracking_plan_violations.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000006_create_tracking_plan_violations.up.sql",
+			modTime:          time.Date(2021, 9, 14, 11, 20, 4, 118342619, time.UTC),
+			uncompressedSize: 367,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x6d\x8f\x51\x4f\xc2\x30\x14\x85\x9f\xe9\xaf\x38\x6f\x6c\x09\x4d\x8c\x3e\xfa\x54\xb0\x68\xe3\x36\x96\xed\x82\xf0\xd4\x34\xac\x9a\x86\xd9\x91\x51\x31\xfc\x7b\x37\x83\x33\x28\x6f\x27\x39\xdf\x97\x7b\x2e\xe7\x9c\x71\xce\x41\xad\xd9\xee\x9c\x7f\x43\x5e\x1b\x8f\x95\x6b\x6a\x13\x5c\xe3\x0f\x7d\xc9\xd8\xac\x90\x82\x24\x48\x4c\x13\x09\x35\x47\xb6\x20\xc8\xb5\x2a\xa9\x44\x38\x8b\x7a\xdf\x89\xfa\x38\x88\x88\xd8\x68\xf4\xd9\xba\x60\xf5\xce\x9e\xb0\x12\xc5\xec\x49\x14\xd1\xdd\x6d\xfc\x6d\x67\xcb\x24\x99\x74\x84\x3d\x5a\x1f\x74\x38\xed\x2d\x48\xae\xe9\x4a\xf7\xde\x54\xb6\xd6\xae\xea\xb2\x7b\x75\xb6\xbd\xe4\xf0\x20\xe7\x62\x99\x10\xc6\xe3\x5e\x19\xee\xeb\x6d\xf3\xe1\x03\xa6\xea\x51\x65\x57\xe8\x9b\x1e\xae\xcd\x21\x9c\x17\xdb\x4a\x9b\x00\x52\xa9\x2c\x49\xa4\xf9\x7f\x21\x5b\xbc\x44\x71\x2f\xe5\x85\x4a\x45\xb1\xc1\xb3\xdc\x20\x1a\xfe\x9b\xe0\xf7\x91\x9f\xfc\x77\x78\x1c\xdf\xb3\x2f\xe8\x8d\xd9\xc2\x6f\x01\x00\x00"),
+		},
+		"/node/000006_drop_tracking_plan_violations.down.sql": &vfsgen۰FileInfo{
+			name:    "000006_drop_tracking_plan_violations.down.sql",
+			modTime: time.Date(2021, 9, 14, 11, 20, 4, 118342619, time.UTC),
+			content: []byte("\x2d\x2d\x2d\x0a\x2d\x2d\x2d\x20\x54\x72\x61\x63\x6b\x69\x6e\x67\x20\x50\x6c\x61\x6e\x20\x56\x69\x6f\x6c\x61\x74\x69\x6f\x6e\x73\x0a\x2d\x2d\x2d\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x74\x72\x61\x63\x6b\x69\x6e\x67\x5f\x70\x6c\x61\x6e\x5f\x76\x69\x6f\x6c\x61\x74\x69\x6f\x6e\x73\x3b\x0a"),
+		},
+		"/node/000007_create_dlq_replays.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000007_create_dlq_replays.up.sql",
+			modTime:          time.Date(2026, 10, 16, 14, 2, 31, 725116000, time.UTC),
+			uncompressedSize: 487,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x8d\x91\x5d\x4b\xc3\x30\x14\x86\xaf\xd7\x5f\x71\xee\xd6\x81\x01\xef\xbd\x6a\x6d\x36\x32\xd3\x74\x36\x29\x76\xc8\x08\xdd\x12\x31\x5a\xd6\x9a\x44\xd0\x7f\x6f\xc2\x54\x28\x53\xf1\xf2\x9c\xf7\x39\x1f\xef\x39\x08\xa1\x04\x21\x04\x05\xbd\x85\x5a\x8f\x7d\xf7\xee\x62\x9c\x24\xd7\x35\xce\x04\x06\x91\xe5\x14\x03\x59\x02\xab\x04\xe0\x96\x70\xc1\x41\xf5\x2f\xd2\x9e\x58\x48\x93\xd9\xcc\x28\x10\xb8\x15\xb0\xa9\x49\x99\xd5\x5b\xb8\xc1\xdb\x8b\x90\x1e\x46\x6d\x3b\x3f\xd8\x93\x18\xeb\x59\x43\x69\x54\xac\xee\xdc\x70\x9c\xe6\xa1\xc0\xcb\xac\xa1\x02\xe6\xf3\x88\x3c\x98\xde\x6b\x0b\x6b\x5e\xb1\x7c\x52\x3b\x76\xfe\xf0\xa8\xdd\x0f\xca\xd3\xb0\x97\x46\x39\xc8\xc9\x8a\x30\x71\xbf\x9b\x88\xee\xd9\x8c\xa3\x56\xf2\x4f\xc8\x0e\xaf\x61\x68\x64\x1c\x04\xf5\x7c\xb7\xcb\x48\xed\xe3\x06\xf2\x7f\xec\x21\x58\xf5\x61\x6c\xe7\x41\x90\x12\x73\x91\x95\x9b\x73\x94\x55\x77\xe9\x62\x71\xf5\x7d\x74\xc2\x0a\xdc\xfe\x7e\xf4\x2f\x13\xd2\x1c\x95\x7e\x83\x8a\x4d\x3e\xd2\x70\xc2\x56\x10\xdc\x41\xfa\xc9\x85\xce\x1f\x71\xd9\xce\xbb\xe7\x01\x00\x00"),
+		},
+		"/node/000007_drop_dlq_replays.down.sql": &vfsgen۰FileInfo{
+			name:    "000007_drop_dlq_replays.down.sql",
+			modTime: time.Date(2026, 10, 16, 14, 2, 31, 725116000, time.UTC),
+			content: []byte("\x2d\x2d\x2d\x0a\x2d\x2d\x2d\x20\x44\x4c\x51\x20\x52\x65\x70\x6c\x61\x79\x73\x0a\x2d\x2d\x2d\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x64\x6c\x71\x5f\x72\x65\x70\x6c\x61\x79\x73\x3b\x0a"),
+		},
+		"/node/000008_create_router_job_leases.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000008_create_router_job_leases.up.sql",
+			modTime:          time.Date(2026, 10, 16, 14, 7, 11, 415168000, time.UTC),
+			uncompressedSize: 253,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x65\x8e\xc1\x0a\x82\x40\x14\x45\xd7\xce\x57\xdc\x9d\x0a\xce\x17\xb4\x9a\x6a\x84\xa9\x51\x43\x9f\xa4\xab\xc1\x70\x16\x46\x34\xa2\x13\xd4\xdf\x97\x2e\x82\x68\x7b\xcf\x39\x70\x39\xe7\x8c\x73\x8e\xd2\x3d\xbc\x9d\x70\x70\x17\x68\xdb\xcd\x76\x5e\x56\xc6\x76\xa5\x14\x24\x41\x62\xab\x25\x54\x8a\xbc\x20\xc8\x46\x55\x54\x61\x5a\x0b\x73\x75\x17\x73\x5b\x0b\x44\x2c\x08\x7a\x3b\x7b\xe3\x5f\xa3\x05\xc9\x86\x56\x3f\xaf\xb5\x4e\x3e\x68\xec\x26\x3f\xf8\xc1\xdd\xa1\xf2\x5f\x72\x77\xbd\x35\x43\xff\x9b\x60\x2f\x53\x51\x6b\x42\x18\x2e\x8e\x7d\x8e\xc3\x64\x67\xd3\x79\x90\xca\x64\x45\x22\x3b\xfd\xbb\x79\x71\x8e\xe2\x45\x3f\x95\x2a\x13\x65\x8b\xa3\x6c\x11\x7d\x4f\x25\xf8\x9e\x88\xe3\x0d\x7b\x03\xb6\x98\xc8\xc6\xfd\x00\x00\x00"),
+		},
+		"/node/000008_drop_router_job_leases.down.sql": &vfsgen۰FileInfo{
+			name:    "000008_drop_router_job_leases.down.sql",
+			modTime: time.Date(2026, 10, 16, 14, 7, 11, 415168000, time.UTC),
+			content: []byte("\x2d\x2d\x2d\x0a\x2d\x2d\x2d\x20\x52\x6f\x75\x74\x65\x72\x20\x4a\x6f\x62\x20\x4c\x65\x61\x73\x65\x73\x0a\x2d\x2d\x2d\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x72\x6f\x75\x74\x65\x72\x5f\x6a\x6f\x62\x5f\x6c\x65\x61\x73\x65\x73\x3b\x0a"),
+		},
+		"/node/000009_create_gw_replays.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000009_create_gw_replays.up.sql",
+			modTime:          time.Date(2026, 10, 16, 14, 32, 27, 573483000, time.UTC),
+			uncompressedSize: 600,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x8d\x92\x41\x4f\xc2\x40\x10\x85\xcf\xf4\x57\xcc\x0d\x48\xdc\xc4\xbb\xa7\x22\x0b\xd9\xd8\x6e\x49\xbb\x44\x88\x31\x9b\xa5\x3b\x98\x8a\xb6\xcd\xee\x36\xd8\x7f\x6f\x6b\xb1\xda\x08\xc4\xeb\x7b\xdf\x4c\x66\xe6\x0d\x21\xc4\x23\x84\xc0\x52\x39\x3c\xaa\x1a\x62\x2c\xdf\x54\x6d\x5b\xcd\xf3\xee\x63\xea\x0b\x0a\xc2\x9f\x05\x14\xd8\x02\x78\x24\x80\x6e\x58\x22\x12\x78\x39\x4a\xd3\xa1\x30\xf1\x46\xa3\x4c\x83\xa0\x1b\x01\xab\x98\x85\x7e\xbc\x85\x07\xba\xbd\x69\x64\x5b\x54\x26\x45\xf9\xed\xb6\xf5\x7c\x1d\x04\xad\xa5\xd1\xba\x2c\x57\x2e\x2b\xf2\xc6\xb7\x5f\xc0\xd3\xf3\x00\xb1\x4e\x19\x27\x5d\xf6\x8e\x20\x58\x48\x13\xe1\x87\xab\x01\x80\xb9\xbe\x66\xef\x54\x7a\xa8\x4a\x79\xc0\xfa\x6c\xfb\x93\x9d\xe5\x1a\x3f\x80\xf1\x9f\xf1\x60\x4e\x17\xfe\x3a\x10\x70\xfb\x0b\x2b\xf6\x7b\x8b\xee\x0a\xd7\x9d\x03\xb5\x7c\x2d\x76\x16\x66\x6c\x79\x19\x6d\x16\x73\x95\xfd\x7b\x13\x34\xa6\x30\x43\xb9\x2f\x1c\x8f\x5b\x22\x35\xd8\x04\xa5\xa5\x72\x67\x76\xee\x59\x1e\x3d\x4e\xa6\x2d\x5e\x95\xfa\xbf\xf8\xf4\xae\xcf\x9b\xf1\x39\xdd\x5c\xcc\x5b\x76\xd3\x9f\xee\x16\xf1\xc1\x2b\x74\x5e\xd3\xeb\x13\xdd\x37\xe8\x18\x58\x02\x00\x00"),
+		},
+		"/node/000009_drop_gw_replays.down.sql": &vfsgen۰FileInfo{
+			name:    "000009_drop_gw_replays.down.sql",
+			modTime: time.Date(2026, 10, 16, 14, 32, 27, 573483000, time.UTC),
+			content: []byte("\x2d\x2d\x2d\x0a\x2d\x2d\x2d\x20\x47\x61\x74\x65\x77\x61\x79\x20\x52\x65\x70\x6c\x61\x79\x73\x0a\x2d\x2d\x2d\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x67\x77\x5f\x72\x65\x70\x6c\x61\x79\x73\x3b\x0a"),
+		},
+		"/node/000010_add_dlq_replays_status.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000010_add_dlq_replays_status.up.sql",
+			modTime:          time.Date(2026, 10, 16, 18, 16, 9, 676099000, time.UTC),
+			uncompressedSize: 264,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x9d\xcd\xbd\x0e\x82\x30\x1c\x04\xf0\x19\x9e\xe2\x36\x16\xfb\x04\x4e\x55\x4a\x42\x52\x21\x4a\x49\xd8\x0c\xd0\xbf\x52\xc3\x87\xb4\x10\xe3\xdb\x0b\x46\x47\x17\xd7\xbb\xdc\xef\x18\x63\x3e\x63\x0c\x5c\x6b\xd3\x5f\xe1\xa6\x72\x9a\x1d\x86\x0b\x42\x79\x84\xa5\x7b\x5b\x3e\xdd\x06\x8f\xc6\xd4\x0d\xea\xb6\x34\x1d\xa6\x86\x8c\xc5\x6d\xa8\x1c\x2a\xba\x0c\x96\x40\xfd\x38\xd3\xbc\xce\x97\xae\x5b\x39\xdf\xe7\x52\x89\x13\x14\xdf\x49\x01\xdd\x8e\xe7\x0f\xe5\x7b\x1e\x0f\x43\xec\x53\x99\x1f\x12\xc4\x11\x92\x54\x41\x14\x71\xa6\xb2\xef\xb7\x12\x85\x7a\xc7\x49\x2e\x25\x42\x11\xf1\x5c\x2a\x04\x6e\xae\x6b\x22\x4d\x3a\xd8\xfe\x83\x93\xb5\x83\xfd\x65\x2f\xe4\x0b\xd9\x55\x6c\x1c\x08\x01\x00\x00"),
+		},
+		"/node/000010_drop_dlq_replays_status.down.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000010_drop_dlq_replays_status.down.sql",
+			modTime:          time.Date(2026, 10, 16, 18, 16, 9, 676099000, time.UTC),
+			uncompressedSize: 155,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xd3\xd5\xd5\xe5\xd2\xd5\xd5\x55\x70\x29\xca\x2f\x28\xc8\xcc\x4b\x57\x28\x2e\x49\x2c\x29\x2d\x56\xc8\x4f\x53\x70\xf1\x09\x54\x28\x4a\x2d\xc8\x49\xac\x2c\x06\x29\xe1\xe2\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xc9\x29\x8c\x87\x49\x72\x72\xba\x04\xf9\x07\x28\x38\xfb\xfb\x84\xfa\xfa\x29\x78\xba\x29\xb8\x46\x78\x06\x87\x04\x43\x8d\xb2\x26\x59\x5f\x6a\x51\x51\x7e\x91\x35\x17\x00\x93\x1d\xae\x93\x9b\x00\x00\x00"),
+		},
 		"/node/00005_alter_event_schemas_autovacuum.up.sql": &vfsgen۰CompressedFileInfo{
 			name:             "00005_alter_event_schemas_autovacuum.up.sql",
 			modTime:          time.Date(2021, 8, 19, 22, 51, 26, 225662068, time.UTC),
@@ -238,6 +300,18 @@ var MigrationAssets = func() http.FileSystem {
 			modTime: time.Date(2021, 8, 23, 11, 6, 47, 959313097, time.UTC),
 			content: []byte("\x0a\x2d\x2d\x0a\x2d\x2d\x20\x77\x68\x5f\x75\x70\x6c\x6f\x61\x64\x73\x0a\x2d\x2d\x0a\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x77\x68\x5f\x75\x70\x6c\x6f\x61\x64\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x49\x46\x20\x4e\x4f\x54\x20\x45\x58\x49\x53\x54\x53\x20\x6d\x65\x72\x67\x65\x64\x73\x63\x68\x65\x6d\x61\x20\x4a\x53\x4f\x4e\x42\x20\x44\x45\x46\x41\x55\x4c\x54\x20\x27\x7b\x7d\x27\x3b\x0a"),
 		},
+		"/warehouse/000013_add_wh_table_uploads_deduped_events.up.sql": &vfsgen۰FileInfo{
+			name:    "000013_add_wh_table_uploads_deduped_events.up.sql",
+			modTime: time.Date(2026, 10, 16, 17, 48, 27, 514359000, time.UTC),
+			content: []byte("\x2d\x2d\x20\x77\x68\x5f\x74\x61\x62\x6c\x65\x5f\x75\x70\x6c\x6f\x61\x64\x73\x20\x2d\x2d\x0a\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x77\x68\x5f\x74\x61\x62\x6c\x65\x5f\x75\x70\x6c\x6f\x61\x64\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x49\x46\x20\x4e\x4f\x54\x20\x45\x58\x49\x53\x54\x53\x20\x64\x65\x64\x75\x70\x65\x64\x5f\x65\x76\x65\x6e\x74\x73\x20\x42\x49\x47\x49\x4e\x54\x3b\x0a"),
+		},
+		"/warehouse/000014_create_wh_schema_changes.up.sql": &vfsgen۰CompressedFileInfo{
+			name:             "000014_create_wh_schema_changes.up.sql",
+			modTime:          time.Date(2026, 10, 16, 17, 48, 37, 43068000, time.UTC),
+			uncompressedSize: 763,
+
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xad\x91\xcd\x52\xc2\x30\x14\x85\xf7\x7d\x8a\xbb\x03\x66\xda\x9d\xe3\x86\x71\x91\x62\x94\x68\x1b\x30\x0d\x5a\x56\x99\xd8\x04\x9a\x19\x48\x2b\x4d\x47\x7d\x7b\x2b\x20\x3f\x55\x91\x85\x59\xe6\x9c\x7b\x73\xf2\x9d\x20\xf0\x82\x00\x5e\x73\x51\x65\xb9\x5e\x4a\x91\xe5\xd2\xce\x75\xd5\x5c\x7a\xde\x80\x61\xc4\x31\x70\x14\x46\x18\xc8\x0d\xd0\x11\x07\x9c\x92\x84\x27\xdf\x07\xa0\xeb\x41\x73\x8c\x82\x90\xdc\x26\x98\x11\x14\xc1\x98\x91\x18\xb1\x29\xdc\xe3\xa9\xbf\x56\xab\xa2\x5e\x65\x5a\x34\xa6\x47\xc4\x06\x43\xc4\xba\x97\x17\xbd\xf5\x5a\x3a\x89\xa2\x8d\x47\xe9\xca\x19\x2b\x9d\x29\xec\xf9\x46\xf7\x5e\xea\x13\x56\x2b\x97\xba\x2a\x65\x76\xca\xe3\xe4\xf3\x42\x8b\x4f\x27\x70\x9c\xf2\x96\xba\xfd\xac\x32\xb3\x19\xdc\x25\x23\x1a\xb6\x75\x27\x5d\x5d\x9d\x58\xdf\x00\xab\xcb\x45\x21\x95\xd8\x20\x22\x94\x6f\x84\x6c\xa5\xa5\xd3\x4a\x48\x07\x9c\xc4\x38\xe1\x28\x1e\xb7\x66\xeb\x52\xfd\x6e\xe9\xf5\x77\x45\x11\x7a\x8d\xd3\xbf\x8a\x12\xc7\x80\xc5\x8e\x8d\xd8\x13\x10\xc6\x2a\xfd\x06\x23\xfa\x53\xcf\xc7\xf3\xfe\x1e\xae\x7f\xc0\xf0\x20\xd5\x84\x92\x87\xc9\xb9\xe1\x4a\x6d\x95\xb1\x73\x51\x5b\xf3\x52\xff\x47\x0e\x78\x1a\x62\x86\xbf\xfa\xb9\x82\xce\xf6\x85\x4e\xdf\xfb\x00\xe7\x6e\x75\x12\xfb\x02\x00\x00"),
+		},
 	}
 	fs["/"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
 		fs["/jobsdb"].(os.FileInfo),
@@ -264,6 +338,16 @@ var MigrationAssets = func() http.FileSystem {
 		fs["/node/000003_remove_event_model_columns.down.sql"].(os.FileInfo),
 		fs["/node/000004_create_ops.down.sql"].(os.FileInfo),
 		fs["/node/000004_create_ops.up.sql"].(os.FileInfo),
+		fs["/node/000006_create_tracking_plan_violations.up.sql"].(os.FileInfo),
+		fs["/node/000006_drop_tracking_plan_violations.down.sql"].(os.FileInfo),
+		fs["/node/000007_create_dlq_replays.up.sql"].(os.FileInfo),
+		fs["/node/000007_drop_dlq_replays.down.sql"].(os.FileInfo),
+		fs["/node/000008_create_router_job_leases.up.sql"].(os.FileInfo),
+		fs["/node/000008_drop_router_job_leases.down.sql"].(os.FileInfo),
+		fs["/node/000009_create_gw_replays.up.sql"].(os.FileInfo),
+		fs["/node/000009_drop_gw_replays.down.sql"].(os.FileInfo),
+		fs["/node/000010_add_dlq_replays_status.up.sql"].(os.FileInfo),
+		fs["/node/000010_drop_dlq_replays_status.down.sql"].(os.FileInfo),
 		fs["/node/00005_alter_event_schemas_autovacuum.up.sql"].(os.FileInfo),
 	}
 	fs["/reports"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
@@ -283,6 +367,8 @@ var MigrationAssets = func() http.FileSystem {
 		fs["/warehouse/000010_add_metadata_to_wh_staging_files.up.sql"].(os.FileInfo),
 		fs["/warehouse/000011_add_wh_loadfiles_metadata_column.up.sql"].(os.FileInfo),
 		fs["/warehouse/000012_add_mergedSchema_to_wh_uploads.up.sql"].(os.FileInfo),
+		fs["/warehouse/000013_add_wh_table_uploads_deduped_events.up.sql"].(os.FileInfo),
+		fs["/warehouse/000014_create_wh_schema_changes.up.sql"].(os.FileInfo),
 	}
 
 	return fs
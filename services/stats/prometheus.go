@@ -0,0 +1,397 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+)
+
+const (
+	StatsDBackend       = "statsd"
+	PrometheusBackend   = "prometheus"
+	defaultTimerBuckets = "0.001,0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10,30,60"
+)
+
+//overflowLabel replaces the tags of stats of a metric which already has Prometheus.maxLabelSets label sets
+const overflowLabel = "stats_overflow"
+
+var invalidMetricNameChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+var invalidLabelNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+//prometheusHandleT implements Stats by aggregating stats in memory, to be scraped from /metrics in the prometheus text format
+type prometheusHandleT struct {
+	buckets       []float64
+	maxLabelSets  int
+	defaultLabels Tags
+
+	metricsLock sync.RWMutex
+	metrics     map[string]*prometheusMetricT
+}
+
+//prometheusMetricT holds the series of a metric, one per label set
+type prometheusMetricT struct {
+	name     string
+	statType string
+
+	seriesLock sync.RWMutex
+	series     map[string]*prometheusSeriesT
+	overflowed bool
+}
+
+type prometheusSeriesT struct {
+	labels string
+
+	lock         sync.Mutex
+	value        float64
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+//prometheusStatT is the RudderStats of a series
+type prometheusStatT struct {
+	handle   *prometheusHandleT
+	statType string
+	series   *prometheusSeriesT
+	start    time.Time
+}
+
+func newPrometheusHandle(buckets []float64, maxLabelSets int, defaultLabels Tags) *prometheusHandleT {
+	return &prometheusHandleT{
+		buckets:       buckets,
+		maxLabelSets:  maxLabelSets,
+		defaultLabels: defaultLabels,
+		metrics:       make(map[string]*prometheusMetricT),
+	}
+}
+
+//parseBuckets parses the comma separated upper bounds of histogram buckets, in seconds
+func parseBuckets(value string) ([]float64, error) {
+	buckets := make([]float64, 0)
+	for _, bound := range strings.Split(value, ",") {
+		bucket, err := strconv.ParseFloat(strings.TrimSpace(bound), 64)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	sort.Float64s(buckets)
+	return buckets, nil
+}
+
+//prometheusDefaultLabels are the labels of every series, the same as the default tags of statsd stats
+func prometheusDefaultLabels() Tags {
+	labels := Tags{"instanceName": instanceID}
+	if namespace := config.GetKubeNamespace(); len(namespace) > 0 {
+		labels["namespace"] = namespace
+	}
+	return labels
+}
+
+func (handle *prometheusHandleT) NewStat(Name string, StatType string) (rStats RudderStats) {
+	return handle.NewTaggedStat(Name, StatType, nil)
+}
+
+func (handle *prometheusHandleT) NewTaggedStat(Name string, StatType string, tags Tags) (rStats RudderStats) {
+	metric := handle.getMetric(Name, StatType)
+	return &prometheusStatT{
+		handle:   handle,
+		statType: StatType,
+		series:   handle.getSeries(metric, tags),
+	}
+}
+
+//NewSampledTaggedStat is the same as NewTaggedStat, stats being aggregated in memory there is no need to sample them
+func (handle *prometheusHandleT) NewSampledTaggedStat(Name string, StatType string, tags Tags) (rStats RudderStats) {
+	return handle.NewTaggedStat(Name, StatType, tags)
+}
+
+func metricName(name string, statType string) string {
+	name = invalidMetricNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	if statType == TimerType {
+		name += "_seconds"
+	}
+	return name
+}
+
+func (handle *prometheusHandleT) getMetric(name string, statType string) *prometheusMetricT {
+	name = metricName(name, statType)
+	handle.metricsLock.RLock()
+	metric, ok := handle.metrics[name]
+	handle.metricsLock.RUnlock()
+	if ok && metric.statType == statType {
+		return metric
+	}
+
+	handle.metricsLock.Lock()
+	defer handle.metricsLock.Unlock()
+	if metric, ok = handle.metrics[name]; ok && metric.statType != statType {
+		//a metric can only have one type, stats of the same name with other types get a name of their own
+		name = name + "_" + statType
+		metric, ok = handle.metrics[name]
+	}
+	if !ok {
+		metric = &prometheusMetricT{name: name, statType: statType, series: make(map[string]*prometheusSeriesT)}
+		handle.metrics[name] = metric
+	}
+	return metric
+}
+
+//formatLabels formats the default labels and tags as sorted prometheus labels
+func (handle *prometheusHandleT) formatLabels(tags Tags) string {
+	labels := make(map[string]string, len(handle.defaultLabels)+len(tags))
+	for name, value := range handle.defaultLabels {
+		labels[name] = value
+	}
+	for name, value := range tags {
+		name = invalidLabelNameChars.ReplaceAllString(name, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		labels[name] = value
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labels[name])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+//getSeries returns the series of metric with tags. Once a metric has maxLabelSets series, stats with new tags are aggregated in a single overflow series
+func (handle *prometheusHandleT) getSeries(metric *prometheusMetricT, tags Tags) *prometheusSeriesT {
+	labels := handle.formatLabels(tags)
+	metric.seriesLock.RLock()
+	series, ok := metric.series[labels]
+	metric.seriesLock.RUnlock()
+	if ok {
+		return series
+	}
+
+	metric.seriesLock.Lock()
+	defer metric.seriesLock.Unlock()
+	if series, ok = metric.series[labels]; ok {
+		return series
+	}
+	if handle.maxLabelSets > 0 && len(metric.series) >= handle.maxLabelSets {
+		if !metric.overflowed {
+			metric.overflowed = true
+			pkgLogger.Errorf("Metric %s has more than %d label sets. Stats with new tags are aggregated under %s=\"true\"", metric.name, handle.maxLabelSets, overflowLabel)
+		}
+		labels = handle.formatLabels(Tags{overflowLabel: "true"})
+		if series, ok = metric.series[labels]; ok {
+			return series
+		}
+	}
+	series = &prometheusSeriesT{labels: labels}
+	if metric.statType == TimerType {
+		series.bucketCounts = make([]uint64, len(handle.buckets))
+	}
+	metric.series[labels] = series
+	return series
+}
+
+func (series *prometheusSeriesT) add(delta float64) {
+	series.lock.Lock()
+	series.value += delta
+	series.lock.Unlock()
+}
+
+func (series *prometheusSeriesT) set(value float64) {
+	series.lock.Lock()
+	series.value = value
+	series.lock.Unlock()
+}
+
+func (series *prometheusSeriesT) observe(buckets []float64, value float64) {
+	series.lock.Lock()
+	defer series.lock.Unlock()
+	for i, bound := range buckets {
+		if value <= bound {
+			series.bucketCounts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+// Count increases the stat by n. Only applies to CountType stats
+func (rStats *prometheusStatT) Count(n int) {
+	if rStats.statType != CountType {
+		panic(fmt.Errorf("rStats.StatType:%s is not count", rStats.statType))
+	}
+	rStats.series.add(float64(n))
+}
+
+// Increment increases the stat by 1. Is the Equivalent of Count(1). Only applies to CountType stats
+func (rStats *prometheusStatT) Increment() {
+	rStats.Count(1)
+}
+
+// Gauge records an absolute value for this stat. Only applies to GaugeType stats. Values which aren't numbers are ignored
+func (rStats *prometheusStatT) Gauge(value interface{}) {
+	if rStats.statType != GaugeType {
+		panic(fmt.Errorf("rStats.StatType:%s is not gauge", rStats.statType))
+	}
+	if number, ok := toFloat64(value); ok {
+		rStats.series.set(number)
+	}
+}
+
+// Start starts a new timing for this stat. Only applies to TimerType stats
+func (rStats *prometheusStatT) Start() {
+	if rStats.statType != TimerType {
+		panic(fmt.Errorf("rStats.StatType:%s is not timer", rStats.statType))
+	}
+	rStats.start = time.Now()
+}
+
+// End observes the time elapsed since the Start() call of this stat. Only applies to TimerType stats
+func (rStats *prometheusStatT) End() {
+	rStats.SendTiming(time.Since(rStats.start))
+}
+
+func (rStats *prometheusStatT) DeferredTimer() {
+	rStats.SendTiming(0)
+}
+
+// SendTiming observes a timing for this stat. Only applies to TimerType stats
+func (rStats *prometheusStatT) SendTiming(duration time.Duration) {
+	if rStats.statType != TimerType {
+		panic(fmt.Errorf("rStats.StatType:%s is not timer", rStats.statType))
+	}
+	rStats.series.observe(rStats.handle.buckets, duration.Seconds())
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case time.Duration:
+		return v.Seconds(), true
+	}
+	return 0, false
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+//joinLabels formats labels and extra, if any, as the labels of a sample
+func joinLabels(labels string, extra string) string {
+	switch {
+	case labels == "" && extra == "":
+		return ""
+	case labels == "":
+		return "{" + extra + "}"
+	case extra == "":
+		return "{" + labels + "}"
+	}
+	return "{" + labels + "," + extra + "}"
+}
+
+//writeMetrics writes every metric in the prometheus text exposition format
+func (handle *prometheusHandleT) writeMetrics(writer *bufio.Writer) {
+	handle.metricsLock.RLock()
+	metrics := make([]*prometheusMetricT, 0, len(handle.metrics))
+	for _, metric := range handle.metrics {
+		metrics = append(metrics, metric)
+	}
+	handle.metricsLock.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	for _, metric := range metrics {
+		metric.seriesLock.RLock()
+		seriesList := make([]*prometheusSeriesT, 0, len(metric.series))
+		for _, series := range metric.series {
+			seriesList = append(seriesList, series)
+		}
+		metric.seriesLock.RUnlock()
+		sort.Slice(seriesList, func(i, j int) bool { return seriesList[i].labels < seriesList[j].labels })
+
+		switch metric.statType {
+		case CountType:
+			fmt.Fprintf(writer, "# TYPE %s counter\n", metric.name)
+		case GaugeType:
+			fmt.Fprintf(writer, "# TYPE %s gauge\n", metric.name)
+		case TimerType:
+			fmt.Fprintf(writer, "# TYPE %s histogram\n", metric.name)
+		default:
+			fmt.Fprintf(writer, "# TYPE %s untyped\n", metric.name)
+		}
+		for _, series := range seriesList {
+			series.lock.Lock()
+			if metric.statType != TimerType {
+				fmt.Fprintf(writer, "%s%s %s\n", metric.name, joinLabels(series.labels, ""), formatFloat(series.value))
+				series.lock.Unlock()
+				continue
+			}
+			for i, bound := range handle.buckets {
+				fmt.Fprintf(writer, "%s_bucket%s %d\n", metric.name, joinLabels(series.labels, fmt.Sprintf(`le="%s"`, formatFloat(bound))), series.bucketCounts[i])
+			}
+			fmt.Fprintf(writer, "%s_bucket%s %d\n", metric.name, joinLabels(series.labels, `le="+Inf"`), series.count)
+			fmt.Fprintf(writer, "%s_sum%s %s\n", metric.name, joinLabels(series.labels, ""), formatFloat(series.sum))
+			fmt.Fprintf(writer, "%s_count%s %d\n", metric.name, joinLabels(series.labels, ""), series.count)
+			series.lock.Unlock()
+		}
+	}
+}
+
+//MetricsHandler serves the stats in the prometheus text format when statsBackend is prometheus
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if prometheusHandle == nil {
+		http.Error(w, "Prometheus stats backend is not enabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writer := bufio.NewWriter(w)
+	prometheusHandle.writeMetrics(writer)
+	writer.Flush()
+}
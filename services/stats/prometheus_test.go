@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+var _ = Describe("Prometheus", func() {
+	var handle *prometheusHandleT
+
+	BeforeEach(func() {
+		pkgLogger = logger.NewLogger().Child("stats")
+		handle = newPrometheusHandle([]float64{0.1, 1}, 2, Tags{"instanceName": "test"})
+	})
+
+	scrape := func() string {
+		var buffer bytes.Buffer
+		writer := bufio.NewWriter(&buffer)
+		handle.writeMetrics(writer)
+		writer.Flush()
+		return buffer.String()
+	}
+
+	It("should expose counters and gauges with tags as labels", func() {
+		handle.NewTaggedStat("router.delivered", CountType, Tags{"destType": "WEBHOOK"}).Count(3)
+		handle.NewTaggedStat("router.delivered", CountType, Tags{"destType": "WEBHOOK"}).Increment()
+		handle.NewStat("gw.pending", GaugeType).Gauge(uint64(7))
+
+		metrics := scrape()
+		Expect(metrics).To(ContainSubstring("# TYPE gw_pending gauge\ngw_pending{instanceName=\"test\"} 7\n"))
+		Expect(metrics).To(ContainSubstring("# TYPE router_delivered counter\nrouter_delivered{destType=\"WEBHOOK\",instanceName=\"test\"} 4\n"))
+	})
+
+	It("should expose timers as histograms in seconds", func() {
+		stat := handle.NewTaggedStat("processor.transform_time", TimerType, Tags{"stage": "dest"})
+		stat.SendTiming(50 * time.Millisecond)
+		stat.SendTiming(500 * time.Millisecond)
+		stat.SendTiming(2 * time.Second)
+
+		metrics := scrape()
+		Expect(metrics).To(ContainSubstring("# TYPE processor_transform_time_seconds histogram\n"))
+		Expect(metrics).To(ContainSubstring(`processor_transform_time_seconds_bucket{instanceName="test",stage="dest",le="0.1"} 1` + "\n"))
+		Expect(metrics).To(ContainSubstring(`processor_transform_time_seconds_bucket{instanceName="test",stage="dest",le="1"} 2` + "\n"))
+		Expect(metrics).To(ContainSubstring(`processor_transform_time_seconds_bucket{instanceName="test",stage="dest",le="+Inf"} 3` + "\n"))
+		Expect(metrics).To(ContainSubstring(`processor_transform_time_seconds_sum{instanceName="test",stage="dest"} 2.55` + "\n"))
+		Expect(metrics).To(ContainSubstring(`processor_transform_time_seconds_count{instanceName="test",stage="dest"} 3` + "\n"))
+	})
+
+	It("should aggregate the label sets past the limit in an overflow series", func() {
+		for _, userID := range []string{"u1", "u2", "u3", "u4"} {
+			handle.NewTaggedStat("events", CountType, Tags{"userId": userID}).Increment()
+		}
+
+		metrics := scrape()
+		Expect(strings.Count(metrics, "events{")).To(Equal(3))
+		Expect(metrics).To(ContainSubstring(`events{instanceName="test",stats_overflow="true"} 2` + "\n"))
+	})
+
+	It("should sanitize names and escape label values", func() {
+		handle.NewTaggedStat("jobsdb.gw-tables", GaugeType, Tags{"custom:val": `a"b\c`}).Gauge(1.5)
+		Expect(scrape()).To(ContainSubstring(`jobsdb_gw_tables{custom_val="a\"b\\c",instanceName="test"} 1.5` + "\n"))
+	})
+
+	It("should give stats of the same name with another type a metric of their own", func() {
+		handle.NewStat("jobs", CountType).Increment()
+		handle.NewStat("jobs", GaugeType).Gauge(2)
+
+		metrics := scrape()
+		Expect(metrics).To(ContainSubstring("# TYPE jobs counter\n"))
+		Expect(metrics).To(ContainSubstring("# TYPE jobs_gauge gauge\n"))
+	})
+
+	It("should parse the timer buckets", func() {
+		buckets, err := parseBuckets("1, 0.5,10")
+		Expect(err).To(BeNil())
+		Expect(buckets).To(Equal([]float64{0.5, 1, 10}))
+		_, err = parseBuckets("1,a")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should serve the metrics only when the prometheus backend is enabled", func() {
+		prometheusHandle = nil
+		recorder := httptest.NewRecorder()
+		MetricsHandler(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+
+		prometheusHandle = handle
+		defer func() { prometheusHandle = nil }()
+		handle.NewStat("events", CountType).Increment()
+		recorder = httptest.NewRecorder()
+		MetricsHandler(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		Expect(recorder.Body.String()).To(ContainSubstring(`events{instanceName="test"} 1`))
+	})
+})
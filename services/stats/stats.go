@@ -34,6 +34,10 @@ var enableGCStats bool
 var rc runtimeStatsCollector
 var pkgLogger logger.LoggerI
 var statsSamplingRate float32
+var statsBackend string
+var prometheusTimerBuckets string
+var prometheusMaxLabelSets int
+var prometheusHandle *prometheusHandleT
 
 // DefaultStats is a common implementation of StatsD stats managements
 var DefaultStats Stats
@@ -49,6 +53,9 @@ func init() {
 	config.RegisterBoolConfigVariable(true, &enableMemStats, false, "RuntimeStats.enabledMemStats")
 	config.RegisterBoolConfigVariable(true, &enableGCStats, false, "RuntimeStats.enableGCStats")
 	statsSamplingRate = float32(config.GetFloat64("statsSamplingRate", 1))
+	config.RegisterStringConfigVariable(StatsDBackend, &statsBackend, false, "statsBackend")
+	config.RegisterStringConfigVariable(defaultTimerBuckets, &prometheusTimerBuckets, false, "Prometheus.timerBuckets")
+	config.RegisterIntConfigVariable(1000, &prometheusMaxLabelSets, false, 1, "Prometheus.maxLabelSets")
 
 	pkgLogger = logger.NewLogger().Child("stats")
 
@@ -90,7 +97,7 @@ type RudderStatsT struct {
 	dontProcess bool
 }
 
-//Setup creates a new statsd client
+//Setup creates a new statsd client, or the prometheus registry served by MetricsHandler if statsBackend is prometheus
 func Setup() {
 	DefaultStats = &HandleT{}
 
@@ -98,6 +105,11 @@ func Setup() {
 		return
 	}
 
+	if statsBackend == PrometheusBackend {
+		setupPrometheus()
+		return
+	}
+
 	var err error
 	conn = statsd.Address(statsdServerURL)
 	//TODO: Add tags by calling a function...
@@ -110,11 +122,28 @@ func Setup() {
 	}
 	if client != nil {
 		rruntime.Go(func() {
-			collectRuntimeStats(client)
+			collectRuntimeStats(func(key string, val uint64) {
+				client.Gauge("runtime_"+key, val)
+			})
 		})
 	}
 }
 
+func setupPrometheus() {
+	buckets, err := parseBuckets(prometheusTimerBuckets)
+	if err != nil {
+		pkgLogger.Errorf("Invalid Prometheus.timerBuckets %q, using the default buckets: %v", prometheusTimerBuckets, err)
+		buckets, _ = parseBuckets(defaultTimerBuckets)
+	}
+	prometheusHandle = newPrometheusHandle(buckets, prometheusMaxLabelSets, prometheusDefaultLabels())
+	DefaultStats = prometheusHandle
+	rruntime.Go(func() {
+		collectRuntimeStats(func(key string, val uint64) {
+			prometheusHandle.NewStat("runtime_"+key, GaugeType).Gauge(val)
+		})
+	})
+}
+
 // NewStat creates a new RudderStats with provided Name and Type
 func (s *HandleT) NewStat(Name string, StatType string) (rStats RudderStats) {
 	return &RudderStatsT{
@@ -261,10 +290,7 @@ func (rStats *RudderStatsT) SendTiming(duration time.Duration) {
 	rStats.Client.Timing(rStats.Name, int(duration/time.Millisecond))
 }
 
-func collectRuntimeStats(client *statsd.Client) {
-	gaugeFunc := func(key string, val uint64) {
-		client.Gauge("runtime_"+key, val)
-	}
+func collectRuntimeStats(gaugeFunc func(key string, val uint64)) {
 	rc = newRuntimeStatsCollector(gaugeFunc)
 	rc.PauseDur = time.Duration(statsCollectionInterval) * time.Second
 	rc.EnableCPU = enableCPUStats
@@ -0,0 +1,35 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//TraceRpcHandler exposes event traces over the admin rpc interface. Arguments and results are json
+type TraceRpcHandler struct {
+	tracer TracerI
+}
+
+//Trace returns the trace of the events matching the RequestT in arg
+func (h *TraceRpcHandler) Trace(arg string, result *string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pkgLogger.Error(r)
+			err = fmt.Errorf("Internal Rudder Server Error. Error: %v", r)
+		}
+	}()
+	var request RequestT
+	if err = json.Unmarshal([]byte(arg), &request); err != nil {
+		return err
+	}
+	trace, err := h.tracer.Trace(request)
+	if err != nil {
+		return err
+	}
+	response, err := json.MarshalIndent(trace, "", " ")
+	if err != nil {
+		return err
+	}
+	*result = string(response)
+	return nil
+}
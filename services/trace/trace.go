@@ -0,0 +1,374 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/admin"
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+	"github.com/rudderlabs/rudder-server/services/dedup"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+//Stages an event is delivered from
+const (
+	PROCESSOR_STAGE    = "processor"
+	ROUTER_STAGE       = "router"
+	BATCH_ROUTER_STAGE = "batch_router"
+)
+
+//Final states of a traced event
+const (
+	//PENDING_PROCESSING events are received by the gateway and not processed yet
+	PENDING_PROCESSING = "pending_processing"
+	//NO_DESTINATIONS events are processed without being sent to any destination
+	NO_DESTINATIONS = "no_destinations"
+	//PENDING_DELIVERY events are yet to be delivered to some of their destinations
+	PENDING_DELIVERY = "pending_delivery"
+	//ABORTED events failed to be delivered to some of their destinations for good
+	ABORTED = "aborted"
+	//DELIVERED events are delivered to all of their destinations
+	DELIVERED = "delivered"
+)
+
+var (
+	enableDedup   bool
+	maxEvents     int
+	maxJobs       int
+	defaultWindow time.Duration
+	pkgLogger     logger.LoggerI
+)
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("trace")
+}
+
+func loadConfig() {
+	config.RegisterBoolConfigVariable(false, &enableDedup, false, "Dedup.enableDedup")
+	config.RegisterIntConfigVariable(100, &maxEvents, true, 1, "Trace.maxEvents")
+	config.RegisterIntConfigVariable(1000, &maxJobs, true, 1, "Trace.maxJobs")
+	config.RegisterDurationConfigVariable(24, &defaultWindow, true, time.Hour, "Trace.defaultWindow")
+}
+
+//RequestT traces the events with MessageID, UserID or AnonymousID received by the gateway between From and To.
+//To defaults to the time of the request and From to Trace.defaultWindow before To
+type RequestT struct {
+	SourceID    string    `json:"sourceId"`
+	MessageID   string    `json:"messageId"`
+	UserID      string    `json:"userId"`
+	AnonymousID string    `json:"anonymousId"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+}
+
+//TraceT is the lifecycle of every traced event, in the order they are received
+type TraceT struct {
+	Events []*EventTraceT `json:"events"`
+}
+
+//EventTraceT is the lifecycle of an event from its receipt at the gateway to its delivery to every destination
+type EventTraceT struct {
+	MessageID    string       `json:"messageId"`
+	Receipts     []*ReceiptT  `json:"receipts"`
+	Deliveries   []*DeliveryT `json:"deliveries"`
+	FinalState   string       `json:"finalState"`
+	gatewayState string
+}
+
+/*
+ReceiptT is a receipt of the event by the gateway. Duplicate is inferred for receipts within the dedup window of an earlier receipt,
+when dedup is enabled
+*/
+type ReceiptT struct {
+	JobID      int64               `json:"jobId"`
+	SourceID   string              `json:"sourceId"`
+	ReceivedAt time.Time           `json:"receivedAt"`
+	Event      json.RawMessage     `json:"event"`
+	Duplicate  bool                `json:"duplicate"`
+	Statuses   []jobsdb.JobStatusT `json:"statuses"`
+}
+
+/*
+DeliveryT is the delivery of the event to a destination. Payload is the output of its transformation for the destination,
+or the events failing it for deliveries aborted by the processor
+*/
+type DeliveryT struct {
+	JobID         int64           `json:"jobId"`
+	Stage         string          `json:"stage"`
+	DestinationID string          `json:"destinationId"`
+	DestType      string          `json:"destType"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      []AttemptT      `json:"attempts"`
+	FinalState    string          `json:"finalState"`
+}
+
+//AttemptT is a status the delivery of an event went through, with the response of the destination
+type AttemptT struct {
+	State      string          `json:"state"`
+	Attempt    int             `json:"attempt"`
+	ExecTime   time.Time       `json:"execTime"`
+	StatusCode string          `json:"statusCode"`
+	Response   json.RawMessage `json:"response"`
+}
+
+//TracerI traces events across the gateway, processor and routers
+type TracerI interface {
+	Trace(request RequestT) (TraceT, error)
+}
+
+//HandleT traces events over the readonly jobsdbs of every stage
+type HandleT struct {
+	gatewayDB     jobsdb.ReadonlyJobsDB
+	routerDB      jobsdb.ReadonlyJobsDB
+	batchRouterDB jobsdb.ReadonlyJobsDB
+	procErrorDB   jobsdb.ReadonlyJobsDB
+	logger        logger.LoggerI
+}
+
+//Setup sets the readonly jobsdbs to trace events over and registers the admin handler
+func (tracer *HandleT) Setup(gatewayDB, routerDB, batchRouterDB, procErrorDB jobsdb.ReadonlyJobsDB) {
+	tracer.logger = pkgLogger
+	tracer.gatewayDB = gatewayDB
+	tracer.routerDB = routerDB
+	tracer.batchRouterDB = batchRouterDB
+	tracer.procErrorDB = procErrorDB
+	admin.RegisterAdminHandler("Trace", &TraceRpcHandler{tracer: tracer})
+}
+
+//Trace returns the lifecycle of the events matching request, at most Trace.maxEvents of them
+func (tracer *HandleT) Trace(request RequestT) (TraceT, error) {
+	if request.MessageID == "" && request.UserID == "" && request.AnonymousID == "" {
+		return TraceT{}, errors.New("a messageId, userId or anonymousId is required")
+	}
+	if request.To.IsZero() {
+		request.To = time.Now()
+	}
+	if request.From.IsZero() {
+		request.From = request.To.Add(-defaultWindow)
+	}
+	if !request.From.Before(request.To) {
+		return TraceT{}, errors.New("from is not before to")
+	}
+
+	filter := jobsdb.TraceFilterT{SourceID: request.SourceID, UserID: request.UserID, AnonymousID: request.AnonymousID, From: request.From, To: request.To, Limit: maxJobs}
+	if request.MessageID != "" {
+		filter.MessageIDs = []string{request.MessageID}
+	}
+	gatewayJobs, err := tracer.gatewayDB.GetTracedJobs(filter)
+	if err != nil {
+		return TraceT{}, err
+	}
+	events := eventsFromGatewayJobs(gatewayJobs, &filter)
+	if len(events.list) == 0 && request.MessageID != "" {
+		//the gateway datasets of the event may be dropped already, its deliveries are traced nonetheless
+		events.get(request.MessageID)
+	}
+	if len(events.list) == 0 {
+		return TraceT{Events: events.list}, nil
+	}
+
+	//deliveries happen after the receipts, up to now
+	deliveryFilter := jobsdb.TraceFilterT{SourceID: request.SourceID, MessageIDs: events.messageIDs(), From: request.From, To: time.Now(), Limit: maxJobs}
+	routerJobs, err := tracer.routerDB.GetTracedJobs(deliveryFilter)
+	if err != nil {
+		return TraceT{}, err
+	}
+	events.addDeliveries(ROUTER_STAGE, routerJobs)
+	batchRouterJobs, err := tracer.batchRouterDB.GetTracedJobs(deliveryFilter)
+	if err != nil {
+		return TraceT{}, err
+	}
+	events.addDeliveries(BATCH_ROUTER_STAGE, batchRouterJobs)
+	failedJobs, err := tracer.procErrorDB.GetTracedJobs(deliveryFilter)
+	if err != nil {
+		return TraceT{}, err
+	}
+	events.addFailedDeliveries(failedJobs)
+
+	for _, event := range events.list {
+		event.FinalState = finalState(event)
+	}
+	return TraceT{Events: events.list}, nil
+}
+
+//eventTracesT keeps event traces in the order their events are first seen
+type eventTracesT struct {
+	list        []*EventTraceT
+	byMessageID map[string]*EventTraceT
+}
+
+func (events *eventTracesT) get(messageID string) *EventTraceT {
+	if event, ok := events.byMessageID[messageID]; ok {
+		return event
+	}
+	event := &EventTraceT{MessageID: messageID, Receipts: make([]*ReceiptT, 0), Deliveries: make([]*DeliveryT, 0)}
+	events.list = append(events.list, event)
+	events.byMessageID[messageID] = event
+	return event
+}
+
+func (events *eventTracesT) messageIDs() []string {
+	messageIDs := make([]string, 0, len(events.list))
+	for _, event := range events.list {
+		messageIDs = append(messageIDs, event.MessageID)
+	}
+	return messageIDs
+}
+
+//eventsFromGatewayJobs returns the traces of the events of gatewayJobs matching filter, with their receipts
+func eventsFromGatewayJobs(gatewayJobs []*jobsdb.TracedJobT, filter *jobsdb.TraceFilterT) *eventTracesT {
+	events := &eventTracesT{list: make([]*EventTraceT, 0), byMessageID: make(map[string]*EventTraceT)}
+	for _, job := range gatewayJobs {
+		sourceID := gjson.GetBytes(job.Parameters, "source_id").String()
+		gatewayState := job.LastJobStatus.JobState
+		for _, event := range jobsdb.PayloadEvents("gw", job.EventPayload) {
+			if !filter.MatchesEvent(event) {
+				continue
+			}
+			messageID := event.Get("messageId").String()
+			if _, ok := events.byMessageID[messageID]; !ok && len(events.list) >= maxEvents {
+				continue
+			}
+			trace := events.get(messageID)
+			receipt := &ReceiptT{
+				JobID:      job.JobID,
+				SourceID:   sourceID,
+				ReceivedAt: job.CreatedAt,
+				Event:      json.RawMessage(event.Raw),
+				Statuses:   job.Statuses,
+			}
+			if enableDedup && len(trace.Receipts) > 0 {
+				first := trace.Receipts[0]
+				receipt.Duplicate = receipt.ReceivedAt.Sub(first.ReceivedAt) < dedup.WindowForSource(sourceID)
+			}
+			if !receipt.Duplicate {
+				trace.gatewayState = gatewayState
+			}
+			trace.Receipts = append(trace.Receipts, receipt)
+		}
+	}
+	return events
+}
+
+func attempts(statuses []jobsdb.JobStatusT) []AttemptT {
+	attempts := make([]AttemptT, 0, len(statuses))
+	for _, status := range statuses {
+		attempts = append(attempts, AttemptT{
+			State:      status.JobState,
+			Attempt:    status.AttemptNum,
+			ExecTime:   status.ExecTime,
+			StatusCode: status.ErrorCode,
+			Response:   status.ErrorResponse,
+		})
+	}
+	return attempts
+}
+
+//addDeliveries adds the router or batch router jobs of the traced events as their deliveries
+func (events *eventTracesT) addDeliveries(stage string, jobs []*jobsdb.TracedJobT) {
+	for _, job := range jobs {
+		event, ok := events.byMessageID[gjson.GetBytes(job.Parameters, "message_id").String()]
+		if !ok {
+			continue
+		}
+		finalState := job.LastJobStatus.JobState
+		if finalState == "" {
+			finalState = jobsdb.NotProcessed.State
+		}
+		event.Deliveries = append(event.Deliveries, &DeliveryT{
+			JobID:         job.JobID,
+			Stage:         stage,
+			DestinationID: gjson.GetBytes(job.Parameters, "destination_id").String(),
+			DestType:      job.CustomVal,
+			Payload:       job.EventPayload,
+			Attempts:      attempts(job.Statuses),
+			FinalState:    finalState,
+		})
+	}
+}
+
+/*
+addFailedDeliveries adds the processor error jobs of the traced events as their aborted deliveries.
+Jobs aborted by the routers replace the deliveries of the router jobs they were moved from, keeping their attempts
+*/
+func (events *eventTracesT) addFailedDeliveries(jobs []*jobsdb.TracedJobT) {
+	for _, job := range jobs {
+		stage := gjson.GetBytes(job.Parameters, "stage").String()
+		destinationID := gjson.GetBytes(job.Parameters, "destination_id").String()
+		attempt := AttemptT{
+			State:      jobsdb.Aborted.State,
+			ExecTime:   job.CreatedAt,
+			StatusCode: gjson.GetBytes(job.Parameters, "status_code").String(),
+			Response:   json.RawMessage(gjson.GetBytes(job.Parameters, "error").Raw),
+		}
+		if stage == ROUTER_STAGE || stage == BATCH_ROUTER_STAGE {
+			event, ok := events.byMessageID[gjson.GetBytes(job.Parameters, "message_id").String()]
+			if !ok {
+				continue
+			}
+			delivery := event.delivery(stage, destinationID)
+			if delivery == nil {
+				delivery = &DeliveryT{JobID: job.JobID, Stage: stage, DestinationID: destinationID, DestType: job.CustomVal, Payload: job.EventPayload, Attempts: make([]AttemptT, 0)}
+				event.Deliveries = append(event.Deliveries, delivery)
+			}
+			attempt.StatusCode = gjson.GetBytes(job.Parameters, "error_code").String()
+			attempt.Response = json.RawMessage(gjson.GetBytes(job.Parameters, "error_response").Raw)
+			delivery.Attempts = append(delivery.Attempts, attempt)
+			delivery.FinalState = jobsdb.Aborted.State
+			continue
+		}
+
+		//the events of a processor error job all failed transformation for its destination
+		for _, failedEvent := range jobsdb.PayloadEvents("proc_error", job.EventPayload) {
+			event, ok := events.byMessageID[failedEvent.Get("messageId").String()]
+			if !ok {
+				continue
+			}
+			event.Deliveries = append(event.Deliveries, &DeliveryT{
+				JobID:         job.JobID,
+				Stage:         PROCESSOR_STAGE,
+				DestinationID: destinationID,
+				DestType:      job.CustomVal,
+				Payload:       json.RawMessage(failedEvent.Raw),
+				Attempts:      []AttemptT{attempt},
+				FinalState:    jobsdb.Aborted.State,
+			})
+		}
+	}
+}
+
+func (event *EventTraceT) delivery(stage, destinationID string) *DeliveryT {
+	for _, delivery := range event.Deliveries {
+		if delivery.Stage == stage && delivery.DestinationID == destinationID {
+			return delivery
+		}
+	}
+	return nil
+}
+
+func finalState(event *EventTraceT) string {
+	if len(event.Deliveries) == 0 {
+		if len(event.Receipts) > 0 && event.gatewayState != jobsdb.Succeeded.State {
+			return PENDING_PROCESSING
+		}
+		return NO_DESTINATIONS
+	}
+	finalState := DELIVERED
+	for _, delivery := range event.Deliveries {
+		switch delivery.FinalState {
+		case jobsdb.Succeeded.State:
+		case jobsdb.Aborted.State:
+			finalState = ABORTED
+		default:
+			if finalState == DELIVERED {
+				finalState = PENDING_DELIVERY
+			}
+		}
+	}
+	return finalState
+}
@@ -0,0 +1,107 @@
+package trace
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+//readonlyDBMockT returns its jobs for any filter, recording the filters
+type readonlyDBMockT struct {
+	jobsdb.ReadonlyJobsDB
+	jobs    []*jobsdb.TracedJobT
+	filters []jobsdb.TraceFilterT
+}
+
+func (db *readonlyDBMockT) GetTracedJobs(filter jobsdb.TraceFilterT) ([]*jobsdb.TracedJobT, error) {
+	db.filters = append(db.filters, filter)
+	return db.jobs, nil
+}
+
+func tracedJob(jobID int64, createdAt time.Time, customVal, parameters, payload string, states ...string) *jobsdb.TracedJobT {
+	job := &jobsdb.TracedJobT{JobT: jobsdb.JobT{JobID: jobID, CreatedAt: createdAt, CustomVal: customVal, Parameters: []byte(parameters), EventPayload: []byte(payload)}}
+	for i, state := range states {
+		status := jobsdb.JobStatusT{JobID: jobID, JobState: state, AttemptNum: i + 1, ErrorCode: "500", ErrorResponse: []byte(`{}`)}
+		job.Statuses = append(job.Statuses, status)
+		job.LastJobStatus = status
+	}
+	return job
+}
+
+var _ = Describe("Trace", func() {
+	var (
+		tracer                                          *HandleT
+		gatewayDB, routerDB, batchRouterDB, procErrorDB *readonlyDBMockT
+		receivedAt                                      = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		gatewayDB, routerDB, batchRouterDB, procErrorDB = &readonlyDBMockT{}, &readonlyDBMockT{}, &readonlyDBMockT{}, &readonlyDBMockT{}
+		tracer = &HandleT{gatewayDB: gatewayDB, routerDB: routerDB, batchRouterDB: batchRouterDB, procErrorDB: procErrorDB, logger: pkgLogger}
+		enableDedup = true
+	})
+
+	It("should require an event id", func() {
+		_, err := tracer.Trace(RequestT{})
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should trace events of a user from their receipt to their deliveries", func() {
+		gatewayDB.jobs = []*jobsdb.TracedJobT{
+			tracedJob(1, receivedAt, "GW", `{"source_id":"s1"}`, `{"batch":[{"messageId":"m1","userId":"u1"},{"messageId":"m2","userId":"u2"}]}`, jobsdb.Succeeded.State),
+			tracedJob(2, receivedAt.Add(time.Minute), "GW", `{"source_id":"s1"}`, `{"batch":[{"messageId":"m1","userId":"u1"}]}`, jobsdb.Succeeded.State),
+			tracedJob(3, receivedAt.Add(time.Minute), "GW", `{"source_id":"s1"}`, `{"batch":[{"messageId":"m3","userId":"u1"}]}`),
+		}
+		routerDB.jobs = []*jobsdb.TracedJobT{
+			tracedJob(10, receivedAt, "WEBHOOK", `{"source_id":"s1","destination_id":"d1","message_id":"m1"}`, `{"out":1}`, jobsdb.Failed.State, jobsdb.Succeeded.State),
+			tracedJob(11, receivedAt, "WEBHOOK", `{"source_id":"s1","destination_id":"d2","message_id":"m1"}`, `{"out":2}`, jobsdb.Failed.State),
+		}
+		procErrorDB.jobs = []*jobsdb.TracedJobT{
+			tracedJob(20, receivedAt, "WEBHOOK", `{"source_id":"s1","destination_id":"d2","message_id":"m1","stage":"router","error_code":"400","error_response":{"e":1}}`, `{"out":2}`),
+			tracedJob(21, receivedAt, "AM", `{"source_id":"s1","destination_id":"d3","stage":"dest_transformer","status_code":400,"error":"bad"}`, `[{"messageId":"m1"},{"messageId":"m9"}]`),
+		}
+
+		trace, err := tracer.Trace(RequestT{SourceID: "s1", UserID: "u1", From: receivedAt.Add(-time.Hour), To: receivedAt.Add(time.Hour)})
+		Expect(err).To(BeNil())
+		Expect(gatewayDB.filters[0].UserID).To(Equal("u1"))
+		Expect(routerDB.filters[0].MessageIDs).To(Equal([]string{"m1", "m3"}))
+
+		Expect(trace.Events).To(HaveLen(2))
+		event := trace.Events[0]
+		Expect(event.MessageID).To(Equal("m1"))
+		Expect(event.Receipts).To(HaveLen(2))
+		Expect(event.Receipts[0].Duplicate).To(BeFalse())
+		Expect(event.Receipts[1].Duplicate).To(BeTrue())
+		Expect(string(event.Receipts[0].Event)).To(Equal(`{"messageId":"m1","userId":"u1"}`))
+
+		Expect(event.Deliveries).To(HaveLen(3))
+		Expect(event.Deliveries[0].DestinationID).To(Equal("d1"))
+		Expect(event.Deliveries[0].Attempts).To(HaveLen(2))
+		Expect(event.Deliveries[0].FinalState).To(Equal(jobsdb.Succeeded.State))
+		Expect(event.Deliveries[1].DestinationID).To(Equal("d2"))
+		Expect(event.Deliveries[1].Attempts).To(HaveLen(2))
+		Expect(event.Deliveries[1].Attempts[1].StatusCode).To(Equal("400"))
+		Expect(event.Deliveries[1].FinalState).To(Equal(jobsdb.Aborted.State))
+		Expect(event.Deliveries[2].Stage).To(Equal(PROCESSOR_STAGE))
+		Expect(string(event.Deliveries[2].Payload)).To(Equal(`{"messageId":"m1"}`))
+		Expect(event.FinalState).To(Equal(ABORTED))
+
+		Expect(trace.Events[1].MessageID).To(Equal("m3"))
+		Expect(trace.Events[1].FinalState).To(Equal(PENDING_PROCESSING))
+	})
+
+	It("should trace the deliveries of events whose gateway jobs are dropped", func() {
+		batchRouterDB.jobs = []*jobsdb.TracedJobT{
+			tracedJob(10, receivedAt, "S3", `{"source_id":"s1","destination_id":"d1","message_id":"m1"}`, `{}`, jobsdb.Executing.State),
+		}
+		trace, err := tracer.Trace(RequestT{MessageID: "m1"})
+		Expect(err).To(BeNil())
+		Expect(trace.Events).To(HaveLen(1))
+		Expect(trace.Events[0].Receipts).To(BeEmpty())
+		Expect(trace.Events[0].Deliveries[0].Stage).To(Equal(BATCH_ROUTER_STAGE))
+		Expect(trace.Events[0].FinalState).To(Equal(PENDING_DELIVERY))
+	})
+})
@@ -0,0 +1,276 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+//TRACEPARENT is the http header and job parameter carrying the w3c trace context of a request across the pipeline
+const TRACEPARENT = "traceparent"
+
+var (
+	enabled            bool
+	samplingRate       float64
+	otlpEndpoint       string
+	serviceName        string
+	exportInterval     time.Duration
+	maxExportBatchSize int
+	maxQueueSize       int
+	maxLinks           int
+	shutdownTimeout    time.Duration
+	pkgLogger          logger.LoggerI
+
+	tracer         = trace.NewNoopTracerProvider().Tracer("")
+	tracerProvider *sdktrace.TracerProvider
+	propagator     = propagation.TraceContext{}
+)
+
+//Span is a span of a trace. Spans started while tracing is disabled are non-recording spans, on which every method is a no-op
+type Span = trace.Span
+
+//Attribute is a key value pair describing a span
+type Attribute = attribute.KeyValue
+
+//Attribute helpers, re-exported so that instrumented packages only depend on tracing
+var (
+	StringAttribute = attribute.String
+	Int64Attribute  = attribute.Int64
+	BoolAttribute   = attribute.Bool
+)
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("tracing")
+}
+
+func loadConfig() {
+	config.RegisterBoolConfigVariable(false, &enabled, false, "Tracing.enabled")
+	config.RegisterFloat64ConfigVariable(0.01, &samplingRate, false, "Tracing.samplingRate")
+	config.RegisterStringConfigVariable("http://localhost:4318/v1/traces", &otlpEndpoint, false, "Tracing.otlpEndpoint")
+	config.RegisterStringConfigVariable("rudder-server", &serviceName, false, "Tracing.serviceName")
+	config.RegisterDurationConfigVariable(time.Duration(5), &exportInterval, false, time.Second, "Tracing.exportInterval")
+	config.RegisterIntConfigVariable(512, &maxExportBatchSize, false, 1, "Tracing.maxExportBatchSize")
+	config.RegisterIntConfigVariable(10000, &maxQueueSize, false, 1, "Tracing.maxQueueSize")
+	config.RegisterIntConfigVariable(32, &maxLinks, false, 1, "Tracing.maxLinks")
+	config.RegisterDurationConfigVariable(time.Duration(5), &shutdownTimeout, false, time.Second, "Tracing.shutdownTimeout")
+}
+
+//Setup samples traces with Tracing.samplingRate and exports the sampled spans to the OTLP/HTTP collector at Tracing.otlpEndpoint
+func Setup() {
+	if !enabled {
+		return
+	}
+	exporter, err := newExporter(otlpEndpoint)
+	if err != nil {
+		panic(err)
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(newSampler(samplingRate)),
+		sdktrace.WithBatcher(exporter,
+			sdktrace.WithBatchTimeout(exportInterval),
+			sdktrace.WithMaxExportBatchSize(maxExportBatchSize),
+			sdktrace.WithMaxQueueSize(maxQueueSize)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceInstanceIDKey.String(config.GetEnv("INSTANCE_ID", "")))),
+	)
+	tracer = tracerProvider.Tracer("github.com/rudderlabs/rudder-server")
+	pkgLogger.Infof("Exporting %v of the traces to %s", samplingRate, otlpEndpoint)
+}
+
+//Shutdown exports the spans which are still queued, waiting at most Tracing.shutdownTimeout
+func Shutdown() {
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		pkgLogger.Errorf("Failed to export the queued spans: %v", err)
+	}
+}
+
+/*
+newSampler samples traces started here and traces continued from a remote traceparent with rate, whatever the sampled flag
+of the traceparent, as clients of the public endpoints could otherwise force every trace to be sampled. The ratio is decided on the trace id,
+so the gateway, processor and router sample the same traces. Children of local spans follow their parent
+*/
+func newSampler(rate float64) sdktrace.Sampler {
+	ratio := sdktrace.TraceIDRatioBased(rate)
+	return sdktrace.ParentBased(ratio, sdktrace.WithRemoteParentSampled(ratio), sdktrace.WithRemoteParentNotSampled(ratio))
+}
+
+//newExporter exports spans as OTLP protobuf over http to endpoint, the url of the traces of a collector
+func newExporter(endpoint string) (*otlptrace.Exporter, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpointURL.Host)}
+	if endpointURL.Path != "" {
+		options = append(options, otlptracehttp.WithURLPath(endpointURL.Path))
+	}
+	if endpointURL.Scheme != "https" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+//StartSpan starts a span as a child of the span in ctx, if any. It returns a non-recording span when tracing is disabled
+func StartSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	if !enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+//StartClientSpan starts a span for a call made to another service, as a child of the span in ctx, if any
+func StartClientSpan(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	return StartLinkedClientSpan(ctx, name, nil, attributes...)
+}
+
+//StartLinkedClientSpan starts a span for a call made to another service handling the requests of several traces, linked to the spans of the traceparents
+func StartLinkedClientSpan(ctx context.Context, name string, traceparents []string, attributes ...Attribute) (context.Context, Span) {
+	if !enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	parent := trace.SpanContextFromContext(ctx)
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...),
+		trace.WithLinks(links(parent.TraceID(), traceparents)...))
+}
+
+//StartSpanFromParameters starts a span continuing the trace in the traceparent of the job parameters, or a new trace if they have none
+func StartSpanFromParameters(ctx context.Context, name string, parameters []byte, attributes ...Attribute) (context.Context, Span) {
+	if !enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return StartSpanFromTraceparent(ctx, name, gjson.GetBytes(parameters, TRACEPARENT).String(), attributes...)
+}
+
+//StartSpanFromTraceparent starts a span continuing the trace of traceparent, or a new trace if traceparent isn't valid
+func StartSpanFromTraceparent(ctx context.Context, name string, traceparent string, attributes ...Attribute) (context.Context, Span) {
+	return StartSpanFromTraceparents(ctx, name, []string{traceparent}, attributes...)
+}
+
+/*
+StartSpanFromTraceparents starts a span handling the jobs of several traces. It continues the trace of the first traceparent,
+or a new trace if it isn't valid, and links to the spans of the other traceparents. Links past Tracing.maxLinks are dropped
+*/
+func StartSpanFromTraceparents(ctx context.Context, name string, traceparents []string, attributes ...Attribute) (context.Context, Span) {
+	if !enabled {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	var linked []string
+	if len(traceparents) > 0 {
+		if parent, ok := ParseTraceparent(traceparents[0]); ok {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+		}
+		linked = traceparents[1:]
+	}
+	parent := trace.SpanContextFromContext(ctx)
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...), trace.WithLinks(links(parent.TraceID(), linked)...))
+}
+
+//StartSpanFromRequest starts a server span continuing the trace in the traceparent header of the request, if any
+func StartSpanFromRequest(r *http.Request, name string, attributes ...Attribute) (context.Context, Span) {
+	if !enabled {
+		return r.Context(), trace.SpanFromContext(r.Context())
+	}
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	attributes = append(attributes, StringAttribute("http.method", r.Method), StringAttribute("http.target", r.URL.Path))
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+}
+
+//FromContext returns the span in ctx, a non-recording span if there is none
+func FromContext(ctx context.Context) Span {
+	return trace.SpanFromContext(ctx)
+}
+
+//Traceparent formats the context of span as a w3c traceparent. It returns an empty string for spans started while tracing is disabled
+func Traceparent(span Span) string {
+	return FormatTraceparent(span.SpanContext())
+}
+
+//FormatTraceparent formats a span context as a w3c traceparent, version-traceid-spanid-flags. It returns an empty string for invalid span contexts
+func FormatTraceparent(sc trace.SpanContext) string {
+	header := http.Header{}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), sc), propagation.HeaderCarrier(header))
+	return header.Get(TRACEPARENT)
+}
+
+//ParseTraceparent parses a w3c traceparent. It returns false if value isn't a valid traceparent
+func ParseTraceparent(value string) (trace.SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	// traceparents of version 00 have no other fields, which the propagator doesn't check
+	if strings.HasPrefix(value, "00-") && len(value) != len("00-")+32+1+16+1+2 {
+		return trace.SpanContext{}, false
+	}
+	header := http.Header{}
+	header.Set(TRACEPARENT, value)
+	ctx := propagator.Extract(context.Background(), propagation.HeaderCarrier(header))
+	sc := trace.SpanContextFromContext(ctx)
+	return sc, sc.IsValid()
+}
+
+//links returns the links to the spans of the traceparents which aren't of the trace traceID, at most Tracing.maxLinks of them
+func links(traceID trace.TraceID, traceparents []string) []trace.Link {
+	var spanLinks []trace.Link
+	linked := make(map[string]struct{})
+	for _, traceparent := range traceparents {
+		if len(spanLinks) >= maxLinks {
+			break
+		}
+		if _, ok := linked[traceparent]; ok {
+			continue
+		}
+		sc, ok := ParseTraceparent(traceparent)
+		if !ok || sc.TraceID() == traceID {
+			continue
+		}
+		linked[traceparent] = struct{}{}
+		spanLinks = append(spanLinks, trace.Link{SpanContext: sc})
+	}
+	return spanLinks
+}
+
+//SetHeader sets the traceparent header of span, if it is recording, so that the receiver can continue the trace
+func SetHeader(header http.Header, span Span) {
+	if !span.SpanContext().IsValid() {
+		return
+	}
+	propagator.Inject(trace.ContextWithSpanContext(context.Background(), span.SpanContext()), propagation.HeaderCarrier(header))
+}
+
+//SetError marks span as failed with err
+func SetError(span Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+//SetStatusCode records the http status code of the response to span, marking span as failed for codes other than 2xx
+func SetStatusCode(span Span, statusCode int) {
+	span.SetAttributes(Int64Attribute("http.status_code", int64(statusCode)))
+	if statusCode < 200 || statusCode >= 300 {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+}
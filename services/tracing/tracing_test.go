@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testTraceparent       = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	otherTestTraceparent  = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	testTraceID           = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparentParent = "00f067aa0ba902b7"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		enabled = true
+		recorder = tracetest.NewSpanRecorder()
+		tracer = sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	})
+
+	AfterEach(func() {
+		enabled = false
+		tracer = trace.NewNoopTracerProvider().Tracer("")
+	})
+
+	It("should parse and format w3c traceparents", func() {
+		sc, ok := ParseTraceparent(testTraceparent)
+		Expect(ok).To(BeTrue())
+		Expect(sc.IsSampled()).To(BeTrue())
+		Expect(FormatTraceparent(sc)).To(Equal(testTraceparent))
+
+		sc, ok = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		Expect(ok).To(BeTrue())
+		Expect(sc.IsSampled()).To(BeFalse())
+	})
+
+	It("should reject invalid traceparents", func() {
+		for _, value := range []string{
+			"",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		} {
+			_, ok := ParseTraceparent(value)
+			Expect(ok).To(BeFalse(), value)
+		}
+	})
+
+	It("should continue the trace in the parameters of a job", func() {
+		_, span := StartSpanFromParameters(context.Background(), "processor.process", []byte(`{"source_id":"s1","traceparent":"`+testTraceparent+`"}`))
+		Expect(span.IsRecording()).To(BeTrue())
+		parent, _ := ParseTraceparent(testTraceparent)
+		Expect(span.SpanContext().TraceID()).To(Equal(parent.TraceID()))
+		Expect(span.SpanContext().SpanID()).NotTo(Equal(parent.SpanID()))
+		Expect(Traceparent(span)).To(HavePrefix("00-" + testTraceID + "-"))
+
+		_, span = StartSpanFromParameters(context.Background(), "processor.process", []byte(`{"source_id":"s1"}`))
+		Expect(span.SpanContext().TraceID()).NotTo(Equal(parent.TraceID()))
+	})
+
+	It("should continue the trace of the first traceparent and link the others", func() {
+		_, span := StartSpanFromTraceparents(context.Background(), "router.deliver", []string{testTraceparent, otherTestTraceparent, testTraceparent, "invalid", otherTestTraceparent})
+		span.End()
+
+		Expect(recorder.Ended()).To(HaveLen(1))
+		ended := recorder.Ended()[0]
+		Expect(ended.Parent().TraceID().String()).To(Equal(testTraceID))
+		Expect(ended.Links()).To(HaveLen(1))
+		Expect(FormatTraceparent(ended.Links()[0].SpanContext)).To(Equal(otherTestTraceparent))
+	})
+
+	It("should continue the trace in the traceparent header of a request", func() {
+		req := httptest.NewRequest("POST", "/v1/batch", nil)
+		req.Header.Set(TRACEPARENT, testTraceparent)
+		ctx, span := StartSpanFromRequest(req, "gateway.request")
+		Expect(FromContext(ctx)).To(Equal(span))
+		Expect(span.SpanContext().TraceID().String()).To(Equal(testTraceID))
+
+		header := http.Header{}
+		SetHeader(header, span)
+		Expect(header.Get(TRACEPARENT)).To(Equal(Traceparent(span)))
+	})
+
+	It("should record errors and failed status codes", func() {
+		_, span := StartClientSpan(context.Background(), "router.send_post")
+		SetError(span, errors.New("connection refused"))
+		SetStatusCode(span, 500)
+		span.End()
+
+		ended := recorder.Ended()[0]
+		Expect(ended.SpanKind()).To(Equal(trace.SpanKindClient))
+		Expect(ended.Status().Code).To(Equal(codes.Error))
+		Expect(ended.Status().Description).To(Equal("Internal Server Error"))
+		Expect(ended.Events()).To(HaveLen(1))
+	})
+
+	It("should sample remote traceparents with the sampling rate, whatever their sampled flag", func() {
+		tracer = sdktrace.NewTracerProvider(sdktrace.WithSampler(newSampler(0)), sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+		req := httptest.NewRequest("POST", "/v1/batch", nil)
+		req.Header.Set(TRACEPARENT, testTraceparent)
+		ctx, span := StartSpanFromRequest(req, "gateway.request")
+		Expect(span.SpanContext().IsSampled()).To(BeFalse())
+		_, child := StartSpan(ctx, "gateway.store")
+		Expect(child.SpanContext().IsSampled()).To(BeFalse())
+
+		tracer = sdktrace.NewTracerProvider(sdktrace.WithSampler(newSampler(1)), sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+		_, span = StartSpanFromTraceparent(context.Background(), "processor.process", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		Expect(span.SpanContext().IsSampled()).To(BeTrue())
+	})
+
+	It("should start no spans when disabled", func() {
+		enabled = false
+		ctx := context.Background()
+		spanCtx, span := StartSpanFromParameters(ctx, "processor.process", []byte(`{"traceparent":"`+testTraceparent+`"}`))
+		Expect(span.IsRecording()).To(BeFalse())
+		Expect(spanCtx).To(Equal(ctx))
+		Expect(Traceparent(span)).To(Equal(""))
+
+		header := http.Header{}
+		SetHeader(header, span)
+		Expect(header).To(BeEmpty())
+		SetStatusCode(span, 500)
+		span.End()
+		Expect(recorder.Ended()).To(BeEmpty())
+	})
+
+	It("should export spans as OTLP protobuf to the collector", func() {
+		requests := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal("/v1/traces"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			request := &coltracepb.ExportTraceServiceRequest{}
+			Expect(proto.Unmarshal(body, request)).To(Succeed())
+			requests <- request
+		}))
+		defer server.Close()
+
+		exporter, err := newExporter(server.URL + "/v1/traces")
+		Expect(err).To(BeNil())
+		tracer = sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSyncer(exporter)).Tracer("test")
+		_, span := StartSpanFromTraceparent(context.Background(), "processor.transform", testTraceparent, StringAttribute("http.url", "http://transformer"))
+		SetStatusCode(span, 400)
+		span.End()
+
+		var request *coltracepb.ExportTraceServiceRequest
+		Eventually(requests).Should(Receive(&request))
+		exported := request.GetResourceSpans()[0].GetInstrumentationLibrarySpans()[0].GetSpans()[0]
+		Expect(exported.GetName()).To(Equal("processor.transform"))
+		Expect(hex.EncodeToString(exported.GetTraceId())).To(Equal(testTraceID))
+		Expect(hex.EncodeToString(exported.GetParentSpanId())).To(Equal(testTraceparentParent))
+		Expect(exported.GetStatus().GetCode()).To(Equal(tracepb.Status_STATUS_CODE_ERROR))
+		Expect(exported.GetAttributes()[0].GetKey()).To(Equal("http.url"))
+		Expect(exported.GetAttributes()[0].GetValue().GetStringValue()).To(Equal("http://transformer"))
+	})
+})
@@ -0,0 +1,11 @@
+---
+--- Tracking Plan Violations
+---
+
+CREATE TABLE IF NOT EXISTS tracking_plan_violations (
+		write_key VARCHAR(32) NOT NULL,
+		event_type TEXT NOT NULL,
+		event_model_identifier TEXT NOT NULL DEFAULT '',
+		violation_count BIGINT NOT NULL DEFAULT 0,
+		last_violated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (write_key, event_type, event_model_identifier));
@@ -0,0 +1,5 @@
+---
+--- Tracking Plan Violations
+---
+
+DROP TABLE IF EXISTS tracking_plan_violations;
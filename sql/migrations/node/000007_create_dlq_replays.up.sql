@@ -0,0 +1,17 @@
+---
+--- DLQ Replays
+---
+
+CREATE TABLE IF NOT EXISTS dlq_replays (
+		id TEXT PRIMARY KEY,
+		operator TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		filter JSONB NOT NULL,
+		patches JSONB NOT NULL,
+		job_ids BIGINT[] NOT NULL,
+		skipped_job_ids BIGINT[] NOT NULL,
+		router_jobs INT NOT NULL DEFAULT 0,
+		batch_router_jobs INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW());
+
+CREATE INDEX IF NOT EXISTS dlq_replays_job_ids_index ON dlq_replays USING GIN (job_ids);
@@ -0,0 +1,5 @@
+---
+--- DLQ Replays
+---
+
+DROP TABLE IF EXISTS dlq_replays;
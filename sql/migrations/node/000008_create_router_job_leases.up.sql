@@ -0,0 +1,10 @@
+---
+--- Router Job Leases
+---
+
+CREATE TABLE IF NOT EXISTS router_job_leases (
+		dest_type TEXT NOT NULL,
+		partition INT NOT NULL,
+		node_id TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (dest_type, partition));
@@ -0,0 +1,5 @@
+---
+--- Router Job Leases
+---
+
+DROP TABLE IF EXISTS router_job_leases;
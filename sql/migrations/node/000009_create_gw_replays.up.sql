@@ -0,0 +1,20 @@
+---
+--- Gateway Replays
+---
+
+CREATE TABLE IF NOT EXISTS gw_replays (
+		id TEXT PRIMARY KEY,
+		source_id TEXT NOT NULL,
+		destination_ids TEXT[] NOT NULL,
+		start_time TIMESTAMP NOT NULL,
+		end_time TIMESTAMP NOT NULL,
+		backup_keys TEXT[] NOT NULL,
+		backup_index INT NOT NULL DEFAULT 0,
+		backup_offset INT NOT NULL DEFAULT 0,
+		replayed_jobs BIGINT NOT NULL DEFAULT 0,
+		status TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW());
+
+CREATE INDEX IF NOT EXISTS gw_replays_status_index ON gw_replays (status);
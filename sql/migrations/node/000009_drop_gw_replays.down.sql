@@ -0,0 +1,5 @@
+---
+--- Gateway Replays
+---
+
+DROP TABLE IF EXISTS gw_replays;
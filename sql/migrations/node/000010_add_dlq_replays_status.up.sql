@@ -0,0 +1,8 @@
+---
+--- Adding status of DLQ replays, which claim their jobs before enqueuing them
+---
+
+ALTER TABLE dlq_replays
+		ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'succeeded';
+ALTER TABLE dlq_replays
+		ADD COLUMN IF NOT EXISTS error TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+---
+--- Dropping status of DLQ replays
+---
+
+ALTER TABLE dlq_replays
+		DROP COLUMN IF EXISTS status;
+ALTER TABLE dlq_replays
+		DROP COLUMN IF EXISTS error;
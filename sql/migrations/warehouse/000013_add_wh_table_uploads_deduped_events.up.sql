@@ -0,0 +1,3 @@
+-- wh_table_uploads --
+
+ALTER TABLE wh_table_uploads ADD COLUMN IF NOT EXISTS deduped_events BIGINT;
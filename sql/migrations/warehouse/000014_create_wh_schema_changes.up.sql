@@ -0,0 +1,20 @@
+--
+-- wh_schema_changes
+--
+
+CREATE TABLE IF NOT EXISTS wh_schema_changes (
+    id BIGSERIAL PRIMARY KEY,
+    source_id VARCHAR(64) NOT NULL,
+    destination_id VARCHAR(64) NOT NULL,
+    destination_type VARCHAR(64) NOT NULL,
+    namespace VARCHAR(64) NOT NULL,
+    table_name TEXT NOT NULL,
+    schema_diff JSONB NOT NULL,
+    status VARCHAR(64) NOT NULL,
+    wh_upload_id BIGINT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL);
+
+CREATE INDEX IF NOT EXISTS wh_schema_changes_destination_id_namespace_table_name_index ON wh_schema_changes (destination_id, namespace, table_name);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wh_schema_changes_pending_unique_index ON wh_schema_changes (destination_id, namespace, table_name) WHERE status = 'pending';
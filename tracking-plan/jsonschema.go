@@ -0,0 +1,403 @@
+package trackingplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaT is a compiled JSON Schema. It supports the validation keywords of draft-07 used in tracking plans,
+// unsupported keywords such as $ref are ignored as required for unknown keywords by the specification
+type schemaT struct {
+	alwaysValid   bool
+	alwaysInvalid bool
+
+	types []string
+	enum  []interface{}
+	cnst  *interface{}
+
+	properties           map[string]*schemaT
+	required             []string
+	additionalProperties *schemaT
+
+	items    *schemaT
+	minItems *int
+	maxItems *int
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	allOf []*schemaT
+	anyOf []*schemaT
+	oneOf []*schemaT
+	not   *schemaT
+}
+
+// schemaDefT is the json representation of a schema, booleans are handled by compileSchema
+type schemaDefT struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []interface{}              `json:"enum"`
+	Const                *json.RawMessage           `json:"const"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Format               string                     `json:"format"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	ExclusiveMinimum     *float64                   `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64                   `json:"exclusiveMaximum"`
+	AllOf                []json.RawMessage          `json:"allOf"`
+	AnyOf                []json.RawMessage          `json:"anyOf"`
+	OneOf                []json.RawMessage          `json:"oneOf"`
+	Not                  json.RawMessage            `json:"not"`
+}
+
+func compileSchema(raw json.RawMessage) (*schemaT, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	switch trimmed {
+	case "", "true", "{}":
+		return &schemaT{alwaysValid: true}, nil
+	case "false":
+		return &schemaT{alwaysInvalid: true}, nil
+	}
+
+	var def schemaDefT
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, err
+	}
+	schema := &schemaT{
+		enum:      def.Enum,
+		required:  def.Required,
+		minItems:  def.MinItems,
+		maxItems:  def.MaxItems,
+		minLength: def.MinLength,
+		maxLength: def.MaxLength,
+		format:    def.Format,
+		minimum:   def.Minimum,
+		maximum:   def.Maximum,
+
+		exclusiveMinimum: def.ExclusiveMinimum,
+		exclusiveMaximum: def.ExclusiveMaximum,
+	}
+
+	if len(def.Type) > 0 {
+		var typ string
+		if err := json.Unmarshal(def.Type, &typ); err == nil {
+			schema.types = []string{typ}
+		} else if err := json.Unmarshal(def.Type, &schema.types); err != nil {
+			return nil, fmt.Errorf("invalid type: %s", string(def.Type))
+		}
+	}
+	if def.Const != nil {
+		var cnst interface{}
+		if err := json.Unmarshal(*def.Const, &cnst); err != nil {
+			return nil, err
+		}
+		schema.cnst = &cnst
+	}
+	if def.Pattern != nil {
+		pattern, err := regexp.Compile(*def.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", *def.Pattern, err)
+		}
+		schema.pattern = pattern
+	}
+
+	var err error
+	if len(def.Properties) > 0 {
+		schema.properties = make(map[string]*schemaT, len(def.Properties))
+		for name, rawProperty := range def.Properties {
+			if schema.properties[name], err = compileSchema(rawProperty); err != nil {
+				return nil, fmt.Errorf("property %s: %v", name, err)
+			}
+		}
+	}
+	if len(def.AdditionalProperties) > 0 {
+		if schema.additionalProperties, err = compileSchema(def.AdditionalProperties); err != nil {
+			return nil, fmt.Errorf("additionalProperties: %v", err)
+		}
+	}
+	if len(def.Items) > 0 {
+		if schema.items, err = compileSchema(def.Items); err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+	}
+	if len(def.Not) > 0 {
+		if schema.not, err = compileSchema(def.Not); err != nil {
+			return nil, fmt.Errorf("not: %v", err)
+		}
+	}
+	if schema.allOf, err = compileSchemas(def.AllOf); err != nil {
+		return nil, fmt.Errorf("allOf: %v", err)
+	}
+	if schema.anyOf, err = compileSchemas(def.AnyOf); err != nil {
+		return nil, fmt.Errorf("anyOf: %v", err)
+	}
+	if schema.oneOf, err = compileSchemas(def.OneOf); err != nil {
+		return nil, fmt.Errorf("oneOf: %v", err)
+	}
+	return schema, nil
+}
+
+func compileSchemas(raws []json.RawMessage) ([]*schemaT, error) {
+	var schemas []*schemaT
+	for _, raw := range raws {
+		schema, err := compileSchema(raw)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
+
+// ViolationErrorT is a single reason for which an event doesn't match the schema of its tracking plan.
+// Field is the dot separated path of the offending value, empty for the event itself
+type ViolationErrorT struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case int, int32, int64:
+		return "integer"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func (schema *schemaT) matchesType(valueType string) bool {
+	if len(schema.types) == 0 {
+		return true
+	}
+	for _, typ := range schema.types {
+		if typ == valueType || (typ == "number" && valueType == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (schema *schemaT) isValid(value interface{}) bool {
+	return len(schema.validate(value, "")) == 0
+}
+
+// validate returns the violations of value against the schema, path being the location of value in the event
+func (schema *schemaT) validate(value interface{}, path string) []ViolationErrorT {
+	if schema.alwaysValid {
+		return nil
+	}
+	if schema.alwaysInvalid {
+		return []ViolationErrorT{{Field: path, Message: "no value is allowed"}}
+	}
+
+	var violations []ViolationErrorT
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, ViolationErrorT{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	valueType := jsonType(value)
+	if !schema.matchesType(valueType) {
+		violate("expected %s, got %s", strings.Join(schema.types, " or "), valueType)
+		return violations
+	}
+	if schema.cnst != nil && !reflect.DeepEqual(normalize(*schema.cnst), normalize(value)) {
+		violate("must be equal to %v", *schema.cnst)
+	}
+	if schema.enum != nil {
+		found := false
+		for _, allowed := range schema.enum {
+			if reflect.DeepEqual(normalize(allowed), normalize(value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violate("must be one of %v", schema.enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if schema.minLength != nil && length < *schema.minLength {
+			violate("length must be at least %d", *schema.minLength)
+		}
+		if schema.maxLength != nil && length > *schema.maxLength {
+			violate("length must be at most %d", *schema.maxLength)
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(v) {
+			violate("must match pattern %s", schema.pattern.String())
+		}
+		if !matchesFormat(schema.format, v) {
+			violate("must be a valid %s", schema.format)
+		}
+	case []interface{}:
+		if schema.minItems != nil && len(v) < *schema.minItems {
+			violate("must have at least %d items", *schema.minItems)
+		}
+		if schema.maxItems != nil && len(v) > *schema.maxItems {
+			violate("must have at most %d items", *schema.maxItems)
+		}
+		if schema.items != nil {
+			for i, item := range v {
+				violations = append(violations, schema.items.validate(item, joinPath(path, fmt.Sprint(i)))...)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range schema.required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, ViolationErrorT{Field: joinPath(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propertySchema, ok := schema.properties[name]; ok {
+				violations = append(violations, propertySchema.validate(v[name], joinPath(path, name))...)
+			} else if schema.additionalProperties != nil {
+				if schema.additionalProperties.alwaysInvalid {
+					violations = append(violations, ViolationErrorT{Field: joinPath(path, name), Message: "is not allowed"})
+				} else {
+					violations = append(violations, schema.additionalProperties.validate(v[name], joinPath(path, name))...)
+				}
+			}
+		}
+	default:
+		if number, ok := toFloat(value); ok {
+			if schema.minimum != nil && number < *schema.minimum {
+				violate("must be >= %v", *schema.minimum)
+			}
+			if schema.maximum != nil && number > *schema.maximum {
+				violate("must be <= %v", *schema.maximum)
+			}
+			if schema.exclusiveMinimum != nil && number <= *schema.exclusiveMinimum {
+				violate("must be > %v", *schema.exclusiveMinimum)
+			}
+			if schema.exclusiveMaximum != nil && number >= *schema.exclusiveMaximum {
+				violate("must be < %v", *schema.exclusiveMaximum)
+			}
+		}
+	}
+
+	for _, subSchema := range schema.allOf {
+		violations = append(violations, subSchema.validate(value, path)...)
+	}
+	if len(schema.anyOf) > 0 {
+		matched := false
+		for _, subSchema := range schema.anyOf {
+			if subSchema.isValid(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			violate("must match at least one schema of anyOf")
+		}
+	}
+	if len(schema.oneOf) > 0 {
+		matched := 0
+		for _, subSchema := range schema.oneOf {
+			if subSchema.isValid(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			violate("must match exactly one schema of oneOf, matched %d", matched)
+		}
+	}
+	if schema.not != nil && schema.not.isValid(value) {
+		violate("must not match schema of not")
+	}
+	return violations
+}
+
+// normalize converts numbers to float64 so that values decoded differently compare equal
+func normalize(value interface{}) interface{} {
+	if number, ok := toFloat(value); ok {
+		return number
+	}
+	return value
+}
+
+var emailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+// matchesFormat checks the formats commonly used in tracking plans, other formats are not checked
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "email":
+		return emailRegex.MatchString(value)
+	}
+	return true
+}
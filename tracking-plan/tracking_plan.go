@@ -0,0 +1,147 @@
+package trackingplan
+
+import (
+	"encoding/json"
+	"sync"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+)
+
+// Actions taken on events violating the tracking plan of their source
+const (
+	DropAction      = "drop"
+	AnnotateAction  = "annotate"
+	ProcErrorAction = "procError"
+)
+
+// ViolationErrorsKey is the key under the event context in which violations are added by the annotate action
+const ViolationErrorsKey = "violationErrors"
+
+var pkgLogger logger.LoggerI
+
+func init() {
+	pkgLogger = logger.NewLogger().Child("tracking-plan")
+}
+
+// ResultT is the outcome of validating an event against the tracking plan of its source
+type ResultT struct {
+	Violated bool
+	Action   string
+	PlanID   string
+	Errors   []ViolationErrorT
+}
+
+type compiledPlanT struct {
+	id                   string
+	version              int
+	violationAction      string
+	blockUnplannedEvents bool
+	schemas              map[string]*schemaT
+}
+
+// HandleT keeps the compiled tracking plans of sources by sourceID, and the violations counted since the last flush
+type HandleT struct {
+	plans          map[string]*compiledPlanT
+	plansLock      sync.RWMutex
+	violations     map[violationKeyT]int64
+	violationsLock sync.Mutex
+}
+
+// Update compiles the tracking plans of the sources. Plans are recompiled only when their id or version changes.
+// Event schemas that fail to compile are logged and skipped, so that a bad schema doesn't block the other events
+func (handle *HandleT) Update(sources []backendconfig.SourceT) {
+	handle.plansLock.RLock()
+	oldPlans := handle.plans
+	handle.plansLock.RUnlock()
+
+	plans := make(map[string]*compiledPlanT)
+	for _, source := range sources {
+		plan := source.TrackingPlan
+		if plan.ID == "" {
+			continue
+		}
+		if oldPlan, ok := oldPlans[source.ID]; ok && oldPlan.id == plan.ID && oldPlan.version == plan.Version {
+			plans[source.ID] = oldPlan
+			continue
+		}
+		compiledPlan := &compiledPlanT{
+			id:                   plan.ID,
+			version:              plan.Version,
+			violationAction:      plan.ViolationAction,
+			blockUnplannedEvents: plan.BlockUnplannedEvents,
+			schemas:              make(map[string]*schemaT),
+		}
+		if compiledPlan.violationAction == "" {
+			compiledPlan.violationAction = AnnotateAction
+		}
+		for eventName, rawSchema := range plan.Events {
+			schema, err := compileSchema(rawSchema)
+			if err != nil {
+				pkgLogger.Errorf("Skipping schema of event %s in tracking plan %s (version %d): %v", eventName, plan.ID, plan.Version, err)
+				continue
+			}
+			compiledPlan.schemas[eventName] = schema
+		}
+		plans[source.ID] = compiledPlan
+	}
+
+	handle.plansLock.Lock()
+	handle.plans = plans
+	handle.plansLock.Unlock()
+}
+
+// EventName returns the name an event is planned under, the event name for track events and the event type for the others
+func EventName(event map[string]interface{}) string {
+	eventType, _ := event["type"].(string)
+	if eventType == "track" {
+		eventName, _ := event["event"].(string)
+		return eventName
+	}
+	return eventType
+}
+
+// Validate checks the event against the tracking plan of the source.
+// Events of sources without a plan and unplanned events, unless the plan blocks them, are not violations
+func (handle *HandleT) Validate(sourceID string, event map[string]interface{}) ResultT {
+	handle.plansLock.RLock()
+	plan, ok := handle.plans[sourceID]
+	handle.plansLock.RUnlock()
+	if !ok {
+		return ResultT{}
+	}
+
+	result := ResultT{Action: plan.violationAction, PlanID: plan.id}
+	eventName := EventName(event)
+	schema, ok := plan.schemas[eventName]
+	if !ok {
+		if plan.blockUnplannedEvents {
+			result.Violated = true
+			result.Errors = []ViolationErrorT{{Message: "event " + eventName + " is not planned"}}
+		}
+		return result
+	}
+	result.Errors = schema.validate(event, "")
+	result.Violated = len(result.Errors) > 0
+	return result
+}
+
+// Annotate adds the violations of the result to the context of the event
+func Annotate(event map[string]interface{}, result ResultT) {
+	eventContext, ok := event["context"].(map[string]interface{})
+	if !ok {
+		eventContext = make(map[string]interface{})
+		event["context"] = eventContext
+	}
+	violationErrors := make([]interface{}, len(result.Errors))
+	for i, violationError := range result.Errors {
+		violationErrors[i] = map[string]interface{}{"field": violationError.Field, "message": violationError.Message}
+	}
+	eventContext[ViolationErrorsKey] = violationErrors
+}
+
+// ErrorsJSON returns the violations of the result as a json array
+func (result ResultT) ErrorsJSON() []byte {
+	violationErrors, _ := json.Marshal(result.Errors)
+	return violationErrors
+}
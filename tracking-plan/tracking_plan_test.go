@@ -0,0 +1,162 @@
+package trackingplan_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	backendconfig "github.com/rudderlabs/rudder-server/config/backend-config"
+	trackingplan "github.com/rudderlabs/rudder-server/tracking-plan"
+)
+
+const (
+	SourceID        = "some-source-id"
+	OtherSourceID   = "other-source-id"
+	TrackingPlanID  = "some-tracking-plan-id"
+	OrderCompleted  = "Order Completed"
+	UnplannedEvent  = "Unplanned Event"
+	orderSchemaJSON = `{
+		"type": "object",
+		"properties": {
+			"properties": {
+				"type": "object",
+				"properties": {
+					"revenue": {"type": "number", "minimum": 0},
+					"currency": {"type": "string", "enum": ["USD", "EUR"]},
+					"products": {"type": "array", "minItems": 1, "items": {"type": "object", "required": ["sku"]}}
+				},
+				"required": ["revenue"],
+				"additionalProperties": false
+			}
+		},
+		"required": ["properties"]
+	}`
+	identifySchemaJSON = `{"properties": {"traits": {"required": ["email"], "properties": {"email": {"type": "string", "format": "email"}}}}}`
+)
+
+func parseEvent(event string) map[string]interface{} {
+	var parsed map[string]interface{}
+	Expect(json.Unmarshal([]byte(event), &parsed)).To(Succeed())
+	return parsed
+}
+
+func sourceWithPlan(sourceID, violationAction string, version int, blockUnplanned bool) backendconfig.SourceT {
+	return backendconfig.SourceT{
+		ID: sourceID,
+		TrackingPlan: backendconfig.TrackingPlanT{
+			ID:                   TrackingPlanID,
+			Version:              version,
+			ViolationAction:      violationAction,
+			BlockUnplannedEvents: blockUnplanned,
+			Events: map[string]json.RawMessage{
+				OrderCompleted: json.RawMessage(orderSchemaJSON),
+				"identify":     json.RawMessage(identifySchemaJSON),
+			},
+		},
+	}
+}
+
+var _ = Describe("TrackingPlan", func() {
+	var handle *trackingplan.HandleT
+
+	BeforeEach(func() {
+		handle = &trackingplan.HandleT{}
+		handle.Update([]backendconfig.SourceT{
+			sourceWithPlan(SourceID, trackingplan.DropAction, 1, false),
+			{ID: OtherSourceID},
+		})
+	})
+
+	Context("validating events", func() {
+		It("should accept events matching their schema", func() {
+			event := parseEvent(`{"type": "track", "event": "Order Completed", "properties": {"revenue": 10.5, "currency": "USD", "products": [{"sku": "a"}]}}`)
+			result := handle.Validate(SourceID, event)
+			Expect(result.Violated).To(BeFalse())
+			Expect(result.Errors).To(BeEmpty())
+		})
+
+		It("should report every violation with the path of the offending field", func() {
+			event := parseEvent(`{"type": "track", "event": "Order Completed", "properties": {"revenue": -1, "currency": "INR", "products": [{}], "coupon": "x"}}`)
+			result := handle.Validate(SourceID, event)
+			Expect(result.Violated).To(BeTrue())
+			Expect(result.Action).To(Equal(trackingplan.DropAction))
+			Expect(result.PlanID).To(Equal(TrackingPlanID))
+			Expect(result.Errors).To(ConsistOf(
+				trackingplan.ViolationErrorT{Field: "properties.coupon", Message: "is not allowed"},
+				trackingplan.ViolationErrorT{Field: "properties.currency", Message: "must be one of [USD EUR]"},
+				trackingplan.ViolationErrorT{Field: "properties.products.0.sku", Message: "is required"},
+				trackingplan.ViolationErrorT{Field: "properties.revenue", Message: "must be >= 0"},
+			))
+		})
+
+		It("should report missing required fields and wrong types", func() {
+			result := handle.Validate(SourceID, parseEvent(`{"type": "track", "event": "Order Completed", "properties": {"revenue": "10"}}`))
+			Expect(result.Errors).To(Equal([]trackingplan.ViolationErrorT{{Field: "properties.revenue", Message: "expected number, got string"}}))
+
+			result = handle.Validate(SourceID, parseEvent(`{"type": "track", "event": "Order Completed"}`))
+			Expect(result.Errors).To(Equal([]trackingplan.ViolationErrorT{{Field: "properties", Message: "is required"}}))
+		})
+
+		It("should plan non track events by their type", func() {
+			result := handle.Validate(SourceID, parseEvent(`{"type": "identify", "traits": {"email": "not-an-email"}}`))
+			Expect(result.Errors).To(Equal([]trackingplan.ViolationErrorT{{Field: "traits.email", Message: "must be a valid email"}}))
+
+			result = handle.Validate(SourceID, parseEvent(`{"type": "identify", "traits": {"email": "user@example.com"}}`))
+			Expect(result.Violated).To(BeFalse())
+		})
+
+		It("should not validate events of sources without a tracking plan", func() {
+			result := handle.Validate(OtherSourceID, parseEvent(`{"type": "track", "event": "Order Completed"}`))
+			Expect(result.Violated).To(BeFalse())
+			Expect(result.Action).To(BeEmpty())
+		})
+
+		It("should let unplanned events pass unless the plan blocks them", func() {
+			event := parseEvent(`{"type": "track", "event": "Unplanned Event"}`)
+			Expect(handle.Validate(SourceID, event).Violated).To(BeFalse())
+
+			handle.Update([]backendconfig.SourceT{sourceWithPlan(SourceID, trackingplan.ProcErrorAction, 2, true)})
+			result := handle.Validate(SourceID, event)
+			Expect(result.Violated).To(BeTrue())
+			Expect(result.Action).To(Equal(trackingplan.ProcErrorAction))
+			Expect(result.Errors).To(Equal([]trackingplan.ViolationErrorT{{Message: "event " + UnplannedEvent + " is not planned"}}))
+		})
+	})
+
+	Context("updating tracking plans", func() {
+		It("should apply a new version of a plan", func() {
+			source := sourceWithPlan(SourceID, trackingplan.AnnotateAction, 2, false)
+			source.TrackingPlan.Events[OrderCompleted] = json.RawMessage(`{"properties": {"properties": {"required": ["orderId"]}}}`)
+			handle.Update([]backendconfig.SourceT{source})
+
+			result := handle.Validate(SourceID, parseEvent(`{"type": "track", "event": "Order Completed", "properties": {"revenue": 1}}`))
+			Expect(result.Action).To(Equal(trackingplan.AnnotateAction))
+			Expect(result.Errors).To(Equal([]trackingplan.ViolationErrorT{{Field: "properties.orderId", Message: "is required"}}))
+		})
+
+		It("should skip schemas that fail to compile and default to the annotate action", func() {
+			source := sourceWithPlan(SourceID, "", 2, false)
+			source.TrackingPlan.Events[OrderCompleted] = json.RawMessage(`{"properties": {"name": {"pattern": "("}}}`)
+			handle.Update([]backendconfig.SourceT{source})
+
+			Expect(handle.Validate(SourceID, parseEvent(`{"type": "track", "event": "Order Completed"}`)).Violated).To(BeFalse())
+			result := handle.Validate(SourceID, parseEvent(`{"type": "identify", "traits": {}}`))
+			Expect(result.Violated).To(BeTrue())
+			Expect(result.Action).To(Equal(trackingplan.AnnotateAction))
+		})
+	})
+
+	Context("annotating events", func() {
+		It("should add the violations to the event context", func() {
+			event := parseEvent(`{"type": "identify", "context": {"library": {"name": "test"}}, "traits": {}}`)
+			trackingplan.Annotate(event, handle.Validate(SourceID, event))
+
+			eventContext := event["context"].(map[string]interface{})
+			Expect(eventContext["library"]).To(Equal(map[string]interface{}{"name": "test"}))
+			Expect(eventContext[trackingplan.ViolationErrorsKey]).To(Equal([]interface{}{
+				map[string]interface{}{"field": "traits.email", "message": "is required"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package trackingplan
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/jobsdb"
+)
+
+// ViolationsTable keeps the number of events violating the tracking plans of their sources, by event model
+const ViolationsTable = "tracking_plan_violations"
+
+var violationsFlushInterval time.Duration
+
+func init() {
+	config.RegisterDurationConfigVariable(time.Duration(60), &violationsFlushInterval, false, time.Second, "Processor.trackingPlanViolationsFlushInterval")
+}
+
+type violationKeyT struct {
+	writeKey        string
+	eventType       string
+	eventIdentifier string
+}
+
+// RecordViolation counts an event violating the tracking plan of its source against its event model.
+// Counts are added to ViolationsTable by FlushViolations
+func (handle *HandleT) RecordViolation(writeKey string, event map[string]interface{}) {
+	eventType, _ := event["type"].(string)
+	var eventIdentifier string
+	if eventType == "track" {
+		// event models of non track events have no identifier
+		eventIdentifier = EventName(event)
+	}
+
+	handle.violationsLock.Lock()
+	defer handle.violationsLock.Unlock()
+	if handle.violations == nil {
+		handle.violations = make(map[violationKeyT]int64)
+	}
+	handle.violations[violationKeyT{writeKey: writeKey, eventType: eventType, eventIdentifier: eventIdentifier}]++
+}
+
+// FlushViolations adds the violations counted since the last flush to the counts in ViolationsTable every Processor.trackingPlanViolationsFlushInterval.
+// Counts that fail to be flushed are kept for the next flush
+func (handle *HandleT) FlushViolations() {
+	var dbHandle *sql.DB
+	for {
+		time.Sleep(violationsFlushInterval)
+		handle.violationsLock.Lock()
+		violations := handle.violations
+		handle.violations = nil
+		handle.violationsLock.Unlock()
+		if len(violations) == 0 {
+			continue
+		}
+
+		var err error
+		if dbHandle == nil {
+			dbHandle, err = sql.Open("postgres", jobsdb.GetConnectionString())
+		}
+		if err == nil {
+			err = writeViolations(dbHandle, violations)
+		}
+		if err != nil {
+			pkgLogger.Errorf("Failed to flush tracking plan violations, retrying in %v: %v", violationsFlushInterval, err)
+			handle.violationsLock.Lock()
+			for key, count := range violations {
+				if handle.violations == nil {
+					handle.violations = make(map[violationKeyT]int64)
+				}
+				handle.violations[key] += count
+			}
+			handle.violationsLock.Unlock()
+		}
+	}
+}
+
+func writeViolations(dbHandle *sql.DB, violations map[violationKeyT]int64) error {
+	txn, err := dbHandle.Begin()
+	if err != nil {
+		return err
+	}
+	upsertSQL := fmt.Sprintf(`INSERT INTO %[1]s (write_key, event_type, event_model_identifier, violation_count, last_violated_at)
+								VALUES ($1, $2, $3, $4, NOW())
+								ON CONFLICT (write_key, event_type, event_model_identifier)
+								DO UPDATE SET violation_count = %[1]s.violation_count + EXCLUDED.violation_count, last_violated_at = EXCLUDED.last_violated_at`, ViolationsTable)
+	stmt, err := txn.Prepare(upsertSQL)
+	if err != nil {
+		txn.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for key, count := range violations {
+		_, err = stmt.Exec(key.writeKey, key.eventType, key.eventIdentifier, count)
+		if err != nil {
+			txn.Rollback()
+			return err
+		}
+	}
+	return txn.Commit()
+}
@@ -0,0 +1,35 @@
+package trackingplan
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Violations", func() {
+	It("should count violations by event model", func() {
+		handle := &HandleT{}
+		handle.RecordViolation("some-write-key", map[string]interface{}{"type": "track", "event": "Order Completed"})
+		handle.RecordViolation("some-write-key", map[string]interface{}{"type": "track", "event": "Order Completed"})
+		handle.RecordViolation("some-write-key", map[string]interface{}{"type": "identify", "event": "ignored"})
+
+		Expect(handle.violations).To(Equal(map[violationKeyT]int64{
+			{writeKey: "some-write-key", eventType: "track", eventIdentifier: "Order Completed"}: 2,
+			{writeKey: "some-write-key", eventType: "identify", eventIdentifier: ""}:             1,
+		}))
+	})
+
+	It("should add violation counts to the counts in db", func() {
+		db, mock, err := sqlmock.New()
+		Expect(err).NotTo(HaveOccurred())
+
+		mock.ExpectBegin()
+		upsert := mock.ExpectPrepare(`INSERT INTO tracking_plan_violations .* ON CONFLICT \(write_key, event_type, event_model_identifier\)\s+DO UPDATE SET violation_count = tracking_plan_violations.violation_count \+ EXCLUDED.violation_count`)
+		upsert.ExpectExec().WithArgs("some-write-key", "track", "Order Completed", int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = writeViolations(db, map[violationKeyT]int64{{writeKey: "some-write-key", eventType: "track", eventIdentifier: "Order Completed"}: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+})
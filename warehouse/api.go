@@ -2,11 +2,13 @@ package warehouse
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/rudderlabs/rudder-server/config"
 	"github.com/rudderlabs/rudder-server/controlplane"
 	proto "github.com/rudderlabs/rudder-server/proto/warehouse"
@@ -82,6 +84,22 @@ type TableUploadResT struct {
 	Duration   int32     `json:"duration"`
 }
 
+type SchemaChangesReqT struct {
+	WorkspaceID   string
+	SourceID      string
+	DestinationID string
+	Status        string
+	Limit         int32
+	Offset        int32
+	API           UploadAPIT
+}
+
+type SchemaChangeReqT struct {
+	WorkspaceID string
+	ID          int64
+	API         UploadAPIT
+}
+
 type UploadAPIT struct {
 	enabled           bool
 	dbHandle          *sql.DB
@@ -471,3 +489,159 @@ func (uploadsReq UploadsReqT) authorizedSources() (sourceIDs []string) {
 	}
 	return sourceIDs
 }
+
+func (schemaChangesReq *SchemaChangesReqT) validateReq() error {
+	if !schemaChangesReq.API.enabled || schemaChangesReq.API.log == nil || schemaChangesReq.API.dbHandle == nil {
+		return errors.New(fmt.Sprint(`warehouse api's are not initialized`))
+	}
+	if schemaChangesReq.Status != "" && !misc.ContainsString([]string{SchemaChangePending, SchemaChangeApproved, SchemaChangeRejected, SchemaChangeApplied}, schemaChangesReq.Status) {
+		return fmt.Errorf("Invalid schema change status: %s", schemaChangesReq.Status)
+	}
+	if schemaChangesReq.Limit < 1 {
+		schemaChangesReq.Limit = 10
+	}
+	if schemaChangesReq.Offset < 0 {
+		schemaChangesReq.Offset = 0
+	}
+	return nil
+}
+
+//generateQuery filters the schema changes of the requested source, or of all authorized sources if none is requested, binding every filter as a parameter
+func (schemaChangesReq *SchemaChangesReqT) generateQuery(authorizedSourceIDs []string) (string, []interface{}) {
+	var whereClauses []string
+	var args []interface{}
+	addClause := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		whereClauses = append(whereClauses, fmt.Sprintf(clause, len(args)))
+	}
+	if schemaChangesReq.SourceID == "" {
+		addClause(`source_id = ANY($%d)`, pq.Array(authorizedSourceIDs))
+	} else {
+		addClause(`source_id = $%d`, schemaChangesReq.SourceID)
+	}
+	if schemaChangesReq.DestinationID != "" {
+		addClause(`destination_id = $%d`, schemaChangesReq.DestinationID)
+	}
+	if schemaChangesReq.Status != "" {
+		addClause(`status = $%d`, schemaChangesReq.Status)
+	}
+	args = append(args, schemaChangesReq.Limit, schemaChangesReq.Offset)
+
+	query := fmt.Sprintf(`select id, source_id, destination_id, destination_type, namespace, table_name, schema_diff, status, wh_upload_id, created_at, updated_at, count(*) OVER() AS total_schema_changes from %s where %s order by id desc limit $%d offset $%d`,
+		warehouseutils.WarehouseSchemaChangesTable, strings.Join(whereClauses, " AND "), len(args)-1, len(args))
+	schemaChangesReq.API.log.Debug(query)
+	return query, args
+}
+
+func (schemaChangesReq *SchemaChangesReqT) authorizedSources() []string {
+	uploadsReq := UploadsReqT{WorkspaceID: schemaChangesReq.WorkspaceID}
+	return uploadsReq.authorizedSources()
+}
+
+func (schemaChangesReq *SchemaChangesReqT) GetWhSchemaChanges() (schemaChangesRes *proto.WHSchemaChangesResponse, err error) {
+	schemaChanges := make([]*proto.WHSchemaChange, 0)
+	schemaChangesRes = &proto.WHSchemaChangesResponse{
+		SchemaChanges: schemaChanges,
+	}
+	err = schemaChangesReq.validateReq()
+	if err != nil {
+		return
+	}
+	schemaChangesRes.Pagination = &proto.Pagination{
+		Limit:  schemaChangesReq.Limit,
+		Offset: schemaChangesReq.Offset,
+	}
+
+	authorizedSourceIDs := schemaChangesReq.authorizedSources()
+	if len(authorizedSourceIDs) == 0 {
+		return schemaChangesRes, nil
+	}
+	if schemaChangesReq.SourceID != "" && !misc.ContainsString(authorizedSourceIDs, schemaChangesReq.SourceID) {
+		pkgLogger.Errorf(`Unauthorized request for schema changes of sourceId:%s in workspaceId:%s`, schemaChangesReq.SourceID, schemaChangesReq.WorkspaceID)
+		return schemaChangesRes, nil
+	}
+
+	query, args := schemaChangesReq.generateQuery(authorizedSourceIDs)
+	rows, err := schemaChangesReq.API.dbHandle.Query(query, args...)
+	if err != nil {
+		schemaChangesReq.API.log.Errorf(err.Error())
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schemaChange proto.WHSchemaChange
+		var rawDiff json.RawMessage
+		var uploadID sql.NullInt64
+		var createdAt, updatedAt time.Time
+		var totalSchemaChanges int32
+		err = rows.Scan(&schemaChange.Id, &schemaChange.SourceId, &schemaChange.DestinationId, &schemaChange.DestinationType, &schemaChange.Namespace, &schemaChange.TableName, &rawDiff, &schemaChange.Status, &uploadID, &createdAt, &updatedAt, &totalSchemaChanges)
+		if err != nil {
+			schemaChangesReq.API.log.Errorf(err.Error())
+			return &proto.WHSchemaChangesResponse{}, err
+		}
+		var diff warehouseutils.TableSchemaDiffT
+		err = json.Unmarshal(rawDiff, &diff)
+		if err != nil {
+			schemaChangesReq.API.log.Errorf(err.Error())
+			return &proto.WHSchemaChangesResponse{}, err
+		}
+		schemaChangesRes.Pagination.Total = totalSchemaChanges
+		schemaChange.Columns = diff.ColumnMap
+		schemaChange.ColumnsAlteredToText = diff.StringColumnsToBeAlteredToText
+		schemaChange.UploadId = uploadID.Int64
+		schemaChange.CreatedAt = timestamppb.New(createdAt)
+		schemaChange.UpdatedAt = timestamppb.New(updatedAt)
+		schemaChanges = append(schemaChanges, &schemaChange)
+	}
+	schemaChangesRes.SchemaChanges = schemaChanges
+	return schemaChangesRes, rows.Err()
+}
+
+func (schemaChangeReq SchemaChangeReqT) validateReq() error {
+	if !schemaChangeReq.API.enabled || schemaChangeReq.API.log == nil || schemaChangeReq.API.dbHandle == nil {
+		return errors.New(fmt.Sprint(`warehouse api's are not initialized`))
+	}
+	if schemaChangeReq.ID < 1 {
+		return errors.New(fmt.Sprint(`id is empty or should be greater than 0 `))
+	}
+	return nil
+}
+
+/*
+DecideWHSchemaChange approves or rejects a schema change, which can be decided again until its columns are added to the table.
+Uploads of the destination held for pending schema changes are retried right away
+*/
+func (schemaChangeReq SchemaChangeReqT) DecideWHSchemaChange(status string) error {
+	err := schemaChangeReq.validateReq()
+	if err != nil {
+		return err
+	}
+	var sourceID, destinationID, namespace, currentStatus string
+	sqlStatement := fmt.Sprintf(`select source_id, destination_id, namespace, status from %s where id = $1`, warehouseutils.WarehouseSchemaChangesTable)
+	err = schemaChangeReq.API.dbHandle.QueryRow(sqlStatement, schemaChangeReq.ID).Scan(&sourceID, &destinationID, &namespace, &currentStatus)
+	if err != nil {
+		schemaChangeReq.API.log.Errorf(err.Error())
+		return err
+	}
+	uploadReq := UploadReqT{WorkspaceID: schemaChangeReq.WorkspaceID}
+	if !uploadReq.authorizeSource(sourceID) {
+		pkgLogger.Errorf(`Unauthorized request for schema change:%d with sourceId:%s in workspaceId:%s`, schemaChangeReq.ID, sourceID, schemaChangeReq.WorkspaceID)
+		return errors.New("Unauthorized request")
+	}
+	if currentStatus == SchemaChangeApplied {
+		return fmt.Errorf("Schema change %d is already applied", schemaChangeReq.ID)
+	}
+
+	// the change is decided only if it wasn't decided by another request since it was read
+	sqlStatement = fmt.Sprintf(`update %s set status = $1, updated_at = $2 where id = $3 and status = $4`, warehouseutils.WarehouseSchemaChangesTable)
+	result, err := schemaChangeReq.API.dbHandle.Exec(sqlStatement, status, timeutil.Now(), schemaChangeReq.ID, currentStatus)
+	if err != nil {
+		schemaChangeReq.API.log.Errorf(err.Error())
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return fmt.Errorf("Schema change %d was decided concurrently", schemaChangeReq.ID)
+	}
+	return releaseHeldUploads(schemaChangeReq.API.dbHandle, destinationID, namespace)
+}
+
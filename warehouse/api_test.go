@@ -0,0 +1,74 @@
+package warehouse
+
+import (
+	"database/sql/driver"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+//arrayArg matches the pq.Array of a string slice bound as a query parameter
+type arrayArg []string
+
+func (a arrayArg) Match(v driver.Value) bool {
+	expected, err := pq.Array([]string(a)).Value()
+	return err == nil && expected == v
+}
+
+var _ = Describe("SchemaChangesReq", func() {
+	const query = `select id, source_id, destination_id, destination_type, namespace, table_name, schema_diff, status, wh_upload_id, created_at, updated_at, count(*) OVER() AS total_schema_changes from wh_schema_changes where `
+	columns := []string{"id", "source_id", "destination_id", "destination_type", "namespace", "table_name", "schema_diff", "status", "wh_upload_id", "created_at", "updated_at", "total_schema_changes"}
+
+	var (
+		mock sqlmock.Sqlmock
+		req  *SchemaChangesReqT
+	)
+
+	BeforeEach(func() {
+		db, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).To(BeNil())
+		mock = sqlMock
+		sourceIDsByWorkspaceLock.Lock()
+		sourceIDsByWorkspace = map[string][]string{"workspace": {"source-a", "source-b"}}
+		sourceIDsByWorkspaceLock.Unlock()
+		req = &SchemaChangesReqT{WorkspaceID: "workspace", API: UploadAPIT{enabled: true, dbHandle: db, log: pkgLogger}}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+
+	It("should list the schema changes of the authorized sources without filters", func() {
+		mock.ExpectQuery(query+`source_id = ANY($1) order by id desc limit $2 offset $3`).
+			WithArgs(arrayArg{"source-a", "source-b"}, 10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		res, err := req.GetWhSchemaChanges()
+		Expect(err).To(BeNil())
+		Expect(res.SchemaChanges).To(BeEmpty())
+	})
+
+	It("should bind the filters as parameters", func() {
+		req.SourceID = "source-a"
+		req.DestinationID = "destination' OR '1'='1"
+		req.Status = SchemaChangePending
+		req.Limit = 5
+		req.Offset = 10
+		mock.ExpectQuery(query+`source_id = $1 AND destination_id = $2 AND status = $3 order by id desc limit $4 offset $5`).
+			WithArgs("source-a", "destination' OR '1'='1", SchemaChangePending, 5, 10).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := req.GetWhSchemaChanges()
+		Expect(err).To(BeNil())
+	})
+
+	It("should list no schema changes of sources outside the workspace", func() {
+		req.SourceID = "source-c"
+
+		res, err := req.GetWhSchemaChanges()
+		Expect(err).To(BeNil())
+		Expect(res.SchemaChanges).To(BeEmpty())
+	})
+})
@@ -14,6 +14,7 @@ import (
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+	uuid "github.com/satori/go.uuid"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -24,6 +25,7 @@ var (
 	partitionExpiryUpdatedLock            sync.RWMutex
 	pkgLogger                             logger.LoggerI
 	setUsersLoadPartitionFirstEventFilter bool
+	stagingTablePrefix                    string
 )
 
 type HandleT struct {
@@ -221,6 +223,11 @@ func (bq *HandleT) loadTable(tableName string, forceLoad bool, getLoadFileLocFro
 	gcsRef.MaxBadRecords = 0
 	gcsRef.IgnoreUnknownValues = false
 
+	if dedup, ok := warehouseutils.GetDedupOnLoad(bq.Warehouse, bq.Uploader, tableName); ok {
+		err = bq.dedupLoadTable(tableName, gcsRef, dedup)
+		return
+	}
+
 	partitionDate = time.Now().Format("2006-01-02")
 	outputTable := partitionedTable(tableName, partitionDate)
 
@@ -244,6 +251,81 @@ func (bq *HandleT) loadTable(tableName string, forceLoad bool, getLoadFileLocFro
 	return
 }
 
+// dedupLoadTable loads the files of gcsRef in a staging table, which is merged with the rows of tableName received within the dedup window
+func (bq *HandleT) dedupLoadTable(tableName string, gcsRef *bigquery.GCSReference, dedup warehouseutils.DedupOnLoadT) (err error) {
+	stagingTableName := misc.TruncateStr(fmt.Sprintf(`%s%s_%s`, stagingTablePrefix, tableName, strings.ReplaceAll(uuid.NewV4().String(), "-", "")), 1024)
+	stagingTableRef := bq.Db.Dataset(bq.Namespace).Table(stagingTableName)
+	gcsRef.Schema = getTableSchema(bq.Uploader.GetTableSchemaInWarehouse(tableName))
+	job, err := stagingTableRef.LoaderFrom(gcsRef).Run(bq.BQContext)
+	if err != nil {
+		pkgLogger.Errorf("BQ: Error initiating load job for staging table %s: %v\n", stagingTableName, err)
+		return
+	}
+	defer func() {
+		if deleteErr := stagingTableRef.Delete(bq.BQContext); deleteErr != nil {
+			pkgLogger.Errorf("BQ: Error dropping staging table %s: %v", stagingTableName, deleteErr)
+		}
+	}()
+	status, err := job.Wait(bq.BQContext)
+	if err != nil {
+		pkgLogger.Errorf("BQ: Error running load job for staging table %s: %v\n", stagingTableName, err)
+		return
+	}
+	if status.Err() != nil {
+		return status.Err()
+	}
+
+	columnNames := warehouseutils.SortColumnKeysFromColumnMap(bq.Uploader.GetTableSchemaInUpload(tableName))
+	stagingColumnNames := make([]string, 0, len(columnNames))
+	columnsWithValues := make([]string, 0, len(columnNames))
+	for _, columnName := range columnNames {
+		stagingColumnNames = append(stagingColumnNames, fmt.Sprintf("staging.`%s`", columnName))
+		columnsWithValues = append(columnsWithValues, fmt.Sprintf("`%[1]s` = staging.`%[1]s`", columnName))
+	}
+	joinConditions := make([]string, 0, len(dedup.Keys)+2)
+	for _, key := range dedup.Keys {
+		joinConditions = append(joinConditions, fmt.Sprintf("original.`%[1]s` = staging.`%[1]s`", key))
+	}
+	if !dedup.Since.IsZero() {
+		since := dedup.Since.Format(misc.RFC3339Milli)
+		joinConditions = append(joinConditions, fmt.Sprintf(`original._PARTITIONTIME >= TIMESTAMP_TRUNC(TIMESTAMP('%[1]s'), DAY, 'UTC') AND original.received_at >= TIMESTAMP('%[1]s')`, since))
+	}
+	var updateClause string
+	if bq.Uploader.ShouldOnDedupUseNewRecord() {
+		updateClause = fmt.Sprintf(`WHEN MATCHED THEN UPDATE SET %s`, strings.Join(columnsWithValues, ", "))
+	}
+	bqTable := func(name string) string { return fmt.Sprintf("`%s`.`%s`", bq.Namespace, name) }
+	sqlStatement := fmt.Sprintf(`MERGE INTO %[1]s AS original
+		USING (
+			SELECT * EXCEPT (_rudder_staging_row_number) FROM (
+				SELECT *, ROW_NUMBER() OVER (PARTITION BY %[3]s ORDER BY received_at DESC) AS _rudder_staging_row_number FROM %[2]s
+			) WHERE _rudder_staging_row_number = 1
+		) AS staging
+		ON %[4]s
+		%[5]s
+		WHEN NOT MATCHED THEN INSERT (%[6]s) VALUES (%[7]s)`,
+		bqTable(tableName),                        // 1
+		bqTable(stagingTableName),                 // 2
+		"`"+strings.Join(dedup.Keys, "`, `")+"`",  // 3
+		strings.Join(joinConditions, " AND "),     // 4
+		updateClause,                              // 5
+		"`"+strings.Join(columnNames, "`, `")+"`", // 6
+		strings.Join(stagingColumnNames, ", "),    // 7
+	)
+	pkgLogger.Infof("BQ: Dedup records for table:%s using staging table: %s\n", tableName, sqlStatement)
+	job, err = bq.Db.Query(sqlStatement).Run(bq.BQContext)
+	if err != nil {
+		pkgLogger.Errorf("BQ: Error initiating merge job: %v\n", err)
+		return
+	}
+	status, err = job.Wait(bq.BQContext)
+	if err != nil {
+		pkgLogger.Errorf("BQ: Error running merge job: %v\n", err)
+		return
+	}
+	return status.Err()
+}
+
 func (bq *HandleT) LoadUserTables() (errorMap map[string]error) {
 	errorMap = map[string]error{warehouseutils.IdentifiesTable: nil}
 	pkgLogger.Infof("BQ: Starting load for identifies and users tables\n")
@@ -355,6 +437,7 @@ func (bq *HandleT) connect(cred BQCredentialsT) (*bigquery.Client, error) {
 
 func loadConfig() {
 	partitionExpiryUpdated = make(map[string]bool)
+	stagingTablePrefix = "rudder_staging_"
 	config.RegisterBoolConfigVariable(true, &setUsersLoadPartitionFirstEventFilter, true, "Warehouse.bigquery.setUsersLoadPartitionFirstEventFilter")
 
 }
@@ -45,7 +45,10 @@ const (
 	caCertificate = "caCertificate"
 	cluster       = "cluster"
 )
-const partitionField = "received_at"
+const (
+	partitionField     = "received_at"
+	dedupKeysBatchSize = 1000
+)
 
 // clickhouse doesnt support bool, they recommend to use Uint8 and set 1,0
 
@@ -411,6 +414,104 @@ func typecastDataFromType(data string, dataType string) interface{} {
 	return dataI
 }
 
+// dedupKeySeparator joins the values of the dedup keys of a row, in go and in clickhouse
+const dedupKeySeparator = "\x1f"
+
+// dedupKey returns the values of the dedup keys of a load file record
+func dedupKey(record []string, keyIndexes []int) string {
+	values := make([]string, 0, len(keyIndexes))
+	for _, index := range keyIndexes {
+		values = append(values, record[index])
+	}
+	return strings.Join(values, dedupKeySeparator)
+}
+
+// dedupKeysInLoadFiles returns the distinct dedup keys of the records of the load files
+func dedupKeysInLoadFiles(fileNames []string, keyIndexes []int) (keys []string, err error) {
+	seen := make(map[string]bool)
+	for _, objectFileName := range fileNames {
+		var gzipFile *os.File
+		gzipFile, err = os.Open(objectFileName)
+		if err != nil {
+			return
+		}
+		var gzipReader *gzip.Reader
+		gzipReader, err = gzip.NewReader(gzipFile)
+		if err != nil {
+			gzipFile.Close()
+			return
+		}
+		csvReader := csv.NewReader(gzipReader)
+		for {
+			var record []string
+			record, err = csvReader.Read()
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			if err != nil {
+				break
+			}
+			if key := dedupKey(record, keyIndexes); !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		gzipReader.Close()
+		gzipFile.Close()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// loadedDedupKeys returns the keys among keys of the rows of tableName received within the dedup window.
+// ReplacingMergeTree only replaces rows with the same sorting key, so rows with the keys of loaded rows but another received_at are skipped instead
+func (ch *HandleT) loadedDedupKeys(tableName string, dedup warehouseutils.DedupOnLoadT, keys []string) (map[string]bool, error) {
+	keyValues := make([]string, 0, len(dedup.Keys))
+	for _, key := range dedup.Keys {
+		keyValues = append(keyValues, fmt.Sprintf(`toString("%s")`, key))
+	}
+	keyExpression := keyValues[0]
+	if len(keyValues) > 1 {
+		keyExpression = fmt.Sprintf(`concat(%s)`, strings.Join(keyValues, `, '\x1f', `))
+	}
+	var windowClause string
+	if !dedup.Since.IsZero() {
+		windowClause = fmt.Sprintf(`AND received_at >= toDateTime('%s', 'UTC')`, dedup.Since.Format("2006-01-02 15:04:05"))
+	}
+
+	loadedKeys := make(map[string]bool)
+	for i := 0; i < len(keys); i += dedupKeysBatchSize {
+		batch := keys[i:misc.MinInt(i+dedupKeysBatchSize, len(keys))]
+		args := make([]interface{}, 0, len(batch))
+		for _, key := range batch {
+			args = append(args, key)
+		}
+		sqlStatement := fmt.Sprintf(`SELECT DISTINCT %[3]s FROM "%[1]s"."%[2]s" WHERE %[3]s IN (%[4]s) %[5]s`, ch.Namespace, tableName, keyExpression, generateArgumentString("?", len(batch)), windowClause)
+		rows, err := ch.Db.Query(sqlStatement, args...)
+		if err != nil {
+			pkgLogger.Errorf("CH: Error querying loaded dedup keys of table:%s: %v", tableName, err)
+			return nil, err
+		}
+		for rows.Next() {
+			var key string
+			if err = rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			loadedKeys[key] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return loadedKeys, nil
+}
+
 // loadTable loads table to clickhouse from the load files
 func (ch *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutils.TableSchemaT) (err error) {
 	pkgLogger.Infof("CH: Starting load for table:%s", tableName)
@@ -425,6 +526,31 @@ func (ch *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutil
 	}
 	defer misc.RemoveFilePaths(fileNames...)
 
+	// dedup on load skips the rows with the keys of rows received within the dedup window, or of rows before them in the load files
+	var keyIndexes []int
+	var skippedKeys map[string]bool
+	dedup, dedupOnLoad := warehouseutils.GetDedupOnLoad(ch.Warehouse, ch.Uploader, tableName)
+	if dedupOnLoad {
+		for _, key := range dedup.Keys {
+			for index, columnName := range sortedColumnKeys {
+				if columnName == key {
+					keyIndexes = append(keyIndexes, index)
+				}
+			}
+		}
+		var keys []string
+		keys, err = dedupKeysInLoadFiles(fileNames, keyIndexes)
+		if err != nil {
+			pkgLogger.Errorf("CH: Error reading dedup keys of load files for table:%s: %v", tableName, err)
+			return
+		}
+		skippedKeys, err = ch.loadedDedupKeys(tableName, dedup, keys)
+		if err != nil {
+			return
+		}
+		pkgLogger.Infof("CH: Skipping %d rows with keys loaded already in table:%s", len(skippedKeys), tableName)
+	}
+
 	txn, err := ch.Db.Begin()
 	if err != nil {
 		pkgLogger.Errorf("CH: Error while beginning a transaction in db for loading in table:%s: %v", tableName, err)
@@ -477,6 +603,13 @@ func (ch *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutil
 				txn.Rollback()
 				return err
 			}
+			if dedupOnLoad {
+				key := dedupKey(record, keyIndexes)
+				if skippedKeys[key] {
+					continue
+				}
+				skippedKeys[key] = true
+			}
 			var recordInterface []interface{}
 			for index, value := range record {
 				columnName := sortedColumnKeys[index]
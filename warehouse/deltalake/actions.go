@@ -0,0 +1,243 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+const (
+	//readerVersion and writerVersion of the protocol of the tables written, see https://github.com/delta-io/delta/blob/master/PROTOCOL.md#protocol-evolution
+	readerVersion = 1
+	writerVersion = 2
+)
+
+//dataTypesMap maps rudder data types to delta primitive types
+var dataTypesMap = map[string]string{
+	"boolean":  "boolean",
+	"int":      "long",
+	"bigint":   "long",
+	"float":    "double",
+	"string":   "string",
+	"text":     "string",
+	"json":     "string",
+	"datetime": "timestamp",
+}
+
+//dataTypesMapToRudder maps delta primitive types to rudder data types
+var dataTypesMapToRudder = map[string]string{
+	"boolean":   "boolean",
+	"byte":      "int",
+	"short":     "int",
+	"integer":   "int",
+	"long":      "int",
+	"float":     "float",
+	"double":    "float",
+	"string":    "string",
+	"timestamp": "datetime",
+}
+
+//actionT is a line of a commit of the delta log, having exactly one of its actions set, see https://github.com/delta-io/delta/blob/master/PROTOCOL.md#actions
+type actionT struct {
+	Txn        *txnT        `json:"txn,omitempty"`
+	Add        *addT        `json:"add,omitempty"`
+	Remove     *removeT     `json:"remove,omitempty"`
+	MetaData   *metadataT   `json:"metaData,omitempty"`
+	Protocol   *protocolT   `json:"protocol,omitempty"`
+	CommitInfo *commitInfoT `json:"commitInfo,omitempty"`
+}
+
+type txnT struct {
+	AppID       string `json:"appId"`
+	Version     int64  `json:"version"`
+	LastUpdated int64  `json:"lastUpdated,omitempty"`
+}
+
+type addT struct {
+	Path             string            `json:"path"`
+	PartitionValues  map[string]string `json:"partitionValues"`
+	Size             int64             `json:"size"`
+	ModificationTime int64             `json:"modificationTime"`
+	DataChange       bool              `json:"dataChange"`
+	Stats            string            `json:"stats,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+type removeT struct {
+	Path              string `json:"path"`
+	DeletionTimestamp int64  `json:"deletionTimestamp,omitempty"`
+	DataChange        bool   `json:"dataChange"`
+}
+
+type formatT struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+type metadataT struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Format           formatT           `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime,omitempty"`
+}
+
+type protocolT struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type commitInfoT struct {
+	Timestamp           int64             `json:"timestamp"`
+	Operation           string            `json:"operation"`
+	OperationParameters map[string]string `json:"operationParameters"`
+	IsBlindAppend       bool              `json:"isBlindAppend"`
+	EngineInfo          string            `json:"engineInfo"`
+}
+
+//structTypeT is the schema of a table, as serialized in schemaString. Types of fields are kept raw, as tables written by other engines can have nested types
+type structTypeT struct {
+	Type   string         `json:"type"`
+	Fields []structFieldT `json:"fields"`
+}
+
+type structFieldT struct {
+	Name     string                 `json:"name"`
+	Type     json.RawMessage        `json:"type"`
+	Nullable bool                   `json:"nullable"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+func newMetadata(id string, columnMap map[string]string, createdTime int64) (*metadataT, error) {
+	metadata := &metadataT{
+		ID:               id,
+		Format:           formatT{Provider: "parquet", Options: map[string]string{}},
+		PartitionColumns: []string{},
+		Configuration:    map[string]string{},
+		CreatedTime:      createdTime,
+	}
+	if err := metadata.setSchema(structTypeT{Type: "struct", Fields: []structFieldT{}}); err != nil {
+		return nil, err
+	}
+	if _, err := metadata.addColumns(columnMap); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func (metadata *metadataT) schema() (structTypeT, error) {
+	var schema structTypeT
+	err := json.Unmarshal([]byte(metadata.SchemaString), &schema)
+	return schema, err
+}
+
+func (metadata *metadataT) setSchema(schema structTypeT) error {
+	rawSchema, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	metadata.SchemaString = string(rawSchema)
+	return nil
+}
+
+//ColumnMap returns the columns of the schema with primitive types, with their rudder data types
+func (metadata *metadataT) ColumnMap() (map[string]string, error) {
+	schema, err := metadata.schema()
+	if err != nil {
+		return nil, err
+	}
+	columnMap := make(map[string]string)
+	for _, field := range schema.Fields {
+		var deltaType string
+		if json.Unmarshal(field.Type, &deltaType) != nil {
+			continue
+		}
+		if dataType, ok := dataTypesMapToRudder[deltaType]; ok {
+			columnMap[field.Name] = dataType
+		}
+	}
+	return columnMap, nil
+}
+
+/*
+schemaDiff returns the columns of columnMap missing from the schema.
+Columns are compared case insensitively, as spark resolves them
+*/
+func (metadata *metadataT) schemaDiff(columnMap map[string]string) (diff warehouseutils.TableSchemaDiffT, err error) {
+	diff = warehouseutils.TableSchemaDiffT{
+		ColumnMap:     make(map[string]string),
+		UpdatedSchema: make(map[string]string),
+	}
+	schema, err := metadata.schema()
+	if err != nil {
+		return diff, err
+	}
+	existingColumns := make(map[string]bool)
+	for _, field := range schema.Fields {
+		existingColumns[strings.ToLower(field.Name)] = true
+	}
+	currentColumnMap, err := metadata.ColumnMap()
+	if err != nil {
+		return diff, err
+	}
+	for columnName, columnType := range currentColumnMap {
+		diff.UpdatedSchema[columnName] = columnType
+	}
+	for columnName, columnType := range columnMap {
+		if existingColumns[strings.ToLower(columnName)] {
+			continue
+		}
+		diff.ColumnMap[columnName] = columnType
+		diff.UpdatedSchema[columnName] = columnType
+		diff.Exists = true
+	}
+	return diff, nil
+}
+
+//addColumns appends the columns of columnMap missing from the schema, returning false if there are none
+func (metadata *metadataT) addColumns(columnMap map[string]string) (bool, error) {
+	diff, err := metadata.schemaDiff(columnMap)
+	if err != nil || !diff.Exists {
+		return false, err
+	}
+	schema, err := metadata.schema()
+	if err != nil {
+		return false, err
+	}
+
+	//columns are sorted so that the schema is stable for a given column map
+	columnNames := make([]string, 0, len(diff.ColumnMap))
+	for columnName := range diff.ColumnMap {
+		columnNames = append(columnNames, columnName)
+	}
+	sort.Strings(columnNames)
+	for _, columnName := range columnNames {
+		deltaType, ok := dataTypesMap[diff.ColumnMap[columnName]]
+		if !ok {
+			return false, fmt.Errorf("unsupported data type %s of column %s", diff.ColumnMap[columnName], columnName)
+		}
+		rawType, err := json.Marshal(deltaType)
+		if err != nil {
+			return false, err
+		}
+		schema.Fields = append(schema.Fields, structFieldT{Name: columnName, Type: rawType, Nullable: true, Metadata: map[string]interface{}{}})
+	}
+	return true, metadata.setSchema(schema)
+}
+
+//copy returns a deep copy of metadata, so that the metadata of a snapshot is not changed by a commit failing
+func (metadata *metadataT) copy() (*metadataT, error) {
+	rawMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	var metadataCopy metadataT
+	err = json.Unmarshal(rawMetadata, &metadataCopy)
+	return &metadataCopy, err
+}
@@ -0,0 +1,326 @@
+package deltalake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+//checkpointSchema is the part of the checkpoint schema of the delta protocol written by rudder, see https://github.com/delta-io/delta/blob/master/PROTOCOL.md#checkpoint-schema
+const checkpointSchema = `{"Tag":"name=parquet_go_root, repetitiontype=REQUIRED","Fields":[
+{"Tag":"name=txn, repetitiontype=OPTIONAL","Fields":[
+	{"Tag":"name=appId, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=version, type=INT64, repetitiontype=OPTIONAL"},
+	{"Tag":"name=lastUpdated, type=INT64, repetitiontype=OPTIONAL"}]},
+{"Tag":"name=add, repetitiontype=OPTIONAL","Fields":[
+	{"Tag":"name=path, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=partitionValues, type=MAP, repetitiontype=OPTIONAL","Fields":[
+		{"Tag":"name=key, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"},
+		{"Tag":"name=value, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]},
+	{"Tag":"name=size, type=INT64, repetitiontype=OPTIONAL"},
+	{"Tag":"name=modificationTime, type=INT64, repetitiontype=OPTIONAL"},
+	{"Tag":"name=dataChange, type=BOOLEAN, repetitiontype=OPTIONAL"},
+	{"Tag":"name=stats, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=tags, type=MAP, repetitiontype=OPTIONAL","Fields":[
+		{"Tag":"name=key, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"},
+		{"Tag":"name=value, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]}]},
+{"Tag":"name=remove, repetitiontype=OPTIONAL","Fields":[
+	{"Tag":"name=path, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=deletionTimestamp, type=INT64, repetitiontype=OPTIONAL"},
+	{"Tag":"name=dataChange, type=BOOLEAN, repetitiontype=OPTIONAL"}]},
+{"Tag":"name=metaData, repetitiontype=OPTIONAL","Fields":[
+	{"Tag":"name=id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=description, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=format, repetitiontype=OPTIONAL","Fields":[
+		{"Tag":"name=provider, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+		{"Tag":"name=options, type=MAP, repetitiontype=OPTIONAL","Fields":[
+			{"Tag":"name=key, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"},
+			{"Tag":"name=value, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]}]},
+	{"Tag":"name=schemaString, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+	{"Tag":"name=partitionColumns, type=LIST, repetitiontype=OPTIONAL","Fields":[
+		{"Tag":"name=element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]},
+	{"Tag":"name=configuration, type=MAP, repetitiontype=OPTIONAL","Fields":[
+		{"Tag":"name=key, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"},
+		{"Tag":"name=value, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"}]},
+	{"Tag":"name=createdTime, type=INT64, repetitiontype=OPTIONAL"}]},
+{"Tag":"name=protocol, repetitiontype=OPTIONAL","Fields":[
+	{"Tag":"name=minReaderVersion, type=INT32, repetitiontype=OPTIONAL"},
+	{"Tag":"name=minWriterVersion, type=INT32, repetitiontype=OPTIONAL"}]}]}`
+
+//parquetFileT is an in memory source.ParquetFile, reading data or writing to buffer
+type parquetFileT struct {
+	data   []byte
+	reader *bytes.Reader
+	buffer *bytes.Buffer
+}
+
+func newParquetReaderFile(data []byte) *parquetFileT {
+	return &parquetFileT{data: data, reader: bytes.NewReader(data)}
+}
+
+func newParquetWriterFile() *parquetFileT {
+	return &parquetFileT{buffer: &bytes.Buffer{}}
+}
+
+func (file *parquetFileT) Seek(offset int64, whence int) (int64, error) {
+	if file.reader == nil {
+		return 0, nil
+	}
+	return file.reader.Seek(offset, whence)
+}
+
+func (file *parquetFileT) Read(p []byte) (int, error) {
+	return file.reader.Read(p)
+}
+
+func (file *parquetFileT) Write(p []byte) (int, error) {
+	return file.buffer.Write(p)
+}
+
+func (file *parquetFileT) Close() error {
+	return nil
+}
+
+func (file *parquetFileT) Open(name string) (source.ParquetFile, error) {
+	return newParquetReaderFile(file.data), nil
+}
+
+func (file *parquetFileT) Create(name string) (source.ParquetFile, error) {
+	return newParquetWriterFile(), nil
+}
+
+/*
+writeCheckpoint encodes the actions of a snapshot as a checkpoint parquet file.
+Actions are written with the json writer of parquet-go, as its struct marshaller drops the values of maps and lists in nested structs
+*/
+func writeCheckpoint(actions []actionT) ([]byte, error) {
+	file := newParquetWriterFile()
+	jsonWriter, err := writer.NewJSONWriter(checkpointSchema, file, 1)
+	if err != nil {
+		return nil, err
+	}
+	jsonWriter.CompressionType = parquet.CompressionCodec_SNAPPY
+	for _, action := range actions {
+		//commit infos are not part of the state of a table
+		action.CommitInfo = nil
+		rawAction, err := json.Marshal(action)
+		if err != nil {
+			return nil, err
+		}
+		if err = jsonWriter.Write(string(rawAction)); err != nil {
+			return nil, err
+		}
+	}
+	if err = jsonWriter.WriteStop(); err != nil {
+		return nil, err
+	}
+	return file.buffer.Bytes(), nil
+}
+
+//checkpointReaderT reads the columns of a checkpoint, written by rudder or any other delta writer
+type checkpointReaderT struct {
+	reader  *reader.ParquetReader
+	numRows int64
+}
+
+func (cr *checkpointReaderT) inPath(path []string) (string, bool) {
+	exPath := strings.Join(append([]string{cr.reader.SchemaHandler.GetRootExName()}, path...), common.PAR_GO_PATH_DELIMITER)
+	inPath, err := cr.reader.SchemaHandler.ConvertToInPathStr(exPath)
+	return inPath, err == nil
+}
+
+//column returns the value of a column at path for every row, nil if undefined or if the checkpoint has no such column
+func (cr *checkpointReaderT) column(path ...string) ([]interface{}, error) {
+	values := make([]interface{}, cr.numRows)
+	inPath, ok := cr.inPath(path)
+	if !ok {
+		return values, nil
+	}
+	columnValues, _, _, err := cr.reader.ReadColumnByPath(inPath, cr.numRows)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(columnValues)) != cr.numRows {
+		return nil, fmt.Errorf("column %s has %d values for %d rows", strings.Join(path, "."), len(columnValues), cr.numRows)
+	}
+	return columnValues, nil
+}
+
+//repeatedColumn returns the values of the entries of a repeated column at path for every row, nil for undefined entries
+func (cr *checkpointReaderT) repeatedColumn(path ...string) ([][]interface{}, error) {
+	rows := make([][]interface{}, cr.numRows)
+	inPath, ok := cr.inPath(path)
+	if !ok {
+		return rows, nil
+	}
+	splitInPath := strings.Split(inPath, common.PAR_GO_PATH_DELIMITER)
+	maxDefinitionLevel, err := cr.reader.SchemaHandler.MaxDefinitionLevel(splitInPath)
+	if err != nil {
+		return nil, err
+	}
+	repetitionType, err := cr.reader.SchemaHandler.GetRepetitionType(splitInPath)
+	if err != nil {
+		return nil, err
+	}
+	//entries are defined up to the repeated group, their optional values up to the leaf
+	entryDefinitionLevel := maxDefinitionLevel
+	if repetitionType == parquet.FieldRepetitionType_OPTIONAL {
+		entryDefinitionLevel--
+	}
+
+	values, repetitionLevels, definitionLevels, err := cr.reader.ReadColumnByPath(inPath, cr.numRows)
+	if err != nil {
+		return nil, err
+	}
+	row := int64(-1)
+	for i, value := range values {
+		if repetitionLevels[i] == 0 {
+			row++
+		}
+		if row >= cr.numRows {
+			return nil, fmt.Errorf("column %s has more than %d rows", strings.Join(path, "."), cr.numRows)
+		}
+		if definitionLevels[i] >= entryDefinitionLevel {
+			rows[row] = append(rows[row], value)
+		}
+	}
+	return rows, nil
+}
+
+//mapColumn returns the map at path for every row
+func (cr *checkpointReaderT) mapColumn(path ...string) ([]map[string]string, error) {
+	keys, err := cr.repeatedColumn(append(path, "key_value", "key")...)
+	if err != nil {
+		return nil, err
+	}
+	values, err := cr.repeatedColumn(append(path, "key_value", "value")...)
+	if err != nil {
+		return nil, err
+	}
+	maps := make([]map[string]string, cr.numRows)
+	for row := range keys {
+		maps[row] = make(map[string]string, len(keys[row]))
+		for i, key := range keys[row] {
+			if i < len(values[row]) {
+				maps[row][toString(key)] = toString(values[row][i])
+			}
+		}
+	}
+	return maps, nil
+}
+
+func toString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	}
+	return 0
+}
+
+func toBool(value interface{}) bool {
+	b, _ := value.(bool)
+	return b
+}
+
+//readCheckpoint decodes the actions of a checkpoint parquet file
+func readCheckpoint(data []byte) ([]actionT, error) {
+	parquetReader, err := reader.NewParquetColumnReader(newParquetReaderFile(data), 1)
+	if err != nil {
+		return nil, err
+	}
+	defer parquetReader.ReadStop()
+	cr := &checkpointReaderT{reader: parquetReader, numRows: parquetReader.GetNumRows()}
+
+	columns := make(map[string][]interface{})
+	for _, path := range [][]string{
+		{"txn", "appId"}, {"txn", "version"}, {"txn", "lastUpdated"},
+		{"add", "path"}, {"add", "size"}, {"add", "modificationTime"}, {"add", "dataChange"}, {"add", "stats"},
+		{"remove", "path"}, {"remove", "deletionTimestamp"}, {"remove", "dataChange"},
+		{"metaData", "id"}, {"metaData", "name"}, {"metaData", "description"}, {"metaData", "format", "provider"},
+		{"metaData", "schemaString"}, {"metaData", "createdTime"},
+		{"protocol", "minReaderVersion"}, {"protocol", "minWriterVersion"},
+	} {
+		if columns[strings.Join(path, ".")], err = cr.column(path...); err != nil {
+			return nil, err
+		}
+	}
+	maps := make(map[string][]map[string]string)
+	for _, path := range [][]string{{"add", "partitionValues"}, {"add", "tags"}, {"metaData", "format", "options"}, {"metaData", "configuration"}} {
+		if maps[strings.Join(path, ".")], err = cr.mapColumn(path...); err != nil {
+			return nil, err
+		}
+	}
+	partitionColumns, err := cr.repeatedColumn("metaData", "partitionColumns", "list", "element")
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]actionT, 0, cr.numRows)
+	for row := int64(0); row < cr.numRows; row++ {
+		var action actionT
+		switch {
+		case columns["txn.appId"][row] != nil:
+			action.Txn = &txnT{
+				AppID:       toString(columns["txn.appId"][row]),
+				Version:     toInt64(columns["txn.version"][row]),
+				LastUpdated: toInt64(columns["txn.lastUpdated"][row]),
+			}
+		case columns["add.path"][row] != nil:
+			action.Add = &addT{
+				Path:             toString(columns["add.path"][row]),
+				PartitionValues:  maps["add.partitionValues"][row],
+				Size:             toInt64(columns["add.size"][row]),
+				ModificationTime: toInt64(columns["add.modificationTime"][row]),
+				DataChange:       toBool(columns["add.dataChange"][row]),
+				Stats:            toString(columns["add.stats"][row]),
+			}
+			if len(maps["add.tags"][row]) > 0 {
+				action.Add.Tags = maps["add.tags"][row]
+			}
+		case columns["remove.path"][row] != nil:
+			action.Remove = &removeT{
+				Path:              toString(columns["remove.path"][row]),
+				DeletionTimestamp: toInt64(columns["remove.deletionTimestamp"][row]),
+				DataChange:        toBool(columns["remove.dataChange"][row]),
+			}
+		case columns["metaData.id"][row] != nil:
+			action.MetaData = &metadataT{
+				ID:               toString(columns["metaData.id"][row]),
+				Name:             toString(columns["metaData.name"][row]),
+				Description:      toString(columns["metaData.description"][row]),
+				Format:           formatT{Provider: toString(columns["metaData.format.provider"][row]), Options: maps["metaData.format.options"][row]},
+				SchemaString:     toString(columns["metaData.schemaString"][row]),
+				PartitionColumns: make([]string, 0, len(partitionColumns[row])),
+				Configuration:    maps["metaData.configuration"][row],
+				CreatedTime:      toInt64(columns["metaData.createdTime"][row]),
+			}
+			for _, partitionColumn := range partitionColumns[row] {
+				action.MetaData.PartitionColumns = append(action.MetaData.PartitionColumns, toString(partitionColumn))
+			}
+		case columns["protocol.minReaderVersion"][row] != nil:
+			action.Protocol = &protocolT{
+				MinReaderVersion: int(toInt64(columns["protocol.minReaderVersion"][row])),
+				MinWriterVersion: int(toInt64(columns["protocol.minWriterVersion"][row])),
+			}
+		default:
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
@@ -0,0 +1,209 @@
+package deltalake
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/utils/timeutil"
+	"github.com/rudderlabs/rudder-server/warehouse/client"
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+	schemarepository "github.com/rudderlabs/rudder-server/warehouse/s3-datalake/schema-repository"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var (
+	pkgLogger          logger.LoggerI
+	checkpointInterval int
+	maxCommitRetries   int
+)
+
+//schemes of the locations of tables by object storage provider
+var schemes = map[string]string{
+	"S3":                   "s3",
+	"MINIO":                "s3",
+	"DIGITAL_OCEAN_SPACES": "s3",
+	"GCS":                  "gs",
+	"AZURE_BLOB":           "wasbs",
+}
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("warehouse").Child("deltalake")
+}
+
+func loadConfig() {
+	config.RegisterIntConfigVariable(10, &checkpointInterval, true, 1, "Warehouse.deltalake.checkpointInterval")
+	config.RegisterIntConfigVariable(3, &maxCommitRetries, true, 1, "Warehouse.deltalake.maxCommitRetries")
+}
+
+/*
+HandleT loads uploads into delta tables, in the bucket of the destination next to the load files.
+Load files are written under the path of their table like for S3_DATALAKE, and are added to the table by a commit to its _delta_log
+*/
+type HandleT struct {
+	SchemaRepository *schemarepository.LocalSchemaRepository
+	Warehouse        warehouseutils.WarehouseT
+	Uploader         warehouseutils.UploaderI
+	FileIO           fileio.FileIO
+	fileManager      filemanager.FileManager
+	prefix           string
+}
+
+func (wh *HandleT) Setup(warehouse warehouseutils.WarehouseT, uploader warehouseutils.UploaderI) (err error) {
+	wh.Warehouse = warehouse
+	wh.Uploader = uploader
+
+	provider := warehouseutils.ObjectStorageType(warehouseutils.DELTALAKE, wh.Warehouse.Destination.Config, wh.Uploader.UseRudderStorage())
+	storageConfig := misc.GetObjectStorageConfig(misc.ObjectStorageOptsT{
+		Provider:         provider,
+		Config:           wh.Warehouse.Destination.Config,
+		UseRudderStorage: wh.Uploader.UseRudderStorage(),
+	})
+	wh.fileManager, err = filemanager.New(&filemanager.SettingsT{
+		Provider: provider,
+		Config:   storageConfig,
+	})
+	if err != nil {
+		return err
+	}
+	bucket, _ := storageConfig["bucketName"].(string)
+	prefix, _ := storageConfig["prefix"].(string)
+	wh.prefix = strings.Trim(prefix, "/")
+	wh.FileIO = fileio.NewObjectStorageFileIO(schemes[provider], bucket, wh.prefix, wh.fileManager, fileio.NewAdvisoryLocker(wh.Uploader.GetDBHandle()))
+
+	wh.SchemaRepository, err = schemarepository.NewLocalSchemaRepository(wh.Warehouse, wh.Uploader)
+	return err
+}
+
+func (wh *HandleT) table(tableName string) *TableT {
+	return NewTable(wh.FileIO, warehouseutils.GetTablePathInObjectStorage(wh.Warehouse.Namespace, tableName))
+}
+
+//appID identifies the transactions of the source and destination in the log of a table, making retries of committed uploads a no-op
+func (wh *HandleT) appID() string {
+	return fmt.Sprintf("rudder-%s-%s", wh.Warehouse.Source.ID, wh.Warehouse.Destination.ID)
+}
+
+//appendLoadFiles commits the load files of the upload for tableName to its delta table, evolving its schema to the schema in the warehouse
+func (wh *HandleT) appendLoadFiles(tableName string) error {
+	table := wh.table(tableName)
+	tableKey := table.Path + "/"
+	if wh.prefix != "" {
+		tableKey = wh.prefix + "/" + tableKey
+	}
+
+	modificationTime := timeutil.Now().UnixNano() / int64(time.Millisecond)
+	loadFiles := wh.Uploader.GetLoadFilesMetadata(warehouseutils.GetLoadFilesOptionsT{Table: tableName})
+	files := make([]addT, 0, len(loadFiles))
+	for _, loadFile := range loadFiles {
+		objectName, err := wh.fileManager.GetObjectNameFromLocation(loadFile.Location)
+		if err != nil {
+			return err
+		}
+		//paths of added files are relative to the table
+		if !strings.HasPrefix(objectName, tableKey) {
+			return fmt.Errorf("load file %s is not under the path %s of table %s", loadFile.Location, tableKey, tableName)
+		}
+		file := addT{
+			Path:             strings.TrimPrefix(objectName, tableKey),
+			PartitionValues:  map[string]string{},
+			Size:             gjson.GetBytes(loadFile.Metadata, "content_length").Int(),
+			ModificationTime: modificationTime,
+			DataChange:       true,
+		}
+		if totalRows := gjson.GetBytes(loadFile.Metadata, "total_rows"); totalRows.Exists() {
+			file.Stats = fmt.Sprintf(`{"numRecords":%d}`, totalRows.Int())
+		}
+		files = append(files, file)
+	}
+
+	txn := txnT{AppID: wh.appID(), Version: wh.Uploader.GetLoadFileGenStartTIme().UnixNano() / int64(time.Millisecond)}
+	pkgLogger.Infof("Committing %d load files to delta table %s : %s", len(files), tableName, wh.Warehouse.Destination.ID)
+	return table.AppendFiles(files, wh.Uploader.GetTableSchemaInWarehouse(tableName), txn)
+}
+
+func (wh *HandleT) CrashRecover(warehouse warehouseutils.WarehouseT) (err error) {
+	return nil
+}
+
+func (wh *HandleT) FetchSchema(warehouse warehouseutils.WarehouseT) (warehouseutils.SchemaT, error) {
+	return wh.SchemaRepository.FetchSchema(warehouse)
+}
+
+func (wh *HandleT) CreateSchema() (err error) {
+	return nil
+}
+
+func (wh *HandleT) CreateTable(tableName string, columnMap map[string]string) (err error) {
+	// the delta table can exist without being in the local schema, if updating the local schema failed after creating it
+	err = wh.table(tableName).UpdateSchema(columnMap)
+	if err != nil {
+		return err
+	}
+	return wh.SchemaRepository.CreateTable(tableName, columnMap)
+}
+
+func (wh *HandleT) AddColumn(tableName string, columnName string, columnType string) (err error) {
+	err = wh.table(tableName).UpdateSchema(map[string]string{columnName: columnType})
+	if err != nil {
+		return err
+	}
+	return wh.SchemaRepository.AddColumn(tableName, columnName, columnType)
+}
+
+//AlterColumn only changes the local schema, as the string columns altered to text are strings in delta tables too
+func (wh *HandleT) AlterColumn(tableName string, columnName string, columnType string) (err error) {
+	return wh.SchemaRepository.AlterColumn(tableName, columnName, columnType)
+}
+
+func (wh *HandleT) LoadTable(tableName string) error {
+	return wh.appendLoadFiles(tableName)
+}
+
+func (wh *HandleT) LoadUserTables() map[string]error {
+	errorMap := map[string]error{warehouseutils.IdentifiesTable: wh.appendLoadFiles(warehouseutils.IdentifiesTable)}
+	if len(wh.Uploader.GetTableSchemaInUpload(warehouseutils.UsersTable)) > 0 {
+		errorMap[warehouseutils.UsersTable] = wh.appendLoadFiles(warehouseutils.UsersTable)
+	}
+	return errorMap
+}
+
+func (wh *HandleT) LoadIdentityMergeRulesTable() error {
+	pkgLogger.Infof("Skipping load for identity merge rules : %s is a delta lake destination", wh.Warehouse.Destination.ID)
+	return nil
+}
+
+func (wh *HandleT) LoadIdentityMappingsTable() error {
+	pkgLogger.Infof("Skipping load for identity mappings : %s is a delta lake destination", wh.Warehouse.Destination.ID)
+	return nil
+}
+
+func (wh *HandleT) Cleanup() {
+}
+
+func (wh *HandleT) IsEmpty(warehouse warehouseutils.WarehouseT) (bool, error) {
+	return false, nil
+}
+
+func (wh *HandleT) TestConnection(warehouse warehouseutils.WarehouseT) error {
+	return fmt.Errorf("deltalake err :not implemented")
+}
+
+func (wh *HandleT) DownloadIdentityRules(*misc.GZipWriter) error {
+	return fmt.Errorf("deltalake err :not implemented")
+}
+
+func (wh *HandleT) GetTotalCountInTable(tableName string) (int64, error) {
+	return 0, nil
+}
+
+func (wh *HandleT) Connect(warehouse warehouseutils.WarehouseT) (client.Client, error) {
+	return client.Client{}, fmt.Errorf("deltalake err :not implemented")
+}
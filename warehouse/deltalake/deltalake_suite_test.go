@@ -0,0 +1,13 @@
+package deltalake
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDeltalake(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Deltalake Suite")
+}
@@ -0,0 +1,395 @@
+package deltalake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/rudderlabs/rudder-server/utils/timeutil"
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+)
+
+const (
+	logDirectory       = "_delta_log"
+	lastCheckpointFile = "_last_checkpoint"
+	engineInfo         = "rudder-server"
+
+	//tombstoneRetention is the default of delta.deletedFileRetentionDuration, removed files older than it are dropped from checkpoints
+	tombstoneRetention = 7 * 24 * time.Hour
+)
+
+var (
+	commitFileRegex                = regexp.MustCompile(`^(\d{20})\.json$`)
+	checkpointFileRegex            = regexp.MustCompile(`^(\d{20})\.checkpoint\.parquet$`)
+	multipartCheckpointFileRegex   = regexp.MustCompile(`^(\d{20})\.checkpoint\.(\d{10})\.(\d{10})\.parquet$`)
+	errUnsupportedPartitionedTable = errors.New("deltalake: loading partitioned tables is not supported")
+)
+
+//TableT is a delta table at Path in the file io, its log being under Path/_delta_log
+type TableT struct {
+	FileIO fileio.FileIO
+	Path   string
+}
+
+func NewTable(fileIO fileio.FileIO, path string) *TableT {
+	return &TableT{FileIO: fileIO, Path: strings.Trim(path, "/")}
+}
+
+//snapshotT is the state of a table at version, built by replaying its log
+type snapshotT struct {
+	version    int64
+	protocol   *protocolT
+	metadata   *metadataT
+	txns       map[string]txnT
+	files      map[string]addT
+	tombstones map[string]removeT
+}
+
+func newSnapshot() *snapshotT {
+	return &snapshotT{
+		version:    -1,
+		txns:       make(map[string]txnT),
+		files:      make(map[string]addT),
+		tombstones: make(map[string]removeT),
+	}
+}
+
+func (snapshot *snapshotT) apply(actions []actionT) {
+	for _, action := range actions {
+		switch {
+		case action.Txn != nil:
+			snapshot.txns[action.Txn.AppID] = *action.Txn
+		case action.Add != nil:
+			snapshot.files[action.Add.Path] = *action.Add
+			delete(snapshot.tombstones, action.Add.Path)
+		case action.Remove != nil:
+			snapshot.tombstones[action.Remove.Path] = *action.Remove
+			delete(snapshot.files, action.Remove.Path)
+		case action.MetaData != nil:
+			snapshot.metadata = action.MetaData
+			if snapshot.metadata.Format.Options == nil {
+				snapshot.metadata.Format.Options = map[string]string{}
+			}
+			if snapshot.metadata.PartitionColumns == nil {
+				snapshot.metadata.PartitionColumns = []string{}
+			}
+			if snapshot.metadata.Configuration == nil {
+				snapshot.metadata.Configuration = map[string]string{}
+			}
+		case action.Protocol != nil:
+			snapshot.protocol = action.Protocol
+		}
+	}
+}
+
+//actions returns the actions reconstructing the snapshot, as written in checkpoints
+func (snapshot *snapshotT) actions(timestampMs int64) []actionT {
+	actions := []actionT{{Protocol: snapshot.protocol}, {MetaData: snapshot.metadata}}
+	appIDs := make([]string, 0, len(snapshot.txns))
+	for appID := range snapshot.txns {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Strings(appIDs)
+	for _, appID := range appIDs {
+		txn := snapshot.txns[appID]
+		actions = append(actions, actionT{Txn: &txn})
+	}
+	paths := make([]string, 0, len(snapshot.files))
+	for path := range snapshot.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		add := snapshot.files[path]
+		actions = append(actions, actionT{Add: &add})
+	}
+	paths = paths[:0]
+	for path, remove := range snapshot.tombstones {
+		if remove.DeletionTimestamp > timestampMs-tombstoneRetention.Milliseconds() {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		remove := snapshot.tombstones[path]
+		actions = append(actions, actionT{Remove: &remove})
+	}
+	return actions
+}
+
+func (table *TableT) logPath(name string) string {
+	return fmt.Sprintf("%s/%s/%s", table.Path, logDirectory, name)
+}
+
+func commitFile(version int64) string {
+	return fmt.Sprintf("%020d.json", version)
+}
+
+func checkpointFile(version int64) string {
+	return fmt.Sprintf("%020d.checkpoint.parquet", version)
+}
+
+func multipartCheckpointFile(version int64, part int, parts int) string {
+	return fmt.Sprintf("%020d.checkpoint.%010d.%010d.parquet", version, part, parts)
+}
+
+/*
+snapshot replays the log of the table, from its latest complete checkpoint if any.
+Checkpoints are found by listing the log, so that a missing or stale _last_checkpoint is not an issue
+*/
+func (table *TableT) snapshot() (*snapshotT, error) {
+	paths, err := table.FileIO.List(table.logPath(""))
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[int64]bool)
+	checkpointParts := make(map[int64]map[int]bool)
+	checkpointVersion, checkpointPartCount := int64(-1), 0
+	latestVersion := int64(-1)
+	for _, path := range paths {
+		name := path[strings.LastIndex(path, "/")+1:]
+		if match := commitFileRegex.FindStringSubmatch(name); match != nil {
+			version, _ := strconv.ParseInt(match[1], 10, 64)
+			commits[version] = true
+			if version > latestVersion {
+				latestVersion = version
+			}
+		} else if match := checkpointFileRegex.FindStringSubmatch(name); match != nil {
+			version, _ := strconv.ParseInt(match[1], 10, 64)
+			if version > checkpointVersion {
+				checkpointVersion, checkpointPartCount = version, 0
+			}
+		} else if match := multipartCheckpointFileRegex.FindStringSubmatch(name); match != nil {
+			version, _ := strconv.ParseInt(match[1], 10, 64)
+			part, _ := strconv.Atoi(match[2])
+			parts, _ := strconv.Atoi(match[3])
+			if checkpointParts[version] == nil {
+				checkpointParts[version] = make(map[int]bool)
+			}
+			checkpointParts[version][part] = true
+			if len(checkpointParts[version]) == parts && version > checkpointVersion {
+				checkpointVersion, checkpointPartCount = version, parts
+			}
+		}
+	}
+
+	snapshot := newSnapshot()
+	if checkpointVersion >= 0 {
+		if err = table.readCheckpoint(snapshot, checkpointVersion, checkpointPartCount); err != nil {
+			return nil, err
+		}
+		snapshot.version = checkpointVersion
+	}
+	if checkpointVersion > latestVersion {
+		latestVersion = checkpointVersion
+	}
+	for version := snapshot.version + 1; version <= latestVersion; version++ {
+		if !commits[version] {
+			return nil, fmt.Errorf("deltalake: commit %d of table %s is missing", version, table.Path)
+		}
+		actions, err := table.readCommit(version)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.apply(actions)
+		snapshot.version = version
+	}
+	if snapshot.version >= 0 && (snapshot.protocol == nil || snapshot.metadata == nil) {
+		return nil, fmt.Errorf("deltalake: log of table %s has no protocol or metadata", table.Path)
+	}
+	return snapshot, nil
+}
+
+func (table *TableT) readCheckpoint(snapshot *snapshotT, version int64, parts int) error {
+	files := []string{checkpointFile(version)}
+	if parts > 0 {
+		files = files[:0]
+		for part := 1; part <= parts; part++ {
+			files = append(files, multipartCheckpointFile(version, part, parts))
+		}
+	}
+	for _, file := range files {
+		data, err := table.FileIO.Read(table.logPath(file))
+		if err != nil {
+			return err
+		}
+		actions, err := readCheckpoint(data)
+		if err != nil {
+			return fmt.Errorf("deltalake: reading checkpoint %s of table %s: %w", file, table.Path, err)
+		}
+		snapshot.apply(actions)
+	}
+	return nil
+}
+
+func (table *TableT) readCommit(version int64) ([]actionT, error) {
+	data, err := table.FileIO.Read(table.logPath(commitFile(version)))
+	if err != nil {
+		return nil, err
+	}
+	var actions []actionT
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var action actionT
+		if err = json.Unmarshal([]byte(line), &action); err != nil {
+			return nil, fmt.Errorf("deltalake: reading commit %d of table %s: %w", version, table.Path, err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+//ColumnMap returns the columns of the table with their rudder data types, nil if the table does not exist
+func (table *TableT) ColumnMap() (map[string]string, error) {
+	snapshot, err := table.snapshot()
+	if err != nil || snapshot.metadata == nil {
+		return nil, err
+	}
+	return snapshot.metadata.ColumnMap()
+}
+
+//UpdateSchema creates the table with the columns of columnMap, or adds the missing ones to its schema
+func (table *TableT) UpdateSchema(columnMap map[string]string) error {
+	return table.commit(columnMap, nil, nil)
+}
+
+//AppendFiles adds files to the table, along with the columns of columnMap missing from its schema, unless txn was committed already
+func (table *TableT) AppendFiles(files []addT, columnMap map[string]string, txn txnT) error {
+	return table.commit(columnMap, files, &txn)
+}
+
+/*
+commit writes the next version of the log of the table. Concurrent writers are resolved optimistically:
+if another writer committed the version first, the snapshot is read again and the commit retried on top of it.
+Every checkpointInterval versions, the state of the table is checkpointed
+*/
+func (table *TableT) commit(columnMap map[string]string, files []addT, txn *txnT) error {
+	for attempt := 0; ; attempt++ {
+		snapshot, err := table.snapshot()
+		if err != nil {
+			return err
+		}
+		if txn != nil {
+			if committed, ok := snapshot.txns[txn.AppID]; ok && committed.Version == txn.Version {
+				pkgLogger.Infof("Skipping commit of table %s : transaction %s version %d is committed already", table.Path, txn.AppID, txn.Version)
+				return nil
+			}
+		}
+
+		now := timeutil.Now().UnixNano() / int64(time.Millisecond)
+		actions, err := table.commitActions(snapshot, columnMap, files, txn, now)
+		if err != nil || len(actions) == 0 {
+			return err
+		}
+		version := snapshot.version + 1
+		err = table.writeCommit(version, actions)
+		if err == fileio.ErrExists && attempt < maxCommitRetries {
+			pkgLogger.Infof("Retrying commit of table %s : version %d was committed concurrently", table.Path, version)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if version > 0 && version%int64(checkpointInterval) == 0 {
+			snapshot.apply(actions)
+			snapshot.version = version
+			if err = table.checkpoint(snapshot, now); err != nil {
+				pkgLogger.Errorf("Failed to checkpoint version %d of table %s : %v", version, table.Path, err)
+			}
+		}
+		return nil
+	}
+}
+
+//commitActions returns the actions committing files and the schema changes of columnMap on top of snapshot, none if there is nothing to commit
+func (table *TableT) commitActions(snapshot *snapshotT, columnMap map[string]string, files []addT, txn *txnT, now int64) ([]actionT, error) {
+	var actions []actionT
+	if snapshot.metadata == nil {
+		metadata, err := newMetadata(uuid.NewV4().String(), columnMap, now)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, actionT{Protocol: &protocolT{MinReaderVersion: readerVersion, MinWriterVersion: writerVersion}}, actionT{MetaData: metadata})
+	} else {
+		if snapshot.protocol.MinWriterVersion > writerVersion {
+			return nil, fmt.Errorf("deltalake: table %s requires writer version %d, supported up to %d", table.Path, snapshot.protocol.MinWriterVersion, writerVersion)
+		}
+		if len(files) > 0 && len(snapshot.metadata.PartitionColumns) > 0 {
+			return nil, errUnsupportedPartitionedTable
+		}
+		metadata, err := snapshot.metadata.copy()
+		if err != nil {
+			return nil, err
+		}
+		added, err := metadata.addColumns(columnMap)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			actions = append(actions, actionT{MetaData: metadata})
+		}
+	}
+	if len(actions) == 0 && len(files) == 0 {
+		return nil, nil
+	}
+
+	if txn != nil {
+		actions = append(actions, actionT{Txn: &txnT{AppID: txn.AppID, Version: txn.Version, LastUpdated: now}})
+	}
+	for i := range files {
+		actions = append(actions, actionT{Add: &files[i]})
+	}
+	commitInfo := &commitInfoT{
+		Timestamp:           now,
+		Operation:           "WRITE",
+		OperationParameters: map[string]string{"mode": "Append", "partitionBy": "[]"},
+		IsBlindAppend:       true,
+		EngineInfo:          engineInfo,
+	}
+	if len(files) == 0 {
+		commitInfo.Operation, commitInfo.OperationParameters = "ADD COLUMNS", map[string]string{}
+		if snapshot.metadata == nil {
+			commitInfo.Operation = "CREATE TABLE"
+		}
+	}
+	return append(actions, actionT{CommitInfo: commitInfo}), nil
+}
+
+func (table *TableT) writeCommit(version int64, actions []actionT) error {
+	lines := make([]string, 0, len(actions))
+	for _, action := range actions {
+		line, err := json.Marshal(action)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(line))
+	}
+	return table.FileIO.WriteNew(table.logPath(commitFile(version)), []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+//checkpoint writes the state of snapshot as a checkpoint of its version, then points _last_checkpoint to it
+func (table *TableT) checkpoint(snapshot *snapshotT, timestampMs int64) error {
+	actions := snapshot.actions(timestampMs)
+	data, err := writeCheckpoint(actions)
+	if err != nil {
+		return err
+	}
+	if err = table.FileIO.Write(table.logPath(checkpointFile(snapshot.version)), data); err != nil {
+		return err
+	}
+	lastCheckpoint, err := json.Marshal(map[string]int64{"version": snapshot.version, "size": int64(len(actions))})
+	if err != nil {
+		return err
+	}
+	return table.FileIO.Write(table.logPath(lastCheckpointFile), lastCheckpoint)
+}
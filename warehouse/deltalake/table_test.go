@@ -0,0 +1,126 @@
+package deltalake
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+)
+
+var _ = Describe("Table", func() {
+	var (
+		root  string
+		table *TableT
+		txn   txnT
+	)
+
+	addedFile := func(path string) addT {
+		return addT{Path: path, PartitionValues: map[string]string{}, Size: 100, ModificationTime: 1, DataChange: true, Stats: `{"numRecords":2}`}
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "deltalake")
+		Expect(err).To(BeNil())
+		fileIO, err := fileio.NewLocalFileIO(root)
+		Expect(err).To(BeNil())
+		table = NewTable(fileIO, "rudder-datalake/namespace/tracks")
+		txn = txnT{AppID: "rudder-source-destination", Version: 1}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("should create the table with the protocol and metadata in its first commit", func() {
+		Expect(table.UpdateSchema(map[string]string{"id": "string", "received_at": "datetime"})).To(BeNil())
+
+		actions, err := table.readCommit(0)
+		Expect(err).To(BeNil())
+		Expect(actions[0].Protocol).To(Equal(&protocolT{MinReaderVersion: readerVersion, MinWriterVersion: writerVersion}))
+		Expect(actions[1].MetaData.SchemaString).To(Equal(`{"type":"struct","fields":[{"name":"id","type":"string","nullable":true,"metadata":{}},{"name":"received_at","type":"timestamp","nullable":true,"metadata":{}}]}`))
+		Expect(actions[2].CommitInfo.Operation).To(Equal("CREATE TABLE"))
+
+		columnMap, err := table.ColumnMap()
+		Expect(err).To(BeNil())
+		Expect(columnMap).To(Equal(map[string]string{"id": "string", "received_at": "datetime"}))
+	})
+
+	It("should evolve the schema when appending files with new columns", func() {
+		Expect(table.UpdateSchema(map[string]string{"id": "string"})).To(BeNil())
+		Expect(table.AppendFiles([]addT{addedFile("2021/06/01/10/load.parquet")}, map[string]string{"ID": "string", "count": "int"}, txn)).To(BeNil())
+
+		actions, err := table.readCommit(1)
+		Expect(err).To(BeNil())
+		Expect(actions).To(HaveLen(4))
+		Expect(actions[1].Txn.AppID).To(Equal(txn.AppID))
+		Expect(actions[2].Add.Path).To(Equal("2021/06/01/10/load.parquet"))
+		Expect(actions[3].CommitInfo.Operation).To(Equal("WRITE"))
+
+		columnMap, err := table.ColumnMap()
+		Expect(err).To(BeNil())
+		Expect(columnMap).To(Equal(map[string]string{"id": "string", "count": "int"}))
+	})
+
+	It("should skip transactions committed already", func() {
+		Expect(table.AppendFiles([]addT{addedFile("load.parquet")}, map[string]string{"id": "string"}, txn)).To(BeNil())
+		Expect(table.AppendFiles([]addT{addedFile("load.parquet")}, map[string]string{"id": "string"}, txn)).To(BeNil())
+
+		snapshot, err := table.snapshot()
+		Expect(err).To(BeNil())
+		Expect(snapshot.version).To(Equal(int64(0)))
+		Expect(snapshot.files).To(HaveLen(1))
+	})
+
+	It("should retry commits on top of versions committed concurrently", func() {
+		Expect(table.UpdateSchema(map[string]string{"id": "string"})).To(BeNil())
+		concurrentCommit := `{"metaData":{"id":"concurrent","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[{\"name\":\"id\",\"type\":\"string\",\"nullable\":true,\"metadata\":{}},{\"name\":\"price\",\"type\":\"double\",\"nullable\":true,\"metadata\":{}}]}","partitionColumns":[],"configuration":{}}}` + "\n"
+		Expect(ioutil.WriteFile(filepath.Join(root, table.logPath(commitFile(1))), []byte(concurrentCommit), 0644)).To(BeNil())
+
+		Expect(table.AppendFiles([]addT{addedFile("load.parquet")}, map[string]string{"id": "string", "count": "int"}, txn)).To(BeNil())
+
+		snapshot, err := table.snapshot()
+		Expect(err).To(BeNil())
+		Expect(snapshot.version).To(Equal(int64(2)))
+		Expect(snapshot.metadata.ID).To(Equal("concurrent"))
+		columnMap, err := snapshot.metadata.ColumnMap()
+		Expect(err).To(BeNil())
+		Expect(columnMap).To(Equal(map[string]string{"id": "string", "price": "float", "count": "int"}))
+	})
+
+	It("should checkpoint the state of the table and read it back", func() {
+		checkpointInterval = 2
+		defer func() { checkpointInterval = 10 }()
+
+		Expect(table.UpdateSchema(map[string]string{"id": "string"})).To(BeNil())
+		removedCommit := `{"metaData":{"id":"table","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[{\"name\":\"id\",\"type\":\"string\",\"nullable\":true,\"metadata\":{}}]}","partitionColumns":[],"configuration":{"delta.appendOnly":"true"}}}` + "\n" +
+			`{"add":{"path":"removed.parquet","partitionValues":{},"size":1,"modificationTime":1,"dataChange":true}}` + "\n"
+		Expect(ioutil.WriteFile(filepath.Join(root, table.logPath(commitFile(1))), []byte(removedCommit), 0644)).To(BeNil())
+		Expect(table.AppendFiles([]addT{addedFile("load.parquet")}, map[string]string{"id": "string"}, txn)).To(BeNil())
+
+		Expect(filepath.Join(root, table.logPath(checkpointFile(2)))).To(BeAnExistingFile())
+		lastCheckpoint, err := ioutil.ReadFile(filepath.Join(root, table.logPath(lastCheckpointFile)))
+		Expect(err).To(BeNil())
+		Expect(string(lastCheckpoint)).To(Equal(`{"size":5,"version":2}`))
+
+		//the state is read from the checkpoint only once the commits before it are cleaned up
+		for version := int64(0); version <= 2; version++ {
+			Expect(os.Remove(filepath.Join(root, table.logPath(commitFile(version))))).To(BeNil())
+		}
+		snapshot, err := table.snapshot()
+		Expect(err).To(BeNil())
+		Expect(snapshot.version).To(Equal(int64(2)))
+		Expect(snapshot.protocol).To(Equal(&protocolT{MinReaderVersion: readerVersion, MinWriterVersion: writerVersion}))
+		Expect(snapshot.metadata.ID).To(Equal("table"))
+		Expect(snapshot.metadata.Configuration).To(Equal(map[string]string{"delta.appendOnly": "true"}))
+		Expect(snapshot.metadata.PartitionColumns).To(BeEmpty())
+		Expect(snapshot.txns).To(HaveKey(txn.AppID))
+		Expect(snapshot.txns[txn.AppID].Version).To(Equal(txn.Version))
+		Expect(snapshot.files).To(HaveLen(2))
+		Expect(snapshot.files["load.parquet"]).To(Equal(addedFile("load.parquet")))
+	})
+})
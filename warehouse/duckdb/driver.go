@@ -0,0 +1,11 @@
+// +build cgo,go1.18
+// +build linux,amd64 duckdb_use_lib
+
+package duckdb
+
+/*
+The DuckDB driver is a cgo binding, written with generics, so it is only linked into builds with cgo enabled and go 1.18 or later.
+Linux amd64 builds link the static libduckdb, with the parquet extension, vendored with the driver. Other platforms need the duckdb_use_lib tag
+to link a libduckdb installed on the system. Builds without the driver, like the CGO_ENABLED=0 release builds, fail to connect to DuckDB destinations
+*/
+import _ "github.com/marcboeker/go-duckdb"
@@ -0,0 +1,6 @@
+// +build cgo,go1.18,!duckdb_use_lib
+
+package duckdb
+
+//The static libduckdb linked by the driver is in a package of its own, which is imported so that it gets vendored
+import _ "github.com/marcboeker/go-duckdb/deps/linux_amd64"
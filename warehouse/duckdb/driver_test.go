@@ -0,0 +1,125 @@
+// +build cgo,go1.18
+// +build linux,amd64 duckdb_use_lib
+
+package duckdb
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var _ = Describe("DuckDB driver", func() {
+	var (
+		root string
+		dk   *HandleT
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "duckdb")
+		Expect(err).To(BeNil())
+		db, err := connect(filepath.Join(root, "warehouse", "warehouse.duckdb"))
+		Expect(err).To(BeNil())
+		dk = &HandleT{Db: db, Namespace: "rudder", Uploader: &uploaderStubT{loadFileType: warehouseutils.LOAD_FILE_TYPE_CSV}}
+	})
+
+	AfterEach(func() {
+		Expect(dk.Db.Close()).To(Succeed())
+		os.RemoveAll(root)
+	})
+
+	It("should create and evolve tables and fetch their schema", func() {
+		Expect(dk.CreateSchema()).To(Succeed())
+		Expect(dk.CreateTable("tracks", map[string]string{"id": "string", "received_at": "datetime", "revenue": "float"})).To(Succeed())
+		Expect(dk.AddColumn("tracks", "count", "int")).To(Succeed())
+		Expect(dk.AddColumn("tracks", "properties", "json")).To(Succeed())
+		Expect(dk.AddColumn("tracks", "enabled", "boolean")).To(Succeed())
+		Expect(dk.AlterColumn("tracks", "count", "float")).To(Succeed())
+
+		schema, err := dk.fetchSchema(dk.Db)
+		Expect(err).To(BeNil())
+		Expect(schema).To(Equal(warehouseutils.SchemaT{
+			"tracks": {"id": "string", "received_at": "datetime", "revenue": "float", "count": "float", "enabled": "boolean", "properties": "string"},
+		}))
+	})
+
+	It("should read parquet load files with different columns by name", func() {
+		writeLoadFile := func(name string, schema warehouseutils.TableSchemaT, rows ...[]interface{}) string {
+			loadFile := filepath.Join(root, name)
+			writer, err := warehouseutils.CreateParquetWriter(schema, loadFile, warehouseutils.DUCKDB)
+			Expect(err).To(BeNil())
+			for _, row := range rows {
+				Expect(writer.WriteRow(row)).To(Succeed())
+			}
+			Expect(writer.Close()).To(Succeed())
+			return loadFile
+		}
+		receivedAt, err := warehouseutils.GetParquetValue("2021-09-01T10:00:00Z", "datetime")
+		Expect(err).To(BeNil())
+		loadFiles := []string{
+			writeLoadFile("tracks-1.parquet", warehouseutils.TableSchemaT{"id": "string", "received_at": "datetime", "revenue": "float"}, []interface{}{"id-1", receivedAt, 1.5}),
+			writeLoadFile("tracks-2.parquet", warehouseutils.TableSchemaT{"event": "string", "id": "string", "received_at": "datetime"}, []interface{}{"purchase", "id-2", receivedAt}),
+		}
+
+		dk.Uploader = &uploaderStubT{loadFileType: warehouseutils.LOAD_FILE_TYPE_PARQUET}
+		tableSchema := warehouseutils.TableSchemaT{"event": "string", "id": "string", "received_at": "datetime", "revenue": "float"}
+		Expect(dk.CreateSchema()).To(Succeed())
+		Expect(dk.CreateTable("tracks", tableSchema)).To(Succeed())
+		_, err = dk.Db.Exec(`INSERT INTO "rudder"."tracks" ("event", "id", "received_at", "revenue") SELECT "event", "id", "received_at", "revenue" FROM ` +
+			dk.loadFilesExpression(loadFiles, []string{"event", "id", "received_at", "revenue"}, tableSchema))
+		Expect(err).To(BeNil())
+
+		var event, revenue sql.NullString
+		Expect(dk.Db.QueryRow(`SELECT "event", CAST("revenue" AS VARCHAR) FROM "rudder"."tracks" WHERE "id" = 'id-1'`).Scan(&event, &revenue)).To(Succeed())
+		Expect(event.Valid).To(BeFalse())
+		Expect(revenue.String).To(Equal("1.5"))
+		Expect(dk.Db.QueryRow(`SELECT "event" FROM "rudder"."tracks" WHERE "id" = 'id-2' AND "received_at" = TIMESTAMPTZ '2021-09-01 10:00:00+00'`).Scan(&event)).To(Succeed())
+		Expect(event.String).To(Equal("purchase"))
+	})
+
+	It("should read gzipped csv load files with the sorted columns of the upload", func() {
+		loadFile := filepath.Join(root, "tracks.csv.gz")
+		file, err := os.Create(loadFile)
+		Expect(err).To(BeNil())
+		gzWriter := gzip.NewWriter(file)
+		_, err = gzWriter.Write([]byte("id-1,2021-09-01 10:00:00+00,1.5\nid-2,2021-09-01 11:00:00+00,\n"))
+		Expect(err).To(BeNil())
+		Expect(gzWriter.Close()).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		tableSchema := warehouseutils.TableSchemaT{"id": "string", "received_at": "datetime", "revenue": "float"}
+		Expect(dk.CreateSchema()).To(Succeed())
+		Expect(dk.CreateTable("tracks", tableSchema)).To(Succeed())
+		_, err = dk.Db.Exec(`INSERT INTO "rudder"."tracks" ("id", "received_at", "revenue") SELECT "id", "received_at", "revenue" FROM ` +
+			dk.loadFilesExpression([]string{loadFile}, []string{"id", "received_at", "revenue"}, tableSchema))
+		Expect(err).To(BeNil())
+
+		total, err := dk.GetTotalCountInTable("tracks")
+		Expect(err).To(BeNil())
+		Expect(total).To(Equal(int64(2)))
+		var revenue float64
+		Expect(dk.Db.QueryRow(`SELECT sum("revenue") FROM "rudder"."tracks"`).Scan(&revenue)).To(Succeed())
+		Expect(revenue).To(Equal(1.5))
+	})
+
+	It("should drop dangling staging tables", func() {
+		Expect(dk.CreateSchema()).To(Succeed())
+		Expect(dk.CreateTable("tracks", map[string]string{"id": "string"})).To(Succeed())
+		Expect(dk.CreateTable(stagingTablePrefix+"tracks_1", map[string]string{"id": "string"})).To(Succeed())
+
+		Expect(dk.dropDanglingStagingTables()).To(BeTrue())
+		schema, err := dk.fetchSchema(dk.Db)
+		Expect(err).To(BeNil())
+		Expect(schema).To(HaveKey("tracks"))
+		_, err = dk.GetTotalCountInTable(stagingTablePrefix + "tracks_1")
+		Expect(err).To(HaveOccurred())
+	})
+})
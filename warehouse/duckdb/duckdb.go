@@ -0,0 +1,587 @@
+package duckdb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/rruntime"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	"github.com/rudderlabs/rudder-server/warehouse/client"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	stagingTablePrefix string
+	pkgLogger          logger.LoggerI
+)
+
+const (
+	path = "path"
+)
+
+const PROVIDER = "DUCKDB"
+
+//driverName is the database/sql driver DuckDB databases are opened with, registered by github.com/marcboeker/go-duckdb
+//in the builds it is linked into, see driver.go
+const driverName = "duckdb"
+
+//json columns are VARCHAR, as the JSON type needs the json extension, which the vendored DuckDB driver doesn't link
+var rudderDataTypesMapToDuckDB = map[string]string{
+	"int":      "BIGINT",
+	"float":    "DOUBLE",
+	"string":   "VARCHAR",
+	"datetime": "TIMESTAMPTZ",
+	"boolean":  "BOOLEAN",
+	"json":     "VARCHAR",
+}
+
+var duckDBDataTypesMapToRudder = map[string]string{
+	"BIGINT":                   "int",
+	"INTEGER":                  "int",
+	"SMALLINT":                 "int",
+	"TINYINT":                  "int",
+	"HUGEINT":                  "int",
+	"DOUBLE":                   "float",
+	"FLOAT":                    "float",
+	"REAL":                     "float",
+	"DECIMAL":                  "float",
+	"VARCHAR":                  "string",
+	"TEXT":                     "string",
+	"TIMESTAMP WITH TIME ZONE": "datetime",
+	"TIMESTAMPTZ":              "datetime",
+	"TIMESTAMP":                "datetime",
+	"BOOLEAN":                  "boolean",
+	"JSON":                     "json",
+}
+
+type HandleT struct {
+	Db            *sql.DB
+	Namespace     string
+	ObjectStorage string
+	Warehouse     warehouseutils.WarehouseT
+	Uploader      warehouseutils.UploaderI
+}
+
+var primaryKeyMap = map[string]string{
+	warehouseutils.UsersTable:      "id",
+	warehouseutils.IdentifiesTable: "id",
+	warehouseutils.DiscardsTable:   "row_id",
+}
+
+var partitionKeyMap = map[string]string{
+	warehouseutils.UsersTable:      "id",
+	warehouseutils.IdentifiesTable: "id",
+	warehouseutils.DiscardsTable:   "row_id, column_name, table_name",
+}
+
+func connect(databasePath string) (*sql.DB, error) {
+	if !misc.ContainsString(sql.Drivers(), driverName) {
+		return nil, fmt.Errorf("duckdb connection error : (no %s database/sql driver linked into this build)", driverName)
+	}
+	if databasePath == "" {
+		return nil, fmt.Errorf("duckdb connection error : (database path is not set)")
+	}
+	if err := os.MkdirAll(filepath.Dir(databasePath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("duckdb connection error : (%v)", err)
+	}
+	db, err := sql.Open(driverName, databasePath)
+	if err != nil {
+		return nil, fmt.Errorf("duckdb connection error : (%v)", err)
+	}
+	return db, nil
+}
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("warehouse").Child("duckdb")
+}
+
+func loadConfig() {
+	stagingTablePrefix = "rudder_staging_"
+}
+
+func (dk *HandleT) getDatabasePath() string {
+	return warehouseutils.GetConfigValue(path, dk.Warehouse)
+}
+
+func columnsWithDataTypes(columns map[string]string) string {
+	var arr []string
+	for _, name := range warehouseutils.SortColumnKeysFromColumnMap(columns) {
+		arr = append(arr, fmt.Sprintf(`"%s" %s`, name, rudderDataTypesMapToDuckDB[columns[name]]))
+	}
+	return strings.Join(arr, ",")
+}
+
+func quotedColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, fmt.Sprintf(`"%s"`, column))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (dk *HandleT) IsEmpty(warehouse warehouseutils.WarehouseT) (empty bool, err error) {
+	return
+}
+
+func (dk *HandleT) DownloadLoadFiles(tableName string) ([]string, error) {
+	objects := dk.Uploader.GetLoadFilesMetadata(warehouseutils.GetLoadFilesOptionsT{Table: tableName})
+	storageProvider := warehouseutils.ObjectStorageType(dk.Warehouse.Destination.DestinationDefinition.Name, dk.Warehouse.Destination.Config, dk.Uploader.UseRudderStorage())
+	downloader, err := filemanager.New(&filemanager.SettingsT{
+		Provider: storageProvider,
+		Config: misc.GetObjectStorageConfig(misc.ObjectStorageOptsT{
+			Provider:         storageProvider,
+			Config:           dk.Warehouse.Destination.Config,
+			UseRudderStorage: dk.Uploader.UseRudderStorage(),
+		}),
+	})
+	if err != nil {
+		pkgLogger.Errorf("DK: Error in setting up a downloader for destionationID : %s Error : %v", dk.Warehouse.Destination.ID, err)
+		return nil, err
+	}
+	var fileNames []string
+	for _, object := range objects {
+		objectName, err := warehouseutils.GetObjectName(object.Location, dk.Warehouse.Destination.Config, dk.ObjectStorage)
+		if err != nil {
+			pkgLogger.Errorf("DK: Error in converting object location to object key for table:%s: %s,%v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		dirName := "/rudder-warehouse-load-uploads-tmp/"
+		tmpDirPath, err := misc.CreateTMPDIR()
+		if err != nil {
+			pkgLogger.Errorf("DK: Error in creating tmp directory for downloading load file for table:%s: %s, %v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		objectPath := tmpDirPath + dirName + fmt.Sprintf(`%s_%s_%d/`, dk.Warehouse.Destination.DestinationDefinition.Name, dk.Warehouse.Destination.ID, time.Now().Unix()) + objectName
+		err = os.MkdirAll(filepath.Dir(objectPath), os.ModePerm)
+		if err != nil {
+			pkgLogger.Errorf("DK: Error in making tmp directory for downloading load file for table:%s: %s, %v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		objectFile, err := os.Create(objectPath)
+		if err != nil {
+			pkgLogger.Errorf("DK: Error in creating file in tmp directory for downloading load file for table:%s: %s, %v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		err = downloader.Download(objectFile, objectName)
+		if err != nil {
+			objectFile.Close()
+			pkgLogger.Errorf("DK: Error in downloading file in tmp directory for downloading load file for table:%s: %s, %v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		fileName := objectFile.Name()
+		if err = objectFile.Close(); err != nil {
+			pkgLogger.Errorf("DK: Error in closing downloaded file in tmp directory for downloading load file for table:%s: %s, %v", tableName, object.Location, err)
+			return fileNames, err
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	return fileNames, nil
+}
+
+//loadFilesExpression reads the load files as a table. Parquet load files have the columns of the merged schema of the upload,
+//gzipped csv load files the sorted columns of the table in the upload
+func (dk *HandleT) loadFilesExpression(fileNames []string, sortedColumnKeys []string, tableSchemaInUpload warehouseutils.TableSchemaT) string {
+	quotedFileNames := make([]string, 0, len(fileNames))
+	for _, fileName := range fileNames {
+		quotedFileNames = append(quotedFileNames, fmt.Sprintf(`'%s'`, strings.ReplaceAll(fileName, `'`, `''`)))
+	}
+	files := fmt.Sprintf(`[%s]`, strings.Join(quotedFileNames, ", "))
+	if dk.Uploader.GetLoadFileType() == warehouseutils.LOAD_FILE_TYPE_PARQUET {
+		return fmt.Sprintf(`read_parquet(%s, union_by_name=true)`, files)
+	}
+	columnTypes := make([]string, 0, len(sortedColumnKeys))
+	for _, column := range sortedColumnKeys {
+		columnTypes = append(columnTypes, fmt.Sprintf(`'%s': '%s'`, column, rudderDataTypesMapToDuckDB[tableSchemaInUpload[column]]))
+	}
+	return fmt.Sprintf(`read_csv(%s, header=false, compression='gzip', columns={%s})`, files, strings.Join(columnTypes, ", "))
+}
+
+func (dk *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutils.TableSchemaT, skipTempTableDelete bool) (stagingTableName string, err error) {
+	pkgLogger.Infof("DK: Starting load for table:%s", tableName)
+
+	// sort column names
+	sortedColumnKeys := warehouseutils.SortColumnKeysFromColumnMap(tableSchemaInUpload)
+	sortedColumnString := quotedColumns(sortedColumnKeys)
+
+	fileNames, err := dk.DownloadLoadFiles(tableName)
+	defer misc.RemoveFilePaths(fileNames...)
+	if err != nil {
+		return
+	}
+
+	txn, err := dk.Db.Begin()
+	if err != nil {
+		pkgLogger.Errorf("DK: Error while beginning a transaction in db for loading in table:%s: %v", tableName, err)
+		return
+	}
+	// create staging table with the columns of the table in the upload
+	stagingTableName = misc.TruncateStr(fmt.Sprintf(`%s%s_%s`, stagingTablePrefix, tableName, strings.ReplaceAll(uuid.NewV4().String(), "-", "")), 63)
+	sqlStatement := fmt.Sprintf(`CREATE TABLE "%[1]s"."%[2]s" AS SELECT %[4]s FROM "%[1]s"."%[3]s" WHERE false`, dk.Namespace, stagingTableName, tableName, sortedColumnString)
+	pkgLogger.Debugf("DK: Creating staging table for table:%s at %s\n", tableName, sqlStatement)
+	_, err = txn.Exec(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("DK: Error creating staging table for table:%s: %v\n", tableName, err)
+		txn.Rollback()
+		return
+	}
+	if !skipTempTableDelete {
+		defer dk.dropStagingTable(stagingTableName)
+	}
+
+	if len(fileNames) > 0 {
+		sqlStatement = fmt.Sprintf(`INSERT INTO "%[1]s"."%[2]s" (%[3]s) SELECT %[3]s FROM %[4]s`, dk.Namespace, stagingTableName, sortedColumnString, dk.loadFilesExpression(fileNames, sortedColumnKeys, tableSchemaInUpload))
+		pkgLogger.Debugf("DK: Loading load files into staging table:%s: %s\n", stagingTableName, sqlStatement)
+		_, err = txn.Exec(sqlStatement)
+		if err != nil {
+			pkgLogger.Errorf("DK: Error loading load files into staging table:%s: %v", stagingTableName, err)
+			txn.Rollback()
+			return
+		}
+	}
+
+	// deduplication process
+	primaryKey := "id"
+	if column, ok := primaryKeyMap[tableName]; ok {
+		primaryKey = column
+	}
+	partitionKey := "id"
+	if column, ok := partitionKeyMap[tableName]; ok {
+		partitionKey = column
+	}
+	var additionalJoinClause string
+	if tableName == warehouseutils.DiscardsTable {
+		additionalJoinClause = fmt.Sprintf(`AND _source.%[3]s = "%[1]s"."%[2]s".%[3]s AND _source.%[4]s = "%[1]s"."%[2]s".%[4]s`, dk.Namespace, tableName, "table_name", "column_name")
+	}
+	sqlStatement = fmt.Sprintf(`DELETE FROM "%[1]s"."%[2]s" USING "%[1]s"."%[3]s" AS _source WHERE (_source.%[4]s = "%[1]s"."%[2]s".%[4]s %[5]s)`, dk.Namespace, tableName, stagingTableName, primaryKey, additionalJoinClause)
+	pkgLogger.Infof("DK: Deduplicate records for table:%s using staging table: %s\n", tableName, sqlStatement)
+	_, err = txn.Exec(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("DK: Error deleting from original table for dedup: %v\n", err)
+		txn.Rollback()
+		return
+	}
+	sqlStatement = fmt.Sprintf(`INSERT INTO "%[1]s"."%[2]s" (%[3]s) SELECT %[3]s FROM ( SELECT *, row_number() OVER (PARTITION BY %[5]s ORDER BY received_at DESC) AS _rudder_staging_row_number FROM "%[1]s"."%[4]s" ) AS _ WHERE _rudder_staging_row_number = 1`, dk.Namespace, tableName, sortedColumnString, stagingTableName, partitionKey)
+	pkgLogger.Infof("DK: Inserting records for table:%s using staging table: %s\n", tableName, sqlStatement)
+	_, err = txn.Exec(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("DK: Error inserting into original table: %v\n", err)
+		txn.Rollback()
+		return
+	}
+
+	if err = txn.Commit(); err != nil {
+		pkgLogger.Errorf("DK: Error while committing transaction as there was error while loading staging table:%s: %v", stagingTableName, err)
+		txn.Rollback()
+		return
+	}
+
+	pkgLogger.Infof("DK: Complete load for table:%s", tableName)
+	return
+}
+
+func (dk *HandleT) loadUserTables() (errorMap map[string]error) {
+	errorMap = map[string]error{warehouseutils.IdentifiesTable: nil}
+	pkgLogger.Infof("DK: Starting load for identifies and users tables\n")
+	identifyStagingTable, err := dk.loadTable(warehouseutils.IdentifiesTable, dk.Uploader.GetTableSchemaInUpload(warehouseutils.IdentifiesTable), true)
+	defer dk.dropStagingTable(identifyStagingTable)
+	if err != nil {
+		errorMap[warehouseutils.IdentifiesTable] = err
+		return
+	}
+
+	if len(dk.Uploader.GetTableSchemaInUpload(warehouseutils.UsersTable)) == 0 {
+		return
+	}
+	errorMap[warehouseutils.UsersTable] = nil
+
+	unionStagingTableName := misc.TruncateStr(fmt.Sprintf(`%s%s_%s`, stagingTablePrefix, strings.ReplaceAll(uuid.NewV4().String(), "-", ""), "users_identifies_union"), 63)
+	stagingTableName := misc.TruncateStr(fmt.Sprintf(`%s%s_%s`, stagingTablePrefix, strings.ReplaceAll(uuid.NewV4().String(), "-", ""), warehouseutils.UsersTable), 63)
+	defer dk.dropStagingTable(stagingTableName)
+	defer dk.dropStagingTable(unionStagingTableName)
+
+	userColMap := dk.Uploader.GetTableSchemaInWarehouse(warehouseutils.UsersTable)
+	var userColNames, firstValProps []string
+	for _, colName := range warehouseutils.SortColumnKeysFromColumnMap(userColMap) {
+		if colName == "id" {
+			continue
+		}
+		userColNames = append(userColNames, colName)
+		// the latest non null value of every trait of a user, over the users table and the identifies of the upload
+		firstValProps = append(firstValProps, fmt.Sprintf(`arg_max("%[1]s", received_at) FILTER (WHERE "%[1]s" IS NOT NULL) AS "%[1]s"`, colName))
+	}
+
+	sqlStatement := fmt.Sprintf(`CREATE TABLE "%[1]s"."%[5]s" AS (
+									(
+										SELECT id, %[4]s FROM "%[1]s"."%[2]s" WHERE id IN (SELECT user_id FROM "%[1]s"."%[3]s" WHERE user_id IS NOT NULL)
+									) UNION ALL
+									(
+										SELECT user_id AS id, %[4]s FROM "%[1]s"."%[3]s" WHERE user_id IS NOT NULL
+									)
+								)`, dk.Namespace, warehouseutils.UsersTable, identifyStagingTable, quotedColumns(userColNames), unionStagingTableName)
+	pkgLogger.Infof("DK: Creating staging table for union of users table with identify staging table: %s\n", sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	if err != nil {
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+
+	sqlStatement = fmt.Sprintf(`CREATE TABLE "%[1]s"."%[2]s" AS SELECT id, %[3]s FROM "%[1]s"."%[4]s" GROUP BY id`, dk.Namespace, stagingTableName, strings.Join(firstValProps, ", "), unionStagingTableName)
+	pkgLogger.Debugf("DK: Creating staging table for users: %s\n", sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	if err != nil {
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+
+	// BEGIN TRANSACTION
+	tx, err := dk.Db.Begin()
+	if err != nil {
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+
+	primaryKey := "id"
+	sqlStatement = fmt.Sprintf(`DELETE FROM "%[1]s"."%[2]s" USING "%[1]s"."%[3]s" AS _source WHERE (_source.%[4]s = "%[1]s"."%[2]s".%[4]s)`, dk.Namespace, warehouseutils.UsersTable, stagingTableName, primaryKey)
+	pkgLogger.Infof("DK: Dedup records for table:%s using staging table: %s\n", warehouseutils.UsersTable, sqlStatement)
+	_, err = tx.Exec(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("DK: Error deleting from original table for dedup: %v\n", err)
+		tx.Rollback()
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+
+	columns := quotedColumns(append([]string{"id"}, userColNames...))
+	sqlStatement = fmt.Sprintf(`INSERT INTO "%[1]s"."%[2]s" (%[4]s) SELECT %[4]s FROM "%[1]s"."%[3]s"`, dk.Namespace, warehouseutils.UsersTable, stagingTableName, columns)
+	pkgLogger.Infof("DK: Inserting records for table:%s using staging table: %s\n", warehouseutils.UsersTable, sqlStatement)
+	_, err = tx.Exec(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("DK: Error inserting into users table from staging table: %v\n", err)
+		tx.Rollback()
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		pkgLogger.Errorf("DK: Error in transaction commit for users table: %v\n", err)
+		tx.Rollback()
+		errorMap[warehouseutils.UsersTable] = err
+		return
+	}
+	return
+}
+
+func (dk *HandleT) CreateSchema() (err error) {
+	sqlStatement := fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, dk.Namespace)
+	pkgLogger.Infof("DK: Creating schema name in duckdb for DK:%s : %v", dk.Warehouse.Destination.ID, sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	return
+}
+
+func (dk *HandleT) dropStagingTable(stagingTableName string) {
+	if stagingTableName == "" {
+		return
+	}
+	pkgLogger.Infof("DK: dropping table %+v\n", stagingTableName)
+	_, err := dk.Db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"."%s"`, dk.Namespace, stagingTableName))
+	if err != nil {
+		pkgLogger.Errorf("DK: Error dropping staging table %s in duckdb: %v", stagingTableName, err)
+	}
+}
+
+func (dk *HandleT) CreateTable(tableName string, columnMap map[string]string) (err error) {
+	sqlStatement := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s"."%s" ( %v )`, dk.Namespace, tableName, columnsWithDataTypes(columnMap))
+	pkgLogger.Infof("DK: Creating table in duckdb for DK:%s : %v", dk.Warehouse.Destination.ID, sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	return
+}
+
+func (dk *HandleT) AddColumn(tableName string, columnName string, columnType string) (err error) {
+	sqlStatement := fmt.Sprintf(`ALTER TABLE "%s"."%s" ADD COLUMN IF NOT EXISTS "%s" %s`, dk.Namespace, tableName, columnName, rudderDataTypesMapToDuckDB[columnType])
+	pkgLogger.Infof("DK: Adding column in duckdb for DK:%s : %v", dk.Warehouse.Destination.ID, sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	return
+}
+
+func (dk *HandleT) AlterColumn(tableName string, columnName string, columnType string) (err error) {
+	sqlStatement := fmt.Sprintf(`ALTER TABLE "%s"."%s" ALTER COLUMN "%s" TYPE %s`, dk.Namespace, tableName, columnName, rudderDataTypesMapToDuckDB[columnType])
+	pkgLogger.Infof("DK: Altering column in duckdb for DK:%s : %v", dk.Warehouse.Destination.ID, sqlStatement)
+	_, err = dk.Db.Exec(sqlStatement)
+	return
+}
+
+func (dk *HandleT) TestConnection(warehouse warehouseutils.WarehouseT) (err error) {
+	dk.Warehouse = warehouse
+	dk.Db, err = connect(dk.getDatabasePath())
+	if err != nil {
+		return
+	}
+	pingResultChannel := make(chan error, 1)
+	defer dk.Db.Close()
+	rruntime.Go(func() {
+		pingResultChannel <- dk.Db.Ping()
+	})
+	var timeOut time.Duration = 5
+	select {
+	case err = <-pingResultChannel:
+	case <-time.After(timeOut * time.Second):
+		err = fmt.Errorf("connection testing timed out after %d sec", timeOut)
+	}
+	return
+}
+
+func (dk *HandleT) Setup(warehouse warehouseutils.WarehouseT, uploader warehouseutils.UploaderI) (err error) {
+	dk.Warehouse = warehouse
+	dk.Namespace = warehouse.Namespace
+	dk.Uploader = uploader
+	dk.ObjectStorage = warehouseutils.ObjectStorageType(warehouseutils.DUCKDB, warehouse.Destination.Config, dk.Uploader.UseRudderStorage())
+
+	dk.Db, err = connect(dk.getDatabasePath())
+	return err
+}
+
+func (dk *HandleT) CrashRecover(warehouse warehouseutils.WarehouseT) (err error) {
+	dk.Warehouse = warehouse
+	dk.Namespace = warehouse.Namespace
+	dk.Db, err = connect(dk.getDatabasePath())
+	if err != nil {
+		return err
+	}
+	defer dk.Db.Close()
+	dk.dropDanglingStagingTables()
+	return
+}
+
+func (dk *HandleT) dropDanglingStagingTables() bool {
+	sqlStatement := fmt.Sprintf(`SELECT table_name FROM information_schema.tables WHERE table_schema = '%s' AND table_name LIKE '%s%s'`, dk.Namespace, stagingTablePrefix, "%")
+	rows, err := dk.Db.Query(sqlStatement)
+	if err != nil {
+		pkgLogger.Errorf("WH: DK: Error dropping dangling staging tables in DK: %v\nQuery: %s\n", err, sqlStatement)
+		return false
+	}
+	defer rows.Close()
+
+	var stagingTableNames []string
+	for rows.Next() {
+		var tableName string
+		err := rows.Scan(&tableName)
+		if err != nil {
+			panic(fmt.Errorf("Failed to scan result from query: %s\nwith Error : %w", sqlStatement, err))
+		}
+		stagingTableNames = append(stagingTableNames, tableName)
+	}
+	pkgLogger.Infof("WH: DK: Dropping dangling staging tables: %+v  %+v\n", len(stagingTableNames), stagingTableNames)
+	delSuccess := true
+	for _, stagingTableName := range stagingTableNames {
+		_, err := dk.Db.Exec(fmt.Sprintf(`DROP TABLE "%[1]s"."%[2]s"`, dk.Namespace, stagingTableName))
+		if err != nil {
+			pkgLogger.Errorf("WH: DK: Error dropping dangling staging table: %s in DK: %v\n", stagingTableName, err)
+			delSuccess = false
+		}
+	}
+	return delSuccess
+}
+
+// FetchSchema queries duckdb and returns the schema associated with provided namespace
+func (dk *HandleT) FetchSchema(warehouse warehouseutils.WarehouseT) (schema warehouseutils.SchemaT, err error) {
+	dk.Warehouse = warehouse
+	dk.Namespace = warehouse.Namespace
+	dbHandle, err := connect(dk.getDatabasePath())
+	if err != nil {
+		return
+	}
+	defer dbHandle.Close()
+	return dk.fetchSchema(dbHandle)
+}
+
+func (dk *HandleT) fetchSchema(dbHandle *sql.DB) (schema warehouseutils.SchemaT, err error) {
+	schema = make(warehouseutils.SchemaT)
+	sqlStatement := fmt.Sprintf(`SELECT t.table_name, c.column_name, c.data_type FROM information_schema.tables t LEFT JOIN information_schema.columns c ON (t.table_name = c.table_name AND t.table_schema = c.table_schema) WHERE t.table_schema = '%s' AND t.table_name NOT LIKE '%s%s'`, dk.Namespace, stagingTablePrefix, "%")
+
+	rows, err := dbHandle.Query(sqlStatement)
+	if err != nil && err != sql.ErrNoRows {
+		pkgLogger.Errorf("DK: Error in fetching schema from duckdb destination:%v, query: %v", dk.Warehouse.Destination.ID, sqlStatement)
+		return
+	}
+	if err == sql.ErrNoRows {
+		pkgLogger.Infof("DK: No rows, while fetching schema from  destination:%v, query: %v", dk.Warehouse.Identifier, sqlStatement)
+		return schema, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tName, cName, cType sql.NullString
+		err = rows.Scan(&tName, &cName, &cType)
+		if err != nil {
+			pkgLogger.Errorf("DK: Error in processing fetched schema from duckdb destination:%v", dk.Warehouse.Destination.ID)
+			return
+		}
+		if _, ok := schema[tName.String]; !ok {
+			schema[tName.String] = make(map[string]string)
+		}
+		if cName.Valid && cType.Valid {
+			// decimals are reported with their width and scale, eg. DECIMAL(18,3)
+			dataType := strings.ToUpper(strings.SplitN(cType.String, "(", 2)[0])
+			if datatype, ok := duckDBDataTypesMapToRudder[dataType]; ok {
+				schema[tName.String][cName.String] = datatype
+			}
+		}
+	}
+	return
+}
+
+func (dk *HandleT) LoadUserTables() map[string]error {
+	return dk.loadUserTables()
+}
+
+func (dk *HandleT) LoadTable(tableName string) error {
+	_, err := dk.loadTable(tableName, dk.Uploader.GetTableSchemaInUpload(tableName), false)
+	return err
+}
+
+func (dk *HandleT) Cleanup() {
+	if dk.Db != nil {
+		dk.dropDanglingStagingTables()
+		dk.Db.Close()
+	}
+}
+
+func (dk *HandleT) LoadIdentityMergeRulesTable() (err error) {
+	return
+}
+
+func (dk *HandleT) LoadIdentityMappingsTable() (err error) {
+	return
+}
+
+func (dk *HandleT) DownloadIdentityRules(*misc.GZipWriter) (err error) {
+	return
+}
+
+func (dk *HandleT) GetTotalCountInTable(tableName string) (total int64, err error) {
+	sqlStatement := fmt.Sprintf(`SELECT count(*) FROM "%[1]s"."%[2]s"`, dk.Namespace, tableName)
+	err = dk.Db.QueryRow(sqlStatement).Scan(&total)
+	if err != nil {
+		pkgLogger.Errorf(`DK: Error getting total count in table %s:%s`, dk.Namespace, tableName)
+	}
+	return
+}
+
+func (dk *HandleT) Connect(warehouse warehouseutils.WarehouseT) (client.Client, error) {
+	dk.Warehouse = warehouse
+	dk.Namespace = warehouse.Namespace
+	dbHandle, err := connect(dk.getDatabasePath())
+	if err != nil {
+		return client.Client{}, err
+	}
+
+	return client.Client{Type: client.SQLClient, SQL: dbHandle}, err
+}
@@ -0,0 +1,13 @@
+package duckdb
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDuckDB(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DuckDB Suite")
+}
@@ -0,0 +1,93 @@
+package duckdb
+
+import (
+	"database/sql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/utils/misc"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+type uploaderStubT struct {
+	warehouseutils.UploaderI
+	loadFileType string
+}
+
+func (uploader *uploaderStubT) GetLoadFileType() string {
+	return uploader.loadFileType
+}
+
+var _ = Describe("DuckDB", func() {
+	var (
+		dk   *HandleT
+		mock sqlmock.Sqlmock
+	)
+
+	BeforeEach(func() {
+		db, sqlMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).To(BeNil())
+		mock = sqlMock
+		dk = &HandleT{Db: db, Namespace: "rudder"}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).To(Succeed())
+	})
+
+	It("should not connect without a duckdb driver linked", func() {
+		if misc.ContainsString(sql.Drivers(), driverName) {
+			Skip("the duckdb driver is linked into this build")
+		}
+		_, err := connect("/tmp/rudder.duckdb")
+		Expect(err).To(MatchError(ContainSubstring("no duckdb database/sql driver")))
+	})
+
+	Context("Schema evolution", func() {
+		It("should create tables with the sorted columns", func() {
+			mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "rudder"."tracks" ( "event" VARCHAR,"id" VARCHAR,"received_at" TIMESTAMPTZ,"revenue" DOUBLE )`).WillReturnResult(sqlmock.NewResult(0, 0))
+			err := dk.CreateTable("tracks", map[string]string{"id": "string", "received_at": "datetime", "event": "string", "revenue": "float"})
+			Expect(err).To(BeNil())
+		})
+
+		It("should add and alter columns", func() {
+			mock.ExpectExec(`ALTER TABLE "rudder"."tracks" ADD COLUMN IF NOT EXISTS "count" BIGINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`ALTER TABLE "rudder"."tracks" ALTER COLUMN "count" TYPE DOUBLE`).WillReturnResult(sqlmock.NewResult(0, 0))
+			Expect(dk.AddColumn("tracks", "count", "int")).To(Succeed())
+			Expect(dk.AlterColumn("tracks", "count", "float")).To(Succeed())
+		})
+	})
+
+	It("should fetch the schema of the namespace", func() {
+		rows := sqlmock.NewRows([]string{"table_name", "column_name", "data_type"}).
+			AddRow("tracks", "id", "VARCHAR").
+			AddRow("tracks", "revenue", "DECIMAL(18,3)").
+			AddRow("tracks", "received_at", "TIMESTAMP WITH TIME ZONE").
+			AddRow("tracks", "tags", "VARCHAR[]").
+			AddRow("empty", nil, nil)
+		mock.ExpectQuery(`SELECT t.table_name, c.column_name, c.data_type FROM information_schema.tables t LEFT JOIN information_schema.columns c ON (t.table_name = c.table_name AND t.table_schema = c.table_schema) WHERE t.table_schema = 'rudder' AND t.table_name NOT LIKE 'rudder_staging_%'`).WillReturnRows(rows)
+
+		schema, err := dk.fetchSchema(dk.Db)
+		Expect(err).To(BeNil())
+		Expect(schema).To(Equal(warehouseutils.SchemaT{
+			"tracks": {"id": "string", "revenue": "float", "received_at": "datetime"},
+			"empty":  {},
+		}))
+	})
+
+	Context("Load files", func() {
+		It("should read parquet load files by column name", func() {
+			dk.Uploader = &uploaderStubT{loadFileType: warehouseutils.LOAD_FILE_TYPE_PARQUET}
+			expression := dk.loadFilesExpression([]string{"/tmp/a.parquet", "/tmp/b'.parquet"}, []string{"id"}, warehouseutils.TableSchemaT{"id": "string"})
+			Expect(expression).To(Equal(`read_parquet(['/tmp/a.parquet', '/tmp/b''.parquet'], union_by_name=true)`))
+		})
+
+		It("should read csv load files with the sorted columns of the upload", func() {
+			dk.Uploader = &uploaderStubT{loadFileType: warehouseutils.LOAD_FILE_TYPE_CSV}
+			expression := dk.loadFilesExpression([]string{"/tmp/a.csv.gz"}, []string{"id", "received_at"}, warehouseutils.TableSchemaT{"id": "string", "received_at": "datetime"})
+			Expect(expression).To(Equal(`read_csv(['/tmp/a.csv.gz'], header=false, compression='gzip', columns={'id': 'VARCHAR', 'received_at': 'TIMESTAMPTZ'})`))
+		})
+	})
+})
@@ -0,0 +1,272 @@
+package fileio
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+	"github.com/rudderlabs/rudder-server/utils/misc"
+)
+
+//ErrExists is returned by WriteNew if path exists
+var ErrExists = errors.New("fileio: file already exists")
+
+//FileIO reads and writes the metadata files of table formats, like iceberg and delta lake, paths being relative to its root
+type FileIO interface {
+	//Location returns the absolute location of path, as written in metadata files
+	Location(path string) string
+	Read(path string) ([]byte, error)
+	Exists(path string) (bool, error)
+	//List returns the paths of the files under prefix
+	List(prefix string) ([]string, error)
+	Write(path string, data []byte) error
+	//WriteNew writes path only if it does not exist, returning ErrExists otherwise
+	WriteNew(path string, data []byte) error
+}
+
+//LocalFileIO keeps metadata files in a local directory
+type LocalFileIO struct {
+	Root string
+}
+
+func NewLocalFileIO(root string) (*LocalFileIO, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalFileIO{Root: root}, os.MkdirAll(root, os.ModePerm)
+}
+
+func (fileIO *LocalFileIO) Location(path string) string {
+	return "file://" + filepath.Join(fileIO.Root, path)
+}
+
+func (fileIO *LocalFileIO) Read(path string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fileIO.Root, path))
+}
+
+func (fileIO *LocalFileIO) Exists(path string) (bool, error) {
+	_, err := os.Stat(filepath.Join(fileIO.Root, path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (fileIO *LocalFileIO) List(prefix string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(fileIO.Root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relativePath, err := filepath.Rel(fileIO.Root, filePath)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if strings.HasPrefix(relativePath, prefix) && !strings.HasPrefix(filepath.Base(relativePath), ".tmp-") {
+			paths = append(paths, relativePath)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+//Write replaces path atomically, renaming a temporary file over it
+func (fileIO *LocalFileIO) Write(path string, data []byte) error {
+	tmpPath, err := fileIO.writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(fileIO.Root, path))
+}
+
+//WriteNew links a temporary file to path, which fails if path exists even with concurrent writers
+func (fileIO *LocalFileIO) WriteNew(path string, data []byte) error {
+	tmpPath, err := fileIO.writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	err = os.Link(tmpPath, filepath.Join(fileIO.Root, path))
+	if os.IsExist(err) {
+		return ErrExists
+	}
+	return err
+}
+
+func (fileIO *LocalFileIO) writeTemp(path string, data []byte) (string, error) {
+	dir := filepath.Dir(filepath.Join(fileIO.Root, path))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	file, err := ioutil.TempFile(dir, ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+//Locker serializes writers of a key, Lock blocking until the lock is acquired and unlock releasing it
+type Locker interface {
+	Lock(key string) (unlock func() error, err error)
+}
+
+//AdvisoryLocker locks keys with postgres advisory locks, which serialize writers across warehouse instances sharing the db
+type AdvisoryLocker struct {
+	DB *sql.DB
+}
+
+func NewAdvisoryLocker(db *sql.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{DB: db}
+}
+
+//Lock takes a transaction level advisory lock of key, released when unlock commits the transaction or the connection is lost
+func (locker *AdvisoryLocker) Lock(key string) (func() error, error) {
+	txn, err := locker.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	_, err = txn.Exec(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`, "fileio", key)
+	if err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+	return txn.Commit, nil
+}
+
+/*
+ObjectStorageFileIO keeps metadata files in the bucket of a file manager, under its prefix.
+Object storages have no conditional writes, so WriteNew holds the lock of the location of path from checking that path does not exist
+until it is written. Commits to a table are safe from concurrent writers as long as they all lock with the same Locker
+*/
+type ObjectStorageFileIO struct {
+	Scheme      string
+	Bucket      string
+	Prefix      string
+	FileManager filemanager.FileManager
+	Locker      Locker
+}
+
+func NewObjectStorageFileIO(scheme string, bucket string, prefix string, fileManager filemanager.FileManager, locker Locker) *ObjectStorageFileIO {
+	return &ObjectStorageFileIO{Scheme: scheme, Bucket: bucket, Prefix: strings.Trim(prefix, "/"), FileManager: fileManager, Locker: locker}
+}
+
+func (fileIO *ObjectStorageFileIO) key(path string) string {
+	if fileIO.Prefix == "" {
+		return path
+	}
+	return fileIO.Prefix + "/" + path
+}
+
+func (fileIO *ObjectStorageFileIO) Location(path string) string {
+	return fmt.Sprintf("%s://%s/%s", fileIO.Scheme, fileIO.Bucket, fileIO.key(path))
+}
+
+func (fileIO *ObjectStorageFileIO) Read(path string) ([]byte, error) {
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return nil, err
+	}
+	file, err := ioutil.TempFile(tmpDirPath, "fileio-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if err = fileIO.FileManager.Download(file, fileIO.key(path)); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(file.Name())
+}
+
+func (fileIO *ObjectStorageFileIO) Exists(path string) (bool, error) {
+	key := fileIO.key(path)
+	fileObjects, err := fileIO.FileManager.ListFilesWithPrefix(key)
+	if err != nil {
+		return false, err
+	}
+	for _, fileObject := range fileObjects {
+		if fileObject.Key == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (fileIO *ObjectStorageFileIO) List(prefix string) ([]string, error) {
+	fileObjects, err := fileIO.FileManager.ListFilesWithPrefix(fileIO.key(prefix))
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(fileObjects))
+	for _, fileObject := range fileObjects {
+		if fileIO.Prefix == "" {
+			paths = append(paths, fileObject.Key)
+			continue
+		}
+		paths = append(paths, strings.TrimPrefix(fileObject.Key, fileIO.Prefix+"/"))
+	}
+	return paths, nil
+}
+
+//Write uploads data as path. File managers upload files with their base name under the given prefixes, so data is written to a temporary directory first
+func (fileIO *ObjectStorageFileIO) Write(path string, data []byte) error {
+	tmpDirPath, err := misc.CreateTMPDIR()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir(tmpDirPath, "fileio-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, filepath.Base(path))
+	if err = ioutil.WriteFile(filePath, data, 0644); err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var prefixes []string
+	if pathDir := filepath.Dir(path); pathDir != "." {
+		prefixes = append(prefixes, pathDir)
+	}
+	_, err = fileIO.FileManager.Upload(file, prefixes...)
+	return err
+}
+
+func (fileIO *ObjectStorageFileIO) WriteNew(path string, data []byte) (err error) {
+	unlock, err := fileIO.Locker.Lock(fileIO.Location(path))
+	if err != nil {
+		return fmt.Errorf("fileio: failed to lock %s: %v", path, err)
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil && unlockErr != nil {
+			err = fmt.Errorf("fileio: failed to unlock %s: %v", path, unlockErr)
+		}
+	}()
+	exists, err := fileIO.Exists(path)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrExists
+	}
+	return fileIO.Write(path, data)
+}
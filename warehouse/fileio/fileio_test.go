@@ -0,0 +1,164 @@
+package fileio
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/services/filemanager"
+)
+
+//memoryFileManager keeps objects in memory, returning listings late so that writers race between checking and writing a path
+type memoryFileManager struct {
+	filemanager.FileManager
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (manager *memoryFileManager) Upload(file *os.File, prefixes ...string) (filemanager.UploadOutput, error) {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return filemanager.UploadOutput{}, err
+	}
+	key := strings.Join(append(prefixes, filepath.Base(file.Name())), "/")
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.objects[key] = data
+	return filemanager.UploadOutput{Location: "s3://bucket/" + key, ObjectName: key}, nil
+}
+
+func (manager *memoryFileManager) Download(file *os.File, key string) error {
+	manager.mu.Lock()
+	data, ok := manager.objects[key]
+	manager.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no such key %s", key)
+	}
+	_, err := file.Write(data)
+	return err
+}
+
+func (manager *memoryFileManager) ListFilesWithPrefix(prefix string) ([]*filemanager.FileObject, error) {
+	manager.mu.Lock()
+	var fileObjects []*filemanager.FileObject
+	for key := range manager.objects {
+		if strings.HasPrefix(key, prefix) {
+			fileObjects = append(fileObjects, &filemanager.FileObject{Key: key})
+		}
+	}
+	manager.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	return fileObjects, nil
+}
+
+//mutexLocker locks keys with in process mutexes
+type mutexLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (locker *mutexLocker) Lock(key string) (func() error, error) {
+	locker.mu.Lock()
+	lock, ok := locker.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		locker.locks[key] = lock
+	}
+	locker.mu.Unlock()
+	lock.Lock()
+	return func() error {
+		lock.Unlock()
+		return nil
+	}, nil
+}
+
+var _ = Describe("ObjectStorageFileIO", func() {
+	var (
+		fileManager *memoryFileManager
+		fileIO      *ObjectStorageFileIO
+	)
+
+	BeforeEach(func() {
+		fileManager = &memoryFileManager{objects: map[string][]byte{}}
+		fileIO = NewObjectStorageFileIO("s3", "bucket", "", fileManager, &mutexLocker{locks: map[string]*sync.Mutex{}})
+	})
+
+	It("should write new files once", func() {
+		Expect(fileIO.WriteNew("tracks/metadata/v1.metadata.json", []byte("first"))).To(Succeed())
+		Expect(fileIO.WriteNew("tracks/metadata/v1.metadata.json", []byte("second"))).To(Equal(ErrExists))
+
+		data, err := fileIO.Read("tracks/metadata/v1.metadata.json")
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("first"))
+		Expect(fileIO.Location("tracks/metadata/v1.metadata.json")).To(Equal("s3://bucket/tracks/metadata/v1.metadata.json"))
+	})
+
+	It("should commit the versions of concurrent committers once", func() {
+		committers := []string{"first", "second"}
+		committed := make([]int, len(committers))
+		var wg sync.WaitGroup
+		for i, committer := range committers {
+			wg.Add(1)
+			go func(i int, committer string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for version := 1; ; version++ {
+					err := fileIO.WriteNew(fmt.Sprintf("tracks/metadata/v%d.metadata.json", version), []byte(committer))
+					if err == ErrExists {
+						continue
+					}
+					Expect(err).To(BeNil())
+					committed[i] = version
+					return
+				}
+			}(i, committer)
+		}
+		wg.Wait()
+
+		Expect(committed).To(ConsistOf(1, 2))
+		for i, committer := range committers {
+			data, err := fileIO.Read(fmt.Sprintf("tracks/metadata/v%d.metadata.json", committed[i]))
+			Expect(err).To(BeNil())
+			Expect(string(data)).To(Equal(committer))
+		}
+	})
+})
+
+var _ = Describe("AdvisoryLocker", func() {
+	It("should hold a transaction level advisory lock of the key until unlocked", func() {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).To(BeNil())
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`).WithArgs("fileio", "s3://bucket/tracks/metadata/v1.metadata.json").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		unlock, err := NewAdvisoryLocker(db).Lock("s3://bucket/tracks/metadata/v1.metadata.json")
+		Expect(err).To(BeNil())
+		Expect(unlock()).To(Succeed())
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+
+	It("should roll back the transaction if the lock fails", func() {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		Expect(err).To(BeNil())
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock(hashtext($1), hashtext($2))`).WillReturnError(fmt.Errorf("connection lost"))
+		mock.ExpectRollback()
+
+		_, err = NewAdvisoryLocker(db).Lock("s3://bucket/tracks/metadata/v1.metadata.json")
+		Expect(err).To(MatchError("connection lost"))
+		Expect(mock.ExpectationsWereMet()).To(BeNil())
+	})
+})
@@ -8,6 +8,8 @@ import (
 	"github.com/rudderlabs/rudder-server/warehouse/bigquery"
 	"github.com/rudderlabs/rudder-server/warehouse/clickhouse"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
+	"github.com/rudderlabs/rudder-server/warehouse/deltalake"
+	"github.com/rudderlabs/rudder-server/warehouse/duckdb"
 	"github.com/rudderlabs/rudder-server/warehouse/mssql"
 	"github.com/rudderlabs/rudder-server/warehouse/postgres"
 	"github.com/rudderlabs/rudder-server/warehouse/redshift"
@@ -63,6 +65,12 @@ func New(destType string) (ManagerI, error) {
 	case "S3_DATALAKE":
 		var s3datalake s3datalake.HandleT
 		return &s3datalake, nil
+	case "DUCKDB":
+		var dk duckdb.HandleT
+		return &dk, nil
+	case "DELTALAKE":
+		var dl deltalake.HandleT
+		return &dl, nil
 	}
 	return nil, fmt.Errorf("Provider of type %s is not configured for WarehouseManager", destType)
 }
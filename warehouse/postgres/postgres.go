@@ -317,6 +317,17 @@ func (pg *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutil
 	if tableName == warehouseutils.DiscardsTable {
 		additionalJoinClause = fmt.Sprintf(`AND _source.%[3]s = "%[1]s"."%[2]s"."%[3]s" AND _source.%[4]s = "%[1]s"."%[2]s"."%[4]s"`, pg.Namespace, tableName, "table_name", "column_name")
 	}
+	// dedup on load merges on the configured keys, with the rows received within the dedup window only
+	if dedup, ok := warehouseutils.GetDedupOnLoad(pg.Warehouse, pg.Uploader, tableName); ok {
+		primaryKey = dedup.Keys[0]
+		partitionKey = warehouseutils.DoubleQuoteAndJoinByComma(dedup.Keys)
+		for _, key := range dedup.Keys[1:] {
+			additionalJoinClause += fmt.Sprintf(` AND _source."%[3]s" = "%[1]s"."%[2]s"."%[3]s"`, pg.Namespace, tableName, key)
+		}
+		if !dedup.Since.IsZero() {
+			additionalJoinClause += fmt.Sprintf(` AND "%[1]s"."%[2]s".received_at >= '%[3]s'`, pg.Namespace, tableName, dedup.Since.Format(time.RFC3339))
+		}
+	}
 	sqlStatement = fmt.Sprintf(`DELETE FROM "%[1]s"."%[2]s" USING "%[3]s" as  _source where (_source.%[4]s = "%[1]s"."%[2]s"."%[4]s" %[5]s)`, pg.Namespace, tableName, stagingTableName, primaryKey, additionalJoinClause)
 	pkgLogger.Infof("PG: Deduplicate records for table:%s using staging table: %s\n", tableName, sqlStatement)
 	_, err = txn.Exec(sqlStatement)
@@ -346,6 +346,17 @@ func (rs *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutil
 	if tableName == warehouseutils.DiscardsTable {
 		additionalJoinClause = fmt.Sprintf(`AND _source.%[3]s = %[1]s.%[2]s.%[3]s AND _source.%[4]s = %[1]s.%[2]s.%[4]s`, rs.Namespace, tableName, "table_name", "column_name")
 	}
+	// dedup on load merges on the configured keys, with the rows received within the dedup window only
+	if dedup, ok := warehouseutils.GetDedupOnLoad(rs.Warehouse, rs.Uploader, tableName); ok {
+		primaryKey = dedup.Keys[0]
+		partitionKey = warehouseutils.DoubleQuoteAndJoinByComma(dedup.Keys)
+		for _, key := range dedup.Keys[1:] {
+			additionalJoinClause += fmt.Sprintf(` AND _source."%[3]s" = %[1]s.%[2]s."%[3]s"`, rs.Namespace, tableName, key)
+		}
+		if !dedup.Since.IsZero() {
+			additionalJoinClause += fmt.Sprintf(` AND %[1]s.%[2]s.received_at >= '%[3]s'`, rs.Namespace, tableName, dedup.Since.Format("2006-01-02 15:04:05"))
+		}
+	}
 
 	sqlStatement = fmt.Sprintf(`DELETE FROM %[1]s."%[2]s" using %[1]s."%[3]s" _source where (_source.%[4]s = %[1]s.%[2]s.%[4]s %[5]s)`, rs.Namespace, tableName, stagingTableName, primaryKey, additionalJoinClause)
 	pkgLogger.Infof("RS: Dedup records for table:%s using staging table: %s\n", tableName, sqlStatement)
@@ -0,0 +1,507 @@
+package iceberg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+//Manifests and manifest lists are avro object container files, see https://avro.apache.org/docs/current/spec.html#Object+Container+Files
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+//avroSchemaT is a parsed avro schema. Named types are resolved while parsing, so that schemas are trees
+type avroSchemaT struct {
+	Type     string
+	Name     string
+	Fields   []avroFieldT
+	Items    *avroSchemaT
+	Values   *avroSchemaT
+	Branches []*avroSchemaT
+	Symbols  []string
+	Size     int
+}
+
+type avroFieldT struct {
+	Name   string
+	Schema *avroSchemaT
+}
+
+func parseAvroSchema(rawSchema []byte) (*avroSchemaT, error) {
+	var schema interface{}
+	if err := json.Unmarshal(rawSchema, &schema); err != nil {
+		return nil, err
+	}
+	return parseAvroSchemaValue(schema, make(map[string]*avroSchemaT), "")
+}
+
+func parseAvroSchemaValue(schema interface{}, named map[string]*avroSchemaT, namespace string) (*avroSchemaT, error) {
+	switch s := schema.(type) {
+	case string:
+		switch s {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroSchemaT{Type: s}, nil
+		}
+		if namedSchema, ok := named[s]; ok {
+			return namedSchema, nil
+		}
+		if namedSchema, ok := named[namespace+"."+s]; ok {
+			return namedSchema, nil
+		}
+		return nil, fmt.Errorf("unknown avro type %s", s)
+	case []interface{}:
+		union := &avroSchemaT{Type: "union"}
+		for _, branch := range s {
+			branchSchema, err := parseAvroSchemaValue(branch, named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.Branches = append(union.Branches, branchSchema)
+		}
+		return union, nil
+	case map[string]interface{}:
+		schemaType, _ := s["type"].(string)
+		if schemaType == "" {
+			//eg. {"type": {"type": "array", ...}}
+			return parseAvroSchemaValue(s["type"], named, namespace)
+		}
+		name, _ := s["name"].(string)
+		if ns, ok := s["namespace"].(string); ok {
+			namespace = ns
+		}
+		switch schemaType {
+		case "record", "error":
+			record := &avroSchemaT{Type: "record", Name: name}
+			registerAvroName(named, record, name, namespace)
+			fields, _ := s["fields"].([]interface{})
+			for _, f := range fields {
+				field, _ := f.(map[string]interface{})
+				fieldName, _ := field["name"].(string)
+				fieldSchema, err := parseAvroSchemaValue(field["type"], named, namespace)
+				if err != nil {
+					return nil, err
+				}
+				record.Fields = append(record.Fields, avroFieldT{Name: fieldName, Schema: fieldSchema})
+			}
+			return record, nil
+		case "enum":
+			enum := &avroSchemaT{Type: "enum", Name: name}
+			registerAvroName(named, enum, name, namespace)
+			symbols, _ := s["symbols"].([]interface{})
+			for _, symbol := range symbols {
+				symbolName, _ := symbol.(string)
+				enum.Symbols = append(enum.Symbols, symbolName)
+			}
+			return enum, nil
+		case "fixed":
+			size, _ := s["size"].(float64)
+			fixed := &avroSchemaT{Type: "fixed", Name: name, Size: int(size)}
+			registerAvroName(named, fixed, name, namespace)
+			return fixed, nil
+		case "array":
+			items, err := parseAvroSchemaValue(s["items"], named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchemaT{Type: "array", Items: items}, nil
+		case "map":
+			values, err := parseAvroSchemaValue(s["values"], named, namespace)
+			if err != nil {
+				return nil, err
+			}
+			return &avroSchemaT{Type: "map", Values: values}, nil
+		}
+		//primitives with attributes, eg. logical types
+		return parseAvroSchemaValue(schemaType, named, namespace)
+	}
+	return nil, fmt.Errorf("invalid avro schema %v", schema)
+}
+
+func registerAvroName(named map[string]*avroSchemaT, schema *avroSchemaT, name string, namespace string) {
+	named[name] = schema
+	if namespace != "" {
+		named[namespace+"."+name] = schema
+	}
+}
+
+func writeAvroLong(w *bytes.Buffer, value int64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], value)
+	w.Write(buf[:n])
+}
+
+func writeAvroBytes(w *bytes.Buffer, value []byte) {
+	writeAvroLong(w, int64(len(value)))
+	w.Write(value)
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("%v is not an integer", value)
+}
+
+//encodeAvro encodes value, records being map[string]interface{}, with schema
+func encodeAvro(w *bytes.Buffer, schema *avroSchemaT, value interface{}) error {
+	switch schema.Type {
+	case "null":
+		return nil
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not a boolean", value)
+		}
+		if b {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case "int", "long":
+		n, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		writeAvroLong(w, n)
+	case "float":
+		f, ok := value.(float32)
+		if !ok {
+			return fmt.Errorf("%v is not a float", value)
+		}
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(f))
+		w.Write(buf[:])
+	case "double":
+		f, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%v is not a double", value)
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(f))
+		w.Write(buf[:])
+	case "bytes", "fixed":
+		b, ok := value.([]byte)
+		if !ok {
+			return fmt.Errorf("%v is not bytes", value)
+		}
+		if schema.Type == "fixed" {
+			w.Write(b)
+		} else {
+			writeAvroBytes(w, b)
+		}
+	case "string", "enum":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", value)
+		}
+		if schema.Type == "string" {
+			writeAvroBytes(w, []byte(s))
+			return nil
+		}
+		for i, symbol := range schema.Symbols {
+			if symbol == s {
+				writeAvroLong(w, int64(i))
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not a symbol of enum %s", s, schema.Name)
+	case "record":
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not a %s record", value, schema.Name)
+		}
+		for _, field := range schema.Fields {
+			if err := encodeAvro(w, field.Schema, record[field.Name]); err != nil {
+				return fmt.Errorf("%s.%s: %v", schema.Name, field.Name, err)
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an array", value)
+		}
+		if len(items) > 0 {
+			writeAvroLong(w, int64(len(items)))
+			for _, item := range items {
+				if err := encodeAvro(w, schema.Items, item); err != nil {
+					return err
+				}
+			}
+		}
+		writeAvroLong(w, 0)
+	case "map":
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not a map", value)
+		}
+		if len(entries) > 0 {
+			writeAvroLong(w, int64(len(entries)))
+			for key, entry := range entries {
+				writeAvroBytes(w, []byte(key))
+				if err := encodeAvro(w, schema.Values, entry); err != nil {
+					return err
+				}
+			}
+		}
+		writeAvroLong(w, 0)
+	case "union":
+		for i, branch := range schema.Branches {
+			if (value == nil) == (branch.Type == "null") {
+				writeAvroLong(w, int64(i))
+				return encodeAvro(w, branch, value)
+			}
+		}
+		return fmt.Errorf("no branch of union for %v", value)
+	default:
+		return fmt.Errorf("unsupported avro type %s", schema.Type)
+	}
+	return nil
+}
+
+func readAvroLong(r *bufio.Reader) (int64, error) {
+	return binary.ReadVarint(r)
+}
+
+func readAvroBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := readAvroLong(r)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("negative avro length %d", n)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+//readAvroBlockCount reads the item count of an array or map block, skipping the byte size of blocks with negative counts
+func readAvroBlockCount(r *bufio.Reader) (int64, error) {
+	count, err := readAvroLong(r)
+	if err != nil || count >= 0 {
+		return count, err
+	}
+	if _, err = readAvroLong(r); err != nil {
+		return 0, err
+	}
+	return -count, nil
+}
+
+//decodeAvro decodes a value of schema, records being decoded as map[string]interface{}
+func decodeAvro(r *bufio.Reader, schema *avroSchemaT) (interface{}, error) {
+	switch schema.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := r.ReadByte()
+		return b != 0, err
+	case "int":
+		n, err := readAvroLong(r)
+		return int32(n), err
+	case "long":
+		return readAvroLong(r)
+	case "float":
+		var buf [4]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(buf[:])), nil
+	case "double":
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(buf[:])), nil
+	case "bytes":
+		return readAvroBytes(r)
+	case "fixed":
+		b := make([]byte, schema.Size)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	case "string":
+		b, err := readAvroBytes(r)
+		return string(b), err
+	case "enum":
+		i, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(schema.Symbols) {
+			return nil, fmt.Errorf("invalid symbol %d of enum %s", i, schema.Name)
+		}
+		return schema.Symbols[i], nil
+	case "record":
+		record := make(map[string]interface{}, len(schema.Fields))
+		for _, field := range schema.Fields {
+			value, err := decodeAvro(r, field.Schema)
+			if err != nil {
+				return nil, err
+			}
+			record[field.Name] = value
+		}
+		return record, nil
+	case "array":
+		items := make([]interface{}, 0)
+		for {
+			count, err := readAvroBlockCount(r)
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return items, nil
+			}
+			for ; count > 0; count-- {
+				item, err := decodeAvro(r, schema.Items)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+	case "map":
+		entries := make(map[string]interface{})
+		for {
+			count, err := readAvroBlockCount(r)
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return entries, nil
+			}
+			for ; count > 0; count-- {
+				key, err := readAvroBytes(r)
+				if err != nil {
+					return nil, err
+				}
+				if entries[string(key)], err = decodeAvro(r, schema.Values); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "union":
+		i, err := readAvroLong(r)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || int(i) >= len(schema.Branches) {
+			return nil, fmt.Errorf("invalid union branch %d", i)
+		}
+		return decodeAvro(r, schema.Branches[i])
+	}
+	return nil, fmt.Errorf("unsupported avro type %s", schema.Type)
+}
+
+//writeAvroFile encodes records with rawSchema as an uncompressed avro object container file, with metadata as its file metadata
+func writeAvroFile(rawSchema string, metadata map[string]string, records []map[string]interface{}) ([]byte, error) {
+	schema, err := parseAvroSchema([]byte(rawSchema))
+	if err != nil {
+		return nil, err
+	}
+	var sync [16]byte
+	if _, err = rand.Read(sync[:]); err != nil {
+		return nil, err
+	}
+
+	var file bytes.Buffer
+	file.Write(avroMagic)
+	fileMetadata := map[string]interface{}{"avro.schema": []byte(rawSchema), "avro.codec": []byte("null")}
+	for key, value := range metadata {
+		fileMetadata[key] = []byte(value)
+	}
+	if err = encodeAvro(&file, &avroSchemaT{Type: "map", Values: &avroSchemaT{Type: "bytes"}}, fileMetadata); err != nil {
+		return nil, err
+	}
+	file.Write(sync[:])
+
+	if len(records) > 0 {
+		var block bytes.Buffer
+		for _, record := range records {
+			if err = encodeAvro(&block, schema, record); err != nil {
+				return nil, err
+			}
+		}
+		writeAvroLong(&file, int64(len(records)))
+		writeAvroBytes(&file, block.Bytes())
+		file.Write(sync[:])
+	}
+	return file.Bytes(), nil
+}
+
+//readAvroFile decodes the records of an avro object container file, compressed with the null or deflate codecs
+func readAvroFile(data []byte) (metadata map[string]string, records []map[string]interface{}, err error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	magic := make([]byte, len(avroMagic))
+	if _, err = io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, avroMagic) {
+		return nil, nil, errors.New("not an avro object container file")
+	}
+	rawMetadata, err := decodeAvro(r, &avroSchemaT{Type: "map", Values: &avroSchemaT{Type: "bytes"}})
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata = make(map[string]string)
+	for key, value := range rawMetadata.(map[string]interface{}) {
+		metadata[key] = string(value.([]byte))
+	}
+	schema, err := parseAvroSchema([]byte(metadata["avro.schema"]))
+	if err != nil {
+		return nil, nil, err
+	}
+	codec := metadata["avro.codec"]
+	if codec != "" && codec != "null" && codec != "deflate" {
+		return nil, nil, fmt.Errorf("unsupported avro codec %s", codec)
+	}
+	var sync [16]byte
+	if _, err = io.ReadFull(r, sync[:]); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		count, err := readAvroLong(r)
+		if err == io.EOF {
+			return metadata, records, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		block, err := readAvroBytes(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if codec == "deflate" {
+			if block, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+				return nil, nil, err
+			}
+		}
+		blockReader := bufio.NewReader(bytes.NewReader(block))
+		for ; count > 0; count-- {
+			record, err := decodeAvro(blockReader, schema)
+			if err != nil {
+				return nil, nil, err
+			}
+			recordMap, ok := record.(map[string]interface{})
+			if !ok {
+				return nil, nil, errors.New("avro object container file of non record values")
+			}
+			records = append(records, recordMap)
+		}
+		var blockSync [16]byte
+		if _, err = io.ReadFull(r, blockSync[:]); err != nil {
+			return nil, nil, err
+		}
+		if blockSync != sync {
+			return nil, nil, errors.New("invalid avro sync marker")
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package iceberg
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/utils/logger"
+	"github.com/rudderlabs/rudder-server/utils/timeutil"
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+const (
+	versionHintFile = "version-hint.text"
+	metadataDir     = "metadata"
+
+	//blockSizeInBytes is written for the deprecated block_size_in_bytes, required by format version 1
+	blockSizeInBytes = 64 * 1024 * 1024
+)
+
+//ErrNoSuchTable is returned when loading a table without metadata
+var ErrNoSuchTable = errors.New("iceberg: table does not exist")
+
+var (
+	maxCommitRetries int
+	pkgLogger        logger.LoggerI
+)
+
+func init() {
+	loadConfig()
+	pkgLogger = logger.NewLogger().Child("warehouse").Child("s3-datalake").Child("iceberg")
+}
+
+func loadConfig() {
+	config.RegisterIntConfigVariable(3, &maxCommitRetries, true, 1, "Warehouse.s3_datalake.iceberg.maxCommitRetries")
+}
+
+const manifestEntrySchema = `{"type":"record","name":"manifest_entry","fields":[
+{"name":"status","type":"int","field-id":0},
+{"name":"snapshot_id","type":"long","field-id":1},
+{"name":"data_file","field-id":2,"type":{"type":"record","name":"r2","fields":[
+{"name":"file_path","type":"string","field-id":100},
+{"name":"file_format","type":"string","field-id":101},
+{"name":"partition","field-id":102,"type":{"type":"record","name":"r102","fields":[]}},
+{"name":"record_count","type":"long","field-id":103},
+{"name":"file_size_in_bytes","type":"long","field-id":104},
+{"name":"block_size_in_bytes","type":"long","field-id":105}]}}]}`
+
+const manifestFileSchema = `{"type":"record","name":"manifest_file","fields":[
+{"name":"manifest_path","type":"string","field-id":500},
+{"name":"manifest_length","type":"long","field-id":501},
+{"name":"partition_spec_id","type":"int","field-id":502},
+{"name":"added_snapshot_id","type":["null","long"],"default":null,"field-id":503},
+{"name":"added_data_files_count","type":["null","int"],"default":null,"field-id":504},
+{"name":"existing_data_files_count","type":["null","int"],"default":null,"field-id":505},
+{"name":"deleted_data_files_count","type":["null","int"],"default":null,"field-id":506},
+{"name":"added_rows_count","type":["null","long"],"default":null,"field-id":512},
+{"name":"existing_rows_count","type":["null","long"],"default":null,"field-id":513},
+{"name":"deleted_rows_count","type":["null","long"],"default":null,"field-id":514}]}`
+
+//DataFileT is a data file appended to a table, FilePath being its absolute location
+type DataFileT struct {
+	FilePath        string
+	FileFormat      string
+	RecordCount     int64
+	FileSizeInBytes int64
+}
+
+//TableT is a loaded table, Version being the version of its metadata file
+type TableT struct {
+	Path     string
+	Version  int
+	Metadata *TableMetadataT
+}
+
+/*
+CatalogT keeps tables on a file system, as the hadoop catalog does: the metadata of version N of a table is written
+to <table>/metadata/vN.metadata.json and <table>/metadata/version-hint.text holds the latest version.
+A commit writes the next version only if it does not exist yet, so that the snapshot of an upload is committed atomically
+*/
+type CatalogT struct {
+	FileIO fileio.FileIO
+}
+
+func NewCatalog(fileIO fileio.FileIO) *CatalogT {
+	return &CatalogT{FileIO: fileIO}
+}
+
+//TablePath returns the path of a table relative to the catalog root, which is the path its data files are uploaded to
+func TablePath(namespace string, tableName string) string {
+	return warehouseutils.GetTablePathInObjectStorage(namespace, tableName)
+}
+
+func metadataFilePath(tablePath string, version int) string {
+	return fmt.Sprintf("%s/%s/v%d.metadata.json", tablePath, metadataDir, version)
+}
+
+func versionHintPath(tablePath string) string {
+	return fmt.Sprintf("%s/%s/%s", tablePath, metadataDir, versionHintFile)
+}
+
+//LoadTable loads the latest metadata of a table, returning ErrNoSuchTable if it does not exist
+func (catalog *CatalogT) LoadTable(namespace string, tableName string) (*TableT, error) {
+	tablePath := TablePath(namespace, tableName)
+	version := 1
+	hintExists, err := catalog.FileIO.Exists(versionHintPath(tablePath))
+	if err != nil {
+		return nil, err
+	}
+	if hintExists {
+		hint, err := catalog.FileIO.Read(versionHintPath(tablePath))
+		if err != nil {
+			return nil, err
+		}
+		if version, err = strconv.Atoi(strings.TrimSpace(string(hint))); err != nil {
+			return nil, fmt.Errorf("invalid version hint of table %s: %v", tableName, err)
+		}
+	}
+	exists, err := catalog.FileIO.Exists(metadataFilePath(tablePath, version))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNoSuchTable
+	}
+	//the hint is written after the metadata file, so it can be behind a committed version
+	for {
+		exists, err = catalog.FileIO.Exists(metadataFilePath(tablePath, version+1))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			break
+		}
+		version++
+	}
+
+	rawMetadata, err := catalog.FileIO.Read(metadataFilePath(tablePath, version))
+	if err != nil {
+		return nil, err
+	}
+	var metadata TableMetadataT
+	if err = json.Unmarshal(rawMetadata, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata of table %s: %v", tableName, err)
+	}
+	if metadata.Properties == nil {
+		metadata.Properties = map[string]string{}
+	}
+	if metadata.Refs == nil {
+		metadata.Refs = map[string]SnapshotRefT{}
+	}
+	return &TableT{Path: tablePath, Version: version, Metadata: &metadata}, nil
+}
+
+//CreateTable writes the first version of the metadata of a table with the columns of columnMap
+func (catalog *CatalogT) CreateTable(namespace string, tableName string, columnMap map[string]string) error {
+	tablePath := TablePath(namespace, tableName)
+	metadata, err := newTableMetadata(uuid.NewV4().String(), catalog.FileIO.Location(tablePath), columnMap, timeutil.Now().UnixNano()/int64(1e6))
+	if err != nil {
+		return err
+	}
+	rawMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	err = catalog.FileIO.WriteNew(metadataFilePath(tablePath, 1), rawMetadata)
+	if err == fileio.ErrExists {
+		return fmt.Errorf("Failed to create table: table %s already exists", tableName)
+	}
+	if err != nil {
+		return err
+	}
+	return catalog.FileIO.Write(versionHintPath(tablePath), []byte("1"))
+}
+
+//AddColumns evolves the schema of a table with the columns of columnMap it does not have
+func (catalog *CatalogT) AddColumns(namespace string, tableName string, columnMap map[string]string) error {
+	return catalog.commit(namespace, tableName, func(table *TableT) (bool, error) {
+		changed := false
+		for columnName, columnType := range columnMap {
+			added, err := table.Metadata.addColumn(columnName, columnType)
+			if err != nil {
+				return false, err
+			}
+			changed = changed || added
+		}
+		return changed, nil
+	})
+}
+
+//AlterColumn checks that a column of a table already has the iceberg type of columnType, as iceberg only allows promotions to the types rudder ints and floats map to
+func (catalog *CatalogT) AlterColumn(namespace string, tableName string, columnName string, columnType string) error {
+	table, err := catalog.LoadTable(namespace, tableName)
+	if err != nil {
+		return err
+	}
+	icebergType, ok := dataTypesMap[columnType]
+	if !ok {
+		return fmt.Errorf("unsupported data type %s of column %s", columnType, columnName)
+	}
+	for _, field := range table.Metadata.CurrentSchema().Fields {
+		if field.Name != columnName {
+			continue
+		}
+		if field.Type != icebergType {
+			return fmt.Errorf("Failed to alter column: changing column %s in table %s from %s to %s is not supported by iceberg", columnName, tableName, field.Type, icebergType)
+		}
+		return nil
+	}
+	return fmt.Errorf("Failed to alter column: column %s does not exist in table %s", columnName, tableName)
+}
+
+/*
+AppendFiles commits a snapshot of a table adding files. The snapshot is marked with a commit id hashed from the paths of files
+and appending files already committed is a no-op, so that failed uploads can be retried
+*/
+func (catalog *CatalogT) AppendFiles(namespace string, tableName string, files []DataFileT) error {
+	if len(files) == 0 {
+		return nil
+	}
+	commitID := filesCommitID(files)
+	return catalog.commit(namespace, tableName, func(table *TableT) (bool, error) {
+		if table.Metadata.hasCommit(commitID) {
+			pkgLogger.Infof("Skipping append to table %s : files of commit %s are already committed", tableName, commitID)
+			return false, nil
+		}
+		snapshotID, err := newSnapshotID()
+		if err != nil {
+			return false, err
+		}
+		parent := table.Metadata.CurrentSnapshot()
+		manifestListLocation, err := catalog.writeManifests(table, parent, snapshotID, files)
+		if err != nil {
+			return false, err
+		}
+		snapshot := SnapshotT{
+			SnapshotID:   snapshotID,
+			TimestampMs:  timeutil.Now().UnixNano() / int64(1e6),
+			ManifestList: manifestListLocation,
+			Summary:      snapshotSummary(parent, files, commitID),
+			SchemaID:     table.Metadata.CurrentSchemaID,
+		}
+		if parent != nil {
+			parentID := parent.SnapshotID
+			snapshot.ParentSnapshotID = &parentID
+		}
+		table.Metadata.addSnapshot(snapshot)
+		return true, nil
+	})
+}
+
+//commit writes the next version of the metadata of a table as changed by update, reloading the table and retrying if another commit wrote it first
+func (catalog *CatalogT) commit(namespace string, tableName string, update func(table *TableT) (bool, error)) error {
+	for attempt := 0; ; attempt++ {
+		table, err := catalog.LoadTable(namespace, tableName)
+		if err != nil {
+			return err
+		}
+		previousMetadataLocation := catalog.FileIO.Location(metadataFilePath(table.Path, table.Version))
+		previousUpdatedMs := table.Metadata.LastUpdatedMs
+
+		changed, err := update(table)
+		if err != nil || !changed {
+			return err
+		}
+		table.Metadata.LastUpdatedMs = timeutil.Now().UnixNano() / int64(1e6)
+		table.Metadata.addMetadataLog(previousMetadataLocation, previousUpdatedMs)
+		rawMetadata, err := json.Marshal(table.Metadata)
+		if err != nil {
+			return err
+		}
+
+		version := table.Version + 1
+		err = catalog.FileIO.WriteNew(metadataFilePath(table.Path, version), rawMetadata)
+		if err == fileio.ErrExists {
+			if attempt >= maxCommitRetries {
+				return fmt.Errorf("Failed to commit table %s: version %d was committed concurrently %d times", tableName, version, attempt+1)
+			}
+			pkgLogger.Infof("Retrying commit of table %s : version %d was committed concurrently", tableName, version)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return catalog.FileIO.Write(versionHintPath(table.Path), []byte(strconv.Itoa(version)))
+	}
+}
+
+//writeManifests writes a manifest of files and a manifest list with it and the manifests of parent, returning the location of the manifest list
+func (catalog *CatalogT) writeManifests(table *TableT, parent *SnapshotT, snapshotID int64, files []DataFileT) (string, error) {
+	rawSchema, err := json.Marshal(table.Metadata.CurrentSchema())
+	if err != nil {
+		return "", err
+	}
+	var addedRows int64
+	entries := make([]map[string]interface{}, 0, len(files))
+	for _, file := range files {
+		addedRows += file.RecordCount
+		entries = append(entries, map[string]interface{}{
+			"status":      1,
+			"snapshot_id": snapshotID,
+			"data_file": map[string]interface{}{
+				"file_path":           file.FilePath,
+				"file_format":         strings.ToUpper(file.FileFormat),
+				"partition":           map[string]interface{}{},
+				"record_count":        file.RecordCount,
+				"file_size_in_bytes":  file.FileSizeInBytes,
+				"block_size_in_bytes": int64(blockSizeInBytes),
+			},
+		})
+	}
+	manifest, err := writeAvroFile(manifestEntrySchema, map[string]string{
+		"schema":            string(rawSchema),
+		"schema-id":         strconv.Itoa(table.Metadata.CurrentSchemaID),
+		"partition-spec":    "[]",
+		"partition-spec-id": "0",
+		"format-version":    strconv.Itoa(FormatVersion),
+	}, entries)
+	if err != nil {
+		return "", err
+	}
+	commitUUID := uuid.NewV4().String()
+	manifestPath := fmt.Sprintf("%s/%s/%s-m0.avro", table.Path, metadataDir, commitUUID)
+	if err = catalog.FileIO.Write(manifestPath, manifest); err != nil {
+		return "", err
+	}
+
+	manifestFiles := []map[string]interface{}{{
+		"manifest_path":             catalog.FileIO.Location(manifestPath),
+		"manifest_length":           int64(len(manifest)),
+		"partition_spec_id":         0,
+		"added_snapshot_id":         snapshotID,
+		"added_data_files_count":    len(files),
+		"existing_data_files_count": 0,
+		"deleted_data_files_count":  0,
+		"added_rows_count":          addedRows,
+		"existing_rows_count":       int64(0),
+		"deleted_rows_count":        int64(0),
+	}}
+	if parent != nil {
+		//manifest lists are written to the metadata directory of the table, whichever the catalog root
+		rawParentList, err := catalog.FileIO.Read(fmt.Sprintf("%s/%s/%s", table.Path, metadataDir, path.Base(parent.ManifestList)))
+		if err != nil {
+			return "", fmt.Errorf("Failed to read manifest list of snapshot %d: %v", parent.SnapshotID, err)
+		}
+		_, parentManifestFiles, err := readAvroFile(rawParentList)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read manifest list of snapshot %d: %v", parent.SnapshotID, err)
+		}
+		manifestFiles = append(manifestFiles, parentManifestFiles...)
+	}
+	manifestList, err := writeAvroFile(manifestFileSchema, map[string]string{
+		"snapshot-id":        strconv.FormatInt(snapshotID, 10),
+		"parent-snapshot-id": parentSnapshotIDString(parent),
+		"format-version":     strconv.Itoa(FormatVersion),
+	}, manifestFiles)
+	if err != nil {
+		return "", err
+	}
+	manifestListPath := fmt.Sprintf("%s/%s/snap-%d-1-%s.avro", table.Path, metadataDir, snapshotID, commitUUID)
+	if err = catalog.FileIO.Write(manifestListPath, manifestList); err != nil {
+		return "", err
+	}
+	return catalog.FileIO.Location(manifestListPath), nil
+}
+
+func parentSnapshotIDString(parent *SnapshotT) string {
+	if parent == nil {
+		return "null"
+	}
+	return strconv.FormatInt(parent.SnapshotID, 10)
+}
+
+func filesCommitID(files []DataFileT) string {
+	filePaths := make([]string, 0, len(files))
+	for _, file := range files {
+		filePaths = append(filePaths, file.FilePath)
+	}
+	sort.Strings(filePaths)
+	hash := sha256.Sum256([]byte(strings.Join(filePaths, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+//newSnapshotID returns a random positive snapshot id
+func newSnapshotID() (int64, error) {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(buf[:])&(1<<63-1)) | 1, nil
+}
@@ -0,0 +1,212 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+)
+
+var _ = Describe("Avro", func() {
+	It("should read the records of the files it writes", func() {
+		records := []map[string]interface{}{{
+			"manifest_path":             "s3://bucket/m0.avro",
+			"manifest_length":           int64(1024),
+			"partition_spec_id":         int32(0),
+			"added_snapshot_id":         int64(-7),
+			"added_data_files_count":    int32(2),
+			"existing_data_files_count": nil,
+			"deleted_data_files_count":  int32(0),
+			"added_rows_count":          int64(1 << 40),
+			"existing_rows_count":       nil,
+			"deleted_rows_count":        int64(0),
+		}}
+		data, err := writeAvroFile(manifestFileSchema, map[string]string{"format-version": "1"}, records)
+		Expect(err).To(BeNil())
+
+		metadata, readRecords, err := readAvroFile(data)
+		Expect(err).To(BeNil())
+		Expect(metadata).To(HaveKeyWithValue("format-version", "1"))
+		Expect(metadata).To(HaveKeyWithValue("avro.codec", "null"))
+		Expect(readRecords).To(Equal(records))
+	})
+
+	It("should reject values not matching the schema", func() {
+		_, err := writeAvroFile(manifestEntrySchema, nil, []map[string]interface{}{{"status": "added"}})
+		Expect(err).To(MatchError(ContainSubstring("manifest_entry.status")))
+	})
+})
+
+var _ = Describe("Catalog", func() {
+	var (
+		root    string
+		catalog *CatalogT
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "iceberg")
+		Expect(err).To(BeNil())
+		fileIO, err := fileio.NewLocalFileIO(root)
+		Expect(err).To(BeNil())
+		catalog = NewCatalog(fileIO)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	metadataFile := func(version string) string {
+		return filepath.Join(root, "rudder-datalake", "rudder", "tracks", "metadata", version)
+	}
+
+	It("should not load tables without metadata", func() {
+		_, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(Equal(ErrNoSuchTable))
+	})
+
+	It("should create tables once", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string", "received_at": "datetime", "count": "int"})).To(Succeed())
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(MatchError(ContainSubstring("already exists")))
+
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(1))
+		Expect(table.Metadata.Location).To(Equal("file://" + filepath.Join(root, "rudder-datalake", "rudder", "tracks")))
+		Expect(table.Metadata.CurrentSchema().Fields).To(Equal([]FieldT{
+			{ID: 1, Name: "count", Type: "long"},
+			{ID: 2, Name: "id", Type: "string"},
+			{ID: 3, Name: "received_at", Type: "timestamptz"},
+		}))
+		Expect(table.Metadata.CurrentSnapshot()).To(BeNil())
+		Expect(table.Metadata.Properties[NameMappingProperty]).To(MatchJSON(`[{"field-id":1,"names":["count"]},{"field-id":2,"names":["id"]},{"field-id":3,"names":["received_at"]}]`))
+	})
+
+	It("should evolve the schema of tables", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(Succeed())
+		Expect(catalog.AddColumns("rudder", "tracks", map[string]string{"id": "string", "revenue": "float"})).To(Succeed())
+		Expect(catalog.AddColumns("rudder", "tracks", map[string]string{"revenue": "float"})).To(Succeed())
+
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(2))
+		Expect(table.Metadata.Schemas).To(HaveLen(2))
+		Expect(table.Metadata.CurrentSchemaID).To(Equal(1))
+		Expect(table.Metadata.LastColumnID).To(Equal(2))
+		Expect(table.Metadata.ColumnMap()).To(Equal(map[string]string{"id": "string", "revenue": "float"}))
+		Expect(table.Metadata.MetadataLog).To(HaveLen(1))
+		Expect(table.Metadata.MetadataLog[0].MetadataFile).To(Equal("file://" + metadataFile("v1.metadata.json")))
+
+		Expect(catalog.AlterColumn("rudder", "tracks", "revenue", "float")).To(Succeed())
+		Expect(catalog.AlterColumn("rudder", "tracks", "revenue", "string")).To(MatchError(ContainSubstring("not supported by iceberg")))
+		Expect(catalog.AlterColumn("rudder", "tracks", "price", "float")).To(MatchError(ContainSubstring("does not exist")))
+	})
+
+	It("should commit appended files as snapshots", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(Succeed())
+		firstFiles := []DataFileT{
+			{FilePath: "s3://bucket/rudder-datalake/rudder/tracks/2021/08/25/10/a.parquet", FileFormat: "parquet", RecordCount: 10, FileSizeInBytes: 100},
+			{FilePath: "s3://bucket/rudder-datalake/rudder/tracks/2021/08/25/10/b.parquet", FileFormat: "parquet", RecordCount: 5, FileSizeInBytes: 50},
+		}
+		secondFiles := []DataFileT{
+			{FilePath: "s3://bucket/rudder-datalake/rudder/tracks/2021/08/25/11/c.parquet", FileFormat: "parquet", RecordCount: 1, FileSizeInBytes: 10},
+		}
+		Expect(catalog.AppendFiles("rudder", "tracks", firstFiles)).To(Succeed())
+		Expect(catalog.AppendFiles("rudder", "tracks", secondFiles)).To(Succeed())
+
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(3))
+		hint, err := ioutil.ReadFile(metadataFile(versionHintFile))
+		Expect(err).To(BeNil())
+		Expect(string(hint)).To(Equal("3"))
+
+		Expect(table.Metadata.Snapshots).To(HaveLen(2))
+		first, current := table.Metadata.Snapshots[0], table.Metadata.CurrentSnapshot()
+		Expect(first.ParentSnapshotID).To(BeNil())
+		Expect(*current.ParentSnapshotID).To(Equal(first.SnapshotID))
+		Expect(table.Metadata.Refs["main"]).To(Equal(SnapshotRefT{SnapshotID: current.SnapshotID, Type: "branch"}))
+		Expect(current.Summary).To(HaveKeyWithValue("added-data-files", "1"))
+		Expect(current.Summary).To(HaveKeyWithValue("total-data-files", "3"))
+		Expect(current.Summary).To(HaveKeyWithValue("total-records", "16"))
+		Expect(current.Summary).To(HaveKeyWithValue("total-files-size", "160"))
+
+		manifestList, err := ioutil.ReadFile(filepath.Join(root, current.ManifestList[len("file://"+root):]))
+		Expect(err).To(BeNil())
+		_, manifestFiles, err := readAvroFile(manifestList)
+		Expect(err).To(BeNil())
+		Expect(manifestFiles).To(HaveLen(2))
+		Expect(manifestFiles[0]["added_snapshot_id"]).To(Equal(current.SnapshotID))
+		Expect(manifestFiles[1]["added_snapshot_id"]).To(Equal(first.SnapshotID))
+		Expect(manifestFiles[1]["added_rows_count"]).To(Equal(int64(15)))
+
+		manifestPath := manifestFiles[1]["manifest_path"].(string)
+		manifest, err := ioutil.ReadFile(filepath.Join(root, manifestPath[len("file://"+root):]))
+		Expect(err).To(BeNil())
+		manifestMetadata, entries, err := readAvroFile(manifest)
+		Expect(err).To(BeNil())
+		Expect(manifestMetadata).To(HaveKeyWithValue("partition-spec", "[]"))
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0]["status"]).To(Equal(int32(1)))
+		dataFile := entries[0]["data_file"].(map[string]interface{})
+		Expect(dataFile["file_path"]).To(Equal(firstFiles[0].FilePath))
+		Expect(dataFile["file_format"]).To(Equal("PARQUET"))
+		Expect(dataFile["record_count"]).To(Equal(int64(10)))
+	})
+
+	It("should skip appending files already committed", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(Succeed())
+		files := []DataFileT{{FilePath: "s3://bucket/a.parquet", FileFormat: "parquet", RecordCount: 1, FileSizeInBytes: 10}}
+		Expect(catalog.AppendFiles("rudder", "tracks", files)).To(Succeed())
+		Expect(catalog.AppendFiles("rudder", "tracks", files)).To(Succeed())
+
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(2))
+		Expect(table.Metadata.Snapshots).To(HaveLen(1))
+	})
+
+	It("should commit the snapshots of concurrent committers", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(Succeed())
+		var wg sync.WaitGroup
+		for _, name := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func(name string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				files := []DataFileT{{FilePath: "s3://bucket/" + name + ".parquet", FileFormat: "parquet", RecordCount: 1, FileSizeInBytes: 10}}
+				Expect(catalog.AppendFiles("rudder", "tracks", files)).To(Succeed())
+			}(name)
+		}
+		wg.Wait()
+
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(4))
+		Expect(table.Metadata.Snapshots).To(HaveLen(3))
+		Expect(table.Metadata.CurrentSnapshot().Summary).To(HaveKeyWithValue("total-data-files", "3"))
+	})
+
+	It("should load versions committed after the version hint", func() {
+		Expect(catalog.CreateTable("rudder", "tracks", map[string]string{"id": "string"})).To(Succeed())
+		table, err := catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		table.Metadata.Properties["owner"] = "rudder"
+		rawMetadata, err := json.Marshal(table.Metadata)
+		Expect(err).To(BeNil())
+		Expect(ioutil.WriteFile(metadataFile("v2.metadata.json"), rawMetadata, 0644)).To(Succeed())
+
+		table, err = catalog.LoadTable("rudder", "tracks")
+		Expect(err).To(BeNil())
+		Expect(table.Version).To(Equal(2))
+		Expect(table.Metadata.Properties).To(HaveKeyWithValue("owner", "rudder"))
+
+		Expect(catalog.FileIO.WriteNew("rudder-datalake/rudder/tracks/metadata/v2.metadata.json", rawMetadata)).To(Equal(fileio.ErrExists))
+	})
+})
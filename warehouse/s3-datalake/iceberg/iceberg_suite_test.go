@@ -0,0 +1,13 @@
+package iceberg
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIceberg(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Iceberg Suite")
+}
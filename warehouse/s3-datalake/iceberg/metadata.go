@@ -0,0 +1,292 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+const (
+	//FormatVersion of the tables written, see https://iceberg.apache.org/spec/#version-1-analytic-data-tables
+	FormatVersion = 1
+
+	//NameMappingProperty maps the columns of data files without field ids, as written by rudder, to the fields of the table
+	NameMappingProperty = "schema.name-mapping.default"
+
+	//CommitIDSummaryProperty marks the snapshot appending a set of data files, so that retried commits of the same files are skipped
+	CommitIDSummaryProperty = "rudder.commit-id"
+
+	unpartitionedLastPartitionID = 999
+	maxMetadataLogEntries        = 100
+)
+
+//dataTypesMap maps rudder data types to iceberg primitive types
+var dataTypesMap = map[string]string{
+	"boolean":  "boolean",
+	"int":      "long",
+	"bigint":   "long",
+	"float":    "double",
+	"string":   "string",
+	"text":     "string",
+	"json":     "string",
+	"datetime": "timestamptz",
+}
+
+//dataTypesMapToRudder maps iceberg primitive types to rudder data types
+var dataTypesMapToRudder = map[string]string{
+	"boolean":     "boolean",
+	"int":         "int",
+	"long":        "int",
+	"float":       "float",
+	"double":      "float",
+	"string":      "string",
+	"timestamp":   "datetime",
+	"timestamptz": "datetime",
+}
+
+type FieldT struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+}
+
+type SchemaT struct {
+	Type     string   `json:"type"`
+	SchemaID int      `json:"schema-id"`
+	Fields   []FieldT `json:"fields"`
+}
+
+type PartitionSpecT struct {
+	SpecID int           `json:"spec-id"`
+	Fields []interface{} `json:"fields"`
+}
+
+type SortOrderT struct {
+	OrderID int           `json:"order-id"`
+	Fields  []interface{} `json:"fields"`
+}
+
+type SnapshotT struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         int               `json:"schema-id"`
+}
+
+type SnapshotLogEntryT struct {
+	TimestampMs int64 `json:"timestamp-ms"`
+	SnapshotID  int64 `json:"snapshot-id"`
+}
+
+type MetadataLogEntryT struct {
+	TimestampMs  int64  `json:"timestamp-ms"`
+	MetadataFile string `json:"metadata-file"`
+}
+
+type SnapshotRefT struct {
+	SnapshotID int64  `json:"snapshot-id"`
+	Type       string `json:"type"`
+}
+
+//TableMetadataT is the content of a table metadata file, see https://iceberg.apache.org/spec/#table-metadata-fields
+type TableMetadataT struct {
+	FormatVersion      int                     `json:"format-version"`
+	TableUUID          string                  `json:"table-uuid"`
+	Location           string                  `json:"location"`
+	LastUpdatedMs      int64                   `json:"last-updated-ms"`
+	LastColumnID       int                     `json:"last-column-id"`
+	Schema             SchemaT                 `json:"schema"`
+	Schemas            []SchemaT               `json:"schemas"`
+	CurrentSchemaID    int                     `json:"current-schema-id"`
+	PartitionSpec      []interface{}           `json:"partition-spec"`
+	PartitionSpecs     []PartitionSpecT        `json:"partition-specs"`
+	DefaultSpecID      int                     `json:"default-spec-id"`
+	LastPartitionID    int                     `json:"last-partition-id"`
+	Properties         map[string]string       `json:"properties"`
+	CurrentSnapshotID  int64                   `json:"current-snapshot-id"`
+	Snapshots          []SnapshotT             `json:"snapshots"`
+	SnapshotLog        []SnapshotLogEntryT     `json:"snapshot-log"`
+	MetadataLog        []MetadataLogEntryT     `json:"metadata-log"`
+	SortOrders         []SortOrderT            `json:"sort-orders"`
+	DefaultSortOrderID int                     `json:"default-sort-order-id"`
+	Refs               map[string]SnapshotRefT `json:"refs"`
+}
+
+func newTableMetadata(tableUUID string, location string, columnMap map[string]string, timestampMs int64) (*TableMetadataT, error) {
+	//columns are sorted so that field ids are stable for a given column map
+	columnNames := make([]string, 0, len(columnMap))
+	for columnName := range columnMap {
+		columnNames = append(columnNames, columnName)
+	}
+	sort.Strings(columnNames)
+
+	schema := SchemaT{Type: "struct", SchemaID: 0, Fields: make([]FieldT, 0, len(columnNames))}
+	for i, columnName := range columnNames {
+		icebergType, ok := dataTypesMap[columnMap[columnName]]
+		if !ok {
+			return nil, fmt.Errorf("unsupported data type %s of column %s", columnMap[columnName], columnName)
+		}
+		schema.Fields = append(schema.Fields, FieldT{ID: i + 1, Name: columnName, Type: icebergType})
+	}
+
+	metadata := &TableMetadataT{
+		FormatVersion:     FormatVersion,
+		TableUUID:         tableUUID,
+		Location:          location,
+		LastUpdatedMs:     timestampMs,
+		LastColumnID:      len(schema.Fields),
+		Schema:            schema,
+		Schemas:           []SchemaT{schema},
+		CurrentSchemaID:   schema.SchemaID,
+		PartitionSpec:     []interface{}{},
+		PartitionSpecs:    []PartitionSpecT{{SpecID: 0, Fields: []interface{}{}}},
+		LastPartitionID:   unpartitionedLastPartitionID,
+		Properties:        map[string]string{},
+		CurrentSnapshotID: -1,
+		Snapshots:         []SnapshotT{},
+		SnapshotLog:       []SnapshotLogEntryT{},
+		MetadataLog:       []MetadataLogEntryT{},
+		SortOrders:        []SortOrderT{{OrderID: 0, Fields: []interface{}{}}},
+		Refs:              map[string]SnapshotRefT{},
+	}
+	if err := metadata.updateNameMapping(); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+//CurrentSchema returns the schema of current-schema-id
+func (metadata *TableMetadataT) CurrentSchema() SchemaT {
+	for _, schema := range metadata.Schemas {
+		if schema.SchemaID == metadata.CurrentSchemaID {
+			return schema
+		}
+	}
+	return metadata.Schema
+}
+
+//CurrentSnapshot returns the snapshot of current-snapshot-id, nil if the table has no snapshots
+func (metadata *TableMetadataT) CurrentSnapshot() *SnapshotT {
+	for i := range metadata.Snapshots {
+		if metadata.Snapshots[i].SnapshotID == metadata.CurrentSnapshotID {
+			return &metadata.Snapshots[i]
+		}
+	}
+	return nil
+}
+
+//ColumnMap returns the columns of the current schema with their rudder data types
+func (metadata *TableMetadataT) ColumnMap() map[string]string {
+	columnMap := make(map[string]string)
+	for _, field := range metadata.CurrentSchema().Fields {
+		if dataType, ok := dataTypesMapToRudder[field.Type]; ok {
+			columnMap[field.Name] = dataType
+		}
+	}
+	return columnMap
+}
+
+//addColumn makes a new current schema with columnName appended, returning false if the column already exists
+func (metadata *TableMetadataT) addColumn(columnName string, columnType string) (bool, error) {
+	icebergType, ok := dataTypesMap[columnType]
+	if !ok {
+		return false, fmt.Errorf("unsupported data type %s of column %s", columnType, columnName)
+	}
+	current := metadata.CurrentSchema()
+	for _, field := range current.Fields {
+		if field.Name == columnName {
+			return false, nil
+		}
+	}
+
+	schemaID := 0
+	for _, schema := range metadata.Schemas {
+		if schema.SchemaID >= schemaID {
+			schemaID = schema.SchemaID + 1
+		}
+	}
+	metadata.LastColumnID++
+	fields := append(append([]FieldT{}, current.Fields...), FieldT{ID: metadata.LastColumnID, Name: columnName, Type: icebergType})
+	schema := SchemaT{Type: "struct", SchemaID: schemaID, Fields: fields}
+	metadata.Schemas = append(metadata.Schemas, schema)
+	metadata.Schema = schema
+	metadata.CurrentSchemaID = schemaID
+	return true, metadata.updateNameMapping()
+}
+
+//updateNameMapping maps every field of the current schema by its name
+func (metadata *TableMetadataT) updateNameMapping() error {
+	type mappedFieldT struct {
+		FieldID int      `json:"field-id"`
+		Names   []string `json:"names"`
+	}
+	fields := metadata.CurrentSchema().Fields
+	mapping := make([]mappedFieldT, 0, len(fields))
+	for _, field := range fields {
+		mapping = append(mapping, mappedFieldT{FieldID: field.ID, Names: []string{field.Name}})
+	}
+	rawMapping, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	metadata.Properties[NameMappingProperty] = string(rawMapping)
+	return nil
+}
+
+//addSnapshot makes snapshot the current snapshot of the main branch
+func (metadata *TableMetadataT) addSnapshot(snapshot SnapshotT) {
+	metadata.Snapshots = append(metadata.Snapshots, snapshot)
+	metadata.SnapshotLog = append(metadata.SnapshotLog, SnapshotLogEntryT{TimestampMs: snapshot.TimestampMs, SnapshotID: snapshot.SnapshotID})
+	metadata.CurrentSnapshotID = snapshot.SnapshotID
+	metadata.Refs["main"] = SnapshotRefT{SnapshotID: snapshot.SnapshotID, Type: "branch"}
+	metadata.LastUpdatedMs = snapshot.TimestampMs
+}
+
+//addMetadataLog records the metadata file replaced by the next version, keeping the last maxMetadataLogEntries entries
+func (metadata *TableMetadataT) addMetadataLog(metadataFile string, timestampMs int64) {
+	metadata.MetadataLog = append(metadata.MetadataLog, MetadataLogEntryT{TimestampMs: timestampMs, MetadataFile: metadataFile})
+	if len(metadata.MetadataLog) > maxMetadataLogEntries {
+		metadata.MetadataLog = metadata.MetadataLog[len(metadata.MetadataLog)-maxMetadataLogEntries:]
+	}
+}
+
+//hasCommit returns whether a snapshot of the table is marked with commitID
+func (metadata *TableMetadataT) hasCommit(commitID string) bool {
+	for _, snapshot := range metadata.Snapshots {
+		if snapshot.Summary[CommitIDSummaryProperty] == commitID {
+			return true
+		}
+	}
+	return false
+}
+
+//snapshotSummary returns the summary of a snapshot appending addedFiles, with the totals carried over from parent
+func snapshotSummary(parent *SnapshotT, addedFiles []DataFileT, commitID string) map[string]string {
+	var addedRecords, addedSize int64
+	for _, file := range addedFiles {
+		addedRecords += file.RecordCount
+		addedSize += file.FileSizeInBytes
+	}
+	var totalFiles, totalRecords, totalSize int64
+	if parent != nil {
+		totalFiles, _ = strconv.ParseInt(parent.Summary["total-data-files"], 10, 64)
+		totalRecords, _ = strconv.ParseInt(parent.Summary["total-records"], 10, 64)
+		totalSize, _ = strconv.ParseInt(parent.Summary["total-files-size"], 10, 64)
+	}
+	summary := map[string]string{
+		"operation":             "append",
+		"added-data-files":      strconv.Itoa(len(addedFiles)),
+		"added-records":         strconv.FormatInt(addedRecords, 10),
+		"added-files-size":      strconv.FormatInt(addedSize, 10),
+		"total-data-files":      strconv.FormatInt(totalFiles+int64(len(addedFiles)), 10),
+		"total-records":         strconv.FormatInt(totalRecords+addedRecords, 10),
+		"total-files-size":      strconv.FormatInt(totalSize+addedSize, 10),
+		"total-delete-files":    "0",
+		CommitIDSummaryProperty: commitID,
+	}
+	return summary
+}
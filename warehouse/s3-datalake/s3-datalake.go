@@ -3,36 +3,110 @@ package s3datalake
 import (
 	"fmt"
 
+	"github.com/tidwall/gjson"
+
+	"github.com/rudderlabs/rudder-server/config"
+	"github.com/rudderlabs/rudder-server/services/filemanager"
 	"github.com/rudderlabs/rudder-server/utils/logger"
 	"github.com/rudderlabs/rudder-server/utils/misc"
 	"github.com/rudderlabs/rudder-server/warehouse/client"
+	"github.com/rudderlabs/rudder-server/warehouse/fileio"
+	"github.com/rudderlabs/rudder-server/warehouse/s3-datalake/iceberg"
 	schemarepository "github.com/rudderlabs/rudder-server/warehouse/s3-datalake/schema-repository"
 	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
 )
 
 var (
-	pkgLogger logger.LoggerI
+	pkgLogger          logger.LoggerI
+	icebergCatalogPath string
 )
 
 func init() {
+	loadConfig()
 	pkgLogger = logger.NewLogger().Child("warehouse").Child("s3-datalake")
 }
 
+func loadConfig() {
+	config.RegisterStringConfigVariable("", &icebergCatalogPath, false, "Warehouse.s3_datalake.iceberg.catalogPath")
+}
+
 type HandleT struct {
 	SchemaRepository schemarepository.SchemaRepository
 	Warehouse        warehouseutils.WarehouseT
 	Uploader         warehouseutils.UploaderI
+	// Catalog commits the load files of uploads to iceberg tables, nil unless the destination is in iceberg mode
+	Catalog     *iceberg.CatalogT
+	fileManager filemanager.FileManager
+	bucket      string
 }
 
 func (wh *HandleT) Setup(warehouse warehouseutils.WarehouseT, uploader warehouseutils.UploaderI) (err error) {
 	wh.Warehouse = warehouse
 	wh.Uploader = uploader
 
+	if warehouseutils.GetConfigValueBoolString(schemarepository.UseIcebergConfig, wh.Warehouse) == "true" {
+		if err = wh.setupCatalog(); err != nil {
+			return err
+		}
+		wh.SchemaRepository, err = schemarepository.NewIcebergSchemaRepository(wh.Warehouse, wh.Uploader, wh.Catalog)
+		return err
+	}
+
 	wh.SchemaRepository, err = schemarepository.NewSchemaRepository(wh.Warehouse, wh.Uploader)
 
 	return err
 }
 
+// setupCatalog keeps the iceberg metadata next to the data files in the bucket, or in Warehouse.s3_datalake.iceberg.catalogPath if set
+func (wh *HandleT) setupCatalog() (err error) {
+	provider := warehouseutils.ObjectStorageType(warehouseutils.S3_DATALAKE, wh.Warehouse.Destination.Config, wh.Uploader.UseRudderStorage())
+	storageConfig := misc.GetObjectStorageConfig(misc.ObjectStorageOptsT{
+		Provider:         provider,
+		Config:           wh.Warehouse.Destination.Config,
+		UseRudderStorage: wh.Uploader.UseRudderStorage(),
+	})
+	wh.fileManager, err = filemanager.New(&filemanager.SettingsT{
+		Provider: provider,
+		Config:   storageConfig,
+	})
+	if err != nil {
+		return err
+	}
+	wh.bucket, _ = storageConfig["bucketName"].(string)
+
+	var fileIO fileio.FileIO
+	if icebergCatalogPath != "" {
+		if fileIO, err = fileio.NewLocalFileIO(icebergCatalogPath); err != nil {
+			return err
+		}
+	} else {
+		prefix, _ := storageConfig["prefix"].(string)
+		fileIO = fileio.NewObjectStorageFileIO("s3", wh.bucket, prefix, wh.fileManager, fileio.NewAdvisoryLocker(wh.Uploader.GetDBHandle()))
+	}
+	wh.Catalog = iceberg.NewCatalog(fileIO)
+	return nil
+}
+
+// appendLoadFiles commits the load files of the upload for tableName as a snapshot of its iceberg table
+func (wh *HandleT) appendLoadFiles(tableName string) error {
+	loadFiles := wh.Uploader.GetLoadFilesMetadata(warehouseutils.GetLoadFilesOptionsT{Table: tableName})
+	dataFiles := make([]iceberg.DataFileT, 0, len(loadFiles))
+	for _, loadFile := range loadFiles {
+		objectName, err := wh.fileManager.GetObjectNameFromLocation(loadFile.Location)
+		if err != nil {
+			return err
+		}
+		dataFiles = append(dataFiles, iceberg.DataFileT{
+			FilePath:        fmt.Sprintf("s3://%s/%s", wh.bucket, objectName),
+			FileFormat:      warehouseutils.LOAD_FILE_TYPE_PARQUET,
+			RecordCount:     gjson.GetBytes(loadFile.Metadata, "total_rows").Int(),
+			FileSizeInBytes: gjson.GetBytes(loadFile.Metadata, "content_length").Int(),
+		})
+	}
+	pkgLogger.Infof("Committing %d load files to iceberg table %s : %s", len(dataFiles), tableName, wh.Warehouse.Destination.ID)
+	return wh.Catalog.AppendFiles(wh.Warehouse.Namespace, tableName, dataFiles)
+}
+
 func (wh *HandleT) CrashRecover(warehouse warehouseutils.WarehouseT) (err error) {
 	return nil
 }
@@ -58,11 +132,21 @@ func (wh *HandleT) AlterColumn(tableName string, columnName string, columnType s
 }
 
 func (wh *HandleT) LoadTable(tableName string) error {
+	if wh.Catalog != nil {
+		return wh.appendLoadFiles(tableName)
+	}
 	pkgLogger.Infof("Skipping load for table %s : %s is a s3 datalake destination", tableName, wh.Warehouse.Destination.ID)
 	return nil
 }
 
 func (wh *HandleT) LoadUserTables() map[string]error {
+	if wh.Catalog != nil {
+		errorMap := map[string]error{warehouseutils.IdentifiesTable: wh.appendLoadFiles(warehouseutils.IdentifiesTable)}
+		if len(wh.Uploader.GetTableSchemaInUpload(warehouseutils.UsersTable)) > 0 {
+			errorMap[warehouseutils.UsersTable] = wh.appendLoadFiles(warehouseutils.UsersTable)
+		}
+		return errorMap
+	}
 	pkgLogger.Infof("Skipping load for user tables : %s is a s3 datalake destination", wh.Warehouse.Destination.ID)
 	// return map with nil error entries for identifies and users(if any) tables
 	// this is so that they are marked as succeeded
@@ -19,6 +19,7 @@ var (
 	AWSS3Prefix         = "prefix"
 	AWSRegion           = "region"
 	UseGlueConfig       = "useGlue"
+	UseIcebergConfig    = "useIceberg"
 
 	// glue
 	glueSerdeName             = "ParquetHiveSerDe"
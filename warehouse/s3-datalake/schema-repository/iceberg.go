@@ -0,0 +1,69 @@
+package schemarepository
+
+import (
+	"github.com/rudderlabs/rudder-server/warehouse/s3-datalake/iceberg"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+//IcebergSchemaRepository keeps the schema in the local db, like LocalSchemaRepository, and evolves the schemas of the iceberg tables of the catalog with it
+type IcebergSchemaRepository struct {
+	local     *LocalSchemaRepository
+	catalog   *iceberg.CatalogT
+	namespace string
+}
+
+func NewIcebergSchemaRepository(wh warehouseutils.WarehouseT, uploader warehouseutils.UploaderI, catalog *iceberg.CatalogT) (*IcebergSchemaRepository, error) {
+	local, err := NewLocalSchemaRepository(wh, uploader)
+	if err != nil {
+		return nil, err
+	}
+	ir := IcebergSchemaRepository{
+		local:     local,
+		catalog:   catalog,
+		namespace: wh.Namespace,
+	}
+
+	return &ir, nil
+}
+
+func (ir *IcebergSchemaRepository) FetchSchema(warehouse warehouseutils.WarehouseT) (warehouseutils.SchemaT, error) {
+	return ir.local.FetchSchema(warehouse)
+}
+
+func (ir *IcebergSchemaRepository) CreateSchema() (err error) {
+	return nil
+}
+
+func (ir *IcebergSchemaRepository) CreateTable(tableName string, columnMap map[string]string) (err error) {
+	// the table can exist in the catalog without being in the local schema, if updating the local schema failed after creating it
+	_, err = ir.catalog.LoadTable(ir.namespace, tableName)
+	switch err {
+	case iceberg.ErrNoSuchTable:
+		err = ir.catalog.CreateTable(ir.namespace, tableName, columnMap)
+	case nil:
+		err = ir.catalog.AddColumns(ir.namespace, tableName, columnMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ir.local.CreateTable(tableName, columnMap)
+}
+
+func (ir *IcebergSchemaRepository) AddColumn(tableName string, columnName string, columnType string) (err error) {
+	err = ir.catalog.AddColumns(ir.namespace, tableName, map[string]string{columnName: columnType})
+	if err != nil {
+		return err
+	}
+
+	return ir.local.AddColumn(tableName, columnName, columnType)
+}
+
+func (ir *IcebergSchemaRepository) AlterColumn(tableName string, columnName string, columnType string) (err error) {
+	err = ir.catalog.AlterColumn(ir.namespace, tableName, columnName, columnType)
+	if err != nil {
+		return err
+	}
+
+	return ir.local.AlterColumn(tableName, columnName, columnType)
+}
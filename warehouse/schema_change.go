@@ -0,0 +1,221 @@
+package warehouse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rudderlabs/rudder-server/utils/timeutil"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+// statuses of schema changes
+const (
+	SchemaChangePending  = "pending"
+	SchemaChangeApproved = "approved"
+	SchemaChangeRejected = "rejected"
+	SchemaChangeApplied  = "applied"
+)
+
+const heldForSchemaChangesField = "heldForSchemaChanges"
+
+//SchemaChangeT is a change to the schema of a table of a destination, recorded for destinations with the notify or approve schema change policy
+type SchemaChangeT struct {
+	ID              int64
+	SourceID        string
+	DestinationID   string
+	DestinationType string
+	Namespace       string
+	TableName       string
+	Diff            warehouseutils.TableSchemaDiffT
+	Status          string
+	UploadID        int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+//SchemaChangesPendingError holds an upload adding columns to tables pending approval
+type SchemaChangesPendingError struct {
+	tableNames []string
+}
+
+func (scpe *SchemaChangesPendingError) Error() string {
+	return fmt.Sprintf("Holding upload until schema changes of tables %s are approved or rejected", strings.Join(scpe.tableNames, ", "))
+}
+
+//getSchemaChanges returns the approved and rejected schema changes of the tables of a destination namespace, in the order they were decided
+func getSchemaChanges(destinationID, namespace string) (map[string][]SchemaChangeT, error) {
+	sqlStatement := fmt.Sprintf(`SELECT id, table_name, schema_diff, status FROM %s WHERE destination_id=$1 AND namespace=$2 AND status IN ('%s', '%s') ORDER BY updated_at ASC, id ASC`, warehouseutils.WarehouseSchemaChangesTable, SchemaChangeApproved, SchemaChangeRejected)
+	rows, err := dbHandle.Query(sqlStatement, destinationID, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("Query: %s failed with Error : %w", sqlStatement, err)
+	}
+	defer rows.Close()
+
+	changesByTable := make(map[string][]SchemaChangeT)
+	for rows.Next() {
+		var change SchemaChangeT
+		var rawDiff json.RawMessage
+		err = rows.Scan(&change.ID, &change.TableName, &rawDiff, &change.Status)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(rawDiff, &change.Diff)
+		if err != nil {
+			return nil, err
+		}
+		changesByTable[change.TableName] = append(changesByTable[change.TableName], change)
+	}
+	return changesByTable, rows.Err()
+}
+
+/*
+ReviewSchemaChanges splits the columns of columnMap by the latest decision on them in changes, which are in the order they were decided.
+Columns without a decision are pending
+*/
+func ReviewSchemaChanges(columnMap map[string]string, changes []SchemaChangeT) (approved, rejected, pending map[string]string) {
+	decisions := make(map[string]string)
+	for _, change := range changes {
+		if change.Status != SchemaChangeApproved && change.Status != SchemaChangeRejected {
+			continue
+		}
+		for columnName := range change.Diff.ColumnMap {
+			decisions[columnName] = change.Status
+		}
+	}
+
+	approved, rejected, pending = make(map[string]string), make(map[string]string), make(map[string]string)
+	for columnName, columnType := range columnMap {
+		switch decisions[columnName] {
+		case SchemaChangeApproved:
+			approved[columnName] = columnType
+		case SchemaChangeRejected:
+			rejected[columnName] = columnType
+		default:
+			pending[columnName] = columnType
+		}
+	}
+	return
+}
+
+//insertSchemaChange records a change to the schema of tableName by the upload with status, merging the columns of pending changes into the pending change of the table
+func (job *UploadJobT) insertSchemaChange(tableName string, diff warehouseutils.TableSchemaDiffT, status string) error {
+	// the whole schema of the table is not part of the change
+	diff.UpdatedSchema = nil
+	rawDiff, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	now := timeutil.Now()
+	sqlStatement := fmt.Sprintf(`INSERT INTO %[1]s (source_id, destination_id, destination_type, namespace, table_name, schema_diff, status, wh_upload_id, created_at, updated_at)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+								ON CONFLICT (destination_id, namespace, table_name) WHERE status = '%[2]s'
+								DO UPDATE SET schema_diff = jsonb_set(%[1]s.schema_diff, '{columnMap}', (%[1]s.schema_diff->'columnMap') || (EXCLUDED.schema_diff->'columnMap')), wh_upload_id = EXCLUDED.wh_upload_id`,
+		warehouseutils.WarehouseSchemaChangesTable, SchemaChangePending)
+	_, err = dbHandle.Exec(sqlStatement, job.warehouse.Source.ID, job.warehouse.Destination.ID, job.warehouse.Type, job.warehouse.Namespace, tableName, rawDiff, status, job.upload.ID, now, now)
+	if err != nil {
+		return fmt.Errorf("Query: %s failed with Error : %w", sqlStatement, err)
+	}
+	return nil
+}
+
+/*
+reviewUploadSchemaChanges removes the columns the upload adds to existing tables from its upload schema unless they were approved, for destinations with the approve schema change policy.
+Columns without a decision are recorded as pending, and the upload is held with a SchemaChangesPendingError if Warehouse.schemaChanges.holdUploads is set.
+Tables are created without approval, as no model reads new tables
+*/
+func (job *UploadJobT) reviewUploadSchemaChanges(schemaHandle *SchemaHandleT) error {
+	if warehouseutils.GetSchemaChangePolicy(job.warehouse) != warehouseutils.SchemaChangePolicyApprove {
+		return nil
+	}
+
+	uploadSchema := schemaHandle.uploadSchema
+	var changesByTable map[string][]SchemaChangeT
+	var heldTables []string
+	for tableName := range uploadSchema {
+		diff := getTableSchemaDiff(tableName, schemaHandle.schemaInWarehouse, uploadSchema)
+		if diff.TableToBeCreated || len(diff.ColumnMap) == 0 {
+			continue
+		}
+		if changesByTable == nil {
+			var err error
+			changesByTable, err = getSchemaChanges(job.warehouse.Destination.ID, job.warehouse.Namespace)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, rejected, pending := ReviewSchemaChanges(diff.ColumnMap, changesByTable[tableName])
+		for columnName := range rejected {
+			delete(uploadSchema[tableName], columnName)
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		for columnName := range pending {
+			delete(uploadSchema[tableName], columnName)
+		}
+		err := job.insertSchemaChange(tableName, warehouseutils.TableSchemaDiffT{Exists: true, ColumnMap: pending}, SchemaChangePending)
+		if err != nil {
+			return err
+		}
+		pkgLogger.Infof("[WH]: Columns %v of table %s in namespace %s of destination %s:%s are pending approval", pending, tableName, job.warehouse.Namespace, job.warehouse.Type, job.warehouse.Destination.ID)
+		job.counterStat("schema_change_pending_columns", tag{name: "tableName", value: strings.ToLower(tableName)}).Count(len(pending))
+		heldTables = append(heldTables, tableName)
+	}
+
+	if holdUploadsOnPendingSchemaChanges && len(heldTables) > 0 {
+		return &SchemaChangesPendingError{tableNames: heldTables}
+	}
+	return nil
+}
+
+//recordAppliedSchemaChange records the change to the schema of tableName applied by the upload, for destinations with the notify or approve schema change policy
+func (job *UploadJobT) recordAppliedSchemaChange(tableName string, diff warehouseutils.TableSchemaDiffT) {
+	if warehouseutils.GetSchemaChangePolicy(job.warehouse) == warehouseutils.SchemaChangePolicyAuto {
+		return
+	}
+	err := job.insertSchemaChange(tableName, diff, SchemaChangeApplied)
+	if err != nil {
+		pkgLogger.Errorf("[WH]: Failed to record schema change of table %s of upload %d: %v", tableName, job.upload.ID, err)
+		return
+	}
+	pkgLogger.Infof("[WH]: Applied schema change of table %s in namespace %s of destination %s:%s : columns added %v, altered to text %v", tableName, job.warehouse.Namespace, job.warehouse.Type, job.warehouse.Destination.ID, diff.ColumnMap, diff.StringColumnsToBeAlteredToText)
+	job.counterStat("schema_change_applied_columns", tag{name: "tableName", value: strings.ToLower(tableName)}).Count(len(diff.ColumnMap) + len(diff.StringColumnsToBeAlteredToText))
+}
+
+//holdUpload sets the upload back to waiting, until a pending schema change of its destination is decided or Warehouse.schemaChanges.retryInterval passes
+func (job *UploadJobT) holdUpload(pendingErr *SchemaChangesPendingError) error {
+	pkgLogger.Infof("[WH]: Upload %d : %v", job.upload.ID, pendingErr)
+	var metadata map[string]interface{}
+	err := json.Unmarshal(job.upload.Metadata, &metadata)
+	if err != nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["nextRetryTime"] = timeutil.Now().Add(pendingSchemaChangesRetryInterval).Format(time.RFC3339)
+	metadata[heldForSchemaChangesField] = true
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	job.upload.Metadata = metadataJSON
+
+	job.counterStat("upload_held_for_schema_changes").Count(1)
+	return job.setUploadColumns(UploadColumnsOpts{Fields: []UploadColumnT{
+		{Column: UploadStatusField, Value: Waiting},
+		{Column: "metadata", Value: metadataJSON},
+		{Column: UploadUpdatedAtField, Value: timeutil.Now()},
+	}})
+}
+
+//releaseHeldUploads retries the uploads of the destination namespace held for pending schema changes right away
+func releaseHeldUploads(dbHandle *sql.DB, destinationID, namespace string) error {
+	sqlStatement := fmt.Sprintf(`UPDATE %[1]s SET metadata = jsonb_set(metadata - '%[2]s', '{nextRetryTime}', to_jsonb($1::text)), updated_at = $2
+								WHERE destination_id = $3 AND namespace = $4 AND status = '%[3]s' AND metadata->>'%[2]s' = 'true'`,
+		warehouseutils.WarehouseUploadsTable, heldForSchemaChangesField, Waiting)
+	_, err := dbHandle.Exec(sqlStatement, timeutil.Now().Add(-time.Hour*1).Format(time.RFC3339), timeutil.Now(), destinationID, namespace)
+	return err
+}
@@ -0,0 +1,50 @@
+package warehouse_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/rudderlabs/rudder-server/warehouse"
+	warehouseutils "github.com/rudderlabs/rudder-server/warehouse/utils"
+)
+
+var _ = Describe("SchemaChange", func() {
+	Describe("ReviewSchemaChanges", func() {
+		change := func(status string, columnMap map[string]string) SchemaChangeT {
+			return SchemaChangeT{Status: status, Diff: warehouseutils.TableSchemaDiffT{Exists: true, ColumnMap: columnMap}}
+		}
+
+		It("should split columns by their decision", func() {
+			changes := []SchemaChangeT{
+				change(SchemaChangeApproved, map[string]string{"plan": "string"}),
+				change(SchemaChangeRejected, map[string]string{"card_number": "string"}),
+			}
+			approved, rejected, pending := ReviewSchemaChanges(map[string]string{"plan": "string", "card_number": "string", "seats": "int"}, changes)
+			Expect(approved).To(Equal(map[string]string{"plan": "string"}))
+			Expect(rejected).To(Equal(map[string]string{"card_number": "string"}))
+			Expect(pending).To(Equal(map[string]string{"seats": "int"}))
+		})
+
+		It("should apply the latest decision on a column", func() {
+			changes := []SchemaChangeT{
+				change(SchemaChangeRejected, map[string]string{"plan": "string"}),
+				change(SchemaChangeApproved, map[string]string{"plan": "string", "seats": "int"}),
+			}
+			approved, rejected, pending := ReviewSchemaChanges(map[string]string{"plan": "string", "seats": "int"}, changes)
+			Expect(approved).To(Equal(map[string]string{"plan": "string", "seats": "int"}))
+			Expect(rejected).To(BeEmpty())
+			Expect(pending).To(BeEmpty())
+		})
+
+		It("should keep columns of undecided changes pending", func() {
+			changes := []SchemaChangeT{
+				change(SchemaChangePending, map[string]string{"plan": "string"}),
+				change(SchemaChangeApplied, map[string]string{"seats": "int"}),
+			}
+			approved, rejected, pending := ReviewSchemaChanges(map[string]string{"plan": "string", "seats": "int"}, changes)
+			Expect(approved).To(BeEmpty())
+			Expect(rejected).To(BeEmpty())
+			Expect(pending).To(Equal(map[string]string{"plan": "string", "seats": "int"}))
+		})
+	})
+})
@@ -325,6 +325,17 @@ func (sf *HandleT) loadTable(tableName string, tableSchemaInUpload warehouseutil
 	if tableName == discardsTable {
 		additionalJoinClause = fmt.Sprintf(`AND original."%[1]s" = staging."%[1]s" AND original."%[2]s" = staging."%[2]s"`, "TABLE_NAME", "COLUMN_NAME")
 	}
+	// dedup on load merges on the configured keys, with the rows received within the dedup window only
+	if dedup, ok := warehouseutils.GetDedupOnLoad(sf.Warehouse, sf.Uploader, tableName); ok {
+		primaryKey = dedup.Keys[0]
+		partitionKey = warehouseutils.DoubleQuoteAndJoinByComma(dedup.Keys)
+		for _, key := range dedup.Keys[1:] {
+			additionalJoinClause += fmt.Sprintf(` AND original."%[1]s" = staging."%[1]s"`, key)
+		}
+		if !dedup.Since.IsZero() {
+			additionalJoinClause += fmt.Sprintf(` AND original."RECEIVED_AT" >= '%s'::TIMESTAMP_TZ`, dedup.Since.Format(time.RFC3339))
+		}
+	}
 
 	keepLatestRecordOnDedup := sf.Uploader.ShouldOnDedupUseNewRecord()
 
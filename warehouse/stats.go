@@ -138,6 +138,23 @@ func (job *UploadJobT) generateUploadAbortedMetrics() {
 	job.counterStat("upload_aborted", tag{name: "attempt_number", value: strconv.Itoa(attempts)}).Count(1)
 }
 
+// recordTableDedup records the events of the table upload deduped on load, given the rows added to the table by the load
+func (job *UploadJobT) recordTableDedup(tableUpload *TableUploadT, tableName string, rowsAdded int64) {
+	numEvents, err := tableUpload.getNumEvents()
+	if err != nil {
+		pkgLogger.Errorf(`[WH]: Failed getting events in table upload of table:%s: %v`, tableName, err)
+		return
+	}
+	dedupedEvents := numEvents - rowsAdded
+	if dedupedEvents < 0 {
+		dedupedEvents = 0
+	}
+	if err = tableUpload.setDedupedEvents(dedupedEvents); err != nil {
+		pkgLogger.Errorf(`[WH]: Failed setting deduped events of table upload of table:%s: %v`, tableName, err)
+	}
+	job.counterStat(`dedup_on_load_events`, tag{name: "tableName", value: strings.ToLower(tableName)}).Count(int(dedupedEvents))
+}
+
 func (job *UploadJobT) recordTableLoad(tableName string, numEvents int64) {
 	rudderAPISupportedEventTypes := []string{"tracks", "identifies", "pages", "screens", "aliases", "groups"}
 	if misc.Contains(rudderAPISupportedEventTypes, strings.ToLower(tableName)) {
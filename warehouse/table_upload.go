@@ -137,6 +137,14 @@ func (tableUpload *TableUploadT) updateTableEventsCount(job *UploadJobT) (err er
 	return
 }
 
+// setDedupedEvents records the events of the table upload merged with rows already in the table
+func (tableUpload *TableUploadT) setDedupedEvents(dedupedEvents int64) (err error) {
+	sqlStatement := fmt.Sprintf(`UPDATE %s SET deduped_events=$1, updated_at=$2 WHERE wh_upload_id=$3 AND table_name=$4`, warehouseutils.WarehouseTableUploadsTable)
+	pkgLogger.Debugf("[WH]: Setting table upload deduped events: %v", sqlStatement)
+	_, err = dbHandle.Exec(sqlStatement, dedupedEvents, timeutil.Now(), tableUpload.uploadID, tableUpload.tableName)
+	return err
+}
+
 func (tableUpload *TableUploadT) getNumEvents() (total int64, err error) {
 	sqlStatement := fmt.Sprintf(`select total_events from wh_table_uploads where wh_upload_id=%d and table_name='%s'`, tableUpload.uploadID, tableUpload.tableName)
 	err = dbHandle.QueryRow(sqlStatement).Scan(&total)
@@ -164,6 +164,8 @@ func setMaxParallelLoads() {
 		"MSSQL":      config.GetInt("Warehouse.mssql.maxParallelLoads", 3),
 		"SNOWFLAKE":  config.GetInt("Warehouse.snowflake.maxParallelLoads", 3),
 		"CLICKHOUSE": config.GetInt("Warehouse.clickhouse.maxParallelLoads", 3),
+		"DUCKDB":     config.GetInt("Warehouse.duckdb.maxParallelLoads", 1),
+		"DELTALAKE":  config.GetInt("Warehouse.deltalake.maxParallelLoads", 3),
 	}
 }
 
@@ -199,16 +201,20 @@ func (job *UploadJobT) trackLongRunningUpload() chan struct{} {
 
 func (job *UploadJobT) generateUploadSchema(schemaHandle *SchemaHandleT) error {
 	schemaHandle.uploadSchema = schemaHandle.consolidateStagingFilesSchemaUsingWarehouseSchema()
+	err := job.reviewUploadSchemaChanges(schemaHandle)
+	if err != nil {
+		return err
+	}
 	if job.upload.LoadFileType == warehouseutils.LOAD_FILE_TYPE_PARQUET {
 		// set merged schema if the loadFileType is parquet
 		mergedSchema := mergeUploadAndLocalSchemas(schemaHandle.uploadSchema, schemaHandle.localSchema)
-		err := job.setMergedSchema(mergedSchema)
+		err = job.setMergedSchema(mergedSchema)
 		if err != nil {
 			return err
 		}
 	}
 	// set upload schema
-	err := job.setUploadSchema(schemaHandle.uploadSchema)
+	err = job.setUploadSchema(schemaHandle.uploadSchema)
 	return err
 }
 
@@ -368,6 +374,9 @@ func (job *UploadJobT) run() (err error) {
 		case GeneratedUploadSchema:
 			newStatus = nextUploadState.failed
 			err = job.generateUploadSchema(schemaHandle)
+			if pendingErr, ok := err.(*SchemaChangesPendingError); ok {
+				return job.holdUpload(pendingErr)
+			}
 			if err != nil {
 				break
 			}
@@ -867,6 +876,7 @@ func (job *UploadJobT) updateSchema(tName string) (alteredSchema bool, err error
 		}
 
 		job.setUpdatedTableSchema(tName, tableSchemaDiff.UpdatedSchema)
+		job.recordAppliedSchemaChange(tName, tableSchemaDiff)
 		alteredSchema = true
 	}
 	return
@@ -902,12 +912,14 @@ func (job *UploadJobT) loadTable(tName string) (alteredSchema bool, err error) {
 	tableUpload.setStatus(TableUploadExecuting)
 
 	generateTableLoadCountVerificationsMetrics := config.GetBool("Warehouse.generateTableLoadCountMetrics", true)
+	// rows deduped on load are the events of the table upload not added to the table, so tables are counted around their load
+	_, dedupOnLoad := warehouseutils.GetDedupOnLoad(job.warehouse, job, tName)
 	var totalBeforeLoad, totalAfterLoad int64
-	if generateTableLoadCountVerificationsMetrics {
-		var countErr error
-		totalBeforeLoad, countErr = job.getTotalCount(tName)
-		if countErr != nil {
-			pkgLogger.Errorf(`Error getting total count in table:%s before load: %v`, tName, countErr)
+	var countBeforeLoadErr, countAfterLoadErr error
+	if generateTableLoadCountVerificationsMetrics || dedupOnLoad {
+		totalBeforeLoad, countBeforeLoadErr = job.getTotalCount(tName)
+		if countBeforeLoadErr != nil {
+			pkgLogger.Errorf(`Error getting total count in table:%s before load: %v`, tName, countBeforeLoadErr)
 		}
 	}
 
@@ -917,12 +929,17 @@ func (job *UploadJobT) loadTable(tName string) (alteredSchema bool, err error) {
 		return
 	}
 
-	if generateTableLoadCountVerificationsMetrics {
-		var countErr error
-		totalAfterLoad, countErr = job.getTotalCount(tName)
-		if countErr != nil {
-			pkgLogger.Errorf(`Error getting total count in table:%s after load: %v`, tName, countErr)
+	if generateTableLoadCountVerificationsMetrics || dedupOnLoad {
+		totalAfterLoad, countAfterLoadErr = job.getTotalCount(tName)
+		if countAfterLoadErr != nil {
+			pkgLogger.Errorf(`Error getting total count in table:%s after load: %v`, tName, countAfterLoadErr)
 		}
+	}
+	if dedupOnLoad && countBeforeLoadErr == nil && countAfterLoadErr == nil {
+		job.recordTableDedup(tableUpload, tName, totalAfterLoad-totalBeforeLoad)
+	}
+
+	if generateTableLoadCountVerificationsMetrics {
 		job.guageStat(`pre_load_table_rows`, tag{name: "tableName", value: strings.ToLower(tName)}).Gauge(int(totalBeforeLoad))
 		eventsInTableUpload := tableUpload.getTotalEvents()
 		job.guageStat(`post_load_table_rows_estimate`, tag{name: "tableName", value: strings.ToLower(tName)}).Gauge(int(totalBeforeLoad + eventsInTableUpload))
@@ -1595,7 +1612,7 @@ func (job *UploadJobT) createLoadFiles(generateAll bool) (startLoadFileID int64,
 				RudderStoragePrefix:  misc.GetRudderObjectStoragePrefix(),
 			}
 
-			if job.warehouse.Type == "S3_DATALAKE" {
+			if misc.ContainsString(warehouseutils.TimeWindowDestinations, job.warehouse.Type) {
 				payload.LoadFilePrefix = stagingFile.TimeWindow.Format(warehouseutils.DatalakeTimeWindowFormat)
 			}
 
@@ -1729,7 +1746,7 @@ func (job *UploadJobT) bulkInsertLoadFileRecords(loadFiles []loadFileUploadOutpu
 	defer stmt.Close()
 
 	for _, loadFile := range loadFiles {
-		metadata := json.RawMessage(fmt.Sprintf(`{"content_length": %d}`, loadFile.ContentLength))
+		metadata := json.RawMessage(fmt.Sprintf(`{"content_length": %d, "total_rows": %d}`, loadFile.ContentLength, loadFile.TotalRows))
 		_, err = stmt.Exec(loadFile.StagingFileID, loadFile.Location, job.upload.SourceID, job.upload.DestinationID, job.upload.DestinationType, loadFile.TableName, loadFile.TotalRows, timeutil.Now(), metadata)
 		if err != nil {
 			pkgLogger.Errorf(`[WH]: Error copying row in pq.CopyIn for loadFules: %v Error: %v`, loadFile, err)
@@ -1885,6 +1902,14 @@ func (job *UploadJobT) GetLoadFileType() string {
 	return job.upload.LoadFileType
 }
 
+func (job *UploadJobT) GetFirstEventAt() time.Time {
+	return job.upload.FirstEventAt
+}
+
+func (job *UploadJobT) GetDBHandle() *sql.DB {
+	return job.dbHandle
+}
+
 /*
  * State Machine for upload job lifecycle
  */
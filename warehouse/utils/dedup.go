@@ -0,0 +1,74 @@
+package warehouseutils
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	//DedupOnLoadConfig enables merging the rows of event tables with the rows already loaded, on the dedup keys
+	DedupOnLoadConfig = "dedupOnLoad"
+	//DedupKeysConfig are the comma separated columns rows are merged on, id if not set
+	DedupKeysConfig = "dedupKeys"
+)
+
+var dedupOnLoadWindow time.Duration
+
+//DedupOnLoadT is how the rows of a table are merged on load
+type DedupOnLoadT struct {
+	//Keys are the columns of the table, in provider case, identifying a row
+	Keys []string
+	//Since is the received_at of the oldest rows already loaded which rows are merged with, zero for all rows
+	Since time.Time
+}
+
+//dedupExcludedTables have their own merge semantics
+var dedupExcludedTables = []string{UsersTable, IdentifiesTable, DiscardsTable, IdentityMergeRulesTable, IdentityMappingsTable}
+
+/*
+GetDedupOnLoad returns how the rows of tableName are merged on load, false if the destination does not dedup on load or the table is not an event table.
+Rows are merged with those received within Warehouse.dedupOnLoad.window before the first event of the upload
+*/
+func GetDedupOnLoad(warehouse WarehouseT, uploader UploaderI, tableName string) (DedupOnLoadT, bool) {
+	if GetConfigValueBoolString(DedupOnLoadConfig, warehouse) != "true" {
+		return DedupOnLoadT{}, false
+	}
+	for _, excludedTable := range dedupExcludedTables {
+		if strings.EqualFold(tableName, excludedTable) {
+			return DedupOnLoadT{}, false
+		}
+	}
+
+	//columns are matched case insensitively, as the schemas of some providers are upper case
+	columns := make(map[string]string)
+	for columnName := range uploader.GetTableSchemaInUpload(tableName) {
+		columns[strings.ToLower(columnName)] = columnName
+	}
+	var keys []string
+	for _, key := range strings.Split(GetConfigValue(DedupKeysConfig, warehouse), ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		columnName, ok := columns[key]
+		if !ok {
+			pkgLogger.Infof("WH: Merging rows of table %s on id : dedup key %s is not a column of the upload of %s", tableName, key, warehouse.Destination.ID)
+			keys = nil
+			break
+		}
+		keys = append(keys, columnName)
+	}
+	if len(keys) == 0 {
+		columnName, ok := columns["id"]
+		if !ok {
+			return DedupOnLoadT{}, false
+		}
+		keys = []string{columnName}
+	}
+
+	dedup := DedupOnLoadT{Keys: keys}
+	if firstEventAt := uploader.GetFirstEventAt(); dedupOnLoadWindow > 0 && !firstEventAt.IsZero() {
+		dedup.Since = firstEventAt.Add(-dedupOnLoadWindow).UTC()
+	}
+	return dedup, true
+}
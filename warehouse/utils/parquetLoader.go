@@ -1,6 +1,7 @@
 package warehouseutils
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -106,6 +107,17 @@ func getString(val interface{}) (string, error) {
 	return stringVal, nil
 }
 
+func getJSONString(val interface{}) (string, error) {
+	if stringVal, ok := val.(string); ok {
+		return stringVal, nil
+	}
+	jsonVal, err := json.Marshal(val)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %v to json: %v", val, err)
+	}
+	return string(jsonVal), nil
+}
+
 func GetParquetValue(val interface{}, colType string) (retVal interface{}, err error) {
 	switch colType {
 	case "bigint", "int":
@@ -123,6 +135,9 @@ func GetParquetValue(val interface{}, colType string) (retVal interface{}, err e
 	case "string", "text":
 		retVal, err = getString(val)
 		return
+	case "json":
+		retVal, err = getJSONString(val)
+		return
 	}
 	return nil, fmt.Errorf("unsupported type for parquet: %s", colType)
 }
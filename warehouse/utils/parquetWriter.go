@@ -38,6 +38,26 @@ var rudderDataTypeToParquetDataType = map[string]map[string]string{
 		"text":     PARQUET_STRING,
 		"datetime": PARQUET_TIMESTAMP_MICROS,
 	},
+	"DELTALAKE": {
+		"bigint":   PARQUET_INT_64,
+		"int":      PARQUET_INT_64,
+		"boolean":  PARQUET_BOOLEAN,
+		"float":    PARQUET_DOUBLE,
+		"string":   PARQUET_STRING,
+		"text":     PARQUET_STRING,
+		"datetime": PARQUET_TIMESTAMP_MICROS,
+		"json":     PARQUET_STRING,
+	},
+	"DUCKDB": {
+		"bigint":   PARQUET_INT_64,
+		"int":      PARQUET_INT_64,
+		"boolean":  PARQUET_BOOLEAN,
+		"float":    PARQUET_DOUBLE,
+		"string":   PARQUET_STRING,
+		"text":     PARQUET_STRING,
+		"datetime": PARQUET_TIMESTAMP_MICROS,
+		"json":     PARQUET_STRING,
+	},
 }
 
 type ParquetWriter struct {
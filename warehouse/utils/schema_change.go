@@ -0,0 +1,22 @@
+package warehouseutils
+
+const (
+	//SchemaChangePolicyConfig is how columns added to the tables of a destination by uploads are applied, SchemaChangePolicyAuto if not set
+	SchemaChangePolicyConfig = "schemaChangePolicy"
+	//SchemaChangePolicyAuto applies schema changes silently
+	SchemaChangePolicyAuto = "auto"
+	//SchemaChangePolicyNotify applies schema changes and records them
+	SchemaChangePolicyNotify = "notify"
+	//SchemaChangePolicyApprove applies the columns added to existing tables once approved
+	SchemaChangePolicyApprove = "approve"
+)
+
+//GetSchemaChangePolicy returns the schema change policy of the destination of warehouse
+func GetSchemaChangePolicy(warehouse WarehouseT) string {
+	switch policy := GetConfigValue(SchemaChangePolicyConfig, warehouse); policy {
+	case SchemaChangePolicyNotify, SchemaChangePolicyApprove:
+		return policy
+	default:
+		return SchemaChangePolicyAuto
+	}
+}
@@ -30,6 +30,9 @@ const (
 	CLICKHOUSE    = "CLICKHOUSE"
 	MSSQL         = "MSSQL"
 	AZURE_SYNAPSE = "AZURE_SYNAPSE"
+	DUCKDB        = "DUCKDB"
+	S3_DATALAKE   = "S3_DATALAKE"
+	DELTALAKE     = "DELTALAKE"
 )
 
 const (
@@ -42,11 +45,12 @@ const (
 
 // warehouse table names
 const (
-	WarehouseStagingFilesTable = "wh_staging_files"
-	WarehouseLoadFilesTable    = "wh_load_files"
-	WarehouseUploadsTable      = "wh_uploads"
-	WarehouseTableUploadsTable = "wh_table_uploads"
-	WarehouseSchemasTable      = "wh_schemas"
+	WarehouseStagingFilesTable  = "wh_staging_files"
+	WarehouseLoadFilesTable     = "wh_load_files"
+	WarehouseUploadsTable       = "wh_uploads"
+	WarehouseTableUploadsTable  = "wh_table_uploads"
+	WarehouseSchemasTable       = "wh_schemas"
+	WarehouseSchemaChangesTable = "wh_schema_changes"
 )
 
 const (
@@ -74,6 +78,7 @@ const (
 var (
 	serverIP                  string
 	IdentityEnabledWarehouses []string
+	TimeWindowDestinations    []string
 	enableIDResolution        bool
 	AWSCredsExpiryInS         int64
 )
@@ -114,8 +119,11 @@ func init() {
 
 func loadConfig() {
 	IdentityEnabledWarehouses = []string{"SNOWFLAKE", "BQ"}
+	//TimeWindowDestinations have their load files written under the path of their table, in folders of the time window of their events
+	TimeWindowDestinations = []string{S3_DATALAKE, DELTALAKE}
 	config.RegisterBoolConfigVariable(false, &enableIDResolution, false, "Warehouse.enableIDResolution")
 	config.RegisterInt64ConfigVariable(3600, &AWSCredsExpiryInS, true, 1, "Warehouse.awsCredsExpiryInS")
+	config.RegisterDurationConfigVariable(time.Duration(72), &dedupOnLoadWindow, true, time.Hour, "Warehouse.dedupOnLoad.window")
 }
 
 type WarehouseT struct {
@@ -164,6 +172,8 @@ type UploaderI interface {
 	UseRudderStorage() bool
 	GetLoadFileGenStartTIme() time.Time
 	GetLoadFileType() string
+	GetFirstEventAt() time.Time
+	GetDBHandle() *sql.DB
 }
 
 type GetLoadFilesOptionsT struct {
@@ -183,11 +193,11 @@ func IDResolutionEnabled() bool {
 }
 
 type TableSchemaDiffT struct {
-	Exists                         bool
-	TableToBeCreated               bool
-	ColumnMap                      map[string]string
-	UpdatedSchema                  map[string]string
-	StringColumnsToBeAlteredToText []string
+	Exists                         bool              `json:"exists"`
+	TableToBeCreated               bool              `json:"tableToBeCreated"`
+	ColumnMap                      map[string]string `json:"columnMap"`
+	UpdatedSchema                  map[string]string `json:"updatedSchema,omitempty"`
+	StringColumnsToBeAlteredToText []string          `json:"stringColumnsToBeAlteredToText,omitempty"`
 }
 
 type QueryResult struct {
@@ -562,7 +572,14 @@ func ObjectStorageType(destType string, config interface{}, useRudderStorage boo
 	if useRudderStorage {
 		return "S3"
 	}
-	if destType == "RS" || destType == "BQ" || destType == "S3_DATALAKE" {
+	if destType == "S3_DATALAKE" {
+		//datalakes in minio buckets, eg. local iceberg tables, set their bucket provider
+		if provider, _ := c["bucketProvider"].(string); provider == "MINIO" {
+			return provider
+		}
+		return ObjectStorageMap[destType]
+	}
+	if destType == "RS" || destType == "BQ" {
 		return ObjectStorageMap[destType]
 	}
 	if destType == "SNOWFLAKE" {
@@ -1,6 +1,8 @@
 package warehouseutils_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -131,6 +133,54 @@ var _ = Describe("Utils", func() {
 		})
 	})
 
+	Describe("GetSchemaChangePolicy", func() {
+		It("should apply schema changes automatically unless the policy is notify or approve", func() {
+			for policy, expected := range map[interface{}]string{
+				nil:                       SchemaChangePolicyAuto,
+				"unknown":                 SchemaChangePolicyAuto,
+				SchemaChangePolicyNotify:  SchemaChangePolicyNotify,
+				SchemaChangePolicyApprove: SchemaChangePolicyApprove,
+			} {
+				var warehouse WarehouseT
+				warehouse.Destination.Config = map[string]interface{}{SchemaChangePolicyConfig: policy}
+				Expect(GetSchemaChangePolicy(warehouse)).To(Equal(expected))
+			}
+		})
+	})
+
+	Describe("GetDedupOnLoad", func() {
+		firstEventAt := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+		uploader := &dedupUploaderStub{
+			schema:       TableSchemaT{"ID": "string", "EVENT": "string", "RECEIVED_AT": "datetime"},
+			firstEventAt: firstEventAt,
+		}
+		warehouse := func(config map[string]interface{}) WarehouseT {
+			var warehouse WarehouseT
+			warehouse.Destination.Config = config
+			return warehouse
+		}
+
+		It("should merge event tables on the configured keys within the dedup window", func() {
+			dedup, ok := GetDedupOnLoad(warehouse(map[string]interface{}{DedupOnLoadConfig: true, DedupKeysConfig: "id, event"}), uploader, "TRACKS")
+			Expect(ok).To(BeTrue())
+			Expect(dedup.Keys).To(Equal([]string{"ID", "EVENT"}))
+			Expect(dedup.Since).To(Equal(firstEventAt.Add(-72 * time.Hour)))
+		})
+
+		It("should merge on id if a configured key is not a column of the table", func() {
+			dedup, ok := GetDedupOnLoad(warehouse(map[string]interface{}{DedupOnLoadConfig: true, DedupKeysConfig: "message_id"}), uploader, "TRACKS")
+			Expect(ok).To(BeTrue())
+			Expect(dedup.Keys).To(Equal([]string{"ID"}))
+		})
+
+		It("should not merge user tables or destinations without dedup on load", func() {
+			_, ok := GetDedupOnLoad(warehouse(map[string]interface{}{DedupOnLoadConfig: true}), uploader, "USERS")
+			Expect(ok).To(BeFalse())
+			_, ok = GetDedupOnLoad(warehouse(map[string]interface{}{}), uploader, "TRACKS")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	// Describe("Compare Schemas", func() {
 	// 	Context("GetSchemaDiff", func() {
 	// 		var currentSchema map[string]map[string]string
@@ -187,3 +237,17 @@ var _ = Describe("Utils", func() {
 	// 	})
 	// })
 })
+
+type dedupUploaderStub struct {
+	UploaderI
+	schema       TableSchemaT
+	firstEventAt time.Time
+}
+
+func (uploader *dedupUploaderStub) GetTableSchemaInUpload(tableName string) TableSchemaT {
+	return uploader.schema
+}
+
+func (uploader *dedupUploaderStub) GetFirstEventAt() time.Time {
+	return uploader.firstEventAt
+}
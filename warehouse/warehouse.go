@@ -27,6 +27,7 @@ import (
 	destinationConnectionTester "github.com/rudderlabs/rudder-server/services/destination-connection-tester"
 	"github.com/rudderlabs/rudder-server/services/pgnotifier"
 	migrator "github.com/rudderlabs/rudder-server/services/sql-migrator"
+	"github.com/rudderlabs/rudder-server/services/stats"
 	"github.com/rudderlabs/rudder-server/services/validators"
 	"github.com/rudderlabs/rudder-server/utils"
 	"github.com/rudderlabs/rudder-server/utils/logger"
@@ -79,6 +80,8 @@ var (
 	uploadBufferTimeInMin               int
 	ShouldForceSetLowerVersion          bool
 	useParquetLoadFilesRS               bool
+	holdUploadsOnPendingSchemaChanges   bool
+	pendingSchemaChangesRetryInterval   time.Duration
 )
 
 var (
@@ -133,12 +136,12 @@ func init() {
 func loadConfig() {
 	//Port where WH is running
 	config.RegisterIntConfigVariable(8082, &webPort, false, 1, "Warehouse.webPort")
-	WarehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE"}
+	WarehouseDestinations = []string{"RS", "BQ", "SNOWFLAKE", "POSTGRES", "CLICKHOUSE", "MSSQL", "AZURE_SYNAPSE", "S3_DATALAKE", "DUCKDB", "DELTALAKE"}
 	config.RegisterIntConfigVariable(4, &noOfSlaveWorkerRoutines, true, 1, "Warehouse.noOfSlaveWorkerRoutines")
 	config.RegisterIntConfigVariable(960, &stagingFilesBatchSize, true, 1, "Warehouse.stagingFilesBatchSize")
 	config.RegisterInt64ConfigVariable(1800, &uploadFreqInS, true, 1, "Warehouse.uploadFreqInS")
 	config.RegisterDurationConfigVariable(time.Duration(5), &mainLoopSleep, true, time.Second, []string{"Warehouse.mainLoopSleep", "Warehouse.mainLoopSleepInS"}...)
-	crashRecoverWarehouses = []string{"RS", "POSTGRES", "MSSQL", "AZURE_SYNAPSE"}
+	crashRecoverWarehouses = []string{"RS", "POSTGRES", "MSSQL", "AZURE_SYNAPSE", "DUCKDB"}
 	inRecoveryMap = map[string]bool{}
 	lastProcessedMarkerMap = map[string]int64{}
 	config.RegisterStringConfigVariable("embedded", &warehouseMode, false, "Warehouse.mode")
@@ -168,6 +171,8 @@ func loadConfig() {
 	config.RegisterDurationConfigVariable(time.Duration(5), &waitForWorkerSleep, false, time.Second, []string{"Warehouse.waitForWorkerSleep", "Warehouse.waitForWorkerSleepInS"}...)
 	config.RegisterBoolConfigVariable(false, &ShouldForceSetLowerVersion, false, "SQLMigrator.forceSetLowerVersion")
 	config.RegisterBoolConfigVariable(false, &useParquetLoadFilesRS, true, "Warehouse.useParquetLoadFilesRS")
+	config.RegisterBoolConfigVariable(false, &holdUploadsOnPendingSchemaChanges, true, "Warehouse.schemaChanges.holdUploads")
+	config.RegisterDurationConfigVariable(time.Duration(30), &pendingSchemaChangesRetryInterval, true, time.Minute, "Warehouse.schemaChanges.retryInterval")
 }
 
 // get name of the worker (`destID_namespace`) to be stored in map wh.workerChannelMap
@@ -961,7 +966,7 @@ func getLoadFileFormat(whType string) string {
 	switch whType {
 	case "BQ":
 		return "json.gz"
-	case "S3_DATALAKE":
+	case "S3_DATALAKE", "DUCKDB", "DELTALAKE":
 		return "parquet"
 	case "RS":
 		if useParquetLoadFilesRS {
@@ -1448,6 +1453,7 @@ func startWebHandler() {
 	// do not register same endpoint when running embedded in rudder backend
 	if isStandAlone() {
 		http.HandleFunc("/health", healthHandler)
+		http.HandleFunc("/metrics", stats.MetricsHandler)
 	}
 	if isMaster() {
 		backendconfig.WaitForConfig()
@@ -1597,7 +1603,7 @@ func getLoadFileType(wh string) string {
 			return warehouseutils.LOAD_FILE_TYPE_PARQUET
 		}
 		return warehouseutils.LOAD_FILE_TYPE_CSV
-	case "S3_DATALAKE":
+	case "S3_DATALAKE", "DUCKDB", "DELTALAKE":
 		return warehouseutils.LOAD_FILE_TYPE_PARQUET
 	default:
 		return warehouseutils.LOAD_FILE_TYPE_CSV
@@ -62,3 +62,37 @@ func (w *warehousegrpc) TriggerWHUpload(context context.Context, request *proto.
 	err := uploadReq.TriggerWHUpload()
 	return new(emptypb.Empty), err
 }
+
+func (w *warehousegrpc) GetWHSchemaChanges(context context.Context, request *proto.WHSchemaChangesRequest) (*proto.WHSchemaChangesResponse, error) {
+	schemaChangesReq := SchemaChangesReqT{
+		WorkspaceID:   request.WorkspaceId,
+		SourceID:      request.SourceId,
+		DestinationID: request.DestinationId,
+		Status:        request.Status,
+		Limit:         request.Limit,
+		Offset:        request.Offset,
+		API:           UploadAPI,
+	}
+	res, err := schemaChangesReq.GetWhSchemaChanges()
+	return res, err
+}
+
+func (w *warehousegrpc) ApproveWHSchemaChange(context context.Context, request *proto.WHSchemaChangeRequest) (*emptypb.Empty, error) {
+	schemaChangeReq := SchemaChangeReqT{
+		ID:          request.Id,
+		WorkspaceID: request.WorkspaceId,
+		API:         UploadAPI,
+	}
+	err := schemaChangeReq.DecideWHSchemaChange(SchemaChangeApproved)
+	return new(emptypb.Empty), err
+}
+
+func (w *warehousegrpc) RejectWHSchemaChange(context context.Context, request *proto.WHSchemaChangeRequest) (*emptypb.Empty, error) {
+	schemaChangeReq := SchemaChangeReqT{
+		ID:          request.Id,
+		WorkspaceID: request.WorkspaceId,
+		API:         UploadAPI,
+	}
+	err := schemaChangeReq.DecideWHSchemaChange(SchemaChangeRejected)
+	return new(emptypb.Empty), err
+}